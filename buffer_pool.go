@@ -55,14 +55,28 @@ func (b *packetBuffer) Len() protocol.ByteCount {
 	return protocol.ByteCount(len(b.Data))
 }
 
+// Cap returns the capacity of Data
+func (b *packetBuffer) Cap() protocol.ByteCount {
+	return protocol.ByteCount(cap(b.Data))
+}
+
 func (b *packetBuffer) putBack() {
-	if cap(b.Data) != int(protocol.MaxReceivePacketSize) {
+	switch cap(b.Data) {
+	case int(protocol.MaxReceivePacketSize):
+		bufferPool.Put(b)
+	case int(maxLargePacketBufferSize):
+		largeBufferPool.Put(b)
+	case int(protocol.MaxJumboPacketSize):
+		jumboBufferPool.Put(b)
+	default:
 		panic("putPacketBuffer called with packet of wrong size!")
 	}
-	bufferPool.Put(b)
 }
 
-var bufferPool sync.Pool
+// maxLargePacketBufferSize is the size of the buffers used for sending batches of packets.
+const maxLargePacketBufferSize = protocol.MaxBatchedPackets * protocol.MaxReceivePacketSize
+
+var bufferPool, largeBufferPool, jumboBufferPool sync.Pool
 
 func getPacketBuffer() *packetBuffer {
 	buf := bufferPool.Get().(*packetBuffer)
@@ -71,10 +85,40 @@ func getPacketBuffer() *packetBuffer {
 	return buf
 }
 
+// getPacketBufferForSize returns a buffer that is large enough to hold a packet of the given size.
+// Buffers for packets larger than protocol.MaxReceivePacketSize are taken from a separate pool.
+func getPacketBufferForSize(size protocol.ByteCount) *packetBuffer {
+	if size <= protocol.MaxReceivePacketSize {
+		return getPacketBuffer()
+	}
+	buf := jumboBufferPool.Get().(*packetBuffer)
+	buf.refCount = 1
+	buf.Data = buf.Data[:0]
+	return buf
+}
+
+// getLargePacketBuffer returns a buffer that is large enough to hold protocol.MaxBatchedPackets packets.
+func getLargePacketBuffer() *packetBuffer {
+	buf := largeBufferPool.Get().(*packetBuffer)
+	buf.refCount = 1
+	buf.Data = buf.Data[:0]
+	return buf
+}
+
 func init() {
 	bufferPool.New = func() interface{} {
 		return &packetBuffer{
 			Data: make([]byte, 0, protocol.MaxReceivePacketSize),
 		}
 	}
+	largeBufferPool.New = func() interface{} {
+		return &packetBuffer{
+			Data: make([]byte, 0, maxLargePacketBufferSize),
+		}
+	}
+	jumboBufferPool.New = func() interface{} {
+		return &packetBuffer{
+			Data: make([]byte, 0, protocol.MaxJumboPacketSize),
+		}
+	}
 }
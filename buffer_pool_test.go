@@ -13,11 +13,29 @@ var _ = Describe("Buffer Pool", func() {
 		Expect(buf.Data).To(HaveCap(int(protocol.MaxReceivePacketSize)))
 	})
 
+	It("returns large buffers", func() {
+		buf := getLargePacketBuffer()
+		Expect(buf.Data).To(HaveCap(int(protocol.MaxBatchedPackets * protocol.MaxReceivePacketSize)))
+		Expect(buf.Cap()).To(Equal(protocol.MaxBatchedPackets * protocol.MaxReceivePacketSize))
+	})
+
+	It("returns buffers for jumbo packets", func() {
+		Expect(getPacketBufferForSize(protocol.MaxReceivePacketSize).Data).To(HaveCap(int(protocol.MaxReceivePacketSize)))
+		buf := getPacketBufferForSize(protocol.MaxReceivePacketSize + 1)
+		Expect(buf.Data).To(HaveCap(int(protocol.MaxJumboPacketSize)))
+		buf.Release()
+	})
+
 	It("releases buffers", func() {
 		buf := getPacketBuffer()
 		buf.Release()
 	})
 
+	It("releases large buffers", func() {
+		buf := getLargePacketBuffer()
+		buf.Release()
+	})
+
 	It("gets the length", func() {
 		buf := getPacketBuffer()
 		buf.Data = append(buf.Data, []byte("foobar")...)
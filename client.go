@@ -119,6 +119,7 @@ func dialAddrContext(
 // Dial establishes a new QUIC connection to a server using a net.PacketConn.
 // If the PacketConn satisfies the ECNCapablePacketConn interface (as a net.UDPConn does), ECN support will be enabled.
 // In this case, ReadMsgUDP will be used instead of ReadFrom to read packets.
+// Outgoing packets are only marked with ECT(0) on Linux, when using a net.UDPConn.
 // The same PacketConn can be used for multiple calls to Dial and Listen,
 // QUIC connection IDs are used for demultiplexing the different connections.
 // The host parameter is used for SNI.
@@ -191,7 +192,7 @@ func dialContext(
 		return nil, err
 	}
 	config = populateClientConfig(config, createdPacketConn)
-	packetHandlers, err := getMultiplexer().AddConn(pconn, config.ConnectionIDLength, config.StatelessResetKey, config.Tracer)
+	packetHandlers, err := getMultiplexer().AddConn(pconn, config.ConnectionIDLength, protocol.ByteCount(config.MaxPacketSize), config.StatelessResetKey, config.Tracer)
 	if err != nil {
 		return nil, err
 	}
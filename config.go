@@ -2,6 +2,7 @@ package quic
 
 import (
 	"errors"
+	"net"
 	"time"
 
 	"github.com/For-ACGN/quic-go/internal/utils"
@@ -29,6 +30,21 @@ func validateConfig(config *Config) error {
 	if config.MaxIncomingUniStreams > 1<<60 {
 		return errors.New("invalid value for Config.MaxIncomingUniStreams")
 	}
+	if config.CongestionControl > protocol.CongestionControlBBR {
+		return errors.New("invalid value for Config.CongestionControl")
+	}
+	if config.StreamScheduling > protocol.StreamSchedulingWeightedFair {
+		return errors.New("invalid value for Config.StreamScheduling")
+	}
+	if config.MaxPacketSize != 0 && (config.MaxPacketSize < protocol.MinInitialPacketSize || protocol.ByteCount(config.MaxPacketSize) > protocol.MaxJumboPacketSize) {
+		return errors.New("invalid value for Config.MaxPacketSize")
+	}
+	if addr := config.PreferredAddressIPv4; addr != nil && (addr.IP.To4() == nil || addr.IP.IsUnspecified()) {
+		return errors.New("invalid value for Config.PreferredAddressIPv4")
+	}
+	if addr := config.PreferredAddressIPv6; addr != nil && (len(addr.IP) != net.IPv6len || addr.IP.To4() != nil || addr.IP.IsUnspecified()) {
+		return errors.New("invalid value for Config.PreferredAddressIPv6")
+	}
 	return nil
 }
 
@@ -79,6 +95,10 @@ func populateConfig(config *Config) *Config {
 	if maxReceiveConnectionFlowControlWindow == 0 {
 		maxReceiveConnectionFlowControlWindow = protocol.DefaultMaxReceiveConnectionFlowControlWindow
 	}
+	maxPacketSize := config.MaxPacketSize
+	if maxPacketSize == 0 {
+		maxPacketSize = int(protocol.MaxReceivePacketSize)
+	}
 	maxIncomingStreams := config.MaxIncomingStreams
 	if maxIncomingStreams == 0 {
 		maxIncomingStreams = protocol.DefaultMaxIncomingStreams
@@ -98,12 +118,19 @@ func populateConfig(config *Config) *Config {
 		MaxIdleTimeout:                        idleTimeout,
 		AcceptToken:                           config.AcceptToken,
 		KeepAlive:                             config.KeepAlive,
+		CongestionControl:                     config.CongestionControl,
+		NewCongestionControl:                  config.NewCongestionControl,
+		EnablePathMTUDiscovery:                config.EnablePathMTUDiscovery,
+		MaxPacketSize:                         maxPacketSize,
+		StreamScheduling:                      config.StreamScheduling,
 		MaxReceiveStreamFlowControlWindow:     maxReceiveStreamFlowControlWindow,
 		MaxReceiveConnectionFlowControlWindow: maxReceiveConnectionFlowControlWindow,
 		MaxIncomingStreams:                    maxIncomingStreams,
 		MaxIncomingUniStreams:                 maxIncomingUniStreams,
 		ConnectionIDLength:                    config.ConnectionIDLength,
 		StatelessResetKey:                     config.StatelessResetKey,
+		PreferredAddressIPv4:                  config.PreferredAddressIPv4,
+		PreferredAddressIPv6:                  config.PreferredAddressIPv6,
 		TokenStore:                            config.TokenStore,
 		EnableDatagrams:                       config.EnableDatagrams,
 		Tracer:                                config.Tracer,
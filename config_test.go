@@ -6,6 +6,7 @@ import (
 	"reflect"
 	"time"
 
+	"github.com/For-ACGN/quic-go/congestion"
 	mocklogging "github.com/For-ACGN/quic-go/internal/mocks/logging"
 	"github.com/For-ACGN/quic-go/internal/protocol"
 
@@ -30,6 +31,34 @@ var _ = Describe("Config", func() {
 		It("errors on too large values for MaxIncomingUniStreams", func() {
 			Expect(validateConfig(&Config{MaxIncomingUniStreams: 1<<60 + 1})).To(MatchError("invalid value for Config.MaxIncomingUniStreams"))
 		})
+
+		It("errors on unknown congestion control algorithms", func() {
+			Expect(validateConfig(&Config{CongestionControl: 42})).To(MatchError("invalid value for Config.CongestionControl"))
+		})
+
+		It("errors on unknown stream scheduling modes", func() {
+			Expect(validateConfig(&Config{StreamScheduling: StreamSchedulingWeightedFair})).To(Succeed())
+			Expect(validateConfig(&Config{StreamScheduling: 42})).To(MatchError("invalid value for Config.StreamScheduling"))
+		})
+
+		It("errors on invalid maximum packet sizes", func() {
+			Expect(validateConfig(&Config{MaxPacketSize: 1200})).To(Succeed())
+			Expect(validateConfig(&Config{MaxPacketSize: int(protocol.MaxJumboPacketSize)})).To(Succeed())
+			Expect(validateConfig(&Config{MaxPacketSize: 1199})).To(MatchError("invalid value for Config.MaxPacketSize"))
+			Expect(validateConfig(&Config{MaxPacketSize: int(protocol.MaxJumboPacketSize) + 1})).To(MatchError("invalid value for Config.MaxPacketSize"))
+		})
+
+		It("errors on invalid preferred IPv4 addresses", func() {
+			Expect(validateConfig(&Config{PreferredAddressIPv4: &net.UDPAddr{IP: net.IPv4(192, 0, 2, 1)}})).To(Succeed())
+			Expect(validateConfig(&Config{PreferredAddressIPv4: &net.UDPAddr{IP: net.ParseIP("2001:db8::1")}})).To(MatchError("invalid value for Config.PreferredAddressIPv4"))
+			Expect(validateConfig(&Config{PreferredAddressIPv4: &net.UDPAddr{IP: net.IPv4zero}})).To(MatchError("invalid value for Config.PreferredAddressIPv4"))
+		})
+
+		It("errors on invalid preferred IPv6 addresses", func() {
+			Expect(validateConfig(&Config{PreferredAddressIPv6: &net.UDPAddr{IP: net.ParseIP("2001:db8::1")}})).To(Succeed())
+			Expect(validateConfig(&Config{PreferredAddressIPv6: &net.UDPAddr{IP: net.IPv4(192, 0, 2, 1)}})).To(MatchError("invalid value for Config.PreferredAddressIPv6"))
+			Expect(validateConfig(&Config{PreferredAddressIPv6: &net.UDPAddr{IP: net.IPv6unspecified}})).To(MatchError("invalid value for Config.PreferredAddressIPv6"))
+		})
 	})
 
 	configWithNonZeroNonFunctionFields := func() *Config {
@@ -45,7 +74,7 @@ var _ = Describe("Config", func() {
 			}
 
 			switch fn := typ.Field(i).Name; fn {
-			case "AcceptToken", "GetLogWriter":
+			case "AcceptToken", "GetLogWriter", "NewCongestionControl":
 				// Can't compare functions.
 			case "Versions":
 				f.Set(reflect.ValueOf([]VersionNumber{1, 2, 3}))
@@ -67,8 +96,20 @@ var _ = Describe("Config", func() {
 				f.Set(reflect.ValueOf(int64(12)))
 			case "StatelessResetKey":
 				f.Set(reflect.ValueOf([]byte{1, 2, 3, 4}))
+			case "PreferredAddressIPv4":
+				f.Set(reflect.ValueOf(&net.UDPAddr{IP: net.IPv4(192, 0, 2, 1), Port: 443}))
+			case "PreferredAddressIPv6":
+				f.Set(reflect.ValueOf(&net.UDPAddr{IP: net.ParseIP("2001:db8::1"), Port: 443}))
 			case "KeepAlive":
 				f.Set(reflect.ValueOf(true))
+			case "CongestionControl":
+				f.Set(reflect.ValueOf(CongestionControlBBR))
+			case "EnablePathMTUDiscovery":
+				f.Set(reflect.ValueOf(true))
+			case "MaxPacketSize":
+				f.Set(reflect.ValueOf(1337))
+			case "StreamScheduling":
+				f.Set(reflect.ValueOf(StreamSchedulingWeightedFair))
 			case "EnableDatagrams":
 				f.Set(reflect.ValueOf(true))
 			case "Tracer":
@@ -122,13 +163,19 @@ var _ = Describe("Config", func() {
 
 	Context("populating", func() {
 		It("populates function fields", func() {
-			var calledAcceptToken bool
+			var calledAcceptToken, calledNewCongestionControl bool
 			c1 := &Config{
 				AcceptToken: func(_ net.Addr, _ *Token) bool { calledAcceptToken = true; return true },
+				NewCongestionControl: func(*congestion.RTTStats) congestion.SendAlgorithm {
+					calledNewCongestionControl = true
+					return nil
+				},
 			}
 			c2 := populateConfig(c1)
 			c2.AcceptToken(&net.UDPAddr{}, &Token{})
 			Expect(calledAcceptToken).To(BeTrue())
+			c2.NewCongestionControl(nil)
+			Expect(calledNewCongestionControl).To(BeTrue())
 		})
 
 		It("copies non-function fields", func() {
@@ -144,6 +191,7 @@ var _ = Describe("Config", func() {
 			Expect(c.MaxReceiveConnectionFlowControlWindow).To(BeEquivalentTo(protocol.DefaultMaxReceiveConnectionFlowControlWindow))
 			Expect(c.MaxIncomingStreams).To(BeEquivalentTo(protocol.DefaultMaxIncomingStreams))
 			Expect(c.MaxIncomingUniStreams).To(BeEquivalentTo(protocol.DefaultMaxIncomingUniStreams))
+			Expect(c.MaxPacketSize).To(BeEquivalentTo(protocol.MaxReceivePacketSize))
 		})
 
 		It("populates empty fields with default values, for the server", func() {
@@ -0,0 +1,51 @@
+// Package congestion defines the interface for congestion controllers used by quic-go.
+package congestion
+
+import (
+	"time"
+
+	"github.com/For-ACGN/quic-go/internal/protocol"
+	"github.com/For-ACGN/quic-go/internal/utils"
+)
+
+type (
+	// A ByteCount is used to count bytes.
+	ByteCount = protocol.ByteCount
+	// The PacketNumber is the packet number of a packet.
+	PacketNumber = protocol.PacketNumber
+	// The RTTStats contain the RTT statistics of a connection.
+	RTTStats = utils.RTTStats
+)
+
+// A SendAlgorithm performs congestion control.
+// All methods are called from the connection's run loop, so implementations don't need to be safe for concurrent use.
+type SendAlgorithm interface {
+	// TimeUntilSend returns when the next packet should be sent.
+	// It is used for pacing packets. A zero value means that a packet can be sent immediately.
+	TimeUntilSend(bytesInFlight ByteCount) time.Time
+	// HasPacingBudget says if the pacer allows sending of a (full size) packet at this moment.
+	HasPacingBudget() bool
+	// OnPacketSent is called for every packet sent.
+	// If the packet is retransmittable, bytesInFlight already includes the bytes of this packet.
+	OnPacketSent(sentTime time.Time, bytesInFlight ByteCount, packetNumber PacketNumber, bytes ByteCount, isRetransmittable bool)
+	// CanSend says if the congestion window allows sending more packets.
+	CanSend(bytesInFlight ByteCount) bool
+	// MaybeExitSlowStart is called when the RTT was updated, before the acknowledged packets are reported.
+	MaybeExitSlowStart()
+	// OnPacketAcked is called for every retransmittable packet that was acknowledged.
+	OnPacketAcked(number PacketNumber, ackedBytes ByteCount, priorInFlight ByteCount, eventTime time.Time)
+	// OnPacketLost is called for every retransmittable packet that was declared lost.
+	OnPacketLost(number PacketNumber, lostBytes ByteCount, priorInFlight ByteCount)
+	// OnRetransmissionTimeout is called when a retransmission timeout fires.
+	OnRetransmissionTimeout(packetsRetransmitted bool)
+	// GetCongestionWindow returns the current congestion window.
+	GetCongestionWindow() ByteCount
+}
+
+// An ECNCongestionHandler is a SendAlgorithm that uses ECN-CE marks as a congestion signal (see section 7 of RFC 9002).
+// If the SendAlgorithm doesn't implement this interface, ECN-CE marks don't change the congestion window.
+type ECNCongestionHandler interface {
+	// OnECNCongestion is called when the peer reports an increase of the ECN-CE count.
+	// largestAcked is the largest packet number acknowledged by the ACK frame that reported the increase.
+	OnECNCongestion(largestAcked PacketNumber, priorInFlight ByteCount)
+}
@@ -19,6 +19,7 @@ type connection interface {
 
 // If the PacketConn passed to Dial or Listen satisfies this interface, quic-go will read the ECN bits from the IP header.
 // In this case, ReadMsgUDP() will be used instead of ReadFrom() to read packets.
+// Setting the ECN bits on outgoing packets requires sending batches of packets, which is only implemented on Linux.
 type ECNCapablePacketConn interface {
 	net.PacketConn
 	SyscallConn() (syscall.RawConn, error)
@@ -27,26 +28,29 @@ type ECNCapablePacketConn interface {
 
 var _ ECNCapablePacketConn = &net.UDPConn{}
 
-func wrapConn(pc net.PacketConn) (connection, error) {
+// wrapConn wraps a net.PacketConn.
+// Packets larger than maxPacketSize are truncated when they are read.
+func wrapConn(pc net.PacketConn, maxPacketSize protocol.ByteCount) (connection, error) {
 	c, ok := pc.(ECNCapablePacketConn)
 	if !ok {
 		utils.DefaultLogger.Infof("PacketConn is not a net.UDPConn. Disabling optimizations possible on UDP connections.")
-		return &basicConn{PacketConn: pc}, nil
+		return &basicConn{PacketConn: pc, maxPacketSize: maxPacketSize}, nil
 	}
-	return newConn(c)
+	return newConn(c, maxPacketSize)
 }
 
 type basicConn struct {
 	net.PacketConn
+	maxPacketSize protocol.ByteCount
 }
 
 var _ connection = &basicConn{}
 
 func (c *basicConn) ReadPacket() (*receivedPacket, error) {
-	buffer := getPacketBuffer()
-	// The packet size should not exceed protocol.MaxReceivePacketSize bytes
+	buffer := getPacketBufferForSize(c.maxPacketSize)
+	// The packet size should not exceed the maximum packet size.
 	// If it does, we only read a truncated packet, which will then end up undecryptable
-	buffer.Data = buffer.Data[:protocol.MaxReceivePacketSize]
+	buffer.Data = buffer.Data[:c.maxPacketSize]
 	n, addr, err := c.PacketConn.ReadFrom(buffer.Data)
 	if err != nil {
 		return nil, err
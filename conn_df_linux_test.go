@@ -0,0 +1,37 @@
+// +build linux
+
+package quic
+
+import (
+	"errors"
+	"net"
+	"os"
+
+	"golang.org/x/sys/unix"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("DF bit", func() {
+	It("sets the DF bit", func() {
+		conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+		Expect(err).ToNot(HaveOccurred())
+		defer conn.Close()
+		rawConn, err := conn.SyscallConn()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(setDF(rawConn)).To(Succeed())
+		var val int
+		Expect(rawConn.Control(func(fd uintptr) {
+			val, err = unix.GetsockoptInt(int(fd), unix.IPPROTO_IP, unix.IP_MTU_DISCOVER)
+		})).To(Succeed())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(val).To(Equal(unix.IP_PMTUDISC_DO))
+	})
+
+	It("detects errors caused by packets that are too large", func() {
+		err := &net.OpError{Op: "write", Err: os.NewSyscallError("sendto", unix.EMSGSIZE)}
+		Expect(isMsgSizeErr(err)).To(BeTrue())
+		Expect(isMsgSizeErr(errors.New("test error"))).To(BeFalse())
+	})
+})
@@ -9,6 +9,7 @@ import (
 	"syscall"
 	"time"
 
+	"golang.org/x/net/ipv4"
 	"golang.org/x/sys/unix"
 
 	"github.com/For-ACGN/quic-go/internal/protocol"
@@ -17,6 +18,9 @@ import (
 
 const ecnMask uint8 = 0x3
 
+// maxGROSize is the maximum size of a datagram coalesced by UDP GRO.
+const maxGROSize = 1 << 16
+
 func inspectReadBuffer(c net.PacketConn) (int, error) {
 	conn, ok := c.(interface {
 		SyscallConn() (syscall.RawConn, error)
@@ -40,16 +44,32 @@ func inspectReadBuffer(c net.PacketConn) (int, error) {
 
 type ecnConn struct {
 	ECNCapablePacketConn
-	oobBuffer []byte
+	oobBuffer     []byte
+	maxPacketSize protocol.ByteCount
+
+	// Only set if multiple packets are read using a single syscall (on Linux).
+	batchConn  *ipv4.PacketConn
+	groEnabled bool
+	messages   []ipv4.Message
+	// The packet buffers the messages are read into. Only used if GRO is disabled.
+	buffers []*packetBuffer
+	// Packets that were read from the conn, but not yet returned by ReadPacket.
+	packets    []*receivedPacket
+	nextPacket int
 }
 
 var _ connection = &ecnConn{}
 
-func newConn(c ECNCapablePacketConn) (*ecnConn, error) {
+func newConn(c ECNCapablePacketConn, maxPacketSize protocol.ByteCount) (*ecnConn, error) {
 	rawConn, err := c.SyscallConn()
 	if err != nil {
 		return nil, err
 	}
+	// Set the Don't Fragment bit, so that packets exceeding the path MTU are dropped instead of fragmented.
+	// This is required for path MTU discovery.
+	if err := setDF(rawConn); err != nil {
+		utils.DefaultLogger.Debugf("Setting DF failed: %s", err)
+	}
 	// We don't know if this a IPv4-only, IPv6-only or a IPv4-and-IPv6 connection.
 	// Try enabling receiving of ECN for both IP versions.
 	// We expect at least one of those syscalls to succeed.
@@ -74,42 +94,153 @@ func newConn(c ECNCapablePacketConn) (*ecnConn, error) {
 	case errIPv4 != nil && errIPv6 != nil:
 		return nil, errors.New("activating ECN failed for both IPv4 and IPv6")
 	}
-	return &ecnConn{
+	conn := &ecnConn{
 		ECNCapablePacketConn: c,
 		oobBuffer:            make([]byte, 128),
-	}, nil
+		maxPacketSize:        maxPacketSize,
+	}
+	// ipv4.PacketConn.ReadBatch only uses recvmmsg for *net.UDPConns.
+	if udpConn, ok := c.(*net.UDPConn); ok && readBatchSize > 1 {
+		conn.initBatchReading(udpConn, rawConn)
+	}
+	return conn, nil
+}
+
+func (c *ecnConn) initBatchReading(udpConn *net.UDPConn, rawConn syscall.RawConn) {
+	c.batchConn = ipv4.NewPacketConn(udpConn)
+	c.groEnabled = enableGRO(rawConn)
+	if c.groEnabled {
+		utils.DefaultLogger.Debugf("Activating UDP GRO.")
+	}
+	c.messages = make([]ipv4.Message, readBatchSize)
+	c.packets = make([]*receivedPacket, 0, readBatchSize)
+	if !c.groEnabled {
+		c.buffers = make([]*packetBuffer, readBatchSize)
+	}
+	for i := range c.messages {
+		if c.groEnabled {
+			// Coalesced datagrams are split into separate packet buffers after reading.
+			c.messages[i].Buffers = [][]byte{make([]byte, maxGROSize)}
+		} else {
+			c.buffers[i] = getPacketBufferForSize(c.maxPacketSize)
+			c.messages[i].Buffers = [][]byte{c.buffers[i].Data[:c.maxPacketSize]}
+		}
+		c.messages[i].OOB = make([]byte, 128)
+	}
 }
 
 func (c *ecnConn) ReadPacket() (*receivedPacket, error) {
-	buffer := getPacketBuffer()
-	// The packet size should not exceed protocol.MaxReceivePacketSize bytes
+	if c.batchConn == nil {
+		return c.readPacket()
+	}
+	if c.nextPacket == len(c.packets) {
+		if err := c.readBatch(); err != nil {
+			return nil, err
+		}
+	}
+	p := c.packets[c.nextPacket]
+	c.packets[c.nextPacket] = nil
+	c.nextPacket++
+	return p, nil
+}
+
+func (c *ecnConn) readPacket() (*receivedPacket, error) {
+	buffer := getPacketBufferForSize(c.maxPacketSize)
+	// The packet size should not exceed the maximum packet size.
 	// If it does, we only read a truncated packet, which will then end up undecryptable
-	buffer.Data = buffer.Data[:protocol.MaxReceivePacketSize]
+	buffer.Data = buffer.Data[:c.maxPacketSize]
 	c.oobBuffer = c.oobBuffer[:cap(c.oobBuffer)]
 	n, oobn, _, addr, err := c.ECNCapablePacketConn.ReadMsgUDP(buffer.Data, c.oobBuffer)
 	if err != nil {
 		return nil, err
 	}
-	ctrlMsgs, err := unix.ParseSocketControlMessage(c.oobBuffer[:oobn])
+	ecn, _, err := parseControlMessages(c.oobBuffer[:oobn])
 	if err != nil {
 		return nil, err
 	}
+	return &receivedPacket{
+		remoteAddr: addr,
+		rcvTime:    time.Now(),
+		data:       buffer.Data[:n],
+		ecn:        ecn,
+		buffer:     buffer,
+	}, nil
+}
+
+// readBatch reads multiple packets using a single recvmmsg syscall.
+// Datagrams coalesced by UDP GRO are split into separate packets.
+func (c *ecnConn) readBatch() error {
+	n, err := c.batchConn.ReadBatch(c.messages, 0)
+	if err != nil {
+		return err
+	}
+	rcvTime := time.Now()
+	c.packets = c.packets[:0]
+	c.nextPacket = 0
+	for i := range c.messages[:n] {
+		msg := &c.messages[i]
+		ecn, segmentSize, err := parseControlMessages(msg.OOB[:msg.NN])
+		if err != nil {
+			return err
+		}
+		if !c.groEnabled {
+			buffer := c.buffers[i]
+			c.packets = append(c.packets, &receivedPacket{
+				remoteAddr: msg.Addr,
+				rcvTime:    rcvTime,
+				data:       buffer.Data[:msg.N],
+				ecn:        ecn,
+				buffer:     buffer,
+			})
+			c.buffers[i] = getPacketBufferForSize(c.maxPacketSize)
+			msg.Buffers[0] = c.buffers[i].Data[:c.maxPacketSize]
+			continue
+		}
+		data := msg.Buffers[0][:msg.N]
+		if segmentSize == 0 {
+			// The datagram wasn't coalesced.
+			segmentSize = len(data)
+		}
+		for len(data) > 0 {
+			size := utils.Min(segmentSize, len(data))
+			buffer := getPacketBufferForSize(c.maxPacketSize)
+			// The packet size should not exceed the maximum packet size.
+			// If it does, we only copy a truncated packet, which will then end up undecryptable
+			l := copy(buffer.Data[:c.maxPacketSize], data[:size])
+			c.packets = append(c.packets, &receivedPacket{
+				remoteAddr: msg.Addr,
+				rcvTime:    rcvTime,
+				data:       buffer.Data[:l],
+				ecn:        ecn,
+				buffer:     buffer,
+			})
+			data = data[size:]
+		}
+	}
+	return nil
+}
+
+// parseControlMessages parses the control messages received with a packet.
+// It returns the ECN bits, and the UDP GRO segment size (0 if the datagram wasn't coalesced).
+func parseControlMessages(oob []byte) (protocol.ECN, int, error) {
+	ctrlMsgs, err := unix.ParseSocketControlMessage(oob)
+	if err != nil {
+		return 0, 0, err
+	}
 	var ecn protocol.ECN
+	var segmentSize int
 	for _, ctrlMsg := range ctrlMsgs {
 		if ctrlMsg.Header.Level == unix.IPPROTO_IP && ctrlMsg.Header.Type == msgTypeIPTOS {
 			ecn = protocol.ECN(ctrlMsg.Data[0] & ecnMask)
-			break
+			continue
 		}
 		if ctrlMsg.Header.Level == unix.IPPROTO_IPV6 && ctrlMsg.Header.Type == unix.IPV6_TCLASS {
 			ecn = protocol.ECN(ctrlMsg.Data[0] & ecnMask)
-			break
+			continue
+		}
+		if size, ok := parseGROSegmentSize(ctrlMsg); ok {
+			segmentSize = size
 		}
 	}
-	return &receivedPacket{
-		remoteAddr: addr,
-		rcvTime:    time.Now(),
-		data:       buffer.Data[:n],
-		ecn:        ecn,
-		buffer:     buffer,
-	}, nil
+	return ecn, segmentSize, nil
 }
@@ -3,6 +3,7 @@
 package quic
 
 import (
+	"fmt"
 	"net"
 	"time"
 
@@ -22,7 +23,7 @@ var _ = Describe("Basic Conn Test", func() {
 			Expect(err).ToNot(HaveOccurred())
 			udpConn, err := net.ListenUDP(network, addr)
 			Expect(err).ToNot(HaveOccurred())
-			ecnConn, err := newConn(udpConn)
+			ecnConn, err := newConn(udpConn, protocol.MaxReceivePacketSize)
 			Expect(err).ToNot(HaveOccurred())
 
 			packetChan := make(chan *receivedPacket)
@@ -93,6 +94,28 @@ var _ = Describe("Basic Conn Test", func() {
 			Expect(p.ecn).To(Equal(protocol.ECNCE))
 		})
 
+		It("reads multiple packets", func() {
+			conn, packetChan := runServer("udp4", "localhost:0")
+			defer conn.Close()
+
+			sendConn, err := net.DialUDP("udp4", nil, conn.LocalAddr().(*net.UDPAddr))
+			Expect(err).ToNot(HaveOccurred())
+			defer sendConn.Close()
+			for i := 0; i < 25; i++ {
+				_, err := sendConn.Write([]byte(fmt.Sprintf("packet %d", i)))
+				Expect(err).ToNot(HaveOccurred())
+			}
+
+			for i := 0; i < 25; i++ {
+				var p *receivedPacket
+				Eventually(packetChan).Should(Receive(&p))
+				Expect(p.data).To(Equal([]byte(fmt.Sprintf("packet %d", i))))
+				Expect(p.remoteAddr).To(Equal(sendConn.LocalAddr()))
+				Expect(p.buffer).ToNot(BeNil())
+				p.buffer.Release()
+			}
+		})
+
 		It("reads ECN flags on a connection that supports both IPv4 and IPv6", func() {
 			conn, packetChan := runServer("udp", "0.0.0.0:0")
 			defer conn.Close()
@@ -2,12 +2,20 @@
 
 package quic
 
-import "net"
+import (
+	"net"
 
-func newConn(c net.PacketConn) (connection, error) {
-	return &basicConn{PacketConn: c}, nil
+	"github.com/For-ACGN/quic-go/internal/protocol"
+)
+
+func newConn(c net.PacketConn, maxPacketSize protocol.ByteCount) (connection, error) {
+	return &basicConn{PacketConn: c, maxPacketSize: maxPacketSize}, nil
 }
 
 func inspectReadBuffer(net.PacketConn) (int, error) {
 	return 0, nil
 }
+
+func newBatchWriter(net.PacketConn) batchWriter {
+	return nil
+}
@@ -2,6 +2,22 @@
 
 package quic
 
-import "golang.org/x/sys/unix"
+import (
+	"net"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
 
 const msgTypeIPTOS = unix.IP_RECVTOS
+
+// macOS doesn't support recvmmsg, so packets are read one by one.
+const readBatchSize = 1
+
+func enableGRO(syscall.RawConn) bool { return false }
+
+func parseGROSegmentSize(unix.SocketControlMessage) (int, bool) { return 0, false }
+
+func newBatchWriter(net.PacketConn) batchWriter {
+	return nil
+}
@@ -2,6 +2,204 @@
 
 package quic
 
-import "golang.org/x/sys/unix"
+import (
+	"errors"
+	"net"
+	"sync"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/net/ipv4"
+	"golang.org/x/sys/unix"
+
+	"github.com/For-ACGN/quic-go/internal/protocol"
+	"github.com/For-ACGN/quic-go/internal/utils"
+)
 
 const msgTypeIPTOS = unix.IP_TOS
+
+// UDP_SEGMENT and UDP_GRO are not defined in the version of x/sys/unix we're using.
+// See https://github.com/torvalds/linux/blob/master/include/uapi/linux/udp.h.
+const (
+	udpSegment = 103
+	udpGRO     = 104
+)
+
+// readBatchSize is the number of packets read using a single recvmmsg syscall.
+const readBatchSize = protocol.MaxBatchedPackets
+
+// enableGRO enables UDP generic receive offload (GRO).
+// This allows the kernel to coalesce multiple datagrams received from the same sender.
+// It is supported on Linux 5.0 and newer.
+func enableGRO(rawConn syscall.RawConn) bool {
+	var serr error
+	if err := rawConn.Control(func(fd uintptr) {
+		serr = unix.SetsockoptInt(int(fd), unix.IPPROTO_UDP, udpGRO, 1)
+	}); err != nil {
+		return false
+	}
+	return serr == nil
+}
+
+// parseGROSegmentSize parses the UDP_GRO control message.
+// It contains the size of the segments of a coalesced datagram.
+func parseGROSegmentSize(ctrlMsg unix.SocketControlMessage) (int, bool) {
+	if ctrlMsg.Header.Level != unix.IPPROTO_UDP || ctrlMsg.Header.Type != udpGRO || len(ctrlMsg.Data) < 4 {
+		return 0, false
+	}
+	return int(*(*int32)(unsafe.Pointer(&ctrlMsg.Data[0]))), true
+}
+
+// A gsoCapablePacketConn is a packet conn that allows us to send out-of-band data.
+// This is used to send the UDP_SEGMENT control message for UDP generic segmentation offload (GSO).
+type gsoCapablePacketConn interface {
+	net.PacketConn
+	SyscallConn() (syscall.RawConn, error)
+	WriteMsgUDP(b, oob []byte, addr *net.UDPAddr) (n, oobn int, err error)
+}
+
+// newBatchWriter returns a batchWriter for the packet conn.
+// If the kernel supports UDP GSO (Linux 4.18 and newer), packets are sent using a single sendmsg syscall.
+// Otherwise, packets are sent using sendmmsg.
+// The ECN codepoint is set using the IP_TOS or IPV6_TCLASS control message.
+// It returns nil if the packet conn is not a UDP conn.
+func newBatchWriter(c net.PacketConn) batchWriter {
+	conn, ok := c.(gsoCapablePacketConn)
+	if !ok {
+		return nil
+	}
+	rawConn, err := conn.SyscallConn()
+	if err != nil {
+		return nil
+	}
+	w := &linuxBatchWriter{
+		conn:    conn,
+		mmsgOut: ipv4.NewPacketConn(conn),
+	}
+	if isGSOSupported(rawConn) {
+		w.gsoEnabled = true
+	} else {
+		utils.DefaultLogger.Debugf("UDP GSO not supported. Falling back to sendmmsg.")
+	}
+	return w
+}
+
+func isGSOSupported(rawConn syscall.RawConn) bool {
+	var serr error
+	if err := rawConn.Control(func(fd uintptr) {
+		_, serr = unix.GetsockoptInt(int(fd), unix.IPPROTO_UDP, udpSegment)
+	}); err != nil {
+		return false
+	}
+	return serr == nil
+}
+
+type linuxBatchWriter struct {
+	conn gsoCapablePacketConn
+
+	mutex      sync.Mutex
+	gsoEnabled bool
+
+	mmsgOut  *ipv4.PacketConn
+	messages []ipv4.Message
+}
+
+var _ batchWriter = &linuxBatchWriter{}
+
+func (w *linuxBatchWriter) WriteBatch(b []byte, segmentSize int, addr net.Addr, ecn protocol.ECN) error {
+	udpAddr, ok := addr.(*net.UDPAddr)
+	if !ok {
+		return errors.New("not a UDP address")
+	}
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.gsoEnabled {
+		err := w.writeGSO(b, segmentSize, udpAddr, ecn)
+		if err == nil || !isGSOError(err) {
+			return err
+		}
+		// The network interface doesn't support GSO (e.g. because it doesn't support checksum offloading).
+		utils.DefaultLogger.Debugf("Sending packets using UDP GSO failed: %s. Falling back to sendmmsg.", err)
+		w.gsoEnabled = false
+	}
+	return w.writeMMsg(b, segmentSize, udpAddr, ecn)
+}
+
+func (w *linuxBatchWriter) writeGSO(b []byte, segmentSize int, addr *net.UDPAddr, ecn protocol.ECN) error {
+	oob := appendUDPSegmentSizeMsg(make([]byte, 0, unix.CmsgSpace(2)+unix.CmsgSpace(4)), uint16(segmentSize))
+	oob = appendECNMsg(oob, ecn, addr)
+	_, _, err := w.conn.WriteMsgUDP(b, oob, addr)
+	return err
+}
+
+func (w *linuxBatchWriter) writeMMsg(b []byte, segmentSize int, addr *net.UDPAddr, ecn protocol.ECN) error {
+	// All packets are sent with the same ECN codepoint, so they can share the control message.
+	oob := appendECNMsg(nil, ecn, addr)
+	w.messages = w.messages[:0]
+	for len(b) > 0 {
+		n := segmentSize
+		if n > len(b) {
+			n = len(b)
+		}
+		w.messages = append(w.messages, ipv4.Message{Buffers: [][]byte{b[:n]}, OOB: oob, Addr: addr})
+		b = b[n:]
+	}
+	// sendmmsg might not send all messages at once
+	for msgs := w.messages; len(msgs) > 0; {
+		n, err := w.mmsgOut.WriteBatch(msgs, 0)
+		if err != nil {
+			return err
+		}
+		msgs = msgs[n:]
+	}
+	return nil
+}
+
+// appendUDPSegmentSizeMsg appends the UDP_SEGMENT control message, which sets the GSO segment size.
+func appendUDPSegmentSizeMsg(b []byte, size uint16) []byte {
+	startLen := len(b)
+	const dataLen = 2 // payload is a uint16
+	b = append(b, make([]byte, unix.CmsgSpace(dataLen))...)
+	h := (*unix.Cmsghdr)(unsafe.Pointer(&b[startLen]))
+	h.Level = unix.IPPROTO_UDP
+	h.Type = udpSegment
+	h.SetLen(unix.CmsgLen(dataLen))
+
+	offset := startLen + unix.CmsgSpace(0)
+	*(*uint16)(unsafe.Pointer(&b[offset])) = size
+	return b
+}
+
+// appendECNMsg appends the control message that sets the ECN codepoint of the packet.
+// For IPv4 (and IPv4-mapped IPv6) addresses, this is the IP_TOS control message.
+// For IPv6 addresses, this is the IPV6_TCLASS control message.
+// Nothing is appended if the packet is not ECN-marked.
+func appendECNMsg(b []byte, ecn protocol.ECN, addr *net.UDPAddr) []byte {
+	if ecn == protocol.ECNNon {
+		return b
+	}
+	startLen := len(b)
+	const dataLen = 4 // payload is an int
+	b = append(b, make([]byte, unix.CmsgSpace(dataLen))...)
+	h := (*unix.Cmsghdr)(unsafe.Pointer(&b[startLen]))
+	if addr.IP.To4() != nil {
+		h.Level = unix.IPPROTO_IP
+		h.Type = unix.IP_TOS
+	} else {
+		h.Level = unix.IPPROTO_IPV6
+		h.Type = unix.IPV6_TCLASS
+	}
+	h.SetLen(unix.CmsgLen(dataLen))
+
+	offset := startLen + unix.CmsgSpace(0)
+	*(*int32)(unsafe.Pointer(&b[offset])) = int32(ecn)
+	return b
+}
+
+// isGSOError says if sending using GSO failed because the network interface doesn't support it.
+func isGSOError(err error) bool {
+	// The kernel returns EIO if the device doesn't support checksum offloading.
+	// See https://github.com/torvalds/linux/blob/master/net/ipv4/udp.c.
+	return errors.Is(err, unix.EIO)
+}
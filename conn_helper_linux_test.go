@@ -0,0 +1,196 @@
+// +build linux
+
+package quic
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"os"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/For-ACGN/quic-go/internal/protocol"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Batch Reading and Writing", func() {
+	var sendConn, rcvConn *net.UDPConn
+
+	BeforeEach(func() {
+		var err error
+		sendConn, err = net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+		Expect(err).ToNot(HaveOccurred())
+		rcvConn, err = net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(sendConn.Close()).To(Succeed())
+		Expect(rcvConn.Close()).To(Succeed())
+	})
+
+	// receive receives n packets
+	receive := func(n int) [][]byte {
+		packets := make([][]byte, 0, n)
+		Expect(rcvConn.SetReadDeadline(time.Now().Add(time.Second))).To(Succeed())
+		for i := 0; i < n; i++ {
+			b := make([]byte, 2000)
+			l, err := rcvConn.Read(b)
+			Expect(err).ToNot(HaveOccurred())
+			packets = append(packets, b[:l])
+		}
+		return packets
+	}
+
+	getBatch := func() []byte {
+		b := make([]byte, 0, 3*1000+500)
+		b = append(b, bytes.Repeat([]byte{'a'}, 1000)...)
+		b = append(b, bytes.Repeat([]byte{'b'}, 1000)...)
+		b = append(b, bytes.Repeat([]byte{'c'}, 1000)...)
+		return append(b, bytes.Repeat([]byte{'d'}, 500)...)
+	}
+
+	checkBatch := func(packets [][]byte) {
+		Expect(packets).To(HaveLen(4))
+		Expect(packets[0]).To(Equal(bytes.Repeat([]byte{'a'}, 1000)))
+		Expect(packets[1]).To(Equal(bytes.Repeat([]byte{'b'}, 1000)))
+		Expect(packets[2]).To(Equal(bytes.Repeat([]byte{'c'}, 1000)))
+		Expect(packets[3]).To(Equal(bytes.Repeat([]byte{'d'}, 500)))
+	}
+
+	It("doesn't create a batch writer for packet conns that aren't UDP conns", func() {
+		Expect(newBatchWriter(NewMockPacketConn(mockCtrl))).To(BeNil())
+	})
+
+	It("sends packets using GSO, if supported", func() {
+		w := newBatchWriter(sendConn)
+		Expect(w).ToNot(BeNil())
+		rawConn, err := sendConn.SyscallConn()
+		Expect(err).ToNot(HaveOccurred())
+		if !isGSOSupported(rawConn) {
+			Skip("GSO not supported by the kernel")
+		}
+		Expect(w.(*linuxBatchWriter).gsoEnabled).To(BeTrue())
+		Expect(w.WriteBatch(getBatch(), 1000, rcvConn.LocalAddr(), protocol.ECNNon)).To(Succeed())
+		checkBatch(receive(4))
+	})
+
+	It("sends packets using sendmmsg", func() {
+		w := newBatchWriter(sendConn)
+		Expect(w).ToNot(BeNil())
+		w.(*linuxBatchWriter).gsoEnabled = false
+		Expect(w.WriteBatch(getBatch(), 1000, rcvConn.LocalAddr(), protocol.ECNNon)).To(Succeed())
+		checkBatch(receive(4))
+	})
+
+	It("refuses to send to non-UDP addresses", func() {
+		w := newBatchWriter(sendConn)
+		Expect(w.WriteBatch(getBatch(), 1000, &net.TCPAddr{}, protocol.ECNNon)).To(MatchError("not a UDP address"))
+	})
+
+	It("appends the UDP_SEGMENT control message", func() {
+		oob := appendUDPSegmentSizeMsg([]byte("foobar"), 1337)
+		Expect(oob[:6]).To(Equal([]byte("foobar")))
+		msgs, err := unix.ParseSocketControlMessage(oob[6:])
+		Expect(err).ToNot(HaveOccurred())
+		Expect(msgs).To(HaveLen(1))
+		Expect(msgs[0].Header.Level).To(BeEquivalentTo(unix.IPPROTO_UDP))
+		Expect(msgs[0].Header.Type).To(BeEquivalentTo(udpSegment))
+		Expect(msgs[0].Data[:2]).To(Equal(appendUDPSegmentSizeMsg(nil, 1337)[unix.CmsgSpace(0) : unix.CmsgSpace(0)+2]))
+	})
+
+	It("sends packets with ECN marks", func() {
+		conn, err := newConn(rcvConn, protocol.MaxReceivePacketSize)
+		Expect(err).ToNot(HaveOccurred())
+		w := newBatchWriter(sendConn)
+		for _, gso := range []bool{true, false} {
+			w.(*linuxBatchWriter).gsoEnabled = gso
+			rawConn, err := sendConn.SyscallConn()
+			Expect(err).ToNot(HaveOccurred())
+			if gso && !isGSOSupported(rawConn) {
+				continue
+			}
+			Expect(w.WriteBatch(getBatch(), 1000, rcvConn.LocalAddr(), protocol.ECT0)).To(Succeed())
+			packets := make([][]byte, 0, 4)
+			for i := 0; i < 4; i++ {
+				p, err := conn.ReadPacket()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(p.ecn).To(Equal(protocol.ECT0))
+				packets = append(packets, p.data)
+			}
+			checkBatch(packets)
+		}
+	})
+
+	It("appends the IP_TOS control message for IPv4 addresses", func() {
+		Expect(appendECNMsg([]byte("foobar"), protocol.ECNNon, &net.UDPAddr{IP: net.IPv4(1, 2, 3, 4)})).To(Equal([]byte("foobar")))
+		oob := appendECNMsg([]byte("foobar"), protocol.ECT0, &net.UDPAddr{IP: net.IPv4(1, 2, 3, 4)})
+		Expect(oob[:6]).To(Equal([]byte("foobar")))
+		msgs, err := unix.ParseSocketControlMessage(oob[6:])
+		Expect(err).ToNot(HaveOccurred())
+		Expect(msgs).To(HaveLen(1))
+		Expect(msgs[0].Header.Level).To(BeEquivalentTo(unix.IPPROTO_IP))
+		Expect(msgs[0].Header.Type).To(BeEquivalentTo(unix.IP_TOS))
+		Expect(*(*int32)(unsafe.Pointer(&msgs[0].Data[0]))).To(BeEquivalentTo(protocol.ECT0))
+	})
+
+	It("appends the IPV6_TCLASS control message for IPv6 addresses", func() {
+		oob := appendECNMsg(nil, protocol.ECT0, &net.UDPAddr{IP: net.ParseIP("2001:db8::1")})
+		msgs, err := unix.ParseSocketControlMessage(oob)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(msgs).To(HaveLen(1))
+		Expect(msgs[0].Header.Level).To(BeEquivalentTo(unix.IPPROTO_IPV6))
+		Expect(msgs[0].Header.Type).To(BeEquivalentTo(unix.IPV6_TCLASS))
+		Expect(*(*int32)(unsafe.Pointer(&msgs[0].Data[0]))).To(BeEquivalentTo(protocol.ECT0))
+	})
+
+	It("detects GSO errors", func() {
+		err := &net.OpError{Op: "write", Err: os.NewSyscallError("sendmsg", unix.EIO)}
+		Expect(isGSOError(err)).To(BeTrue())
+		Expect(isGSOError(errors.New("test error"))).To(BeFalse())
+	})
+
+	It("reads packets coalesced by GRO", func() {
+		rawConn, err := sendConn.SyscallConn()
+		Expect(err).ToNot(HaveOccurred())
+		if !isGSOSupported(rawConn) {
+			Skip("GSO not supported by the kernel")
+		}
+		conn, err := newConn(rcvConn, protocol.MaxReceivePacketSize)
+		Expect(err).ToNot(HaveOccurred())
+		if !conn.groEnabled {
+			Skip("GRO not supported by the kernel")
+		}
+		Expect(newBatchWriter(sendConn).WriteBatch(getBatch(), 1000, rcvConn.LocalAddr(), protocol.ECNNon)).To(Succeed())
+		packets := make([][]byte, 0, 4)
+		for i := 0; i < 4; i++ {
+			p, err := conn.ReadPacket()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(p.remoteAddr).To(Equal(sendConn.LocalAddr()))
+			Expect(p.buffer.Cap()).To(Equal(protocol.MaxReceivePacketSize))
+			packets = append(packets, p.data)
+		}
+		checkBatch(packets)
+	})
+
+	It("parses the UDP_GRO control message", func() {
+		data := make([]byte, 4)
+		*(*int32)(unsafe.Pointer(&data[0])) = 1337
+		size, ok := parseGROSegmentSize(unix.SocketControlMessage{
+			Header: unix.Cmsghdr{Level: unix.IPPROTO_UDP, Type: udpGRO},
+			Data:   data,
+		})
+		Expect(ok).To(BeTrue())
+		Expect(size).To(Equal(1337))
+		_, ok = parseGROSegmentSize(unix.SocketControlMessage{
+			Header: unix.Cmsghdr{Level: unix.IPPROTO_IP, Type: unix.IP_TOS},
+			Data:   []byte{2},
+		})
+		Expect(ok).To(BeFalse())
+	})
+})
@@ -2,6 +2,7 @@ package quic
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/For-ACGN/quic-go/internal/protocol"
 	"github.com/For-ACGN/quic-go/internal/qerr"
@@ -9,12 +10,22 @@ import (
 	"github.com/For-ACGN/quic-go/internal/wire"
 )
 
+type retiredConnID struct {
+	connID    protocol.ConnectionID
+	retiredAt time.Time
+}
+
 type connIDGenerator struct {
 	connIDLen  int
 	highestSeq uint64
 
 	activeSrcConnIDs        map[uint64]protocol.ConnectionID
 	initialClientDestConnID protocol.ConnectionID
+	// The connection ID sent in the preferred_address transport parameter, until it is added.
+	preferredAddressConnID protocol.ConnectionID
+	// Connection IDs retired within the last protocol.RetiredConnectionIDDeleteTimeout.
+	// The session runner keeps them around until then.
+	recentlyRetired []retiredConnID
 
 	addConnectionID        func(protocol.ConnectionID)
 	getStatelessResetToken func(protocol.ConnectionID) protocol.StatelessResetToken
@@ -57,13 +68,15 @@ func (m *connIDGenerator) SetMaxActiveConnIDs(limit uint64) error {
 	if m.connIDLen == 0 {
 		return nil
 	}
+	if m.preferredAddressConnID != nil {
+		m.addConnectionID(m.preferredAddressConnID)
+		m.preferredAddressConnID = nil
+	}
 	// The active_connection_id_limit transport parameter is the number of
 	// connection IDs the peer will store. This limit includes the connection ID
 	// used during the handshake, and the one sent in the preferred_address
 	// transport parameter.
-	// We currently don't send the preferred_address transport parameter,
-	// so we can issue (limit - 1) connection IDs.
-	for i := uint64(1); i < utils.MinUint64(limit, protocol.MaxIssuedConnectionIDs); i++ {
+	for i := m.highestSeq + 1; i < utils.MinUint64(limit, protocol.MaxIssuedConnectionIDs); i++ {
 		if err := m.issueNewConnID(); err != nil {
 			return err
 		}
@@ -71,6 +84,21 @@ func (m *connIDGenerator) SetMaxActiveConnIDs(limit uint64) error {
 	return nil
 }
 
+// IssuePreferredAddressConnID issues the connection ID sent in the preferred_address transport parameter.
+// This connection ID has the sequence number 1, so it must be called before SetMaxActiveConnIDs.
+// The server creates the session while holding the lock of the session runner,
+// so the connection ID is only added to the runner when SetMaxActiveConnIDs is called.
+func (m *connIDGenerator) IssuePreferredAddressConnID() (protocol.ConnectionID, protocol.StatelessResetToken, error) {
+	connID, err := protocol.GenerateConnectionID(m.connIDLen)
+	if err != nil {
+		return nil, protocol.StatelessResetToken{}, err
+	}
+	m.highestSeq++
+	m.activeSrcConnIDs[m.highestSeq] = connID
+	m.preferredAddressConnID = connID
+	return connID, m.getStatelessResetToken(connID), nil
+}
+
 func (m *connIDGenerator) Retire(seq uint64, sentWithDestConnID protocol.ConnectionID) error {
 	if seq > m.highestSeq {
 		return qerr.NewError(qerr.ProtocolViolation, fmt.Sprintf("tried to retire connection ID %d. Highest issued: %d", seq, m.highestSeq))
@@ -85,6 +113,9 @@ func (m *connIDGenerator) Retire(seq uint64, sentWithDestConnID protocol.Connect
 	}
 	m.retireConnectionID(connID)
 	delete(m.activeSrcConnIDs, seq)
+	now := time.Now()
+	m.pruneRecentlyRetired(now)
+	m.recentlyRetired = append(m.recentlyRetired, retiredConnID{connID: connID, retiredAt: now})
 	// Don't issue a replacement for the initial connection ID.
 	if seq == 0 {
 		return nil
@@ -92,6 +123,14 @@ func (m *connIDGenerator) Retire(seq uint64, sentWithDestConnID protocol.Connect
 	return m.issueNewConnID()
 }
 
+func (m *connIDGenerator) pruneRecentlyRetired(now time.Time) {
+	var i int
+	for i < len(m.recentlyRetired) && now.Sub(m.recentlyRetired[i].retiredAt) >= protocol.RetiredConnectionIDDeleteTimeout {
+		i++
+	}
+	m.recentlyRetired = m.recentlyRetired[i:]
+}
+
 func (m *connIDGenerator) issueNewConnID() error {
 	if protocol.UseRetireBugBackwardsCompatibilityMode(RetireBugBackwardsCompatibilityMode, m.version) {
 		return nil
@@ -135,3 +174,32 @@ func (m *connIDGenerator) ReplaceWithClosed(handler packetHandler) {
 		m.replaceWithClosed(connID, handler)
 	}
 }
+
+// ActiveConnIDs returns all connection IDs that are currently active.
+func (m *connIDGenerator) ActiveConnIDs() []protocol.ConnectionID {
+	connIDs := make([]protocol.ConnectionID, 0, len(m.activeSrcConnIDs))
+	for _, connID := range m.activeSrcConnIDs {
+		connIDs = append(connIDs, connID)
+	}
+	return connIDs
+}
+
+// ReplaceRunner moves all active connection IDs from the current session runner to a new session runner.
+// Recently retired connection IDs are removed from the current session runner right away.
+// It is used when the connection is migrated to a new packet conn.
+func (m *connIDGenerator) ReplaceRunner(runner sessionRunner, handler packetHandler) {
+	for _, connID := range m.activeSrcConnIDs {
+		m.removeConnectionID(connID)
+		runner.Add(connID, handler)
+	}
+	m.pruneRecentlyRetired(time.Now())
+	for _, r := range m.recentlyRetired {
+		m.removeConnectionID(r.connID)
+	}
+	m.recentlyRetired = nil
+	m.addConnectionID = func(connID protocol.ConnectionID) { runner.Add(connID, handler) }
+	m.getStatelessResetToken = runner.GetStatelessResetToken
+	m.removeConnectionID = runner.Remove
+	m.retireConnectionID = runner.Retire
+	m.replaceWithClosed = runner.ReplaceWithClosed
+}
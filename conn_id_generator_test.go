@@ -6,6 +6,8 @@ import (
 	"github.com/For-ACGN/quic-go/internal/protocol"
 	"github.com/For-ACGN/quic-go/internal/wire"
 
+	"github.com/golang/mock/gomock"
+
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
@@ -79,6 +81,25 @@ var _ = Describe("Connection ID Generator", func() {
 		Expect(queuedFrames).To(HaveLen(protocol.MaxIssuedConnectionIDs - 1))
 	})
 
+	It("issues the connection ID for the preferred_address", func() {
+		connID, token, err := g.IssuePreferredAddressConnID()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(connID.Len()).To(Equal(7))
+		Expect(token).To(Equal(connIDToToken(connID)))
+		Expect(addedConnIDs).To(BeEmpty())
+		Expect(g.SetMaxActiveConnIDs(4)).To(Succeed())
+		Expect(addedConnIDs).To(HaveLen(3))
+		Expect(addedConnIDs[0]).To(Equal(connID))
+		// the connection ID is sent in the transport parameters, not in a NEW_CONNECTION_ID frame
+		Expect(queuedFrames).To(HaveLen(2))
+		for i, f := range queuedFrames {
+			Expect(f.(*wire.NewConnectionIDFrame).SequenceNumber).To(BeEquivalentTo(i + 2))
+		}
+		// the connection ID can be retired like any other connection ID
+		Expect(g.Retire(1, protocol.ConnectionID{})).To(Succeed())
+		Expect(retiredConnIDs).To(Equal([]protocol.ConnectionID{connID}))
+	})
+
 	It("errors if the peers tries to retire a connection ID that wasn't yet issued", func() {
 		Expect(g.Retire(1, protocol.ConnectionID{})).To(MatchError("PROTOCOL_VIOLATION: tried to retire connection ID 1. Highest issued: 0"))
 	})
@@ -167,4 +188,51 @@ var _ = Describe("Connection ID Generator", func() {
 			Expect(replacedWithClosed).To(HaveKeyWithValue(string(nf.ConnectionID), sess))
 		}
 	})
+
+	It("removes recently retired connection IDs from the old runner", func() {
+		Expect(g.SetMaxActiveConnIDs(2)).To(Succeed())
+		Expect(queuedFrames).To(HaveLen(1))
+		issuedConnID := queuedFrames[0].(*wire.NewConnectionIDFrame).ConnectionID
+		Expect(g.Retire(1, protocol.ConnectionID{})).To(Succeed())
+		Expect(retiredConnIDs).To(Equal([]protocol.ConnectionID{issuedConnID}))
+		runner := NewMockSessionRunner(mockCtrl)
+		handler := NewMockPacketHandler(mockCtrl)
+		runner.EXPECT().Add(gomock.Any(), handler).Times(2)
+		g.ReplaceRunner(runner, handler)
+		Expect(removedConnIDs).To(HaveLen(3))
+		Expect(removedConnIDs).To(ContainElement(issuedConnID))
+	})
+
+	It("returns the active connection IDs", func() {
+		Expect(g.SetMaxActiveConnIDs(2)).To(Succeed())
+		Expect(queuedFrames).To(HaveLen(1))
+		issuedConnID := queuedFrames[0].(*wire.NewConnectionIDFrame).ConnectionID
+		Expect(g.ActiveConnIDs()).To(ConsistOf(initialConnID, issuedConnID))
+	})
+
+	It("moves connection IDs to a new runner", func() {
+		Expect(g.SetMaxActiveConnIDs(2)).To(Succeed())
+		Expect(queuedFrames).To(HaveLen(1))
+		issuedConnID := queuedFrames[0].(*wire.NewConnectionIDFrame).ConnectionID
+		addedConnIDs = nil
+		runner := NewMockSessionRunner(mockCtrl)
+		handler := NewMockPacketHandler(mockCtrl)
+		runner.EXPECT().Add(initialConnID, handler)
+		runner.EXPECT().Add(issuedConnID, handler)
+		g.ReplaceRunner(runner, handler)
+		Expect(removedConnIDs).To(ConsistOf(initialConnID, issuedConnID))
+		removedConnIDs = nil
+		// retiring a connection ID issues a new one
+		runner.EXPECT().GetStatelessResetToken(gomock.Any())
+		runner.EXPECT().Retire(issuedConnID)
+		runner.EXPECT().Add(gomock.Any(), handler)
+		Expect(g.Retire(1, protocol.ConnectionID{})).To(Succeed())
+		Expect(removedConnIDs).To(BeEmpty())
+		runner.EXPECT().ReplaceWithClosed(gomock.Any(), handler).Times(3)
+		g.ReplaceWithClosed(handler)
+		// the old runner isn't used any more
+		Expect(retiredConnIDs).To(BeEmpty())
+		Expect(addedConnIDs).To(BeEmpty())
+		Expect(replacedWithClosed).To(BeEmpty())
+	})
 })
@@ -20,6 +20,9 @@ type connIDManager struct {
 	highestRetired            uint64
 	activeConnectionID        protocol.ConnectionID
 	activeStatelessResetToken *protocol.StatelessResetToken
+	// The connection ID used for probing a new path.
+	// It is taken from the queue, such that it isn't used on the current path.
+	pathProbeConnID *utils.NewConnectionID
 
 	// We change the connection ID after sending on average
 	// protocol.PacketsPerConnectionID packets. The actual value is randomized
@@ -78,6 +81,9 @@ func (h *connIDManager) add(f *wire.NewConnectionIDFrame) error {
 	// Retire elements in the queue.
 	// Doesn't retire the active connection ID.
 	if f.RetirePriorTo > h.highestRetired {
+		if h.pathProbeConnID != nil && h.pathProbeConnID.SequenceNumber < f.RetirePriorTo {
+			h.RetirePathProbeConnID()
+		}
 		var next *utils.NewConnectionIDElement
 		for el := h.queue.Front(); el != nil; el = next {
 			if el.Value.SequenceNumber >= f.RetirePriorTo {
@@ -142,6 +148,10 @@ func (h *connIDManager) addConnectionID(seq uint64, connID protocol.ConnectionID
 }
 
 func (h *connIDManager) updateConnectionID() {
+	h.switchToConnectionID(h.queue.Remove(h.queue.Front()))
+}
+
+func (h *connIDManager) switchToConnectionID(next utils.NewConnectionID) {
 	h.queueControlFrame(&wire.RetireConnectionIDFrame{
 		SequenceNumber: h.activeSequenceNumber,
 	})
@@ -150,10 +160,9 @@ func (h *connIDManager) updateConnectionID() {
 		h.removeStatelessResetToken(*h.activeStatelessResetToken)
 	}
 
-	front := h.queue.Remove(h.queue.Front())
-	h.activeSequenceNumber = front.SequenceNumber
-	h.activeConnectionID = front.ConnectionID
-	h.activeStatelessResetToken = &front.StatelessResetToken
+	h.activeSequenceNumber = next.SequenceNumber
+	h.activeConnectionID = next.ConnectionID
+	h.activeStatelessResetToken = &next.StatelessResetToken
 	h.packetsSinceLastChange = 0
 	h.packetsPerConnectionID = protocol.PacketsPerConnectionID/2 + uint64(h.rand.Int63n(protocol.PacketsPerConnectionID))
 	h.addStatelessResetToken(*h.activeStatelessResetToken)
@@ -212,3 +221,49 @@ func (h *connIDManager) Get() protocol.ConnectionID {
 func (h *connIDManager) SetHandshakeComplete() {
 	h.handshakeComplete = true
 }
+
+// GetForPathProbe returns an unused connection ID for probing a new path.
+// It returns false if the peer didn't provide us with an unused connection ID.
+func (h *connIDManager) GetForPathProbe() (protocol.ConnectionID, bool) {
+	if h.pathProbeConnID == nil {
+		if h.queue.Len() == 0 {
+			return nil, false
+		}
+		front := h.queue.Remove(h.queue.Front())
+		h.pathProbeConnID = &front
+	}
+	return h.pathProbeConnID.ConnectionID, true
+}
+
+// SwitchToPathProbeConnID is called when the probed path was validated.
+// It retires the active connection ID and switches to the connection ID used for probing the path.
+func (h *connIDManager) SwitchToPathProbeConnID() {
+	if h.pathProbeConnID == nil {
+		return
+	}
+	h.switchToConnectionID(*h.pathProbeConnID)
+	h.pathProbeConnID = nil
+}
+
+// RetirePathProbeConnID is called when path validation failed.
+func (h *connIDManager) RetirePathProbeConnID() {
+	if h.pathProbeConnID == nil {
+		return
+	}
+	h.queueControlFrame(&wire.RetireConnectionIDFrame{
+		SequenceNumber: h.pathProbeConnID.SequenceNumber,
+	})
+	h.highestRetired = utils.MaxUint64(h.highestRetired, h.pathProbeConnID.SequenceNumber)
+	h.pathProbeConnID = nil
+}
+
+// ReplaceRunner moves the stateless reset token from the current session runner to a new session runner.
+// It is used when the connection is migrated to a new packet conn.
+func (h *connIDManager) ReplaceRunner(runner sessionRunner, handler packetHandler) {
+	if h.activeStatelessResetToken != nil {
+		h.removeStatelessResetToken(*h.activeStatelessResetToken)
+		runner.AddResetToken(*h.activeStatelessResetToken, handler)
+	}
+	h.addStatelessResetToken = func(token protocol.StatelessResetToken) { runner.AddResetToken(token, handler) }
+	h.removeStatelessResetToken = runner.RemoveResetToken
+}
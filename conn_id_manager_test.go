@@ -345,6 +345,94 @@ var _ = Describe("Connection ID Manager", func() {
 		Expect(removedTokens[0]).To(Equal(protocol.StatelessResetToken{1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1}))
 	})
 
+	It("reserves a connection ID for probing a new path", func() {
+		_, ok := m.GetForPathProbe()
+		Expect(ok).To(BeFalse())
+		Expect(m.Add(&wire.NewConnectionIDFrame{
+			SequenceNumber: 1,
+			ConnectionID:   protocol.ConnectionID{1, 2, 3, 4},
+		})).To(Succeed())
+		connID, ok := m.GetForPathProbe()
+		Expect(ok).To(BeTrue())
+		Expect(connID).To(Equal(protocol.ConnectionID{1, 2, 3, 4}))
+		Expect(m.queue.Len()).To(BeZero())
+		// the reservation is kept until the path is validated or abandoned
+		connID, ok = m.GetForPathProbe()
+		Expect(ok).To(BeTrue())
+		Expect(connID).To(Equal(protocol.ConnectionID{1, 2, 3, 4}))
+		Expect(m.Get()).To(Equal(initialConnID))
+	})
+
+	It("switches to the connection ID used for probing a new path", func() {
+		Expect(m.Add(&wire.NewConnectionIDFrame{
+			SequenceNumber:      1,
+			ConnectionID:        protocol.ConnectionID{1, 2, 3, 4},
+			StatelessResetToken: protocol.StatelessResetToken{16, 15, 14, 13, 12, 11, 10, 9, 8, 7, 6, 5, 4, 3, 2, 1},
+		})).To(Succeed())
+		_, ok := m.GetForPathProbe()
+		Expect(ok).To(BeTrue())
+		m.SwitchToPathProbeConnID()
+		Expect(m.Get()).To(Equal(protocol.ConnectionID{1, 2, 3, 4}))
+		Expect(*tokenAdded).To(Equal(protocol.StatelessResetToken{16, 15, 14, 13, 12, 11, 10, 9, 8, 7, 6, 5, 4, 3, 2, 1}))
+		Expect(frameQueue).To(HaveLen(1))
+		Expect(frameQueue[0].(*wire.RetireConnectionIDFrame).SequenceNumber).To(BeZero())
+		_, ok = m.GetForPathProbe()
+		Expect(ok).To(BeFalse())
+	})
+
+	It("retires the connection ID used for probing a new path", func() {
+		Expect(m.Add(&wire.NewConnectionIDFrame{
+			SequenceNumber: 1,
+			ConnectionID:   protocol.ConnectionID{1, 2, 3, 4},
+		})).To(Succeed())
+		_, ok := m.GetForPathProbe()
+		Expect(ok).To(BeTrue())
+		m.RetirePathProbeConnID()
+		Expect(frameQueue).To(HaveLen(1))
+		Expect(frameQueue[0].(*wire.RetireConnectionIDFrame).SequenceNumber).To(BeEquivalentTo(1))
+		Expect(m.Get()).To(Equal(initialConnID))
+		_, ok = m.GetForPathProbe()
+		Expect(ok).To(BeFalse())
+	})
+
+	It("retires the connection ID used for probing a new path when the peer asks us to", func() {
+		Expect(m.Add(&wire.NewConnectionIDFrame{
+			SequenceNumber: 1,
+			ConnectionID:   protocol.ConnectionID{1, 2, 3, 4},
+		})).To(Succeed())
+		_, ok := m.GetForPathProbe()
+		Expect(ok).To(BeTrue())
+		Expect(m.Add(&wire.NewConnectionIDFrame{
+			SequenceNumber: 2,
+			ConnectionID:   protocol.ConnectionID{2, 3, 4, 5},
+			RetirePriorTo:  2,
+		})).To(Succeed())
+		Expect(frameQueue).To(ContainElement(&wire.RetireConnectionIDFrame{SequenceNumber: 1}))
+		Expect(m.pathProbeConnID).To(BeNil())
+	})
+
+	It("moves the stateless reset tokens to a new runner", func() {
+		m.SetStatelessResetToken(protocol.StatelessResetToken{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16})
+		runner := NewMockSessionRunner(mockCtrl)
+		handler := NewMockPacketHandler(mockCtrl)
+		runner.EXPECT().AddResetToken(protocol.StatelessResetToken{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}, handler)
+		m.ReplaceRunner(runner, handler)
+		Expect(removedTokens).To(Equal([]protocol.StatelessResetToken{{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}}))
+		removedTokens = nil
+		Expect(m.Add(&wire.NewConnectionIDFrame{
+			SequenceNumber:      1,
+			ConnectionID:        protocol.ConnectionID{1, 2, 3, 4},
+			StatelessResetToken: protocol.StatelessResetToken{16, 15, 14, 13, 12, 11, 10, 9, 8, 7, 6, 5, 4, 3, 2, 1},
+		})).To(Succeed())
+		_, ok := m.GetForPathProbe()
+		Expect(ok).To(BeTrue())
+		runner.EXPECT().RemoveResetToken(protocol.StatelessResetToken{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16})
+		runner.EXPECT().AddResetToken(protocol.StatelessResetToken{16, 15, 14, 13, 12, 11, 10, 9, 8, 7, 6, 5, 4, 3, 2, 1}, handler)
+		m.SwitchToPathProbeConnID()
+		// the old runner isn't used any more
+		Expect(removedTokens).To(BeEmpty())
+	})
+
 	It("removes the currently active stateless reset token when it is closed", func() {
 		m.Close()
 		Expect(removedTokens).To(BeEmpty())
@@ -22,7 +22,7 @@ var _ = Describe("Basic Conn Test", func() {
 			return copy(b, data), addr, nil
 		})
 
-		conn, err := wrapConn(c)
+		conn, err := wrapConn(c, protocol.MaxReceivePacketSize)
 		Expect(err).ToNot(HaveOccurred())
 		p, err := conn.ReadPacket()
 		Expect(err).ToNot(HaveOccurred())
@@ -30,4 +30,19 @@ var _ = Describe("Basic Conn Test", func() {
 		Expect(p.rcvTime).To(BeTemporally("~", time.Now(), scaleDuration(100*time.Millisecond)))
 		Expect(p.remoteAddr).To(Equal(addr))
 	})
+
+	It("reads jumbo packets", func() {
+		c := NewMockPacketConn(mockCtrl)
+		c.EXPECT().ReadFrom(gomock.Any()).DoAndReturn(func(b []byte) (int, net.Addr, error) {
+			Expect(b).To(HaveLen(int(protocol.MaxJumboPacketSize)))
+			return len(b), &net.UDPAddr{IP: net.IPv4(1, 2, 3, 4), Port: 1234}, nil
+		})
+
+		conn, err := wrapConn(c, protocol.MaxJumboPacketSize)
+		Expect(err).ToNot(HaveOccurred())
+		p, err := conn.ReadPacket()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(p.data).To(HaveLen(int(protocol.MaxJumboPacketSize)))
+		p.buffer.Release()
+	})
 })
@@ -9,10 +9,16 @@ import (
 	"syscall"
 
 	"golang.org/x/sys/windows"
+
+	"github.com/For-ACGN/quic-go/internal/protocol"
 )
 
-func newConn(c net.PacketConn) (connection, error) {
-	return &basicConn{PacketConn: c}, nil
+func newConn(c net.PacketConn, maxPacketSize protocol.ByteCount) (connection, error) {
+	return &basicConn{PacketConn: c, maxPacketSize: maxPacketSize}, nil
+}
+
+func newBatchWriter(net.PacketConn) batchWriter {
+	return nil
 }
 
 func inspectReadBuffer(c net.PacketConn) (int, error) {
@@ -23,7 +23,7 @@ var _ = Describe("Datagram Queue", func() {
 
 	Context("sending", func() {
 		It("returns nil when there's no datagram to send", func() {
-			Expect(queue.Get()).To(BeNil())
+			Expect(queue.Peek()).To(BeNil())
 		})
 
 		It("queues a datagram", func() {
@@ -36,11 +36,27 @@ var _ = Describe("Datagram Queue", func() {
 
 			Eventually(queued).Should(HaveLen(1))
 			Consistently(done).ShouldNot(BeClosed())
-			f := queue.Get()
+			f := queue.Peek()
 			Expect(f).ToNot(BeNil())
 			Expect(f.Data).To(Equal([]byte("foobar")))
 			Eventually(done).Should(BeClosed())
-			Expect(queue.Get()).To(BeNil())
+			queue.Pop()
+			Expect(queue.Peek()).To(BeNil())
+		})
+
+		It("returns the same datagram until it is popped", func() {
+			go func() {
+				defer GinkgoRecover()
+				Expect(queue.AddAndWait(&wire.DatagramFrame{Data: []byte("foo")})).To(Succeed())
+				Expect(queue.AddAndWait(&wire.DatagramFrame{Data: []byte("bar")})).To(Succeed())
+			}()
+
+			Eventually(queue.Peek).ShouldNot(BeNil())
+			Expect(queue.Peek().Data).To(Equal([]byte("foo")))
+			Expect(queue.Peek().Data).To(Equal([]byte("foo")))
+			queue.Pop()
+			Eventually(queue.Peek).ShouldNot(BeNil())
+			Expect(queue.Peek().Data).To(Equal([]byte("bar")))
 		})
 
 		It("closes", func() {
@@ -16,6 +16,8 @@ type framer interface {
 	AppendControlFrames([]ackhandler.Frame, protocol.ByteCount) ([]ackhandler.Frame, protocol.ByteCount)
 
 	AddActiveStream(protocol.StreamID)
+	SetStreamPriority(protocol.StreamID, protocol.StreamPriority)
+	RemoveStream(protocol.StreamID)
 	AppendStreamFrames([]ackhandler.Frame, protocol.ByteCount) ([]ackhandler.Frame, protocol.ByteCount)
 }
 
@@ -26,7 +28,9 @@ type framerI struct {
 	version      protocol.VersionNumber
 
 	activeStreams map[protocol.StreamID]struct{}
-	streamQueue   []protocol.StreamID
+	scheduler     streamScheduler
+	// only contains streams that don't use the default priority
+	priorities map[protocol.StreamID]protocol.StreamPriority
 
 	controlFrameMutex sync.Mutex
 	controlFrames     []wire.Frame
@@ -34,20 +38,28 @@ type framerI struct {
 
 var _ framer = &framerI{}
 
+var defaultStreamPriority = protocol.StreamPriority{
+	Urgency:     protocol.DefaultStreamUrgency,
+	Incremental: true,
+}
+
 func newFramer(
 	streamGetter streamGetter,
+	schedulingMode protocol.StreamSchedulingMode,
 	v protocol.VersionNumber,
 ) framer {
 	return &framerI{
 		streamGetter:  streamGetter,
 		activeStreams: make(map[protocol.StreamID]struct{}),
+		scheduler:     newStreamScheduler(schedulingMode),
+		priorities:    make(map[protocol.StreamID]protocol.StreamPriority),
 		version:       v,
 	}
 }
 
 func (f *framerI) HasData() bool {
 	f.mutex.Lock()
-	hasData := len(f.streamQueue) > 0
+	hasData := f.scheduler.Len() > 0
 	f.mutex.Unlock()
 	if hasData {
 		return true
@@ -84,29 +96,63 @@ func (f *framerI) AppendControlFrames(frames []ackhandler.Frame, maxLen protocol
 func (f *framerI) AddActiveStream(id protocol.StreamID) {
 	f.mutex.Lock()
 	if _, ok := f.activeStreams[id]; !ok {
-		f.streamQueue = append(f.streamQueue, id)
+		f.scheduler.Add(id, f.priority(id))
 		f.activeStreams[id] = struct{}{}
 	}
 	f.mutex.Unlock()
 }
 
+// SetStreamPriority sets the priority of a stream.
+// If the stream is currently active, the new priority takes effect after it sent its next STREAM frame.
+// It is a no-op if the stream was already completed.
+func (f *framerI) SetStreamPriority(id protocol.StreamID, prio protocol.StreamPriority) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if prio == defaultStreamPriority {
+		delete(f.priorities, id)
+		return
+	}
+	// The stream might have completed after the priority was changed.
+	// Completed streams are deleted from the streams map before RemoveStream is called,
+	// so checking for the stream while holding the mutex makes sure that the priority is not kept forever.
+	if str, err := f.streamGetter.GetOrOpenSendStream(id); str == nil || err != nil {
+		return
+	}
+	f.priorities[id] = prio
+}
+
+// RemoveStream is called when a stream is completed, after it was deleted from the streams map.
+func (f *framerI) RemoveStream(id protocol.StreamID) {
+	f.mutex.Lock()
+	delete(f.priorities, id)
+	f.mutex.Unlock()
+}
+
+func (f *framerI) priority(id protocol.StreamID) protocol.StreamPriority {
+	if prio, ok := f.priorities[id]; ok {
+		return prio
+	}
+	return defaultStreamPriority
+}
+
 func (f *framerI) AppendStreamFrames(frames []ackhandler.Frame, maxLen protocol.ByteCount) ([]ackhandler.Frame, protocol.ByteCount) {
 	var length protocol.ByteCount
 	var lastFrame *ackhandler.Frame
 	f.mutex.Lock()
 	// pop STREAM frames, until less than MinStreamFrameSize bytes are left in the packet
-	numActiveStreams := len(f.streamQueue)
+	numActiveStreams := f.scheduler.Len()
 	for i := 0; i < numActiveStreams; i++ {
 		if protocol.MinStreamFrameSize+length > maxLen {
 			break
 		}
-		id := f.streamQueue[0]
-		f.streamQueue = f.streamQueue[1:]
+		id := f.scheduler.Pop()
+		prio := f.priority(id)
 		// This should never return an error. Better check it anyway.
-		// The stream will only be in the streamQueue, if it enqueued itself there.
+		// The stream will only be in the scheduler, if it enqueued itself there.
 		str, err := f.streamGetter.GetOrOpenSendStream(id)
 		// The stream can be nil if it completed after it said it had data.
 		if str == nil || err != nil {
+			f.scheduler.Sent(id, prio, 0, false)
 			delete(f.activeStreams, id)
 			continue
 		}
@@ -116,19 +162,21 @@ func (f *framerI) AppendStreamFrames(frames []ackhandler.Frame, maxLen protocol.
 		// the STREAM frame (which will always have the DataLen set).
 		remainingLen += quicvarint.Len(uint64(remainingLen))
 		frame, hasMoreData := str.popStreamFrame(remainingLen)
-		if hasMoreData { // put the stream back in the queue (at the end)
-			f.streamQueue = append(f.streamQueue, id)
-		} else { // no more data to send. Stream is not active any more
+		if !hasMoreData { // no more data to send. Stream is not active any more
 			delete(f.activeStreams, id)
 		}
 		// The frame can be nil
 		// * if the receiveStream was canceled after it said it had data
 		// * the remaining size doesn't allow us to add another STREAM frame
 		if frame == nil {
+			f.scheduler.Sent(id, prio, 0, hasMoreData)
 			continue
 		}
+		frameLen := frame.Length(f.version)
+		// put the stream back into the scheduler, if it has more data
+		f.scheduler.Sent(id, prio, frameLen, hasMoreData)
 		frames = append(frames, *frame)
-		length += frame.Length(f.version)
+		length += frameLen
 		lastFrame = frame
 	}
 	f.mutex.Unlock()
@@ -33,7 +33,7 @@ var _ = Describe("Framer", func() {
 		stream1.EXPECT().StreamID().Return(protocol.StreamID(5)).AnyTimes()
 		stream2 = NewMockSendStreamI(mockCtrl)
 		stream2.EXPECT().StreamID().Return(protocol.StreamID(6)).AnyTimes()
-		framer = newFramer(streamGetter, version)
+		framer = newFramer(streamGetter, protocol.StreamSchedulingStrictPriority, version)
 	})
 
 	Context("handling control frames", func() {
@@ -354,4 +354,96 @@ var _ = Describe("Framer", func() {
 			Expect(length).To(Equal(f.Length(version)))
 		})
 	})
+
+	Context("stream priorities", func() {
+		It("sends data of the more urgent stream first", func() {
+			streamGetter.EXPECT().GetOrOpenSendStream(id1).Return(stream1, nil)
+			streamGetter.EXPECT().GetOrOpenSendStream(id2).Return(stream2, nil).Times(2)
+			f1 := &wire.StreamFrame{StreamID: id1, Data: []byte("foobar")}
+			f2 := &wire.StreamFrame{StreamID: id2, Data: []byte("raboof")}
+			stream1.EXPECT().popStreamFrame(gomock.Any()).Return(&ackhandler.Frame{Frame: f1}, false)
+			stream2.EXPECT().popStreamFrame(gomock.Any()).Return(&ackhandler.Frame{Frame: f2}, false)
+			framer.SetStreamPriority(id2, protocol.StreamPriority{Urgency: 0})
+			framer.AddActiveStream(id1)
+			framer.AddActiveStream(id2)
+			frames, _ := framer.AppendStreamFrames(nil, 1000)
+			Expect(frames).To(HaveLen(2))
+			Expect(frames[0].Frame).To(Equal(f2))
+			Expect(frames[1].Frame).To(Equal(f1))
+		})
+
+		It("sends all data of a non-incremental stream first", func() {
+			streamGetter.EXPECT().GetOrOpenSendStream(id1).Return(stream1, nil).Times(3)
+			streamGetter.EXPECT().GetOrOpenSendStream(id2).Return(stream2, nil).Times(2)
+			f11 := &wire.StreamFrame{StreamID: id1, Data: []byte("foobar")}
+			f12 := &wire.StreamFrame{StreamID: id1, Data: []byte("foobaz")}
+			f2 := &wire.StreamFrame{StreamID: id2, Data: []byte("raboof")}
+			stream1.EXPECT().popStreamFrame(gomock.Any()).Return(&ackhandler.Frame{Frame: f11}, true)
+			stream1.EXPECT().popStreamFrame(gomock.Any()).Return(&ackhandler.Frame{Frame: f12}, false)
+			stream2.EXPECT().popStreamFrame(gomock.Any()).Return(&ackhandler.Frame{Frame: f2}, false)
+			framer.SetStreamPriority(id1, protocol.StreamPriority{Urgency: protocol.DefaultStreamUrgency})
+			framer.SetStreamPriority(id2, protocol.StreamPriority{Urgency: protocol.DefaultStreamUrgency})
+			framer.AddActiveStream(id1)
+			framer.AddActiveStream(id2)
+			frames, _ := framer.AppendStreamFrames(nil, protocol.MinStreamFrameSize)
+			Expect(frames).To(HaveLen(1))
+			Expect(frames[0].Frame).To(Equal(f11))
+			frames, _ = framer.AppendStreamFrames(nil, protocol.MinStreamFrameSize)
+			Expect(frames).To(HaveLen(1))
+			Expect(frames[0].Frame).To(Equal(f12))
+			frames, _ = framer.AppendStreamFrames(nil, protocol.MinStreamFrameSize)
+			Expect(frames).To(HaveLen(1))
+			Expect(frames[0].Frame).To(Equal(f2))
+		})
+
+		It("uses the default priority after a stream was removed", func() {
+			streamGetter.EXPECT().GetOrOpenSendStream(id1).Return(stream1, nil)
+			streamGetter.EXPECT().GetOrOpenSendStream(id2).Return(stream2, nil).Times(2)
+			f1 := &wire.StreamFrame{StreamID: id1, Data: []byte("foobar")}
+			f2 := &wire.StreamFrame{StreamID: id2, Data: []byte("raboof")}
+			stream1.EXPECT().popStreamFrame(gomock.Any()).Return(&ackhandler.Frame{Frame: f1}, false)
+			stream2.EXPECT().popStreamFrame(gomock.Any()).Return(&ackhandler.Frame{Frame: f2}, false)
+			framer.SetStreamPriority(id2, protocol.StreamPriority{Urgency: 0})
+			framer.RemoveStream(id2)
+			framer.AddActiveStream(id1)
+			framer.AddActiveStream(id2)
+			frames, _ := framer.AppendStreamFrames(nil, 1000)
+			Expect(frames).To(HaveLen(2))
+			Expect(frames[0].Frame).To(Equal(f1))
+			Expect(frames[1].Frame).To(Equal(f2))
+		})
+
+		It("ignores priority changes of streams that were already completed", func() {
+			streamGetter.EXPECT().GetOrOpenSendStream(id1).Return(nil, nil)
+			framer.SetStreamPriority(id1, protocol.StreamPriority{Urgency: 0})
+			Expect(framer.(*framerI).priorities).To(BeEmpty())
+		})
+
+		It("doesn't starve less urgent streams when using weighted fair scheduling", func() {
+			framer = newFramer(streamGetter, protocol.StreamSchedulingWeightedFair, version)
+			streamGetter.EXPECT().GetOrOpenSendStream(id1).Return(stream1, nil).AnyTimes()
+			streamGetter.EXPECT().GetOrOpenSendStream(id2).Return(stream2, nil).AnyTimes()
+			framer.SetStreamPriority(id1, protocol.StreamPriority{Urgency: 0})
+			framer.SetStreamPriority(id2, protocol.StreamPriority{Urgency: protocol.MaxStreamUrgency})
+			var sent1, sent2 protocol.ByteCount
+			popFrame := func(id protocol.StreamID, sent *protocol.ByteCount) func(protocol.ByteCount) (*ackhandler.Frame, bool) {
+				return func(size protocol.ByteCount) (*ackhandler.Frame, bool) {
+					f := &wire.StreamFrame{StreamID: id, DataLenPresent: true}
+					f.Data = make([]byte, f.MaxDataLen(size, version))
+					*sent += protocol.ByteCount(len(f.Data))
+					return &ackhandler.Frame{Frame: f}, true
+				}
+			}
+			stream1.EXPECT().popStreamFrame(gomock.Any()).DoAndReturn(popFrame(id1, &sent1)).AnyTimes()
+			stream2.EXPECT().popStreamFrame(gomock.Any()).DoAndReturn(popFrame(id2, &sent2)).AnyTimes()
+			framer.AddActiveStream(id1)
+			framer.AddActiveStream(id2)
+			for i := 0; i < 900; i++ {
+				frames, _ := framer.AppendStreamFrames(nil, 1000)
+				Expect(frames).To(HaveLen(1))
+			}
+			Expect(sent2).ToNot(BeZero())
+			Expect(sent1).To(BeNumerically("~", 8*sent2, 8*2*1000))
+		})
+	})
 })
@@ -0,0 +1,399 @@
+package http3
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/For-ACGN/quic-go/internal/utils"
+)
+
+const (
+	// defaultAltSvcMaxAge is the freshness lifetime of an alternative that doesn't have an ma parameter.
+	defaultAltSvcMaxAge = 24 * time.Hour
+	// defaultAltSvcRaceTimeout is the default value of AltSvcRoundTripper.RaceTimeout.
+	defaultAltSvcRaceTimeout = 300 * time.Millisecond
+	// altSvcBrokenDuration is the time an alternative isn't used after the QUIC handshake failed.
+	altSvcBrokenDuration = 5 * time.Minute
+)
+
+// An AltSvcRoundTripper is an http.RoundTripper that discovers HTTP/3 support using Alt-Svc (RFC 7838).
+// Requests to an origin are sent over TCP (using HTTP/1.1 or HTTP/2), until the origin advertises
+// an HTTP/3 alternative in the Alt-Svc header field of a response.
+// Subsequent requests are sent using HTTP/3, once the QUIC handshake with the alternative completed.
+// If the handshake fails (e.g. because UDP is blocked on the path), or if the QUIC connection fails while sending a request,
+// the alternative is considered broken for a few minutes, and requests are sent over TCP in the meantime.
+// Failed requests are retried over TCP, if they are idempotent and the body can be rewound.
+//
+// Only alternatives on the same host as the origin are used.
+type AltSvcRoundTripper struct {
+	// TCP sends requests over TCP.
+	// If nil, http.DefaultTransport is used.
+	TCP http.RoundTripper
+
+	// HTTP3 sends requests using HTTP/3.
+	// If nil, a RoundTripper with the default configuration is used.
+	HTTP3 *RoundTripper
+
+	// RaceTimeout is the time a request waits for the QUIC handshake to complete,
+	// if there's no connection to the HTTP/3 alternative yet.
+	// If the handshake doesn't complete in time, the request is sent over TCP,
+	// and the handshake continues in the background.
+	// If zero, a timeout of 300ms is used.
+	RaceTimeout time.Duration
+
+	initOnce sync.Once
+	tcp      http.RoundTripper
+	h3       *RoundTripper
+	alpn     string
+	cache    *altSvcCache
+	logger   utils.Logger
+}
+
+var _ roundTripCloser = &AltSvcRoundTripper{}
+
+func (r *AltSvcRoundTripper) init() {
+	r.tcp = r.TCP
+	if r.tcp == nil {
+		r.tcp = http.DefaultTransport
+	}
+	r.h3 = r.HTTP3
+	if r.h3 == nil {
+		r.h3 = &RoundTripper{}
+	}
+	version := defaultQuicConfig.Versions[0]
+	if r.h3.QuicConfig != nil && len(r.h3.QuicConfig.Versions) > 0 {
+		version = r.h3.QuicConfig.Versions[0]
+	}
+	r.alpn = versionToALPN(version)
+	r.cache = &altSvcCache{entries: make(map[string]*altSvcEntry)}
+	r.logger = utils.DefaultLogger.WithPrefix("h3 alt-svc")
+}
+
+// RoundTrip sends the request using HTTP/3, if the origin advertised an HTTP/3 alternative
+// that is reachable, and over TCP otherwise.
+func (r *AltSvcRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	r.initOnce.Do(r.init)
+	if req.URL == nil || req.URL.Scheme != "https" || req.URL.Host == "" {
+		return r.tcp.RoundTrip(req)
+	}
+	origin := authorityAddr("https", req.URL.Host)
+	if addr, ok := r.cache.get(origin, time.Now()); ok && r.useHTTP3(req.Context(), origin, addr) {
+		rsp, err := r.h3.RoundTrip(requestForAlternative(req, addr))
+		if err == nil {
+			rsp.Request = req
+			r.handleAltSvc(origin, rsp)
+			return rsp, nil
+		}
+		// Errors of a single request (e.g. invalid header fields, or a reset stream) are returned to the application.
+		if req.Context().Err() != nil || !isConnectionError(err) {
+			return nil, err
+		}
+		// The handshake succeeded, but the connection failed, e.g. because the path stopped working.
+		r.markBroken(origin, addr, err)
+		if !isReplayable(req) {
+			return nil, err
+		}
+		newReq, rerr := rewindBody(req)
+		if rerr != nil {
+			return nil, err
+		}
+		req = newReq
+	}
+	rsp, err := r.tcp.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	r.handleAltSvc(origin, rsp)
+	return rsp, nil
+}
+
+// useHTTP3 says if a request to origin can be sent to the HTTP/3 alternative at addr.
+// It waits up to RaceTimeout for the QUIC handshake to complete.
+// If the handshake fails, the alternative is marked as broken.
+func (r *AltSvcRoundTripper) useHTTP3(ctx context.Context, origin, addr string) bool {
+	if r.h3.hasConn(addr) {
+		return true
+	}
+	done := make(chan error, 1)
+	go func() { done <- r.h3.handshake(context.Background(), addr) }()
+
+	raceTimeout := r.RaceTimeout
+	if raceTimeout == 0 {
+		raceTimeout = defaultAltSvcRaceTimeout
+	}
+	timer := time.NewTimer(raceTimeout)
+	defer timer.Stop()
+	select {
+	case err := <-done:
+		if err != nil {
+			r.markBroken(origin, addr, err)
+			return false
+		}
+		return true
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+	// The request is sent over TCP, but the handshake continues.
+	// If it succeeds, the following requests are sent using HTTP/3.
+	go func() {
+		if err := <-done; err != nil {
+			r.markBroken(origin, addr, err)
+		}
+	}()
+	return false
+}
+
+func (r *AltSvcRoundTripper) markBroken(origin, addr string, err error) {
+	r.logger.Debugf("HTTP/3 alternative %s for %s failed: %s", addr, origin, err)
+	r.cache.markBroken(origin, addr, time.Now().Add(altSvcBrokenDuration))
+}
+
+func (r *AltSvcRoundTripper) handleAltSvc(origin string, rsp *http.Response) {
+	values := rsp.Header.Values("Alt-Svc")
+	if len(values) == 0 {
+		return
+	}
+	alts, clear := parseAltSvc(values)
+	r.cache.update(origin, alts, clear, r.alpn, time.Now())
+}
+
+// isConnectionError says if a request sent using HTTP/3 failed because the QUIC connection failed,
+// e.g. because it was closed or timed out, or because the server didn't process the request.
+func isConnectionError(err error) bool {
+	if err == errRequestNotProcessed {
+		return true
+	}
+	// The errors returned when the QUIC connection is closed, as well as dial errors, are net.Errors.
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// isReplayable says if a request that failed over HTTP/3 can be sent again over TCP.
+// Like net/http, only idempotent requests are replayed, and only if the body can be rewound.
+func isReplayable(req *http.Request) bool {
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+	switch req.Method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	if _, ok := req.Header["Idempotency-Key"]; ok {
+		return true
+	}
+	_, ok := req.Header["X-Idempotency-Key"]
+	return ok
+}
+
+// requestForAlternative returns a copy of the request that is sent to the alternative at addr.
+// The :authority pseudo header field still contains the origin.
+func requestForAlternative(req *http.Request, addr string) *http.Request {
+	newReq := *req
+	u := *req.URL
+	u.Host = addr
+	newReq.URL = &u
+	if newReq.Host == "" {
+		newReq.Host = req.URL.Host
+	}
+	return &newReq
+}
+
+// Close closes the QUIC connections, as well as the idle TCP connections.
+func (r *AltSvcRoundTripper) Close() error {
+	r.initOnce.Do(r.init)
+	if cr, ok := r.tcp.(interface{ CloseIdleConnections() }); ok {
+		cr.CloseIdleConnections()
+	}
+	return r.h3.Close()
+}
+
+// An altSvc is an alternative service, advertised in an Alt-Svc header field.
+type altSvc struct {
+	ALPN   string
+	Host   string // empty if the alternative is on the same host as the origin
+	Port   int
+	MaxAge time.Duration
+}
+
+// parseAltSvc parses the values of the Alt-Svc header fields of a response (RFC 7838, section 3).
+// clear is true if the origin invalidated all alternatives.
+// Malformed alternatives are skipped.
+func parseAltSvc(values []string) (alts []altSvc, clear bool) {
+	for _, value := range splitQuoted(strings.Join(values, ","), ',') {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		if value == "clear" {
+			return nil, true
+		}
+		params := splitQuoted(value, ';')
+		alt, ok := parseAlternative(strings.TrimSpace(params[0]))
+		if !ok {
+			continue
+		}
+		alt.MaxAge = defaultAltSvcMaxAge
+		for _, param := range params[1:] {
+			kv := strings.SplitN(strings.TrimSpace(param), "=", 2)
+			if len(kv) != 2 || strings.ToLower(kv[0]) != "ma" {
+				continue
+			}
+			v, ok := unquote(kv[1])
+			if !ok {
+				continue
+			}
+			if ma, err := strconv.ParseUint(v, 10, 32); err == nil {
+				alt.MaxAge = time.Duration(ma) * time.Second
+			}
+		}
+		alts = append(alts, alt)
+	}
+	return alts, false
+}
+
+// parseAlternative parses an alternative of the form protocol-id="[host]:port".
+func parseAlternative(s string) (altSvc, bool) {
+	kv := strings.SplitN(s, "=", 2)
+	if len(kv) != 2 || len(kv[1]) == 0 || kv[1][0] != '"' {
+		return altSvc{}, false
+	}
+	alpn, err := url.PathUnescape(kv[0])
+	if err != nil || alpn == "" {
+		return altSvc{}, false
+	}
+	authority, ok := unquote(kv[1])
+	if !ok {
+		return altSvc{}, false
+	}
+	host, portStr, err := net.SplitHostPort(authority)
+	if err != nil {
+		return altSvc{}, false
+	}
+	port, err := strconv.ParseUint(portStr, 10, 16)
+	if err != nil || port == 0 {
+		return altSvc{}, false
+	}
+	return altSvc{ALPN: alpn, Host: host, Port: int(port)}, true
+}
+
+// splitQuoted splits s at every occurrence of sep that is not inside a quoted-string.
+func splitQuoted(s string, sep byte) []string {
+	var parts []string
+	var inQuotes, escaped bool
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch {
+		case escaped:
+			escaped = false
+		case inQuotes && s[i] == '\\':
+			escaped = true
+		case s[i] == '"':
+			inQuotes = !inQuotes
+		case !inQuotes && s[i] == sep:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// unquote returns the value of a token or a quoted-string.
+func unquote(s string) (string, bool) {
+	if len(s) == 0 || s[0] != '"' {
+		return s, len(s) > 0
+	}
+	if len(s) < 2 || s[len(s)-1] != '"' {
+		return "", false
+	}
+	var b strings.Builder
+	s = s[1 : len(s)-1]
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String(), true
+}
+
+// altSvcCache caches the HTTP/3 alternatives advertised by origins.
+// For every origin, only the most preferred alternative that can be used is cached.
+type altSvcCache struct {
+	mutex   sync.Mutex
+	entries map[string]*altSvcEntry // indexed by the origin (host:port)
+}
+
+type altSvcEntry struct {
+	addr        string // host:port of the alternative
+	expires     time.Time
+	brokenUntil time.Time
+}
+
+// update processes the alternatives advertised by origin.
+// They replace all alternatives previously cached for the origin.
+// If the alternative didn't change, it remains broken.
+func (c *altSvcCache) update(origin string, alts []altSvc, clear bool, alpn string, now time.Time) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	old := c.entries[origin]
+	delete(c.entries, origin)
+	if clear {
+		return
+	}
+	host, _, err := net.SplitHostPort(origin)
+	if err != nil {
+		return
+	}
+	for _, alt := range alts {
+		if alt.ALPN != alpn || (alt.Host != "" && alt.Host != host) || alt.MaxAge == 0 {
+			continue
+		}
+		entry := &altSvcEntry{
+			addr:    net.JoinHostPort(host, strconv.Itoa(alt.Port)),
+			expires: now.Add(alt.MaxAge),
+		}
+		if old != nil && old.addr == entry.addr {
+			entry.brokenUntil = old.brokenUntil
+		}
+		c.entries[origin] = entry
+		return
+	}
+}
+
+// get returns the address of the alternative for origin.
+// ok is false if there's no alternative, if it expired, or if it is broken.
+func (c *altSvcCache) get(origin string, now time.Time) (addr string, ok bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	entry, ok := c.entries[origin]
+	if !ok {
+		return "", false
+	}
+	if !now.Before(entry.expires) {
+		delete(c.entries, origin)
+		return "", false
+	}
+	if now.Before(entry.brokenUntil) {
+		return "", false
+	}
+	return entry.addr, true
+}
+
+// markBroken marks the alternative at addr as broken until the given time.
+func (c *altSvcCache) markBroken(origin, addr string, until time.Time) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if entry, ok := c.entries[origin]; ok && entry.addr == addr {
+		entry.brokenUntil = until
+	}
+}
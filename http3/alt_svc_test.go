@@ -0,0 +1,343 @@
+package http3
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	quic "github.com/For-ACGN/quic-go"
+	mockquic "github.com/For-ACGN/quic-go/internal/mocks/quic"
+	"github.com/For-ACGN/quic-go/internal/qerr"
+	"github.com/golang/mock/gomock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+var _ = Describe("Alt-Svc", func() {
+	Context("parsing", func() {
+		It("parses an alternative on the same host", func() {
+			alts, clear := parseAltSvc([]string{`h3=":443"; ma=2592000`})
+			Expect(clear).To(BeFalse())
+			Expect(alts).To(Equal([]altSvc{{ALPN: "h3", Port: 443, MaxAge: 2592000 * time.Second}}))
+		})
+
+		It("uses the default max-age", func() {
+			alts, _ := parseAltSvc([]string{`h3="alt.example.com:8443"`})
+			Expect(alts).To(Equal([]altSvc{{ALPN: "h3", Host: "alt.example.com", Port: 8443, MaxAge: 24 * time.Hour}}))
+		})
+
+		It("parses multiple alternatives, in multiple header fields", func() {
+			alts, clear := parseAltSvc([]string{
+				`h3=":443"; ma=60, h3-29=":8443"; persist=1`,
+				`h2="[::1]:443";ma="120"`,
+			})
+			Expect(clear).To(BeFalse())
+			Expect(alts).To(Equal([]altSvc{
+				{ALPN: "h3", Port: 443, MaxAge: time.Minute},
+				{ALPN: "h3-29", Port: 8443, MaxAge: 24 * time.Hour},
+				{ALPN: "h2", Host: "::1", Port: 443, MaxAge: 2 * time.Minute},
+			}))
+		})
+
+		It("percent-decodes the protocol ID", func() {
+			alts, _ := parseAltSvc([]string{`w%3Dx%3Ay=":443"`})
+			Expect(alts).To(HaveLen(1))
+			Expect(alts[0].ALPN).To(Equal("w=x:y"))
+		})
+
+		It("doesn't split at separators inside quoted strings", func() {
+			alts, _ := parseAltSvc([]string{`h3=":443"; foo="a,b;c", h3-29=":444"`})
+			Expect(alts).To(HaveLen(2))
+			Expect(alts[1].Port).To(Equal(444))
+		})
+
+		It("parses clear", func() {
+			alts, clear := parseAltSvc([]string{"clear"})
+			Expect(clear).To(BeTrue())
+			Expect(alts).To(BeEmpty())
+		})
+
+		It("skips malformed alternatives", func() {
+			alts, _ := parseAltSvc([]string{`h3=:443, h3="foobar", h3=":0", h3=":99999", ="":443", h3=":443`})
+			Expect(alts).To(BeEmpty())
+			alts, _ = parseAltSvc([]string{`h3=:443, h3=":1234"`})
+			Expect(alts).To(Equal([]altSvc{{ALPN: "h3", Port: 1234, MaxAge: 24 * time.Hour}}))
+		})
+	})
+
+	Context("caching", func() {
+		var cache *altSvcCache
+		now := time.Now()
+
+		BeforeEach(func() {
+			cache = &altSvcCache{entries: make(map[string]*altSvcEntry)}
+		})
+
+		It("caches the first alternative that uses the ALPN", func() {
+			cache.update("example.com:443", []altSvc{
+				{ALPN: "h3-29", Port: 1000, MaxAge: time.Hour},
+				{ALPN: "h3", Port: 2000, MaxAge: time.Hour},
+				{ALPN: "h3", Port: 3000, MaxAge: time.Hour},
+			}, false, "h3", now)
+			addr, ok := cache.get("example.com:443", now)
+			Expect(ok).To(BeTrue())
+			Expect(addr).To(Equal("example.com:2000"))
+			_, ok = cache.get("foo.com:443", now)
+			Expect(ok).To(BeFalse())
+		})
+
+		It("ignores alternatives on other hosts", func() {
+			cache.update("example.com:443", []altSvc{
+				{ALPN: "h3", Host: "alt.example.com", Port: 1000, MaxAge: time.Hour},
+				{ALPN: "h3", Host: "example.com", Port: 2000, MaxAge: time.Hour},
+			}, false, "h3", now)
+			addr, ok := cache.get("example.com:443", now)
+			Expect(ok).To(BeTrue())
+			Expect(addr).To(Equal("example.com:2000"))
+		})
+
+		It("expires alternatives", func() {
+			cache.update("example.com:443", []altSvc{{ALPN: "h3", Port: 443, MaxAge: time.Minute}}, false, "h3", now)
+			_, ok := cache.get("example.com:443", now.Add(time.Minute-time.Nanosecond))
+			Expect(ok).To(BeTrue())
+			_, ok = cache.get("example.com:443", now.Add(time.Minute))
+			Expect(ok).To(BeFalse())
+			Expect(cache.entries).To(BeEmpty())
+		})
+
+		It("replaces alternatives, and clears them", func() {
+			cache.update("example.com:443", []altSvc{{ALPN: "h3", Port: 443, MaxAge: time.Minute}}, false, "h3", now)
+			cache.update("example.com:443", []altSvc{{ALPN: "h3-29", Port: 443, MaxAge: time.Minute}}, false, "h3", now)
+			_, ok := cache.get("example.com:443", now)
+			Expect(ok).To(BeFalse())
+			cache.update("example.com:443", []altSvc{{ALPN: "h3", Port: 443, MaxAge: time.Minute}}, false, "h3", now)
+			cache.update("example.com:443", nil, true, "h3", now)
+			_, ok = cache.get("example.com:443", now)
+			Expect(ok).To(BeFalse())
+		})
+
+		It("doesn't use broken alternatives", func() {
+			cache.update("example.com:443", []altSvc{{ALPN: "h3", Port: 443, MaxAge: time.Hour}}, false, "h3", now)
+			cache.markBroken("example.com:443", "example.com:443", now.Add(time.Minute))
+			_, ok := cache.get("example.com:443", now)
+			Expect(ok).To(BeFalse())
+			// the alternative remains broken when it is advertised again
+			cache.update("example.com:443", []altSvc{{ALPN: "h3", Port: 443, MaxAge: time.Hour}}, false, "h3", now)
+			_, ok = cache.get("example.com:443", now)
+			Expect(ok).To(BeFalse())
+			addr, ok := cache.get("example.com:443", now.Add(time.Minute))
+			Expect(ok).To(BeTrue())
+			Expect(addr).To(Equal("example.com:443"))
+			// a different alternative is not broken
+			cache.update("example.com:443", []altSvc{{ALPN: "h3", Port: 8443, MaxAge: time.Hour}}, false, "h3", now)
+			_, ok = cache.get("example.com:443", now)
+			Expect(ok).To(BeTrue())
+		})
+	})
+
+	It("says which requests can be replayed", func() {
+		req, err := http.NewRequest(http.MethodGet, "https://www.example.com/", nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(isReplayable(req)).To(BeTrue())
+		req, err = http.NewRequest(http.MethodPut, "https://www.example.com/", strings.NewReader("foobar"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(isReplayable(req)).To(BeFalse())
+		req.Header.Set("Idempotency-Key", "foo")
+		Expect(isReplayable(req)).To(BeTrue())
+		req.GetBody = nil
+		Expect(isReplayable(req)).To(BeFalse())
+	})
+
+	It("says which errors are caused by the QUIC connection", func() {
+		Expect(isConnectionError(qerr.NewTimeoutError("No recent network activity"))).To(BeTrue())
+		Expect(isConnectionError(qerr.NewApplicationError(0x100, ""))).To(BeTrue())
+		Expect(isConnectionError(errRequestNotProcessed)).To(BeTrue())
+		Expect(isConnectionError(&net.OpError{Op: "dial", Err: errors.New("connection refused")})).To(BeTrue())
+		Expect(isConnectionError(&streamError{code: 0x10c})).To(BeFalse())
+		Expect(isConnectionError(errors.New("http3: invalid method"))).To(BeFalse())
+	})
+
+	Context("the RoundTripper", func() {
+		var (
+			rt           *AltSvcRoundTripper
+			tcpRequests  int32
+			altSvcHeader string
+		)
+
+		BeforeEach(func() {
+			atomic.StoreInt32(&tcpRequests, 0)
+			altSvcHeader = `h3=":8443"`
+			rt = &AltSvcRoundTripper{
+				TCP: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+					atomic.AddInt32(&tcpRequests, 1)
+					return &http.Response{
+						StatusCode: 200,
+						Header:     http.Header{"Alt-Svc": {altSvcHeader}},
+						Request:    req,
+					}, nil
+				}),
+				HTTP3: &RoundTripper{},
+			}
+		})
+
+		It("uses TCP for origins that don't advertise HTTP/3", func() {
+			altSvcHeader = `h2=":443"`
+			var dialed bool
+			rt.HTTP3.Dial = func(string, string, *tls.Config, *quic.Config) (quic.EarlySession, error) {
+				dialed = true
+				return nil, errors.New("dial error")
+			}
+			for i := 0; i < 2; i++ {
+				rsp, err := rt.RoundTrip(newAltSvcTestRequest())
+				Expect(err).ToNot(HaveOccurred())
+				Expect(rsp.StatusCode).To(Equal(200))
+			}
+			Expect(atomic.LoadInt32(&tcpRequests)).To(BeEquivalentTo(2))
+			Expect(dialed).To(BeFalse())
+		})
+
+		It("uses TCP for http URLs", func() {
+			req, err := http.NewRequest(http.MethodGet, "http://www.example.com/", nil)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = rt.RoundTrip(req)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = rt.RoundTrip(req)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(atomic.LoadInt32(&tcpRequests)).To(BeEquivalentTo(2))
+		})
+
+		It("falls back to TCP if the handshake fails", func() {
+			var dials int32
+			rt.HTTP3.Dial = func(_, addr string, _ *tls.Config, _ *quic.Config) (quic.EarlySession, error) {
+				defer GinkgoRecover()
+				Expect(addr).To(Equal("www.example.com:8443"))
+				atomic.AddInt32(&dials, 1)
+				return nil, errors.New("handshake timeout")
+			}
+			for i := 0; i < 3; i++ {
+				_, err := rt.RoundTrip(newAltSvcTestRequest())
+				Expect(err).ToNot(HaveOccurred())
+			}
+			Expect(atomic.LoadInt32(&tcpRequests)).To(BeEquivalentTo(3))
+			// The alternative is marked broken after the first handshake failed.
+			Expect(atomic.LoadInt32(&dials)).To(BeEquivalentTo(1))
+		})
+
+		It("uses TCP while the handshake is running", func() {
+			rt.RaceTimeout = 10 * time.Millisecond
+			dialDone := make(chan struct{})
+			var dials int32
+			rt.HTTP3.Dial = func(string, string, *tls.Config, *quic.Config) (quic.EarlySession, error) {
+				atomic.AddInt32(&dials, 1)
+				<-dialDone
+				return nil, errors.New("handshake timeout")
+			}
+			_, err := rt.RoundTrip(newAltSvcTestRequest()) // discovers the alternative
+			Expect(err).ToNot(HaveOccurred())
+			_, err = rt.RoundTrip(newAltSvcTestRequest())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(atomic.LoadInt32(&tcpRequests)).To(BeEquivalentTo(2))
+			Expect(atomic.LoadInt32(&dials)).To(BeEquivalentTo(1))
+			close(dialDone)
+			Eventually(func() bool {
+				_, ok := rt.cache.get("www.example.com:443", time.Now())
+				return ok
+			}).Should(BeFalse())
+		})
+
+		Context("using HTTP/3", func() {
+			var (
+				mockCtrl *gomock.Controller
+				sess     *mockquic.MockEarlySession
+				testErr  error
+				closed   chan struct{}
+			)
+
+			BeforeEach(func() {
+				mockCtrl = gomock.NewController(GinkgoT())
+				sess = mockquic.NewMockEarlySession(mockCtrl)
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+				testErr = qerr.NewTimeoutError("test err")
+				// Use a local variable, since the mock might be called after the test completed.
+				closedChan := make(chan struct{})
+				closed = closedChan
+				sess.EXPECT().HandshakeComplete().Return(ctx).AnyTimes()
+				sess.EXPECT().Context().Return(context.Background()).AnyTimes()
+				sess.EXPECT().OpenUniStream().Return(nil, testErr).AnyTimes()
+				sess.EXPECT().AcceptUniStream(gomock.Any()).DoAndReturn(func(context.Context) (quic.ReceiveStream, error) {
+					<-closedChan
+					return nil, errors.New("test done")
+				}).MaxTimes(1)
+				sess.EXPECT().CloseWithError(gomock.Any(), gomock.Any()).Do(func(quic.ErrorCode, string) { close(closedChan) })
+				rt.HTTP3.Dial = func(_, addr string, _ *tls.Config, _ *quic.Config) (quic.EarlySession, error) {
+					defer GinkgoRecover()
+					Expect(addr).To(Equal("www.example.com:8443"))
+					return sess, nil
+				}
+			})
+
+			AfterEach(func() {
+				Eventually(closed).Should(BeClosed())
+				mockCtrl.Finish()
+			})
+
+			It("uses HTTP/3 once the handshake completed, and retries failed requests over TCP", func() {
+				sess.EXPECT().OpenStream().Return(nil, testErr)
+				_, err := rt.RoundTrip(newAltSvcTestRequest())
+				Expect(err).ToNot(HaveOccurred())
+				Expect(rt.h3.hasConn("www.example.com:8443")).To(BeFalse())
+				// The request is sent using HTTP/3, which fails since we can't open a stream.
+				// It is then retried over TCP.
+				_, err = rt.RoundTrip(newAltSvcTestRequest())
+				Expect(err).ToNot(HaveOccurred())
+				Expect(rt.h3.hasConn("www.example.com:8443")).To(BeTrue())
+				Expect(atomic.LoadInt32(&tcpRequests)).To(BeEquivalentTo(2))
+				// The alternative is marked broken.
+				_, ok := rt.cache.get("www.example.com:443", time.Now())
+				Expect(ok).To(BeFalse())
+			})
+
+			It("doesn't retry non-idempotent requests over TCP", func() {
+				sess.EXPECT().OpenStream().Return(nil, testErr)
+				_, err := rt.RoundTrip(newAltSvcTestRequest())
+				Expect(err).ToNot(HaveOccurred())
+				req, err := http.NewRequest(http.MethodPost, "https://www.example.com/upload", nil)
+				Expect(err).ToNot(HaveOccurred())
+				_, err = rt.RoundTrip(req)
+				Expect(err).To(MatchError(testErr))
+				Expect(atomic.LoadInt32(&tcpRequests)).To(BeEquivalentTo(1))
+				_, ok := rt.cache.get("www.example.com:443", time.Now())
+				Expect(ok).To(BeFalse())
+			})
+
+			It("doesn't mark the alternative broken if a single request fails", func() {
+				_, err := rt.RoundTrip(newAltSvcTestRequest())
+				Expect(err).ToNot(HaveOccurred())
+				req := newAltSvcTestRequest()
+				req.Header.Set("foo", "invalid\r\nvalue")
+				_, err = rt.RoundTrip(req)
+				Expect(err).To(MatchError(ContainSubstring("invalid http header field value")))
+				Expect(rt.h3.hasConn("www.example.com:8443")).To(BeTrue())
+				Expect(atomic.LoadInt32(&tcpRequests)).To(BeEquivalentTo(1))
+				_, ok := rt.cache.get("www.example.com:443", time.Now())
+				Expect(ok).To(BeTrue())
+			})
+		})
+	})
+})
+
+func newAltSvcTestRequest() *http.Request {
+	req, err := http.NewRequest(http.MethodGet, "https://www.example.com/file1.html", nil)
+	Expect(err).ToNot(HaveOccurred())
+	return req
+}
@@ -1,15 +1,18 @@
 package http3
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 
 	"github.com/For-ACGN/quic-go"
 )
 
 // The body of a http.Request or http.Response.
 type body struct {
-	str quic.Stream
+	str quic.ReceiveStream
 
 	// only set for the http.Response
 	// The channel is closed when the user is done with this response:
@@ -18,11 +21,25 @@ type body struct {
 	reqDoneClosed bool
 
 	onFrameError func()
+	// only set for the http.Response, if PUSH_PROMISE frames are allowed
+	onPushPromise func(*pushPromiseFrame) error
+	// called for the trailing HEADERS frame, if set
+	onTrailers   func(*headersFrame) error
+	trailersRead bool
+
+	// only set for the http.Response, if HTTP datagrams are enabled
+	datagrams *datagramFlow
+	// only set for the http.Response
+	updatePriority func(Priority) error
 
 	bytesRemainingInFrame uint64
 }
 
-var _ io.ReadCloser = &body{}
+var (
+	_ io.ReadCloser   = &body{}
+	_ Datagrammer     = &body{}
+	_ PriorityUpdater = &body{}
+)
 
 func newRequestBody(str quic.Stream, onFrameError func()) *body {
 	return &body{
@@ -31,7 +48,7 @@ func newRequestBody(str quic.Stream, onFrameError func()) *body {
 	}
 }
 
-func newResponseBody(str quic.Stream, done chan<- struct{}, onFrameError func()) *body {
+func newResponseBody(str quic.ReceiveStream, done chan<- struct{}, onFrameError func()) *body {
 	return &body{
 		str:          str,
 		onFrameError: onFrameError,
@@ -57,12 +74,29 @@ func (r *body) readImpl(b []byte) (int, error) {
 			}
 			switch f := frame.(type) {
 			case *headersFrame:
-				// skip HEADERS frames
+				if r.trailersRead {
+					r.onFrameError()
+					return 0, errors.New("peer sent multiple trailing HEADERS frames")
+				}
+				r.trailersRead = true
+				if err := r.readTrailers(f); err != nil {
+					return 0, err
+				}
 				continue
 			case *dataFrame:
+				if r.trailersRead {
+					r.onFrameError()
+					return 0, errors.New("peer sent a DATA frame after the trailers")
+				}
 				r.bytesRemainingInFrame = f.Length
 				break parseLoop
 			default:
+				if pf, ok := f.(*pushPromiseFrame); ok && r.onPushPromise != nil {
+					if err := r.onPushPromise(pf); err != nil {
+						return 0, err
+					}
+					continue
+				}
 				r.onFrameError()
 				// parseNextFrame skips over unknown frame types
 				// Therefore, this condition is only entered when we parsed another known frame type.
@@ -82,6 +116,16 @@ func (r *body) readImpl(b []byte) (int, error) {
 	return n, err
 }
 
+// readTrailers reads the trailing HEADERS frame.
+// The trailers are discarded if there's no onTrailers callback.
+func (r *body) readTrailers(hf *headersFrame) error {
+	if r.onTrailers == nil {
+		_, err := io.CopyN(ioutil.Discard, r.str, int64(hf.Length))
+		return err
+	}
+	return r.onTrailers(hf)
+}
+
 func (r *body) requestDone() {
 	if r.reqDoneClosed || r.reqDone == nil {
 		return
@@ -96,3 +140,28 @@ func (r *body) Close() error {
 	r.str.CancelRead(quic.ErrorCode(errorRequestCanceled))
 	return nil
 }
+
+// SendDatagram sends an HTTP datagram associated with the request.
+func (r *body) SendDatagram(b []byte) error {
+	if r.datagrams == nil {
+		return errDatagramsNotEnabled
+	}
+	return r.datagrams.send(b)
+}
+
+// ReceiveDatagram gets an HTTP datagram associated with the request.
+// It returns an error once the response body was closed.
+func (r *body) ReceiveDatagram(ctx context.Context) ([]byte, error) {
+	if r.datagrams == nil {
+		return nil, errDatagramsNotEnabled
+	}
+	return r.datagrams.receive(ctx)
+}
+
+// UpdatePriority changes the priority of the request, by sending a PRIORITY_UPDATE frame.
+func (r *body) UpdatePriority(p Priority) error {
+	if r.updatePriority == nil {
+		return errPriorityUpdateNotSupported
+	}
+	return r.updatePriority(p)
+}
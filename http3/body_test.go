@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"io/ioutil"
 
 	"github.com/For-ACGN/quic-go"
 	mockquic "github.com/For-ACGN/quic-go/internal/mocks/quic"
@@ -130,16 +131,49 @@ var _ = Describe("Body", func() {
 				Expect(b[:n]).To(Equal([]byte("bar")))
 			})
 
-			It("skips HEADERS frames", func() {
+			It("reads trailers", func() {
+				buf.Write(getDataFrame([]byte("foobar")))
+				(&headersFrame{Length: 3}).Write(buf)
+				buf.Write([]byte("raw"))
+				var trailers []byte
+				rb.onTrailers = func(hf *headersFrame) error {
+					trailers = make([]byte, hf.Length)
+					_, err := io.ReadFull(buf, trailers)
+					return err
+				}
+				data, err := ioutil.ReadAll(rb)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(data).To(Equal([]byte("foobar")))
+				Expect(trailers).To(Equal([]byte("raw")))
+			})
+
+			It("discards trailers if there's no callback", func() {
 				buf.Write(getDataFrame([]byte("foo")))
 				(&headersFrame{Length: 10}).Write(buf)
 				buf.Write(make([]byte, 10))
-				buf.Write(getDataFrame([]byte("bar")))
-				b := make([]byte, 6)
-				n, err := io.ReadFull(rb, b)
+				data, err := ioutil.ReadAll(rb)
 				Expect(err).ToNot(HaveOccurred())
-				Expect(n).To(Equal(6))
-				Expect(b).To(Equal([]byte("foobar")))
+				Expect(data).To(Equal([]byte("foo")))
+			})
+
+			It("errors on DATA frames after the trailers", func() {
+				buf.Write(getDataFrame([]byte("foo")))
+				(&headersFrame{Length: 10}).Write(buf)
+				buf.Write(make([]byte, 10))
+				buf.Write(getDataFrame([]byte("bar")))
+				_, err := ioutil.ReadAll(rb)
+				Expect(err).To(MatchError("peer sent a DATA frame after the trailers"))
+				Expect(errorCbCalled).To(BeTrue())
+			})
+
+			It("errors on multiple trailing HEADERS frames", func() {
+				(&headersFrame{Length: 1}).Write(buf)
+				buf.WriteByte(42)
+				(&headersFrame{Length: 1}).Write(buf)
+				buf.WriteByte(42)
+				_, err := ioutil.ReadAll(rb)
+				Expect(err).To(MatchError("peer sent multiple trailing HEADERS frames"))
+				Expect(errorCbCalled).To(BeTrue())
 			})
 
 			It("errors when it can't parse the frame", func() {
@@ -0,0 +1,69 @@
+package http3
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/For-ACGN/quic-go/quicvarint"
+)
+
+// CapsuleType is the type of a capsule, see RFC 9297, section 3.2.
+type CapsuleType uint64
+
+// CapsuleTypeDatagram is the type of the DATAGRAM capsule.
+// It carries an HTTP datagram on the request stream, see RFC 9297, section 3.5.
+const CapsuleTypeDatagram CapsuleType = 0x00
+
+// CapsuleProtocolHeader is the header used to signal that
+// the data on the request stream uses the Capsule Protocol, see RFC 9297, section 3.4.
+// Its value is "?1".
+const CapsuleProtocolHeader = "Capsule-Protocol"
+
+// ParseCapsule parses the header of the next capsule read from r.
+// The capsules are carried in the body of the request or the response.
+// It returns a reader for the capsule value, which must be read to the end before the next capsule can be parsed.
+// It returns io.EOF if r returns io.EOF before the first byte of the capsule.
+func ParseCapsule(r io.Reader) (CapsuleType, io.Reader, error) {
+	br, ok := r.(byteReader)
+	if !ok {
+		br = &byteReaderImpl{r}
+	}
+	ct, err := quicvarint.Read(br)
+	if err != nil {
+		return 0, nil, err
+	}
+	l, err := quicvarint.Read(br)
+	if err != nil {
+		if err == io.EOF {
+			return 0, nil, io.ErrUnexpectedEOF
+		}
+		return 0, nil, err
+	}
+	return CapsuleType(ct), &capsuleReader{r: &io.LimitedReader{R: br, N: int64(l)}}, nil
+}
+
+// capsuleReader reads the value of a capsule.
+// It returns io.ErrUnexpectedEOF if the underlying reader returns io.EOF before the value was read completely.
+type capsuleReader struct {
+	r *io.LimitedReader
+}
+
+func (r *capsuleReader) Read(b []byte) (int, error) {
+	n, err := r.r.Read(b)
+	if err == io.EOF && r.r.N > 0 {
+		return n, io.ErrUnexpectedEOF
+	}
+	return n, err
+}
+
+// WriteCapsule writes a capsule to w.
+// To send capsules in the body of a request or a response, w is the request body or the http.ResponseWriter.
+func WriteCapsule(w io.Writer, ct CapsuleType, value []byte) error {
+	buf := &bytes.Buffer{}
+	buf.Grow(int(quicvarint.Len(uint64(ct))+quicvarint.Len(uint64(len(value)))) + len(value))
+	quicvarint.Write(buf, uint64(ct))
+	quicvarint.Write(buf, uint64(len(value)))
+	buf.Write(value)
+	_, err := w.Write(buf.Bytes())
+	return err
+}
@@ -0,0 +1,62 @@
+package http3
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+
+	"github.com/For-ACGN/quic-go/quicvarint"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Capsules", func() {
+	It("writes and parses capsules", func() {
+		buf := &bytes.Buffer{}
+		Expect(WriteCapsule(buf, CapsuleTypeDatagram, []byte("foobar"))).To(Succeed())
+		Expect(WriteCapsule(buf, 1337, nil)).To(Succeed())
+		Expect(buf.Bytes()[:2]).To(Equal([]byte{0, 6}))
+
+		ct, r, err := ParseCapsule(buf)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ct).To(Equal(CapsuleTypeDatagram))
+		Expect(ioutil.ReadAll(r)).To(Equal([]byte("foobar")))
+		ct, r, err = ParseCapsule(buf)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ct).To(BeEquivalentTo(1337))
+		Expect(ioutil.ReadAll(r)).To(BeEmpty())
+		_, _, err = ParseCapsule(buf)
+		Expect(err).To(MatchError(io.EOF))
+	})
+
+	It("parses capsules from readers that don't implement io.ByteReader", func() {
+		buf := &bytes.Buffer{}
+		Expect(WriteCapsule(buf, 42, []byte("foo"))).To(Succeed())
+		Expect(WriteCapsule(buf, 43, []byte("bar"))).To(Succeed())
+		r := struct{ io.Reader }{buf}
+		ct, val, err := ParseCapsule(r)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ct).To(BeEquivalentTo(42))
+		Expect(ioutil.ReadAll(val)).To(Equal([]byte("foo")))
+		ct, val, err = ParseCapsule(r)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ct).To(BeEquivalentTo(43))
+		Expect(ioutil.ReadAll(val)).To(Equal([]byte("bar")))
+	})
+
+	It("errors on truncated capsules", func() {
+		b := &bytes.Buffer{}
+		quicvarint.Write(b, 42)
+		quicvarint.Write(b, 10)
+		b.WriteString("foo")
+		data := b.Bytes()
+		_, r, err := ParseCapsule(bytes.NewReader(data))
+		Expect(err).ToNot(HaveOccurred())
+		_, err = ioutil.ReadAll(r)
+		Expect(err).To(MatchError(io.ErrUnexpectedEOF))
+		// the capsule length is missing
+		_, _, err = ParseCapsule(bytes.NewReader(data[:1]))
+		Expect(err).To(MatchError(io.ErrUnexpectedEOF))
+	})
+})
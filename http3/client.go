@@ -7,9 +7,12 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"strconv"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/For-ACGN/quic-go"
 	"github.com/For-ACGN/quic-go/internal/protocol"
@@ -19,6 +22,19 @@ import (
 	"github.com/marten-seemann/qpack"
 )
 
+// errRequestNotProcessed is returned when the server didn't process a request,
+// either because it rejected the request, or because it sent a GOAWAY frame.
+// It is safe to retry these requests on a new connection.
+var errRequestNotProcessed = errors.New("http3: request not processed by the server")
+
+// errNoStreamAvailable is returned by TryRoundTrip when the server's stream limit is reached.
+// The request was not sent, and can be sent on a different connection.
+var errNoStreamAvailable = errors.New("http3: no stream available")
+
+// errControlStreamNotOpen is returned when a frame can't be sent on the control stream,
+// since the control stream wasn't opened (yet).
+var errControlStreamNotOpen = errors.New("http3: control stream not open")
+
 // MethodGet0RTT allows a GET request to be sent using 0-RTT.
 // Note that 0-RTT data doesn't provide replay protection.
 const MethodGet0RTT = "GET_0RTT"
@@ -31,15 +47,20 @@ const (
 var defaultQuicConfig = &quic.Config{
 	MaxIncomingStreams: -1, // don't allow the server to create bidirectional streams
 	KeepAlive:          true,
-	Versions:           []protocol.VersionNumber{protocol.VersionTLS},
+	Versions:           []protocol.VersionNumber{protocol.Version1},
 }
 
 var dialAddr = quic.DialAddrEarly
 
 type roundTripperOpts struct {
-	DisableCompression bool
-	EnableDatagram     bool
-	MaxHeaderBytes     int64
+	DisableCompression    bool
+	EnableDatagram        bool
+	MaxHeaderBytes        int64
+	PushHandler           PushHandler
+	QPACKMaxTableCapacity uint64
+	QPACKBlockedStreams   uint64
+	EnableWebTransport    bool
+	IdleTimeout           time.Duration
 }
 
 // client is a HTTP3 client doing requests
@@ -50,15 +71,45 @@ type client struct {
 
 	dialOnce     sync.Once
 	dialer       func(network, addr string, tlsCfg *tls.Config, cfg *quic.Config) (quic.EarlySession, error)
-	handshakeErr error
+	handshakeErr error // protected by mutex until dialed is set
 
 	requestWriter *requestWriter
 
-	decoder *qpack.Decoder
+	encoder *qpackEncoder
+	decoder *qpackDecoder
 
 	hostname string
 	session  quic.EarlySession
 
+	controlStrMutex sync.Mutex
+	controlStr      quic.SendStream
+
+	// closed when the server's SETTINGS frame is received
+	settingsChan chan struct{}
+	settings     *settingsFrame
+
+	datagrams    *datagramManager
+	webTransport *webTransportManager // nil if WebTransport is not enabled
+
+	mutex sync.Mutex
+	// set when a GOAWAY frame is received
+	goingAway bool
+	// requests on streams with this ID or greater were not processed by the server
+	goAwayID protocol.StreamID
+	// closed (and replaced) every time a GOAWAY frame is received
+	goAwayChan     chan struct{}
+	activeRequests int
+	// set once the connection was dialed (whether dialing succeeded or not)
+	dialed bool
+	// set when the connection was closed after being idle for IdleTimeout
+	idleClosed bool
+	idleTimer  *time.Timer
+
+	// server push, only used if a PushHandler is set
+	pushMutex sync.Mutex
+	maxPushID uint64 // the push ID sent in the last MAX_PUSH_ID frame
+	pushes    map[uint64]*promisedPush
+
 	logger utils.Logger
 }
 
@@ -78,8 +129,15 @@ func newClient(
 	if len(quicConfig.Versions) != 1 {
 		return nil, errors.New("can only use a single QUIC version for dialing a HTTP/3 connection")
 	}
-	quicConfig.MaxIncomingStreams = -1 // don't allow any bidirectional streams
-	quicConfig.EnableDatagrams = opts.EnableDatagram
+	if opts.EnableWebTransport {
+		// The server opens bidirectional streams that belong to WebTransport sessions.
+		if quicConfig.MaxIncomingStreams < 0 {
+			quicConfig.MaxIncomingStreams = 0 // use the default value
+		}
+	} else {
+		quicConfig.MaxIncomingStreams = -1 // don't allow any bidirectional streams
+	}
+	quicConfig.EnableDatagrams = opts.EnableDatagram || opts.EnableWebTransport
 	logger := utils.DefaultLogger.WithPrefix("h3 client")
 
 	if tlsConf == nil {
@@ -90,18 +148,65 @@ func newClient(
 	// Replace existing ALPNs by H3
 	tlsConf.NextProtos = []string{versionToALPN(quicConfig.Versions[0])}
 
+	encoder := newQPACKEncoder(opts.QPACKMaxTableCapacity)
 	return &client{
 		hostname:      authorityAddr("https", hostname),
 		tlsConf:       tlsConf,
-		requestWriter: newRequestWriter(logger),
-		decoder:       qpack.NewDecoder(func(hf qpack.HeaderField) {}),
+		requestWriter: newRequestWriter(encoder, logger),
+		encoder:       encoder,
+		decoder:       newQPACKDecoder(opts.QPACKMaxTableCapacity, opts.QPACKBlockedStreams),
 		config:        quicConfig,
 		opts:          opts,
 		dialer:        dialer,
+		settingsChan:  make(chan struct{}),
+		goAwayChan:    make(chan struct{}),
+		maxPushID:     pushIDWindow - 1,
+		pushes:        make(map[uint64]*promisedPush),
 		logger:        logger,
 	}, nil
 }
 
+// dialSession dials the QUIC connection. It is called (once) before the first request is sent.
+func (c *client) dialSession() {
+	err := c.dial()
+	c.mutex.Lock()
+	c.handshakeErr = err
+	c.dialed = true
+	c.mutex.Unlock()
+}
+
+// handshake dials the QUIC connection (unless it was already dialed), and waits for the handshake to complete.
+func (c *client) handshake(ctx context.Context) error {
+	c.dialOnce.Do(c.dialSession)
+	if c.handshakeErr != nil {
+		return c.handshakeErr
+	}
+	select {
+	case <-c.session.HandshakeComplete().Done():
+		return nil
+	case <-c.session.Context().Done():
+		return errors.New("http3: connection closed during the handshake")
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// handshakeComplete says if the QUIC handshake completed.
+// Unlike handshake, it doesn't dial the connection.
+func (c *client) handshakeComplete() bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if !c.dialed || c.handshakeErr != nil {
+		return false
+	}
+	select {
+	case <-c.session.HandshakeComplete().Done():
+		return true
+	default:
+		return false
+	}
+}
+
 func (c *client) dial() error {
 	var err error
 	if c.dialer != nil {
@@ -112,6 +217,11 @@ func (c *client) dial() error {
 	if err != nil {
 		return err
 	}
+	c.datagrams = newDatagramManager(c.session, c.logger)
+	if c.opts.EnableWebTransport {
+		c.webTransport = newWebTransportManager(c.session, c.datagrams, c.logger)
+		go c.handleBidirectionalStreams()
+	}
 
 	// send the SETTINGs frame, using 0-RTT data, if possible
 	go func() {
@@ -134,9 +244,26 @@ func (c *client) setupSession() error {
 	buf := &bytes.Buffer{}
 	quicvarint.Write(buf, streamTypeControlStream)
 	// send the SETTINGS frame
-	(&settingsFrame{Datagram: c.opts.EnableDatagram}).Write(buf)
+	(&settingsFrame{
+		QPACKMaxTableCapacity: c.opts.QPACKMaxTableCapacity,
+		QPACKBlockedStreams:   c.opts.QPACKBlockedStreams,
+		Datagram:              c.opts.EnableDatagram || c.opts.EnableWebTransport,
+		WebTransport:          c.opts.EnableWebTransport,
+	}).Write(buf)
+	// allow the server to push, if we have a PushHandler
+	if c.opts.PushHandler != nil {
+		c.pushMutex.Lock()
+		(&maxPushIDFrame{PushID: c.maxPushID}).Write(buf)
+		c.pushMutex.Unlock()
+	}
+	c.controlStrMutex.Lock()
+	c.controlStr = str
 	_, err = str.Write(buf.Bytes())
-	return err
+	c.controlStrMutex.Unlock()
+	if err != nil {
+		return err
+	}
+	return openQPACKStreams(c.session, c.encoder, c.decoder)
 }
 
 func (c *client) handleUnidirectionalStreams() {
@@ -144,6 +271,8 @@ func (c *client) handleUnidirectionalStreams() {
 		str, err := c.session.AcceptUniStream(context.Background())
 		if err != nil {
 			c.logger.Debugf("accepting unidirectional stream failed: %s", err)
+			// The session was closed. Unblock requests waiting for QPACK encoder instructions.
+			c.decoder.close()
 			return
 		}
 
@@ -153,12 +282,37 @@ func (c *client) handleUnidirectionalStreams() {
 				c.logger.Debugf("reading stream type on stream %d failed: %s", str.StreamID(), err)
 				return
 			}
-			// We're only interested in the control stream here.
 			switch streamType {
 			case streamTypeControlStream:
 			case streamTypePushStream:
-				// We never increased the Push ID, so we don't expect any push streams.
-				c.session.CloseWithError(quic.ErrorCode(errorIDError), "")
+				if c.opts.PushHandler == nil {
+					// We never sent a MAX_PUSH_ID frame, so we don't expect any push streams.
+					c.session.CloseWithError(quic.ErrorCode(errorIDError), "")
+					return
+				}
+				c.handlePushStream(str)
+				return
+			case streamTypeQPACKEncoderStream:
+				err := c.decoder.readEncoderStream(str)
+				c.logger.Debugf("Reading the QPACK encoder stream failed: %s", err)
+				c.session.CloseWithError(quic.ErrorCode(qpackStreamErrorCode(err)), "")
+				return
+			case streamTypeQPACKDecoderStream:
+				err := c.encoder.readDecoderStream(str)
+				c.logger.Debugf("Reading the QPACK decoder stream failed: %s", err)
+				c.session.CloseWithError(quic.ErrorCode(qpackStreamErrorCode(err)), "")
+				return
+			case streamTypeWebTransportStream:
+				if c.webTransport == nil {
+					str.CancelRead(quic.ErrorCode(errorStreamCreationError))
+					return
+				}
+				id, err := quicvarint.Read(&byteReaderImpl{str})
+				if err != nil {
+					c.logger.Debugf("reading the session ID on stream %d failed: %s", str.StreamID(), err)
+					return
+				}
+				c.webTransport.handleUniStream(protocol.StreamID(id), str)
 				return
 			default:
 				str.CancelRead(quic.ErrorCode(errorStreamCreationError))
@@ -174,19 +328,221 @@ func (c *client) handleUnidirectionalStreams() {
 				c.session.CloseWithError(quic.ErrorCode(errorMissingSettings), "")
 				return
 			}
-			if !sf.Datagram {
-				return
-			}
 			// If datagram support was enabled on our side as well as on the server side,
 			// we can expect it to have been negotiated both on the transport and on the HTTP/3 layer.
 			// Note: ConnectionState() will block until the handshake is complete (relevant when using 0-RTT).
-			if c.opts.EnableDatagram && !c.session.ConnectionState().SupportsDatagrams {
-				c.session.CloseWithError(quic.ErrorCode(errorSettingsError), "missing QUIC Datagram support")
+			if sf.Datagram && (c.opts.EnableDatagram || c.opts.EnableWebTransport) {
+				if !c.session.ConnectionState().SupportsDatagrams {
+					c.session.CloseWithError(quic.ErrorCode(errorSettingsError), "missing QUIC Datagram support")
+					return
+				}
+				c.datagrams.start()
+			}
+			if err := c.encoder.setPeerSettings(sf.QPACKMaxTableCapacity, sf.QPACKBlockedStreams); err != nil {
+				c.logger.Debugf("Writing to the QPACK encoder stream failed: %s", err)
+			}
+			c.settings = sf
+			close(c.settingsChan)
+			c.handleControlStream(str)
+		}()
+	}
+}
+
+// handleBidirectionalStreams accepts the bidirectional streams opened by the server.
+// These are only allowed for WebTransport sessions.
+func (c *client) handleBidirectionalStreams() {
+	for {
+		str, err := c.session.AcceptStream(context.Background())
+		if err != nil {
+			c.logger.Debugf("accepting bidirectional stream failed: %s", err)
+			return
+		}
+		go func() {
+			f, err := parseNextFrame(str)
+			if err != nil {
+				c.logger.Debugf("reading the first frame on stream %d failed: %s", str.StreamID(), err)
+				str.CancelRead(quic.ErrorCode(errorFrameError))
+				str.CancelWrite(quic.ErrorCode(errorFrameError))
+				return
+			}
+			wf, ok := f.(*webTransportStreamFrame)
+			if !ok {
+				c.session.CloseWithError(quic.ErrorCode(errorStreamCreationError), "")
+				return
 			}
+			c.webTransport.handleStream(wf.SessionID, str)
 		}()
 	}
 }
 
+// waitForSettings waits until the server's SETTINGS frame was received.
+func (c *client) waitForSettings(ctx context.Context) (*settingsFrame, error) {
+	select {
+	case <-c.settingsChan:
+		return c.settings, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-c.session.Context().Done():
+		return nil, errors.New("http3: session closed before receiving the server's SETTINGS")
+	}
+}
+
+// handleControlStream processes the frames the server sends on the control stream after the SETTINGS frame.
+func (c *client) handleControlStream(str quic.ReceiveStream) {
+	for {
+		f, err := parseNextFrame(str)
+		if err != nil {
+			// The server must not close the control stream.
+			// If the session was already closed, this is a no-op.
+			code := errorFrameError
+			var serr quic.StreamError
+			if err == io.EOF || errors.As(err, &serr) {
+				code = errorClosedCriticalStream
+			}
+			c.session.CloseWithError(quic.ErrorCode(code), "")
+			return
+		}
+		switch f := f.(type) {
+		case *goAwayFrame:
+			if err := c.handleGoAway(f.StreamID); err != nil {
+				c.session.CloseWithError(quic.ErrorCode(errorIDError), err.Error())
+				return
+			}
+		case *cancelPushFrame:
+			if err := c.handleCancelPush(f.PushID); err != nil {
+				c.session.CloseWithError(quic.ErrorCode(errorIDError), err.Error())
+				return
+			}
+		default:
+			c.session.CloseWithError(quic.ErrorCode(errorFrameUnexpected), "")
+			return
+		}
+	}
+}
+
+func (c *client) handleGoAway(id protocol.StreamID) error {
+	if id.InitiatedBy() != protocol.PerspectiveClient || id.Type() != protocol.StreamTypeBidi {
+		return fmt.Errorf("invalid stream ID in GOAWAY frame: %d", id)
+	}
+	c.mutex.Lock()
+	if c.goingAway && id > c.goAwayID {
+		c.mutex.Unlock()
+		return fmt.Errorf("GOAWAY stream ID increased from %d to %d", c.goAwayID, id)
+	}
+	c.logger.Debugf("Received GOAWAY frame. Requests on stream %d and above were not processed.", id)
+	c.goingAway = true
+	c.goAwayID = id
+	close(c.goAwayChan)
+	c.goAwayChan = make(chan struct{})
+	idle := c.activeRequests == 0
+	c.mutex.Unlock()
+
+	if idle {
+		c.session.CloseWithError(quic.ErrorCode(errorNoError), "")
+	}
+	return nil
+}
+
+// Draining says if the server sent a GOAWAY frame.
+// New requests must be sent on a new connection.
+func (c *client) Draining() bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.goingAway
+}
+
+// Closed says if the connection was closed, or if it couldn't be established.
+// Closed clients must not be used for new requests.
+func (c *client) Closed() bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if !c.dialed {
+		return false
+	}
+	if c.handshakeErr != nil || c.idleClosed {
+		return true
+	}
+	select {
+	case <-c.session.Context().Done():
+		return true
+	default:
+		return false
+	}
+}
+
+// ActiveRequests returns the number of requests in flight.
+func (c *client) ActiveRequests() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.activeRequests
+}
+
+// startRequest is called before a request is sent.
+// It returns false if the server sent a GOAWAY frame, or if the connection was closed due to the idle timeout.
+func (c *client) startRequest() bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.goingAway || c.idleClosed {
+		return false
+	}
+	c.activeRequests++
+	if c.idleTimer != nil {
+		c.idleTimer.Stop()
+		c.idleTimer = nil
+	}
+	return true
+}
+
+// finishRequest is called once a request is done.
+// Once the server sent a GOAWAY frame, the connection is closed after the last request completes.
+// Otherwise, the idle timer is started after the last request completes.
+func (c *client) finishRequest() {
+	c.mutex.Lock()
+	c.activeRequests--
+	closeSession := c.goingAway && c.activeRequests == 0
+	if !c.goingAway && c.activeRequests == 0 && c.opts.IdleTimeout > 0 {
+		c.idleTimer = time.AfterFunc(c.opts.IdleTimeout, c.closeIdle)
+	}
+	c.mutex.Unlock()
+
+	if closeSession {
+		c.session.CloseWithError(quic.ErrorCode(errorNoError), "")
+	}
+}
+
+// closeIdle closes the connection, if no request was sent since the idle timer was started.
+func (c *client) closeIdle() {
+	c.mutex.Lock()
+	if c.activeRequests > 0 || c.idleClosed {
+		c.mutex.Unlock()
+		return
+	}
+	c.idleClosed = true
+	c.mutex.Unlock()
+
+	c.logger.Debugf("Closing idle connection to %s.", c.hostname)
+	c.session.CloseWithError(quic.ErrorCode(errorNoError), "")
+}
+
+// notProcessed says if the server didn't process a request sent on stream str.
+// This is the case if the server rejected the request (using H3_REQUEST_REJECTED),
+// or if the stream ID is not smaller than the stream ID in the GOAWAY frame.
+func (c *client) notProcessed(str quic.Stream, err error) bool {
+	var serr quic.StreamError
+	if errors.As(err, &serr) && serr.ErrorCode() == quic.ErrorCode(errorRequestRejected) {
+		return true
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.goingAway && str.StreamID() >= c.goAwayID
+}
+
+func (c *client) goAwayNotify() <-chan struct{} {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.goAwayChan
+}
+
 func (c *client) Close() error {
 	if c.session == nil {
 		return nil
@@ -201,20 +557,33 @@ func (c *client) maxHeaderBytes() uint64 {
 	return uint64(c.opts.MaxHeaderBytes)
 }
 
-// RoundTrip executes a request and returns a response
+// RoundTrip executes a request and returns a response.
+// If the server's stream limit is reached, it blocks until a stream can be opened.
 func (c *client) RoundTrip(req *http.Request) (*http.Response, error) {
+	return c.roundTrip(req, true)
+}
+
+// TryRoundTrip is like RoundTrip, but it returns errNoStreamAvailable (without sending the request)
+// if the server's stream limit is reached.
+func (c *client) TryRoundTrip(req *http.Request) (*http.Response, error) {
+	return c.roundTrip(req, false)
+}
+
+func (c *client) roundTrip(req *http.Request, waitForStream bool) (*http.Response, error) {
 	if authorityAddr("https", hostnameFromRequest(req)) != c.hostname {
 		return nil, fmt.Errorf("http3 client BUG: RoundTrip called for the wrong client (expected %s, got %s)", c.hostname, req.Host)
 	}
 
-	c.dialOnce.Do(func() {
-		c.handshakeErr = c.dial()
-	})
+	c.dialOnce.Do(c.dialSession)
 
 	if c.handshakeErr != nil {
 		return nil, c.handshakeErr
 	}
 
+	if !c.startRequest() {
+		return nil, errRequestNotProcessed
+	}
+
 	// Immediately send out this request, if this is a 0-RTT request.
 	if req.Method == MethodGet0RTT {
 		req.Method = http.MethodGet
@@ -223,31 +592,91 @@ func (c *client) RoundTrip(req *http.Request) (*http.Response, error) {
 		select {
 		case <-c.session.HandshakeComplete().Done():
 		case <-req.Context().Done():
+			c.finishRequest()
 			return nil, req.Context().Err()
 		}
 	}
 
-	str, err := c.session.OpenStreamSync(req.Context())
+	if extendedConnectProtocol(req) != "" {
+		settings, err := c.waitForSettings(req.Context())
+		if err != nil {
+			c.finishRequest()
+			return nil, err
+		}
+		if !settings.ExtendedConnect {
+			c.finishRequest()
+			return nil, errors.New("http3: server didn't enable extended CONNECT")
+		}
+	}
+
+	var str quic.Stream
+	var err error
+	if waitForStream {
+		str, err = c.session.OpenStreamSync(req.Context())
+	} else {
+		str, err = c.session.OpenStream()
+		if nerr, ok := err.(net.Error); ok && nerr.Temporary() {
+			c.finishRequest()
+			return nil, errNoStreamAvailable
+		}
+	}
 	if err != nil {
+		c.finishRequest()
 		return nil, err
 	}
+	// The priority also applies to sending the request body.
+	if _, ok := req.Header[PriorityHeader]; ok {
+		str.SetPriority(ParsePriority(strings.Join(req.Header[PriorityHeader], ",")).streamPriority())
+	}
+	var datagrams *datagramFlow
+	if c.opts.EnableDatagram {
+		datagrams = c.datagrams.register(str.StreamID())
+	}
 
 	// Request Cancellation:
 	// This go routine keeps running even after RoundTrip() returns.
 	// It is shut down when the application is done processing the body.
+	// If the server sends a GOAWAY frame, and this request wasn't processed, it is canceled as well.
 	reqDone := make(chan struct{})
 	go func() {
-		select {
-		case <-req.Context().Done():
-			str.CancelWrite(quic.ErrorCode(errorRequestCanceled))
-			str.CancelRead(quic.ErrorCode(errorRequestCanceled))
-		case <-reqDone:
+		defer c.finishRequest()
+		if datagrams != nil {
+			defer c.datagrams.unregister(str.StreamID())
+		}
+		for {
+			goAway := c.goAwayNotify()
+			if c.notProcessed(str, nil) {
+				str.CancelWrite(quic.ErrorCode(errorRequestCanceled))
+				str.CancelRead(quic.ErrorCode(errorRequestCanceled))
+				<-reqDone
+				return
+			}
+			select {
+			case <-req.Context().Done():
+				// Don't reset the stream if the request was already completed,
+				// e.g. if the context is canceled right after the response body was closed.
+				select {
+				case <-reqDone:
+					return
+				default:
+				}
+				str.CancelWrite(quic.ErrorCode(errorRequestCanceled))
+				str.CancelRead(quic.ErrorCode(errorRequestCanceled))
+				return
+			case <-goAway:
+			case <-reqDone:
+				return
+			}
 		}
 	}()
 
-	rsp, rerr := c.doRequest(req, str, reqDone)
+	rsp, rerr := c.doRequest(req, str, reqDone, datagrams)
 	if rerr.err != nil { // if any error occurred
 		close(reqDone)
+		if c.notProcessed(str, rerr.err) {
+			str.CancelWrite(quic.ErrorCode(errorRequestCanceled))
+			return nil, errRequestNotProcessed
+		}
 		if rerr.streamErr != 0 { // if it was a stream error
 			str.CancelWrite(quic.ErrorCode(rerr.streamErr))
 		}
@@ -266,6 +695,7 @@ func (c *client) doRequest(
 	req *http.Request,
 	str quic.Stream,
 	reqDone chan struct{},
+	datagrams *datagramFlow,
 ) (*http.Response, requestError) {
 	var requestGzip bool
 	if !c.opts.DisableCompression && req.Method != "HEAD" && req.Header.Get("Accept-Encoding") == "" && req.Header.Get("Range") == "" {
@@ -275,13 +705,137 @@ func (c *client) doRequest(
 		return nil, newStreamError(errorInternalError, err)
 	}
 
-	frame, err := parseNextFrame(str)
+	decode := c.decoder.streamDecoder(req.Context(), str.StreamID(), c.session)
+	res, rerr := c.readResponse(str, reqDone, decode, func(f *pushPromiseFrame) error {
+		return c.handlePushPromise(f, str, decode)
+	})
+	if rerr.err != nil {
+		return nil, rerr
+	}
+	res.Body.(*body).datagrams = datagrams
+	res.Body.(*body).updatePriority = func(p Priority) error {
+		str.SetPriority(p.streamPriority())
+		return c.writeControlFrame(&priorityUpdateFrame{ElementID: uint64(str.StreamID()), Priority: p.String()})
+	}
+	if requestGzip && res.Header.Get("Content-Encoding") == "gzip" {
+		res.Header.Del("Content-Encoding")
+		res.Header.Del("Content-Length")
+		res.ContentLength = -1
+		res.Body = newGzipReader(res.Body)
+		res.Uncompressed = true
+	}
+	return res, requestError{}
+}
+
+// dialWebTransport sends a WebTransport request (an extended CONNECT request).
+// If the server responds with a 2xx status code, a WebTransport session is established on the request stream.
+// The response body is always empty.
+func (c *client) dialWebTransport(req *http.Request) (*http.Response, *WebTransportSession, error) {
+	if !c.opts.EnableWebTransport {
+		return nil, nil, errors.New("http3: WebTransport not enabled")
+	}
+	c.dialOnce.Do(c.dialSession)
+	if c.handshakeErr != nil {
+		return nil, nil, c.handshakeErr
+	}
+	if !c.startRequest() {
+		return nil, nil, errRequestNotProcessed
+	}
+
+	ctx := req.Context()
+	// The SETTINGS frame is only received after the handshake completed.
+	settings, err := c.waitForSettings(ctx)
+	if err != nil {
+		c.finishRequest()
+		return nil, nil, err
+	}
+	if !settings.ExtendedConnect || !settings.WebTransport {
+		c.finishRequest()
+		return nil, nil, errors.New("http3: server didn't enable WebTransport")
+	}
+	str, err := c.session.OpenStreamSync(ctx)
 	if err != nil {
-		return nil, newStreamError(errorFrameError, err)
+		c.finishRequest()
+		return nil, nil, err
 	}
-	hf, ok := frame.(*headersFrame)
-	if !ok {
-		return nil, newConnError(errorFrameUnexpected, errors.New("expected first frame to be a HEADERS frame"))
+	// Only the HEADERS frame is sent. Closing the stream would close the session.
+	buf := &bytes.Buffer{}
+	if err := c.requestWriter.writeHeaders(buf, str.StreamID(), req, false, ""); err != nil {
+		str.CancelWrite(quic.ErrorCode(errorInternalError))
+		c.finishRequest()
+		return nil, nil, err
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			str.CancelWrite(quic.ErrorCode(errorRequestCanceled))
+			str.CancelRead(quic.ErrorCode(errorRequestCanceled))
+		case <-done:
+		}
+	}()
+
+	var rsp *http.Response
+	rerr := requestError{}
+	if _, err := str.Write(buf.Bytes()); err != nil {
+		rerr = newStreamError(errorInternalError, err)
+	} else {
+		decode := c.decoder.streamDecoder(ctx, str.StreamID(), c.session)
+		rsp, rerr = c.readResponse(str, nil, decode, nil)
+	}
+	if rerr.err != nil {
+		c.finishRequest()
+		if c.notProcessed(str, rerr.err) {
+			str.CancelWrite(quic.ErrorCode(errorRequestCanceled))
+			return nil, nil, errRequestNotProcessed
+		}
+		if rerr.streamErr != 0 {
+			str.CancelWrite(quic.ErrorCode(rerr.streamErr))
+		}
+		if rerr.connErr != 0 {
+			c.session.CloseWithError(quic.ErrorCode(rerr.connErr), rerr.err.Error())
+		}
+		return nil, nil, rerr.err
+	}
+	rsp.Body = http.NoBody
+	rsp.Request = req
+	if rsp.StatusCode < 200 || rsp.StatusCode > 299 {
+		str.CancelRead(quic.ErrorCode(errorNoError))
+		str.Close()
+		c.finishRequest()
+		return rsp, nil, fmt.Errorf("http3: WebTransport request failed: %s", rsp.Status)
+	}
+	return rsp, c.webTransport.newSession(str, c.finishRequest), nil
+}
+
+// readResponse reads the response from a request stream or from a push stream.
+// The field sections are decoded using decode.
+// PUSH_PROMISE frames are passed to onPushPromise. They are not allowed if onPushPromise is nil.
+func (c *client) readResponse(
+	str quic.ReceiveStream,
+	reqDone chan struct{},
+	decode func([]byte) ([]qpack.HeaderField, error),
+	onPushPromise func(*pushPromiseFrame) error,
+) (*http.Response, requestError) {
+	var hf *headersFrame
+	for hf == nil {
+		frame, err := parseNextFrame(str)
+		if err != nil {
+			return nil, newStreamError(errorFrameError, err)
+		}
+		if pf, ok := frame.(*pushPromiseFrame); ok && onPushPromise != nil {
+			if err := onPushPromise(pf); err != nil {
+				return nil, requestError{err: err}
+			}
+			continue
+		}
+		var ok bool
+		hf, ok = frame.(*headersFrame)
+		if !ok {
+			return nil, newConnError(errorFrameUnexpected, errors.New("expected first frame to be a HEADERS frame"))
+		}
 	}
 	if hf.Length > c.maxHeaderBytes() {
 		return nil, newStreamError(errorFrameError, fmt.Errorf("HEADERS frame too large: %d bytes (max: %d)", hf.Length, c.maxHeaderBytes()))
@@ -290,10 +844,10 @@ func (c *client) doRequest(
 	if _, err := io.ReadFull(str, headerBlock); err != nil {
 		return nil, newStreamError(errorRequestIncomplete, err)
 	}
-	hfs, err := c.decoder.DecodeFull(headerBlock)
+	hfs, err := decode(headerBlock)
 	if err != nil {
-		// TODO: use the right error code
-		return nil, newConnError(errorGeneralProtocolError, err)
+		// If decoding failed due to a QPACK error, the connection was closed.
+		return nil, newStreamError(errorRequestCanceled, err)
 	}
 
 	connState := qtls.ToTLSConnectionState(c.session.ConnectionState().TLS)
@@ -316,18 +870,14 @@ func (c *client) doRequest(
 			res.Header.Add(hf.Name, hf.Value)
 		}
 	}
+	res.Trailer = declaredTrailers(res.Header)
 	respBody := newResponseBody(str, reqDone, func() {
 		c.session.CloseWithError(quic.ErrorCode(errorFrameUnexpected), "")
 	})
-	if requestGzip && res.Header.Get("Content-Encoding") == "gzip" {
-		res.Header.Del("Content-Encoding")
-		res.Header.Del("Content-Length")
-		res.ContentLength = -1
-		res.Body = newGzipReader(respBody)
-		res.Uncompressed = true
-	} else {
-		res.Body = respBody
+	respBody.onPushPromise = onPushPromise
+	respBody.onTrailers = func(hf *headersFrame) error {
+		return readTrailers(str, hf, decode, c.maxHeaderBytes(), &res.Trailer)
 	}
-
+	res.Body = respBody
 	return res, requestError{}
 }
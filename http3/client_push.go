@@ -0,0 +1,280 @@
+package http3
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+
+	"github.com/For-ACGN/quic-go"
+	"github.com/For-ACGN/quic-go/quicvarint"
+	"github.com/marten-seemann/qpack"
+)
+
+// A PushHandler handles resources pushed by the server.
+// Server push is only enabled if the RoundTripper has a PushHandler.
+type PushHandler interface {
+	// HandlePromise is called when the server promises to push a resource.
+	// The push is canceled if HandlePromise returns false.
+	HandlePromise(promised *http.Request) bool
+	// HandlePush is called with the response for an accepted promise.
+	// The PushHandler is responsible for closing the response body.
+	HandlePush(promised *http.Request, rsp *http.Response)
+}
+
+// pushIDWindow is the number of push IDs that the server is allowed to use at the same time.
+// Every time a push is completed or canceled, the client allows the server to use another push ID.
+const pushIDWindow = 16
+
+type promisedPush struct {
+	req      *http.Request      // set once the PUSH_PROMISE frame is received
+	str      quic.ReceiveStream // set once the push stream is received
+	accepted bool               // the PushHandler accepted the promise
+	canceled bool
+	done     bool // the push ID was released
+}
+
+// writeControlFrame sends a frame on our control stream.
+func (c *client) writeControlFrame(f interface{ Write(*bytes.Buffer) }) error {
+	buf := &bytes.Buffer{}
+	f.Write(buf)
+	c.controlStrMutex.Lock()
+	defer c.controlStrMutex.Unlock()
+	if c.controlStr == nil {
+		return errControlStreamNotOpen
+	}
+	if _, err := c.controlStr.Write(buf.Bytes()); err != nil {
+		c.logger.Debugf("Writing to the control stream failed: %s", err)
+		return err
+	}
+	return nil
+}
+
+// getPush gets the state of a push ID. It creates a new state if necessary.
+// It must be called with the pushMutex held.
+func (c *client) getPush(pushID uint64) *promisedPush {
+	p, ok := c.pushes[pushID]
+	if !ok {
+		p = &promisedPush{}
+		c.pushes[pushID] = p
+	}
+	return p
+}
+
+// handlePushPromise handles a PUSH_PROMISE frame received on the request stream str.
+// The field section is decoded using decode.
+// If it returns an error, the connection was closed, or the request was canceled.
+func (c *client) handlePushPromise(f *pushPromiseFrame, str io.Reader, decode func([]byte) ([]qpack.HeaderField, error)) error {
+	if c.opts.PushHandler == nil {
+		// We never sent a MAX_PUSH_ID frame, so the server is not allowed to push.
+		err := errors.New("received PUSH_PROMISE frame, but server push is disabled")
+		c.session.CloseWithError(quic.ErrorCode(errorIDError), err.Error())
+		return err
+	}
+	c.pushMutex.Lock()
+	maxPushID := c.maxPushID
+	c.pushMutex.Unlock()
+	if f.PushID > maxPushID {
+		err := fmt.Errorf("push ID %d exceeds the limit (%d)", f.PushID, maxPushID)
+		c.session.CloseWithError(quic.ErrorCode(errorIDError), err.Error())
+		return err
+	}
+	if f.Length > c.maxHeaderBytes() {
+		err := fmt.Errorf("PUSH_PROMISE frame too large: %d bytes (max: %d)", f.Length, c.maxHeaderBytes())
+		c.session.CloseWithError(quic.ErrorCode(errorFrameError), err.Error())
+		return err
+	}
+	headerBlock := make([]byte, f.Length)
+	if _, err := io.ReadFull(str, headerBlock); err != nil {
+		return err
+	}
+	hfs, err := decode(headerBlock)
+	if err != nil {
+		return err
+	}
+	req, err := requestFromHeaders(hfs)
+	if err == nil && req.Method != http.MethodGet && req.Method != http.MethodHead {
+		err = fmt.Errorf("promised request with method %s", req.Method)
+	}
+	if err != nil {
+		// TODO: use the right error code
+		c.session.CloseWithError(quic.ErrorCode(errorGeneralProtocolError), err.Error())
+		return err
+	}
+	req.URL.Scheme = "https"
+	req.URL.Host = req.Host
+	req.RequestURI = ""
+	req.TLS = nil
+	c.promise(f.PushID, req)
+	return nil
+}
+
+func (c *client) promise(pushID uint64, req *http.Request) {
+	c.pushMutex.Lock()
+	p := c.getPush(pushID)
+	// The server may promise the same push ID on multiple request streams.
+	if p.req != nil || p.canceled {
+		c.pushMutex.Unlock()
+		return
+	}
+	p.req = req
+	c.pushMutex.Unlock()
+
+	if !c.opts.PushHandler.HandlePromise(req) {
+		if c.cancelPush(pushID) {
+			c.writeControlFrame(&cancelPushFrame{PushID: pushID})
+		}
+		return
+	}
+
+	c.pushMutex.Lock()
+	if p.canceled {
+		c.pushMutex.Unlock()
+		return
+	}
+	p.accepted = true
+	str := p.str
+	c.pushMutex.Unlock()
+	if str != nil {
+		go c.deliverPush(pushID, req, str)
+	}
+}
+
+// handlePushStream handles a push stream, after the stream type was read.
+func (c *client) handlePushStream(str quic.ReceiveStream) {
+	pushID, err := quicvarint.Read(&byteReaderImpl{str})
+	if err != nil {
+		c.logger.Debugf("reading push ID on stream %d failed: %s", str.StreamID(), err)
+		return
+	}
+	c.pushMutex.Lock()
+	if pushID > c.maxPushID {
+		c.pushMutex.Unlock()
+		c.session.CloseWithError(quic.ErrorCode(errorIDError), fmt.Sprintf("push ID %d exceeds the limit", pushID))
+		return
+	}
+	p := c.getPush(pushID)
+	if p.str != nil {
+		c.pushMutex.Unlock()
+		c.session.CloseWithError(quic.ErrorCode(errorIDError), fmt.Sprintf("duplicate push stream for push ID %d", pushID))
+		return
+	}
+	p.str = str
+	if p.canceled {
+		delete(c.pushes, pushID)
+		c.pushMutex.Unlock()
+		str.CancelRead(quic.ErrorCode(errorRequestCanceled))
+		return
+	}
+	accepted := p.accepted
+	req := p.req
+	c.pushMutex.Unlock()
+	if accepted {
+		c.deliverPush(pushID, req, str)
+	}
+}
+
+// deliverPush reads the response from the push stream and passes it to the PushHandler.
+func (c *client) deliverPush(pushID uint64, req *http.Request, str quic.ReceiveStream) {
+	reqDone := make(chan struct{})
+	decode := c.decoder.streamDecoder(context.Background(), str.StreamID(), c.session)
+	rsp, rerr := c.readResponse(str, reqDone, decode, nil)
+	if rerr.err != nil {
+		c.logger.Debugf("Reading the pushed response failed: %s", rerr.err)
+		if rerr.streamErr != 0 {
+			str.CancelRead(quic.ErrorCode(rerr.streamErr))
+		}
+		if rerr.connErr != 0 {
+			c.session.CloseWithError(quic.ErrorCode(rerr.connErr), rerr.err.Error())
+		}
+		c.finishPush(pushID)
+		return
+	}
+	rsp.Request = req
+	rsp.Body.(*body).updatePriority = func(p Priority) error {
+		return c.writeControlFrame(&priorityUpdateFrame{Push: true, ElementID: pushID, Priority: p.String()})
+	}
+	go func() {
+		<-reqDone
+		c.finishPush(pushID)
+	}()
+	c.opts.PushHandler.HandlePush(req, rsp)
+}
+
+// forgetCanceledPushes limits the number of canceled pushes that are kept track of.
+// The server is not required to open the push stream after a push was canceled,
+// so only the pushIDWindow most recent canceled pushes whose push stream wasn't received yet are kept.
+// It must be called with the pushMutex held.
+func (c *client) forgetCanceledPushes() {
+	var canceled []uint64
+	for id, p := range c.pushes {
+		if p.done && p.str == nil {
+			canceled = append(canceled, id)
+		}
+	}
+	if len(canceled) <= pushIDWindow {
+		return
+	}
+	sort.Slice(canceled, func(i, j int) bool { return canceled[i] < canceled[j] })
+	for _, id := range canceled[:len(canceled)-pushIDWindow] {
+		delete(c.pushes, id)
+	}
+}
+
+// handleCancelPush handles a CANCEL_PUSH frame sent by the server.
+func (c *client) handleCancelPush(pushID uint64) error {
+	c.pushMutex.Lock()
+	maxPushID := c.maxPushID
+	c.pushMutex.Unlock()
+	if c.opts.PushHandler == nil || pushID > maxPushID {
+		return fmt.Errorf("CANCEL_PUSH for push ID %d exceeds the limit", pushID)
+	}
+	c.cancelPush(pushID)
+	return nil
+}
+
+// cancelPush cancels a push. If the push stream was already received, reading from it is canceled.
+// It returns false if the push was already canceled.
+func (c *client) cancelPush(pushID uint64) bool {
+	c.pushMutex.Lock()
+	p := c.getPush(pushID)
+	if p.canceled {
+		c.pushMutex.Unlock()
+		return false
+	}
+	p.canceled = true
+	str := p.str
+	c.pushMutex.Unlock()
+
+	if str != nil {
+		str.CancelRead(quic.ErrorCode(errorRequestCanceled))
+	}
+	c.finishPush(pushID)
+	return true
+}
+
+// finishPush is called once a push is canceled, or once the pushed response was consumed.
+// It allows the server to use another push ID.
+func (c *client) finishPush(pushID uint64) {
+	c.pushMutex.Lock()
+	p, ok := c.pushes[pushID]
+	if !ok || p.done {
+		c.pushMutex.Unlock()
+		return
+	}
+	p.done = true
+	// If the push stream was already received, we don't expect any other frames or streams for this push ID.
+	if p.str != nil {
+		delete(c.pushes, pushID)
+	} else {
+		c.forgetCanceledPushes()
+	}
+	c.maxPushID++
+	maxPushID := c.maxPushID
+	c.pushMutex.Unlock()
+
+	c.writeControlFrame(&maxPushIDFrame{PushID: maxPushID})
+}
@@ -0,0 +1,246 @@
+package http3
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/For-ACGN/quic-go"
+	mockquic "github.com/For-ACGN/quic-go/internal/mocks/quic"
+	"github.com/For-ACGN/quic-go/quicvarint"
+
+	"github.com/golang/mock/gomock"
+	"github.com/marten-seemann/qpack"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type testPushHandler struct {
+	accept   bool
+	promised chan *http.Request
+	pushed   chan *http.Response
+}
+
+func (h *testPushHandler) HandlePromise(req *http.Request) bool {
+	h.promised <- req
+	return h.accept
+}
+
+func (h *testPushHandler) HandlePush(_ *http.Request, rsp *http.Response) {
+	h.pushed <- rsp
+}
+
+var _ = Describe("Client Push", func() {
+	var (
+		cl         *client
+		sess       *mockquic.MockEarlySession
+		handler    *testPushHandler
+		controlBuf *bytes.Buffer
+	)
+
+	BeforeEach(func() {
+		handler = &testPushHandler{
+			accept:   true,
+			promised: make(chan *http.Request, 10),
+			pushed:   make(chan *http.Response, 10),
+		}
+		var err error
+		cl, err = newClient("www.example.com:443", nil, &roundTripperOpts{PushHandler: handler}, nil, nil)
+		Expect(err).ToNot(HaveOccurred())
+		sess = mockquic.NewMockEarlySession(mockCtrl)
+		sess.EXPECT().ConnectionState().Return(quic.ConnectionState{}).AnyTimes()
+		cl.session = sess
+		controlBuf = &bytes.Buffer{}
+		controlStr := mockquic.NewMockStream(mockCtrl)
+		controlStr.EXPECT().Write(gomock.Any()).DoAndReturn(controlBuf.Write).AnyTimes()
+		cl.controlStr = controlStr
+	})
+
+	encodePromise := func(pushID uint64, path string) []byte {
+		headers := &bytes.Buffer{}
+		enc := qpack.NewEncoder(headers)
+		enc.WriteField(qpack.HeaderField{Name: ":method", Value: http.MethodGet})
+		enc.WriteField(qpack.HeaderField{Name: ":scheme", Value: "https"})
+		enc.WriteField(qpack.HeaderField{Name: ":authority", Value: "www.example.com"})
+		enc.WriteField(qpack.HeaderField{Name: ":path", Value: path})
+		buf := &bytes.Buffer{}
+		(&pushPromiseFrame{PushID: pushID, Length: uint64(headers.Len())}).Write(buf)
+		buf.Write(headers.Bytes())
+		return buf.Bytes()
+	}
+
+	// pushStream creates a push stream carrying a response, after the stream type was read
+	pushStream := func(pushID uint64, body string) *mockquic.MockStream {
+		buf := &bytes.Buffer{}
+		quicvarint.Write(buf, pushID)
+		headers := &bytes.Buffer{}
+		qpack.NewEncoder(headers).WriteField(qpack.HeaderField{Name: ":status", Value: "200"})
+		(&headersFrame{Length: uint64(headers.Len())}).Write(buf)
+		buf.Write(headers.Bytes())
+		(&dataFrame{Length: uint64(len(body))}).Write(buf)
+		buf.WriteString(body)
+		str := mockquic.NewMockStream(mockCtrl)
+		str.EXPECT().Read(gomock.Any()).DoAndReturn(buf.Read).AnyTimes()
+		str.EXPECT().StreamID().AnyTimes()
+		return str
+	}
+
+	// receivePromise parses a PUSH_PROMISE frame
+	receivePromise := func(data []byte) error {
+		r := bytes.NewReader(data)
+		f, err := parseNextFrame(r)
+		Expect(err).ToNot(HaveOccurred())
+		return cl.handlePushPromise(f.(*pushPromiseFrame), r, qpack.NewDecoder(nil).DecodeFull)
+	}
+
+	// controlFrames parses the frames sent on the control stream
+	controlFrames := func() []frame {
+		cl.controlStrMutex.Lock()
+		defer cl.controlStrMutex.Unlock()
+		var frames []frame
+		for controlBuf.Len() > 0 {
+			f, err := parseNextFrame(controlBuf)
+			Expect(err).ToNot(HaveOccurred())
+			frames = append(frames, f)
+		}
+		return frames
+	}
+
+	It("sends a MAX_PUSH_ID frame on the control stream", func() {
+		buf := &bytes.Buffer{}
+		str := mockquic.NewMockStream(mockCtrl)
+		str.EXPECT().Write(gomock.Any()).DoAndReturn(buf.Write)
+		sess.EXPECT().OpenUniStream().Return(str, nil)
+		Expect(cl.setupSession()).To(Succeed())
+		streamType, err := quicvarint.Read(buf)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(streamType).To(BeEquivalentTo(streamTypeControlStream))
+		f, err := parseNextFrame(buf)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(f).To(BeAssignableToTypeOf(&settingsFrame{}))
+		f, err = parseNextFrame(buf)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(f).To(Equal(&maxPushIDFrame{PushID: pushIDWindow - 1}))
+	})
+
+	It("delivers accepted pushes, and releases the push ID", func() {
+		Expect(receivePromise(encodePromise(3, "/style.css"))).To(Succeed())
+		var req *http.Request
+		Expect(handler.promised).To(Receive(&req))
+		Expect(req.Method).To(Equal(http.MethodGet))
+		Expect(req.URL.String()).To(Equal("https://www.example.com/style.css"))
+		cl.handlePushStream(pushStream(3, "body { }"))
+		var rsp *http.Response
+		Eventually(handler.pushed).Should(Receive(&rsp))
+		Expect(rsp.StatusCode).To(Equal(200))
+		Expect(rsp.Request).To(Equal(req))
+		data, err := ioutil.ReadAll(rsp.Body)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(data)).To(Equal("body { }"))
+		Eventually(controlFrames).Should(Equal([]frame{&maxPushIDFrame{PushID: pushIDWindow}}))
+		cl.pushMutex.Lock()
+		defer cl.pushMutex.Unlock()
+		Expect(cl.pushes).To(BeEmpty())
+	})
+
+	It("delivers pushes when the push stream arrives before the PUSH_PROMISE", func() {
+		cl.handlePushStream(pushStream(0, "foobar"))
+		Consistently(handler.pushed).ShouldNot(Receive())
+		Expect(receivePromise(encodePromise(0, "/foo"))).To(Succeed())
+		var rsp *http.Response
+		Eventually(handler.pushed).Should(Receive(&rsp))
+		data, err := ioutil.ReadAll(rsp.Body)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(data)).To(Equal("foobar"))
+	})
+
+	It("cancels rejected pushes", func() {
+		handler.accept = false
+		Expect(receivePromise(encodePromise(1, "/style.css"))).To(Succeed())
+		Expect(controlFrames()).To(Equal([]frame{
+			&maxPushIDFrame{PushID: pushIDWindow},
+			&cancelPushFrame{PushID: 1},
+		}))
+		// the push stream might still arrive
+		str := pushStream(1, "body { }")
+		str.EXPECT().CancelRead(quic.ErrorCode(errorRequestCanceled))
+		cl.handlePushStream(str)
+		Expect(handler.pushed).ToNot(Receive())
+		Expect(cl.pushes).To(BeEmpty())
+	})
+
+	It("only keeps track of a limited number of canceled pushes", func() {
+		handler.accept = false
+		handler.promised = make(chan *http.Request, 2*pushIDWindow)
+		for i := 0; i < 2*pushIDWindow; i++ {
+			Expect(receivePromise(encodePromise(uint64(i), "/foo"))).To(Succeed())
+		}
+		Expect(controlFrames()).To(HaveLen(4 * pushIDWindow))
+		Expect(cl.pushes).To(HaveLen(pushIDWindow))
+		Expect(cl.pushes).ToNot(HaveKey(uint64(pushIDWindow - 1)))
+		Expect(cl.pushes).To(HaveKey(uint64(pushIDWindow)))
+		Expect(cl.pushes).To(HaveKey(uint64(2*pushIDWindow - 1)))
+	})
+
+	It("handles CANCEL_PUSH frames sent by the server", func() {
+		Expect(receivePromise(encodePromise(2, "/foo"))).To(Succeed())
+		Expect(handler.promised).To(Receive())
+		Expect(cl.handleCancelPush(2)).To(Succeed())
+		Expect(controlFrames()).To(Equal([]frame{&maxPushIDFrame{PushID: pushIDWindow}}))
+		str := pushStream(2, "foobar")
+		str.EXPECT().CancelRead(quic.ErrorCode(errorRequestCanceled))
+		cl.handlePushStream(str)
+		Expect(handler.pushed).ToNot(Receive())
+		Expect(cl.handleCancelPush(pushIDWindow + 1)).To(MatchError("CANCEL_PUSH for push ID 17 exceeds the limit"))
+	})
+
+	It("closes the connection when the push ID exceeds the limit", func() {
+		sess.EXPECT().CloseWithError(quic.ErrorCode(errorIDError), "push ID 16 exceeds the limit (15)")
+		Expect(receivePromise(encodePromise(pushIDWindow, "/foo"))).To(MatchError("push ID 16 exceeds the limit (15)"))
+		sess.EXPECT().CloseWithError(quic.ErrorCode(errorIDError), "push ID 16 exceeds the limit")
+		cl.handlePushStream(pushStream(pushIDWindow, "foobar"))
+	})
+
+	It("closes the connection when the client didn't enable server push", func() {
+		cl.opts.PushHandler = nil
+		sess.EXPECT().CloseWithError(quic.ErrorCode(errorIDError), gomock.Any())
+		Expect(receivePromise(encodePromise(0, "/foo"))).To(HaveOccurred())
+	})
+
+	It("handles PUSH_PROMISE frames on the request stream", func() {
+		buf := &bytes.Buffer{}
+		buf.Write(encodePromise(0, "/foo"))
+		headers := &bytes.Buffer{}
+		qpack.NewEncoder(headers).WriteField(qpack.HeaderField{Name: ":status", Value: "200"})
+		(&headersFrame{Length: uint64(headers.Len())}).Write(buf)
+		buf.Write(headers.Bytes())
+		buf.Write(encodePromise(1, "/bar"))
+		(&dataFrame{Length: 6}).Write(buf)
+		buf.WriteString("foobar")
+		str := mockquic.NewMockStream(mockCtrl)
+		str.EXPECT().Read(gomock.Any()).DoAndReturn(buf.Read).AnyTimes()
+		decode := qpack.NewDecoder(nil).DecodeFull
+		rsp, rerr := cl.readResponse(str, make(chan struct{}), decode, func(f *pushPromiseFrame) error {
+			return cl.handlePushPromise(f, str, decode)
+		})
+		Expect(rerr.err).ToNot(HaveOccurred())
+		Expect(rsp.StatusCode).To(Equal(200))
+		var req *http.Request
+		Expect(handler.promised).To(Receive(&req))
+		Expect(req.URL.Path).To(Equal("/foo"))
+		data, err := ioutil.ReadAll(rsp.Body)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(data)).To(Equal("foobar"))
+		Expect(handler.promised).To(Receive(&req))
+		Expect(req.URL.Path).To(Equal("/bar"))
+	})
+
+	It("rejects PUSH_PROMISE frames on push streams", func() {
+		buf := bytes.NewBuffer(encodePromise(0, "/foo"))
+		str := mockquic.NewMockStream(mockCtrl)
+		str.EXPECT().Read(gomock.Any()).DoAndReturn(buf.Read).AnyTimes()
+		_, rerr := cl.readResponse(str, make(chan struct{}), qpack.NewDecoder(nil).DecodeFull, nil)
+		Expect(rerr.connErr).To(Equal(errorFrameUnexpected))
+	})
+})
@@ -6,8 +6,10 @@ import (
 	"context"
 	"crypto/tls"
 	"errors"
+	"fmt"
 	"io"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"time"
 
@@ -24,6 +26,26 @@ import (
 	. "github.com/onsi/gomega"
 )
 
+type streamError struct {
+	code quic.ErrorCode
+}
+
+var _ quic.StreamError = &streamError{}
+
+func (e *streamError) Error() string {
+	return fmt.Sprintf("stream canceled with error code %d", e.code)
+}
+func (e *streamError) Canceled() bool            { return true }
+func (e *streamError) ErrorCode() quic.ErrorCode { return e.code }
+
+type tooManyStreamsError struct{}
+
+var _ net.Error = &tooManyStreamsError{}
+
+func (tooManyStreamsError) Error() string   { return "too many open streams" }
+func (tooManyStreamsError) Temporary() bool { return true }
+func (tooManyStreamsError) Timeout() bool   { return false }
+
 var _ = Describe("Client", func() {
 	var (
 		client       *client
@@ -60,14 +82,20 @@ var _ = Describe("Client", func() {
 		Expect(err).To(MatchError("can only use a single QUIC version for dialing a HTTP/3 connection"))
 	})
 
+	It("errors when sending a frame before the control stream was opened", func() {
+		cl, err := newClient("localhost:1337", nil, &roundTripperOpts{}, nil, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cl.writeControlFrame(&maxPushIDFrame{PushID: 1})).To(MatchError(errControlStreamNotOpen))
+	})
+
 	It("uses the default QUIC and TLS config if none is give", func() {
 		client, err := newClient("localhost:1337", nil, &roundTripperOpts{}, nil, nil)
 		Expect(err).ToNot(HaveOccurred())
 		var dialAddrCalled bool
 		dialAddr = func(_ string, tlsConf *tls.Config, quicConf *quic.Config) (quic.EarlySession, error) {
 			Expect(quicConf).To(Equal(defaultQuicConfig))
-			Expect(tlsConf.NextProtos).To(Equal([]string{nextProtoH3Draft29}))
-			Expect(quicConf.Versions).To(Equal([]protocol.VersionNumber{protocol.VersionTLS}))
+			Expect(tlsConf.NextProtos).To(Equal([]string{nextProtoH3}))
+			Expect(quicConf.Versions).To(Equal([]protocol.VersionNumber{protocol.Version1}))
 			dialAddrCalled = true
 			return nil, errors.New("test done")
 		}
@@ -106,7 +134,7 @@ var _ = Describe("Client", func() {
 		) (quic.EarlySession, error) {
 			Expect(hostname).To(Equal("localhost:1337"))
 			Expect(tlsConfP.ServerName).To(Equal(tlsConf.ServerName))
-			Expect(tlsConfP.NextProtos).To(Equal([]string{nextProtoH3Draft29}))
+			Expect(tlsConfP.NextProtos).To(Equal([]string{nextProtoH3}))
 			Expect(quicConfP.MaxIdleTimeout).To(Equal(quicConf.MaxIdleTimeout))
 			dialAddrCalled = true
 			return nil, errors.New("test done")
@@ -216,12 +244,29 @@ var _ = Describe("Client", func() {
 			Eventually(settingsFrameWritten).Should(BeClosed())
 		})
 
+		// controlStreamReader returns a Read function that reads from buf.
+		// Once buf is exhausted, it blocks until more data is received on the more channel, or until closed is closed.
+		controlStreamReader := func(buf *bytes.Buffer, more <-chan []byte, closed <-chan struct{}) func([]byte) (int, error) {
+			return func(b []byte) (int, error) {
+				for buf.Len() == 0 {
+					select {
+					case data := <-more:
+						buf.Write(data)
+					case <-closed:
+						return 0, io.EOF
+					}
+				}
+				return buf.Read(b)
+			}
+		}
+
 		It("parses the SETTINGS frame", func() {
 			buf := &bytes.Buffer{}
 			quicvarint.Write(buf, streamTypeControlStream)
 			(&settingsFrame{}).Write(buf)
+			streamClosed := make(chan struct{})
 			controlStr := mockquic.NewMockStream(mockCtrl)
-			controlStr.EXPECT().Read(gomock.Any()).DoAndReturn(buf.Read).AnyTimes()
+			controlStr.EXPECT().Read(gomock.Any()).DoAndReturn(controlStreamReader(buf, nil, streamClosed)).AnyTimes()
 			sess.EXPECT().AcceptUniStream(gomock.Any()).DoAndReturn(func(context.Context) (quic.ReceiveStream, error) {
 				return controlStr, nil
 			})
@@ -232,6 +277,116 @@ var _ = Describe("Client", func() {
 			_, err := client.RoundTrip(request)
 			Expect(err).To(MatchError("done"))
 			time.Sleep(scaleDuration(20 * time.Millisecond)) // don't EXPECT any calls to sess.CloseWithError
+			// closing the control stream is a connection error
+			done := make(chan struct{})
+			sess.EXPECT().CloseWithError(quic.ErrorCode(errorClosedCriticalStream), gomock.Any()).Do(func(quic.ErrorCode, string) { close(done) })
+			close(streamClosed)
+			Eventually(done).Should(BeClosed())
+		})
+
+		It("handles GOAWAY frames", func() {
+			buf := &bytes.Buffer{}
+			quicvarint.Write(buf, streamTypeControlStream)
+			(&settingsFrame{}).Write(buf)
+			more := make(chan []byte, 1)
+			streamClosed := make(chan struct{})
+			defer close(streamClosed)
+			controlStr := mockquic.NewMockStream(mockCtrl)
+			controlStr.EXPECT().Read(gomock.Any()).DoAndReturn(controlStreamReader(buf, more, streamClosed)).AnyTimes()
+			sess.EXPECT().AcceptUniStream(gomock.Any()).DoAndReturn(func(context.Context) (quic.ReceiveStream, error) {
+				return controlStr, nil
+			})
+			sess.EXPECT().AcceptUniStream(gomock.Any()).DoAndReturn(func(context.Context) (quic.ReceiveStream, error) {
+				<-testDone
+				return nil, errors.New("test done")
+			})
+			_, err := client.RoundTrip(request)
+			Expect(err).To(MatchError("done"))
+			Expect(client.Draining()).To(BeFalse())
+			// There are no active requests, so the client closes the connection right away.
+			done := make(chan struct{})
+			sess.EXPECT().CloseWithError(quic.ErrorCode(errorNoError), gomock.Any()).Do(func(quic.ErrorCode, string) { close(done) })
+			sess.EXPECT().CloseWithError(gomock.Any(), gomock.Any()).AnyTimes() // when the control stream is closed
+			b := &bytes.Buffer{}
+			(&goAwayFrame{StreamID: 8}).Write(b)
+			more <- b.Bytes()
+			Eventually(done).Should(BeClosed())
+			Expect(client.Draining()).To(BeTrue())
+			_, err = client.RoundTrip(request)
+			Expect(err).To(MatchError(errRequestNotProcessed))
+		})
+
+		It("errors when the stream ID in the GOAWAY frame increases", func() {
+			buf := &bytes.Buffer{}
+			quicvarint.Write(buf, streamTypeControlStream)
+			(&settingsFrame{}).Write(buf)
+			(&goAwayFrame{StreamID: 8}).Write(buf)
+			(&goAwayFrame{StreamID: 12}).Write(buf)
+			controlStr := mockquic.NewMockStream(mockCtrl)
+			controlStr.EXPECT().Read(gomock.Any()).DoAndReturn(buf.Read).AnyTimes()
+			sess.EXPECT().AcceptUniStream(gomock.Any()).DoAndReturn(func(context.Context) (quic.ReceiveStream, error) {
+				return controlStr, nil
+			})
+			sess.EXPECT().AcceptUniStream(gomock.Any()).DoAndReturn(func(context.Context) (quic.ReceiveStream, error) {
+				<-testDone
+				return nil, errors.New("test done")
+			})
+			done := make(chan struct{})
+			sess.EXPECT().CloseWithError(quic.ErrorCode(errorNoError), gomock.Any()).MaxTimes(1)
+			sess.EXPECT().CloseWithError(quic.ErrorCode(errorIDError), gomock.Any()).Do(func(_ quic.ErrorCode, reason string) {
+				defer GinkgoRecover()
+				Expect(reason).To(Equal("GOAWAY stream ID increased from 8 to 12"))
+				close(done)
+			})
+			_, err := client.RoundTrip(request)
+			Expect(err).To(Or(MatchError("done"), MatchError(errRequestNotProcessed)))
+			Eventually(done).Should(BeClosed())
+		})
+
+		It("errors when the GOAWAY frame contains an invalid stream ID", func() {
+			buf := &bytes.Buffer{}
+			quicvarint.Write(buf, streamTypeControlStream)
+			(&settingsFrame{}).Write(buf)
+			(&goAwayFrame{StreamID: 2}).Write(buf) // a client-initiated unidirectional stream
+			controlStr := mockquic.NewMockStream(mockCtrl)
+			controlStr.EXPECT().Read(gomock.Any()).DoAndReturn(buf.Read).AnyTimes()
+			sess.EXPECT().AcceptUniStream(gomock.Any()).DoAndReturn(func(context.Context) (quic.ReceiveStream, error) {
+				return controlStr, nil
+			})
+			sess.EXPECT().AcceptUniStream(gomock.Any()).DoAndReturn(func(context.Context) (quic.ReceiveStream, error) {
+				<-testDone
+				return nil, errors.New("test done")
+			})
+			done := make(chan struct{})
+			sess.EXPECT().CloseWithError(quic.ErrorCode(errorIDError), gomock.Any()).Do(func(_ quic.ErrorCode, reason string) {
+				defer GinkgoRecover()
+				Expect(reason).To(Equal("invalid stream ID in GOAWAY frame: 2"))
+				close(done)
+			})
+			_, err := client.RoundTrip(request)
+			Expect(err).To(MatchError("done"))
+			Eventually(done).Should(BeClosed())
+		})
+
+		It("errors when the server sends a second SETTINGS frame", func() {
+			buf := &bytes.Buffer{}
+			quicvarint.Write(buf, streamTypeControlStream)
+			(&settingsFrame{}).Write(buf)
+			(&settingsFrame{}).Write(buf)
+			controlStr := mockquic.NewMockStream(mockCtrl)
+			controlStr.EXPECT().Read(gomock.Any()).DoAndReturn(buf.Read).AnyTimes()
+			sess.EXPECT().AcceptUniStream(gomock.Any()).DoAndReturn(func(context.Context) (quic.ReceiveStream, error) {
+				return controlStr, nil
+			})
+			sess.EXPECT().AcceptUniStream(gomock.Any()).DoAndReturn(func(context.Context) (quic.ReceiveStream, error) {
+				<-testDone
+				return nil, errors.New("test done")
+			})
+			done := make(chan struct{})
+			sess.EXPECT().CloseWithError(quic.ErrorCode(errorFrameUnexpected), gomock.Any()).Do(func(quic.ErrorCode, string) { close(done) })
+			_, err := client.RoundTrip(request)
+			Expect(err).To(MatchError("done"))
+			Eventually(done).Should(BeClosed())
 		})
 
 		It("ignores streams other than the control stream", func() {
@@ -362,6 +517,7 @@ var _ = Describe("Client", func() {
 			request              *http.Request
 			str                  *mockquic.MockStream
 			sess                 *mockquic.MockEarlySession
+			controlStr           *mockquic.MockStream
 			settingsFrameWritten chan struct{}
 		)
 		testDone := make(chan struct{})
@@ -387,7 +543,7 @@ var _ = Describe("Client", func() {
 
 		BeforeEach(func() {
 			settingsFrameWritten = make(chan struct{})
-			controlStr := mockquic.NewMockStream(mockCtrl)
+			controlStr = mockquic.NewMockStream(mockCtrl)
 			controlStr.EXPECT().Write(gomock.Any()).Do(func(b []byte) {
 				defer GinkgoRecover()
 				r := bytes.NewReader(b)
@@ -397,6 +553,7 @@ var _ = Describe("Client", func() {
 				close(settingsFrameWritten)
 			}) // SETTINGS frame
 			str = mockquic.NewMockStream(mockCtrl)
+			str.EXPECT().StreamID().Return(quic.StreamID(4)).AnyTimes()
 			sess = mockquic.NewMockEarlySession(mockCtrl)
 			sess.EXPECT().OpenUniStream().Return(controlStr, nil)
 			sess.EXPECT().AcceptUniStream(gomock.Any()).DoAndReturn(func(context.Context) (quic.ReceiveStream, error) {
@@ -423,6 +580,42 @@ var _ = Describe("Client", func() {
 			Expect(err).To(MatchError(testErr))
 		})
 
+		It("doesn't send the request if the stream limit is reached", func() {
+			sess.EXPECT().HandshakeComplete().Return(handshakeCtx)
+			sess.EXPECT().OpenStream().Return(nil, &tooManyStreamsError{})
+			_, err := client.TryRoundTrip(request)
+			Expect(err).To(MatchError(errNoStreamAvailable))
+			Expect(client.ActiveRequests()).To(BeZero())
+		})
+
+		It("closes the connection when it is idle", func() {
+			client.opts.IdleTimeout = scaleDuration(20 * time.Millisecond)
+			rspBuf := &bytes.Buffer{}
+			rw := newResponseWriter(rspBuf, newQPACKEncoder(0), 0, utils.DefaultLogger)
+			rw.WriteHeader(200)
+			rw.Flush()
+
+			sess.EXPECT().HandshakeComplete().Return(handshakeCtx)
+			sess.EXPECT().OpenStreamSync(context.Background()).Return(str, nil)
+			sess.EXPECT().ConnectionState().Return(quic.ConnectionState{})
+			str.EXPECT().Write(gomock.Any()).AnyTimes().DoAndReturn(func(p []byte) (int, error) { return len(p), nil })
+			str.EXPECT().Close()
+			str.EXPECT().Read(gomock.Any()).DoAndReturn(rspBuf.Read).AnyTimes()
+			str.EXPECT().CancelRead(gomock.Any())
+			rsp, err := client.RoundTrip(request)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(client.ActiveRequests()).To(Equal(1))
+			sess.EXPECT().Context().Return(context.Background()).AnyTimes()
+			Consistently(client.Closed).Should(BeFalse())
+			closed := make(chan struct{})
+			sess.EXPECT().CloseWithError(quic.ErrorCode(errorNoError), "").Do(func(quic.ErrorCode, string) { close(closed) })
+			Expect(rsp.Body.Close()).To(Succeed())
+			Eventually(closed).Should(BeClosed())
+			Expect(client.Closed()).To(BeTrue())
+			_, err = client.RoundTrip(request)
+			Expect(err).To(MatchError(errRequestNotProcessed))
+		})
+
 		It("performs a 0-RTT request", func() {
 			testErr := errors.New("stream open error")
 			request.Method = MethodGet0RTT
@@ -442,7 +635,7 @@ var _ = Describe("Client", func() {
 
 		It("returns a response", func() {
 			rspBuf := &bytes.Buffer{}
-			rw := newResponseWriter(rspBuf, utils.DefaultLogger)
+			rw := newResponseWriter(rspBuf, newQPACKEncoder(0), 0, utils.DefaultLogger)
 			rw.WriteHeader(418)
 			rw.Flush()
 
@@ -463,6 +656,124 @@ var _ = Describe("Client", func() {
 			Expect(rsp.StatusCode).To(Equal(418))
 		})
 
+		It("sets the priority of the request, and updates it", func() {
+			rspBuf := &bytes.Buffer{}
+			rw := newResponseWriter(rspBuf, newQPACKEncoder(0), 0, utils.DefaultLogger)
+			rw.WriteHeader(200)
+			rw.Flush()
+
+			request.Header.Set(PriorityHeader, Priority{Urgency: 1}.String())
+			gomock.InOrder(
+				sess.EXPECT().HandshakeComplete().Return(handshakeCtx),
+				sess.EXPECT().OpenStreamSync(context.Background()).Return(str, nil),
+				sess.EXPECT().ConnectionState().Return(quic.ConnectionState{}),
+			)
+			str.EXPECT().SetPriority(quic.StreamPriority{Urgency: 1})
+			str.EXPECT().Write(gomock.Any()).AnyTimes().DoAndReturn(func(p []byte) (int, error) { return len(p), nil })
+			str.EXPECT().Close()
+			str.EXPECT().Read(gomock.Any()).DoAndReturn(rspBuf.Read).AnyTimes()
+			rsp, err := client.RoundTrip(request)
+			Expect(err).ToNot(HaveOccurred())
+
+			Eventually(settingsFrameWritten).Should(BeClosed())
+			controlBuf := &bytes.Buffer{}
+			controlStr.EXPECT().Write(gomock.Any()).DoAndReturn(controlBuf.Write)
+			str.EXPECT().SetPriority(quic.StreamPriority{Urgency: 5, Incremental: true})
+			Expect(rsp.Body.(PriorityUpdater).UpdatePriority(Priority{Urgency: 5, Incremental: true})).To(Succeed())
+			f, err := parseNextFrame(controlBuf)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(f).To(Equal(&priorityUpdateFrame{ElementID: 4, Priority: "u=5, i"}))
+		})
+
+		It("returns the error when sending the PRIORITY_UPDATE frame fails", func() {
+			rspBuf := &bytes.Buffer{}
+			rw := newResponseWriter(rspBuf, newQPACKEncoder(0), 0, utils.DefaultLogger)
+			rw.WriteHeader(200)
+			rw.Flush()
+
+			gomock.InOrder(
+				sess.EXPECT().HandshakeComplete().Return(handshakeCtx),
+				sess.EXPECT().OpenStreamSync(context.Background()).Return(str, nil),
+				sess.EXPECT().ConnectionState().Return(quic.ConnectionState{}),
+			)
+			str.EXPECT().Write(gomock.Any()).AnyTimes().DoAndReturn(func(p []byte) (int, error) { return len(p), nil })
+			str.EXPECT().Close()
+			str.EXPECT().Read(gomock.Any()).DoAndReturn(rspBuf.Read).AnyTimes()
+			rsp, err := client.RoundTrip(request)
+			Expect(err).ToNot(HaveOccurred())
+
+			Eventually(settingsFrameWritten).Should(BeClosed())
+			testErr := errors.New("test error")
+			controlStr.EXPECT().Write(gomock.Any()).Return(0, testErr)
+			str.EXPECT().SetPriority(quic.StreamPriority{Urgency: 5})
+			Expect(rsp.Body.(PriorityUpdater).UpdatePriority(Priority{Urgency: 5})).To(MatchError(testErr))
+		})
+
+		It("returns an error for requests rejected by the server", func() {
+			sess.EXPECT().HandshakeComplete().Return(handshakeCtx)
+			sess.EXPECT().OpenStreamSync(context.Background()).Return(str, nil)
+			str.EXPECT().Write(gomock.Any()).AnyTimes().DoAndReturn(func(p []byte) (int, error) { return len(p), nil })
+			str.EXPECT().Close()
+			str.EXPECT().Read(gomock.Any()).Return(0, &streamError{code: quic.ErrorCode(errorRequestRejected)})
+			str.EXPECT().CancelWrite(gomock.Any())
+			_, err := client.RoundTrip(request)
+			Expect(err).To(MatchError(errRequestNotProcessed))
+		})
+
+		It("cancels requests that weren't processed when receiving a GOAWAY frame", func() {
+			sess.EXPECT().HandshakeComplete().Return(handshakeCtx)
+			sess.EXPECT().OpenStreamSync(context.Background()).Return(str, nil)
+			str.EXPECT().Write(gomock.Any()).AnyTimes().DoAndReturn(func(p []byte) (int, error) { return len(p), nil })
+			str.EXPECT().Close()
+			canceled := make(chan struct{})
+			str.EXPECT().CancelWrite(quic.ErrorCode(errorRequestCanceled)).Times(2)
+			str.EXPECT().CancelRead(quic.ErrorCode(errorRequestCanceled)).Do(func(quic.ErrorCode) { close(canceled) })
+			str.EXPECT().Read(gomock.Any()).DoAndReturn(func([]byte) (int, error) {
+				// the request is still active, so the connection is not closed yet
+				Expect(client.handleGoAway(4)).To(Succeed())
+				<-canceled
+				return 0, errors.New("read canceled")
+			})
+			// the connection is closed once the request completed
+			closed := make(chan struct{})
+			sess.EXPECT().CloseWithError(quic.ErrorCode(errorNoError), "").Do(func(quic.ErrorCode, string) { close(closed) })
+			_, err := client.RoundTrip(request)
+			Expect(err).To(MatchError(errRequestNotProcessed))
+			Eventually(closed).Should(BeClosed())
+			Expect(client.Draining()).To(BeTrue())
+		})
+
+		It("doesn't cancel requests that were processed when receiving a GOAWAY frame", func() {
+			rspBuf := &bytes.Buffer{}
+			rw := newResponseWriter(rspBuf, newQPACKEncoder(0), 0, utils.DefaultLogger)
+			rw.WriteHeader(418)
+			rw.Flush()
+
+			sess.EXPECT().HandshakeComplete().Return(handshakeCtx)
+			sess.EXPECT().OpenStreamSync(context.Background()).Return(str, nil)
+			sess.EXPECT().ConnectionState().Return(quic.ConnectionState{})
+			str.EXPECT().Write(gomock.Any()).AnyTimes().DoAndReturn(func(p []byte) (int, error) { return len(p), nil })
+			str.EXPECT().Close()
+			var receivedGoAway bool
+			str.EXPECT().Read(gomock.Any()).DoAndReturn(func(b []byte) (int, error) {
+				if !receivedGoAway {
+					receivedGoAway = true
+					Expect(client.handleGoAway(8)).To(Succeed())
+				}
+				return rspBuf.Read(b)
+			}).AnyTimes()
+			rsp, err := client.RoundTrip(request)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rsp.StatusCode).To(Equal(418))
+			// the connection is closed once the response body was consumed
+			closed := make(chan struct{})
+			sess.EXPECT().CloseWithError(quic.ErrorCode(errorNoError), "").Do(func(quic.ErrorCode, string) { close(closed) })
+			str.EXPECT().CancelRead(gomock.Any())
+			Consistently(closed).ShouldNot(BeClosed())
+			Expect(rsp.Body.Close()).To(Succeed())
+			Eventually(closed).Should(BeClosed())
+		})
+
 		Context("requests containing a Body", func() {
 			var strBuf *bytes.Buffer
 
@@ -591,7 +902,7 @@ var _ = Describe("Client", func() {
 
 			It("cancels a request after the response arrived", func() {
 				rspBuf := &bytes.Buffer{}
-				rw := newResponseWriter(rspBuf, utils.DefaultLogger)
+				rw := newResponseWriter(rspBuf, newQPACKEncoder(0), 0, utils.DefaultLogger)
 				rw.WriteHeader(418)
 				rw.Flush()
 
@@ -613,6 +924,30 @@ var _ = Describe("Client", func() {
 				cancel()
 				Eventually(done).Should(BeClosed())
 			})
+
+			It("doesn't reset the stream when the context is canceled after the response body was closed", func() {
+				rspBuf := &bytes.Buffer{}
+				rw := newResponseWriter(rspBuf, newQPACKEncoder(0), 0, utils.DefaultLogger)
+				rw.WriteHeader(200)
+				rw.Flush()
+
+				ctx, cancel := context.WithCancel(context.Background())
+				req := request.WithContext(ctx)
+				sess.EXPECT().HandshakeComplete().Return(handshakeCtx)
+				sess.EXPECT().OpenStreamSync(ctx).Return(str, nil)
+				sess.EXPECT().ConnectionState().Return(quic.ConnectionState{})
+				buf := &bytes.Buffer{}
+				str.EXPECT().Close().MaxTimes(1)
+				str.EXPECT().Write(gomock.Any()).DoAndReturn(buf.Write)
+				str.EXPECT().Read(gomock.Any()).DoAndReturn(rspBuf.Read).AnyTimes()
+				// only the read side is canceled when closing the body
+				str.EXPECT().CancelRead(quic.ErrorCode(errorRequestCanceled))
+				rsp, err := client.RoundTrip(req)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(rsp.Body.Close()).To(Succeed())
+				cancel()
+				time.Sleep(50 * time.Millisecond) // make sure the stream isn't reset
+			})
 		})
 
 		Context("gzip compression", func() {
@@ -656,7 +991,7 @@ var _ = Describe("Client", func() {
 				sess.EXPECT().OpenStreamSync(context.Background()).Return(str, nil)
 				sess.EXPECT().ConnectionState().Return(quic.ConnectionState{})
 				buf := &bytes.Buffer{}
-				rw := newResponseWriter(buf, utils.DefaultLogger)
+				rw := newResponseWriter(buf, newQPACKEncoder(0), 0, utils.DefaultLogger)
 				rw.Header().Set("Content-Encoding", "gzip")
 				gz := gzip.NewWriter(rw)
 				gz.Write([]byte("gzipped response"))
@@ -680,7 +1015,7 @@ var _ = Describe("Client", func() {
 				sess.EXPECT().OpenStreamSync(context.Background()).Return(str, nil)
 				sess.EXPECT().ConnectionState().Return(quic.ConnectionState{})
 				buf := &bytes.Buffer{}
-				rw := newResponseWriter(buf, utils.DefaultLogger)
+				rw := newResponseWriter(buf, newQPACKEncoder(0), 0, utils.DefaultLogger)
 				rw.Write([]byte("not gzipped"))
 				rw.Flush()
 				str.EXPECT().Write(gomock.Any()).AnyTimes().DoAndReturn(func(p []byte) (int, error) { return len(p), nil })
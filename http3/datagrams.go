@@ -0,0 +1,154 @@
+package http3
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/For-ACGN/quic-go"
+	"github.com/For-ACGN/quic-go/internal/protocol"
+	"github.com/For-ACGN/quic-go/internal/utils"
+	"github.com/For-ACGN/quic-go/quicvarint"
+)
+
+// datagramQueueLen is the number of HTTP datagrams that are queued per request stream.
+// Datagrams are unreliable, so once the queue is full, new datagrams are dropped.
+const datagramQueueLen = 32
+
+// maxQuarterStreamID is the largest quarter stream ID that can be used, see RFC 9297, section 2.1.
+const maxQuarterStreamID = 1<<60 - 1
+
+var (
+	errDatagramsNotNegotiated = errors.New("http3: HTTP datagrams not negotiated")
+	errDatagramsNotEnabled    = errors.New("http3: HTTP datagrams not enabled")
+	errDatagramFlowClosed     = errors.New("http3: datagram flow closed")
+)
+
+// Datagrammer sends and receives HTTP datagrams (RFC 9297) associated with a request stream.
+// It is implemented by the http.ResponseWriter passed to the handlers of a http3.Server,
+// and by the http.Response.Body returned by the http3.RoundTripper,
+// if HTTP datagrams are enabled (using EnableDatagrams).
+// Datagrams can only be sent once both endpoints negotiated support for HTTP datagrams.
+type Datagrammer interface {
+	// SendDatagram sends an HTTP datagram.
+	SendDatagram([]byte) error
+	// ReceiveDatagram gets an HTTP datagram sent by the peer.
+	// It returns an error once the request is done.
+	ReceiveDatagram(context.Context) ([]byte, error)
+}
+
+// A datagramFlow carries the HTTP datagrams associated with a single request stream.
+type datagramFlow struct {
+	id      protocol.StreamID
+	manager *datagramManager
+	queue   <-chan []byte
+}
+
+func (f *datagramFlow) send(b []byte) error {
+	return f.manager.send(f.id, b)
+}
+
+func (f *datagramFlow) receive(ctx context.Context) ([]byte, error) {
+	select {
+	case data, ok := <-f.queue:
+		if !ok {
+			return nil, errDatagramFlowClosed
+		}
+		return data, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// A datagramManager demultiplexes the HTTP datagrams received on a QUIC session.
+// Every HTTP datagram starts with the quarter stream ID of the request stream it belongs to.
+type datagramManager struct {
+	sess   quic.Session
+	logger utils.Logger
+
+	// set once both endpoints negotiated support for HTTP datagrams
+	negotiated utils.AtomicBool
+
+	mutex  sync.Mutex
+	queues map[uint64]chan []byte // indexed by the quarter stream ID
+}
+
+func newDatagramManager(sess quic.Session, logger utils.Logger) *datagramManager {
+	return &datagramManager{
+		sess:   sess,
+		logger: logger,
+		queues: make(map[uint64]chan []byte),
+	}
+}
+
+// register registers the request stream id.
+// Datagrams for this stream are queued until unregister is called.
+func (m *datagramManager) register(id protocol.StreamID) *datagramFlow {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	c := make(chan []byte, datagramQueueLen)
+	m.queues[uint64(id)/4] = c
+	return &datagramFlow{id: id, manager: m, queue: c}
+}
+
+func (m *datagramManager) unregister(id protocol.StreamID) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if c, ok := m.queues[uint64(id)/4]; ok {
+		close(c)
+		delete(m.queues, uint64(id)/4)
+	}
+}
+
+// send sends an HTTP datagram associated with the request stream id.
+func (m *datagramManager) send(id protocol.StreamID, data []byte) error {
+	if !m.negotiated.Get() {
+		return errDatagramsNotNegotiated
+	}
+	buf := &bytes.Buffer{}
+	buf.Grow(int(quicvarint.Len(uint64(id)/4)) + len(data))
+	quicvarint.Write(buf, uint64(id)/4)
+	buf.Write(data)
+	return m.sess.SendMessage(buf.Bytes())
+}
+
+// start is called once both endpoints negotiated support for HTTP datagrams.
+// It starts reading datagrams from the QUIC session.
+func (m *datagramManager) start() {
+	m.negotiated.Set(true)
+	go m.run()
+}
+
+// run reads datagrams from the QUIC session, until the session is closed.
+func (m *datagramManager) run() {
+	for {
+		data, err := m.sess.ReceiveMessage()
+		if err != nil {
+			m.logger.Debugf("Receiving datagrams failed: %s", err)
+			return
+		}
+		r := bytes.NewReader(data)
+		qsid, err := quicvarint.Read(r)
+		if err != nil || qsid > maxQuarterStreamID {
+			m.sess.CloseWithError(quic.ErrorCode(errorDatagramError), "malformed HTTP datagram")
+			return
+		}
+		m.deliver(qsid, data[len(data)-r.Len():])
+	}
+}
+
+func (m *datagramManager) deliver(qsid uint64, data []byte) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	c, ok := m.queues[qsid]
+	if !ok {
+		m.logger.Debugf("Dropping datagram for unknown quarter stream ID %d.", qsid)
+		return
+	}
+	select {
+	case c <- data:
+	default:
+		m.logger.Debugf("Dropping datagram for quarter stream ID %d, since the queue is full.", qsid)
+	}
+}
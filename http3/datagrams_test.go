@@ -0,0 +1,113 @@
+package http3
+
+import (
+	"bytes"
+	"context"
+	"errors"
+
+	"github.com/For-ACGN/quic-go"
+	mockquic "github.com/For-ACGN/quic-go/internal/mocks/quic"
+	"github.com/For-ACGN/quic-go/internal/utils"
+	"github.com/For-ACGN/quic-go/quicvarint"
+
+	"github.com/golang/mock/gomock"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("HTTP datagrams", func() {
+	var (
+		sess    *mockquic.MockEarlySession
+		manager *datagramManager
+	)
+
+	// datagram creates an HTTP datagram for the quarter stream ID qsid
+	datagram := func(qsid uint64, data string) []byte {
+		b := &bytes.Buffer{}
+		quicvarint.Write(b, qsid)
+		b.WriteString(data)
+		return b.Bytes()
+	}
+
+	BeforeEach(func() {
+		sess = mockquic.NewMockEarlySession(mockCtrl)
+		manager = newDatagramManager(sess, utils.DefaultLogger)
+	})
+
+	It("doesn't send datagrams before they were negotiated", func() {
+		flow := manager.register(4)
+		Expect(flow.send([]byte("foobar"))).To(MatchError(errDatagramsNotNegotiated))
+	})
+
+	It("sends datagrams, prefixed with the quarter stream ID", func() {
+		manager.negotiated.Set(true)
+		flow := manager.register(1337 * 4)
+		sess.EXPECT().SendMessage(datagram(1337, "foobar"))
+		Expect(flow.send([]byte("foobar"))).To(Succeed())
+	})
+
+	It("delivers datagrams to the request stream", func() {
+		done := make(chan struct{})
+		gomock.InOrder(
+			sess.EXPECT().ReceiveMessage().Return(datagram(2, "foo"), nil),
+			sess.EXPECT().ReceiveMessage().Return(datagram(1, "bar"), nil),
+			sess.EXPECT().ReceiveMessage().Return(datagram(2, "baz"), nil),
+			sess.EXPECT().ReceiveMessage().DoAndReturn(func() ([]byte, error) {
+				close(done)
+				return nil, errors.New("test done")
+			}),
+		)
+		flow := manager.register(8)
+		manager.start()
+		Eventually(done).Should(BeClosed())
+		// the datagram for stream 4 is dropped
+		Expect(flow.receive(context.Background())).To(Equal([]byte("foo")))
+		Expect(flow.receive(context.Background())).To(Equal([]byte("baz")))
+	})
+
+	It("drops datagrams if the queue is full", func() {
+		flow := manager.register(0)
+		for i := 0; i < datagramQueueLen+1; i++ {
+			manager.deliver(0, []byte{byte(i)})
+		}
+		for i := 0; i < datagramQueueLen; i++ {
+			Expect(flow.receive(context.Background())).To(Equal([]byte{byte(i)}))
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err := flow.receive(ctx)
+		Expect(err).To(MatchError(context.Canceled))
+	})
+
+	It("closes the flow when the stream is unregistered", func() {
+		flow := manager.register(4)
+		manager.unregister(4)
+		_, err := flow.receive(context.Background())
+		Expect(err).To(MatchError(errDatagramFlowClosed))
+	})
+
+	It("closes the connection when receiving a malformed datagram", func() {
+		done := make(chan struct{})
+		sess.EXPECT().ReceiveMessage().Return(datagram(1<<61, ""), nil)
+		sess.EXPECT().CloseWithError(quic.ErrorCode(errorDatagramError), gomock.Any()).Do(func(quic.ErrorCode, string) { close(done) })
+		manager.start()
+		Eventually(done).Should(BeClosed())
+	})
+
+	It("returns an error if datagrams are not enabled", func() {
+		rw := newResponseWriter(nil, nil, 0, utils.DefaultLogger)
+		Expect(rw.SendDatagram([]byte("foobar"))).To(MatchError(errDatagramsNotEnabled))
+		_, err := (&body{}).ReceiveDatagram(context.Background())
+		Expect(err).To(MatchError(errDatagramsNotEnabled))
+	})
+
+	It("sends and receives datagrams on gzipped response bodies", func() {
+		manager.negotiated.Set(true)
+		rsp := newGzipReader(&body{datagrams: manager.register(4)}).(Datagrammer)
+		sess.EXPECT().SendMessage(datagram(1, "foo"))
+		Expect(rsp.SendDatagram([]byte("foo"))).To(Succeed())
+		manager.deliver(1, []byte("bar"))
+		Expect(rsp.ReceiveDatagram(context.Background())).To(Equal([]byte("bar")))
+	})
+})
@@ -2,6 +2,7 @@ package http3
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -48,14 +49,20 @@ func parseNextFrame(b io.Reader) (frame, error) {
 		return &headersFrame{Length: l}, nil
 	case 0x4:
 		return parseSettingsFrame(br, l)
-	case 0x3: // CANCEL_PUSH
-		fallthrough
-	case 0x5: // PUSH_PROMISE
-		fallthrough
-	case 0x7: // GOAWAY
-		fallthrough
-	case 0xd: // MAX_PUSH_ID
-		fallthrough
+	case 0x3:
+		return parseCancelPushFrame(br, l)
+	case 0x5:
+		return parsePushPromiseFrame(br, l)
+	case 0x7:
+		return parseGoAwayFrame(br, l)
+	case 0xd:
+		return parseMaxPushIDFrame(br, l)
+	case 0xf0700, 0xf0701:
+		return parsePriorityUpdateFrame(br, l, t == 0xf0701)
+	case 0x41: // WEBTRANSPORT_STREAM
+		// This frame doesn't have a length field.
+		// The varint following the frame type is the session ID, and the rest of the stream is application data.
+		return &webTransportStreamFrame{SessionID: protocol.StreamID(l)}, nil
 	case 0xe: // DUPLICATE_PUSH
 		fallthrough
 	default:
@@ -85,11 +92,21 @@ func (f *headersFrame) Write(b *bytes.Buffer) {
 	quicvarint.Write(b, f.Length)
 }
 
-const settingDatagram = 0x276
+const (
+	settingQPACKMaxTableCapacity = 0x1
+	settingQPACKBlockedStreams   = 0x7
+	settingExtendedConnect       = 0x8
+	settingDatagram              = 0x33
+	settingWebTransport          = 0x2b603742
+)
 
 type settingsFrame struct {
-	Datagram bool
-	other    map[uint64]uint64 // all settings that we don't explicitly recognize
+	QPACKMaxTableCapacity uint64
+	QPACKBlockedStreams   uint64
+	ExtendedConnect       bool // SETTINGS_ENABLE_CONNECT_PROTOCOL, see RFC 9220
+	Datagram              bool
+	WebTransport          bool              // SETTINGS_ENABLE_WEBTRANSPORT, see draft-ietf-webtrans-http3-02
+	other                 map[uint64]uint64 // all settings that we don't explicitly recognize
 }
 
 func parseSettingsFrame(r io.Reader, l uint64) (*settingsFrame, error) {
@@ -105,7 +122,7 @@ func parseSettingsFrame(r io.Reader, l uint64) (*settingsFrame, error) {
 	}
 	frame := &settingsFrame{}
 	b := bytes.NewReader(buf)
-	var readDatagram bool
+	var readDatagram, readMaxTableCapacity, readBlockedStreams, readExtendedConnect, readWebTransport bool
 	for b.Len() > 0 {
 		id, err := quicvarint.Read(b)
 		if err != nil { // should not happen. We allocated the whole frame already.
@@ -117,6 +134,18 @@ func parseSettingsFrame(r io.Reader, l uint64) (*settingsFrame, error) {
 		}
 
 		switch id {
+		case settingQPACKMaxTableCapacity:
+			if readMaxTableCapacity {
+				return nil, fmt.Errorf("duplicate setting: %d", id)
+			}
+			readMaxTableCapacity = true
+			frame.QPACKMaxTableCapacity = val
+		case settingQPACKBlockedStreams:
+			if readBlockedStreams {
+				return nil, fmt.Errorf("duplicate setting: %d", id)
+			}
+			readBlockedStreams = true
+			frame.QPACKBlockedStreams = val
 		case settingDatagram:
 			if readDatagram {
 				return nil, fmt.Errorf("duplicate setting: %d", id)
@@ -126,6 +155,24 @@ func parseSettingsFrame(r io.Reader, l uint64) (*settingsFrame, error) {
 				return nil, fmt.Errorf("invalid value for H3_DATAGRAM: %d", val)
 			}
 			frame.Datagram = val == 1
+		case settingExtendedConnect:
+			if readExtendedConnect {
+				return nil, fmt.Errorf("duplicate setting: %d", id)
+			}
+			readExtendedConnect = true
+			if val != 0 && val != 1 {
+				return nil, fmt.Errorf("invalid value for SETTINGS_ENABLE_CONNECT_PROTOCOL: %d", val)
+			}
+			frame.ExtendedConnect = val == 1
+		case settingWebTransport:
+			if readWebTransport {
+				return nil, fmt.Errorf("duplicate setting: %d", id)
+			}
+			readWebTransport = true
+			if val != 0 && val != 1 {
+				return nil, fmt.Errorf("invalid value for SETTINGS_ENABLE_WEBTRANSPORT: %d", val)
+			}
+			frame.WebTransport = val == 1
 		default:
 			if _, ok := frame.other[id]; ok {
 				return nil, fmt.Errorf("duplicate setting: %d", id)
@@ -145,16 +192,197 @@ func (f *settingsFrame) Write(b *bytes.Buffer) {
 	for id, val := range f.other {
 		l += quicvarint.Len(id) + quicvarint.Len(val)
 	}
+	if f.QPACKMaxTableCapacity > 0 {
+		l += quicvarint.Len(settingQPACKMaxTableCapacity) + quicvarint.Len(f.QPACKMaxTableCapacity)
+	}
+	if f.QPACKBlockedStreams > 0 {
+		l += quicvarint.Len(settingQPACKBlockedStreams) + quicvarint.Len(f.QPACKBlockedStreams)
+	}
+	if f.ExtendedConnect {
+		l += quicvarint.Len(settingExtendedConnect) + quicvarint.Len(1)
+	}
 	if f.Datagram {
 		l += quicvarint.Len(settingDatagram) + quicvarint.Len(1)
 	}
+	if f.WebTransport {
+		l += quicvarint.Len(settingWebTransport) + quicvarint.Len(1)
+	}
 	quicvarint.Write(b, uint64(l))
+	if f.QPACKMaxTableCapacity > 0 {
+		quicvarint.Write(b, settingQPACKMaxTableCapacity)
+		quicvarint.Write(b, f.QPACKMaxTableCapacity)
+	}
+	if f.QPACKBlockedStreams > 0 {
+		quicvarint.Write(b, settingQPACKBlockedStreams)
+		quicvarint.Write(b, f.QPACKBlockedStreams)
+	}
+	if f.ExtendedConnect {
+		quicvarint.Write(b, settingExtendedConnect)
+		quicvarint.Write(b, 1)
+	}
 	if f.Datagram {
 		quicvarint.Write(b, settingDatagram)
 		quicvarint.Write(b, 1)
 	}
+	if f.WebTransport {
+		quicvarint.Write(b, settingWebTransport)
+		quicvarint.Write(b, 1)
+	}
 	for id, val := range f.other {
 		quicvarint.Write(b, id)
 		quicvarint.Write(b, val)
 	}
 }
+
+type pushPromiseFrame struct {
+	PushID uint64
+	Length uint64 // the length of the encoded field section
+}
+
+func parsePushPromiseFrame(r byteReader, l uint64) (*pushPromiseFrame, error) {
+	pushID, err := quicvarint.Read(r)
+	if err != nil {
+		return nil, err
+	}
+	n := uint64(quicvarint.Len(pushID))
+	if n > l {
+		return nil, errors.New("invalid PUSH_PROMISE frame")
+	}
+	return &pushPromiseFrame{PushID: pushID, Length: l - n}, nil
+}
+
+func (f *pushPromiseFrame) Write(b *bytes.Buffer) {
+	quicvarint.Write(b, 0x5)
+	quicvarint.Write(b, uint64(quicvarint.Len(f.PushID))+f.Length)
+	quicvarint.Write(b, f.PushID)
+}
+
+type cancelPushFrame struct {
+	PushID uint64
+}
+
+func parseCancelPushFrame(r io.Reader, l uint64) (*cancelPushFrame, error) {
+	pushID, err := parseVarIntPayload(r, l, "CANCEL_PUSH")
+	if err != nil {
+		return nil, err
+	}
+	return &cancelPushFrame{PushID: pushID}, nil
+}
+
+func (f *cancelPushFrame) Write(b *bytes.Buffer) {
+	writeVarIntFrame(b, 0x3, f.PushID)
+}
+
+type goAwayFrame struct {
+	StreamID protocol.StreamID
+}
+
+func parseGoAwayFrame(r io.Reader, l uint64) (*goAwayFrame, error) {
+	id, err := parseVarIntPayload(r, l, "GOAWAY")
+	if err != nil {
+		return nil, err
+	}
+	return &goAwayFrame{StreamID: protocol.StreamID(id)}, nil
+}
+
+func (f *goAwayFrame) Write(b *bytes.Buffer) {
+	writeVarIntFrame(b, 0x7, uint64(f.StreamID))
+}
+
+type maxPushIDFrame struct {
+	PushID uint64
+}
+
+func parseMaxPushIDFrame(r io.Reader, l uint64) (*maxPushIDFrame, error) {
+	pushID, err := parseVarIntPayload(r, l, "MAX_PUSH_ID")
+	if err != nil {
+		return nil, err
+	}
+	return &maxPushIDFrame{PushID: pushID}, nil
+}
+
+func (f *maxPushIDFrame) Write(b *bytes.Buffer) {
+	writeVarIntFrame(b, 0xd, f.PushID)
+}
+
+// maxPriorityUpdateFrameSize is the maximum size of the payload of a PRIORITY_UPDATE frame
+const maxPriorityUpdateFrameSize = 1024
+
+// priorityUpdateFrame is a PRIORITY_UPDATE frame (RFC 9218, section 7).
+// It is sent by the client on the control stream.
+type priorityUpdateFrame struct {
+	Push      bool   // the prioritized element is a push stream (and not a request stream)
+	ElementID uint64 // the stream ID of the request stream, or the push ID
+	Priority  string // the value of the Priority header field
+}
+
+func parsePriorityUpdateFrame(r io.Reader, l uint64, push bool) (*priorityUpdateFrame, error) {
+	if l > maxPriorityUpdateFrameSize {
+		return nil, fmt.Errorf("PRIORITY_UPDATE frame too large: %d bytes", l)
+	}
+	buf := make([]byte, l)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return nil, io.EOF
+		}
+		return nil, err
+	}
+	b := bytes.NewReader(buf)
+	id, err := quicvarint.Read(b)
+	if err != nil {
+		return nil, errors.New("invalid PRIORITY_UPDATE frame")
+	}
+	return &priorityUpdateFrame{
+		Push:      push,
+		ElementID: id,
+		Priority:  string(buf[len(buf)-b.Len():]),
+	}, nil
+}
+
+func (f *priorityUpdateFrame) Write(b *bytes.Buffer) {
+	if f.Push {
+		quicvarint.Write(b, 0xf0701)
+	} else {
+		quicvarint.Write(b, 0xf0700)
+	}
+	quicvarint.Write(b, uint64(quicvarint.Len(f.ElementID))+uint64(len(f.Priority)))
+	quicvarint.Write(b, f.ElementID)
+	b.WriteString(f.Priority)
+}
+
+// webTransportStreamFrame is the signal value sent at the beginning of a
+// bidirectional stream that belongs to a WebTransport session.
+type webTransportStreamFrame struct {
+	SessionID protocol.StreamID
+}
+
+func (f *webTransportStreamFrame) Write(b *bytes.Buffer) {
+	quicvarint.Write(b, 0x41)
+	quicvarint.Write(b, uint64(f.SessionID))
+}
+
+// parseVarIntPayload parses the payload of frames that consist of a single variable-length integer.
+func parseVarIntPayload(r io.Reader, l uint64, name string) (uint64, error) {
+	if l > 8 {
+		return 0, fmt.Errorf("unexpected size for %s frame: %d", name, l)
+	}
+	buf := make([]byte, l)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return 0, io.EOF
+		}
+		return 0, err
+	}
+	b := bytes.NewReader(buf)
+	val, err := quicvarint.Read(b)
+	if err != nil || b.Len() > 0 {
+		return 0, fmt.Errorf("invalid %s frame", name)
+	}
+	return val, nil
+}
+
+func writeVarIntFrame(b *bytes.Buffer, t, val uint64) {
+	quicvarint.Write(b, t)
+	quicvarint.Write(b, uint64(quicvarint.Len(val)))
+	quicvarint.Write(b, val)
+}
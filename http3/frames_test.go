@@ -103,7 +103,7 @@ var _ = Describe("Frames", func() {
 
 		It("writes", func() {
 			sf := &settingsFrame{other: map[uint64]uint64{
-				1:  2,
+				42: 2,
 				99: 999,
 				13: 37,
 			}}
@@ -179,5 +179,267 @@ var _ = Describe("Frames", func() {
 				Expect(frame).To(Equal(sf))
 			})
 		})
+
+		Context("QPACK settings", func() {
+			It("reads the QPACK settings", func() {
+				settings := appendVarInt(nil, settingQPACKMaxTableCapacity)
+				settings = appendVarInt(settings, 4096)
+				settings = appendVarInt(settings, settingQPACKBlockedStreams)
+				settings = appendVarInt(settings, 100)
+				data := appendVarInt(nil, 4) // type byte
+				data = appendVarInt(data, uint64(len(settings)))
+				data = append(data, settings...)
+				f, err := parseNextFrame(bytes.NewReader(data))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(f).To(Equal(&settingsFrame{QPACKMaxTableCapacity: 4096, QPACKBlockedStreams: 100}))
+			})
+
+			It("rejects duplicate QPACK settings", func() {
+				settings := appendVarInt(nil, settingQPACKBlockedStreams)
+				settings = appendVarInt(settings, 1)
+				settings = appendVarInt(settings, settingQPACKBlockedStreams)
+				settings = appendVarInt(settings, 2)
+				data := appendVarInt(nil, 4) // type byte
+				data = appendVarInt(data, uint64(len(settings)))
+				data = append(data, settings...)
+				_, err := parseNextFrame(bytes.NewReader(data))
+				Expect(err).To(MatchError(fmt.Sprintf("duplicate setting: %d", settingQPACKBlockedStreams)))
+			})
+
+			It("writes the QPACK settings", func() {
+				sf := &settingsFrame{QPACKMaxTableCapacity: 1 << 16, QPACKBlockedStreams: 10}
+				buf := &bytes.Buffer{}
+				sf.Write(buf)
+				frame, err := parseNextFrame(buf)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(frame).To(Equal(sf))
+			})
+		})
+
+		Context("extended CONNECT and WebTransport", func() {
+			It("reads the settings", func() {
+				settings := appendVarInt(nil, settingExtendedConnect)
+				settings = appendVarInt(settings, 1)
+				settings = appendVarInt(settings, settingWebTransport)
+				settings = appendVarInt(settings, 1)
+				data := appendVarInt(nil, 4) // type byte
+				data = appendVarInt(data, uint64(len(settings)))
+				data = append(data, settings...)
+				f, err := parseNextFrame(bytes.NewReader(data))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(f).To(Equal(&settingsFrame{ExtendedConnect: true, WebTransport: true}))
+			})
+
+			It("rejects duplicate settings", func() {
+				settings := appendVarInt(nil, settingWebTransport)
+				settings = appendVarInt(settings, 1)
+				settings = appendVarInt(settings, settingWebTransport)
+				settings = appendVarInt(settings, 1)
+				data := appendVarInt(nil, 4) // type byte
+				data = appendVarInt(data, uint64(len(settings)))
+				data = append(data, settings...)
+				_, err := parseNextFrame(bytes.NewReader(data))
+				Expect(err).To(MatchError(fmt.Sprintf("duplicate setting: %d", settingWebTransport)))
+			})
+
+			It("rejects invalid values", func() {
+				settings := appendVarInt(nil, settingExtendedConnect)
+				settings = appendVarInt(settings, 2)
+				data := appendVarInt(nil, 4) // type byte
+				data = appendVarInt(data, uint64(len(settings)))
+				data = append(data, settings...)
+				_, err := parseNextFrame(bytes.NewReader(data))
+				Expect(err).To(MatchError("invalid value for SETTINGS_ENABLE_CONNECT_PROTOCOL: 2"))
+			})
+
+			It("writes the settings", func() {
+				sf := &settingsFrame{ExtendedConnect: true, Datagram: true, WebTransport: true}
+				buf := &bytes.Buffer{}
+				sf.Write(buf)
+				frame, err := parseNextFrame(buf)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(frame).To(Equal(sf))
+			})
+		})
+	})
+
+	Context("WEBTRANSPORT_STREAM frames", func() {
+		It("writes and parses", func() {
+			buf := &bytes.Buffer{}
+			(&webTransportStreamFrame{SessionID: 1337}).Write(buf)
+			buf.WriteString("foobar")
+			Expect(buf.Bytes()).To(Equal(append(appendVarInt(appendVarInt(nil, 0x41), 1337), []byte("foobar")...)))
+			frame, err := parseNextFrame(buf)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame).To(Equal(&webTransportStreamFrame{SessionID: 1337}))
+			// the frame doesn't have a length, the rest of the stream is application data
+			Expect(buf.String()).To(Equal("foobar"))
+		})
+	})
+
+	Context("PUSH_PROMISE frames", func() {
+		It("parses", func() {
+			data := appendVarInt(nil, 5) // type byte
+			data = appendVarInt(data, 2+0x1337)
+			data = appendVarInt(data, 0x42)
+			frame, err := parseNextFrame(bytes.NewReader(data))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame).To(Equal(&pushPromiseFrame{PushID: 0x42, Length: 0x1337}))
+		})
+
+		It("writes", func() {
+			buf := &bytes.Buffer{}
+			(&pushPromiseFrame{PushID: 0x1337, Length: 0xdead}).Write(buf)
+			frame, err := parseNextFrame(buf)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame).To(Equal(&pushPromiseFrame{PushID: 0x1337, Length: 0xdead}))
+		})
+
+		It("rejects frames that are too short for the push ID", func() {
+			data := appendVarInt(nil, 5) // type byte
+			data = appendVarInt(data, 1)
+			data = appendVarInt(data, 0x1337)
+			_, err := parseNextFrame(bytes.NewReader(data))
+			Expect(err).To(MatchError("invalid PUSH_PROMISE frame"))
+		})
+	})
+
+	Context("CANCEL_PUSH frames", func() {
+		It("writes and parses", func() {
+			buf := &bytes.Buffer{}
+			(&cancelPushFrame{PushID: 0x1337}).Write(buf)
+			frame, err := parseNextFrame(buf)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame).To(Equal(&cancelPushFrame{PushID: 0x1337}))
+		})
+
+		It("rejects invalid frames", func() {
+			data := appendVarInt(nil, 3) // type byte
+			data = appendVarInt(data, 2)
+			data = append(data, 0x1, 0x2)
+			_, err := parseNextFrame(bytes.NewReader(data))
+			Expect(err).To(MatchError("invalid CANCEL_PUSH frame"))
+		})
+	})
+
+	Context("MAX_PUSH_ID frames", func() {
+		It("writes and parses", func() {
+			buf := &bytes.Buffer{}
+			(&maxPushIDFrame{PushID: 0xdeadbeef}).Write(buf)
+			frame, err := parseNextFrame(buf)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame).To(Equal(&maxPushIDFrame{PushID: 0xdeadbeef}))
+		})
+
+		It("rejects invalid frames", func() {
+			data := appendVarInt(nil, 0xd) // type byte
+			data = appendVarInt(data, 9)
+			data = append(data, make([]byte, 9)...)
+			_, err := parseNextFrame(bytes.NewReader(data))
+			Expect(err).To(MatchError("unexpected size for MAX_PUSH_ID frame: 9"))
+		})
+	})
+
+	Context("GOAWAY frames", func() {
+		It("parses", func() {
+			data := appendVarInt(nil, 7) // type byte
+			data = appendVarInt(data, 2)
+			data = appendVarInt(data, 0x1337)
+			frame, err := parseNextFrame(bytes.NewReader(data))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame).To(Equal(&goAwayFrame{StreamID: 0x1337}))
+		})
+
+		It("writes", func() {
+			buf := &bytes.Buffer{}
+			(&goAwayFrame{StreamID: 0x1337}).Write(buf)
+			expected := appendVarInt(nil, 7) // type byte
+			expected = appendVarInt(expected, 2)
+			expected = appendVarInt(expected, 0x1337)
+			Expect(buf.Bytes()).To(Equal(expected))
+			frame, err := parseNextFrame(buf)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame).To(Equal(&goAwayFrame{StreamID: 0x1337}))
+		})
+
+		It("rejects frames with a length that doesn't match the stream ID", func() {
+			data := appendVarInt(nil, 7) // type byte
+			data = appendVarInt(data, 3)
+			data = appendVarInt(data, 0x1337)
+			data = append(data, 0)
+			_, err := parseNextFrame(bytes.NewReader(data))
+			Expect(err).To(MatchError("invalid GOAWAY frame"))
+		})
+
+		It("rejects too large frames", func() {
+			data := appendVarInt(nil, 7) // type byte
+			data = appendVarInt(data, 9)
+			data = append(data, make([]byte, 9)...)
+			_, err := parseNextFrame(bytes.NewReader(data))
+			Expect(err).To(MatchError("unexpected size for GOAWAY frame: 9"))
+		})
+
+		It("errors on EOF", func() {
+			data := appendVarInt(nil, 7) // type byte
+			data = appendVarInt(data, 2)
+			data = appendVarInt(data, 0x1337)
+			_, err := parseNextFrame(bytes.NewReader(data[:len(data)-1]))
+			Expect(err).To(MatchError(io.EOF))
+		})
+	})
+
+	Context("PRIORITY_UPDATE frames", func() {
+		It("parses frames for request streams", func() {
+			data := appendVarInt(nil, 0xf0700) // type byte
+			data = appendVarInt(data, 2+6)
+			data = appendVarInt(data, 0x1337)
+			data = append(data, []byte("u=2, i")...)
+			frame, err := parseNextFrame(bytes.NewReader(data))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame).To(Equal(&priorityUpdateFrame{ElementID: 0x1337, Priority: "u=2, i"}))
+		})
+
+		It("writes and parses frames for push streams", func() {
+			buf := &bytes.Buffer{}
+			(&priorityUpdateFrame{Push: true, ElementID: 42, Priority: "u=5"}).Write(buf)
+			expected := appendVarInt(nil, 0xf0701) // type byte
+			expected = appendVarInt(expected, 1+3)
+			expected = appendVarInt(expected, 42)
+			expected = append(expected, []byte("u=5")...)
+			Expect(buf.Bytes()).To(Equal(expected))
+			frame, err := parseNextFrame(buf)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame).To(Equal(&priorityUpdateFrame{Push: true, ElementID: 42, Priority: "u=5"}))
+		})
+
+		It("parses frames with an empty priority", func() {
+			buf := &bytes.Buffer{}
+			(&priorityUpdateFrame{ElementID: 4}).Write(buf)
+			frame, err := parseNextFrame(buf)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame).To(Equal(&priorityUpdateFrame{ElementID: 4, Priority: ""}))
+		})
+
+		It("rejects frames without an element ID", func() {
+			data := appendVarInt(nil, 0xf0700) // type byte
+			data = appendVarInt(data, 0)
+			_, err := parseNextFrame(bytes.NewReader(data))
+			Expect(err).To(MatchError("invalid PRIORITY_UPDATE frame"))
+		})
+
+		It("rejects too large frames", func() {
+			data := appendVarInt(nil, 0xf0700) // type byte
+			data = appendVarInt(data, maxPriorityUpdateFrameSize+1)
+			data = append(data, make([]byte, maxPriorityUpdateFrameSize+1)...)
+			_, err := parseNextFrame(bytes.NewReader(data))
+			Expect(err).To(MatchError(fmt.Sprintf("PRIORITY_UPDATE frame too large: %d bytes", maxPriorityUpdateFrameSize+1)))
+		})
+
+		It("errors on EOF", func() {
+			buf := &bytes.Buffer{}
+			(&priorityUpdateFrame{ElementID: 0x1337, Priority: "u=1"}).Write(buf)
+			_, err := parseNextFrame(bytes.NewReader(buf.Bytes()[:buf.Len()-1]))
+			Expect(err).To(MatchError(io.EOF))
+		})
 	})
 })
@@ -6,6 +6,7 @@ package http3
 // call gzip.NewReader on the first call to Read
 import (
 	"compress/gzip"
+	"context"
 	"io"
 )
 
@@ -37,3 +38,27 @@ func (gz *gzipReader) Read(p []byte) (n int, err error) {
 func (gz *gzipReader) Close() error {
 	return gz.body.Close()
 }
+
+// UpdatePriority changes the priority of the request, if the underlying body supports it.
+func (gz *gzipReader) UpdatePriority(p Priority) error {
+	if u, ok := gz.body.(PriorityUpdater); ok {
+		return u.UpdatePriority(p)
+	}
+	return errPriorityUpdateNotSupported
+}
+
+// SendDatagram sends an HTTP datagram, if the underlying body supports it.
+func (gz *gzipReader) SendDatagram(b []byte) error {
+	if d, ok := gz.body.(Datagrammer); ok {
+		return d.SendDatagram(b)
+	}
+	return errDatagramsNotEnabled
+}
+
+// ReceiveDatagram gets an HTTP datagram, if the underlying body supports it.
+func (gz *gzipReader) ReceiveDatagram(ctx context.Context) ([]byte, error) {
+	if d, ok := gz.body.(Datagrammer); ok {
+		return d.ReceiveDatagram(ctx)
+	}
+	return nil, errDatagramsNotEnabled
+}
@@ -0,0 +1,88 @@
+package http3
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/For-ACGN/quic-go"
+	"github.com/For-ACGN/quic-go/internal/protocol"
+)
+
+// PriorityHeader is the name of the header field carrying the priority of a request (RFC 9218).
+// Clients set it to the value returned by Priority.String.
+const PriorityHeader = "Priority"
+
+// maxPendingPriorityUpdates is the maximum number of PRIORITY_UPDATE frames the server buffers
+// for request streams that weren't opened yet.
+const maxPendingPriorityUpdates = 100
+
+// A Priority is the priority of an HTTP request (RFC 9218).
+// The server sends the data of more urgent responses first, see quic.Config.StreamScheduling.
+type Priority struct {
+	// Urgency ranges from 0 (most urgent) to 7 (least urgent).
+	Urgency uint8
+	// Incremental says if the client processes the response incrementally,
+	// i.e. if it benefits from receiving it interleaved with other responses of the same urgency.
+	Incremental bool
+}
+
+// DefaultPriority is the priority of a request that doesn't carry a Priority header field.
+var DefaultPriority = Priority{Urgency: protocol.DefaultStreamUrgency}
+
+// A PriorityUpdater changes the priority of an in-flight request, by sending a PRIORITY_UPDATE frame.
+// It is implemented by the http.Response.Body of responses received by the RoundTripper.
+type PriorityUpdater interface {
+	UpdatePriority(Priority) error
+}
+
+var errPriorityUpdateNotSupported = errors.New("http3: priority updates not supported")
+
+// ParsePriority parses the value of a Priority header field, a Structured Field Dictionary (RFC 8941).
+// Parameters that are absent or invalid take their default value, unknown parameters are ignored.
+func ParsePriority(s string) Priority {
+	p := DefaultPriority
+	for _, member := range strings.Split(s, ",") {
+		member = strings.TrimSpace(member)
+		// parameters of dictionary members are ignored
+		if i := strings.IndexByte(member, ';'); i != -1 {
+			member = member[:i]
+		}
+		key, value := member, "?1" // a member without a value is a boolean true
+		if i := strings.IndexByte(member, '='); i != -1 {
+			key, value = member[:i], member[i+1:]
+		}
+		switch key {
+		case "u":
+			if u, err := strconv.ParseUint(value, 10, 8); err == nil && u <= protocol.MaxStreamUrgency {
+				p.Urgency = uint8(u)
+			}
+		case "i":
+			switch value {
+			case "?1":
+				p.Incremental = true
+			case "?0":
+				p.Incremental = false
+			}
+		}
+	}
+	return p
+}
+
+// String returns the value of the Priority header field.
+// Parameters that have their default value are omitted,
+// i.e. the DefaultPriority results in an empty string.
+func (p Priority) String() string {
+	var params []string
+	if p.Urgency != DefaultPriority.Urgency {
+		params = append(params, "u="+strconv.Itoa(int(p.Urgency)))
+	}
+	if p.Incremental {
+		params = append(params, "i")
+	}
+	return strings.Join(params, ", ")
+}
+
+func (p Priority) streamPriority() quic.StreamPriority {
+	return quic.StreamPriority{Urgency: p.Urgency, Incremental: p.Incremental}
+}
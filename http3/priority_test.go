@@ -0,0 +1,49 @@
+package http3
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Priority", func() {
+	It("uses the default values", func() {
+		Expect(ParsePriority("")).To(Equal(Priority{Urgency: 3}))
+		Expect(ParsePriority("")).To(Equal(DefaultPriority))
+	})
+
+	It("parses the urgency and the incremental flag", func() {
+		Expect(ParsePriority("u=1")).To(Equal(Priority{Urgency: 1}))
+		Expect(ParsePriority("i")).To(Equal(Priority{Urgency: 3, Incremental: true}))
+		Expect(ParsePriority("u=0, i")).To(Equal(Priority{Urgency: 0, Incremental: true}))
+		Expect(ParsePriority("i=?1,u=7")).To(Equal(Priority{Urgency: 7, Incremental: true}))
+		Expect(ParsePriority("u=5, i=?0")).To(Equal(Priority{Urgency: 5}))
+	})
+
+	It("ignores parameters and unknown keys", func() {
+		Expect(ParsePriority("u=2;foo=bar, x=1, i;a")).To(Equal(Priority{Urgency: 2, Incremental: true}))
+	})
+
+	It("ignores invalid values", func() {
+		Expect(ParsePriority("u=8")).To(Equal(DefaultPriority))
+		Expect(ParsePriority("u=-1")).To(Equal(DefaultPriority))
+		Expect(ParsePriority("u=foo, i=1")).To(Equal(DefaultPriority))
+		Expect(ParsePriority("u=1, u=9")).To(Equal(Priority{Urgency: 1}))
+	})
+
+	It("uses the last value if a key is repeated", func() {
+		Expect(ParsePriority("u=1, u=4, i, i=?0")).To(Equal(Priority{Urgency: 4}))
+	})
+
+	It("composes the header field value", func() {
+		Expect(DefaultPriority.String()).To(BeEmpty())
+		Expect(Priority{Urgency: 1}.String()).To(Equal("u=1"))
+		Expect(Priority{Urgency: 3, Incremental: true}.String()).To(Equal("i"))
+		Expect(Priority{Urgency: 0, Incremental: true}.String()).To(Equal("u=0, i"))
+		for u := uint8(0); u <= 7; u++ {
+			for _, incremental := range []bool{false, true} {
+				p := Priority{Urgency: u, Incremental: incremental}
+				Expect(ParsePriority(p.String())).To(Equal(p))
+			}
+		}
+	})
+})
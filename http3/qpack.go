@@ -0,0 +1,324 @@
+package http3
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/For-ACGN/quic-go"
+	"github.com/For-ACGN/quic-go/quicvarint"
+	"github.com/marten-seemann/qpack"
+	"golang.org/x/net/http2/hpack"
+)
+
+// The QPACK encoder and decoder use the dynamic table defined in RFC 9204.
+// The field sections reference entries of the static and of the dynamic table.
+// The dynamic table is updated using instructions sent on the encoder stream,
+// and the decoder acknowledges field sections and insertions on the decoder stream.
+
+// qpackEntryOverhead is added to the length of name and value to calculate the size of an entry, see section 3.2.1 of RFC 9204.
+const qpackEntryOverhead = 32
+
+var errQPACKIntegerOverflow = errors.New("integer overflow")
+
+// A qpackError is a connection error caused by QPACK, see section 6 of RFC 9204.
+type qpackError struct {
+	code errorCode
+	err  error
+}
+
+func (e *qpackError) Error() string {
+	return fmt.Sprintf("%s: %s", e.code, e.err)
+}
+
+func newQPACKError(code errorCode, format string, a ...interface{}) error {
+	return &qpackError{code: code, err: fmt.Errorf(format, a...)}
+}
+
+// qpackStreamErrorCode returns the error code used to close the connection,
+// if reading from the encoder or decoder stream failed.
+func qpackStreamErrorCode(err error) errorCode {
+	var qerr *qpackError
+	if errors.As(err, &qerr) {
+		return qerr.code
+	}
+	// The encoder and decoder streams are critical streams.
+	return errorClosedCriticalStream
+}
+
+// openQPACKStreams opens the encoder and the decoder stream.
+// The streams are only opened if the dynamic table is enabled.
+func openQPACKStreams(sess quic.Session, encoder *qpackEncoder, decoder *qpackDecoder) error {
+	openStream := func(streamType uint64) (quic.SendStream, error) {
+		str, err := sess.OpenUniStream()
+		if err != nil {
+			return nil, err
+		}
+		buf := &bytes.Buffer{}
+		quicvarint.Write(buf, streamType)
+		if _, err := str.Write(buf.Bytes()); err != nil {
+			return nil, err
+		}
+		return str, nil
+	}
+	if encoder.maxCapacity > 0 {
+		str, err := openStream(streamTypeQPACKEncoderStream)
+		if err != nil {
+			return err
+		}
+		if err := encoder.setStream(str); err != nil {
+			return err
+		}
+	}
+	if decoder.maxCapacity > 0 {
+		str, err := openStream(streamTypeQPACKDecoderStream)
+		if err != nil {
+			return err
+		}
+		if err := decoder.setStream(str); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// qpackReader is implemented by bytes.Reader and bufio.Reader.
+type qpackReader interface {
+	io.Reader
+	io.ByteReader
+}
+
+func qpackEntrySize(hf qpack.HeaderField) uint64 {
+	return uint64(len(hf.Name)+len(hf.Value)) + qpackEntryOverhead
+}
+
+// qpackAppendInt appends an integer with an n-bit prefix, see section 4.1.1 of RFC 9204.
+// The bits of first that are not part of the prefix are preserved.
+func qpackAppendInt(b []byte, first byte, n uint8, i uint64) []byte {
+	max := uint64(1)<<n - 1
+	if i < max {
+		return append(b, first|byte(i))
+	}
+	b = append(b, first|byte(max))
+	i -= max
+	for ; i >= 0x80; i >>= 7 {
+		b = append(b, byte(i&0x7f)|0x80)
+	}
+	return append(b, byte(i))
+}
+
+// qpackReadInt reads an integer with an n-bit prefix.
+// first is the first byte of the integer, which has already been read.
+func qpackReadInt(r io.ByteReader, first byte, n uint8) (uint64, error) {
+	max := uint64(1)<<n - 1
+	i := uint64(first) & max
+	if i < max {
+		return i, nil
+	}
+	var m uint
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		i += uint64(b&0x7f) << m
+		if b&0x80 == 0 {
+			return i, nil
+		}
+		m += 7
+		if m >= 63 {
+			return 0, errQPACKIntegerOverflow
+		}
+	}
+}
+
+// qpackAppendString appends a string literal with an n-bit prefix, see section 4.1.2 of RFC 9204.
+// The string is Huffman encoded if that makes it shorter.
+func qpackAppendString(b []byte, first byte, n uint8, s string) []byte {
+	if l := hpack.HuffmanEncodeLength(s); l < uint64(len(s)) {
+		b = qpackAppendInt(b, first|1<<n, n, l)
+		return hpack.AppendHuffmanString(b, s)
+	}
+	b = qpackAppendInt(b, first, n, uint64(len(s)))
+	return append(b, s...)
+}
+
+// qpackReadString reads a string literal with an n-bit prefix.
+// first is the first byte of the string literal, which has already been read.
+func qpackReadString(r qpackReader, first byte, n uint8, maxLen uint64) (string, error) {
+	huffman := first&(1<<n) > 0
+	l, err := qpackReadInt(r, first, n)
+	if err != nil {
+		return "", err
+	}
+	if l > maxLen {
+		return "", fmt.Errorf("string literal too long: %d bytes", l)
+	}
+	b := make([]byte, l)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return "", err
+	}
+	if huffman {
+		return hpack.HuffmanDecodeToString(b)
+	}
+	return string(b), nil
+}
+
+// A qpackDynamicTable is a dynamic table, see section 3.2 of RFC 9204.
+// Entries are addressed by their absolute index.
+type qpackDynamicTable struct {
+	entries  []qpack.HeaderField // the first entry has the absolute index evicted
+	evicted  uint64              // the number of entries that were evicted
+	size     uint64
+	capacity uint64
+}
+
+// insertCount is the total number of entries inserted into the table.
+func (t *qpackDynamicTable) insertCount() uint64 {
+	return t.evicted + uint64(len(t.entries))
+}
+
+func (t *qpackDynamicTable) get(abs uint64) (qpack.HeaderField, bool) {
+	if abs < t.evicted || abs >= t.insertCount() {
+		return qpack.HeaderField{}, false
+	}
+	return t.entries[abs-t.evicted], true
+}
+
+// insert inserts an entry. The caller must make sure that the entry fits into the table.
+func (t *qpackDynamicTable) insert(hf qpack.HeaderField) {
+	t.entries = append(t.entries, hf)
+	t.size += qpackEntrySize(hf)
+}
+
+// evictOldest evicts the oldest entry.
+func (t *qpackDynamicTable) evictOldest() qpack.HeaderField {
+	hf := t.entries[0]
+	t.entries[0] = qpack.HeaderField{}
+	t.entries = t.entries[1:]
+	t.evicted++
+	t.size -= qpackEntrySize(hf)
+	return hf
+}
+
+// The static table, see Appendix A of RFC 9204.
+var qpackStaticTable = [...]qpack.HeaderField{
+	{Name: ":authority"},
+	{Name: ":path", Value: "/"},
+	{Name: "age", Value: "0"},
+	{Name: "content-disposition"},
+	{Name: "content-length", Value: "0"},
+	{Name: "cookie"},
+	{Name: "date"},
+	{Name: "etag"},
+	{Name: "if-modified-since"},
+	{Name: "if-none-match"},
+	{Name: "last-modified"},
+	{Name: "link"},
+	{Name: "location"},
+	{Name: "referer"},
+	{Name: "set-cookie"},
+	{Name: ":method", Value: "CONNECT"},
+	{Name: ":method", Value: "DELETE"},
+	{Name: ":method", Value: "GET"},
+	{Name: ":method", Value: "HEAD"},
+	{Name: ":method", Value: "OPTIONS"},
+	{Name: ":method", Value: "POST"},
+	{Name: ":method", Value: "PUT"},
+	{Name: ":scheme", Value: "http"},
+	{Name: ":scheme", Value: "https"},
+	{Name: ":status", Value: "103"},
+	{Name: ":status", Value: "200"},
+	{Name: ":status", Value: "304"},
+	{Name: ":status", Value: "404"},
+	{Name: ":status", Value: "503"},
+	{Name: "accept", Value: "*/*"},
+	{Name: "accept", Value: "application/dns-message"},
+	{Name: "accept-encoding", Value: "gzip, deflate, br"},
+	{Name: "accept-ranges", Value: "bytes"},
+	{Name: "access-control-allow-headers", Value: "cache-control"},
+	{Name: "access-control-allow-headers", Value: "content-type"},
+	{Name: "access-control-allow-origin", Value: "*"},
+	{Name: "cache-control", Value: "max-age=0"},
+	{Name: "cache-control", Value: "max-age=2592000"},
+	{Name: "cache-control", Value: "max-age=604800"},
+	{Name: "cache-control", Value: "no-cache"},
+	{Name: "cache-control", Value: "no-store"},
+	{Name: "cache-control", Value: "public, max-age=31536000"},
+	{Name: "content-encoding", Value: "br"},
+	{Name: "content-encoding", Value: "gzip"},
+	{Name: "content-type", Value: "application/dns-message"},
+	{Name: "content-type", Value: "application/javascript"},
+	{Name: "content-type", Value: "application/json"},
+	{Name: "content-type", Value: "application/x-www-form-urlencoded"},
+	{Name: "content-type", Value: "image/gif"},
+	{Name: "content-type", Value: "image/jpeg"},
+	{Name: "content-type", Value: "image/png"},
+	{Name: "content-type", Value: "text/css"},
+	{Name: "content-type", Value: "text/html; charset=utf-8"},
+	{Name: "content-type", Value: "text/plain"},
+	{Name: "content-type", Value: "text/plain;charset=utf-8"},
+	{Name: "range", Value: "bytes=0-"},
+	{Name: "strict-transport-security", Value: "max-age=31536000"},
+	{Name: "strict-transport-security", Value: "max-age=31536000; includesubdomains"},
+	{Name: "strict-transport-security", Value: "max-age=31536000; includesubdomains; preload"},
+	{Name: "vary", Value: "accept-encoding"},
+	{Name: "vary", Value: "origin"},
+	{Name: "x-content-type-options", Value: "nosniff"},
+	{Name: "x-xss-protection", Value: "1; mode=block"},
+	{Name: ":status", Value: "100"},
+	{Name: ":status", Value: "204"},
+	{Name: ":status", Value: "206"},
+	{Name: ":status", Value: "302"},
+	{Name: ":status", Value: "400"},
+	{Name: ":status", Value: "403"},
+	{Name: ":status", Value: "421"},
+	{Name: ":status", Value: "425"},
+	{Name: ":status", Value: "500"},
+	{Name: "accept-language"},
+	{Name: "access-control-allow-credentials", Value: "FALSE"},
+	{Name: "access-control-allow-credentials", Value: "TRUE"},
+	{Name: "access-control-allow-headers", Value: "*"},
+	{Name: "access-control-allow-methods", Value: "get"},
+	{Name: "access-control-allow-methods", Value: "get, post, options"},
+	{Name: "access-control-allow-methods", Value: "options"},
+	{Name: "access-control-expose-headers", Value: "content-length"},
+	{Name: "access-control-request-headers", Value: "content-type"},
+	{Name: "access-control-request-method", Value: "get"},
+	{Name: "access-control-request-method", Value: "post"},
+	{Name: "alt-svc", Value: "clear"},
+	{Name: "authorization"},
+	{Name: "content-security-policy", Value: "script-src 'none'; object-src 'none'; base-uri 'none'"},
+	{Name: "early-data", Value: "1"},
+	{Name: "expect-ct"},
+	{Name: "forwarded"},
+	{Name: "if-range"},
+	{Name: "origin"},
+	{Name: "purpose", Value: "prefetch"},
+	{Name: "server"},
+	{Name: "timing-allow-origin", Value: "*"},
+	{Name: "upgrade-insecure-requests", Value: "1"},
+	{Name: "user-agent"},
+	{Name: "x-forwarded-for"},
+	{Name: "x-frame-options", Value: "deny"},
+	{Name: "x-frame-options", Value: "sameorigin"},
+}
+
+var (
+	// the index of every field in the static table
+	qpackStaticFields = make(map[qpack.HeaderField]uint64, len(qpackStaticTable))
+	// the index of the first entry with a given name in the static table
+	qpackStaticNames = make(map[string]uint64)
+)
+
+func init() {
+	for i, hf := range qpackStaticTable {
+		if _, ok := qpackStaticFields[hf]; !ok {
+			qpackStaticFields[hf] = uint64(i)
+		}
+		if _, ok := qpackStaticNames[hf.Name]; !ok {
+			qpackStaticNames[hf.Name] = uint64(i)
+		}
+	}
+}
@@ -0,0 +1,452 @@
+package http3
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"sync"
+
+	"github.com/For-ACGN/quic-go"
+	"github.com/For-ACGN/quic-go/internal/protocol"
+	"github.com/marten-seemann/qpack"
+)
+
+var errQPACKDecoderClosed = errors.New("http3: QPACK decoder closed")
+
+// A qpackDecoder decodes the field sections received on a connection.
+// The peer's encoder inserts entries into the dynamic table using the encoder stream.
+// Field sections that reference entries that weren't received yet are blocked until the entries arrive.
+type qpackDecoder struct {
+	maxCapacity       uint64 // our SETTINGS_QPACK_MAX_TABLE_CAPACITY
+	maxBlockedStreams uint64 // our SETTINGS_QPACK_BLOCKED_STREAMS
+
+	mutex sync.Mutex
+
+	table               qpackDynamicTable
+	knownReceivedCount  uint64        // the Known Received Count of the peer's encoder
+	blockedStreams      uint64        // the number of field sections waiting for insertions
+	inserted            chan struct{} // closed (and replaced) every time entries are inserted
+	closeErr            error
+	encoderStreamOpened bool
+
+	str     io.Writer // the decoder stream, nil until it is opened
+	pending []byte    // instructions that are sent once the decoder stream is opened
+}
+
+func newQPACKDecoder(maxCapacity, maxBlockedStreams uint64) *qpackDecoder {
+	return &qpackDecoder{
+		maxCapacity:       maxCapacity,
+		maxBlockedStreams: maxBlockedStreams,
+		inserted:          make(chan struct{}),
+	}
+}
+
+// setStream sets the decoder stream, after the stream type was written.
+func (d *qpackDecoder) setStream(str io.Writer) error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.str = str
+	if len(d.pending) == 0 {
+		return nil
+	}
+	_, err := str.Write(d.pending)
+	d.pending = nil
+	return err
+}
+
+// writeInstruction sends an instruction on the decoder stream.
+// It must be called with the mutex held.
+func (d *qpackDecoder) writeInstruction(b []byte) error {
+	if d.str == nil {
+		d.pending = append(d.pending, b...)
+		return nil
+	}
+	_, err := d.str.Write(b)
+	return err
+}
+
+// close unblocks all blocked field sections. It is called when the connection is closed.
+func (d *qpackDecoder) close() {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if d.closeErr != nil {
+		return
+	}
+	d.closeErr = errQPACKDecoderClosed
+	close(d.inserted)
+}
+
+// decode decodes a field section received on stream id.
+// If the field section references entries that weren't received yet, decode blocks until
+// the entries are inserted, the context is canceled, or the decoder is closed.
+// Malformed field sections result in a *qpackError.
+func (d *qpackDecoder) decode(ctx context.Context, id protocol.StreamID, headerBlock []byte) ([]qpack.HeaderField, error) {
+	r := bytes.NewReader(headerBlock)
+	requiredInsertCount, base, err := d.readPrefix(r)
+	if err != nil {
+		return nil, err
+	}
+	if err := d.waitForInsertions(ctx, id, requiredInsertCount); err != nil {
+		return nil, err
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	var hfs []qpack.HeaderField
+	for r.Len() > 0 {
+		hf, err := d.readFieldLine(r, requiredInsertCount, base)
+		if err != nil {
+			if _, ok := err.(*qpackError); !ok {
+				err = newQPACKError(errorQPACKDecompressionFailed, "invalid field line: %s", err)
+			}
+			return nil, err
+		}
+		hfs = append(hfs, hf)
+	}
+	if requiredInsertCount > 0 {
+		if requiredInsertCount > d.knownReceivedCount {
+			d.knownReceivedCount = requiredInsertCount
+		}
+		// Section Acknowledgment
+		if err := d.writeInstruction(qpackAppendInt(nil, 0x80, 7, uint64(id))); err != nil {
+			return nil, err
+		}
+	}
+	return hfs, nil
+}
+
+// streamDecoder returns a function that decodes the field sections received on stream id.
+// QPACK errors close the connection.
+func (d *qpackDecoder) streamDecoder(ctx context.Context, id protocol.StreamID, sess quic.Session) func([]byte) ([]qpack.HeaderField, error) {
+	return func(headerBlock []byte) ([]qpack.HeaderField, error) {
+		hfs, err := d.decode(ctx, id, headerBlock)
+		var qerr *qpackError
+		if errors.As(err, &qerr) {
+			sess.CloseWithError(quic.ErrorCode(qerr.code), qerr.err.Error())
+		}
+		return hfs, err
+	}
+}
+
+// readPrefix reads the field section prefix, see section 4.5.1 of RFC 9204.
+func (d *qpackDecoder) readPrefix(r qpackReader) (requiredInsertCount, base uint64, _ error) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return 0, 0, newQPACKError(errorQPACKDecompressionFailed, "truncated field section prefix")
+	}
+	encodedInsertCount, err := qpackReadInt(r, b, 8)
+	if err != nil {
+		return 0, 0, newQPACKError(errorQPACKDecompressionFailed, "invalid Required Insert Count: %s", err)
+	}
+	b, err = r.ReadByte()
+	if err != nil {
+		return 0, 0, newQPACKError(errorQPACKDecompressionFailed, "truncated field section prefix")
+	}
+	deltaBase, err := qpackReadInt(r, b, 7)
+	if err != nil {
+		return 0, 0, newQPACKError(errorQPACKDecompressionFailed, "invalid Delta Base: %s", err)
+	}
+	if encodedInsertCount == 0 {
+		return 0, 0, nil
+	}
+
+	d.mutex.Lock()
+	totalInserts := d.table.insertCount()
+	d.mutex.Unlock()
+	// see section 4.5.1.1 of RFC 9204
+	maxEntries := d.maxCapacity / qpackEntryOverhead
+	fullRange := 2 * maxEntries
+	if encodedInsertCount > fullRange {
+		return 0, 0, newQPACKError(errorQPACKDecompressionFailed, "invalid Required Insert Count: %d", encodedInsertCount)
+	}
+	maxValue := totalInserts + maxEntries
+	maxWrapped := (maxValue / fullRange) * fullRange
+	requiredInsertCount = maxWrapped + encodedInsertCount - 1
+	if requiredInsertCount > maxValue {
+		if requiredInsertCount <= fullRange {
+			return 0, 0, newQPACKError(errorQPACKDecompressionFailed, "invalid Required Insert Count: %d", encodedInsertCount)
+		}
+		requiredInsertCount -= fullRange
+	}
+	if requiredInsertCount == 0 {
+		return 0, 0, newQPACKError(errorQPACKDecompressionFailed, "invalid Required Insert Count: %d", encodedInsertCount)
+	}
+	if b&0x80 == 0 {
+		return requiredInsertCount, requiredInsertCount + deltaBase, nil
+	}
+	if deltaBase >= requiredInsertCount {
+		return 0, 0, newQPACKError(errorQPACKDecompressionFailed, "invalid Delta Base: %d", deltaBase)
+	}
+	return requiredInsertCount, requiredInsertCount - deltaBase - 1, nil
+}
+
+// waitForInsertions blocks until the Required Insert Count of a field section is reached.
+func (d *qpackDecoder) waitForInsertions(ctx context.Context, id protocol.StreamID, requiredInsertCount uint64) error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if requiredInsertCount <= d.table.insertCount() {
+		return nil
+	}
+	if d.closeErr != nil {
+		return d.closeErr
+	}
+	if d.blockedStreams >= d.maxBlockedStreams {
+		return newQPACKError(errorQPACKDecompressionFailed, "too many blocked streams (max: %d)", d.maxBlockedStreams)
+	}
+	d.blockedStreams++
+	defer func() { d.blockedStreams-- }()
+	for requiredInsertCount > d.table.insertCount() {
+		inserted := d.inserted
+		d.mutex.Unlock()
+		select {
+		case <-inserted:
+			d.mutex.Lock()
+		case <-ctx.Done():
+			d.mutex.Lock()
+			// Stream Cancellation
+			d.writeInstruction(qpackAppendInt(nil, 0x40, 6, uint64(id)))
+			return ctx.Err()
+		}
+		if d.closeErr != nil {
+			return d.closeErr
+		}
+	}
+	return nil
+}
+
+// readFieldLine reads a field line, see section 4.5 of RFC 9204.
+// It must be called with the mutex held.
+func (d *qpackDecoder) readFieldLine(r *bytes.Reader, requiredInsertCount, base uint64) (qpack.HeaderField, error) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return qpack.HeaderField{}, err
+	}
+	// getDynamic returns the entry of the dynamic table with the absolute index abs
+	getDynamic := func(abs uint64) (qpack.HeaderField, error) {
+		if abs >= requiredInsertCount {
+			return qpack.HeaderField{}, newQPACKError(errorQPACKDecompressionFailed, "reference to entry %d exceeds the Required Insert Count", abs)
+		}
+		hf, ok := d.table.get(abs)
+		if !ok {
+			return qpack.HeaderField{}, newQPACKError(errorQPACKDecompressionFailed, "reference to evicted entry %d", abs)
+		}
+		return hf, nil
+	}
+	getRelative := func(idx uint64) (qpack.HeaderField, error) {
+		if idx >= base {
+			return qpack.HeaderField{}, newQPACKError(errorQPACKDecompressionFailed, "invalid relative index: %d", idx)
+		}
+		return getDynamic(base - 1 - idx)
+	}
+
+	var hf qpack.HeaderField
+	switch {
+	case b&0x80 > 0: // Indexed Field Line
+		idx, err := qpackReadInt(r, b, 6)
+		if err != nil {
+			return hf, err
+		}
+		if b&0x40 > 0 {
+			return qpackStaticEntry(idx)
+		}
+		return getRelative(idx)
+	case b&0xc0 == 0x40: // Literal Field Line with Name Reference
+		idx, err := qpackReadInt(r, b, 4)
+		if err != nil {
+			return hf, err
+		}
+		if b&0x10 > 0 {
+			hf, err = qpackStaticEntry(idx)
+		} else {
+			hf, err = getRelative(idx)
+		}
+		if err != nil {
+			return hf, err
+		}
+	case b&0xe0 == 0x20: // Literal Field Line with Literal Name
+		hf.Name, err = qpackReadString(r, b, 3, uint64(r.Len()))
+		if err != nil {
+			return hf, err
+		}
+	case b&0xf0 == 0x10: // Indexed Field Line with Post-Base Index
+		idx, err := qpackReadInt(r, b, 4)
+		if err != nil {
+			return hf, err
+		}
+		return getDynamic(base + idx)
+	default: // Literal Field Line with Post-Base Name Reference
+		idx, err := qpackReadInt(r, b, 3)
+		if err != nil {
+			return hf, err
+		}
+		if hf, err = getDynamic(base + idx); err != nil {
+			return hf, err
+		}
+	}
+	b, err = r.ReadByte()
+	if err != nil {
+		return hf, err
+	}
+	hf.Value, err = qpackReadString(r, b, 7, uint64(r.Len()))
+	return hf, err
+}
+
+func qpackStaticEntry(idx uint64) (qpack.HeaderField, error) {
+	if idx >= uint64(len(qpackStaticTable)) {
+		return qpack.HeaderField{}, newQPACKError(errorQPACKDecompressionFailed, "invalid static table index: %d", idx)
+	}
+	return qpackStaticTable[idx], nil
+}
+
+// readEncoderStream reads the instructions sent on the peer's encoder stream, after the stream type was read.
+// It only returns once reading from the stream fails.
+func (d *qpackDecoder) readEncoderStream(str io.Reader) error {
+	d.mutex.Lock()
+	if d.encoderStreamOpened {
+		d.mutex.Unlock()
+		return newQPACKError(errorStreamCreationError, "duplicate QPACK encoder stream")
+	}
+	d.encoderStreamOpened = true
+	d.mutex.Unlock()
+
+	r := bufio.NewReader(str)
+	for {
+		if err := d.handleEncoderInstruction(r); err != nil {
+			return err
+		}
+		// Acknowledge the insertions once all instructions that were received so far are processed.
+		if r.Buffered() == 0 {
+			if err := d.acknowledgeInsertions(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (d *qpackDecoder) handleEncoderInstruction(r qpackReader) error {
+	b, err := r.ReadByte()
+	if err != nil {
+		return err
+	}
+	// The capacity limits the size of the strings: an entry can't be larger than the table.
+	d.mutex.Lock()
+	maxLen := d.table.capacity
+	d.mutex.Unlock()
+
+	switch {
+	case b&0x80 > 0: // Insert with Name Reference
+		static := b&0x40 > 0
+		idx, err := qpackReadInt(r, b, 6)
+		if err != nil {
+			return err
+		}
+		b, err := r.ReadByte()
+		if err != nil {
+			return err
+		}
+		value, err := qpackReadString(r, b, 7, maxLen)
+		if err != nil {
+			return newQPACKError(errorQPACKEncoderStreamError, "invalid value: %s", err)
+		}
+		d.mutex.Lock()
+		defer d.mutex.Unlock()
+		var hf qpack.HeaderField
+		if static {
+			hf, err = qpackStaticEntry(idx)
+		} else if insertCount := d.table.insertCount(); idx < insertCount {
+			var ok bool
+			if hf, ok = d.table.get(insertCount - 1 - idx); !ok {
+				err = errors.New("reference to evicted entry")
+			}
+		} else {
+			err = errors.New("invalid relative index")
+		}
+		if err != nil {
+			return newQPACKError(errorQPACKEncoderStreamError, "invalid name reference %d: %s", idx, err)
+		}
+		return d.insert(qpack.HeaderField{Name: hf.Name, Value: value})
+	case b&0xc0 == 0x40: // Insert with Literal Name
+		name, err := qpackReadString(r, b, 5, maxLen)
+		if err != nil {
+			return newQPACKError(errorQPACKEncoderStreamError, "invalid name: %s", err)
+		}
+		b, err := r.ReadByte()
+		if err != nil {
+			return err
+		}
+		value, err := qpackReadString(r, b, 7, maxLen)
+		if err != nil {
+			return newQPACKError(errorQPACKEncoderStreamError, "invalid value: %s", err)
+		}
+		d.mutex.Lock()
+		defer d.mutex.Unlock()
+		return d.insert(qpack.HeaderField{Name: name, Value: value})
+	case b&0xe0 == 0x20: // Set Dynamic Table Capacity
+		capacity, err := qpackReadInt(r, b, 5)
+		if err != nil {
+			return err
+		}
+		d.mutex.Lock()
+		defer d.mutex.Unlock()
+		if capacity > d.maxCapacity {
+			return newQPACKError(errorQPACKEncoderStreamError, "dynamic table capacity %d exceeds the limit (%d)", capacity, d.maxCapacity)
+		}
+		d.table.capacity = capacity
+		d.evict()
+		return nil
+	default: // Duplicate
+		idx, err := qpackReadInt(r, b, 5)
+		if err != nil {
+			return err
+		}
+		d.mutex.Lock()
+		defer d.mutex.Unlock()
+		insertCount := d.table.insertCount()
+		if idx >= insertCount {
+			return newQPACKError(errorQPACKEncoderStreamError, "invalid relative index: %d", idx)
+		}
+		hf, ok := d.table.get(insertCount - 1 - idx)
+		if !ok {
+			return newQPACKError(errorQPACKEncoderStreamError, "reference to evicted entry %d", insertCount-1-idx)
+		}
+		return d.insert(hf)
+	}
+}
+
+// insert inserts an entry into the dynamic table, evicting old entries if necessary.
+// It must be called with the mutex held.
+func (d *qpackDecoder) insert(hf qpack.HeaderField) error {
+	if qpackEntrySize(hf) > d.table.capacity {
+		return newQPACKError(errorQPACKEncoderStreamError, "entry of size %d exceeds the dynamic table capacity (%d)", qpackEntrySize(hf), d.table.capacity)
+	}
+	d.table.insert(hf)
+	d.evict()
+	if d.closeErr == nil {
+		close(d.inserted)
+		d.inserted = make(chan struct{})
+	}
+	return nil
+}
+
+// evict evicts entries until the table doesn't exceed its capacity.
+// It must be called with the mutex held.
+func (d *qpackDecoder) evict() {
+	for d.table.size > d.table.capacity {
+		d.table.evictOldest()
+	}
+}
+
+// acknowledgeInsertions sends an Insert Count Increment instruction, if there are unacknowledged insertions.
+func (d *qpackDecoder) acknowledgeInsertions() error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	insertCount := d.table.insertCount()
+	if insertCount <= d.knownReceivedCount {
+		return nil
+	}
+	increment := insertCount - d.knownReceivedCount
+	d.knownReceivedCount = insertCount
+	return d.writeInstruction(qpackAppendInt(nil, 0, 6, increment))
+}
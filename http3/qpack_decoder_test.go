@@ -0,0 +1,215 @@
+package http3
+
+import (
+	"bytes"
+	"context"
+	"io"
+
+	"github.com/For-ACGN/quic-go"
+	mockquic "github.com/For-ACGN/quic-go/internal/mocks/quic"
+	"github.com/For-ACGN/quic-go/internal/protocol"
+
+	"github.com/marten-seemann/qpack"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("QPACK decoder", func() {
+	var (
+		encoder        *qpackEncoder
+		decoder        *qpackDecoder
+		encBuf, decBuf *bytes.Buffer
+	)
+
+	fields := []qpack.HeaderField{
+		{Name: ":status", Value: "200"},
+		{Name: "content-type", Value: "text/markdown"},
+		{Name: "server", Value: "quic-go"},
+		{Name: "x-custom", Value: "foobar"},
+	}
+
+	BeforeEach(func() {
+		encBuf = &bytes.Buffer{}
+		decBuf = &bytes.Buffer{}
+		encoder = newQPACKEncoder(1000)
+		Expect(encoder.setStream(encBuf)).To(Succeed())
+		Expect(encoder.setPeerSettings(1000, 10)).To(Succeed())
+		decoder = newQPACKDecoder(1000, 10)
+		Expect(decoder.setStream(decBuf)).To(Succeed())
+	})
+
+	// processEncoderStream passes the instructions sent on the encoder stream to the decoder
+	processEncoderStream := func() {
+		for encBuf.Len() > 0 {
+			ExpectWithOffset(1, decoder.handleEncoderInstruction(encBuf)).To(Succeed())
+		}
+		ExpectWithOffset(1, decoder.acknowledgeInsertions()).To(Succeed())
+	}
+
+	// processDecoderStream passes the instructions sent on the decoder stream to the encoder
+	processDecoderStream := func() {
+		for decBuf.Len() > 0 {
+			ExpectWithOffset(1, encoder.handleDecoderInstruction(decBuf)).To(Succeed())
+		}
+	}
+
+	It("decodes field sections that reference the dynamic table", func() {
+		data, err := encoder.encode(4, fields)
+		Expect(err).ToNot(HaveOccurred())
+		processEncoderStream()
+		Expect(decoder.decode(context.Background(), 4, data)).To(Equal(fields))
+		processDecoderStream()
+		Expect(encoder.knownReceivedCount).To(BeEquivalentTo(3))
+		Expect(encoder.sections).To(BeEmpty())
+		Expect(encoder.refs).To(BeEmpty())
+		// use name references into the dynamic table
+		fields2 := []qpack.HeaderField{
+			{Name: "x-custom", Value: "lorem ipsum"},
+			{Name: "content-length", Value: "1337"},
+		}
+		data, err = encoder.encode(8, fields2)
+		Expect(err).ToNot(HaveOccurred())
+		processEncoderStream()
+		Expect(decoder.decode(context.Background(), 8, data)).To(Equal(fields2))
+	})
+
+	It("decodes field sections after entries were evicted", func() {
+		encBuf.Reset()
+		encoder = newQPACKEncoder(200)
+		Expect(encoder.setStream(encBuf)).To(Succeed())
+		Expect(encoder.setPeerSettings(200, 10)).To(Succeed())
+		decoder = newQPACKDecoder(200, 10)
+		Expect(decoder.setStream(decBuf)).To(Succeed())
+		for i := 0; i < 20; i++ {
+			hfs := []qpack.HeaderField{{Name: "x-counter", Value: string(rune('a' + i))}, {Name: "x-foo", Value: "bar"}}
+			data, err := encoder.encode(protocol.StreamID(4*i), hfs)
+			Expect(err).ToNot(HaveOccurred())
+			processEncoderStream()
+			Expect(decoder.decode(context.Background(), protocol.StreamID(4*i), data)).To(Equal(hfs))
+			processDecoderStream()
+		}
+		Expect(decoder.table.evicted).ToNot(BeZero())
+		Expect(decoder.table.size).To(BeNumerically("<=", 200))
+	})
+
+	It("blocks until the referenced entries are inserted", func() {
+		data, err := encoder.encode(4, fields)
+		Expect(err).ToNot(HaveOccurred())
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			defer close(done)
+			Expect(decoder.decode(context.Background(), 4, data)).To(Equal(fields))
+		}()
+		Consistently(done).ShouldNot(BeClosed())
+		processEncoderStream()
+		Eventually(done).Should(BeClosed())
+	})
+
+	It("sends a Stream Cancellation when a blocked field section is canceled", func() {
+		data, err := encoder.encode(4, fields)
+		Expect(err).ToNot(HaveOccurred())
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			defer close(done)
+			_, err := decoder.decode(ctx, 4, data)
+			Expect(err).To(MatchError(context.Canceled))
+		}()
+		Consistently(done).ShouldNot(BeClosed())
+		cancel()
+		Eventually(done).Should(BeClosed())
+		Expect(decBuf.Bytes()).To(Equal(qpackAppendInt(nil, 0x40, 6, 4)))
+		processDecoderStream()
+		Expect(encoder.sections).To(BeEmpty())
+	})
+
+	It("unblocks field sections when it is closed", func() {
+		data, err := encoder.encode(4, fields)
+		Expect(err).ToNot(HaveOccurred())
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			defer close(done)
+			_, err := decoder.decode(context.Background(), 4, data)
+			Expect(err).To(MatchError(errQPACKDecoderClosed))
+		}()
+		Consistently(done).ShouldNot(BeClosed())
+		decoder.close()
+		Eventually(done).Should(BeClosed())
+	})
+
+	It("errors when too many streams are blocked", func() {
+		decoder.maxBlockedStreams = 0
+		data, err := encoder.encode(4, fields)
+		Expect(err).ToNot(HaveOccurred())
+		_, err = decoder.decode(context.Background(), 4, data)
+		Expect(err).To(MatchError("QPACK_DECOMPRESSION_FAILED: too many blocked streams (max: 0)"))
+	})
+
+	It("errors on references to the dynamic table if it is disabled", func() {
+		data, err := encoder.encode(4, fields)
+		Expect(err).ToNot(HaveOccurred())
+		_, err = newQPACKDecoder(0, 0).decode(context.Background(), 4, data)
+		Expect(err).To(BeAssignableToTypeOf(&qpackError{}))
+		Expect(err.(*qpackError).code).To(Equal(errorQPACKDecompressionFailed))
+	})
+
+	It("errors on invalid static table indices", func() {
+		_, err := decoder.decode(context.Background(), 4, qpackAppendInt([]byte{0, 0}, 0xc0, 6, 99))
+		Expect(err).To(MatchError("QPACK_DECOMPRESSION_FAILED: invalid static table index: 99"))
+	})
+
+	It("closes the connection on QPACK errors", func() {
+		sess := mockquic.NewMockEarlySession(mockCtrl)
+		sess.EXPECT().CloseWithError(quic.ErrorCode(errorQPACKDecompressionFailed), "invalid static table index: 99")
+		decode := decoder.streamDecoder(context.Background(), 4, sess)
+		_, err := decode(qpackAppendInt([]byte{0, 0}, 0xc0, 6, 99))
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("errors when the capacity exceeds the limit", func() {
+		err := decoder.handleEncoderInstruction(bytes.NewReader(qpackAppendInt(nil, 0x20, 5, 1001)))
+		Expect(err).To(MatchError("QPACK_ENCODER_STREAM_ERROR: dynamic table capacity 1001 exceeds the limit (1000)"))
+	})
+
+	It("errors when an entry exceeds the capacity", func() {
+		Expect(decoder.handleEncoderInstruction(bytes.NewReader(qpackAppendInt(nil, 0x20, 5, 40)))).To(Succeed())
+		b := qpackAppendString(nil, 0x40, 5, "foo")
+		b = qpackAppendString(b, 0, 7, "foobar")
+		err := decoder.handleEncoderInstruction(bytes.NewReader(b))
+		Expect(err).To(MatchError("QPACK_ENCODER_STREAM_ERROR: entry of size 41 exceeds the dynamic table capacity (40)"))
+	})
+
+	It("handles Duplicate instructions", func() {
+		Expect(decoder.handleEncoderInstruction(bytes.NewReader(qpackAppendInt(nil, 0x20, 5, 1000)))).To(Succeed())
+		b := qpackAppendString(nil, 0x40, 5, "foo")
+		b = qpackAppendString(b, 0, 7, "bar")
+		Expect(decoder.handleEncoderInstruction(bytes.NewReader(b))).To(Succeed())
+		Expect(decoder.handleEncoderInstruction(bytes.NewReader(qpackAppendInt(nil, 0, 5, 0)))).To(Succeed())
+		Expect(decoder.table.insertCount()).To(BeEquivalentTo(2))
+		hf, ok := decoder.table.get(1)
+		Expect(ok).To(BeTrue())
+		Expect(hf).To(Equal(qpack.HeaderField{Name: "foo", Value: "bar"}))
+		err := decoder.handleEncoderInstruction(bytes.NewReader(qpackAppendInt(nil, 0, 5, 2)))
+		Expect(err).To(MatchError("QPACK_ENCODER_STREAM_ERROR: invalid relative index: 2"))
+	})
+
+	It("acknowledges insertions when reading from the encoder stream", func() {
+		_, err := encoder.encode(4, fields)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(decoder.readEncoderStream(encBuf)).To(MatchError(io.EOF))
+		Expect(decBuf.Bytes()).To(Equal(qpackAppendInt(nil, 0, 6, 3)))
+		processDecoderStream()
+		Expect(encoder.knownReceivedCount).To(BeEquivalentTo(3))
+	})
+
+	It("errors when the peer opens a second encoder stream", func() {
+		Expect(decoder.readEncoderStream(&bytes.Buffer{})).To(MatchError(io.EOF))
+		err := decoder.readEncoderStream(&bytes.Buffer{})
+		Expect(err).To(BeAssignableToTypeOf(&qpackError{}))
+		Expect(err.(*qpackError).code).To(Equal(errorStreamCreationError))
+	})
+})
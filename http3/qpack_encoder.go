@@ -0,0 +1,372 @@
+package http3
+
+import (
+	"bufio"
+	"io"
+	"sync"
+
+	"github.com/For-ACGN/quic-go/internal/protocol"
+	"github.com/marten-seemann/qpack"
+)
+
+// An unacknowledged field section that references the dynamic table.
+type qpackSection struct {
+	requiredInsertCount uint64
+	refs                []uint64 // the absolute indices of the referenced entries
+}
+
+// A qpackEncoder encodes the field sections sent on a connection.
+// Once the peer's SETTINGS frame was received, and the encoder stream was opened,
+// fields are inserted into the dynamic table.
+type qpackEncoder struct {
+	maxCapacity uint64 // our limit for the capacity of the dynamic table
+
+	mutex sync.Mutex
+
+	str                 io.Writer // the encoder stream, nil until it is opened
+	settingsReceived    bool
+	peerMaxCapacity     uint64 // the SETTINGS_QPACK_MAX_TABLE_CAPACITY sent by the peer
+	maxBlockedStreams   uint64 // the SETTINGS_QPACK_BLOCKED_STREAMS sent by the peer
+	decoderStreamOpened bool
+
+	table              qpackDynamicTable
+	knownReceivedCount uint64
+	fields             map[qpack.HeaderField]uint64 // the absolute index of the newest entry for every field
+	names              map[string]uint64            // the absolute index of the newest entry for every name
+	refs               map[uint64]int               // the number of references from unacknowledged field sections
+	sections           map[protocol.StreamID][]qpackSection
+}
+
+func newQPACKEncoder(maxCapacity uint64) *qpackEncoder {
+	return &qpackEncoder{
+		maxCapacity: maxCapacity,
+		fields:      make(map[qpack.HeaderField]uint64),
+		names:       make(map[string]uint64),
+		refs:        make(map[uint64]int),
+		sections:    make(map[protocol.StreamID][]qpackSection),
+	}
+}
+
+// setStream sets the encoder stream, after the stream type was written.
+func (e *qpackEncoder) setStream(str io.Writer) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.str = str
+	return e.maybeEnableDynamicTable()
+}
+
+// setPeerSettings is called when the peer's SETTINGS frame is received.
+func (e *qpackEncoder) setPeerSettings(maxCapacity, maxBlockedStreams uint64) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.settingsReceived = true
+	e.peerMaxCapacity = maxCapacity
+	e.maxBlockedStreams = maxBlockedStreams
+	return e.maybeEnableDynamicTable()
+}
+
+// maybeEnableDynamicTable sets the capacity of the dynamic table,
+// once we know the peer's limit and the encoder stream is open.
+func (e *qpackEncoder) maybeEnableDynamicTable() error {
+	if e.str == nil || !e.settingsReceived || e.table.capacity > 0 {
+		return nil
+	}
+	capacity := e.maxCapacity
+	if e.peerMaxCapacity < capacity {
+		capacity = e.peerMaxCapacity
+	}
+	if capacity == 0 {
+		return nil
+	}
+	e.table.capacity = capacity
+	// Set Dynamic Table Capacity
+	_, err := e.str.Write(qpackAppendInt(nil, 0x20, 5, capacity))
+	return err
+}
+
+// The representation of a field line, see section 4.5 of RFC 9204.
+type qpackFieldLine struct {
+	field   qpack.HeaderField
+	index   uint64 // the index into the static table, or the absolute index into the dynamic table
+	static  bool
+	indexed bool // use an indexed field line, otherwise a literal field line with a name reference
+	literal bool // use a literal field line with a literal name
+}
+
+// encode encodes a field section sent on stream id.
+// Insertions into the dynamic table are written to the encoder stream before the field section is returned.
+func (e *qpackEncoder) encode(id protocol.StreamID, fields []qpack.HeaderField) ([]byte, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	// A field section may reference entries that weren't acknowledged yet,
+	// if that doesn't exceed the number of blocked streams allowed by the peer.
+	canBlock := e.isBlocking(id) || e.blockingStreams() < e.maxBlockedStreams
+	usable := func(abs uint64) bool { return abs < e.knownReceivedCount || canBlock }
+
+	var instructions []byte
+	var refs []uint64
+	reference := func(abs uint64) {
+		e.refs[abs]++
+		refs = append(refs, abs)
+	}
+	lines := make([]qpackFieldLine, 0, len(fields))
+	for _, hf := range fields {
+		if idx, ok := qpackStaticFields[hf]; ok {
+			lines = append(lines, qpackFieldLine{field: hf, index: idx, static: true, indexed: true})
+			continue
+		}
+		if abs, ok := e.fields[hf]; ok && usable(abs) {
+			reference(abs)
+			lines = append(lines, qpackFieldLine{field: hf, index: abs, indexed: true})
+			continue
+		}
+		if qpackShouldIndex(hf) {
+			var abs uint64
+			var ok bool
+			instructions, abs, ok = e.insert(instructions, hf)
+			if ok && usable(abs) {
+				reference(abs)
+				lines = append(lines, qpackFieldLine{field: hf, index: abs, indexed: true})
+				continue
+			}
+		}
+		if idx, ok := qpackStaticNames[hf.Name]; ok {
+			lines = append(lines, qpackFieldLine{field: hf, index: idx, static: true})
+		} else if abs, ok := e.names[hf.Name]; ok && usable(abs) {
+			reference(abs)
+			lines = append(lines, qpackFieldLine{field: hf, index: abs})
+		} else {
+			lines = append(lines, qpackFieldLine{field: hf, literal: true})
+		}
+	}
+
+	if len(instructions) > 0 {
+		if _, err := e.str.Write(instructions); err != nil {
+			return nil, err
+		}
+	}
+
+	var requiredInsertCount uint64
+	for _, abs := range refs {
+		if abs+1 > requiredInsertCount {
+			requiredInsertCount = abs + 1
+		}
+	}
+	if requiredInsertCount > 0 {
+		e.sections[id] = append(e.sections[id], qpackSection{requiredInsertCount: requiredInsertCount, refs: refs})
+	}
+
+	// All references to the dynamic table use relative indices (and no post-base indices),
+	// since the Base is the number of entries inserted so far.
+	base := e.table.insertCount()
+	var b []byte
+	if requiredInsertCount == 0 {
+		b = append(b, 0, 0)
+	} else {
+		// Encoded Required Insert Count, see section 4.5.1.1 of RFC 9204
+		maxEntries := e.peerMaxCapacity / qpackEntryOverhead
+		b = qpackAppendInt(b, 0, 8, requiredInsertCount%(2*maxEntries)+1)
+		// the sign bit is 0, since the Base is not smaller than the Required Insert Count
+		b = qpackAppendInt(b, 0, 7, base-requiredInsertCount)
+	}
+	for _, l := range lines {
+		switch {
+		case l.literal:
+			b = qpackAppendString(b, 0x20, 3, l.field.Name)
+			b = qpackAppendString(b, 0, 7, l.field.Value)
+		case l.indexed && l.static:
+			b = qpackAppendInt(b, 0xc0, 6, l.index)
+		case l.indexed:
+			b = qpackAppendInt(b, 0x80, 6, base-1-l.index)
+		case l.static:
+			b = qpackAppendInt(b, 0x50, 4, l.index)
+			b = qpackAppendString(b, 0, 7, l.field.Value)
+		default:
+			b = qpackAppendInt(b, 0x40, 4, base-1-l.index)
+			b = qpackAppendString(b, 0, 7, l.field.Value)
+		}
+	}
+	return b, nil
+}
+
+// qpackShouldIndex says if a field is worth inserting into the dynamic table.
+// Fields that are different for (almost) every request or response aren't inserted.
+func qpackShouldIndex(hf qpack.HeaderField) bool {
+	switch hf.Name {
+	case ":path", "content-length", "content-range", "date", "etag", "last-modified":
+		return false
+	}
+	return true
+}
+
+// insert inserts a field into the dynamic table, and appends the instruction to the encoder stream.
+// It returns false if there's not enough space in the table.
+func (e *qpackEncoder) insert(instructions []byte, hf qpack.HeaderField) ([]byte, uint64, bool) {
+	if !e.makeRoom(qpackEntrySize(hf)) {
+		return instructions, 0, false
+	}
+	if idx, ok := qpackStaticNames[hf.Name]; ok {
+		// Insert with Name Reference, referencing the static table
+		instructions = qpackAppendInt(instructions, 0xc0, 6, idx)
+	} else if abs, ok := e.names[hf.Name]; ok {
+		// Insert with Name Reference, referencing the dynamic table
+		instructions = qpackAppendInt(instructions, 0x80, 6, e.table.insertCount()-1-abs)
+	} else {
+		// Insert with Literal Name
+		instructions = qpackAppendString(instructions, 0x40, 5, hf.Name)
+	}
+	instructions = qpackAppendString(instructions, 0, 7, hf.Value)
+	abs := e.table.insertCount()
+	e.table.insert(hf)
+	e.fields[hf] = abs
+	e.names[hf.Name] = abs
+	return instructions, abs, true
+}
+
+// makeRoom evicts entries, such that an entry of the given size can be inserted.
+// Entries can only be evicted once their insertion was acknowledged,
+// and if they aren't referenced by any unacknowledged field section.
+func (e *qpackEncoder) makeRoom(size uint64) bool {
+	if size > e.table.capacity {
+		return false
+	}
+	var n int
+	freed := uint64(0)
+	for e.table.size-freed+size > e.table.capacity {
+		abs := e.table.evicted + uint64(n)
+		if abs >= e.knownReceivedCount || e.refs[abs] > 0 {
+			return false
+		}
+		freed += qpackEntrySize(e.table.entries[n])
+		n++
+	}
+	for i := 0; i < n; i++ {
+		abs := e.table.evicted
+		hf := e.table.evictOldest()
+		if e.fields[hf] == abs {
+			delete(e.fields, hf)
+		}
+		if e.names[hf.Name] == abs {
+			delete(e.names, hf.Name)
+		}
+	}
+	return true
+}
+
+// isBlocking says if stream id has a field section that might be blocked by the decoder.
+func (e *qpackEncoder) isBlocking(id protocol.StreamID) bool {
+	for _, s := range e.sections[id] {
+		if s.requiredInsertCount > e.knownReceivedCount {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *qpackEncoder) blockingStreams() uint64 {
+	var n uint64
+	for id := range e.sections {
+		if e.isBlocking(id) {
+			n++
+		}
+	}
+	return n
+}
+
+// readDecoderStream reads the instructions sent on the peer's decoder stream, after the stream type was read.
+// It only returns once reading from the stream fails.
+func (e *qpackEncoder) readDecoderStream(str io.Reader) error {
+	e.mutex.Lock()
+	if e.decoderStreamOpened {
+		e.mutex.Unlock()
+		return newQPACKError(errorStreamCreationError, "duplicate QPACK decoder stream")
+	}
+	e.decoderStreamOpened = true
+	e.mutex.Unlock()
+
+	r := bufio.NewReader(str)
+	for {
+		if err := e.handleDecoderInstruction(r); err != nil {
+			return err
+		}
+	}
+}
+
+func (e *qpackEncoder) handleDecoderInstruction(r qpackReader) error {
+	b, err := r.ReadByte()
+	if err != nil {
+		return err
+	}
+	switch {
+	case b&0x80 > 0: // Section Acknowledgment
+		id, err := qpackReadInt(r, b, 7)
+		if err != nil {
+			return err
+		}
+		return e.acknowledgeSection(protocol.StreamID(id))
+	case b&0x40 > 0: // Stream Cancellation
+		id, err := qpackReadInt(r, b, 6)
+		if err != nil {
+			return err
+		}
+		e.cancelStream(protocol.StreamID(id))
+		return nil
+	default: // Insert Count Increment
+		increment, err := qpackReadInt(r, b, 6)
+		if err != nil {
+			return err
+		}
+		return e.incrementInsertCount(increment)
+	}
+}
+
+func (e *qpackEncoder) acknowledgeSection(id protocol.StreamID) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	sections := e.sections[id]
+	if len(sections) == 0 {
+		return newQPACKError(errorQPACKDecoderStreamError, "Section Acknowledgment for stream %d without outstanding field sections", id)
+	}
+	s := sections[0]
+	if len(sections) == 1 {
+		delete(e.sections, id)
+	} else {
+		e.sections[id] = sections[1:]
+	}
+	e.release(s)
+	if s.requiredInsertCount > e.knownReceivedCount {
+		e.knownReceivedCount = s.requiredInsertCount
+	}
+	return nil
+}
+
+func (e *qpackEncoder) cancelStream(id protocol.StreamID) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	for _, s := range e.sections[id] {
+		e.release(s)
+	}
+	delete(e.sections, id)
+}
+
+func (e *qpackEncoder) incrementInsertCount(increment uint64) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	if increment == 0 || e.knownReceivedCount+increment > e.table.insertCount() {
+		return newQPACKError(errorQPACKDecoderStreamError, "invalid Insert Count Increment: %d", increment)
+	}
+	e.knownReceivedCount += increment
+	return nil
+}
+
+// release removes the references of an acknowledged or canceled field section.
+func (e *qpackEncoder) release(s qpackSection) {
+	for _, abs := range s.refs {
+		if e.refs[abs] <= 1 {
+			delete(e.refs, abs)
+		} else {
+			e.refs[abs]--
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package http3
+
+import (
+	"bytes"
+
+	"github.com/For-ACGN/quic-go/internal/protocol"
+
+	"github.com/marten-seemann/qpack"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("QPACK encoder", func() {
+	var (
+		encoder *qpackEncoder
+		encBuf  *bytes.Buffer
+	)
+
+	fields := []qpack.HeaderField{
+		{Name: ":status", Value: "200"},
+		{Name: "content-type", Value: "text/markdown"},
+		{Name: "server", Value: "quic-go"},
+		{Name: "x-custom", Value: "foobar"},
+	}
+
+	BeforeEach(func() {
+		encoder = newQPACKEncoder(1000)
+		encBuf = &bytes.Buffer{}
+	})
+
+	// decodeStatic decodes a field section that doesn't reference the dynamic table
+	decodeStatic := func(data []byte) []qpack.HeaderField {
+		hfs, err := qpack.NewDecoder(nil).DecodeFull(data)
+		ExpectWithOffset(1, err).ToNot(HaveOccurred())
+		return hfs
+	}
+
+	It("only uses the static table if the dynamic table is disabled", func() {
+		encoder = newQPACKEncoder(0)
+		Expect(encoder.setPeerSettings(1000, 10)).To(Succeed())
+		data, err := encoder.encode(0, fields)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(decodeStatic(data)).To(Equal(fields))
+	})
+
+	It("only uses the static table before the peer's SETTINGS were received", func() {
+		Expect(encoder.setStream(encBuf)).To(Succeed())
+		data, err := encoder.encode(0, fields)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(decodeStatic(data)).To(Equal(fields))
+		Expect(encBuf.Len()).To(BeZero())
+	})
+
+	It("uses the smaller of both capacities", func() {
+		Expect(encoder.setPeerSettings(2000, 10)).To(Succeed())
+		Expect(encoder.setStream(encBuf)).To(Succeed())
+		Expect(encBuf.Bytes()).To(Equal(qpackAppendInt(nil, 0x20, 5, 1000)))
+		Expect(encoder.table.capacity).To(BeEquivalentTo(1000))
+	})
+
+	It("inserts fields into the dynamic table, and references them", func() {
+		Expect(encoder.setStream(encBuf)).To(Succeed())
+		Expect(encoder.setPeerSettings(1000, 10)).To(Succeed())
+		encBuf.Reset()
+		data, err := encoder.encode(4, fields)
+		Expect(err).ToNot(HaveOccurred())
+		// content-type, server and x-custom are inserted, :status 200 is in the static table
+		Expect(encoder.table.insertCount()).To(BeEquivalentTo(3))
+		Expect(encBuf.Len()).ToNot(BeZero())
+		Expect(encoder.sections).To(HaveKeyWithValue(protocol.StreamID(4), []qpackSection{
+			{requiredInsertCount: 3, refs: []uint64{0, 1, 2}},
+		}))
+		encBuf.Reset()
+		// encoding the same fields again doesn't insert anything
+		data2, err := encoder.encode(8, fields)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(encBuf.Len()).To(BeZero())
+		Expect(data2).To(Equal(data))
+		staticData, err := newQPACKEncoder(0).encode(8, fields)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(len(data2)).To(BeNumerically("<", len(staticData)))
+	})
+
+	It("doesn't insert fields that change for every request", func() {
+		Expect(encoder.setStream(encBuf)).To(Succeed())
+		Expect(encoder.setPeerSettings(1000, 10)).To(Succeed())
+		_, err := encoder.encode(0, []qpack.HeaderField{
+			{Name: ":path", Value: "/foo"},
+			{Name: "content-length", Value: "1337"},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(encoder.table.insertCount()).To(BeZero())
+	})
+
+	It("doesn't reference unacknowledged entries if the peer doesn't allow blocked streams", func() {
+		Expect(encoder.setStream(encBuf)).To(Succeed())
+		Expect(encoder.setPeerSettings(1000, 0)).To(Succeed())
+		data, err := encoder.encode(0, fields)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(encoder.table.insertCount()).To(BeEquivalentTo(3))
+		Expect(decodeStatic(data)).To(Equal(fields))
+		Expect(encoder.sections).To(BeEmpty())
+		// once the insertions are acknowledged, the entries are referenced
+		Expect(encoder.handleDecoderInstruction(bytes.NewReader(qpackAppendInt(nil, 0, 6, 3)))).To(Succeed())
+		_, err = encoder.encode(4, fields)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(encoder.sections).To(HaveKey(protocol.StreamID(4)))
+	})
+
+	It("limits the number of blocked streams", func() {
+		Expect(encoder.setStream(encBuf)).To(Succeed())
+		Expect(encoder.setPeerSettings(1000, 1)).To(Succeed())
+		_, err := encoder.encode(0, fields)
+		Expect(err).ToNot(HaveOccurred())
+		// stream 0 is already blocking, so it may reference unacknowledged entries
+		_, err = encoder.encode(0, []qpack.HeaderField{{Name: "x-custom", Value: "foobar"}})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(encoder.sections[0]).To(HaveLen(2))
+		data, err := encoder.encode(4, fields)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(decodeStatic(data)).To(Equal(fields))
+		Expect(encoder.blockingStreams()).To(BeEquivalentTo(1))
+	})
+
+	It("only evicts entries that were acknowledged and are not referenced", func() {
+		Expect(encoder.setStream(encBuf)).To(Succeed())
+		Expect(encoder.setPeerSettings(100, 10)).To(Succeed())
+		foo := qpack.HeaderField{Name: "foo", Value: "foobarfoobarfoobar"}
+		bar := qpack.HeaderField{Name: "bar", Value: "foobarfoobarfoobar"}
+		_, err := encoder.encode(0, []qpack.HeaderField{foo})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(encoder.table.insertCount()).To(BeEquivalentTo(1))
+		// there's no space for bar, since foo is still referenced
+		_, err = encoder.encode(4, []qpack.HeaderField{bar})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(encoder.table.insertCount()).To(BeEquivalentTo(1))
+		// Section Acknowledgment for stream 0
+		Expect(encoder.handleDecoderInstruction(bytes.NewReader(qpackAppendInt(nil, 0x80, 7, 0)))).To(Succeed())
+		Expect(encoder.knownReceivedCount).To(BeEquivalentTo(1))
+		Expect(encoder.refs).To(BeEmpty())
+		_, err = encoder.encode(4, []qpack.HeaderField{bar})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(encoder.table.insertCount()).To(BeEquivalentTo(2))
+		Expect(encoder.table.evicted).To(BeEquivalentTo(1))
+		Expect(encoder.fields).ToNot(HaveKey(foo))
+		Expect(encoder.fields).To(HaveKeyWithValue(bar, uint64(1)))
+	})
+
+	It("releases the references when a stream is canceled", func() {
+		Expect(encoder.setStream(encBuf)).To(Succeed())
+		Expect(encoder.setPeerSettings(1000, 10)).To(Succeed())
+		_, err := encoder.encode(4, fields)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(encoder.refs).ToNot(BeEmpty())
+		// Stream Cancellation
+		Expect(encoder.handleDecoderInstruction(bytes.NewReader(qpackAppendInt(nil, 0x40, 6, 4)))).To(Succeed())
+		Expect(encoder.refs).To(BeEmpty())
+		Expect(encoder.sections).To(BeEmpty())
+	})
+
+	It("errors on Section Acknowledgments for streams without field sections", func() {
+		err := encoder.handleDecoderInstruction(bytes.NewReader(qpackAppendInt(nil, 0x80, 7, 4)))
+		Expect(err).To(MatchError("QPACK_DECODER_STREAM_ERROR: Section Acknowledgment for stream 4 without outstanding field sections"))
+	})
+
+	It("errors on invalid Insert Count Increments", func() {
+		Expect(encoder.setStream(encBuf)).To(Succeed())
+		Expect(encoder.setPeerSettings(1000, 10)).To(Succeed())
+		_, err := encoder.encode(0, fields)
+		Expect(err).ToNot(HaveOccurred())
+		err = encoder.handleDecoderInstruction(bytes.NewReader(qpackAppendInt(nil, 0, 6, 0)))
+		Expect(err).To(MatchError("QPACK_DECODER_STREAM_ERROR: invalid Insert Count Increment: 0"))
+		err = encoder.handleDecoderInstruction(bytes.NewReader(qpackAppendInt(nil, 0, 6, 4)))
+		Expect(err).To(MatchError("QPACK_DECODER_STREAM_ERROR: invalid Insert Count Increment: 4"))
+	})
+
+	It("errors when the peer opens a second decoder stream", func() {
+		Expect(encoder.readDecoderStream(&bytes.Buffer{})).ToNot(BeAssignableToTypeOf(&qpackError{}))
+		err := encoder.readDecoderStream(&bytes.Buffer{})
+		Expect(err).To(BeAssignableToTypeOf(&qpackError{}))
+		Expect(err.(*qpackError).code).To(Equal(errorStreamCreationError))
+	})
+})
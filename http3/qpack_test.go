@@ -0,0 +1,144 @@
+package http3
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	mockquic "github.com/For-ACGN/quic-go/internal/mocks/quic"
+	"github.com/For-ACGN/quic-go/quicvarint"
+
+	"github.com/golang/mock/gomock"
+	"github.com/marten-seemann/qpack"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("QPACK", func() {
+	Context("integers", func() {
+		It("encodes integers that fit into the prefix", func() {
+			Expect(qpackAppendInt(nil, 0xa0, 5, 10)).To(Equal([]byte{0xaa}))
+		})
+
+		It("encodes integers that don't fit into the prefix", func() {
+			// example from section C.1.2 of RFC 7541
+			Expect(qpackAppendInt(nil, 0, 5, 1337)).To(Equal([]byte{0x1f, 0x9a, 0x0a}))
+		})
+
+		for _, n := range []uint8{3, 4, 5, 6, 7, 8} {
+			prefix := n
+
+			It(fmt.Sprintf("reads what it wrote, for a %d bit prefix", prefix), func() {
+				for _, i := range []uint64{0, 1, 1<<prefix - 2, 1<<prefix - 1, 1 << prefix, 1337, 1 << 40} {
+					b := qpackAppendInt(nil, 0, prefix, i)
+					r := bytes.NewReader(b[1:])
+					v, err := qpackReadInt(r, b[0], prefix)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(v).To(Equal(i))
+					Expect(r.Len()).To(BeZero())
+				}
+			})
+		}
+
+		It("errors on overflows", func() {
+			b := bytes.Repeat([]byte{0xff}, 10)
+			_, err := qpackReadInt(bytes.NewReader(b), 0xff, 8)
+			Expect(err).To(MatchError(errQPACKIntegerOverflow))
+		})
+	})
+
+	Context("strings", func() {
+		It("uses Huffman encoding if it's shorter", func() {
+			b := qpackAppendString(nil, 0, 7, "www.example.com")
+			Expect(b[0] & 0x80).ToNot(BeZero())
+			Expect(len(b)).To(BeNumerically("<", 1+len("www.example.com")))
+			r := bytes.NewReader(b[1:])
+			s, err := qpackReadString(r, b[0], 7, 100)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(s).To(Equal("www.example.com"))
+		})
+
+		It("doesn't use Huffman encoding if it's not shorter", func() {
+			b := qpackAppendString(nil, 0x40, 5, "{}")
+			Expect(b).To(Equal([]byte{0x42, '{', '}'}))
+			s, err := qpackReadString(bytes.NewReader(b[1:]), b[0], 5, 100)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(s).To(Equal("{}"))
+		})
+
+		It("errors when a string is too long", func() {
+			b := qpackAppendString(nil, 0, 7, "{foobar}")
+			_, err := qpackReadString(bytes.NewReader(b[1:]), b[0], 7, 7)
+			Expect(err).To(MatchError("string literal too long: 8 bytes"))
+		})
+	})
+
+	Context("dynamic table", func() {
+		It("inserts and evicts entries", func() {
+			t := &qpackDynamicTable{capacity: 100}
+			t.insert(qpack.HeaderField{Name: "foo", Value: "bar"})
+			t.insert(qpack.HeaderField{Name: "lorem", Value: "ipsum"})
+			Expect(t.insertCount()).To(BeEquivalentTo(2))
+			Expect(t.size).To(BeEquivalentTo(38 + 42))
+			Expect(t.evictOldest()).To(Equal(qpack.HeaderField{Name: "foo", Value: "bar"}))
+			Expect(t.insertCount()).To(BeEquivalentTo(2))
+			Expect(t.size).To(BeEquivalentTo(42))
+			_, ok := t.get(0)
+			Expect(ok).To(BeFalse())
+			hf, ok := t.get(1)
+			Expect(ok).To(BeTrue())
+			Expect(hf).To(Equal(qpack.HeaderField{Name: "lorem", Value: "ipsum"}))
+			_, ok = t.get(2)
+			Expect(ok).To(BeFalse())
+		})
+	})
+
+	It("has the static table of RFC 9204", func() {
+		Expect(qpackStaticTable).To(HaveLen(99))
+		Expect(qpackStaticFields).To(HaveKeyWithValue(qpack.HeaderField{Name: ":method", Value: "GET"}, uint64(17)))
+		Expect(qpackStaticNames).To(HaveKeyWithValue("user-agent", uint64(95)))
+	})
+
+	Context("opening the encoder and decoder streams", func() {
+		It("doesn't open any streams if the dynamic table is disabled", func() {
+			sess := mockquic.NewMockEarlySession(mockCtrl)
+			Expect(openQPACKStreams(sess, newQPACKEncoder(0), newQPACKDecoder(0, 0))).To(Succeed())
+		})
+
+		It("opens the streams", func() {
+			sess := mockquic.NewMockEarlySession(mockCtrl)
+			encBuf := &bytes.Buffer{}
+			encStr := mockquic.NewMockStream(mockCtrl)
+			encStr.EXPECT().Write(gomock.Any()).DoAndReturn(encBuf.Write).AnyTimes()
+			decBuf := &bytes.Buffer{}
+			decStr := mockquic.NewMockStream(mockCtrl)
+			decStr.EXPECT().Write(gomock.Any()).DoAndReturn(decBuf.Write).AnyTimes()
+			gomock.InOrder(
+				sess.EXPECT().OpenUniStream().Return(encStr, nil),
+				sess.EXPECT().OpenUniStream().Return(decStr, nil),
+			)
+			encoder := newQPACKEncoder(1000)
+			decoder := newQPACKDecoder(1000, 10)
+			Expect(openQPACKStreams(sess, encoder, decoder)).To(Succeed())
+			Expect(quicvarint.Read(encBuf)).To(BeEquivalentTo(streamTypeQPACKEncoderStream))
+			Expect(quicvarint.Read(decBuf)).To(BeEquivalentTo(streamTypeQPACKDecoderStream))
+			// The capacity is only set once the peer's SETTINGS were received.
+			Expect(encBuf.Len()).To(BeZero())
+			Expect(encoder.setPeerSettings(500, 10)).To(Succeed())
+			Expect(encBuf.Bytes()).To(Equal(qpackAppendInt(nil, 0x20, 5, 500)))
+		})
+
+		It("returns errors that occur when opening the streams", func() {
+			sess := mockquic.NewMockEarlySession(mockCtrl)
+			testErr := errors.New("test error")
+			sess.EXPECT().OpenUniStream().Return(nil, testErr)
+			Expect(openQPACKStreams(sess, newQPACKEncoder(1000), newQPACKDecoder(0, 0))).To(MatchError(testErr))
+		})
+	})
+
+	It("uses the error code of QPACK errors", func() {
+		Expect(qpackStreamErrorCode(newQPACKError(errorQPACKEncoderStreamError, "foobar"))).To(Equal(errorQPACKEncoderStreamError))
+		Expect(qpackStreamErrorCode(errors.New("read error"))).To(Equal(errorClosedCriticalStream))
+	})
+})
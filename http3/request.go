@@ -12,7 +12,7 @@ import (
 )
 
 func requestFromHeaders(headers []qpack.HeaderField) (*http.Request, error) {
-	var path, authority, method, contentLengthStr string
+	var path, authority, method, protocol, scheme, contentLengthStr string
 	httpHeaders := http.Header{}
 
 	for _, h := range headers {
@@ -23,6 +23,10 @@ func requestFromHeaders(headers []qpack.HeaderField) (*http.Request, error) {
 			method = h.Value
 		case ":authority":
 			authority = h.Value
+		case ":protocol":
+			protocol = h.Value
+		case ":scheme":
+			scheme = h.Value
 		case "content-length":
 			contentLengthStr = h.Value
 		default:
@@ -36,9 +40,19 @@ func requestFromHeaders(headers []qpack.HeaderField) (*http.Request, error) {
 	if len(httpHeaders["Cookie"]) > 0 {
 		httpHeaders.Set("Cookie", strings.Join(httpHeaders["Cookie"], "; "))
 	}
+	trailer := declaredTrailers(httpHeaders)
 
 	isConnect := method == http.MethodConnect
-	if isConnect {
+	// Extended CONNECT, see RFC 8441 and RFC 9220
+	isExtendedConnect := isConnect && protocol != ""
+	if protocol != "" && !isConnect {
+		return nil, errors.New(":protocol must only be used with the CONNECT method")
+	}
+	if isExtendedConnect {
+		if path == "" || authority == "" || scheme == "" {
+			return nil, errors.New("extended CONNECT: :path, :authority and :scheme must not be empty")
+		}
+	} else if isConnect {
 		if path != "" || authority == "" {
 			return nil, errors.New(":path must be empty and :authority must not be empty")
 		}
@@ -50,7 +64,15 @@ func requestFromHeaders(headers []qpack.HeaderField) (*http.Request, error) {
 	var requestURI string
 	var err error
 
-	if isConnect {
+	if isExtendedConnect {
+		u, err = url.ParseRequestURI(path)
+		if err != nil {
+			return nil, err
+		}
+		u.Scheme = scheme
+		u.Host = authority
+		requestURI = path
+	} else if isConnect {
 		u = &url.URL{Host: authority}
 		requestURI = authority
 	} else {
@@ -69,13 +91,19 @@ func requestFromHeaders(headers []qpack.HeaderField) (*http.Request, error) {
 		}
 	}
 
+	proto := "HTTP/3"
+	if isExtendedConnect {
+		proto = protocol
+	}
+
 	return &http.Request{
 		Method:        method,
 		URL:           u,
-		Proto:         "HTTP/3",
+		Proto:         proto,
 		ProtoMajor:    3,
 		ProtoMinor:    0,
 		Header:        httpHeaders,
+		Trailer:       trailer,
 		Body:          nil,
 		ContentLength: contentLength,
 		Host:          authority,
@@ -84,6 +112,20 @@ func requestFromHeaders(headers []qpack.HeaderField) (*http.Request, error) {
 	}, nil
 }
 
+// extendedConnectProtocol returns the value of the :protocol pseudo header
+// of an extended CONNECT request (RFC 9220), or an empty string for all other requests.
+// Analogous to Go's HTTP/2 implementation, the protocol is carried in the Proto field.
+func extendedConnectProtocol(req *http.Request) string {
+	if req.Method != http.MethodConnect {
+		return ""
+	}
+	switch req.Proto {
+	case "", "HTTP/1.0", "HTTP/1.1", "HTTP/2.0", "HTTP/3", "HTTP/3.0":
+		return ""
+	}
+	return req.Proto
+}
+
 func hostnameFromRequest(req *http.Request) string {
 	if req.URL != nil {
 		return req.URL.Host
@@ -81,6 +81,22 @@ var _ = Describe("Request", func() {
 		}))
 	})
 
+	It("parses the declared trailers", func() {
+		headers := []qpack.HeaderField{
+			{Name: ":path", Value: "/foo"},
+			{Name: ":authority", Value: "quic.clemente.io"},
+			{Name: ":method", Value: "POST"},
+			{Name: "trailer", Value: "grpc-status, Grpc-Message,content-length"},
+		}
+		req, err := requestFromHeaders(headers)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(req.Header).To(BeEmpty())
+		Expect(req.Trailer).To(Equal(http.Header{
+			"Grpc-Status":  nil,
+			"Grpc-Message": nil,
+		}))
+	})
+
 	It("handles CONNECT method", func() {
 		headers := []qpack.HeaderField{
 			{Name: ":authority", Value: "quic.clemente.io"},
@@ -90,6 +106,47 @@ var _ = Describe("Request", func() {
 		Expect(err).NotTo(HaveOccurred())
 		Expect(req.Method).To(Equal(http.MethodConnect))
 		Expect(req.RequestURI).To(Equal("quic.clemente.io"))
+		Expect(extendedConnectProtocol(req)).To(BeEmpty())
+	})
+
+	It("handles extended CONNECT requests", func() {
+		headers := []qpack.HeaderField{
+			{Name: ":authority", Value: "quic.clemente.io"},
+			{Name: ":method", Value: http.MethodConnect},
+			{Name: ":protocol", Value: "webtransport"},
+			{Name: ":scheme", Value: "https"},
+			{Name: ":path", Value: "/foo?bar=baz"},
+		}
+		req, err := requestFromHeaders(headers)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(req.Method).To(Equal(http.MethodConnect))
+		Expect(req.Proto).To(Equal("webtransport"))
+		Expect(req.URL.String()).To(Equal("https://quic.clemente.io/foo?bar=baz"))
+		Expect(req.RequestURI).To(Equal("/foo?bar=baz"))
+		Expect(extendedConnectProtocol(req)).To(Equal("webtransport"))
+	})
+
+	It("errors with missing scheme in extended CONNECT requests", func() {
+		headers := []qpack.HeaderField{
+			{Name: ":authority", Value: "quic.clemente.io"},
+			{Name: ":method", Value: http.MethodConnect},
+			{Name: ":protocol", Value: "webtransport"},
+			{Name: ":path", Value: "/foo"},
+		}
+		_, err := requestFromHeaders(headers)
+		Expect(err).To(MatchError("extended CONNECT: :path, :authority and :scheme must not be empty"))
+	})
+
+	It("errors when :protocol is used without the CONNECT method", func() {
+		headers := []qpack.HeaderField{
+			{Name: ":authority", Value: "quic.clemente.io"},
+			{Name: ":method", Value: http.MethodGet},
+			{Name: ":protocol", Value: "webtransport"},
+			{Name: ":scheme", Value: "https"},
+			{Name: ":path", Value: "/foo"},
+		}
+		_, err := requestFromHeaders(headers)
+		Expect(err).To(MatchError(":protocol must only be used with the CONNECT method"))
 	})
 
 	It("errors with missing path", func() {
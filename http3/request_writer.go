@@ -8,9 +8,9 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
-	"sync"
 
 	"github.com/For-ACGN/quic-go"
+	"github.com/For-ACGN/quic-go/internal/protocol"
 	"github.com/For-ACGN/quic-go/internal/utils"
 	"github.com/marten-seemann/qpack"
 	"golang.org/x/net/http/httpguts"
@@ -21,33 +21,34 @@ import (
 const bodyCopyBufferSize = 8 * 1024
 
 type requestWriter struct {
-	mutex     sync.Mutex
-	encoder   *qpack.Encoder
-	headerBuf *bytes.Buffer
+	encoder *qpackEncoder
 
 	logger utils.Logger
 }
 
-func newRequestWriter(logger utils.Logger) *requestWriter {
-	headerBuf := &bytes.Buffer{}
-	encoder := qpack.NewEncoder(headerBuf)
+func newRequestWriter(encoder *qpackEncoder, logger utils.Logger) *requestWriter {
 	return &requestWriter{
-		encoder:   encoder,
-		headerBuf: headerBuf,
-		logger:    logger,
+		encoder: encoder,
+		logger:  logger,
 	}
 }
 
 func (w *requestWriter) WriteRequest(str quic.Stream, req *http.Request, gzip bool) error {
+	trailers, err := commaSeparatedTrailers(req)
+	if err != nil {
+		return err
+	}
 	buf := &bytes.Buffer{}
-	if err := w.writeHeaders(buf, req, gzip); err != nil {
+	if err := w.writeHeaders(buf, str.StreamID(), req, gzip, trailers); err != nil {
 		return err
 	}
 	if _, err := str.Write(buf.Bytes()); err != nil {
 		return err
 	}
-	// TODO: add support for trailers
 	if req.Body == nil {
+		if err := w.writeTrailers(str, str.StreamID(), req.Trailer); err != nil {
+			return err
+		}
 		str.Close()
 		return nil
 	}
@@ -84,57 +85,77 @@ func (w *requestWriter) WriteRequest(str quic.Stream, req *http.Request, gzip bo
 				return
 			}
 		}
+		// The values of the trailers can be set while the body is read.
+		if err := w.writeTrailers(str, str.StreamID(), req.Trailer); err != nil {
+			w.logger.Errorf("Error writing request trailers: %s", err)
+			return
+		}
 		str.Close()
 	}()
 
 	return nil
 }
 
-func (w *requestWriter) writeHeaders(wr io.Writer, req *http.Request, gzip bool) error {
-	w.mutex.Lock()
-	defer w.mutex.Unlock()
-	defer w.encoder.Close()
-
-	if err := w.encodeHeaders(req, gzip, "", actualContentLength(req)); err != nil {
+func (w *requestWriter) writeHeaders(wr io.Writer, id protocol.StreamID, req *http.Request, gzip bool, trailers string) error {
+	hfs, err := w.encodeHeaders(req, gzip, trailers, actualContentLength(req))
+	if err != nil {
+		return err
+	}
+	headers, err := w.encoder.encode(id, hfs)
+	if err != nil {
 		return err
 	}
 
 	buf := &bytes.Buffer{}
-	hf := headersFrame{Length: uint64(w.headerBuf.Len())}
+	hf := headersFrame{Length: uint64(len(headers))}
 	hf.Write(buf)
 	if _, err := wr.Write(buf.Bytes()); err != nil {
 		return err
 	}
-	if _, err := wr.Write(w.headerBuf.Bytes()); err != nil {
+	_, err = wr.Write(headers)
+	return err
+}
+
+// writeTrailers writes a HEADERS frame containing the trailers, if there are any.
+func (w *requestWriter) writeTrailers(wr io.Writer, id protocol.StreamID, trailer http.Header) error {
+	if len(trailer) == 0 {
+		return nil
+	}
+	headers, err := w.encoder.encode(id, trailerFields(trailer))
+	if err != nil {
 		return err
 	}
-	w.headerBuf.Reset()
-	return nil
+	buf := &bytes.Buffer{}
+	(&headersFrame{Length: uint64(len(headers))}).Write(buf)
+	buf.Write(headers)
+	_, err = wr.Write(buf.Bytes())
+	return err
 }
 
 // copied from net/transport.go
 
-func (w *requestWriter) encodeHeaders(req *http.Request, addGzipHeader bool, trailers string, contentLength int64) error {
+func (w *requestWriter) encodeHeaders(req *http.Request, addGzipHeader bool, trailers string, contentLength int64) ([]qpack.HeaderField, error) {
 	host := req.Host
 	if host == "" {
 		host = req.URL.Host
 	}
 	host, err := httpguts.PunycodeHostPort(host)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
+	protocol := extendedConnectProtocol(req)
 	var path string
-	if req.Method != "CONNECT" {
+	if req.Method != "CONNECT" || protocol != "" {
 		path = req.URL.RequestURI()
 		if !validPseudoPath(path) {
 			orig := path
 			path = strings.TrimPrefix(path, req.URL.Scheme+"://"+host)
 			if !validPseudoPath(path) {
 				if req.URL.Opaque != "" {
-					return fmt.Errorf("invalid request :path %q from URL.Opaque = %q", orig, req.URL.Opaque)
+					return nil, fmt.Errorf("invalid request :path %q from URL.Opaque = %q", orig, req.URL.Opaque)
 				} else {
-					return fmt.Errorf("invalid request :path %q", orig)
+					return nil, fmt.Errorf("invalid request :path %q", orig)
 				}
 			}
 		}
@@ -145,11 +166,11 @@ func (w *requestWriter) encodeHeaders(req *http.Request, addGzipHeader bool, tra
 	// continue to reuse the hpack encoder for future requests)
 	for k, vv := range req.Header {
 		if !httpguts.ValidHeaderFieldName(k) {
-			return fmt.Errorf("invalid HTTP header name %q", k)
+			return nil, fmt.Errorf("invalid HTTP header name %q", k)
 		}
 		for _, v := range vv {
 			if !httpguts.ValidHeaderFieldValue(v) {
-				return fmt.Errorf("invalid HTTP header value %q for header %q", v, k)
+				return nil, fmt.Errorf("invalid HTTP header value %q for header %q", v, k)
 			}
 		}
 	}
@@ -162,7 +183,10 @@ func (w *requestWriter) encodeHeaders(req *http.Request, addGzipHeader bool, tra
 		// [RFC3986]).
 		f(":authority", host)
 		f(":method", req.Method)
-		if req.Method != "CONNECT" {
+		if protocol != "" {
+			f(":protocol", protocol)
+		}
+		if req.Method != "CONNECT" || protocol != "" {
 			f(":path", path)
 			f(":scheme", req.URL.Scheme)
 		}
@@ -234,15 +258,16 @@ func (w *requestWriter) encodeHeaders(req *http.Request, addGzipHeader bool, tra
 	// traceHeaders := traceHasWroteHeaderField(trace)
 
 	// Header list size is ok. Write the headers.
+	var hfs []qpack.HeaderField
 	enumerateHeaders(func(name, value string) {
 		name = strings.ToLower(name)
-		w.encoder.WriteField(qpack.HeaderField{Name: name, Value: value})
+		hfs = append(hfs, qpack.HeaderField{Name: name, Value: value})
 		// if traceHeaders {
 		// 	traceWroteHeaderField(trace, name, value)
 		// }
 	})
 
-	return nil
+	return hfs, nil
 }
 
 // authorityAddr returns a given authority (a host/IP, or host:port / ip:port)
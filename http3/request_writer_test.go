@@ -48,12 +48,13 @@ var _ = Describe("Request Writer", func() {
 	}
 
 	BeforeEach(func() {
-		rw = newRequestWriter(utils.DefaultLogger)
+		rw = newRequestWriter(newQPACKEncoder(0), utils.DefaultLogger)
 		strBuf = &bytes.Buffer{}
 		str = mockquic.NewMockStream(mockCtrl)
 		str.EXPECT().Write(gomock.Any()).DoAndReturn(func(p []byte) (int, error) {
 			return strBuf.Write(p)
 		}).AnyTimes()
+		str.EXPECT().StreamID().AnyTimes()
 	})
 
 	It("writes a GET request", func() {
@@ -108,6 +109,43 @@ var _ = Describe("Request Writer", func() {
 		Expect(frame.(*dataFrame).Length).To(BeEquivalentTo(6))
 	})
 
+	It("sends trailers after the body", func() {
+		closed := make(chan struct{})
+		str.EXPECT().Close().Do(func() { close(closed) })
+		req, err := http.NewRequest("POST", "https://quic.clemente.io/upload.html", &foobarReader{})
+		Expect(err).ToNot(HaveOccurred())
+		req.Trailer = http.Header{"Grpc-Status": nil}
+		// the trailer values are usually set while the body is read
+		req.Trailer.Set("Grpc-Status", "0")
+		Expect(rw.WriteRequest(str, req, false)).To(Succeed())
+
+		Eventually(closed).Should(BeClosed())
+		Expect(decode(strBuf)).To(HaveKeyWithValue("trailer", "Grpc-Status"))
+		frame, err := parseNextFrame(strBuf)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(frame).To(Equal(&dataFrame{Length: 6}))
+		strBuf.Next(6)
+		Expect(decode(strBuf)).To(Equal(map[string]string{"grpc-status": "0"}))
+		Expect(strBuf.Len()).To(BeZero())
+	})
+
+	It("sends trailers for requests without a body", func() {
+		str.EXPECT().Close()
+		req, err := http.NewRequest("GET", "https://quic.clemente.io/", nil)
+		Expect(err).ToNot(HaveOccurred())
+		req.Trailer = http.Header{"Foo": []string{"bar"}}
+		Expect(rw.WriteRequest(str, req, false)).To(Succeed())
+		Expect(decode(strBuf)).To(HaveKeyWithValue("trailer", "Foo"))
+		Expect(decode(strBuf)).To(Equal(map[string]string{"foo": "bar"}))
+	})
+
+	It("rejects invalid trailer keys", func() {
+		req, err := http.NewRequest("GET", "https://quic.clemente.io/", nil)
+		Expect(err).ToNot(HaveOccurred())
+		req.Trailer = http.Header{"Content-Length": []string{"42"}}
+		Expect(rw.WriteRequest(str, req, false)).To(MatchError(`invalid Trailer key "Content-Length"`))
+	})
+
 	It("sends cookies", func() {
 		str.EXPECT().Close()
 		req, err := http.NewRequest("GET", "https://quic.clemente.io/", nil)
@@ -135,4 +173,31 @@ var _ = Describe("Request Writer", func() {
 		headerFields := decode(strBuf)
 		Expect(headerFields).To(HaveKeyWithValue("accept-encoding", "gzip"))
 	})
+
+	It("writes a CONNECT request", func() {
+		str.EXPECT().Close()
+		req, err := http.NewRequest(http.MethodConnect, "https://quic.clemente.io:1337", nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(rw.WriteRequest(str, req, false)).To(Succeed())
+		headerFields := decode(strBuf)
+		Expect(headerFields).To(HaveKeyWithValue(":authority", "quic.clemente.io:1337"))
+		Expect(headerFields).To(HaveKeyWithValue(":method", http.MethodConnect))
+		Expect(headerFields).ToNot(HaveKey(":path"))
+		Expect(headerFields).ToNot(HaveKey(":scheme"))
+		Expect(headerFields).ToNot(HaveKey(":protocol"))
+	})
+
+	It("writes an extended CONNECT request", func() {
+		str.EXPECT().Close()
+		req, err := http.NewRequest(http.MethodConnect, "https://quic.clemente.io/chat?room=1", nil)
+		Expect(err).ToNot(HaveOccurred())
+		req.Proto = "websocket"
+		Expect(rw.WriteRequest(str, req, false)).To(Succeed())
+		headerFields := decode(strBuf)
+		Expect(headerFields).To(HaveKeyWithValue(":authority", "quic.clemente.io"))
+		Expect(headerFields).To(HaveKeyWithValue(":method", http.MethodConnect))
+		Expect(headerFields).To(HaveKeyWithValue(":protocol", "websocket"))
+		Expect(headerFields).To(HaveKeyWithValue(":path", "/chat?room=1"))
+		Expect(headerFields).To(HaveKeyWithValue(":scheme", "https"))
+	})
 })
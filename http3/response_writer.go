@@ -3,21 +3,38 @@ package http3
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"io"
 	"net/http"
+	"net/textproto"
 	"strconv"
 	"strings"
 
+	"github.com/For-ACGN/quic-go"
+	"github.com/For-ACGN/quic-go/internal/protocol"
 	"github.com/For-ACGN/quic-go/internal/utils"
 	"github.com/marten-seemann/qpack"
+	"golang.org/x/net/http/httpguts"
 )
 
 type responseWriter struct {
-	stream *bufio.Writer
+	stream   *bufio.Writer
+	encoder  *qpackEncoder
+	streamID protocol.StreamID
 
 	header        http.Header
 	status        int // status code passed to WriteHeader
 	headerWritten bool
+	trailers      []string // declared using the "Trailer" header
+
+	pusher *pusher // nil if the response can't push
+
+	// only set if WebTransport is enabled
+	webTransport  *webTransportManager
+	requestStream quic.Stream
+	hijacked      bool // set once the stream was taken over by a WebTransport session
+
+	datagrams *datagramFlow // nil if HTTP datagrams are not enabled
 
 	logger utils.Logger
 }
@@ -25,13 +42,17 @@ type responseWriter struct {
 var (
 	_ http.ResponseWriter = &responseWriter{}
 	_ http.Flusher        = &responseWriter{}
+	_ http.Pusher         = &responseWriter{}
+	_ Datagrammer         = &responseWriter{}
 )
 
-func newResponseWriter(stream io.Writer, logger utils.Logger) *responseWriter {
+func newResponseWriter(stream io.Writer, encoder *qpackEncoder, streamID protocol.StreamID, logger utils.Logger) *responseWriter {
 	return &responseWriter{
-		header: http.Header{},
-		stream: bufio.NewWriter(stream),
-		logger: logger,
+		header:   http.Header{},
+		stream:   bufio.NewWriter(stream),
+		encoder:  encoder,
+		streamID: streamID,
+		logger:   logger,
 	}
 }
 
@@ -46,28 +67,46 @@ func (w *responseWriter) WriteHeader(status int) {
 	w.headerWritten = true
 	w.status = status
 
-	var headers bytes.Buffer
-	enc := qpack.NewEncoder(&headers)
-	enc.WriteField(qpack.HeaderField{Name: ":status", Value: strconv.Itoa(status)})
+	hfs := []qpack.HeaderField{{Name: ":status", Value: strconv.Itoa(status)}}
+
+	for _, v := range w.header["Trailer"] {
+		for _, key := range strings.Split(v, ",") {
+			if key = http.CanonicalHeaderKey(textproto.TrimString(key)); httpguts.ValidTrailerHeader(key) {
+				w.trailers = append(w.trailers, key)
+			}
+		}
+	}
 
 	for k, v := range w.header {
+		// trailers using the http.TrailerPrefix are sent after the body
+		if strings.HasPrefix(k, http.TrailerPrefix) {
+			continue
+		}
 		for index := range v {
-			enc.WriteField(qpack.HeaderField{Name: strings.ToLower(k), Value: v[index]})
+			hfs = append(hfs, qpack.HeaderField{Name: strings.ToLower(k), Value: v[index]})
 		}
 	}
+	headers, err := w.encoder.encode(w.streamID, hfs)
+	if err != nil {
+		w.logger.Errorf("could not encode headers: %s", err.Error())
+		return
+	}
 
 	buf := &bytes.Buffer{}
-	(&headersFrame{Length: uint64(headers.Len())}).Write(buf)
+	(&headersFrame{Length: uint64(len(headers))}).Write(buf)
 	w.logger.Infof("Responding with %d", status)
 	if _, err := w.stream.Write(buf.Bytes()); err != nil {
 		w.logger.Errorf("could not write headers frame: %s", err.Error())
 	}
-	if _, err := w.stream.Write(headers.Bytes()); err != nil {
+	if _, err := w.stream.Write(headers); err != nil {
 		w.logger.Errorf("could not write header frame payload: %s", err.Error())
 	}
 }
 
 func (w *responseWriter) Write(p []byte) (int, error) {
+	if w.hijacked {
+		return 0, http.ErrHijacked
+	}
 	if !w.headerWritten {
 		w.WriteHeader(200)
 	}
@@ -89,6 +128,64 @@ func (w *responseWriter) Flush() {
 	}
 }
 
+// writeTrailers writes the trailers after the body.
+// These are the trailers declared using the "Trailer" header,
+// as well as headers set using the http.TrailerPrefix.
+func (w *responseWriter) writeTrailers() {
+	trailer := make(http.Header)
+	for _, k := range w.trailers {
+		if vv, ok := w.header[k]; ok {
+			trailer[k] = vv
+		}
+	}
+	for k, vv := range w.header {
+		if strings.HasPrefix(k, http.TrailerPrefix) {
+			trailer[http.CanonicalHeaderKey(strings.TrimPrefix(k, http.TrailerPrefix))] = vv
+		}
+	}
+	if len(trailer) == 0 {
+		return
+	}
+
+	headers, err := w.encoder.encode(w.streamID, trailerFields(trailer))
+	if err != nil {
+		w.logger.Errorf("could not encode trailers: %s", err.Error())
+		return
+	}
+	buf := &bytes.Buffer{}
+	(&headersFrame{Length: uint64(len(headers))}).Write(buf)
+	buf.Write(headers)
+	if _, err := w.stream.Write(buf.Bytes()); err != nil {
+		w.logger.Errorf("could not write trailers: %s", err.Error())
+	}
+}
+
+// Push initiates an HTTP/3 server push.
+// It returns http.ErrNotSupported if the client didn't allow server push.
+func (w *responseWriter) Push(target string, opts *http.PushOptions) error {
+	if w.pusher == nil {
+		return http.ErrNotSupported
+	}
+	return w.pusher.Push(w.stream, target, opts)
+}
+
+// SendDatagram sends an HTTP datagram associated with the request.
+func (w *responseWriter) SendDatagram(b []byte) error {
+	if w.datagrams == nil {
+		return errDatagramsNotEnabled
+	}
+	return w.datagrams.send(b)
+}
+
+// ReceiveDatagram gets an HTTP datagram associated with the request.
+// It returns an error once the handler returned.
+func (w *responseWriter) ReceiveDatagram(ctx context.Context) ([]byte, error) {
+	if w.datagrams == nil {
+		return nil, errDatagramsNotEnabled
+	}
+	return w.datagrams.receive(ctx)
+}
+
 // copied from http2/http2.go
 // bodyAllowedForStatus reports whether a given response status code
 // permits a body. See RFC 2616, section 4.4.
@@ -20,7 +20,7 @@ var _ = Describe("Response Writer", func() {
 
 	BeforeEach(func() {
 		strBuf = &bytes.Buffer{}
-		rw = newResponseWriter(strBuf, utils.DefaultLogger)
+		rw = newResponseWriter(strBuf, newQPACKEncoder(0), 0, utils.DefaultLogger)
 	})
 
 	decodeHeader := func(str io.Reader) map[string][]string {
@@ -112,6 +112,42 @@ var _ = Describe("Response Writer", func() {
 		Expect(fields).To(HaveKeyWithValue(":status", []string{"200"}))
 	})
 
+	It("writes declared trailers", func() {
+		rw.Header().Set("Trailer", "Grpc-Status, Grpc-Message")
+		rw.Header().Set("Grpc-Message", "not sent")
+		_, err := rw.Write([]byte("foobar"))
+		Expect(err).ToNot(HaveOccurred())
+		rw.Header().Set("Grpc-Status", "0")
+		rw.Header().Del("Grpc-Message")
+		rw.Header().Set("Undeclared", "not sent")
+		rw.writeTrailers()
+		fields := decodeHeader(strBuf)
+		Expect(fields).To(HaveKeyWithValue("trailer", []string{"Grpc-Status, Grpc-Message"}))
+		Expect(getData(strBuf)).To(Equal([]byte("foobar")))
+		Expect(decodeHeader(strBuf)).To(Equal(map[string][]string{"grpc-status": {"0"}}))
+	})
+
+	It("writes trailers using the http.TrailerPrefix", func() {
+		rw.Header().Set(http.TrailerPrefix+"Foo", "early")
+		rw.WriteHeader(http.StatusOK)
+		rw.Header().Set(http.TrailerPrefix+"Foo", "bar")
+		rw.Header().Set(http.TrailerPrefix+"Lorem", "ipsum")
+		rw.writeTrailers()
+		fields := decodeHeader(strBuf)
+		Expect(fields).To(Equal(map[string][]string{":status": {"200"}}))
+		Expect(decodeHeader(strBuf)).To(Equal(map[string][]string{
+			"foo":   {"bar"},
+			"lorem": {"ipsum"},
+		}))
+	})
+
+	It("doesn't write trailers if there are none", func() {
+		rw.WriteHeader(http.StatusOK)
+		rw.writeTrailers()
+		decodeHeader(strBuf)
+		Expect(strBuf.Len()).To(BeZero())
+	})
+
 	It("doesn't allow writes if the status code doesn't allow a body", func() {
 		rw.WriteHeader(304)
 		n, err := rw.Write([]byte("foobar"))
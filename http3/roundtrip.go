@@ -1,13 +1,16 @@
 package http3
 
 import (
+	"context"
 	"crypto/tls"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"strings"
 	"sync"
+	"time"
 
 	quic "github.com/For-ACGN/quic-go"
 
@@ -19,6 +22,35 @@ type roundTripCloser interface {
 	io.Closer
 }
 
+// A cachedClient is a client cached by the RoundTripper.
+type cachedClient interface {
+	roundTripCloser
+	// TryRoundTrip is like RoundTrip, but it returns errNoStreamAvailable
+	// (without sending the request) if the server's stream limit is reached.
+	TryRoundTrip(*http.Request) (*http.Response, error)
+	// Draining says if the server sent a GOAWAY frame.
+	// New requests must not be sent on a draining connection.
+	Draining() bool
+	// Closed says if the connection was closed, or if it couldn't be established.
+	Closed() bool
+	// ActiveRequests returns the number of requests in flight.
+	ActiveRequests() int
+}
+
+// A webTransportDialer is a client that can establish WebTransport sessions.
+type webTransportDialer interface {
+	dialWebTransport(*http.Request) (*http.Response, *WebTransportSession, error)
+}
+
+// A handshaker is a client that can establish its connection before sending a request.
+type handshaker interface {
+	handshake(context.Context) error
+	handshakeComplete() bool
+}
+
+// maxRequestRetries is the number of times a request that wasn't processed by the server is retried.
+const maxRequestRetries = 3
+
 // RoundTripper implements the http.RoundTripper interface
 type RoundTripper struct {
 	mutex sync.Mutex
@@ -41,9 +73,9 @@ type RoundTripper struct {
 	// If nil, reasonable default values will be used.
 	QuicConfig *quic.Config
 
-	// Enable support for HTTP/3 datagrams.
+	// Enable support for HTTP datagrams (RFC 9297).
 	// If set to true, QuicConfig.EnableDatagram will be set.
-	// See https://www.ietf.org/archive/id/draft-schinazi-masque-h3-datagram-02.html.
+	// Datagrams are sent and received using the Datagrammer interface implemented by the http.Response.Body.
 	EnableDatagrams bool
 
 	// Dial specifies an optional dial function for creating QUIC
@@ -56,7 +88,40 @@ type RoundTripper struct {
 	// Zero means to use a default limit.
 	MaxResponseHeaderBytes int64
 
-	clients map[string]roundTripCloser
+	// PushHandler enables HTTP/3 server push.
+	// If nil, the server is not allowed to push.
+	PushHandler PushHandler
+
+	// QPACKMaxTableCapacity is the maximum capacity of the QPACK dynamic table, in bytes.
+	// It limits the dynamic table used for decoding responses (SETTINGS_QPACK_MAX_TABLE_CAPACITY),
+	// as well as the dynamic table used for encoding requests.
+	// If zero, the dynamic table is disabled, and header fields are only compressed using the static table.
+	QPACKMaxTableCapacity uint64
+
+	// QPACKBlockedStreams is the maximum number of request streams that can be blocked
+	// waiting for QPACK encoder instructions (SETTINGS_QPACK_BLOCKED_STREAMS).
+	QPACKBlockedStreams uint64
+
+	// EnableWebTransport enables support for WebTransport (draft-ietf-webtrans-http3-02).
+	// It implies EnableDatagrams, and allows the server to open bidirectional streams.
+	// WebTransport sessions are established using DialWebTransport.
+	EnableWebTransport bool
+
+	// MaxConnsPerHost limits the number of QUIC connections per host.
+	// Once the server's stream limit is reached on all connections to a host, a new connection is dialed.
+	// When the limit is reached, requests wait until a stream can be opened on one of the existing connections.
+	// Zero means no limit.
+	MaxConnsPerHost int
+
+	// IdleConnTimeout is the maximum amount of time a connection without any active requests
+	// remains open before closing itself.
+	// Zero means no limit.
+	IdleConnTimeout time.Duration
+
+	clients map[string][]cachedClient // the pool of clients for every host
+	// Clients that received a GOAWAY frame are removed from the pool.
+	// They close their connection once all running requests completed, or when the RoundTripper is closed.
+	draining map[cachedClient]struct{}
 }
 
 // RoundTripOpt are options for the Transport.RoundTripOpt method.
@@ -111,11 +176,138 @@ func (r *RoundTripper) RoundTripOpt(req *http.Request, opt RoundTripOpt) (*http.
 	}
 
 	hostname := authorityAddr("https", hostnameFromRequest(req))
-	cl, err := r.getClient(hostname, opt.OnlyCachedConn)
+	for retry := 0; ; retry++ {
+		rsp, err := r.roundTripOnPool(hostname, req, opt.OnlyCachedConn)
+		if err != errRequestNotProcessed || retry >= maxRequestRetries {
+			return rsp, err
+		}
+		// The server didn't process the request.
+		// It's safe to retry it on a new connection, as long as we can send the same body again.
+		newReq, rerr := rewindBody(req)
+		if rerr != nil {
+			return nil, err
+		}
+		req = newReq
+	}
+}
+
+// roundTripOnPool sends the request on one of the connections to hostname.
+// The request is sent on the first connection that has a stream available.
+// If none has, a new connection is dialed, unless MaxConnsPerHost is reached.
+func (r *RoundTripper) roundTripOnPool(hostname string, req *http.Request, onlyCached bool) (*http.Response, error) {
+	tried := make(map[cachedClient]struct{})
+	for {
+		cl, wait, err := r.getClient(hostname, onlyCached, tried)
+		if err != nil {
+			return nil, err
+		}
+		if wait {
+			return cl.RoundTrip(req)
+		}
+		rsp, err := cl.TryRoundTrip(req)
+		if err != errNoStreamAvailable {
+			return rsp, err
+		}
+		tried[cl] = struct{}{}
+	}
+}
+
+// rewindBody returns a copy of the request with a fresh request body.
+// This is only possible if the request has no body, or if req.GetBody is set.
+func rewindBody(req *http.Request) (*http.Request, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return req, nil
+	}
+	if req.GetBody == nil {
+		return nil, errors.New("http3: cannot rewind the request body")
+	}
+	body, err := req.GetBody()
 	if err != nil {
 		return nil, err
 	}
-	return cl.RoundTrip(req)
+	newReq := *req
+	newReq.Body = body
+	return &newReq, nil
+}
+
+// DialWebTransport establishes a WebTransport session with the server at urlStr.
+// It sends an extended CONNECT request using the "webtransport" protocol,
+// and returns the server's response, as well as the session if the server accepted it.
+// The request is canceled if ctx is canceled before the server responds.
+func (r *RoundTripper) DialWebTransport(ctx context.Context, urlStr string, header http.Header) (*http.Response, *WebTransportSession, error) {
+	if !r.EnableWebTransport {
+		return nil, nil, errors.New("http3: WebTransport not enabled")
+	}
+	u, err := url.Parse(urlStr)
+	if err != nil {
+		return nil, nil, err
+	}
+	if u.Scheme != "https" {
+		return nil, nil, fmt.Errorf("http3: unsupported protocol scheme: %s", u.Scheme)
+	}
+	if u.Host == "" {
+		return nil, nil, errors.New("http3: no Host in request URL")
+	}
+	if header == nil {
+		header = http.Header{}
+	} else {
+		header = header.Clone()
+	}
+	header.Set(webTransportDraftOfferHeader, "1")
+	req := (&http.Request{
+		Method: http.MethodConnect,
+		Proto:  webTransportProtocol,
+		URL:    u,
+		Host:   u.Host,
+		Header: header,
+	}).WithContext(ctx)
+
+	hostname := authorityAddr("https", hostnameFromRequest(req))
+	for retry := 0; ; retry++ {
+		cl, _, err := r.getClient(hostname, false, nil)
+		if err != nil {
+			return nil, nil, err
+		}
+		dialer, ok := cl.(webTransportDialer)
+		if !ok {
+			return nil, nil, errors.New("http3: client doesn't support WebTransport")
+		}
+		rsp, sess, err := dialer.dialWebTransport(req)
+		if err != errRequestNotProcessed || retry >= maxRequestRetries {
+			return rsp, sess, err
+		}
+	}
+}
+
+// handshake establishes a connection to hostname (unless there already is one),
+// and waits for the QUIC handshake to complete.
+func (r *RoundTripper) handshake(ctx context.Context, hostname string) error {
+	cl, _, err := r.getClient(hostname, false, nil)
+	if err != nil {
+		return err
+	}
+	h, ok := cl.(handshaker)
+	if !ok {
+		return errors.New("http3: client doesn't support handshakes")
+	}
+	return h.handshake(ctx)
+}
+
+// hasConn says if there's a connection to hostname that completed the handshake,
+// and that can be used for new requests.
+func (r *RoundTripper) hasConn(hostname string) bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for _, cl := range r.clients[hostname] {
+		if cl.Draining() || cl.Closed() {
+			continue
+		}
+		if h, ok := cl.(handshaker); ok && h.handshakeComplete() {
+			return true
+		}
+	}
+	return false
 }
 
 // RoundTrip does a round trip.
@@ -123,49 +315,105 @@ func (r *RoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
 	return r.RoundTripOpt(req, RoundTripOpt{})
 }
 
-func (r *RoundTripper) getClient(hostname string, onlyCached bool) (http.RoundTripper, error) {
+// getClient returns a client for hostname that wasn't tried yet.
+// Closed and draining clients are removed from the pool, draining clients are kept track of until they are closed.
+// If all clients were tried, it dials a new connection, unless MaxConnsPerHost is reached
+// (or onlyCached is set), in which case it returns the client with the fewest active requests.
+// wait says if the request should wait for a stream to become available on the client.
+func (r *RoundTripper) getClient(hostname string, onlyCached bool, tried map[cachedClient]struct{}) (cl cachedClient, wait bool, err error) {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
 
 	if r.clients == nil {
-		r.clients = make(map[string]roundTripCloser)
+		r.clients = make(map[string][]cachedClient)
+	}
+	if r.draining == nil {
+		r.draining = make(map[cachedClient]struct{})
+	}
+	for cl := range r.draining {
+		if cl.Closed() {
+			delete(r.draining, cl)
+		}
 	}
 
-	client, ok := r.clients[hostname]
-	if !ok {
-		if onlyCached {
-			return nil, ErrNoCachedConn
-		}
-		var err error
-		client, err = newClient(
-			hostname,
-			r.TLSClientConfig,
-			&roundTripperOpts{
-				EnableDatagram:     r.EnableDatagrams,
-				DisableCompression: r.DisableCompression,
-				MaxHeaderBytes:     r.MaxResponseHeaderBytes,
-			},
-			r.QuicConfig,
-			r.Dial,
-		)
-		if err != nil {
-			return nil, err
+	pool := r.clients[hostname][:0]
+	for _, cl := range r.clients[hostname] {
+		if cl.Closed() {
+			continue
+		}
+		// If the server is shutting down this connection, the client closes the connection once all
+		// running requests are completed.
+		if cl.Draining() {
+			r.draining[cl] = struct{}{}
+			continue
+		}
+		pool = append(pool, cl)
+	}
+	if len(pool) == 0 {
+		delete(r.clients, hostname)
+	} else {
+		r.clients[hostname] = pool
+	}
+
+	for _, cl := range pool {
+		if _, ok := tried[cl]; !ok {
+			return cl, false, nil
+		}
+	}
+	if len(pool) > 0 && (onlyCached || (r.MaxConnsPerHost > 0 && len(pool) >= r.MaxConnsPerHost)) {
+		leastBusy := pool[0]
+		for _, cl := range pool[1:] {
+			if cl.ActiveRequests() < leastBusy.ActiveRequests() {
+				leastBusy = cl
+			}
 		}
-		r.clients[hostname] = client
+		return leastBusy, true, nil
+	}
+	if onlyCached {
+		return nil, false, ErrNoCachedConn
+	}
+	client, err := newClient(
+		hostname,
+		r.TLSClientConfig,
+		&roundTripperOpts{
+			EnableDatagram:        r.EnableDatagrams,
+			DisableCompression:    r.DisableCompression,
+			MaxHeaderBytes:        r.MaxResponseHeaderBytes,
+			PushHandler:           r.PushHandler,
+			QPACKMaxTableCapacity: r.QPACKMaxTableCapacity,
+			QPACKBlockedStreams:   r.QPACKBlockedStreams,
+			EnableWebTransport:    r.EnableWebTransport,
+			IdleTimeout:           r.IdleConnTimeout,
+		},
+		r.QuicConfig,
+		r.Dial,
+	)
+	if err != nil {
+		return nil, false, err
 	}
-	return client, nil
+	r.clients[hostname] = append(pool, client)
+	// This is the first request on a new connection, so there's no need to try it first.
+	return client, true, nil
 }
 
 // Close closes the QUIC connections that this RoundTripper has used
 func (r *RoundTripper) Close() error {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
-	for _, client := range r.clients {
+	for _, pool := range r.clients {
+		for _, client := range pool {
+			if err := client.Close(); err != nil {
+				return err
+			}
+		}
+	}
+	r.clients = nil
+	for client := range r.draining {
 		if err := client.Close(); err != nil {
 			return err
 		}
 	}
-	r.clients = nil
+	r.draining = nil
 	return nil
 }
 
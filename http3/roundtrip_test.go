@@ -17,10 +17,41 @@ import (
 )
 
 type mockClient struct {
-	closed bool
+	closed     bool
+	draining   bool
+	connClosed bool
+	// If set, RoundTrip returns errRequestNotProcessed.
+	notProcessed bool
+	// If set, the client starts draining after the first request.
+	goAway bool
+	// If set, TryRoundTrip returns errNoStreamAvailable.
+	noStreams      bool
+	activeRequests int
+	numRoundTrips  int
+	// the number of requests sent using RoundTrip (i.e. waiting for a stream)
+	numWaitingRoundTrips int
 }
 
 func (m *mockClient) RoundTrip(req *http.Request) (*http.Response, error) {
+	m.numWaitingRoundTrips++
+	return m.roundTrip(req)
+}
+
+func (m *mockClient) TryRoundTrip(req *http.Request) (*http.Response, error) {
+	if m.noStreams {
+		return nil, errNoStreamAvailable
+	}
+	return m.roundTrip(req)
+}
+
+func (m *mockClient) roundTrip(req *http.Request) (*http.Response, error) {
+	m.numRoundTrips++
+	if m.goAway {
+		m.draining = true
+	}
+	if m.notProcessed {
+		return nil, errRequestNotProcessed
+	}
 	return &http.Response{Request: req}, nil
 }
 
@@ -29,7 +60,19 @@ func (m *mockClient) Close() error {
 	return nil
 }
 
-var _ roundTripCloser = &mockClient{}
+func (m *mockClient) Draining() bool {
+	return m.draining
+}
+
+func (m *mockClient) Closed() bool {
+	return m.connClosed
+}
+
+func (m *mockClient) ActiveRequests() int {
+	return m.activeRequests
+}
+
+var _ cachedClient = &mockClient{}
 
 type mockBody struct {
 	reader   bytes.Reader
@@ -142,7 +185,10 @@ var _ = Describe("RoundTripper", func() {
 			testErr := errors.New("test err")
 			session.EXPECT().OpenUniStream().AnyTimes().Return(nil, testErr)
 			session.EXPECT().HandshakeComplete().Return(handshakeCtx).Times(2)
-			session.EXPECT().OpenStreamSync(context.Background()).Return(nil, testErr).Times(2)
+			session.EXPECT().Context().Return(context.Background()).AnyTimes()
+			// The first request is sent on a new connection, the second one is only sent if a stream is available.
+			session.EXPECT().OpenStreamSync(context.Background()).Return(nil, testErr)
+			session.EXPECT().OpenStream().Return(nil, testErr)
 			session.EXPECT().AcceptUniStream(gomock.Any()).DoAndReturn(func(context.Context) (quic.ReceiveStream, error) {
 				<-closed
 				return nil, errors.New("test done")
@@ -161,6 +207,146 @@ var _ = Describe("RoundTripper", func() {
 			Eventually(closed).Should(BeClosed())
 		})
 
+		It("retries requests that weren't processed on a new connection", func() {
+			closed := make(chan struct{})
+			testErr := errors.New("test err")
+			cl := &mockClient{notProcessed: true, goAway: true}
+			rt.clients = map[string][]cachedClient{"quic.clemente.io:443": {cl}}
+			session.EXPECT().OpenUniStream().AnyTimes().Return(nil, testErr)
+			session.EXPECT().HandshakeComplete().Return(handshakeCtx)
+			session.EXPECT().OpenStreamSync(context.Background()).Return(nil, testErr)
+			session.EXPECT().AcceptUniStream(gomock.Any()).DoAndReturn(func(context.Context) (quic.ReceiveStream, error) {
+				<-closed
+				return nil, errors.New("test done")
+			}).MaxTimes(1)
+			session.EXPECT().CloseWithError(gomock.Any(), gomock.Any()).Do(func(quic.ErrorCode, string) { close(closed) })
+			req, err := http.NewRequest("POST", "https://quic.clemente.io/foobar.html", bytes.NewReader([]byte("foobar")))
+			Expect(err).ToNot(HaveOccurred())
+			_, err = rt.RoundTrip(req)
+			Expect(err).To(MatchError(testErr))
+			Expect(cl.numRoundTrips).To(Equal(1))
+			Expect(rt.clients).To(HaveLen(1))
+			Expect(rt.clients["quic.clemente.io:443"]).To(HaveLen(1))
+			Expect(rt.clients["quic.clemente.io:443"][0]).ToNot(Equal(cl))
+			Eventually(closed).Should(BeClosed())
+		})
+
+		It("doesn't retry requests if the body can't be rewound", func() {
+			cl := &mockClient{notProcessed: true}
+			rt.clients = map[string][]cachedClient{"quic.clemente.io:443": {cl}}
+			req, err := http.NewRequest("POST", "https://quic.clemente.io/foobar.html", &mockBody{})
+			Expect(err).ToNot(HaveOccurred())
+			_, err = rt.RoundTrip(req)
+			Expect(err).To(MatchError(errRequestNotProcessed))
+			Expect(cl.numRoundTrips).To(Equal(1))
+		})
+
+		It("limits the number of retries", func() {
+			cl := &mockClient{notProcessed: true}
+			rt.clients = map[string][]cachedClient{"quic.clemente.io:443": {cl}}
+			req, err := http.NewRequest("GET", "https://quic.clemente.io/foobar.html", nil)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = rt.RoundTrip(req)
+			Expect(err).To(MatchError(errRequestNotProcessed))
+			Expect(cl.numRoundTrips).To(Equal(maxRequestRetries + 1))
+		})
+
+		It("doesn't use draining clients", func() {
+			rt.clients = map[string][]cachedClient{"quic.clemente.io:443": {&mockClient{draining: true}}}
+			req, err := http.NewRequest("GET", "https://quic.clemente.io/foobar.html", nil)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = rt.RoundTripOpt(req, RoundTripOpt{OnlyCachedConn: true})
+			Expect(err).To(MatchError(ErrNoCachedConn))
+			Expect(rt.clients).To(BeEmpty())
+		})
+
+		It("keeps track of draining clients until they are closed", func() {
+			cl := &mockClient{draining: true}
+			rt.clients = map[string][]cachedClient{"quic.clemente.io:443": {cl}}
+			req, err := http.NewRequest("GET", "https://quic.clemente.io/foobar.html", nil)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = rt.RoundTripOpt(req, RoundTripOpt{OnlyCachedConn: true})
+			Expect(err).To(MatchError(ErrNoCachedConn))
+			Expect(rt.draining).To(HaveKey(cl))
+			// the client closes the connection once the last request completed
+			cl.connClosed = true
+			_, err = rt.RoundTripOpt(req, RoundTripOpt{OnlyCachedConn: true})
+			Expect(err).To(MatchError(ErrNoCachedConn))
+			Expect(rt.draining).To(BeEmpty())
+		})
+
+		It("sends requests on the next connection if the stream limit is reached", func() {
+			cl1 := &mockClient{noStreams: true}
+			cl2 := &mockClient{}
+			rt.clients = map[string][]cachedClient{"quic.clemente.io:443": {cl1, cl2}}
+			req, err := http.NewRequest("GET", "https://quic.clemente.io/foobar.html", nil)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = rt.RoundTrip(req)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cl1.numRoundTrips).To(BeZero())
+			Expect(cl2.numRoundTrips).To(Equal(1))
+			Expect(cl2.numWaitingRoundTrips).To(BeZero())
+		})
+
+		It("dials a new connection if the stream limit is reached on all connections", func() {
+			closed := make(chan struct{})
+			testErr := errors.New("test err")
+			cl := &mockClient{noStreams: true}
+			rt.clients = map[string][]cachedClient{"quic.clemente.io:443": {cl}}
+			session.EXPECT().OpenUniStream().AnyTimes().Return(nil, testErr)
+			session.EXPECT().HandshakeComplete().Return(handshakeCtx)
+			session.EXPECT().OpenStreamSync(context.Background()).Return(nil, testErr)
+			session.EXPECT().AcceptUniStream(gomock.Any()).DoAndReturn(func(context.Context) (quic.ReceiveStream, error) {
+				<-closed
+				return nil, errors.New("test done")
+			}).MaxTimes(1)
+			session.EXPECT().CloseWithError(gomock.Any(), gomock.Any()).Do(func(quic.ErrorCode, string) { close(closed) })
+			req, err := http.NewRequest("GET", "https://quic.clemente.io/foobar.html", nil)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = rt.RoundTrip(req)
+			Expect(err).To(MatchError(testErr))
+			Expect(cl.numRoundTrips).To(BeZero())
+			Expect(rt.clients["quic.clemente.io:443"]).To(HaveLen(2))
+			Eventually(closed).Should(BeClosed())
+		})
+
+		It("waits for a stream on the least busy connection if MaxConnsPerHost is reached", func() {
+			rt.MaxConnsPerHost = 2
+			cl1 := &mockClient{noStreams: true, activeRequests: 10}
+			cl2 := &mockClient{noStreams: true, activeRequests: 5}
+			rt.clients = map[string][]cachedClient{"quic.clemente.io:443": {cl1, cl2}}
+			req, err := http.NewRequest("GET", "https://quic.clemente.io/foobar.html", nil)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = rt.RoundTrip(req)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cl1.numRoundTrips).To(BeZero())
+			Expect(cl2.numWaitingRoundTrips).To(Equal(1))
+			Expect(rt.clients["quic.clemente.io:443"]).To(HaveLen(2))
+		})
+
+		It("removes closed connections", func() {
+			cl1 := &mockClient{connClosed: true}
+			cl2 := &mockClient{}
+			rt.clients = map[string][]cachedClient{"quic.clemente.io:443": {cl1, cl2}}
+			req, err := http.NewRequest("GET", "https://quic.clemente.io/foobar.html", nil)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = rt.RoundTrip(req)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cl1.numRoundTrips).To(BeZero())
+			Expect(cl2.numRoundTrips).To(Equal(1))
+			Expect(rt.clients["quic.clemente.io:443"]).To(Equal([]cachedClient{cl2}))
+		})
+
+		It("waits for a stream on a cached connection if RoundTripOpt.OnlyCachedConn is set", func() {
+			cl := &mockClient{noStreams: true}
+			rt.clients = map[string][]cachedClient{"quic.clemente.io:443": {cl}}
+			req, err := http.NewRequest("GET", "https://quic.clemente.io/foobar.html", nil)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = rt.RoundTripOpt(req, RoundTripOpt{OnlyCachedConn: true})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cl.numWaitingRoundTrips).To(Equal(1))
+		})
+
 		It("doesn't create new clients if RoundTripOpt.OnlyCachedConn is set", func() {
 			req, err := http.NewRequest("GET", "https://quic.clemente.io/foobar.html", nil)
 			Expect(err).ToNot(HaveOccurred())
@@ -242,15 +428,23 @@ var _ = Describe("RoundTripper", func() {
 
 	Context("closing", func() {
 		It("closes", func() {
-			rt.clients = make(map[string]roundTripCloser)
+			rt.clients = make(map[string][]cachedClient)
 			cl := &mockClient{}
-			rt.clients["foo.bar"] = cl
+			rt.clients["foo.bar"] = []cachedClient{cl}
 			err := rt.Close()
 			Expect(err).ToNot(HaveOccurred())
 			Expect(len(rt.clients)).To(BeZero())
 			Expect(cl.closed).To(BeTrue())
 		})
 
+		It("closes draining clients", func() {
+			cl := &mockClient{}
+			rt.draining = map[cachedClient]struct{}{cl: {}}
+			Expect(rt.Close()).To(Succeed())
+			Expect(cl.closed).To(BeTrue())
+			Expect(rt.draining).To(BeEmpty())
+		})
+
 		It("closes a RoundTripper that has never been used", func() {
 			Expect(len(rt.clients)).To(BeZero())
 			err := rt.Close()
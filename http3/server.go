@@ -21,7 +21,6 @@ import (
 	"github.com/For-ACGN/quic-go/internal/protocol"
 	"github.com/For-ACGN/quic-go/internal/utils"
 	"github.com/For-ACGN/quic-go/quicvarint"
-	"github.com/marten-seemann/qpack"
 )
 
 func init() {
@@ -38,18 +37,37 @@ var (
 )
 
 const (
-	nextProtoH3Draft29      = "h3-29"
-	nextProtoH3Draft32      = "h3-32"
-	streamTypeControlStream = 0
-	streamTypePushStream    = 1
+	nextProtoH3Draft29           = "h3-29"
+	nextProtoH3Draft32           = "h3-32"
+	nextProtoH3                  = "h3"
+	streamTypeControlStream      = 0
+	streamTypePushStream         = 1
+	streamTypeQPACKEncoderStream = 2
+	streamTypeQPACKDecoderStream = 3
+	streamTypeWebTransportStream = 0x54
 )
 
 func versionToALPN(v protocol.VersionNumber) string {
-	if v == protocol.VersionTLS || v == protocol.VersionDraft29 {
+	//nolint:exhaustive
+	switch v {
+	case protocol.Version1, protocol.Version2:
+		return nextProtoH3
+	case protocol.VersionDraft29:
 		return nextProtoH3Draft29
-	}
-	if v == protocol.VersionDraft32 {
+	case protocol.VersionDraft32:
 		return nextProtoH3Draft32
+	default:
+		return ""
+	}
+}
+
+// selectALPN returns the first HTTP/3 ALPN offered by the client.
+func selectALPN(protos []string) string {
+	for _, p := range protos {
+		switch p {
+		case nextProtoH3, nextProtoH3Draft29, nextProtoH3Draft32:
+			return p
+		}
 	}
 	return ""
 }
@@ -68,6 +86,12 @@ func (k *contextKey) String() string { return "quic-go/http3 context value " + k
 // type *http3.Server.
 var ServerContextKey = &contextKey{"http3-server"}
 
+// SessionContextKey is a context key. It can be used in HTTP
+// handlers with Context.Value to access the QUIC session the
+// request was received on. The associated value will be of
+// type quic.EarlySession.
+var SessionContextKey = &contextKey{"http3-session"}
+
 type requestError struct {
 	err       error
 	streamErr errorCode
@@ -82,6 +106,10 @@ func newConnError(code errorCode, err error) requestError {
 	return requestError{err: err, connErr: code}
 }
 
+// errHijacked is returned by handleRequest when the request stream was taken over,
+// e.g. by a WebTransport session.
+var errHijacked = errors.New("http3: request stream hijacked")
+
 // Server is a HTTP/3 server.
 type Server struct {
 	*http.Server
@@ -90,15 +118,49 @@ type Server struct {
 	// If nil, it uses reasonable default values.
 	QuicConfig *quic.Config
 
-	// Enable support for HTTP/3 datagrams.
+	// Enable support for HTTP datagrams (RFC 9297).
 	// If set to true, QuicConfig.EnableDatagram will be set.
-	// See https://www.ietf.org/archive/id/draft-schinazi-masque-h3-datagram-02.html.
+	// Handlers send and receive datagrams using the Datagrammer interface implemented by the http.ResponseWriter.
 	EnableDatagrams bool
 
+	// QPACKMaxTableCapacity is the maximum capacity of the QPACK dynamic table, in bytes.
+	// It limits the dynamic table used for decoding requests (SETTINGS_QPACK_MAX_TABLE_CAPACITY),
+	// as well as the dynamic table used for encoding responses.
+	// If zero, the dynamic table is disabled, and header fields are only compressed using the static table.
+	QPACKMaxTableCapacity uint64
+
+	// QPACKBlockedStreams is the maximum number of request streams that can be blocked
+	// waiting for QPACK encoder instructions (SETTINGS_QPACK_BLOCKED_STREAMS).
+	QPACKBlockedStreams uint64
+
+	// EnableExtendedConnect enables the extended CONNECT method (RFC 9220),
+	// i.e. CONNECT requests that carry a :protocol pseudo header.
+	// The protocol is passed to the handler in http.Request.Proto.
+	EnableExtendedConnect bool
+
+	// EnableWebTransport enables support for WebTransport (draft-ietf-webtrans-http3-02).
+	// It implies EnableExtendedConnect and EnableDatagrams.
+	// WebTransport sessions are established by calling UpgradeWebTransport from the handler.
+	EnableWebTransport bool
+
+	// ConnContext optionally specifies a function that modifies the context used for a new connection.
+	// It is the equivalent of http.Server.ConnContext (which is not used by the Server),
+	// but it is called with the QUIC session.
+	// The values of the returned context are available in the context of every request received on the connection,
+	// and the request contexts are canceled when the returned context is canceled.
+	ConnContext func(ctx context.Context, sess quic.Session) context.Context
+
+	// ConnState specifies an optional callback function that is called when a connection changes state.
+	// It is the equivalent of http.Server.ConnState (which is not used by the Server), but it is called with the QUIC session.
+	// A connection is active while at least one request is being served, and idle otherwise.
+	// The http.StateHijacked state is never used.
+	ConnState func(quic.Session, http.ConnState)
+
 	port uint32 // used atomically
 
 	mutex     sync.Mutex
 	listeners map[*quic.EarlyListener]struct{}
+	conns     map[*serverConn]struct{}
 	closed    utils.AtomicBool
 
 	loggerOnce sync.Once
@@ -152,9 +214,14 @@ func (s *Server) serveImpl(tlsConf *tls.Config, conn net.PacketConn) error {
 	baseConf := &tls.Config{
 		GetConfigForClient: func(ch *tls.ClientHelloInfo) (*tls.Config, error) {
 			// determine the ALPN from the QUIC version used
-			proto := nextProtoH3Draft29
-			if qconn, ok := ch.Conn.(handshake.ConnWithVersion); ok && qconn.GetQUICVersion() == quic.VersionDraft32 {
-				proto = nextProtoH3Draft32
+			proto := nextProtoH3
+			if qconn, ok := ch.Conn.(handshake.ConnWithVersion); ok {
+				if p := versionToALPN(qconn.GetQUICVersion()); p != "" {
+					proto = p
+				}
+			} else if p := selectALPN(ch.SupportedProtos); p != "" {
+				// If we can't determine the QUIC version, use the ALPN offered by the client.
+				proto = p
 			}
 			config := tlsConf
 			if tlsConf.GetConfigForClient != nil {
@@ -185,7 +252,7 @@ func (s *Server) serveImpl(tlsConf *tls.Config, conn net.PacketConn) error {
 	} else {
 		quicConf = s.QuicConfig.Clone()
 	}
-	if s.EnableDatagrams {
+	if s.datagramsEnabled() {
 		quicConf.EnableDatagrams = true
 	}
 	if conn == nil {
@@ -208,6 +275,16 @@ func (s *Server) serveImpl(tlsConf *tls.Config, conn net.PacketConn) error {
 	}
 }
 
+// datagramsEnabled says if HTTP datagrams are enabled. They are required by WebTransport.
+func (s *Server) datagramsEnabled() bool {
+	return s.EnableDatagrams || s.EnableWebTransport
+}
+
+// extendedConnectEnabled says if extended CONNECT is enabled. It is required by WebTransport.
+func (s *Server) extendedConnectEnabled() bool {
+	return s.EnableExtendedConnect || s.EnableWebTransport
+}
+
 // We store a pointer to interface in the map set. This is safe because we only
 // call trackListener via Serve and can track+defer untrack the same pointer to
 // local variable there. We never need to compare a Listener from another caller.
@@ -226,9 +303,272 @@ func (s *Server) removeListener(l *quic.EarlyListener) {
 	s.mutex.Unlock()
 }
 
-func (s *Server) handleConn(sess quic.EarlySession) {
-	decoder := qpack.NewDecoder(nil)
+// A serverConn is a QUIC session handled by the Server.
+// It keeps track of the request streams, such that the connection can be shut down gracefully.
+type serverConn struct {
+	quic.EarlySession
+
+	// the context of the connection, as returned by Server.ConnContext
+	ctx context.Context
+
+	controlStream quic.SendStream
+	encoder       *qpackEncoder
+	decoder       *qpackDecoder
+
+	mutex     sync.Mutex
+	goingAway bool
+	// the stream ID sent in the GOAWAY frame:
+	// the ID of the request stream following the last accepted request stream
+	nextStreamID protocol.StreamID
+	requests     sync.WaitGroup
+
+	// server push, limited by the MAX_PUSH_ID frames sent by the client
+	pushAllowed bool
+	maxPushID   uint64
+	nextPushID  uint64
+	pushStreams map[uint64]quic.SendStream // push streams that are currently being served
+
+	// prioritization (RFC 9218), protected by the mutex
+	requestStreams    map[protocol.StreamID]*servedRequest // request streams that are currently being served
+	pendingPriorities map[protocol.StreamID]Priority       // PRIORITY_UPDATE frames for request streams that weren't opened yet
+
+	datagrams    *datagramManager
+	webTransport *webTransportManager // nil if WebTransport is not enabled
+
+	connState      func(quic.Session, http.ConnState)
+	stateMutex     sync.Mutex
+	activeRequests int  // the number of request streams that are currently being served
+	stateClosed    bool // once the connection is closed, the state doesn't change any more
+}
+
+// A servedRequest is a request stream that is currently being served.
+type servedRequest struct {
+	str quic.Stream
+	// set once the priority was changed by a PRIORITY_UPDATE frame,
+	// which takes precedence over the Priority header field
+	priorityUpdated bool
+}
+
+func (s *Server) newServerConn(sess quic.EarlySession) *serverConn {
+	conn := &serverConn{
+		EarlySession:      sess,
+		encoder:           newQPACKEncoder(s.QPACKMaxTableCapacity),
+		decoder:           newQPACKDecoder(s.QPACKMaxTableCapacity, s.QPACKBlockedStreams),
+		requestStreams:    make(map[protocol.StreamID]*servedRequest),
+		pendingPriorities: make(map[protocol.StreamID]Priority),
+		datagrams:         newDatagramManager(sess, s.logger),
+	}
+	if s.EnableWebTransport {
+		conn.webTransport = newWebTransportManager(sess, conn.datagrams, s.logger)
+	}
+	ctx := context.WithValue(context.Background(), ServerContextKey, s)
+	ctx = context.WithValue(ctx, SessionContextKey, sess)
+	if s.ConnContext != nil {
+		ctx = s.ConnContext(ctx, sess)
+		if ctx == nil {
+			panic("ConnContext returned nil")
+		}
+	}
+	conn.ctx = ctx
+	conn.connState = s.ConnState
+	return conn
+}
+
+// requestContext returns the context of a request (or a push), given the context of its stream.
+// It is canceled when the stream is closed, or when the connection's context is canceled,
+// and carries the values of the connection's context.
+func (c *serverConn) requestContext(strCtx context.Context) context.Context {
+	var ctx context.Context = &streamContext{Context: strCtx, values: c.ctx}
+	// The context returned by ConnContext might be canceled before the stream is closed.
+	if connDone := c.ctx.Done(); connDone != nil {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithCancel(ctx)
+		go func() {
+			defer cancel()
+			select {
+			case <-connDone:
+			case <-ctx.Done():
+			}
+		}()
+	}
+	return context.WithValue(ctx, http.LocalAddrContextKey, c.LocalAddr())
+}
+
+// A streamContext is canceled when the stream is closed,
+// and looks up values in the context of the connection.
+type streamContext struct {
+	context.Context // the context of the stream
+	values          context.Context
+}
+
+func (c *streamContext) Value(key interface{}) interface{} {
+	if v := c.values.Value(key); v != nil {
+		return v
+	}
+	return c.Context.Value(key)
+}
+
+// setState calls the ConnState callback.
+// It must be called with the stateMutex held.
+func (c *serverConn) setState(state http.ConnState) {
+	if c.stateClosed {
+		return
+	}
+	c.stateClosed = state == http.StateClosed
+	if c.connState != nil {
+		c.connState(c.EarlySession, state)
+	}
+}
+
+func (c *serverConn) setStateNew() {
+	c.stateMutex.Lock()
+	c.setState(http.StateNew)
+	c.stateMutex.Unlock()
+}
+
+func (c *serverConn) setStateClosed() {
+	c.stateMutex.Lock()
+	c.setState(http.StateClosed)
+	c.stateMutex.Unlock()
+}
+
+// acceptRequest is called for every request stream.
+// It returns false if the stream must be rejected, since a GOAWAY frame was already sent.
+// A PRIORITY_UPDATE frame received before the stream was opened is applied now.
+func (c *serverConn) acceptRequest(str quic.Stream) bool {
+	c.mutex.Lock()
+	if c.goingAway {
+		c.mutex.Unlock()
+		return false
+	}
+	id := str.StreamID()
+	if id >= c.nextStreamID {
+		c.nextStreamID = id + 4
+	}
+	r := &servedRequest{str: str}
+	if p, ok := c.pendingPriorities[id]; ok {
+		delete(c.pendingPriorities, id)
+		str.SetPriority(p.streamPriority())
+		r.priorityUpdated = true
+	}
+	c.requestStreams[id] = r
+	c.requests.Add(1)
+	c.mutex.Unlock()
+
+	// The ConnState callback is called without holding the mutex,
+	// so that it doesn't block the processing of the connection.
+	c.stateMutex.Lock()
+	c.activeRequests++
+	if c.activeRequests == 1 {
+		c.setState(http.StateActive)
+	}
+	c.stateMutex.Unlock()
+	return true
+}
+
+// finishRequest is called once a request stream was served.
+// The connection becomes idle once the last request was served.
+func (c *serverConn) finishRequest(id protocol.StreamID) {
+	c.mutex.Lock()
+	delete(c.requestStreams, id)
+	c.mutex.Unlock()
+
+	c.stateMutex.Lock()
+	c.activeRequests--
+	if c.activeRequests == 0 {
+		c.setState(http.StateIdle)
+	}
+	c.stateMutex.Unlock()
+	c.requests.Done()
+}
+
+// setRequestPriority sets the priority of a request stream, as requested by the Priority header field.
+// It is a no-op if the header field is absent, or if the priority was already changed by a PRIORITY_UPDATE frame.
+func (c *serverConn) setRequestPriority(id protocol.StreamID, hdr http.Header) {
+	if _, ok := hdr[PriorityHeader]; !ok {
+		return
+	}
+	p := ParsePriority(strings.Join(hdr[PriorityHeader], ","))
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if r, ok := c.requestStreams[id]; ok && !r.priorityUpdated {
+		r.str.SetPriority(p.streamPriority())
+	}
+}
+
+// handlePriorityUpdate handles a PRIORITY_UPDATE frame.
+// Frames for request streams that weren't opened yet are buffered,
+// frames for streams that were already served are ignored.
+func (c *serverConn) handlePriorityUpdate(f *priorityUpdateFrame) error {
+	p := ParsePriority(f.Priority)
+	if f.Push {
+		c.mutex.Lock()
+		defer c.mutex.Unlock()
+		if !c.pushAllowed || f.ElementID > c.maxPushID {
+			return fmt.Errorf("PRIORITY_UPDATE for push ID %d exceeds the limit", f.ElementID)
+		}
+		if str, ok := c.pushStreams[f.ElementID]; ok {
+			str.SetPriority(p.streamPriority())
+		}
+		return nil
+	}
+	id := protocol.StreamID(f.ElementID)
+	if id.InitiatedBy() != protocol.PerspectiveClient || id.Type() != protocol.StreamTypeBidi {
+		return fmt.Errorf("PRIORITY_UPDATE for invalid stream %d", f.ElementID)
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if r, ok := c.requestStreams[id]; ok {
+		r.str.SetPriority(p.streamPriority())
+		r.priorityUpdated = true
+		return nil
+	}
+	if id < c.nextStreamID {
+		return nil
+	}
+	if _, ok := c.pendingPriorities[id]; ok || len(c.pendingPriorities) < maxPendingPriorityUpdates {
+		c.pendingPriorities[id] = p
+	}
+	return nil
+}
+
+// goAway sends a GOAWAY frame on the control stream.
+// Requests with stream IDs larger than the last accepted stream ID won't be processed.
+func (c *serverConn) goAway() {
+	c.mutex.Lock()
+	if c.goingAway {
+		c.mutex.Unlock()
+		return
+	}
+	c.goingAway = true
+	id := c.nextStreamID
+	c.mutex.Unlock()
+
+	buf := &bytes.Buffer{}
+	(&goAwayFrame{StreamID: id}).Write(buf)
+	c.controlStream.Write(buf.Bytes())
+}
 
+func (s *Server) addConn(c *serverConn) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.closed.Get() {
+		return false
+	}
+	if s.conns == nil {
+		s.conns = make(map[*serverConn]struct{})
+	}
+	s.conns[c] = struct{}{}
+	return true
+}
+
+func (s *Server) removeConn(c *serverConn) {
+	s.mutex.Lock()
+	delete(s.conns, c)
+	s.mutex.Unlock()
+}
+
+func (s *Server) handleConn(sess quic.EarlySession) {
 	// send a SETTINGS frame
 	str, err := sess.OpenUniStream()
 	if err != nil {
@@ -237,10 +577,32 @@ func (s *Server) handleConn(sess quic.EarlySession) {
 	}
 	buf := &bytes.Buffer{}
 	quicvarint.Write(buf, streamTypeControlStream) // stream type
-	(&settingsFrame{Datagram: s.EnableDatagrams}).Write(buf)
+	(&settingsFrame{
+		QPACKMaxTableCapacity: s.QPACKMaxTableCapacity,
+		QPACKBlockedStreams:   s.QPACKBlockedStreams,
+		ExtendedConnect:       s.extendedConnectEnabled(),
+		Datagram:              s.datagramsEnabled(),
+		WebTransport:          s.EnableWebTransport,
+	}).Write(buf)
 	str.Write(buf.Bytes())
 
-	go s.handleUnidirectionalStreams(sess)
+	conn := s.newServerConn(sess)
+	conn.controlStream = str
+	conn.setStateNew()
+	defer conn.setStateClosed()
+	defer conn.decoder.close()
+	if err := openQPACKStreams(sess, conn.encoder, conn.decoder); err != nil {
+		s.logger.Debugf("Opening the QPACK streams failed: %s", err)
+		return
+	}
+	if !s.addConn(conn) {
+		// The server is shutting down.
+		sess.CloseWithError(quic.ErrorCode(errorNoError), "")
+		return
+	}
+	defer s.removeConn(conn)
+
+	go s.handleUnidirectionalStreams(conn)
 
 	// Process all requests immediately.
 	// It's the client's responsibility to decide which requests are eligible for 0-RTT.
@@ -250,10 +612,20 @@ func (s *Server) handleConn(sess quic.EarlySession) {
 			s.logger.Debugf("Accepting stream failed: %s", err)
 			return
 		}
+		if !conn.acceptRequest(str) {
+			// We already sent a GOAWAY frame, which tells the client that this request wasn't processed.
+			str.CancelRead(quic.ErrorCode(errorRequestRejected))
+			str.CancelWrite(quic.ErrorCode(errorRequestRejected))
+			continue
+		}
 		go func() {
-			rerr := s.handleRequest(sess, str, decoder, func() {
+			defer conn.finishRequest(str.StreamID())
+			rerr := s.handleRequest(conn, str, func() {
 				sess.CloseWithError(quic.ErrorCode(errorFrameUnexpected), "")
 			})
+			if rerr.err == errHijacked {
+				return
+			}
 			if rerr.err != nil || rerr.streamErr != 0 || rerr.connErr != 0 {
 				s.logger.Debugf("Handling request failed: %s", err)
 				if rerr.streamErr != 0 {
@@ -273,9 +645,9 @@ func (s *Server) handleConn(sess quic.EarlySession) {
 	}
 }
 
-func (s *Server) handleUnidirectionalStreams(sess quic.EarlySession) {
+func (s *Server) handleUnidirectionalStreams(conn *serverConn) {
 	for {
-		str, err := sess.AcceptUniStream(context.Background())
+		str, err := conn.AcceptUniStream(context.Background())
 		if err != nil {
 			s.logger.Debugf("accepting unidirectional stream failed: %s", err)
 			return
@@ -287,11 +659,32 @@ func (s *Server) handleUnidirectionalStreams(sess quic.EarlySession) {
 				s.logger.Debugf("reading stream type on stream %d failed: %s", str.StreamID(), err)
 				return
 			}
-			// We're only interested in the control stream here.
 			switch streamType {
 			case streamTypeControlStream:
 			case streamTypePushStream: // only the server can push
-				sess.CloseWithError(quic.ErrorCode(errorStreamCreationError), "")
+				conn.CloseWithError(quic.ErrorCode(errorStreamCreationError), "")
+				return
+			case streamTypeQPACKEncoderStream:
+				err := conn.decoder.readEncoderStream(str)
+				s.logger.Debugf("Reading the QPACK encoder stream failed: %s", err)
+				conn.CloseWithError(quic.ErrorCode(qpackStreamErrorCode(err)), "")
+				return
+			case streamTypeQPACKDecoderStream:
+				err := conn.encoder.readDecoderStream(str)
+				s.logger.Debugf("Reading the QPACK decoder stream failed: %s", err)
+				conn.CloseWithError(quic.ErrorCode(qpackStreamErrorCode(err)), "")
+				return
+			case streamTypeWebTransportStream:
+				if conn.webTransport == nil {
+					str.CancelRead(quic.ErrorCode(errorStreamCreationError))
+					return
+				}
+				id, err := quicvarint.Read(&byteReaderImpl{str})
+				if err != nil {
+					s.logger.Debugf("reading the session ID on stream %d failed: %s", str.StreamID(), err)
+					return
+				}
+				conn.webTransport.handleUniStream(protocol.StreamID(id), str)
 				return
 			default:
 				str.CancelRead(quic.ErrorCode(errorStreamCreationError))
@@ -299,27 +692,68 @@ func (s *Server) handleUnidirectionalStreams(sess quic.EarlySession) {
 			}
 			f, err := parseNextFrame(str)
 			if err != nil {
-				sess.CloseWithError(quic.ErrorCode(errorFrameError), "")
+				conn.CloseWithError(quic.ErrorCode(errorFrameError), "")
 				return
 			}
 			sf, ok := f.(*settingsFrame)
 			if !ok {
-				sess.CloseWithError(quic.ErrorCode(errorMissingSettings), "")
-				return
-			}
-			if !sf.Datagram {
+				conn.CloseWithError(quic.ErrorCode(errorMissingSettings), "")
 				return
 			}
 			// If datagram support was enabled on our side as well as on the client side,
 			// we can expect it to have been negotiated both on the transport and on the HTTP/3 layer.
 			// Note: ConnectionState() will block until the handshake is complete (relevant when using 0-RTT).
-			if s.EnableDatagrams && !sess.ConnectionState().SupportsDatagrams {
-				sess.CloseWithError(quic.ErrorCode(errorSettingsError), "missing QUIC Datagram support")
+			if sf.Datagram && s.datagramsEnabled() {
+				if !conn.ConnectionState().SupportsDatagrams {
+					conn.CloseWithError(quic.ErrorCode(errorSettingsError), "missing QUIC Datagram support")
+					return
+				}
+				conn.datagrams.start()
+			}
+			if err := conn.encoder.setPeerSettings(sf.QPACKMaxTableCapacity, sf.QPACKBlockedStreams); err != nil {
+				s.logger.Debugf("Writing to the QPACK encoder stream failed: %s", err)
 			}
+			s.handleControlStream(conn, str)
 		}(str)
 	}
 }
 
+// handleControlStream reads the frames following the SETTINGS frame on the client's control stream.
+func (s *Server) handleControlStream(conn *serverConn, str quic.ReceiveStream) {
+	for {
+		f, err := parseNextFrame(str)
+		if err != nil {
+			// The client must not close the control stream.
+			// If the session was already closed, this is a no-op.
+			code := errorFrameError
+			var serr quic.StreamError
+			if err == io.EOF || errors.As(err, &serr) {
+				code = errorClosedCriticalStream
+			}
+			conn.CloseWithError(quic.ErrorCode(code), "")
+			return
+		}
+		switch f := f.(type) {
+		case *maxPushIDFrame:
+			err = conn.handleMaxPushID(f.PushID)
+		case *cancelPushFrame:
+			err = conn.handleCancelPush(f.PushID)
+		case *goAwayFrame:
+			// Sent by the client, the GOAWAY frame contains a push ID.
+			conn.handleGoAway(uint64(f.StreamID))
+		case *priorityUpdateFrame:
+			err = conn.handlePriorityUpdate(f)
+		default:
+			conn.CloseWithError(quic.ErrorCode(errorFrameUnexpected), "")
+			return
+		}
+		if err != nil {
+			conn.CloseWithError(quic.ErrorCode(errorIDError), err.Error())
+			return
+		}
+	}
+}
+
 func (s *Server) maxHeaderBytes() uint64 {
 	if s.Server.MaxHeaderBytes <= 0 {
 		return http.DefaultMaxHeaderBytes
@@ -327,11 +761,18 @@ func (s *Server) maxHeaderBytes() uint64 {
 	return uint64(s.Server.MaxHeaderBytes)
 }
 
-func (s *Server) handleRequest(sess quic.Session, str quic.Stream, decoder *qpack.Decoder, onFrameError func()) requestError {
+func (s *Server) handleRequest(conn *serverConn, str quic.Stream, onFrameError func()) requestError {
 	frame, err := parseNextFrame(str)
 	if err != nil {
 		return newStreamError(errorRequestIncomplete, err)
 	}
+	if wf, ok := frame.(*webTransportStreamFrame); ok {
+		if conn.webTransport == nil {
+			return newConnError(errorFrameUnexpected, errors.New("received WEBTRANSPORT_STREAM frame, but WebTransport is not enabled"))
+		}
+		conn.webTransport.handleStream(wf.SessionID, str)
+		return requestError{err: errHijacked}
+	}
 	hf, ok := frame.(*headersFrame)
 	if !ok {
 		return newConnError(errorFrameUnexpected, errors.New("expected first frame to be a HEADERS frame"))
@@ -343,19 +784,27 @@ func (s *Server) handleRequest(sess quic.Session, str quic.Stream, decoder *qpac
 	if _, err := io.ReadFull(str, headerBlock); err != nil {
 		return newStreamError(errorRequestIncomplete, err)
 	}
-	hfs, err := decoder.DecodeFull(headerBlock)
+	ctx := str.Context()
+	decode := conn.decoder.streamDecoder(ctx, str.StreamID(), conn)
+	hfs, err := decode(headerBlock)
 	if err != nil {
-		// TODO: use the right error code
-		return newConnError(errorGeneralProtocolError, err)
+		// If decoding failed due to a QPACK error, the connection was closed.
+		return newStreamError(errorRequestCanceled, err)
 	}
 	req, err := requestFromHeaders(hfs)
 	if err != nil {
 		// TODO: use the right error code
 		return newStreamError(errorGeneralProtocolError, err)
 	}
+	if extendedConnectProtocol(req) != "" && !s.extendedConnectEnabled() {
+		return newStreamError(errorMessageError, errors.New("extended CONNECT not enabled"))
+	}
+
+	conn.setRequestPriority(str.StreamID(), req.Header)
 
-	req.RemoteAddr = sess.RemoteAddr().String()
-	req.Body = newRequestBody(str, onFrameError)
+	req.RemoteAddr = conn.RemoteAddr().String()
+	body := newRequestBody(str, onFrameError)
+	req.Body = body
 
 	if s.logger.Debug() {
 		s.logger.Infof("%s %s%s, on stream %d", req.Method, req.Host, req.RequestURI, str.StreamID())
@@ -363,12 +812,37 @@ func (s *Server) handleRequest(sess quic.Session, str quic.Stream, decoder *qpac
 		s.logger.Infof("%s %s%s", req.Method, req.Host, req.RequestURI)
 	}
 
-	ctx := str.Context()
-	ctx = context.WithValue(ctx, ServerContextKey, s)
-	ctx = context.WithValue(ctx, http.LocalAddrContextKey, sess.LocalAddr())
-	req = req.WithContext(ctx)
-	responseWriter := newResponseWriter(str, s.logger)
+	req = req.WithContext(conn.requestContext(ctx))
+	// The trailers are added to the request passed to the handler.
+	body.onTrailers = func(hf *headersFrame) error {
+		return readTrailers(str, hf, decode, s.maxHeaderBytes(), &req.Trailer)
+	}
+	responseWriter := newResponseWriter(str, conn.encoder, str.StreamID(), s.logger)
+	responseWriter.pusher = newPusher(s, conn, str.StreamID(), req)
+	if conn.webTransport != nil && extendedConnectProtocol(req) == webTransportProtocol {
+		responseWriter.webTransport = conn.webTransport
+		responseWriter.requestStream = str
+	} else if s.datagramsEnabled() {
+		// A WebTransport session registers its datagram flow once it is established.
+		responseWriter.datagrams = conn.datagrams.register(str.StreamID())
+		defer conn.datagrams.unregister(str.StreamID())
+	}
 	defer responseWriter.Flush()
+	s.serveHTTP(responseWriter, req)
+	if responseWriter.hijacked {
+		// The stream is now owned by the WebTransport session.
+		return requestError{err: errHijacked}
+	}
+
+	// If the EOF was read by the handler, CancelRead() is a no-op.
+	str.CancelRead(quic.ErrorCode(errorNoError))
+	return requestError{}
+}
+
+// serveHTTP calls the handler, and writes the response header if the handler didn't.
+// A panicking handler results in a 500 response.
+// Finally, the trailers set by the handler are written.
+func (s *Server) serveHTTP(w *responseWriter, req *http.Request) {
 	handler := s.Handler
 	if handler == nil {
 		handler = http.DefaultServeMux
@@ -386,18 +860,18 @@ func (s *Server) handleRequest(sess quic.Session, str quic.Stream, decoder *qpac
 				panicked = true
 			}
 		}()
-		handler.ServeHTTP(responseWriter, req)
+		handler.ServeHTTP(w, req)
 	}()
 
+	if w.hijacked {
+		return
+	}
 	if panicked {
-		responseWriter.WriteHeader(500)
+		w.WriteHeader(500)
 	} else {
-		responseWriter.WriteHeader(200)
+		w.WriteHeader(200)
 	}
-
-	// If the EOF was read by the handler, CancelRead() is a no-op.
-	str.CancelRead(quic.ErrorCode(errorNoError))
-	return requestError{}
+	w.writeTrailers()
 }
 
 // Close the server immediately, aborting requests and sending CONNECTION_CLOSE frames to connected clients.
@@ -418,15 +892,84 @@ func (s *Server) Close() error {
 }
 
 // CloseGracefully shuts down the server gracefully. The server sends a GOAWAY frame first, then waits for either timeout to trigger, or for all running requests to complete.
+// Once all requests on a connection are completed, the connection is closed when the client closes it, or after a few RTTs.
+// When the timeout triggers, the remaining connections are closed, and context.DeadlineExceeded is returned.
 // CloseGracefully in combination with ListenAndServe() (instead of Serve()) may race if it is called before a UDP socket is established.
 func (s *Server) CloseGracefully(timeout time.Duration) error {
-	// TODO: implement
-	return nil
+	s.mutex.Lock()
+	s.closed.Set(true)
+	conns := make([]*serverConn, 0, len(s.conns))
+	for c := range s.conns {
+		conns = append(conns, c)
+	}
+	s.mutex.Unlock()
+
+	if len(conns) == 0 {
+		return s.Close()
+	}
+
+	for _, c := range conns {
+		c.goAway()
+	}
+	timedOut := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(len(conns))
+	for _, c := range conns {
+		go func(c *serverConn) {
+			defer wg.Done()
+			// Waiting for the requests can't be interrupted, so it's done in a separate go routine.
+			// That go routine returns once the handlers of the remaining requests returned.
+			requestsDone := make(chan struct{})
+			go func() {
+				c.requests.Wait()
+				close(requestsDone)
+			}()
+			select {
+			case <-requestsDone:
+			case <-timedOut:
+				return
+			}
+			// Once all requests are completed, the connection becomes idle.
+			// Closing it right away might prevent the delivery of the responses,
+			// so we give the client a few PTOs to close the connection, before closing it.
+			stats := c.ConnectionStats()
+			timer := time.NewTimer(3 * (stats.SmoothedRTT + 4*stats.MeanDeviation))
+			defer timer.Stop()
+			select {
+			case <-c.Context().Done():
+			case <-timer.C:
+			case <-timedOut:
+				return
+			}
+			c.CloseWithError(quic.ErrorCode(errorNoError), "")
+		}(c)
+	}
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-done:
+		return s.Close()
+	case <-timer.C:
+	}
+	close(timedOut)
+	for _, c := range conns {
+		c.CloseWithError(quic.ErrorCode(errorNoError), "")
+	}
+	if err := s.Close(); err != nil {
+		return err
+	}
+	return context.DeadlineExceeded
 }
 
 // SetQuicHeaders can be used to set the proper headers that announce that this server supports QUIC.
 // The values that are set depend on the port information from s.Server.Addr, and currently look like this (if Addr has port 443):
-//  Alt-Svc: quic=":443"; ma=2592000; v="33,32,31,30"
+//  Alt-Svc: h3=":443"; ma=2592000
+// Every ALPN is only advertised once, even if it is used by multiple QUIC versions.
 func (s *Server) SetQuicHeaders(hdr http.Header) error {
 	port := atomic.LoadUint32(&s.port)
 
@@ -450,11 +993,17 @@ func (s *Server) SetQuicHeaders(hdr http.Header) error {
 		supportedVersions = s.QuicConfig.Versions
 	}
 	altSvc := make([]string, 0, len(supportedVersions))
+	advertised := make(map[string]struct{}, len(supportedVersions))
 	for _, version := range supportedVersions {
 		v := versionToALPN(version)
-		if len(v) > 0 {
-			altSvc = append(altSvc, fmt.Sprintf(`%s=":%d"; ma=2592000`, v, port))
+		if len(v) == 0 {
+			continue
+		}
+		if _, ok := advertised[v]; ok {
+			continue
 		}
+		advertised[v] = struct{}{}
+		altSvc = append(altSvc, fmt.Sprintf(`%s=":%d"; ma=2592000`, v, port))
 	}
 	hdr.Add("Alt-Svc", strings.Join(altSvc, ","))
 	return nil
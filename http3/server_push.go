@@ -0,0 +1,231 @@
+package http3
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"strings"
+
+	"github.com/For-ACGN/quic-go"
+	"github.com/For-ACGN/quic-go/internal/protocol"
+	"github.com/For-ACGN/quic-go/quicvarint"
+	"github.com/marten-seemann/qpack"
+)
+
+var errPushLimitReached = errors.New("http3: push ID limit reached")
+
+// A pusher pushes resources associated with a request.
+type pusher struct {
+	server   *Server
+	conn     *serverConn
+	streamID protocol.StreamID // the request stream
+	req      *http.Request     // the request that triggered the push
+}
+
+func newPusher(s *Server, conn *serverConn, streamID protocol.StreamID, req *http.Request) *pusher {
+	return &pusher{server: s, conn: conn, streamID: streamID, req: req}
+}
+
+// Push sends a PUSH_PROMISE frame on the request stream, and serves the promised request on a new push stream.
+func (p *pusher) Push(reqStr *bufio.Writer, target string, opts *http.PushOptions) error {
+	hfs, err := p.promisedHeaders(target, opts)
+	if err != nil {
+		return err
+	}
+	req, err := requestFromHeaders(hfs)
+	if err != nil {
+		return err
+	}
+	req.RemoteAddr = p.req.RemoteAddr
+
+	// The PUSH_PROMISE frame is sent on the request stream.
+	headers, err := p.conn.encoder.encode(p.streamID, hfs)
+	if err != nil {
+		return err
+	}
+
+	pushID, str, err := p.conn.openPushStream()
+	if err != nil {
+		return err
+	}
+	buf := &bytes.Buffer{}
+	(&pushPromiseFrame{PushID: pushID, Length: uint64(len(headers))}).Write(buf)
+	buf.Write(headers)
+	if _, err := reqStr.Write(buf.Bytes()); err != nil {
+		p.conn.abortPush(pushID, str)
+		return err
+	}
+	// The PUSH_PROMISE must be sent before the response references the pushed resource.
+	if err := reqStr.Flush(); err != nil {
+		p.conn.abortPush(pushID, str)
+		return err
+	}
+	go p.server.handlePush(p.conn, pushID, str, req)
+	return nil
+}
+
+// promisedHeaders validates the target and creates the header fields of the promised request.
+func (p *pusher) promisedHeaders(target string, opts *http.PushOptions) ([]qpack.HeaderField, error) {
+	method := http.MethodGet
+	var header http.Header
+	if opts != nil {
+		if opts.Method != "" {
+			method = opts.Method
+		}
+		header = opts.Header
+	}
+	// Only safe and cacheable methods can be pushed, see section 4.6 of RFC 9114.
+	if method != http.MethodGet && method != http.MethodHead {
+		return nil, fmt.Errorf("http3: method %q must be GET or HEAD", method)
+	}
+	u, err := url.Parse(target)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme == "" {
+		if !strings.HasPrefix(target, "/") {
+			return nil, fmt.Errorf("http3: target must be an absolute URL or absolute path: %q", target)
+		}
+		u.Scheme = "https"
+		u.Host = p.req.Host
+	} else if u.Scheme != "https" || u.Host != p.req.Host {
+		return nil, fmt.Errorf("http3: cannot push URL %q for a request to %s", target, p.req.Host)
+	}
+	if u.Host == "" {
+		return nil, errors.New("http3: cannot push without an authority")
+	}
+
+	hfs := []qpack.HeaderField{
+		{Name: ":method", Value: method},
+		{Name: ":scheme", Value: u.Scheme},
+		{Name: ":authority", Value: u.Host},
+		{Name: ":path", Value: u.RequestURI()},
+	}
+	for k, vv := range header {
+		if strings.HasPrefix(k, ":") {
+			return nil, fmt.Errorf("http3: promised request headers cannot include pseudo header %q", k)
+		}
+		// These headers are meaningless for a request without a body, copied from http2/server.go.
+		switch textproto.CanonicalMIMEHeaderKey(k) {
+		case "Content-Length", "Content-Encoding", "Trailer", "Te", "Expect", "Host":
+			return nil, fmt.Errorf("http3: promised request headers cannot include %q", k)
+		}
+		for _, v := range vv {
+			hfs = append(hfs, qpack.HeaderField{Name: strings.ToLower(k), Value: v})
+		}
+	}
+	return hfs, nil
+}
+
+// openPushStream allocates the next push ID, and opens a push stream for it.
+// The client limits the push IDs we're allowed to use by sending MAX_PUSH_ID frames.
+func (c *serverConn) openPushStream() (uint64, quic.SendStream, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if !c.pushAllowed {
+		return 0, nil, http.ErrNotSupported
+	}
+	if c.nextPushID > c.maxPushID {
+		return 0, nil, errPushLimitReached
+	}
+	str, err := c.OpenUniStream()
+	if err != nil {
+		return 0, nil, err
+	}
+	pushID := c.nextPushID
+	c.nextPushID++
+	buf := &bytes.Buffer{}
+	quicvarint.Write(buf, streamTypePushStream)
+	quicvarint.Write(buf, pushID)
+	if _, err := str.Write(buf.Bytes()); err != nil {
+		str.CancelWrite(quic.ErrorCode(errorRequestCanceled))
+		return 0, nil, err
+	}
+	if c.pushStreams == nil {
+		c.pushStreams = make(map[uint64]quic.SendStream)
+	}
+	c.pushStreams[pushID] = str
+	c.requests.Add(1)
+	return pushID, str, nil
+}
+
+func (c *serverConn) removePushStream(pushID uint64) {
+	c.mutex.Lock()
+	delete(c.pushStreams, pushID)
+	c.mutex.Unlock()
+}
+
+// abortPush is called if the PUSH_PROMISE couldn't be sent.
+func (c *serverConn) abortPush(pushID uint64, str quic.SendStream) {
+	str.CancelWrite(quic.ErrorCode(errorRequestCanceled))
+	c.removePushStream(pushID)
+	c.requests.Done()
+}
+
+// cancelPush resets the push stream, if it is still open.
+func (c *serverConn) cancelPush(pushID uint64) {
+	c.mutex.Lock()
+	str, ok := c.pushStreams[pushID]
+	c.mutex.Unlock()
+	if ok {
+		str.CancelWrite(quic.ErrorCode(errorRequestCanceled))
+	}
+}
+
+func (c *serverConn) handleMaxPushID(pushID uint64) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.pushAllowed && pushID < c.maxPushID {
+		return fmt.Errorf("MAX_PUSH_ID decreased from %d to %d", c.maxPushID, pushID)
+	}
+	c.pushAllowed = true
+	c.maxPushID = pushID
+	return nil
+}
+
+func (c *serverConn) handleCancelPush(pushID uint64) error {
+	c.mutex.Lock()
+	if !c.pushAllowed || pushID > c.maxPushID {
+		c.mutex.Unlock()
+		return fmt.Errorf("CANCEL_PUSH for push ID %d exceeds the limit", pushID)
+	}
+	c.mutex.Unlock()
+	c.cancelPush(pushID)
+	return nil
+}
+
+// handleGoAway handles a GOAWAY frame sent by the client.
+// The client won't accept any pushes with a push ID greater than or equal to the ID in the frame.
+func (c *serverConn) handleGoAway(pushID uint64) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if pushID == 0 {
+		c.pushAllowed = false
+	} else if pushID-1 < c.maxPushID {
+		c.maxPushID = pushID - 1
+	}
+}
+
+func (s *Server) handlePush(conn *serverConn, pushID uint64, str quic.SendStream, req *http.Request) {
+	defer conn.requests.Done()
+	defer conn.removePushStream(pushID)
+
+	if s.logger.Debug() {
+		s.logger.Infof("Pushing %s %s%s, on stream %d", req.Method, req.Host, req.RequestURI, str.StreamID())
+	} else {
+		s.logger.Infof("Pushing %s %s%s", req.Method, req.Host, req.RequestURI)
+	}
+
+	req = req.WithContext(conn.requestContext(str.Context()))
+	req.Body = http.NoBody
+
+	responseWriter := newResponseWriter(str, conn.encoder, str.StreamID(), s.logger)
+	// Pushed responses can't trigger further pushes, see section 4.6 of RFC 9114.
+	s.serveHTTP(responseWriter, req)
+	responseWriter.Flush()
+	str.Close()
+}
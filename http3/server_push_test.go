@@ -0,0 +1,264 @@
+package http3
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+
+	"github.com/For-ACGN/quic-go"
+	mockquic "github.com/For-ACGN/quic-go/internal/mocks/quic"
+	"github.com/For-ACGN/quic-go/internal/utils"
+	"github.com/For-ACGN/quic-go/quicvarint"
+
+	"github.com/golang/mock/gomock"
+	"github.com/marten-seemann/qpack"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Server Push", func() {
+	var (
+		s    *Server
+		sess *mockquic.MockEarlySession
+		conn *serverConn
+		req  *http.Request
+	)
+
+	BeforeEach(func() {
+		s = &Server{Server: &http.Server{}, logger: utils.DefaultLogger}
+		sess = mockquic.NewMockEarlySession(mockCtrl)
+		sess.EXPECT().LocalAddr().Return(&net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 443}).AnyTimes()
+		conn = s.newServerConn(sess)
+		var err error
+		req, err = http.NewRequest(http.MethodGet, "https://www.example.com/index.html", nil)
+		Expect(err).ToNot(HaveOccurred())
+		req.RemoteAddr = "127.0.0.1:1337"
+	})
+
+	Context("promised requests", func() {
+		decode := func(hfs []qpack.HeaderField) map[string]string {
+			fields := make(map[string]string)
+			for _, hf := range hfs {
+				fields[hf.Name] = hf.Value
+			}
+			return fields
+		}
+
+		It("uses GET for absolute paths", func() {
+			hfs, err := newPusher(s, conn, 0, req).promisedHeaders("/style.css?v=1", nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(decode(hfs)).To(Equal(map[string]string{
+				":method":    "GET",
+				":scheme":    "https",
+				":authority": "www.example.com",
+				":path":      "/style.css?v=1",
+			}))
+		})
+
+		It("accepts URLs with the same authority, and sets headers", func() {
+			hfs, err := newPusher(s, conn, 0, req).promisedHeaders("https://www.example.com/script.js", &http.PushOptions{
+				Method: http.MethodHead,
+				Header: http.Header{"Accept-Encoding": []string{"gzip"}},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			fields := decode(hfs)
+			Expect(fields).To(HaveKeyWithValue(":method", "HEAD"))
+			Expect(fields).To(HaveKeyWithValue(":path", "/script.js"))
+			Expect(fields).To(HaveKeyWithValue("accept-encoding", "gzip"))
+		})
+
+		It("rejects invalid targets", func() {
+			p := newPusher(s, conn, 0, req)
+			_, err := p.promisedHeaders("style.css", nil)
+			Expect(err).To(MatchError(`http3: target must be an absolute URL or absolute path: "style.css"`))
+			_, err = p.promisedHeaders("https://www.example.org/style.css", nil)
+			Expect(err).To(MatchError(`http3: cannot push URL "https://www.example.org/style.css" for a request to www.example.com`))
+			_, err = p.promisedHeaders("http://www.example.com/style.css", nil)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("rejects methods other than GET and HEAD", func() {
+			_, err := newPusher(s, conn, 0, req).promisedHeaders("/upload", &http.PushOptions{Method: http.MethodPost})
+			Expect(err).To(MatchError(`http3: method "POST" must be GET or HEAD`))
+		})
+
+		It("rejects headers that don't make sense for a promised request", func() {
+			p := newPusher(s, conn, 0, req)
+			_, err := p.promisedHeaders("/style.css", &http.PushOptions{Header: http.Header{"Content-Length": []string{"42"}}})
+			Expect(err).To(MatchError(`http3: promised request headers cannot include "Content-Length"`))
+			_, err = p.promisedHeaders("/style.css", &http.PushOptions{Header: http.Header{":path": []string{"/"}}})
+			Expect(err).To(MatchError(`http3: promised request headers cannot include pseudo header ":path"`))
+		})
+	})
+
+	Context("push IDs", func() {
+		It("doesn't push before receiving a MAX_PUSH_ID frame", func() {
+			_, _, err := conn.openPushStream()
+			Expect(err).To(MatchError(http.ErrNotSupported))
+		})
+
+		It("opens push streams up to the MAX_PUSH_ID", func() {
+			Expect(conn.handleMaxPushID(1)).To(Succeed())
+			for i := 0; i < 2; i++ {
+				buf := &bytes.Buffer{}
+				str := mockquic.NewMockStream(mockCtrl)
+				str.EXPECT().Write(gomock.Any()).DoAndReturn(buf.Write)
+				sess.EXPECT().OpenUniStream().Return(str, nil)
+				pushID, pushStr, err := conn.openPushStream()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(pushID).To(BeEquivalentTo(i))
+				Expect(pushStr).To(Equal(str))
+				r := bytes.NewReader(buf.Bytes())
+				streamType, err := quicvarint.Read(r)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(streamType).To(BeEquivalentTo(streamTypePushStream))
+				id, err := quicvarint.Read(r)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(id).To(BeEquivalentTo(i))
+			}
+			_, _, err := conn.openPushStream()
+			Expect(err).To(MatchError(errPushLimitReached))
+			Expect(conn.handleMaxPushID(2)).To(Succeed())
+			str := mockquic.NewMockStream(mockCtrl)
+			str.EXPECT().Write(gomock.Any())
+			sess.EXPECT().OpenUniStream().Return(str, nil)
+			pushID, _, err := conn.openPushStream()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(pushID).To(BeEquivalentTo(2))
+		})
+
+		It("errors when the MAX_PUSH_ID decreases", func() {
+			Expect(conn.handleMaxPushID(10)).To(Succeed())
+			Expect(conn.handleMaxPushID(9)).To(MatchError("MAX_PUSH_ID decreased from 10 to 9"))
+		})
+
+		It("cancels pushes", func() {
+			Expect(conn.handleMaxPushID(10)).To(Succeed())
+			str := mockquic.NewMockStream(mockCtrl)
+			str.EXPECT().Write(gomock.Any())
+			sess.EXPECT().OpenUniStream().Return(str, nil)
+			pushID, _, err := conn.openPushStream()
+			Expect(err).ToNot(HaveOccurred())
+			str.EXPECT().CancelWrite(quic.ErrorCode(errorRequestCanceled))
+			Expect(conn.handleCancelPush(pushID)).To(Succeed())
+			// push streams that were already closed are ignored
+			Expect(conn.handleCancelPush(5)).To(Succeed())
+			Expect(conn.handleCancelPush(11)).To(MatchError("CANCEL_PUSH for push ID 11 exceeds the limit"))
+		})
+
+		It("stops pushing when the client sends a GOAWAY frame", func() {
+			Expect(conn.handleMaxPushID(10)).To(Succeed())
+			conn.handleGoAway(5)
+			Expect(conn.maxPushID).To(BeEquivalentTo(4))
+			conn.handleGoAway(0)
+			_, _, err := conn.openPushStream()
+			Expect(err).To(MatchError(http.ErrNotSupported))
+		})
+	})
+
+	Context("pushing", func() {
+		It("returns http.ErrNotSupported for responses that can't push", func() {
+			rw := newResponseWriter(&bytes.Buffer{}, newQPACKEncoder(0), 0, utils.DefaultLogger)
+			Expect(rw.Push("/style.css", nil)).To(MatchError(http.ErrNotSupported))
+		})
+
+		It("sends a PUSH_PROMISE and serves the promised request", func() {
+			handlerCalled := make(chan *http.Request, 1)
+			s.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				handlerCalled <- r
+				io.WriteString(w, "body { }")
+			})
+			Expect(conn.handleMaxPushID(0)).To(Succeed())
+			pushBuf := &bytes.Buffer{}
+			pushStr := mockquic.NewMockStream(mockCtrl)
+			pushStr.EXPECT().Write(gomock.Any()).DoAndReturn(pushBuf.Write).AnyTimes()
+			pushStr.EXPECT().StreamID().AnyTimes()
+			pushStr.EXPECT().Context().Return(context.Background())
+			closed := make(chan struct{})
+			pushStr.EXPECT().Close().Do(func() { close(closed) })
+			sess.EXPECT().OpenUniStream().Return(pushStr, nil)
+
+			reqBuf := &bytes.Buffer{}
+			rw := newResponseWriter(reqBuf, newQPACKEncoder(0), 0, utils.DefaultLogger)
+			rw.pusher = newPusher(s, conn, 0, req)
+			Expect(rw.Push("/style.css", nil)).To(Succeed())
+			Eventually(closed).Should(BeClosed())
+			conn.requests.Wait()
+
+			// check the PUSH_PROMISE frame
+			f, err := parseNextFrame(reqBuf)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(f).To(BeAssignableToTypeOf(&pushPromiseFrame{}))
+			ppf := f.(*pushPromiseFrame)
+			Expect(ppf.PushID).To(BeZero())
+			hfs, err := qpack.NewDecoder(nil).DecodeFull(reqBuf.Next(int(ppf.Length)))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(hfs).To(ContainElement(qpack.HeaderField{Name: ":path", Value: "/style.css"}))
+			Expect(reqBuf.Len()).To(BeZero())
+
+			// check the pushed response
+			var r *http.Request
+			Expect(handlerCalled).To(Receive(&r))
+			Expect(r.Method).To(Equal(http.MethodGet))
+			Expect(r.Host).To(Equal("www.example.com"))
+			Expect(r.URL.Path).To(Equal("/style.css"))
+			Expect(r.RemoteAddr).To(Equal("127.0.0.1:1337"))
+			streamType, err := quicvarint.Read(pushBuf)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(streamType).To(BeEquivalentTo(streamTypePushStream))
+			pushID, err := quicvarint.Read(pushBuf)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(pushID).To(BeZero())
+			f, err = parseNextFrame(pushBuf)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(f).To(BeAssignableToTypeOf(&headersFrame{}))
+			pushBuf.Next(int(f.(*headersFrame).Length))
+			f, err = parseNextFrame(pushBuf)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(f).To(Equal(&dataFrame{Length: 8}))
+			Expect(pushBuf.String()).To(Equal("body { }"))
+		})
+
+		It("cancels the push stream if the PUSH_PROMISE can't be sent", func() {
+			Expect(conn.handleMaxPushID(0)).To(Succeed())
+			pushStr := mockquic.NewMockStream(mockCtrl)
+			pushStr.EXPECT().Write(gomock.Any())
+			pushStr.EXPECT().CancelWrite(quic.ErrorCode(errorRequestCanceled))
+			sess.EXPECT().OpenUniStream().Return(pushStr, nil)
+			reqStr := mockquic.NewMockStream(mockCtrl)
+			reqStr.EXPECT().Write(gomock.Any()).Return(0, errors.New("stream reset"))
+			p := newPusher(s, conn, 0, req)
+			Expect(p.Push(bufio.NewWriter(reqStr), "/style.css", nil)).To(MatchError("stream reset"))
+			conn.requests.Wait()
+			Expect(conn.pushStreams).To(BeEmpty())
+		})
+	})
+
+	Context("control stream", func() {
+		It("closes the connection when the MAX_PUSH_ID decreases", func() {
+			buf := &bytes.Buffer{}
+			(&maxPushIDFrame{PushID: 10}).Write(buf)
+			(&cancelPushFrame{PushID: 3}).Write(buf)
+			(&maxPushIDFrame{PushID: 5}).Write(buf)
+			str := mockquic.NewMockStream(mockCtrl)
+			str.EXPECT().Read(gomock.Any()).DoAndReturn(buf.Read).AnyTimes()
+			sess.EXPECT().CloseWithError(quic.ErrorCode(errorIDError), "MAX_PUSH_ID decreased from 10 to 5")
+			s.handleControlStream(conn, str)
+			Expect(conn.maxPushID).To(BeEquivalentTo(10))
+		})
+
+		It("closes the connection on unexpected frames", func() {
+			buf := &bytes.Buffer{}
+			(&dataFrame{}).Write(buf)
+			str := mockquic.NewMockStream(mockCtrl)
+			str.EXPECT().Read(gomock.Any()).DoAndReturn(buf.Read).AnyTimes()
+			sess.EXPECT().CloseWithError(quic.ErrorCode(errorFrameUnexpected), gomock.Any())
+			s.handleControlStream(conn, str)
+		})
+	})
+})
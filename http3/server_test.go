@@ -59,7 +59,6 @@ var _ = Describe("Server", func() {
 
 	Context("handling requests", func() {
 		var (
-			qpackDecoder       *qpack.Decoder
 			str                *mockquic.MockStream
 			sess               *mockquic.MockEarlySession
 			exampleGetRequest  *http.Request
@@ -90,9 +89,10 @@ var _ = Describe("Server", func() {
 			buf := &bytes.Buffer{}
 			str := mockquic.NewMockStream(mockCtrl)
 			str.EXPECT().Write(gomock.Any()).DoAndReturn(buf.Write).AnyTimes()
+			str.EXPECT().StreamID().AnyTimes()
 			closed := make(chan struct{})
 			str.EXPECT().Close().Do(func() { close(closed) })
-			rw := newRequestWriter(utils.DefaultLogger)
+			rw := newRequestWriter(newQPACKEncoder(0), utils.DefaultLogger)
 			Expect(rw.WriteRequest(str, req, false)).To(Succeed())
 			Eventually(closed).Should(BeClosed())
 			return buf.Bytes()
@@ -115,8 +115,8 @@ var _ = Describe("Server", func() {
 			examplePostRequest, err = http.NewRequest("POST", "https://www.example.com", bytes.NewReader([]byte("foobar")))
 			Expect(err).ToNot(HaveOccurred())
 
-			qpackDecoder = qpack.NewDecoder(nil)
 			str = mockquic.NewMockStream(mockCtrl)
+			str.EXPECT().StreamID().AnyTimes()
 
 			sess = mockquic.NewMockEarlySession(mockCtrl)
 			addr := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1337}
@@ -137,12 +137,71 @@ var _ = Describe("Server", func() {
 			}).AnyTimes()
 			str.EXPECT().CancelRead(gomock.Any())
 
-			Expect(s.handleRequest(sess, str, qpackDecoder, nil)).To(Equal(requestError{}))
+			Expect(s.handleRequest(s.newServerConn(sess), str, nil)).To(Equal(requestError{}))
 			var req *http.Request
 			Eventually(requestChan).Should(Receive(&req))
 			Expect(req.Host).To(Equal("www.example.com"))
 			Expect(req.RemoteAddr).To(Equal("127.0.0.1:1337"))
 			Expect(req.Context().Value(ServerContextKey)).To(Equal(s))
+			Expect(req.Context().Value(SessionContextKey)).To(Equal(sess))
+		})
+
+		It("adds the values of the connection context to the request context", func() {
+			type ctxKey int
+			s.ConnContext = func(ctx context.Context, sess quic.Session) context.Context {
+				Expect(ctx.Value(SessionContextKey)).To(Equal(sess))
+				return context.WithValue(ctx, ctxKey(42), "foobar")
+			}
+			requestChan := make(chan *http.Request, 1)
+			s.Handler = http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+				requestChan <- r
+			})
+
+			setRequest(encodeRequest(exampleGetRequest))
+			str.EXPECT().Context().Return(reqContext)
+			str.EXPECT().Write(gomock.Any()).DoAndReturn(func(p []byte) (int, error) {
+				return len(p), nil
+			}).AnyTimes()
+			str.EXPECT().CancelRead(gomock.Any())
+
+			Expect(s.handleRequest(s.newServerConn(sess), str, nil)).To(Equal(requestError{}))
+			var req *http.Request
+			Eventually(requestChan).Should(Receive(&req))
+			Expect(req.Context().Value(ctxKey(42))).To(Equal("foobar"))
+			Expect(req.Context().Value(ServerContextKey)).To(Equal(s))
+			Expect(req.Context().Value(SessionContextKey)).To(Equal(sess))
+		})
+
+		It("cancels the request context when the connection context is canceled", func() {
+			var cancel context.CancelFunc
+			s.ConnContext = func(ctx context.Context, _ quic.Session) context.Context {
+				ctx, cancel = context.WithCancel(ctx)
+				return ctx
+			}
+			requestChan := make(chan *http.Request, 1)
+			s.Handler = http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+				requestChan <- r
+			})
+
+			setRequest(encodeRequest(exampleGetRequest))
+			str.EXPECT().Context().Return(reqContext)
+			str.EXPECT().Write(gomock.Any()).DoAndReturn(func(p []byte) (int, error) {
+				return len(p), nil
+			}).AnyTimes()
+			str.EXPECT().CancelRead(gomock.Any())
+
+			Expect(s.handleRequest(s.newServerConn(sess), str, nil)).To(Equal(requestError{}))
+			var req *http.Request
+			Eventually(requestChan).Should(Receive(&req))
+			Consistently(req.Context().Done()).ShouldNot(BeClosed())
+			cancel()
+			Eventually(req.Context().Done()).Should(BeClosed())
+			Expect(req.Context().Value(SessionContextKey)).To(Equal(sess))
+		})
+
+		It("panics if ConnContext returns nil", func() {
+			s.ConnContext = func(context.Context, quic.Session) context.Context { return nil }
+			Expect(func() { s.newServerConn(sess) }).To(Panic())
 		})
 
 		It("returns 200 with an empty handler", func() {
@@ -154,7 +213,7 @@ var _ = Describe("Server", func() {
 			str.EXPECT().Write(gomock.Any()).DoAndReturn(responseBuf.Write).AnyTimes()
 			str.EXPECT().CancelRead(gomock.Any())
 
-			serr := s.handleRequest(sess, str, qpackDecoder, nil)
+			serr := s.handleRequest(s.newServerConn(sess), str, nil)
 			Expect(serr.err).ToNot(HaveOccurred())
 			hfs := decodeHeader(responseBuf)
 			Expect(hfs).To(HaveKeyWithValue(":status", []string{"200"}))
@@ -171,12 +230,94 @@ var _ = Describe("Server", func() {
 			str.EXPECT().Write(gomock.Any()).DoAndReturn(responseBuf.Write).AnyTimes()
 			str.EXPECT().CancelRead(gomock.Any())
 
-			serr := s.handleRequest(sess, str, qpackDecoder, nil)
+			serr := s.handleRequest(s.newServerConn(sess), str, nil)
 			Expect(serr.err).ToNot(HaveOccurred())
 			hfs := decodeHeader(responseBuf)
 			Expect(hfs).To(HaveKeyWithValue(":status", []string{"500"}))
 		})
 
+		It("sets the priority requested in the Priority header", func() {
+			s.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+			exampleGetRequest.Header.Set(PriorityHeader, "u=1, i")
+			setRequest(encodeRequest(exampleGetRequest))
+			str.EXPECT().Context().Return(reqContext)
+			str.EXPECT().Write(gomock.Any()).DoAndReturn(func(p []byte) (int, error) {
+				return len(p), nil
+			}).AnyTimes()
+			str.EXPECT().CancelRead(gomock.Any())
+			str.EXPECT().SetPriority(quic.StreamPriority{Urgency: 1, Incremental: true})
+
+			conn := s.newServerConn(sess)
+			Expect(conn.acceptRequest(str)).To(BeTrue())
+			Expect(s.handleRequest(conn, str, nil)).To(Equal(requestError{}))
+		})
+
+		Context("extended CONNECT", func() {
+			var extendedConnectRequest *http.Request
+
+			BeforeEach(func() {
+				var err error
+				extendedConnectRequest, err = http.NewRequest(http.MethodConnect, "https://www.example.com/chat", nil)
+				Expect(err).ToNot(HaveOccurred())
+				extendedConnectRequest.Proto = "websocket"
+			})
+
+			It("passes the protocol to the handler", func() {
+				s.EnableExtendedConnect = true
+				requestChan := make(chan *http.Request, 1)
+				s.Handler = http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+					requestChan <- r
+				})
+
+				setRequest(encodeRequest(extendedConnectRequest))
+				str.EXPECT().Context().Return(reqContext)
+				str.EXPECT().Write(gomock.Any()).DoAndReturn(func(p []byte) (int, error) {
+					return len(p), nil
+				}).AnyTimes()
+				str.EXPECT().CancelRead(gomock.Any())
+
+				Expect(s.handleRequest(s.newServerConn(sess), str, nil)).To(Equal(requestError{}))
+				var req *http.Request
+				Eventually(requestChan).Should(Receive(&req))
+				Expect(req.Method).To(Equal(http.MethodConnect))
+				Expect(req.Proto).To(Equal("websocket"))
+				Expect(req.URL.Path).To(Equal("/chat"))
+			})
+
+			It("rejects extended CONNECT requests if it is not enabled", func() {
+				s.Handler = http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+					Fail("Handler should not be called.")
+				})
+
+				setRequest(encodeRequest(extendedConnectRequest))
+				str.EXPECT().Context().Return(reqContext)
+
+				serr := s.handleRequest(s.newServerConn(sess), str, nil)
+				Expect(serr.streamErr).To(Equal(errorMessageError))
+			})
+
+			It("closes the connection when receiving a WEBTRANSPORT_STREAM frame, if WebTransport is not enabled", func() {
+				buf := &bytes.Buffer{}
+				(&webTransportStreamFrame{SessionID: 4}).Write(buf)
+				setRequest(buf.Bytes())
+
+				serr := s.handleRequest(s.newServerConn(sess), str, nil)
+				Expect(serr.connErr).To(Equal(errorFrameUnexpected))
+			})
+
+			It("passes WebTransport streams to the session", func() {
+				s.EnableWebTransport = true
+				buf := &bytes.Buffer{}
+				(&webTransportStreamFrame{SessionID: 4}).Write(buf)
+				setRequest(buf.Bytes())
+
+				conn := s.newServerConn(sess)
+				serr := s.handleRequest(conn, str, nil)
+				Expect(serr.err).To(MatchError(errHijacked))
+				Expect(conn.webTransport.buffered).To(HaveLen(1))
+			})
+		})
+
 		Context("control stream handling", func() {
 			var sess *mockquic.MockEarlySession
 			testDone := make(chan struct{})
@@ -197,8 +338,15 @@ var _ = Describe("Server", func() {
 				buf := &bytes.Buffer{}
 				quicvarint.Write(buf, streamTypeControlStream)
 				(&settingsFrame{}).Write(buf)
+				streamClosed := make(chan struct{})
 				controlStr := mockquic.NewMockStream(mockCtrl)
-				controlStr.EXPECT().Read(gomock.Any()).DoAndReturn(buf.Read).AnyTimes()
+				controlStr.EXPECT().Read(gomock.Any()).DoAndReturn(func(b []byte) (int, error) {
+					if buf.Len() == 0 {
+						<-streamClosed
+						return 0, io.EOF
+					}
+					return buf.Read(b)
+				}).AnyTimes()
 				sess.EXPECT().AcceptUniStream(gomock.Any()).DoAndReturn(func(context.Context) (quic.ReceiveStream, error) {
 					return controlStr, nil
 				})
@@ -208,6 +356,11 @@ var _ = Describe("Server", func() {
 				})
 				s.handleConn(sess)
 				time.Sleep(scaleDuration(20 * time.Millisecond)) // don't EXPECT any calls to sess.CloseWithError
+				// closing the control stream is a connection error
+				done := make(chan struct{})
+				sess.EXPECT().CloseWithError(quic.ErrorCode(errorClosedCriticalStream), gomock.Any()).Do(func(quic.ErrorCode, string) { close(done) })
+				close(streamClosed)
+				Eventually(done).Should(BeClosed())
 			})
 
 			It("ignores streams other than the control stream", func() {
@@ -457,6 +610,184 @@ var _ = Describe("Server", func() {
 			})
 		})
 
+		Context("closing gracefully", func() {
+			var (
+				controlStr *mockquic.MockStream
+				controlBuf *bytes.Buffer
+				goAwaySent chan struct{}
+				testDone   chan struct{}
+				connDone   chan struct{}
+			)
+
+			BeforeEach(func() {
+				testDone = make(chan struct{})
+				goAwaySent = make(chan struct{})
+				connDone = make(chan struct{})
+				testDone := testDone // the closure might run after the next BeforeEach
+				controlBuf = &bytes.Buffer{}
+				controlStr = mockquic.NewMockStream(mockCtrl)
+				controlStr.EXPECT().Write(gomock.Any()).DoAndReturn(controlBuf.Write)
+				controlStr.EXPECT().Write(gomock.Any()).DoAndReturn(func(b []byte) (int, error) {
+					defer close(goAwaySent)
+					return controlBuf.Write(b)
+				})
+				sess.EXPECT().OpenUniStream().Return(controlStr, nil)
+				sess.EXPECT().AcceptUniStream(gomock.Any()).DoAndReturn(func(context.Context) (quic.ReceiveStream, error) {
+					<-testDone
+					return nil, errors.New("test done")
+				}).MaxTimes(1)
+				str.EXPECT().StreamID().Return(quic.StreamID(0)).AnyTimes()
+				str.EXPECT().Context().Return(reqContext)
+				str.EXPECT().Write(gomock.Any()).DoAndReturn(func(p []byte) (int, error) {
+					return len(p), nil
+				}).AnyTimes()
+				str.EXPECT().CancelRead(gomock.Any()).AnyTimes()
+				setRequest(encodeRequest(exampleGetRequest))
+			})
+
+			AfterEach(func() {
+				close(testDone)
+				Eventually(connDone).Should(BeClosed())
+			})
+
+			// parseGoAway checks that a SETTINGS and a GOAWAY frame were sent on the control stream,
+			// and returns the stream ID of the GOAWAY frame.
+			parseGoAway := func() quic.StreamID {
+				b := bytes.NewReader(controlBuf.Bytes())
+				t, err := quicvarint.Read(b)
+				ExpectWithOffset(1, err).ToNot(HaveOccurred())
+				ExpectWithOffset(1, t).To(BeEquivalentTo(streamTypeControlStream))
+				f, err := parseNextFrame(b)
+				ExpectWithOffset(1, err).ToNot(HaveOccurred())
+				ExpectWithOffset(1, f).To(BeAssignableToTypeOf(&settingsFrame{}))
+				t, err = quicvarint.Read(b)
+				ExpectWithOffset(1, err).ToNot(HaveOccurred())
+				ExpectWithOffset(1, t).To(BeEquivalentTo(0x7))
+				_, err = quicvarint.Read(b) // length
+				ExpectWithOffset(1, err).ToNot(HaveOccurred())
+				id, err := quicvarint.Read(b)
+				ExpectWithOffset(1, err).ToNot(HaveOccurred())
+				return quic.StreamID(id)
+			}
+
+			It("rejects requests that race the GOAWAY, and waits for running requests", func() {
+				handlerCalled := make(chan struct{})
+				unblockHandler := make(chan struct{})
+				s.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					close(handlerCalled)
+					<-unblockHandler
+				})
+				str2 := mockquic.NewMockStream(mockCtrl)
+				str2.EXPECT().StreamID().Return(quic.StreamID(4)).AnyTimes()
+				rejected := make(chan struct{})
+				str2.EXPECT().CancelRead(quic.ErrorCode(errorRequestRejected))
+				str2.EXPECT().CancelWrite(quic.ErrorCode(errorRequestRejected)).Do(func(quic.ErrorCode) { close(rejected) })
+				sess.EXPECT().AcceptStream(gomock.Any()).Return(str, nil)
+				sess.EXPECT().AcceptStream(gomock.Any()).DoAndReturn(func(context.Context) (quic.Stream, error) {
+					<-goAwaySent
+					return str2, nil
+				})
+				sess.EXPECT().AcceptStream(gomock.Any()).DoAndReturn(func(context.Context) (quic.Stream, error) {
+					<-testDone
+					return nil, errors.New("test done")
+				})
+				go func() {
+					defer close(connDone)
+					s.handleConn(sess)
+				}()
+				Eventually(handlerCalled).Should(BeClosed())
+
+				closed := make(chan error, 1)
+				go func() { closed <- s.CloseGracefully(time.Hour) }()
+				Eventually(goAwaySent).Should(BeClosed())
+				Expect(parseGoAway()).To(Equal(quic.StreamID(4)))
+				Eventually(rejected).Should(BeClosed())
+				Consistently(closed).ShouldNot(Receive())
+
+				// once all requests are completed, the connection is closed after a few RTTs
+				sess.EXPECT().ConnectionStats().Return(quic.ConnectionStats{
+					SmoothedRTT:   scaleDuration(20 * time.Millisecond),
+					MeanDeviation: scaleDuration(5 * time.Millisecond),
+				})
+				sess.EXPECT().Context().Return(context.Background())
+				str.EXPECT().Close()
+				sess.EXPECT().CloseWithError(quic.ErrorCode(errorNoError), "")
+				start := time.Now()
+				close(unblockHandler)
+				Eventually(closed).Should(Receive(BeNil()))
+				Expect(time.Since(start)).To(BeNumerically(">=", scaleDuration(120*time.Millisecond)))
+			})
+
+			It("closes the connection when the client closes it, after all requests are completed", func() {
+				handlerCalled := make(chan struct{})
+				s.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { close(handlerCalled) })
+				str.EXPECT().Close()
+				sess.EXPECT().AcceptStream(gomock.Any()).Return(str, nil)
+				sess.EXPECT().AcceptStream(gomock.Any()).DoAndReturn(func(context.Context) (quic.Stream, error) {
+					<-testDone
+					return nil, errors.New("test done")
+				})
+				go func() {
+					defer close(connDone)
+					s.handleConn(sess)
+				}()
+				Eventually(handlerCalled).Should(BeClosed())
+
+				ctx, cancel := context.WithCancel(context.Background())
+				sess.EXPECT().ConnectionStats().Return(quic.ConnectionStats{SmoothedRTT: time.Hour})
+				sess.EXPECT().Context().Return(ctx)
+				closed := make(chan error, 1)
+				go func() { closed <- s.CloseGracefully(time.Hour) }()
+				Eventually(goAwaySent).Should(BeClosed())
+				Consistently(closed).ShouldNot(Receive())
+				sess.EXPECT().CloseWithError(quic.ErrorCode(errorNoError), "")
+				cancel()
+				Eventually(closed).Should(Receive(BeNil()))
+			})
+
+			It("closes the connection when the timeout expires", func() {
+				handlerCalled := make(chan struct{})
+				unblockHandler := make(chan struct{})
+				handlerDone := make(chan struct{})
+				// the handler ignores the context of the request
+				s.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					defer close(handlerDone)
+					close(handlerCalled)
+					<-unblockHandler
+				})
+				sess.EXPECT().AcceptStream(gomock.Any()).Return(str, nil)
+				sess.EXPECT().AcceptStream(gomock.Any()).DoAndReturn(func(context.Context) (quic.Stream, error) {
+					<-testDone
+					return nil, errors.New("test done")
+				})
+				str.EXPECT().Close().AnyTimes()
+				go func() {
+					defer close(connDone)
+					s.handleConn(sess)
+				}()
+				Eventually(handlerCalled).Should(BeClosed())
+
+				// The connection is closed when the timeout expires, but not again once the request completed.
+				sess.EXPECT().CloseWithError(quic.ErrorCode(errorNoError), "")
+				start := time.Now()
+				Expect(s.CloseGracefully(scaleDuration(50 * time.Millisecond))).To(MatchError(context.DeadlineExceeded))
+				Expect(time.Since(start)).To(BeNumerically(">=", scaleDuration(50*time.Millisecond)))
+				Expect(parseGoAway()).To(Equal(quic.StreamID(4)))
+				close(unblockHandler)
+				Eventually(handlerDone).Should(BeClosed())
+				time.Sleep(scaleDuration(10 * time.Millisecond)) // make sure the connection isn't closed again
+			})
+		})
+
+		It("closes new connections after closing gracefully", func() {
+			Expect(s.CloseGracefully(0)).To(Succeed())
+			controlStr := mockquic.NewMockStream(mockCtrl)
+			controlStr.EXPECT().Write(gomock.Any())
+			sess.EXPECT().OpenUniStream().Return(controlStr, nil)
+			sess.EXPECT().CloseWithError(quic.ErrorCode(errorNoError), "")
+			s.handleConn(sess)
+		})
+
 		It("resets the stream when the body of POST request is not read, and the request handler replaces the request.Body", func() {
 			handlerCalled := make(chan struct{})
 			s.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -474,7 +805,7 @@ var _ = Describe("Server", func() {
 			}).AnyTimes()
 			str.EXPECT().CancelRead(quic.ErrorCode(errorNoError))
 
-			serr := s.handleRequest(sess, str, qpackDecoder, nil)
+			serr := s.handleRequest(s.newServerConn(sess), str, nil)
 			Expect(serr.err).ToNot(HaveOccurred())
 			Eventually(handlerCalled).Should(BeClosed())
 		})
@@ -497,12 +828,151 @@ var _ = Describe("Server", func() {
 			}).AnyTimes()
 			str.EXPECT().CancelRead(quic.ErrorCode(errorNoError))
 
-			serr := s.handleRequest(sess, str, qpackDecoder, nil)
+			serr := s.handleRequest(s.newServerConn(sess), str, nil)
 			Expect(serr.err).ToNot(HaveOccurred())
 			Eventually(handlerCalled).Should(BeClosed())
 		})
 	})
 
+	Context("prioritization", func() {
+		var (
+			conn *serverConn
+			sess *mockquic.MockEarlySession
+		)
+
+		BeforeEach(func() {
+			sess = mockquic.NewMockEarlySession(mockCtrl)
+			conn = s.newServerConn(sess)
+		})
+
+		newStream := func(id quic.StreamID) *mockquic.MockStream {
+			str := mockquic.NewMockStream(mockCtrl)
+			str.EXPECT().StreamID().Return(id).AnyTimes()
+			return str
+		}
+
+		It("changes the priority of request streams", func() {
+			str := newStream(4)
+			Expect(conn.acceptRequest(str)).To(BeTrue())
+			str.EXPECT().SetPriority(quic.StreamPriority{Urgency: 6})
+			Expect(conn.handlePriorityUpdate(&priorityUpdateFrame{ElementID: 4, Priority: "u=6"})).To(Succeed())
+			// the PRIORITY_UPDATE frame takes precedence over the Priority header field
+			conn.setRequestPriority(4, http.Header{PriorityHeader: {"u=1"}})
+		})
+
+		It("buffers PRIORITY_UPDATE frames for streams that weren't opened yet", func() {
+			Expect(conn.handlePriorityUpdate(&priorityUpdateFrame{ElementID: 8, Priority: "u=0, i"})).To(Succeed())
+			Expect(conn.acceptRequest(newStream(4))).To(BeTrue())
+			str := newStream(8)
+			str.EXPECT().SetPriority(quic.StreamPriority{Urgency: 0, Incremental: true})
+			Expect(conn.acceptRequest(str)).To(BeTrue())
+			conn.setRequestPriority(8, http.Header{PriorityHeader: {"u=1"}})
+			Expect(conn.pendingPriorities).To(BeEmpty())
+		})
+
+		It("limits the number of buffered PRIORITY_UPDATE frames", func() {
+			for i := 0; i < maxPendingPriorityUpdates+10; i++ {
+				Expect(conn.handlePriorityUpdate(&priorityUpdateFrame{ElementID: uint64(4 * i), Priority: "u=1"})).To(Succeed())
+			}
+			Expect(conn.pendingPriorities).To(HaveLen(maxPendingPriorityUpdates))
+		})
+
+		It("ignores PRIORITY_UPDATE frames for streams that were already served", func() {
+			Expect(conn.acceptRequest(newStream(0))).To(BeTrue())
+			conn.finishRequest(0)
+			Expect(conn.handlePriorityUpdate(&priorityUpdateFrame{ElementID: 0, Priority: "u=1"})).To(Succeed())
+			Expect(conn.pendingPriorities).To(BeEmpty())
+		})
+
+		It("rejects PRIORITY_UPDATE frames for streams that are not client-initiated bidirectional streams", func() {
+			Expect(conn.handlePriorityUpdate(&priorityUpdateFrame{ElementID: 2})).To(MatchError("PRIORITY_UPDATE for invalid stream 2"))
+			Expect(conn.handlePriorityUpdate(&priorityUpdateFrame{ElementID: 5})).To(MatchError("PRIORITY_UPDATE for invalid stream 5"))
+		})
+
+		It("changes the priority of push streams", func() {
+			Expect(conn.handleMaxPushID(10)).To(Succeed())
+			str := mockquic.NewMockStream(mockCtrl)
+			conn.pushStreams = map[uint64]quic.SendStream{3: str}
+			str.EXPECT().SetPriority(quic.StreamPriority{Urgency: 7, Incremental: true})
+			Expect(conn.handlePriorityUpdate(&priorityUpdateFrame{Push: true, ElementID: 3, Priority: "u=7, i"})).To(Succeed())
+			// push streams that are already done are ignored
+			Expect(conn.handlePriorityUpdate(&priorityUpdateFrame{Push: true, ElementID: 4, Priority: "u=7"})).To(Succeed())
+			Expect(conn.handlePriorityUpdate(&priorityUpdateFrame{Push: true, ElementID: 11})).To(MatchError("PRIORITY_UPDATE for push ID 11 exceeds the limit"))
+		})
+	})
+
+	Context("connection state", func() {
+		var (
+			conn   *serverConn
+			sess   *mockquic.MockEarlySession
+			states []http.ConnState
+		)
+
+		BeforeEach(func() {
+			states = nil
+			sess = mockquic.NewMockEarlySession(mockCtrl)
+			s.ConnState = func(c quic.Session, state http.ConnState) {
+				Expect(c).To(Equal(sess))
+				states = append(states, state)
+			}
+			conn = s.newServerConn(sess)
+		})
+
+		newStream := func(id quic.StreamID) *mockquic.MockStream {
+			str := mockquic.NewMockStream(mockCtrl)
+			str.EXPECT().StreamID().Return(id).AnyTimes()
+			return str
+		}
+
+		It("reports the state changes of a connection", func() {
+			conn.setStateNew()
+			Expect(states).To(Equal([]http.ConnState{http.StateNew}))
+			Expect(conn.acceptRequest(newStream(0))).To(BeTrue())
+			Expect(states).To(Equal([]http.ConnState{http.StateNew, http.StateActive}))
+			conn.finishRequest(0)
+			Expect(states).To(Equal([]http.ConnState{http.StateNew, http.StateActive, http.StateIdle}))
+			conn.setStateClosed()
+			Expect(states).To(Equal([]http.ConnState{http.StateNew, http.StateActive, http.StateIdle, http.StateClosed}))
+		})
+
+		It("stays active until all requests were served", func() {
+			conn.setStateNew()
+			Expect(conn.acceptRequest(newStream(0))).To(BeTrue())
+			Expect(conn.acceptRequest(newStream(4))).To(BeTrue())
+			conn.finishRequest(0)
+			Expect(states).To(Equal([]http.ConnState{http.StateNew, http.StateActive}))
+			Expect(conn.acceptRequest(newStream(8))).To(BeTrue())
+			conn.finishRequest(8)
+			Expect(states).To(Equal([]http.ConnState{http.StateNew, http.StateActive}))
+			conn.finishRequest(4)
+			Expect(states).To(Equal([]http.ConnState{http.StateNew, http.StateActive, http.StateIdle}))
+		})
+
+		It("doesn't report any state changes after the connection was closed", func() {
+			conn.setStateNew()
+			Expect(conn.acceptRequest(newStream(0))).To(BeTrue())
+			conn.setStateClosed()
+			conn.finishRequest(0)
+			conn.setStateClosed()
+			Expect(states).To(Equal([]http.ConnState{http.StateNew, http.StateActive, http.StateClosed}))
+		})
+
+		It("doesn't hold the connection's mutex when calling the callback", func() {
+			s.ConnState = func(quic.Session, http.ConnState) {
+				// acquires the mutex
+				conn.handleGoAway(0)
+			}
+			conn = s.newServerConn(sess)
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				defer close(done)
+				Expect(conn.acceptRequest(newStream(0))).To(BeTrue())
+			}()
+			Eventually(done).Should(BeClosed())
+		})
+	})
+
 	Context("setting http headers", func() {
 		BeforeEach(func() {
 			s.QuicConfig = &quic.Config{Versions: []protocol.VersionNumber{protocol.VersionDraft29}}
@@ -550,6 +1020,14 @@ var _ = Describe("Server", func() {
 			Expect(s.SetQuicHeaders(hdr)).To(Succeed())
 			Expect(hdr).To(Equal(http.Header{"Alt-Svc": {`h3-32=":443"; ma=2592000,h3-29=":443"; ma=2592000`}}))
 		})
+
+		It("advertises h3 for QUIC v1 and v2", func() {
+			s.Server.Addr = ":443"
+			s.QuicConfig.Versions = []quic.VersionNumber{quic.Version1, quic.Version2, quic.VersionDraft29}
+			hdr := http.Header{}
+			Expect(s.SetQuicHeaders(hdr)).To(Succeed())
+			Expect(hdr).To(Equal(http.Header{"Alt-Svc": {`h3=":443"; ma=2592000,h3-29=":443"; ma=2592000`}}))
+		})
 	})
 
 	It("errors when ListenAndServe is called with s.Server nil", func() {
@@ -671,6 +1149,19 @@ var _ = Describe("Server", func() {
 			c, err = conf.GetConfigForClient(&tls.ClientHelloInfo{Conn: newMockConn(protocol.VersionDraft32)})
 			ExpectWithOffset(1, err).ToNot(HaveOccurred())
 			ExpectWithOffset(1, c.NextProtos).To(Equal([]string{nextProtoH3Draft32}))
+			c, err = conf.GetConfigForClient(&tls.ClientHelloInfo{Conn: newMockConn(protocol.Version1)})
+			ExpectWithOffset(1, err).ToNot(HaveOccurred())
+			ExpectWithOffset(1, c.NextProtos).To(Equal([]string{nextProtoH3}))
+			c, err = conf.GetConfigForClient(&tls.ClientHelloInfo{Conn: newMockConn(protocol.Version2)})
+			ExpectWithOffset(1, err).ToNot(HaveOccurred())
+			ExpectWithOffset(1, c.NextProtos).To(Equal([]string{nextProtoH3}))
+			// if the QUIC version can't be determined, the ALPN offered by the client is used
+			c, err = conf.GetConfigForClient(&tls.ClientHelloInfo{SupportedProtos: []string{"foo", nextProtoH3Draft29}})
+			ExpectWithOffset(1, err).ToNot(HaveOccurred())
+			ExpectWithOffset(1, c.NextProtos).To(Equal([]string{nextProtoH3Draft29}))
+			c, err = conf.GetConfigForClient(&tls.ClientHelloInfo{SupportedProtos: []string{"foo"}})
+			ExpectWithOffset(1, err).ToNot(HaveOccurred())
+			ExpectWithOffset(1, c.NextProtos).To(Equal([]string{nextProtoH3}))
 		}
 
 		It("uses the quic.Config to start the QUIC server", func() {
@@ -0,0 +1,112 @@
+package http3
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/textproto"
+	"sort"
+	"strings"
+
+	"github.com/marten-seemann/qpack"
+	"golang.org/x/net/http/httpguts"
+)
+
+// declaredTrailers removes the "Trailer" header and returns the trailers it declares.
+// The values are filled in once the trailers are received.
+// Adapted from http2/server.go.
+func declaredTrailers(h http.Header) http.Header {
+	var trailer http.Header
+	for _, v := range h["Trailer"] {
+		for _, key := range strings.Split(v, ",") {
+			key = http.CanonicalHeaderKey(textproto.TrimString(key))
+			switch key {
+			case "Transfer-Encoding", "Trailer", "Content-Length":
+				// Bogus. (copy of http1 rules)
+				// Ignore.
+			default:
+				if trailer == nil {
+					trailer = make(http.Header)
+				}
+				trailer[key] = nil
+			}
+		}
+	}
+	delete(h, "Trailer")
+	return trailer
+}
+
+// readTrailers reads and decodes the trailer section of a trailing HEADERS frame.
+// The trailers are added to trailer, which is allocated if necessary.
+func readTrailers(
+	str io.Reader,
+	hf *headersFrame,
+	decode func([]byte) ([]qpack.HeaderField, error),
+	maxHeaderBytes uint64,
+	trailer *http.Header,
+) error {
+	if hf.Length > maxHeaderBytes {
+		return fmt.Errorf("HEADERS frame too large: %d bytes (max: %d)", hf.Length, maxHeaderBytes)
+	}
+	headerBlock := make([]byte, hf.Length)
+	if _, err := io.ReadFull(str, headerBlock); err != nil {
+		return err
+	}
+	hfs, err := decode(headerBlock)
+	if err != nil {
+		return err
+	}
+	t := make(http.Header, len(hfs))
+	for _, hf := range hfs {
+		if hf.IsPseudo() {
+			return fmt.Errorf("invalid pseudo header in trailers: %s", hf.Name)
+		}
+		key := http.CanonicalHeaderKey(hf.Name)
+		if !httpguts.ValidTrailerHeader(key) {
+			return fmt.Errorf("invalid trailer: %s", key)
+		}
+		t[key] = append(t[key], hf.Value)
+	}
+	if *trailer == nil {
+		*trailer = t
+		return nil
+	}
+	for k, vv := range t {
+		(*trailer)[k] = vv
+	}
+	return nil
+}
+
+// commaSeparatedTrailers returns the value of the "Trailer" header for a request.
+// Copied from http2/transport.go.
+func commaSeparatedTrailers(req *http.Request) (string, error) {
+	keys := make([]string, 0, len(req.Trailer))
+	for k := range req.Trailer {
+		k = http.CanonicalHeaderKey(k)
+		switch k {
+		case "Transfer-Encoding", "Trailer", "Content-Length":
+			return "", fmt.Errorf("invalid Trailer key %q", k)
+		}
+		keys = append(keys, k)
+	}
+	if len(keys) > 0 {
+		sort.Strings(keys)
+		return strings.Join(keys, ","), nil
+	}
+	return "", nil
+}
+
+// trailerFields returns the fields of the trailer section, skipping invalid trailer fields.
+func trailerFields(trailer http.Header) []qpack.HeaderField {
+	var hfs []qpack.HeaderField
+	for k, vv := range trailer {
+		if !httpguts.ValidTrailerHeader(k) {
+			continue
+		}
+		name := strings.ToLower(k)
+		for _, v := range vv {
+			hfs = append(hfs, qpack.HeaderField{Name: name, Value: v})
+		}
+	}
+	return hfs
+}
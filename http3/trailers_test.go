@@ -0,0 +1,62 @@
+package http3
+
+import (
+	"bytes"
+	"net/http"
+
+	"github.com/marten-seemann/qpack"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Trailers", func() {
+	encode := func(fields ...qpack.HeaderField) (*headersFrame, *bytes.Buffer) {
+		buf := &bytes.Buffer{}
+		enc := qpack.NewEncoder(buf)
+		for _, f := range fields {
+			Expect(enc.WriteField(f)).To(Succeed())
+		}
+		return &headersFrame{Length: uint64(buf.Len())}, buf
+	}
+
+	It("reads trailers", func() {
+		hf, buf := encode(
+			qpack.HeaderField{Name: "grpc-status", Value: "0"},
+			qpack.HeaderField{Name: "foo", Value: "bar"},
+			qpack.HeaderField{Name: "foo", Value: "baz"},
+		)
+		trailer := http.Header{"Grpc-Status": nil, "Declared": nil}
+		Expect(readTrailers(buf, hf, qpack.NewDecoder(nil).DecodeFull, 1000, &trailer)).To(Succeed())
+		Expect(trailer).To(Equal(http.Header{
+			"Grpc-Status": []string{"0"},
+			"Foo":         []string{"bar", "baz"},
+			"Declared":    nil,
+		}))
+	})
+
+	It("allocates the trailers", func() {
+		hf, buf := encode(qpack.HeaderField{Name: "foo", Value: "bar"})
+		var trailer http.Header
+		Expect(readTrailers(buf, hf, qpack.NewDecoder(nil).DecodeFull, 1000, &trailer)).To(Succeed())
+		Expect(trailer).To(Equal(http.Header{"Foo": []string{"bar"}}))
+	})
+
+	It("rejects too large trailers", func() {
+		hf, buf := encode(qpack.HeaderField{Name: "foo", Value: "bar"})
+		var trailer http.Header
+		Expect(readTrailers(buf, hf, qpack.NewDecoder(nil).DecodeFull, 3, &trailer)).To(MatchError(ContainSubstring("HEADERS frame too large")))
+	})
+
+	It("rejects pseudo headers", func() {
+		hf, buf := encode(qpack.HeaderField{Name: ":status", Value: "200"})
+		var trailer http.Header
+		Expect(readTrailers(buf, hf, qpack.NewDecoder(nil).DecodeFull, 1000, &trailer)).To(MatchError("invalid pseudo header in trailers: :status"))
+	})
+
+	It("rejects headers that are not allowed in trailers", func() {
+		hf, buf := encode(qpack.HeaderField{Name: "content-length", Value: "42"})
+		var trailer http.Header
+		Expect(readTrailers(buf, hf, qpack.NewDecoder(nil).DecodeFull, 1000, &trailer)).To(MatchError("invalid trailer: Content-Length"))
+	})
+})
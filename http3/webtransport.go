@@ -0,0 +1,396 @@
+package http3
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"sync"
+
+	"github.com/For-ACGN/quic-go"
+	"github.com/For-ACGN/quic-go/internal/protocol"
+	"github.com/For-ACGN/quic-go/internal/utils"
+	"github.com/For-ACGN/quic-go/quicvarint"
+)
+
+const (
+	// the value of the :protocol pseudo header used by WebTransport
+	webTransportProtocol = "webtransport"
+	// the header sent by the client, announcing support for draft-ietf-webtrans-http3-02
+	webTransportDraftOfferHeader = "Sec-Webtransport-Http3-Draft02"
+	// the header sent by the server, selecting draft-ietf-webtrans-http3-02
+	webTransportDraftHeader = "Sec-Webtransport-Http3-Draft"
+)
+
+// maxBufferedWebTransportStreams is the maximum number of streams that are buffered
+// because they arrived before the WebTransport session they belong to was established.
+const maxBufferedWebTransportStreams = 16
+
+// maxClosedWebTransportSessions is the number of closed WebTransport sessions that are remembered,
+// such that streams arriving after the session was closed are rejected instead of being buffered.
+const maxClosedWebTransportSessions = 128
+
+// webTransportAcceptQueueLen is the number of streams per session that are queued until they are accepted.
+const webTransportAcceptQueueLen = 32
+
+// ErrWebTransportSessionClosed is returned when using a WebTransport session that was already closed.
+var ErrWebTransportSessionClosed = errors.New("http3: WebTransport session closed")
+
+// A WebTransportSession is a WebTransport session (draft-ietf-webtrans-http3-02).
+// It is established by an extended CONNECT request,
+// and is terminated when the stream carrying that request is closed.
+type WebTransportSession struct {
+	id   protocol.StreamID // the stream ID of the CONNECT stream
+	str  quic.Stream       // the CONNECT stream
+	sess quic.Session
+
+	manager   *webTransportManager
+	datagrams *datagramFlow
+
+	streams    chan quic.Stream
+	uniStreams chan quic.ReceiveStream
+
+	ctx       context.Context
+	cancel    context.CancelFunc
+	closeOnce sync.Once
+	onClose   func()
+}
+
+// OpenStream opens a new bidirectional stream, without blocking.
+// It returns an error if the stream limit of the QUIC session was reached.
+func (s *WebTransportSession) OpenStream() (quic.Stream, error) {
+	if s.ctx.Err() != nil {
+		return nil, ErrWebTransportSessionClosed
+	}
+	str, err := s.sess.OpenStream()
+	if err != nil {
+		return nil, err
+	}
+	return str, s.writeStreamHeader(str)
+}
+
+// OpenStreamSync opens a new bidirectional stream.
+// It blocks until a new stream can be opened.
+func (s *WebTransportSession) OpenStreamSync(ctx context.Context) (quic.Stream, error) {
+	if s.ctx.Err() != nil {
+		return nil, ErrWebTransportSessionClosed
+	}
+	str, err := s.sess.OpenStreamSync(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return str, s.writeStreamHeader(str)
+}
+
+// OpenUniStream opens a new unidirectional stream, without blocking.
+// It returns an error if the stream limit of the QUIC session was reached.
+func (s *WebTransportSession) OpenUniStream() (quic.SendStream, error) {
+	if s.ctx.Err() != nil {
+		return nil, ErrWebTransportSessionClosed
+	}
+	str, err := s.sess.OpenUniStream()
+	if err != nil {
+		return nil, err
+	}
+	return str, s.writeUniStreamHeader(str)
+}
+
+// OpenUniStreamSync opens a new unidirectional stream.
+// It blocks until a new stream can be opened.
+func (s *WebTransportSession) OpenUniStreamSync(ctx context.Context) (quic.SendStream, error) {
+	if s.ctx.Err() != nil {
+		return nil, ErrWebTransportSessionClosed
+	}
+	str, err := s.sess.OpenUniStreamSync(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return str, s.writeUniStreamHeader(str)
+}
+
+// The stream header is sent right away, such that the peer can associate the stream with this session,
+// even if the application doesn't send any data.
+func (s *WebTransportSession) writeStreamHeader(str quic.Stream) error {
+	buf := &bytes.Buffer{}
+	(&webTransportStreamFrame{SessionID: s.id}).Write(buf)
+	_, err := str.Write(buf.Bytes())
+	return err
+}
+
+func (s *WebTransportSession) writeUniStreamHeader(str quic.SendStream) error {
+	buf := &bytes.Buffer{}
+	quicvarint.Write(buf, streamTypeWebTransportStream)
+	quicvarint.Write(buf, uint64(s.id))
+	_, err := str.Write(buf.Bytes())
+	return err
+}
+
+// AcceptStream accepts a bidirectional stream opened by the peer.
+func (s *WebTransportSession) AcceptStream(ctx context.Context) (quic.Stream, error) {
+	select {
+	case str := <-s.streams:
+		return str, nil
+	case <-s.ctx.Done():
+		return nil, ErrWebTransportSessionClosed
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// AcceptUniStream accepts a unidirectional stream opened by the peer.
+func (s *WebTransportSession) AcceptUniStream(ctx context.Context) (quic.ReceiveStream, error) {
+	select {
+	case str := <-s.uniStreams:
+		return str, nil
+	case <-s.ctx.Done():
+		return nil, ErrWebTransportSessionClosed
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// SendMessage sends a datagram.
+// Datagram support must have been negotiated on the QUIC and on the HTTP/3 layer.
+func (s *WebTransportSession) SendMessage(b []byte) error {
+	if s.ctx.Err() != nil {
+		return ErrWebTransportSessionClosed
+	}
+	return s.datagrams.send(b)
+}
+
+// ReceiveMessage gets a datagram sent by the peer.
+func (s *WebTransportSession) ReceiveMessage(ctx context.Context) ([]byte, error) {
+	select {
+	case data, ok := <-s.datagrams.queue:
+		if !ok {
+			return nil, ErrWebTransportSessionClosed
+		}
+		return data, nil
+	case <-s.ctx.Done():
+		return nil, ErrWebTransportSessionClosed
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Context returns a context that is canceled when the session is closed.
+func (s *WebTransportSession) Context() context.Context {
+	return s.ctx
+}
+
+// LocalAddr returns the local address of the QUIC session.
+func (s *WebTransportSession) LocalAddr() net.Addr {
+	return s.sess.LocalAddr()
+}
+
+// RemoteAddr returns the address of the peer.
+func (s *WebTransportSession) RemoteAddr() net.Addr {
+	return s.sess.RemoteAddr()
+}
+
+// Close closes the session, by closing the CONNECT stream.
+// Streams that were opened or accepted are not closed.
+func (s *WebTransportSession) Close() error {
+	s.close()
+	return nil
+}
+
+// run reads from the CONNECT stream, until the peer closes it.
+func (s *WebTransportSession) run() {
+	// draft-ietf-webtrans-http3-02 doesn't define any data sent on the CONNECT stream.
+	io.Copy(ioutil.Discard, s.str)
+	s.close()
+}
+
+func (s *WebTransportSession) close() {
+	s.closeOnce.Do(func() {
+		s.str.CancelRead(quic.ErrorCode(errorNoError))
+		s.str.Close()
+		s.cancel()
+		s.manager.removeSession(s.id)
+		if s.onClose != nil {
+			s.onClose()
+		}
+	})
+}
+
+func (s *WebTransportSession) addStream(str quic.Stream) {
+	select {
+	case s.streams <- str:
+	default:
+		str.CancelRead(quic.ErrorCode(errorWebTransportBufferedStreamRejected))
+		str.CancelWrite(quic.ErrorCode(errorWebTransportBufferedStreamRejected))
+	}
+}
+
+func (s *WebTransportSession) addUniStream(str quic.ReceiveStream) {
+	select {
+	case s.uniStreams <- str:
+	default:
+		str.CancelRead(quic.ErrorCode(errorWebTransportBufferedStreamRejected))
+	}
+}
+
+// A bufferedWebTransportStream is a stream that was received before
+// the WebTransport session it belongs to was established.
+// Either str or uniStr is set.
+type bufferedWebTransportStream struct {
+	sessionID protocol.StreamID
+	str       quic.Stream
+	uniStr    quic.ReceiveStream
+}
+
+func (b *bufferedWebTransportStream) reject() {
+	if b.str != nil {
+		b.str.CancelRead(quic.ErrorCode(errorWebTransportBufferedStreamRejected))
+		b.str.CancelWrite(quic.ErrorCode(errorWebTransportBufferedStreamRejected))
+	} else {
+		b.uniStr.CancelRead(quic.ErrorCode(errorWebTransportBufferedStreamRejected))
+	}
+}
+
+// A webTransportManager keeps track of the WebTransport sessions on a QUIC session,
+// and associates incoming streams with these sessions.
+type webTransportManager struct {
+	sess      quic.Session
+	datagrams *datagramManager
+	logger    utils.Logger
+
+	mutex    sync.Mutex
+	sessions map[protocol.StreamID]*WebTransportSession
+	closed   map[protocol.StreamID]struct{} // the most recently closed sessions
+	// the IDs of the sessions in closed, in the order they were closed
+	closedOrder []protocol.StreamID
+	buffered    []bufferedWebTransportStream
+}
+
+func newWebTransportManager(sess quic.Session, datagrams *datagramManager, logger utils.Logger) *webTransportManager {
+	return &webTransportManager{
+		sess:      sess,
+		datagrams: datagrams,
+		logger:    logger,
+		sessions:  make(map[protocol.StreamID]*WebTransportSession),
+		closed:    make(map[protocol.StreamID]struct{}),
+	}
+}
+
+// newSession establishes a new WebTransport session on the CONNECT stream str.
+// Streams for this session that were buffered are delivered to the session.
+// onClose is called when the session is closed.
+func (m *webTransportManager) newSession(str quic.Stream, onClose func()) *WebTransportSession {
+	ctx, cancel := context.WithCancel(m.sess.Context())
+	s := &WebTransportSession{
+		id:         str.StreamID(),
+		str:        str,
+		sess:       m.sess,
+		manager:    m,
+		datagrams:  m.datagrams.register(str.StreamID()),
+		streams:    make(chan quic.Stream, webTransportAcceptQueueLen),
+		uniStreams: make(chan quic.ReceiveStream, webTransportAcceptQueueLen),
+		ctx:        ctx,
+		cancel:     cancel,
+		onClose:    onClose,
+	}
+
+	m.mutex.Lock()
+	m.sessions[s.id] = s
+	buffered := m.buffered[:0]
+	for _, b := range m.buffered {
+		switch {
+		case b.sessionID != s.id:
+			buffered = append(buffered, b)
+		case b.str != nil:
+			s.addStream(b.str)
+		default:
+			s.addUniStream(b.uniStr)
+		}
+	}
+	m.buffered = buffered
+	m.mutex.Unlock()
+
+	go s.run()
+	return s
+}
+
+func (m *webTransportManager) removeSession(id protocol.StreamID) {
+	m.mutex.Lock()
+	delete(m.sessions, id)
+	m.closed[id] = struct{}{}
+	m.closedOrder = append(m.closedOrder, id)
+	if len(m.closedOrder) > maxClosedWebTransportSessions {
+		delete(m.closed, m.closedOrder[0])
+		m.closedOrder = m.closedOrder[1:]
+	}
+	m.mutex.Unlock()
+	m.datagrams.unregister(id)
+}
+
+// handleStream handles a bidirectional stream opened by the peer,
+// after the WEBTRANSPORT_STREAM frame was read.
+func (m *webTransportManager) handleStream(id protocol.StreamID, str quic.Stream) {
+	m.handle(bufferedWebTransportStream{sessionID: id, str: str})
+}
+
+// handleUniStream handles a unidirectional stream opened by the peer,
+// after the stream type and the session ID were read.
+func (m *webTransportManager) handleUniStream(id protocol.StreamID, str quic.ReceiveStream) {
+	m.handle(bufferedWebTransportStream{sessionID: id, uniStr: str})
+}
+
+func (m *webTransportManager) handle(b bufferedWebTransportStream) {
+	// The session ID is the stream ID of the CONNECT stream, which is always opened by the client.
+	if b.sessionID.InitiatedBy() != protocol.PerspectiveClient || b.sessionID.Type() != protocol.StreamTypeBidi {
+		m.sess.CloseWithError(quic.ErrorCode(errorIDError), "invalid WebTransport session ID")
+		return
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if s, ok := m.sessions[b.sessionID]; ok {
+		if b.str != nil {
+			s.addStream(b.str)
+		} else {
+			s.addUniStream(b.uniStr)
+		}
+		return
+	}
+	if _, ok := m.closed[b.sessionID]; ok {
+		b.reject()
+		return
+	}
+	if len(m.buffered) >= maxBufferedWebTransportStreams {
+		m.logger.Debugf("Rejecting stream for WebTransport session %d. Too many buffered streams.", b.sessionID)
+		b.reject()
+		return
+	}
+	m.buffered = append(m.buffered, b)
+}
+
+// UpgradeWebTransport establishes a WebTransport session.
+// It must be called from the http.Handler of a http3.Server with EnableWebTransport set,
+// for a WebTransport request (an extended CONNECT request using the "webtransport" protocol).
+// It sends a 200 response. The handler must not write to the http.ResponseWriter afterwards.
+// The session is closed when either side closes the CONNECT stream;
+// it is not affected by the handler returning.
+func UpgradeWebTransport(w http.ResponseWriter, r *http.Request) (*WebTransportSession, error) {
+	rw, ok := w.(*responseWriter)
+	if !ok || rw.webTransport == nil {
+		return nil, errors.New("http3: WebTransport not enabled")
+	}
+	if extendedConnectProtocol(r) != webTransportProtocol {
+		return nil, errors.New("http3: not a WebTransport request")
+	}
+	if rw.headerWritten {
+		return nil, errors.New("http3: response header already written")
+	}
+	rw.header.Set(webTransportDraftHeader, "draft02")
+	rw.WriteHeader(http.StatusOK)
+	if err := rw.stream.Flush(); err != nil {
+		return nil, err
+	}
+	rw.hijacked = true
+	return rw.webTransport.newSession(rw.requestStream, nil), nil
+}
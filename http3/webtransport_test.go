@@ -0,0 +1,148 @@
+package http3
+
+import (
+	"bytes"
+	"context"
+	"io"
+
+	"github.com/For-ACGN/quic-go"
+	mockquic "github.com/For-ACGN/quic-go/internal/mocks/quic"
+	"github.com/For-ACGN/quic-go/internal/protocol"
+	"github.com/For-ACGN/quic-go/internal/utils"
+	"github.com/For-ACGN/quic-go/quicvarint"
+
+	"github.com/golang/mock/gomock"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("WebTransport", func() {
+	var (
+		sess    *mockquic.MockEarlySession
+		manager *webTransportManager
+	)
+
+	BeforeEach(func() {
+		sess = mockquic.NewMockEarlySession(mockCtrl)
+		sess.EXPECT().Context().Return(context.Background()).AnyTimes()
+		manager = newWebTransportManager(sess, newDatagramManager(sess, utils.DefaultLogger), utils.DefaultLogger)
+	})
+
+	// newConnectStream creates a CONNECT stream. The peer closes the stream when the returned channel is closed.
+	newConnectStream := func(id protocol.StreamID) (*mockquic.MockStream, chan struct{}) {
+		str := mockquic.NewMockStream(mockCtrl)
+		str.EXPECT().StreamID().Return(id).AnyTimes()
+		closed := make(chan struct{})
+		str.EXPECT().Read(gomock.Any()).DoAndReturn(func([]byte) (int, error) {
+			<-closed
+			return 0, io.EOF
+		}).AnyTimes()
+		str.EXPECT().CancelRead(quic.ErrorCode(errorNoError)).AnyTimes()
+		str.EXPECT().Close().AnyTimes()
+		return str, closed
+	}
+
+	It("accepts streams", func() {
+		connStr, closed := newConnectStream(4)
+		defer close(closed)
+		s := manager.newSession(connStr, nil)
+		str := mockquic.NewMockStream(mockCtrl)
+		manager.handleStream(4, str)
+		Expect(s.AcceptStream(context.Background())).To(Equal(str))
+		uniStr := mockquic.NewMockStream(mockCtrl)
+		manager.handleUniStream(4, uniStr)
+		Expect(s.AcceptUniStream(context.Background())).To(Equal(uniStr))
+	})
+
+	It("buffers streams that arrive before the session is established", func() {
+		str := mockquic.NewMockStream(mockCtrl)
+		manager.handleStream(8, str)
+		uniStr := mockquic.NewMockStream(mockCtrl)
+		manager.handleUniStream(8, uniStr)
+		connStr, closed := newConnectStream(8)
+		defer close(closed)
+		s := manager.newSession(connStr, nil)
+		Expect(s.AcceptStream(context.Background())).To(Equal(str))
+		Expect(s.AcceptUniStream(context.Background())).To(Equal(uniStr))
+		Expect(manager.buffered).To(BeEmpty())
+	})
+
+	It("rejects streams if too many streams are buffered", func() {
+		for i := 0; i < maxBufferedWebTransportStreams; i++ {
+			manager.handleStream(8, mockquic.NewMockStream(mockCtrl))
+		}
+		str := mockquic.NewMockStream(mockCtrl)
+		str.EXPECT().CancelRead(quic.ErrorCode(errorWebTransportBufferedStreamRejected))
+		str.EXPECT().CancelWrite(quic.ErrorCode(errorWebTransportBufferedStreamRejected))
+		manager.handleStream(12, str)
+	})
+
+	It("closes the connection if the session ID is not a client-initiated bidirectional stream", func() {
+		sess.EXPECT().CloseWithError(quic.ErrorCode(errorIDError), gomock.Any())
+		manager.handleStream(5, mockquic.NewMockStream(mockCtrl))
+	})
+
+	It("opens streams", func() {
+		connStr, closed := newConnectStream(4)
+		defer close(closed)
+		s := manager.newSession(connStr, nil)
+		buf := &bytes.Buffer{}
+		str := mockquic.NewMockStream(mockCtrl)
+		str.EXPECT().Write(gomock.Any()).DoAndReturn(buf.Write)
+		sess.EXPECT().OpenStreamSync(context.Background()).Return(str, nil)
+		Expect(s.OpenStreamSync(context.Background())).To(Equal(str))
+		Expect(buf.Bytes()).To(Equal([]byte{0x40, 0x41, 0x4}))
+
+		buf.Reset()
+		uniStr := mockquic.NewMockStream(mockCtrl)
+		uniStr.EXPECT().Write(gomock.Any()).DoAndReturn(buf.Write)
+		sess.EXPECT().OpenUniStream().Return(uniStr, nil)
+		Expect(s.OpenUniStream()).To(Equal(uniStr))
+		Expect(quicvarint.Read(buf)).To(BeEquivalentTo(streamTypeWebTransportStream))
+		Expect(quicvarint.Read(buf)).To(BeEquivalentTo(4))
+	})
+
+	It("sends and receives datagrams", func() {
+		connStr, closed := newConnectStream(8)
+		defer close(closed)
+		s := manager.newSession(connStr, nil)
+		manager.datagrams.negotiated.Set(true)
+		sess.EXPECT().SendMessage([]byte{2, 'f', 'o', 'o'})
+		Expect(s.SendMessage([]byte("foo"))).To(Succeed())
+		manager.datagrams.deliver(2, []byte("bar"))
+		Expect(s.ReceiveMessage(context.Background())).To(Equal([]byte("bar")))
+	})
+
+	It("closes the session when the peer closes the CONNECT stream", func() {
+		connStr, closed := newConnectStream(4)
+		onCloseCalled := make(chan struct{})
+		s := manager.newSession(connStr, func() { close(onCloseCalled) })
+		close(closed)
+		Eventually(onCloseCalled).Should(BeClosed())
+		Expect(s.Context().Done()).To(BeClosed())
+		_, err := s.AcceptStream(context.Background())
+		Expect(err).To(MatchError(ErrWebTransportSessionClosed))
+		_, err = s.ReceiveMessage(context.Background())
+		Expect(err).To(MatchError(ErrWebTransportSessionClosed))
+		_, err = s.OpenStream()
+		Expect(err).To(MatchError(ErrWebTransportSessionClosed))
+		// streams for the closed session are rejected
+		str := mockquic.NewMockStream(mockCtrl)
+		str.EXPECT().CancelRead(quic.ErrorCode(errorWebTransportBufferedStreamRejected))
+		str.EXPECT().CancelWrite(quic.ErrorCode(errorWebTransportBufferedStreamRejected))
+		manager.handleStream(4, str)
+	})
+
+	It("only remembers a limited number of closed sessions", func() {
+		for i := 0; i < maxClosedWebTransportSessions+10; i++ {
+			manager.removeSession(protocol.StreamID(4 * i))
+		}
+		Expect(manager.closed).To(HaveLen(maxClosedWebTransportSessions))
+		Expect(manager.closed).ToNot(HaveKey(protocol.StreamID(4 * 9)))
+		Expect(manager.closed).To(HaveKey(protocol.StreamID(4 * 10)))
+		// streams for a session that was forgotten are buffered
+		manager.handleStream(0, mockquic.NewMockStream(mockCtrl))
+		Expect(manager.buffered).To(HaveLen(1))
+	})
+})
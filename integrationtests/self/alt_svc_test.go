@@ -0,0 +1,138 @@
+package self_test
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"time"
+
+	quic "github.com/For-ACGN/quic-go"
+	"github.com/For-ACGN/quic-go/http3"
+	"github.com/For-ACGN/quic-go/internal/protocol"
+	"github.com/For-ACGN/quic-go/internal/testdata"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Alt-Svc", func() {
+	var (
+		server       *http3.Server
+		tcpServer    *http.Server
+		tcpPort      int
+		stoppedTCP   chan struct{}
+		stoppedQUIC  chan struct{}
+		rt           *http3.AltSvcRoundTripper
+		client       *http.Client
+		quicBlocked  bool
+		blockedConn  *net.UDPConn
+		advertisedTo int
+	)
+
+	BeforeEach(func() {
+		quicBlocked = false
+	})
+
+	JustBeforeEach(func() {
+		mux := http.NewServeMux()
+		mux.HandleFunc("/proto", func(w http.ResponseWriter, r *http.Request) {
+			defer GinkgoRecover()
+			Expect(server.SetQuicHeaders(w.Header())).To(Succeed())
+			w.Write([]byte(r.Proto))
+		})
+
+		conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 0})
+		Expect(err).ToNot(HaveOccurred())
+		advertisedTo = conn.LocalAddr().(*net.UDPAddr).Port
+		if quicBlocked {
+			// advertise a UDP socket that drops all packets
+			blockedConn, err = net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 0})
+			Expect(err).ToNot(HaveOccurred())
+			advertisedTo = blockedConn.LocalAddr().(*net.UDPAddr).Port
+		}
+		server = &http3.Server{
+			Server: &http.Server{
+				Addr:      fmt.Sprintf("localhost:%d", advertisedTo),
+				Handler:   mux,
+				TLSConfig: testdata.GetTLSConfig(),
+			},
+			QuicConfig: getQuicConfig(&quic.Config{Versions: []protocol.VersionNumber{protocol.Version1}}),
+		}
+		stoppedQUIC = make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			server.Serve(conn)
+			close(stoppedQUIC)
+		}()
+
+		ln, err := tls.Listen("tcp", "127.0.0.1:0", testdata.GetTLSConfig())
+		Expect(err).ToNot(HaveOccurred())
+		tcpPort = ln.Addr().(*net.TCPAddr).Port
+		tcpServer = &http.Server{Handler: mux}
+		stoppedTCP = make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			tcpServer.Serve(ln)
+			close(stoppedTCP)
+		}()
+
+		rt = &http3.AltSvcRoundTripper{
+			TCP: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: testdata.GetRootCA()}},
+			HTTP3: &http3.RoundTripper{
+				TLSClientConfig: &tls.Config{RootCAs: testdata.GetRootCA()},
+				QuicConfig: getQuicConfig(&quic.Config{
+					Versions:             []protocol.VersionNumber{protocol.Version1},
+					HandshakeIdleTimeout: scaleDuration(200 * time.Millisecond),
+				}),
+			},
+			RaceTimeout: scaleDuration(50 * time.Millisecond),
+		}
+		client = &http.Client{Transport: rt}
+	})
+
+	AfterEach(func() {
+		Expect(rt.Close()).To(Succeed())
+		Expect(server.Close()).To(Succeed())
+		Eventually(stoppedQUIC).Should(BeClosed())
+		Expect(tcpServer.Close()).To(Succeed())
+		Eventually(stoppedTCP).Should(BeClosed())
+		if blockedConn != nil {
+			blockedConn.Close()
+			blockedConn = nil
+		}
+	})
+
+	getProto := func() string {
+		rsp, err := client.Get(fmt.Sprintf("https://localhost:%d/proto", tcpPort))
+		ExpectWithOffset(1, err).ToNot(HaveOccurred())
+		ExpectWithOffset(1, rsp.StatusCode).To(Equal(200))
+		ExpectWithOffset(1, rsp.Header.Get("Alt-Svc")).To(Equal(fmt.Sprintf(`h3=":%d"; ma=2592000`, advertisedTo)))
+		body, err := ioutil.ReadAll(rsp.Body)
+		ExpectWithOffset(1, err).ToNot(HaveOccurred())
+		ExpectWithOffset(1, rsp.Body.Close()).To(Succeed())
+		return string(body)
+	}
+
+	It("switches to HTTP/3 once it was advertised", func() {
+		Expect(getProto()).To(Equal("HTTP/1.1"))
+		Eventually(getProto).Should(Equal("HTTP/3"))
+		Expect(getProto()).To(Equal("HTTP/3"))
+	})
+
+	Context("if QUIC is blocked", func() {
+		BeforeEach(func() {
+			quicBlocked = true
+		})
+
+		It("falls back to TCP", func() {
+			for i := 0; i < 5; i++ {
+				Expect(getProto()).To(Equal("HTTP/1.1"))
+			}
+			// wait until the handshake times out
+			time.Sleep(scaleDuration(300 * time.Millisecond))
+			Consistently(getProto, scaleDuration(100*time.Millisecond)).Should(Equal("HTTP/1.1"))
+		})
+	})
+})
@@ -129,6 +129,36 @@ var _ = Describe("Handshake tests", func() {
 				Expect(sess.(versioner).GetVersion()).To(Equal(protocol.SupportedVersions[0]))
 				Expect(sess.CloseWithError(0, "")).To(Succeed())
 			})
+
+			It("performs compatible version negotiation", func() {
+				serverConfig.Versions = []protocol.VersionNumber{protocol.Version2, protocol.Version1}
+				ln, err := quic.ListenAddr("localhost:0", getTLSConfig(), serverConfig)
+				Expect(err).ToNot(HaveOccurred())
+				defer ln.Close()
+				sess, err := quic.DialAddr(
+					fmt.Sprintf("localhost:%d", ln.Addr().(*net.UDPAddr).Port),
+					getTLSClientConfig(),
+					getQuicConfig(&quic.Config{
+						Versions: []protocol.VersionNumber{protocol.Version1, protocol.Version2},
+					}),
+				)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(sess.(versioner).GetVersion()).To(Equal(protocol.Version2))
+				str, err := sess.OpenUniStream()
+				Expect(err).ToNot(HaveOccurred())
+				_, err = str.Write(PRData)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(str.Close()).To(Succeed())
+				serverSess, err := ln.Accept(context.Background())
+				Expect(err).ToNot(HaveOccurred())
+				Expect(serverSess.(versioner).GetVersion()).To(Equal(protocol.Version2))
+				rstr, err := serverSess.AcceptUniStream(context.Background())
+				Expect(err).ToNot(HaveOccurred())
+				data, err := ioutil.ReadAll(rstr)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(data).To(Equal(PRData))
+				Expect(sess.CloseWithError(0, "")).To(Succeed())
+			})
 		})
 	}
 
@@ -12,6 +12,7 @@ import (
 	"net"
 	"net/http"
 	"strconv"
+	"sync"
 	"time"
 
 	quic "github.com/For-ACGN/quic-go"
@@ -29,6 +30,23 @@ type streamCancelError interface {
 	ErrorCode() protocol.ApplicationErrorCode
 }
 
+type pushHandler struct {
+	accept   bool
+	promised chan *http.Request
+	pushed   chan *http.Response
+}
+
+var _ http3.PushHandler = &pushHandler{}
+
+func (h *pushHandler) HandlePromise(req *http.Request) bool {
+	h.promised <- req
+	return h.accept
+}
+
+func (h *pushHandler) HandlePush(_ *http.Request, rsp *http.Response) {
+	h.pushed <- rsp
+}
+
 var _ = Describe("HTTP tests", func() {
 	var (
 		mux            *http.ServeMux
@@ -319,6 +337,654 @@ var _ = Describe("HTTP tests", func() {
 				Expect(req.Body.Close()).To(Succeed())
 				Eventually(done).Should(BeClosed())
 			})
+
+			It("completes running requests when closing gracefully", func() {
+				handlerCalled := make(chan struct{})
+				unblock := make(chan struct{})
+				mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+					defer GinkgoRecover()
+					close(handlerCalled)
+					<-unblock
+					io.WriteString(w, "finally done")
+				})
+
+				type result struct {
+					body []byte
+					err  error
+				}
+				resultChan := make(chan result, 1)
+				go func() {
+					defer GinkgoRecover()
+					resp, err := client.Get("https://localhost:" + port + "/slow")
+					if err != nil {
+						resultChan <- result{err: err}
+						return
+					}
+					body, err := ioutil.ReadAll(resp.Body)
+					resultChan <- result{body: body, err: err}
+				}()
+				Eventually(handlerCalled).Should(BeClosed())
+
+				closed := make(chan error, 1)
+				go func() { closed <- server.CloseGracefully(10 * time.Second) }()
+				Consistently(closed, scaleDuration(50*time.Millisecond)).ShouldNot(Receive())
+				close(unblock)
+				var res result
+				Eventually(resultChan).Should(Receive(&res))
+				Expect(res.err).ToNot(HaveOccurred())
+				Expect(string(res.body)).To(Equal("finally done"))
+				// the connection is closed once the request completed
+				Eventually(closed).Should(Receive(BeNil()))
+				Eventually(stoppedServing).Should(BeClosed())
+			})
+
+			It("sends and receives trailers", func() {
+				mux.HandleFunc("/trailers", func(w http.ResponseWriter, r *http.Request) {
+					defer GinkgoRecover()
+					Expect(r.Trailer).To(HaveKey("Checksum"))
+					body, err := ioutil.ReadAll(r.Body)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(r.Trailer.Get("Checksum")).To(Equal(strconv.Itoa(len(body))))
+					w.Header().Set("Trailer", "Grpc-Status")
+					w.Write(body)
+					w.Header().Set("Grpc-Status", "0")
+					w.Header().Set(http.TrailerPrefix+"Grpc-Message", "ok")
+				})
+
+				req, err := http.NewRequest(http.MethodPost, "https://localhost:"+port+"/trailers", bytes.NewReader(PRData))
+				Expect(err).ToNot(HaveOccurred())
+				req.Trailer = http.Header{"Checksum": []string{strconv.Itoa(len(PRData))}}
+				resp, err := client.Do(req)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(resp.StatusCode).To(Equal(200))
+				Expect(resp.Trailer).To(HaveKey("Grpc-Status"))
+				body, err := ioutil.ReadAll(gbytes.TimeoutReader(resp.Body, 5*time.Second))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(body).To(Equal(PRData))
+				Expect(resp.Trailer).To(Equal(http.Header{
+					"Grpc-Status":  []string{"0"},
+					"Grpc-Message": []string{"ok"},
+				}))
+			})
+
+			Context("using the QPACK dynamic table", func() {
+				BeforeEach(func() {
+					// No connection was established yet, so the server uses these values.
+					server.QPACKMaxTableCapacity = 4096
+					server.QPACKBlockedStreams = 10
+					rt := client.Transport.(*http3.RoundTripper)
+					rt.QPACKMaxTableCapacity = 4096
+					rt.QPACKBlockedStreams = 10
+					mux.HandleFunc("/headers", func(w http.ResponseWriter, r *http.Request) {
+						defer GinkgoRecover()
+						Expect(r.Header.Get("X-Request-Id")).ToNot(BeEmpty())
+						Expect(r.Header.Get("User-Agent")).To(Equal("quic-go integration test"))
+						w.Header().Set("X-Request-Id", r.Header.Get("X-Request-Id"))
+						w.Header().Set("X-Server", "quic-go")
+						io.WriteString(w, "foobar")
+					})
+				})
+
+				It("sends and receives headers", func() {
+					const num = 50
+					errChan := make(chan error, num)
+					for i := 0; i < num; i++ {
+						go func(i int) {
+							defer GinkgoRecover()
+							req, err := http.NewRequest(http.MethodGet, "https://localhost:"+port+"/headers", nil)
+							Expect(err).ToNot(HaveOccurred())
+							req.Header.Set("X-Request-Id", strconv.Itoa(i%5))
+							req.Header.Set("User-Agent", "quic-go integration test")
+							resp, err := client.Do(req)
+							if err != nil {
+								errChan <- err
+								return
+							}
+							defer resp.Body.Close()
+							Expect(resp.StatusCode).To(Equal(200))
+							Expect(resp.Header.Get("X-Request-Id")).To(Equal(strconv.Itoa(i % 5)))
+							Expect(resp.Header.Get("X-Server")).To(Equal("quic-go"))
+							body, err := ioutil.ReadAll(gbytes.TimeoutReader(resp.Body, 5*time.Second))
+							Expect(err).ToNot(HaveOccurred())
+							Expect(string(body)).To(Equal("foobar"))
+							errChan <- nil
+						}(i)
+					}
+					for i := 0; i < num; i++ {
+						var err error
+						Eventually(errChan, 5*time.Second).Should(Receive(&err))
+						Expect(err).ToNot(HaveOccurred())
+					}
+				})
+			})
+
+			Context("prioritization", func() {
+				const numRequests = 4
+				var start chan struct{}
+
+				BeforeEach(func() {
+					start = make(chan struct{})
+					var mutex sync.Mutex
+					var numArrived int
+					allArrived := make(chan struct{})
+					mux.HandleFunc("/prioritized", func(w http.ResponseWriter, r *http.Request) {
+						defer GinkgoRecover()
+						mutex.Lock()
+						numArrived++
+						if numArrived == numRequests {
+							close(allArrived)
+						}
+						mutex.Unlock()
+						w.WriteHeader(200)
+						w.(http.Flusher).Flush()
+						// block until all requests arrived, so that all responses are sent at the same time
+						<-allArrived
+						<-start
+						w.Write(PRData)
+					})
+				})
+
+				// sendRequests sends the requests with the given priorities, and returns the responses.
+				sendRequests := func(priorities []http3.Priority) []*http.Response {
+					rsps := make([]*http.Response, len(priorities))
+					var wg sync.WaitGroup
+					for i, p := range priorities {
+						req, err := http.NewRequest(http.MethodGet, "https://localhost:"+port+"/prioritized", nil)
+						ExpectWithOffset(1, err).ToNot(HaveOccurred())
+						req.Header.Set(http3.PriorityHeader, p.String())
+						wg.Add(1)
+						go func(i int) {
+							defer GinkgoRecover()
+							defer wg.Done()
+							rsp, err := client.Do(req)
+							Expect(err).ToNot(HaveOccurred())
+							Expect(rsp.StatusCode).To(Equal(200))
+							rsps[i] = rsp
+						}(i)
+					}
+					wg.Wait()
+					return rsps
+				}
+
+				// firstCompleted reads all response bodies, and returns the index of the response that completed first.
+				firstCompleted := func(rsps []*http.Response) int {
+					completed := make(chan int, len(rsps))
+					for i, rsp := range rsps {
+						go func(i int, rsp *http.Response) {
+							defer GinkgoRecover()
+							data, err := ioutil.ReadAll(rsp.Body)
+							Expect(err).ToNot(HaveOccurred())
+							Expect(data).To(Equal(PRData))
+							completed <- i
+						}(i, rsp)
+					}
+					var first int
+					Eventually(completed, 5*time.Second).Should(Receive(&first))
+					for i := 1; i < len(rsps); i++ {
+						Eventually(completed, 5*time.Second).Should(Receive())
+					}
+					return first
+				}
+
+				It("sends more urgent responses first", func() {
+					rsps := sendRequests([]http3.Priority{{Urgency: 6}, {Urgency: 6, Incremental: true}, {Urgency: 5}, {Urgency: 1}})
+					close(start)
+					Expect(firstCompleted(rsps)).To(Equal(3))
+				})
+
+				It("changes the priority of in-flight requests", func() {
+					rsps := sendRequests([]http3.Priority{{Urgency: 7}, {Urgency: 4}, {Urgency: 4}, {Urgency: 5}})
+					Expect(rsps[0].Body.(http3.PriorityUpdater).UpdatePriority(http3.Priority{Urgency: 0})).To(Succeed())
+					// give the PRIORITY_UPDATE frame some time to arrive
+					time.Sleep(scaleDuration(50 * time.Millisecond))
+					close(start)
+					Expect(firstCompleted(rsps)).To(BeZero())
+				})
+			})
+
+			Context("connection hooks", func() {
+				type ctxKey int
+
+				var (
+					hookServer         *http3.Server
+					hookPort           string
+					hookStoppedServing chan struct{}
+					statesMutex        sync.Mutex
+					states             []http.ConnState
+				)
+
+				getStates := func() []http.ConnState {
+					statesMutex.Lock()
+					defer statesMutex.Unlock()
+					return append([]http.ConnState{}, states...)
+				}
+
+				BeforeEach(func() {
+					states = nil
+					hookMux := http.NewServeMux()
+					hookMux.HandleFunc("/session", func(w http.ResponseWriter, r *http.Request) {
+						defer GinkgoRecover()
+						Expect(r.Context().Value(ctxKey(1))).To(Equal("foobar"))
+						sess, ok := r.Context().Value(http3.SessionContextKey).(quic.Session)
+						Expect(ok).To(BeTrue())
+						Expect(sess.RemoteAddr().String()).To(Equal(r.RemoteAddr))
+						io.WriteString(w, sess.ConnectionState().TLS.ServerName)
+					})
+					// The hooks need to be set before the server starts serving,
+					// so we can't use the server started for the other tests.
+					hookServer = &http3.Server{
+						Server: &http.Server{
+							Handler:   hookMux,
+							TLSConfig: testdata.GetTLSConfig(),
+						},
+						QuicConfig: getQuicConfig(&quic.Config{Versions: versions}),
+						ConnContext: func(ctx context.Context, _ quic.Session) context.Context {
+							return context.WithValue(ctx, ctxKey(1), "foobar")
+						},
+						ConnState: func(_ quic.Session, state http.ConnState) {
+							statesMutex.Lock()
+							states = append(states, state)
+							statesMutex.Unlock()
+						},
+					}
+					conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 0})
+					Expect(err).ToNot(HaveOccurred())
+					hookPort = strconv.Itoa(conn.LocalAddr().(*net.UDPAddr).Port)
+					hookStoppedServing = make(chan struct{})
+					go func() {
+						defer GinkgoRecover()
+						hookServer.Serve(conn)
+						close(hookStoppedServing)
+					}()
+				})
+
+				AfterEach(func() {
+					Expect(hookServer.Close()).To(Succeed())
+					Eventually(hookStoppedServing).Should(BeClosed())
+				})
+
+				It("passes the connection context to handlers, and reports connection state changes", func() {
+					for i := 0; i < 2; i++ {
+						resp, err := client.Get("https://localhost:" + hookPort + "/session")
+						Expect(err).ToNot(HaveOccurred())
+						Expect(resp.StatusCode).To(Equal(200))
+						body, err := ioutil.ReadAll(gbytes.TimeoutReader(resp.Body, 3*time.Second))
+						Expect(err).ToNot(HaveOccurred())
+						Expect(string(body)).To(Equal("localhost"))
+						Eventually(getStates).Should(HaveLen(1 + 2*(i+1)))
+					}
+					Expect(getStates()).To(Equal([]http.ConnState{
+						http.StateNew,
+						http.StateActive,
+						http.StateIdle,
+						http.StateActive,
+						http.StateIdle,
+					}))
+					Expect(client.Transport.(*http3.RoundTripper).Close()).To(Succeed())
+					Eventually(getStates).Should(HaveLen(6))
+					Expect(getStates()[5]).To(Equal(http.StateClosed))
+				})
+			})
+
+			It("opens multiple connections when the stream limit is reached", func() {
+				const numRequests = 6
+				var mutex sync.Mutex
+				var numArrived int
+				remoteAddrs := make(map[string]struct{})
+				allArrived := make(chan struct{})
+				poolMux := http.NewServeMux()
+				poolMux.HandleFunc("/wait", func(w http.ResponseWriter, r *http.Request) {
+					mutex.Lock()
+					remoteAddrs[r.RemoteAddr] = struct{}{}
+					numArrived++
+					if numArrived == numRequests {
+						close(allArrived)
+					}
+					mutex.Unlock()
+					// block until all requests arrived, so every request needs its own stream
+					<-allArrived
+				})
+				poolServer := &http3.Server{
+					Server: &http.Server{
+						Handler:   poolMux,
+						TLSConfig: testdata.GetTLSConfig(),
+					},
+					QuicConfig: getQuicConfig(&quic.Config{
+						Versions:           []protocol.VersionNumber{version},
+						MaxIncomingStreams: 2,
+					}),
+				}
+				conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 0})
+				Expect(err).ToNot(HaveOccurred())
+				go poolServer.Serve(conn)
+				defer poolServer.Close()
+
+				rt := &http3.RoundTripper{
+					TLSClientConfig: &tls.Config{RootCAs: testdata.GetRootCA()},
+					QuicConfig:      getQuicConfig(&quic.Config{Versions: []protocol.VersionNumber{version}}),
+					MaxConnsPerHost: numRequests / 2,
+				}
+				defer rt.Close()
+				poolClient := &http.Client{Transport: rt}
+				url := fmt.Sprintf("https://localhost:%d/wait", conn.LocalAddr().(*net.UDPAddr).Port)
+				errChan := make(chan error, numRequests)
+				for i := 0; i < numRequests; i++ {
+					go func() {
+						rsp, err := poolClient.Get(url)
+						if err == nil {
+							rsp.Body.Close()
+							if rsp.StatusCode != 200 {
+								err = fmt.Errorf("unexpected status code: %d", rsp.StatusCode)
+							}
+						}
+						errChan <- err
+					}()
+				}
+				for i := 0; i < numRequests; i++ {
+					Eventually(errChan, 5*time.Second).Should(Receive(BeNil()))
+				}
+				mutex.Lock()
+				defer mutex.Unlock()
+				Expect(remoteAddrs).To(HaveLen(numRequests / 2))
+			})
+
+			Context("HTTP datagrams and capsules", func() {
+				var (
+					dgServer         *http3.Server
+					dgPort           string
+					dgStoppedServing chan struct{}
+				)
+
+				BeforeEach(func() {
+					dgMux := http.NewServeMux()
+					dgMux.HandleFunc("/echo", func(w http.ResponseWriter, r *http.Request) {
+						defer GinkgoRecover()
+						d := w.(http3.Datagrammer)
+						w.Header().Set(http3.CapsuleProtocolHeader, "?1")
+						w.WriteHeader(200)
+						w.(http.Flusher).Flush()
+						// echo datagrams until the handler returns
+						go func() {
+							for {
+								data, err := d.ReceiveDatagram(context.Background())
+								if err != nil {
+									return
+								}
+								d.SendDatagram(data)
+							}
+						}()
+						// echo capsules until the client closes the request stream
+						for {
+							ct, cr, err := http3.ParseCapsule(r.Body)
+							if err == io.EOF {
+								return
+							}
+							Expect(err).ToNot(HaveOccurred())
+							val, err := ioutil.ReadAll(cr)
+							Expect(err).ToNot(HaveOccurred())
+							Expect(http3.WriteCapsule(w, ct, val)).To(Succeed())
+							w.(http.Flusher).Flush()
+						}
+					})
+					// The QUIC datagram extension needs to be enabled before the server starts listening,
+					// so we can't use the server started for the other tests.
+					dgServer = &http3.Server{
+						Server: &http.Server{
+							Handler:   dgMux,
+							TLSConfig: testdata.GetTLSConfig(),
+						},
+						QuicConfig:      getQuicConfig(&quic.Config{Versions: versions}),
+						EnableDatagrams: true,
+					}
+					conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 0})
+					Expect(err).ToNot(HaveOccurred())
+					dgPort = strconv.Itoa(conn.LocalAddr().(*net.UDPAddr).Port)
+					dgStoppedServing = make(chan struct{})
+					go func() {
+						defer GinkgoRecover()
+						dgServer.Serve(conn)
+						close(dgStoppedServing)
+					}()
+					client.Transport.(*http3.RoundTripper).EnableDatagrams = true
+				})
+
+				AfterEach(func() {
+					Expect(dgServer.Close()).To(Succeed())
+					Eventually(dgStoppedServing).Should(BeClosed())
+				})
+
+				It("sends and receives datagrams and capsules", func() {
+					pr, pw := io.Pipe()
+					req, err := http.NewRequest(http.MethodPost, "https://localhost:"+dgPort+"/echo", pr)
+					Expect(err).ToNot(HaveOccurred())
+					rsp, err := client.Transport.RoundTrip(req)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(rsp.StatusCode).To(Equal(200))
+					Expect(rsp.Header.Get(http3.CapsuleProtocolHeader)).To(Equal("?1"))
+
+					d, ok := rsp.Body.(http3.Datagrammer)
+					Expect(ok).To(BeTrue())
+					// datagrams are unreliable, so we might need to send them multiple times
+					Eventually(func() []byte {
+						d.SendDatagram([]byte("foobar"))
+						ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+						defer cancel()
+						data, _ := d.ReceiveDatagram(ctx)
+						return data
+					}).Should(Equal([]byte("foobar")))
+
+					Expect(http3.WriteCapsule(pw, 0x1337, []byte("lorem ipsum"))).To(Succeed())
+					ct, cr, err := http3.ParseCapsule(rsp.Body)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(ct).To(BeEquivalentTo(0x1337))
+					Expect(ioutil.ReadAll(cr)).To(Equal([]byte("lorem ipsum")))
+
+					Expect(pw.Close()).To(Succeed())
+					_, _, err = http3.ParseCapsule(gbytes.TimeoutReader(rsp.Body, 5*time.Second))
+					Expect(err).To(MatchError(io.EOF))
+					Expect(rsp.Body.Close()).To(Succeed())
+				})
+			})
+
+			Context("WebTransport", func() {
+				var (
+					wtServer         *http3.Server
+					wtPort           string
+					wtStoppedServing chan struct{}
+					sessChan         chan *http3.WebTransportSession
+				)
+
+				BeforeEach(func() {
+					sessChan = make(chan *http3.WebTransportSession, 1)
+					wtMux := http.NewServeMux()
+					wtMux.HandleFunc("/webtransport", func(w http.ResponseWriter, r *http.Request) {
+						defer GinkgoRecover()
+						Expect(r.Header.Get("Sec-Webtransport-Http3-Draft02")).To(Equal("1"))
+						sess, err := http3.UpgradeWebTransport(w, r)
+						Expect(err).ToNot(HaveOccurred())
+						sessChan <- sess
+					})
+					// The QUIC datagram extension needs to be enabled before the server starts listening,
+					// so we can't use the server started for the other tests.
+					wtServer = &http3.Server{
+						Server: &http.Server{
+							Handler:   wtMux,
+							TLSConfig: testdata.GetTLSConfig(),
+						},
+						QuicConfig:         getQuicConfig(&quic.Config{Versions: versions}),
+						EnableWebTransport: true,
+					}
+					conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 0})
+					Expect(err).ToNot(HaveOccurred())
+					wtPort = strconv.Itoa(conn.LocalAddr().(*net.UDPAddr).Port)
+					wtStoppedServing = make(chan struct{})
+					go func() {
+						defer GinkgoRecover()
+						wtServer.Serve(conn)
+						close(wtStoppedServing)
+					}()
+					client.Transport.(*http3.RoundTripper).EnableWebTransport = true
+				})
+
+				AfterEach(func() {
+					Expect(wtServer.Close()).To(Succeed())
+					Eventually(wtStoppedServing).Should(BeClosed())
+				})
+
+				It("establishes a session, and exchanges streams and datagrams", func() {
+					ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+					defer cancel()
+					rsp, sess, err := client.Transport.(*http3.RoundTripper).DialWebTransport(ctx, "https://localhost:"+wtPort+"/webtransport", nil)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(rsp.StatusCode).To(Equal(200))
+					var serverSess *http3.WebTransportSession
+					Eventually(sessChan).Should(Receive(&serverSess))
+
+					// a bidirectional stream opened by the client, echoed by the server
+					go func() {
+						defer GinkgoRecover()
+						str, err := serverSess.AcceptStream(ctx)
+						Expect(err).ToNot(HaveOccurred())
+						_, err = io.Copy(str, str)
+						Expect(err).ToNot(HaveOccurred())
+						Expect(str.Close()).To(Succeed())
+					}()
+					str, err := sess.OpenStreamSync(ctx)
+					Expect(err).ToNot(HaveOccurred())
+					_, err = str.Write([]byte("foobar"))
+					Expect(err).ToNot(HaveOccurred())
+					Expect(str.Close()).To(Succeed())
+					data, err := ioutil.ReadAll(gbytes.TimeoutReader(str, 5*time.Second))
+					Expect(err).ToNot(HaveOccurred())
+					Expect(string(data)).To(Equal("foobar"))
+
+					// streams opened by the server
+					uniStr, err := serverSess.OpenUniStreamSync(ctx)
+					Expect(err).ToNot(HaveOccurred())
+					_, err = uniStr.Write([]byte("lorem ipsum"))
+					Expect(err).ToNot(HaveOccurred())
+					Expect(uniStr.Close()).To(Succeed())
+					rstr, err := sess.AcceptUniStream(ctx)
+					Expect(err).ToNot(HaveOccurred())
+					data, err = ioutil.ReadAll(gbytes.TimeoutReader(rstr, 5*time.Second))
+					Expect(err).ToNot(HaveOccurred())
+					Expect(string(data)).To(Equal("lorem ipsum"))
+
+					bidiStr, err := serverSess.OpenStreamSync(ctx)
+					Expect(err).ToNot(HaveOccurred())
+					_, err = bidiStr.Write([]byte("dolor sit amet"))
+					Expect(err).ToNot(HaveOccurred())
+					Expect(bidiStr.Close()).To(Succeed())
+					cstr, err := sess.AcceptStream(ctx)
+					Expect(err).ToNot(HaveOccurred())
+					data, err = ioutil.ReadAll(gbytes.TimeoutReader(cstr, 5*time.Second))
+					Expect(err).ToNot(HaveOccurred())
+					Expect(string(data)).To(Equal("dolor sit amet"))
+
+					// datagrams are unreliable, so we might need to send them multiple times
+					receiveDatagram := func(sender, receiver *http3.WebTransportSession, msg []byte) func() []byte {
+						return func() []byte {
+							Expect(sender.SendMessage(msg)).To(Succeed())
+							ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+							defer cancel()
+							data, _ := receiver.ReceiveMessage(ctx)
+							return data
+						}
+					}
+					Eventually(receiveDatagram(sess, serverSess, []byte("ping"))).Should(Equal([]byte("ping")))
+					Eventually(receiveDatagram(serverSess, sess, []byte("pong"))).Should(Equal([]byte("pong")))
+
+					Expect(sess.Close()).To(Succeed())
+					Eventually(serverSess.Context().Done()).Should(BeClosed())
+				})
+
+				It("returns the response if the server rejects the session", func() {
+					_, _, err := client.Transport.(*http3.RoundTripper).DialWebTransport(context.Background(), "https://localhost:"+wtPort+"/not-found", nil)
+					Expect(err).To(MatchError("http3: WebTransport request failed: 404 Not Found"))
+				})
+			})
+
+			Context("server push", func() {
+				var handler *pushHandler
+
+				BeforeEach(func() {
+					handler = &pushHandler{
+						accept:   true,
+						promised: make(chan *http.Request, 10),
+						pushed:   make(chan *http.Response, 10),
+					}
+					client.Transport.(*http3.RoundTripper).PushHandler = handler
+					mux.HandleFunc("/push", func(w http.ResponseWriter, r *http.Request) {
+						defer GinkgoRecover()
+						pusher, ok := w.(http.Pusher)
+						Expect(ok).To(BeTrue())
+						Expect(pusher.Push("/hello", nil)).To(Succeed())
+						Expect(pusher.Push("/prdata", &http.PushOptions{Header: http.Header{"Foo": []string{"bar"}}})).To(Succeed())
+						io.WriteString(w, "pushed")
+					})
+				})
+
+				// The server can only push once it received the client's MAX_PUSH_ID frame.
+				// Send a request first to make sure that the control stream was processed.
+				establishConnection := func() {
+					resp, err := client.Get("https://localhost:" + port + "/hello")
+					Expect(err).ToNot(HaveOccurred())
+					Expect(resp.StatusCode).To(Equal(200))
+					resp.Body.Close()
+				}
+
+				It("receives pushed resources", func() {
+					establishConnection()
+					resp, err := client.Get("https://localhost:" + port + "/push")
+					Expect(err).ToNot(HaveOccurred())
+					body, err := ioutil.ReadAll(gbytes.TimeoutReader(resp.Body, 3*time.Second))
+					Expect(err).ToNot(HaveOccurred())
+					Expect(string(body)).To(Equal("pushed"))
+
+					pushed := make(map[string][]byte)
+					for i := 0; i < 2; i++ {
+						var rsp *http.Response
+						Eventually(handler.pushed).Should(Receive(&rsp))
+						Expect(rsp.StatusCode).To(Equal(200))
+						Expect(rsp.Request.Method).To(Equal(http.MethodGet))
+						if rsp.Request.URL.Path == "/prdata" {
+							Expect(rsp.Request.Header.Get("Foo")).To(Equal("bar"))
+						}
+						data, err := ioutil.ReadAll(gbytes.TimeoutReader(rsp.Body, 3*time.Second))
+						Expect(err).ToNot(HaveOccurred())
+						Expect(rsp.Body.Close()).To(Succeed())
+						pushed[rsp.Request.URL.Path] = data
+					}
+					Expect(pushed).To(HaveKeyWithValue("/hello", []byte("Hello, World!\n")))
+					Expect(pushed).To(HaveKeyWithValue("/prdata", PRData))
+					Expect(handler.promised).To(HaveLen(2))
+				})
+
+				It("cancels promised resources", func() {
+					handler.accept = false
+					establishConnection()
+					resp, err := client.Get("https://localhost:" + port + "/push")
+					Expect(err).ToNot(HaveOccurred())
+					body, err := ioutil.ReadAll(gbytes.TimeoutReader(resp.Body, 3*time.Second))
+					Expect(err).ToNot(HaveOccurred())
+					Expect(string(body)).To(Equal("pushed"))
+					Eventually(handler.promised).Should(HaveLen(2))
+					Consistently(handler.pushed, scaleDuration(50*time.Millisecond)).ShouldNot(Receive())
+				})
+
+				It("doesn't push if the client didn't enable server push", func() {
+					client.Transport.(*http3.RoundTripper).PushHandler = nil
+					pushErr := make(chan error, 1)
+					mux.HandleFunc("/nopush", func(w http.ResponseWriter, r *http.Request) {
+						pushErr <- w.(http.Pusher).Push("/hello", nil)
+					})
+					establishConnection()
+					resp, err := client.Get("https://localhost:" + port + "/nopush")
+					Expect(err).ToNot(HaveOccurred())
+					Expect(resp.StatusCode).To(Equal(200))
+					Eventually(pushErr).Should(Receive(Equal(http.ErrNotSupported)))
+				})
+			})
 		})
 	}
 })
@@ -62,6 +62,8 @@ var _ logging.ConnectionTracer = &connTracer{}
 
 func (t *connTracer) StartedConnection(local, remote net.Addr, version logging.VersionNumber, srcConnID, destConnID logging.ConnectionID) {
 }
+func (t *connTracer) NegotiatedVersion(logging.VersionNumber, []logging.VersionNumber, []logging.VersionNumber) {
+}
 func (t *connTracer) ClosedConnection(logging.CloseReason)                     {}
 func (t *connTracer) SentTransportParameters(*logging.TransportParameters)     {}
 func (t *connTracer) ReceivedTransportParameters(*logging.TransportParameters) {}
@@ -82,6 +84,7 @@ func (t *connTracer) LostPacket(logging.EncryptionLevel, logging.PacketNumber, l
 }
 func (t *connTracer) UpdatedCongestionState(logging.CongestionState)                     {}
 func (t *connTracer) UpdatedPTOCount(value uint32)                                       {}
+func (t *connTracer) ECNStateUpdated(logging.ECNState, logging.ECNStateTrigger)          {}
 func (t *connTracer) UpdatedKeyFromTLS(logging.EncryptionLevel, logging.Perspective)     {}
 func (t *connTracer) UpdatedKey(generation logging.KeyPhase, remote bool)                {}
 func (t *connTracer) DroppedEncryptionLevel(logging.EncryptionLevel)                     {}
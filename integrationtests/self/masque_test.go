@@ -0,0 +1,200 @@
+package self_test
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"time"
+
+	quic "github.com/For-ACGN/quic-go"
+	"github.com/For-ACGN/quic-go/http3"
+	"github.com/For-ACGN/quic-go/internal/protocol"
+	"github.com/For-ACGN/quic-go/internal/testdata"
+	"github.com/For-ACGN/quic-go/masque"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("CONNECT-UDP", func() {
+	const template = "https://localhost:%d/.well-known/masque/udp/{target_host}/{target_port}/"
+
+	for _, v := range protocol.SupportedVersions {
+		version := v
+
+		Context(fmt.Sprintf("with QUIC version %s", version), func() {
+			var (
+				server         *http3.Server
+				stoppedServing chan struct{}
+				client         *masque.Client
+				proxyPort      int
+			)
+
+			BeforeEach(func() {
+				conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 0})
+				Expect(err).ToNot(HaveOccurred())
+				proxyPort = conn.LocalAddr().(*net.UDPAddr).Port
+				tmpl, err := masque.ParseTemplate(fmt.Sprintf(template, proxyPort))
+				Expect(err).ToNot(HaveOccurred())
+				server = &http3.Server{
+					Server: &http.Server{
+						Handler:   &masque.Proxy{Template: tmpl},
+						TLSConfig: testdata.GetTLSConfig(),
+					},
+					QuicConfig: getQuicConfig(&quic.Config{
+						Versions:               []protocol.VersionNumber{version},
+						EnablePathMTUDiscovery: true,
+					}),
+					EnableExtendedConnect: true,
+					EnableDatagrams:       true,
+				}
+				stoppedServing = make(chan struct{})
+				go func() {
+					defer GinkgoRecover()
+					server.Serve(conn)
+					close(stoppedServing)
+				}()
+
+				client = &masque.Client{
+					Template: tmpl,
+					RoundTripper: &http3.RoundTripper{
+						TLSClientConfig: &tls.Config{RootCAs: testdata.GetRootCA()},
+						QuicConfig: getQuicConfig(&quic.Config{
+							Versions:               []protocol.VersionNumber{version},
+							MaxIdleTimeout:         10 * time.Second,
+							EnablePathMTUDiscovery: true,
+						}),
+						EnableDatagrams: true,
+					},
+				}
+			})
+
+			AfterEach(func() {
+				Expect(client.RoundTripper.Close()).To(Succeed())
+				Expect(server.Close()).To(Succeed())
+				Eventually(stoppedServing).Should(BeClosed())
+			})
+
+			It("proxies UDP payloads", func() {
+				// run a UDP echo server
+				echoConn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 0})
+				Expect(err).ToNot(HaveOccurred())
+				defer echoConn.Close()
+				go func() {
+					b := make([]byte, protocol.MaxReceivePacketSize)
+					for {
+						n, addr, err := echoConn.ReadFrom(b)
+						if err != nil {
+							return
+						}
+						echoConn.WriteTo(b[:n], addr)
+					}
+				}()
+
+				conn, err := client.Dial(context.Background(), echoConn.LocalAddr().String())
+				Expect(err).ToNot(HaveOccurred())
+				defer conn.Close()
+				// UDP payloads are unreliable, so we might need to send them multiple times
+				Eventually(func() []byte {
+					_, err := conn.WriteTo([]byte("foobar"), echoConn.LocalAddr())
+					Expect(err).ToNot(HaveOccurred())
+					b := make([]byte, 100)
+					Expect(conn.SetReadDeadline(time.Now().Add(scaleDuration(100 * time.Millisecond)))).To(Succeed())
+					n, _, err := conn.ReadFrom(b)
+					if err != nil {
+						return nil
+					}
+					return b[:n]
+				}).Should(Equal([]byte("foobar")))
+			})
+
+			It("runs a QUIC connection through the proxy", func() {
+				ln, err := quic.ListenAddr("localhost:0", getTLSConfig(), getQuicConfig(nil))
+				Expect(err).ToNot(HaveOccurred())
+				defer ln.Close()
+				go func() {
+					defer GinkgoRecover()
+					sess, err := ln.Accept(context.Background())
+					Expect(err).ToNot(HaveOccurred())
+					str, err := sess.AcceptStream(context.Background())
+					Expect(err).ToNot(HaveOccurred())
+					_, err = io.Copy(str, str)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(str.Close()).To(Succeed())
+				}()
+
+				conn, err := client.Dial(context.Background(), fmt.Sprintf("localhost:%d", ln.Addr().(*net.UDPAddr).Port))
+				Expect(err).ToNot(HaveOccurred())
+				defer conn.Close()
+				sess, err := quic.Dial(conn, ln.Addr(), "localhost", getTLSClientConfig(), getQuicConfig(nil))
+				Expect(err).ToNot(HaveOccurred())
+				defer sess.CloseWithError(0, "")
+				str, err := sess.OpenStream()
+				Expect(err).ToNot(HaveOccurred())
+				_, err = str.Write(PRData)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(str.Close()).To(Succeed())
+				data, err := ioutil.ReadAll(str)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(data).To(Equal(PRData))
+			})
+
+			It("closes the request stream when the tunnel is closed", func() {
+				// use a handler that reads the request stream instead of the proxy
+				streamErrChan := make(chan error, 10)
+				conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 0})
+				Expect(err).ToNot(HaveOccurred())
+				srv := &http3.Server{
+					Server: &http.Server{
+						Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+							w.Header().Set(http3.CapsuleProtocolHeader, "?1")
+							w.WriteHeader(http.StatusOK)
+							w.(http.Flusher).Flush()
+							_, err := io.Copy(ioutil.Discard, r.Body)
+							streamErrChan <- err
+						}),
+						TLSConfig: testdata.GetTLSConfig(),
+					},
+					QuicConfig:            getQuicConfig(&quic.Config{Versions: []protocol.VersionNumber{version}}),
+					EnableExtendedConnect: true,
+					EnableDatagrams:       true,
+				}
+				done := make(chan struct{})
+				go func() {
+					defer GinkgoRecover()
+					srv.Serve(conn)
+					close(done)
+				}()
+				defer func() {
+					Expect(srv.Close()).To(Succeed())
+					Eventually(done).Should(BeClosed())
+				}()
+				tmpl, err := masque.ParseTemplate(fmt.Sprintf(template, conn.LocalAddr().(*net.UDPAddr).Port))
+				Expect(err).ToNot(HaveOccurred())
+				client.Template = tmpl
+
+				for i := 0; i < 10; i++ {
+					tunnel, err := client.Dial(context.Background(), "localhost:1234")
+					Expect(err).ToNot(HaveOccurred())
+					Expect(tunnel.Close()).To(Succeed())
+					// The stream must be closed (FIN), not reset with H3_REQUEST_CANCELLED.
+					var streamErr error
+					Eventually(streamErrChan).Should(Receive(&streamErr))
+					Expect(streamErr).ToNot(HaveOccurred())
+				}
+			})
+
+			It("rejects requests that don't match the template", func() {
+				tmpl, err := masque.ParseTemplate(fmt.Sprintf("https://localhost:%d/masque/{target_host}/{target_port}", proxyPort))
+				Expect(err).ToNot(HaveOccurred())
+				client.Template = tmpl
+				_, err = client.Dial(context.Background(), "localhost:1234")
+				Expect(err).To(MatchError("masque: CONNECT-UDP request failed: 400 Bad Request"))
+			})
+		})
+	}
+})
@@ -0,0 +1,135 @@
+package self_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+
+	quic "github.com/For-ACGN/quic-go"
+	"github.com/For-ACGN/quic-go/internal/protocol"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Connection Migration", func() {
+	for _, v := range protocol.SupportedVersions {
+		version := v
+
+		Context(fmt.Sprintf("with QUIC version %s", version), func() {
+			It("migrates the connection to a new packet conn", func() {
+				ln, err := quic.ListenAddr("localhost:0", getTLSConfig(), getQuicConfig(&quic.Config{Versions: []protocol.VersionNumber{version}}))
+				Expect(err).ToNot(HaveOccurred())
+				defer ln.Close()
+
+				serverSessChan := make(chan quic.Session, 1)
+				go func() {
+					defer GinkgoRecover()
+					sess, err := ln.Accept(context.Background())
+					Expect(err).ToNot(HaveOccurred())
+					serverSessChan <- sess
+					str, err := sess.AcceptStream(context.Background())
+					Expect(err).ToNot(HaveOccurred())
+					// echo all data until the client closes the session
+					io.Copy(str, str)
+				}()
+
+				conn1, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 0})
+				Expect(err).ToNot(HaveOccurred())
+				defer conn1.Close()
+				conn2, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 0})
+				Expect(err).ToNot(HaveOccurred())
+				defer conn2.Close()
+
+				sess, err := quic.Dial(
+					conn1,
+					ln.Addr(),
+					"localhost",
+					getTLSClientConfig(),
+					getQuicConfig(&quic.Config{Versions: []protocol.VersionNumber{version}}),
+				)
+				Expect(err).ToNot(HaveOccurred())
+				defer sess.CloseWithError(0, "")
+				var serverSess quic.Session
+				Eventually(serverSessChan).Should(Receive(&serverSess))
+
+				str, err := sess.OpenStream()
+				Expect(err).ToNot(HaveOccurred())
+				_, err = str.Write([]byte("foo"))
+				Expect(err).ToNot(HaveOccurred())
+				data := make([]byte, 3)
+				_, err = io.ReadFull(str, data)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(data).To(Equal([]byte("foo")))
+				Expect(serverSess.RemoteAddr().String()).To(Equal(conn1.LocalAddr().String()))
+
+				// Migration is only possible once the handshake is confirmed,
+				// and the server has issued new connection IDs.
+				Eventually(func() error { return sess.MigrateConnection(conn2) }).Should(Succeed())
+				Expect(sess.LocalAddr()).To(Equal(conn2.LocalAddr()))
+				// the old packet conn isn't used any more
+				Expect(conn1.Close()).To(Succeed())
+
+				_, err = str.Write([]byte("bar"))
+				Expect(err).ToNot(HaveOccurred())
+				_, err = io.ReadFull(str, data)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(data).To(Equal([]byte("bar")))
+				Eventually(func() string { return serverSess.RemoteAddr().String() }).Should(Equal(conn2.LocalAddr().String()))
+			})
+
+			It("migrates the connection to the server's preferred address", func() {
+				ln, err := quic.ListenAddr(
+					"localhost:0",
+					getTLSConfig(),
+					getQuicConfig(&quic.Config{
+						Versions:             []protocol.VersionNumber{version},
+						PreferredAddressIPv4: &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)},
+					}),
+				)
+				Expect(err).ToNot(HaveOccurred())
+				defer ln.Close()
+
+				serverSessChan := make(chan quic.Session, 1)
+				go func() {
+					defer GinkgoRecover()
+					sess, err := ln.Accept(context.Background())
+					Expect(err).ToNot(HaveOccurred())
+					serverSessChan <- sess
+					str, err := sess.AcceptStream(context.Background())
+					Expect(err).ToNot(HaveOccurred())
+					// echo all data until the client closes the session
+					io.Copy(str, str)
+				}()
+
+				sess, err := quic.DialAddr(
+					fmt.Sprintf("localhost:%d", ln.Addr().(*net.UDPAddr).Port),
+					getTLSClientConfig(),
+					getQuicConfig(&quic.Config{Versions: []protocol.VersionNumber{version}}),
+				)
+				Expect(err).ToNot(HaveOccurred())
+				defer sess.CloseWithError(0, "")
+				var serverSess quic.Session
+				Eventually(serverSessChan).Should(Receive(&serverSess))
+
+				str, err := sess.OpenStream()
+				Expect(err).ToNot(HaveOccurred())
+				_, err = str.Write([]byte("foo"))
+				Expect(err).ToNot(HaveOccurred())
+				data := make([]byte, 3)
+				_, err = io.ReadFull(str, data)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(data).To(Equal([]byte("foo")))
+
+				Eventually(func() string { return sess.RemoteAddr().String() }).ShouldNot(Equal(ln.Addr().String()))
+				_, err = str.Write([]byte("bar"))
+				Expect(err).ToNot(HaveOccurred())
+				_, err = io.ReadFull(str, data)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(data).To(Equal([]byte("bar")))
+				Eventually(func() string { return serverSess.LocalAddr().String() }).Should(Equal(sess.RemoteAddr().String()))
+			})
+		})
+	}
+})
@@ -0,0 +1,139 @@
+package self_test
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/For-ACGN/quic-go"
+	quicproxy "github.com/For-ACGN/quic-go/integrationtests/tools/proxy"
+	"github.com/For-ACGN/quic-go/internal/protocol"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("DPLPMTUD", func() {
+	const numRepetitions = 20
+
+	var (
+		server quic.Listener
+		proxy  *quicproxy.QuicProxy
+
+		mutex            sync.Mutex
+		maxPacketSize    int
+		blackHoleSize    int  // packets larger than this size are dropped
+		blackHole        bool // if set, a black hole is created once the first packet larger than the initial size was sent
+		numDroppedLarger int
+	)
+
+	runServerAndProxy := func(conf *quic.Config) {
+		maxPacketSize = 0
+		blackHoleSize = 0
+		blackHole = false
+		numDroppedLarger = 0
+		var err error
+		server, err = quic.ListenAddr("localhost:0", getTLSConfig(), getQuicConfig(conf))
+		Expect(err).ToNot(HaveOccurred())
+		go func() {
+			defer GinkgoRecover()
+			sess, err := server.Accept(context.Background())
+			if err != nil {
+				return
+			}
+			str, err := sess.OpenStream()
+			Expect(err).ToNot(HaveOccurred())
+			for i := 0; i < numRepetitions; i++ {
+				_, err = str.Write(PRData)
+				Expect(err).ToNot(HaveOccurred())
+			}
+			Expect(str.Close()).To(Succeed())
+		}()
+
+		proxy, err = quicproxy.NewQuicProxy("localhost:0", &quicproxy.Opts{
+			RemoteAddr: fmt.Sprintf("localhost:%d", server.Addr().(*net.UDPAddr).Port),
+			DropPacket: func(dir quicproxy.Direction, data []byte) bool {
+				if dir != quicproxy.DirectionOutgoing {
+					return false
+				}
+				mutex.Lock()
+				defer mutex.Unlock()
+				if blackHoleSize > 0 && len(data) > blackHoleSize {
+					numDroppedLarger++
+					return true
+				}
+				if len(data) > maxPacketSize {
+					maxPacketSize = len(data)
+				}
+				if blackHole && maxPacketSize > protocol.MaxPacketSizeIPv4 {
+					blackHoleSize = protocol.MaxPacketSizeIPv4
+				}
+				return false
+			},
+			DelayPacket: func(quicproxy.Direction, []byte) time.Duration { return 5 * time.Millisecond },
+		})
+		Expect(err).ToNot(HaveOccurred())
+	}
+
+	AfterEach(func() {
+		Expect(proxy.Close()).To(Succeed())
+		Expect(server.Close()).To(Succeed())
+	})
+
+	download := func(conf *quic.Config) {
+		sess, err := quic.DialAddr(
+			fmt.Sprintf("localhost:%d", proxy.LocalPort()),
+			getTLSClientConfig(),
+			getQuicConfig(conf),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer sess.CloseWithError(0, "")
+		str, err := sess.AcceptStream(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		data, err := ioutil.ReadAll(str)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(data).To(HaveLen(numRepetitions * len(PRData)))
+	}
+
+	It("discovers the path MTU", func() {
+		runServerAndProxy(&quic.Config{EnablePathMTUDiscovery: true})
+		download(&quic.Config{EnablePathMTUDiscovery: true})
+		mutex.Lock()
+		defer mutex.Unlock()
+		Expect(maxPacketSize).To(BeNumerically(">", protocol.MaxPacketSizeIPv4))
+		Expect(maxPacketSize).To(BeNumerically("<=", protocol.MaxReceivePacketSize))
+	})
+
+	It("discovers jumbo path MTUs", func() {
+		runServerAndProxy(&quic.Config{EnablePathMTUDiscovery: true, MaxPacketSize: int(protocol.MaxJumboPacketSize)})
+		download(&quic.Config{EnablePathMTUDiscovery: true, MaxPacketSize: int(protocol.MaxJumboPacketSize)})
+		mutex.Lock()
+		defer mutex.Unlock()
+		Expect(maxPacketSize).To(BeNumerically(">", protocol.MaxReceivePacketSize))
+		Expect(maxPacketSize).To(BeNumerically("<=", protocol.MaxJumboPacketSize))
+	})
+
+	It("doesn't send larger packets when path MTU discovery is disabled", func() {
+		runServerAndProxy(&quic.Config{})
+		download(&quic.Config{EnablePathMTUDiscovery: true})
+		mutex.Lock()
+		defer mutex.Unlock()
+		Expect(maxPacketSize).To(BeNumerically("<=", protocol.MaxPacketSizeIPv4))
+	})
+
+	It("recovers from a path MTU black hole", func() {
+		runServerAndProxy(&quic.Config{EnablePathMTUDiscovery: true})
+		// Once the MTU was raised, start dropping all packets larger than the initial size.
+		mutex.Lock()
+		blackHole = true
+		mutex.Unlock()
+		download(&quic.Config{EnablePathMTUDiscovery: true})
+		mutex.Lock()
+		defer mutex.Unlock()
+		Expect(blackHoleSize).ToNot(BeZero())
+		Expect(numDroppedLarger).ToNot(BeZero())
+	})
+})
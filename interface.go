@@ -6,6 +6,7 @@ import (
 	"net"
 	"time"
 
+	"github.com/For-ACGN/quic-go/congestion"
 	"github.com/For-ACGN/quic-go/internal/handshake"
 	"github.com/For-ACGN/quic-go/internal/protocol"
 	"github.com/For-ACGN/quic-go/logging"
@@ -32,6 +33,46 @@ const (
 	VersionDraft29 = protocol.VersionDraft29
 	// VersionDraft32 is IETF QUIC draft-32
 	VersionDraft32 = protocol.VersionDraft32
+	// Version1 is RFC 9000
+	Version1 = protocol.Version1
+	// Version2 is RFC 9369
+	Version2 = protocol.Version2
+)
+
+// A CongestionControlAlgorithm is a congestion control algorithm.
+type CongestionControlAlgorithm = protocol.CongestionControlAlgorithm
+
+const (
+	// CongestionControlReno is TCP NewReno
+	CongestionControlReno = protocol.CongestionControlReno
+	// CongestionControlCubic is TCP Cubic
+	CongestionControlCubic = protocol.CongestionControlCubic
+	// CongestionControlBBR is BBR
+	CongestionControlBBR = protocol.CongestionControlBBR
+)
+
+// A StreamPriority is the priority of a stream.
+// Streams that never had their priority set have an urgency of DefaultStreamUrgency and are incremental.
+type StreamPriority = protocol.StreamPriority
+
+const (
+	// DefaultStreamUrgency is the urgency of a stream if no priority was set.
+	DefaultStreamUrgency = protocol.DefaultStreamUrgency
+	// MaxStreamUrgency is the largest (i.e. least urgent) urgency value of a stream.
+	MaxStreamUrgency = protocol.MaxStreamUrgency
+)
+
+// A StreamSchedulingMode decides how data of multiple streams is scheduled for sending.
+type StreamSchedulingMode = protocol.StreamSchedulingMode
+
+const (
+	// StreamSchedulingStrictPriority always sends data of the most urgent streams first.
+	// Incremental streams of the same urgency are served round-robin,
+	// non-incremental streams of the same urgency are served one after the other.
+	StreamSchedulingStrictPriority = protocol.StreamSchedulingStrictPriority
+	// StreamSchedulingWeightedFair shares the bandwidth between all streams.
+	// The share of a stream is weighted by its urgency, no stream is starved.
+	StreamSchedulingWeightedFair = protocol.StreamSchedulingWeightedFair
 )
 
 // A Token can be used to verify the ownership of the client address.
@@ -132,6 +173,10 @@ type SendStream interface {
 	// some of the data was successfully written.
 	// A zero value for t means Write will not time out.
 	SetWriteDeadline(t time.Time) error
+	// SetPriority sets the priority of the stream.
+	// It is used to decide which stream's data is sent first, see Config.StreamScheduling.
+	// Urgency values larger than MaxStreamUrgency are treated as MaxStreamUrgency.
+	SetPriority(StreamPriority)
 }
 
 // StreamError is returned by Read and Write when the peer cancels the stream.
@@ -187,8 +232,21 @@ type Session interface {
 	// It blocks until the handshake completes.
 	// Warning: This API should not be considered stable and might change soon.
 	ConnectionState() ConnectionState
+	// ConnectionStats returns a snapshot of the transport metrics of the connection.
+	// Once the session is closed, it returns the metrics at the time the session was closed.
+	// Warning: This API should not be considered stable and might change soon.
+	ConnectionStats() ConnectionStats
+	// MigrateConnection migrates the connection to a new packet conn.
+	// It blocks until the new path was validated, or until path validation failed.
+	// If path validation fails, the connection continues to use the current path.
+	// Only the client can migrate a connection, and only after the handshake was confirmed.
+	// Once the connection was migrated, the old packet conn is not used any more.
+	// If path validation fails, the new packet conn is not used.
+	// Warning: This API should not be considered stable and might change soon.
+	MigrateConnection(net.PacketConn) error
 
 	// SendMessage sends a message as a datagram.
+	// The message needs to fit into a single QUIC packet, so the maximum message size depends on the current packet size.
 	// See https://datatracker.ietf.org/doc/draft-pauly-quic-datagram/.
 	SendMessage([]byte) error
 	// ReceiveMessage gets a message received in a datagram.
@@ -264,8 +322,36 @@ type Config struct {
 	// The StatelessResetKey is used to generate stateless reset tokens.
 	// If no key is configured, sending of stateless resets is disabled.
 	StatelessResetKey []byte
+	// PreferredAddressIPv4 and PreferredAddressIPv6 are sent to the client in the preferred_address transport parameter.
+	// After completion of the handshake, the client validates the path to the preferred address and migrates the connection.
+	// If path validation fails, the client continues using the address it used for the handshake.
+	// The server listens on these addresses in addition to the address it was started on.
+	// If the port is 0, a port is chosen automatically.
+	// The IP must not be the unspecified address.
+	// This option is only valid for the server.
+	PreferredAddressIPv4 *net.UDPAddr
+	PreferredAddressIPv6 *net.UDPAddr
 	// KeepAlive defines whether this peer will periodically send a packet to keep the connection alive.
 	KeepAlive bool
+	// CongestionControl is the congestion control algorithm used for sending.
+	// If not set, Reno is used.
+	CongestionControl CongestionControlAlgorithm
+	// NewCongestionControl creates the congestion controller of a connection.
+	// It is passed the RTT statistics of the connection, which are updated before the congestion controller is notified about acknowledged packets.
+	// If set, CongestionControl is ignored.
+	NewCongestionControl func(rttStats *congestion.RTTStats) congestion.SendAlgorithm
+	// EnablePathMTUDiscovery enables Path MTU Discovery (RFC 8899).
+	// If disabled, packets are at most 1252 (IPv4) / 1232 (IPv6) bytes in size.
+	EnablePathMTUDiscovery bool
+	// MaxPacketSize is the maximum size of the packets (i.e. of the UDP payload) that can be received.
+	// It is sent to the peer as the max_udp_payload_size transport parameter,
+	// and it limits the packet size that Path MTU Discovery probes for.
+	// Values larger than 1452 bytes allow using jumbo frames. The maximum value is 8972 bytes.
+	// If not set, it defaults to 1452 bytes.
+	MaxPacketSize int
+	// StreamScheduling is the algorithm used to decide which stream's data is sent next.
+	// If not set, streams are scheduled by strict priority.
+	StreamScheduling StreamSchedulingMode
 	// See https://datatracker.ietf.org/doc/draft-ietf-quic-datagram/.
 	// Datagrams will only be available when both peers enable datagram support.
 	EnableDatagrams bool
@@ -278,6 +364,49 @@ type ConnectionState struct {
 	SupportsDatagrams bool
 }
 
+// ConnectionStats is a snapshot of the transport metrics of a QUIC connection.
+// Byte and packet counts include all packet number spaces.
+type ConnectionStats struct {
+	// The RTT estimates, as defined in RFC 9002.
+	// They are 0 until the first RTT sample was obtained.
+	MinRTT        time.Duration
+	LatestRTT     time.Duration
+	SmoothedRTT   time.Duration
+	MeanDeviation time.Duration
+
+	// CongestionWindow is the congestion window, in bytes.
+	CongestionWindow uint64
+	// BytesInFlight is the number of bytes sent in packets that were neither acknowledged nor declared lost.
+	BytesInFlight uint64
+	// MTU is the maximum size of the packets sent, as determined by path MTU discovery.
+	MTU uint64
+
+	// Statistics about slow start (STARTUP in BBR).
+	// They are only collected when using CongestionControlBBR.
+	SlowStartCount       uint64
+	SlowStartRTTs        uint64
+	SlowStartPacketsLost uint64
+	SlowStartBytesLost   uint64
+	// SlowStartDuration is the total time spent in slow start.
+	SlowStartDuration time.Duration
+
+	BytesSent       uint64
+	BytesReceived   uint64
+	PacketsSent     uint64
+	PacketsReceived uint64
+	PacketsLost     uint64
+	// PacketsRetransmitted is the number of packets whose contents were retransmitted,
+	// either because the packet was declared lost, or in a probe packet sent after a PTO.
+	PacketsRetransmitted uint64
+	// PTOCount is the number of consecutive PTOs that occurred without receiving an acknowledgement.
+	PTOCount uint32
+
+	// SendWindow is the number of bytes that can be sent before the connection is blocked by connection-level flow control.
+	SendWindow uint64
+	// ReceiveWindow is the size of the connection-level receive flow control window.
+	ReceiveWindow uint64
+}
+
 // A Listener for incoming QUIC connections
 type Listener interface {
 	// Close the server. All active sessions will be closed.
@@ -0,0 +1,81 @@
+package ackhandler
+
+import (
+	"time"
+
+	"github.com/For-ACGN/quic-go/internal/protocol"
+	"github.com/For-ACGN/quic-go/internal/utils"
+	"github.com/For-ACGN/quic-go/internal/wire"
+)
+
+// The ackFrequencyController decides when to send ACK_FREQUENCY frames,
+// as defined in draft-ietf-quic-ack-frequency.
+// It asks the peer to acknowledge a quarter of the congestion window at a time,
+// which reduces the number of ACKs sent on paths with a large bandwidth-delay product.
+type ackFrequencyController struct {
+	enabled         bool
+	peerMinAckDelay time.Duration
+
+	nextSequenceNumber uint64
+	lastFrame          *wire.AckFrequencyFrame
+	lastFrameTime      time.Time
+
+	rttStats *utils.RTTStats
+}
+
+func newAckFrequencyController(rttStats *utils.RTTStats) *ackFrequencyController {
+	return &ackFrequencyController{rttStats: rttStats}
+}
+
+// Enable is called when the peer advertised support for the ACK frequency extension.
+func (c *ackFrequencyController) Enable(peerMinAckDelay time.Duration) {
+	c.enabled = true
+	c.peerMinAckDelay = peerMinAckDelay
+}
+
+// Reset is called when the connection migrated to a new path.
+// Since the RTT estimate and the congestion window are reset, the parameters are recomputed from scratch.
+func (c *ackFrequencyController) Reset() {
+	c.lastFrameTime = time.Time{}
+}
+
+// GetFrame returns an ACK_FREQUENCY frame, if the parameters requested from the peer should be updated.
+// It returns at most one frame per RTT.
+func (c *ackFrequencyController) GetFrame(now time.Time, cwnd, maxDatagramSize protocol.ByteCount) *wire.AckFrequencyFrame {
+	if !c.enabled || c.rttStats.SmoothedRTT() == 0 {
+		return nil
+	}
+	if !c.lastFrameTime.IsZero() && now.Sub(c.lastFrameTime) < c.rttStats.SmoothedRTT() {
+		return nil
+	}
+
+	threshold := uint64(1)
+	if packets := uint64(cwnd / maxDatagramSize); packets/4 > 1 {
+		threshold = utils.MinUint64(packets/4-1, protocol.MaxAckElicitingThreshold)
+	}
+	delay := c.rttStats.SmoothedRTT() / 4
+	delay = utils.MaxDuration(delay, c.peerMinAckDelay)
+	// We must not request a delay larger than the peer's max_ack_delay, since this is the value used to calculate the PTO.
+	delay = utils.MinDuration(delay, c.rttStats.MaxAckDelay())
+
+	if c.lastFrame != nil && c.lastFrame.AckElicitingThreshold == threshold {
+		diff := delay - c.lastFrame.RequestMaxAckDelay
+		if diff < 0 {
+			diff = -diff
+		}
+		// Only send a new frame if the ACK delay changed by more than 25%.
+		if 4*diff <= c.lastFrame.RequestMaxAckDelay {
+			return nil
+		}
+	}
+	f := &wire.AckFrequencyFrame{
+		SequenceNumber:        c.nextSequenceNumber,
+		AckElicitingThreshold: threshold,
+		RequestMaxAckDelay:    delay,
+		ReorderingThreshold:   1,
+	}
+	c.nextSequenceNumber++
+	c.lastFrame = f
+	c.lastFrameTime = now
+	return f
+}
@@ -0,0 +1,98 @@
+package ackhandler
+
+import (
+	"time"
+
+	"github.com/For-ACGN/quic-go/internal/protocol"
+	"github.com/For-ACGN/quic-go/internal/utils"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ACK Frequency Controller", func() {
+	var (
+		c        *ackFrequencyController
+		rttStats *utils.RTTStats
+		now      time.Time
+	)
+
+	const packetSize protocol.ByteCount = 1000
+
+	BeforeEach(func() {
+		rttStats = &utils.RTTStats{}
+		rttStats.SetMaxAckDelay(25 * time.Millisecond)
+		rttStats.UpdateRTT(40*time.Millisecond, 0, time.Now())
+		c = newAckFrequencyController(rttStats)
+		c.Enable(time.Millisecond)
+		now = time.Now()
+	})
+
+	It("doesn't send frames if the peer doesn't support the extension", func() {
+		c = newAckFrequencyController(rttStats)
+		Expect(c.GetFrame(now, 100*packetSize, packetSize)).To(BeNil())
+	})
+
+	It("doesn't send frames before an RTT sample was obtained", func() {
+		c = newAckFrequencyController(&utils.RTTStats{})
+		c.Enable(time.Millisecond)
+		Expect(c.GetFrame(now, 100*packetSize, packetSize)).To(BeNil())
+	})
+
+	It("requests an ACK for every quarter of the congestion window", func() {
+		f := c.GetFrame(now, 20*packetSize, packetSize)
+		Expect(f).ToNot(BeNil())
+		Expect(f.SequenceNumber).To(BeZero())
+		Expect(f.AckElicitingThreshold).To(BeEquivalentTo(4))
+		Expect(f.RequestMaxAckDelay).To(Equal(10 * time.Millisecond))
+		Expect(f.ReorderingThreshold).To(BeEquivalentTo(1))
+	})
+
+	It("limits the ack-eliciting threshold", func() {
+		f := c.GetFrame(now, 1000*packetSize, packetSize)
+		Expect(f).ToNot(BeNil())
+		Expect(f.AckElicitingThreshold).To(BeEquivalentTo(protocol.MaxAckElicitingThreshold))
+		f = c.GetFrame(now.Add(time.Second), 2*packetSize, packetSize)
+		Expect(f).ToNot(BeNil())
+		Expect(f.AckElicitingThreshold).To(BeEquivalentTo(1))
+	})
+
+	It("limits the requested ACK delay", func() {
+		rttStats.UpdateRTT(time.Second, 0, time.Now())
+		f := c.GetFrame(now, 20*packetSize, packetSize)
+		Expect(f).ToNot(BeNil())
+		Expect(f.RequestMaxAckDelay).To(Equal(25 * time.Millisecond))
+	})
+
+	It("doesn't request an ACK delay smaller than the peer's min_ack_delay", func() {
+		c.Enable(15 * time.Millisecond)
+		f := c.GetFrame(now, 20*packetSize, packetSize)
+		Expect(f).ToNot(BeNil())
+		Expect(f.RequestMaxAckDelay).To(Equal(15 * time.Millisecond))
+	})
+
+	It("sends at most one frame per RTT", func() {
+		Expect(c.GetFrame(now, 20*packetSize, packetSize)).ToNot(BeNil())
+		Expect(c.GetFrame(now.Add(30*time.Millisecond), 40*packetSize, packetSize)).To(BeNil())
+		f := c.GetFrame(now.Add(40*time.Millisecond), 40*packetSize, packetSize)
+		Expect(f).ToNot(BeNil())
+		Expect(f.SequenceNumber).To(BeEquivalentTo(1))
+		Expect(f.AckElicitingThreshold).To(BeEquivalentTo(9))
+	})
+
+	It("only sends a new frame if the parameters changed significantly", func() {
+		Expect(c.GetFrame(now, 20*packetSize, packetSize)).ToNot(BeNil())
+		now = now.Add(time.Second)
+		Expect(c.GetFrame(now, 21*packetSize, packetSize)).To(BeNil())
+		// the smoothed RTT changes slightly
+		rttStats.UpdateRTT(50*time.Millisecond, 0, time.Now())
+		Expect(c.GetFrame(now, 20*packetSize, packetSize)).To(BeNil())
+		// the smoothed RTT changes by more than 25%
+		for i := 0; i < 10; i++ {
+			rttStats.UpdateRTT(80*time.Millisecond, 0, time.Now())
+		}
+		f := c.GetFrame(now, 20*packetSize, packetSize)
+		Expect(f).ToNot(BeNil())
+		Expect(f.SequenceNumber).To(BeEquivalentTo(1))
+	})
+})
@@ -1,6 +1,7 @@
 package ackhandler
 
 import (
+	"github.com/For-ACGN/quic-go/internal/congestion"
 	"github.com/For-ACGN/quic-go/internal/protocol"
 	"github.com/For-ACGN/quic-go/internal/utils"
 	"github.com/For-ACGN/quic-go/logging"
@@ -11,10 +12,12 @@ func NewAckHandler(
 	initialPacketNumber protocol.PacketNumber,
 	rttStats *utils.RTTStats,
 	pers protocol.Perspective,
+	congestionControl protocol.CongestionControlAlgorithm,
+	newCongestionControl func(*utils.RTTStats) congestion.SendAlgorithm,
 	tracer logging.ConnectionTracer,
 	logger utils.Logger,
 	version protocol.VersionNumber,
 ) (SentPacketHandler, ReceivedPacketHandler) {
-	sph := newSentPacketHandler(initialPacketNumber, rttStats, pers, tracer, logger)
+	sph := newSentPacketHandler(initialPacketNumber, rttStats, pers, congestionControl, newCongestionControl, tracer, logger)
 	return sph, newReceivedPacketHandler(sph, rttStats, logger, version)
 }
@@ -0,0 +1,209 @@
+package ackhandler
+
+import (
+	"github.com/For-ACGN/quic-go/internal/protocol"
+	"github.com/For-ACGN/quic-go/internal/utils"
+	"github.com/For-ACGN/quic-go/logging"
+)
+
+type ecnState uint8
+
+const (
+	ecnStateInitial ecnState = iota
+	ecnStateTesting
+	ecnStateUnknown
+	ecnStateCapable
+	ecnStateFailed
+)
+
+// numECNTestingPackets is the number of packets sent with ECT(0) when starting ECN validation.
+// See section 13.4.2 of RFC 9000.
+const numECNTestingPackets = 10
+
+type ecnHandler interface {
+	SentPacket(protocol.PacketNumber, protocol.ECN)
+	Mode() protocol.ECN
+	HandleNewlyAcked(packets []*Packet, ect0, ect1, ecnce int64) (congested bool)
+	LostPacket(protocol.PacketNumber)
+	MigratedPath()
+}
+
+// The ecnTracker performs ECN validation of a path, as described in section 13.4.2 of RFC 9000.
+// We only send packets marked ECT(0), and only in the application data packet number space.
+type ecnTracker struct {
+	state                                 ecnState
+	numSentTesting, numLostTesting        uint8
+	firstTestingPacket, lastTestingPacket protocol.PacketNumber
+
+	numSentECT0 int64
+	// the ECN counts reported in the last ACK frame
+	numAckedECT0, numAckedECT1, numAckedECNCE int64
+	// the ECN counts reported when the current validation was started
+	validationStartECT0, validationStartECNCE int64
+
+	tracer logging.ConnectionTracer
+	logger utils.Logger
+}
+
+var _ ecnHandler = &ecnTracker{}
+
+func newECNTracker(logger utils.Logger, tracer logging.ConnectionTracer) *ecnTracker {
+	return &ecnTracker{
+		firstTestingPacket: protocol.InvalidPacketNumber,
+		lastTestingPacket:  protocol.InvalidPacketNumber,
+		tracer:             tracer,
+		logger:             logger,
+	}
+}
+
+// Mode returns the ECN codepoint to use for the next packet.
+func (e *ecnTracker) Mode() protocol.ECN {
+	switch e.state {
+	case ecnStateInitial, ecnStateTesting, ecnStateCapable:
+		return protocol.ECT0
+	default:
+		return protocol.ECNNon
+	}
+}
+
+// MigratedPath is called when the connection migrated to a new path.
+// ECN needs to be validated for every path, so the testing phase is restarted.
+// The ECN counts are kept, since the peer reports cumulative counts for the whole packet number space.
+func (e *ecnTracker) MigratedPath() {
+	e.logger.Debugf("Restarting ECN validation on the new path.")
+	e.state = ecnStateInitial
+	e.numSentTesting = 0
+	e.numLostTesting = 0
+	e.firstTestingPacket = protocol.InvalidPacketNumber
+	e.lastTestingPacket = protocol.InvalidPacketNumber
+	e.validationStartECT0 = e.numAckedECT0
+	e.validationStartECNCE = e.numAckedECNCE
+}
+
+// SentPacket is called for every packet sent in the application data packet number space.
+func (e *ecnTracker) SentPacket(pn protocol.PacketNumber, ecn protocol.ECN) {
+	if ecn == protocol.ECNNon {
+		return
+	}
+	if ecn != protocol.ECT0 {
+		panic("ecnTracker BUG: only ECT(0) is supported")
+	}
+	e.numSentECT0++
+
+	switch e.state {
+	case ecnStateInitial:
+		e.logger.Debugf("Starting ECN validation.")
+		e.setState(ecnStateTesting, logging.ECNTriggerNoTrigger)
+		e.firstTestingPacket = pn
+		fallthrough
+	case ecnStateTesting:
+		e.lastTestingPacket = pn
+		e.numSentTesting++
+		if e.numSentTesting >= numECNTestingPackets {
+			e.logger.Debugf("Finished sending ECN testing packets.")
+			e.setState(ecnStateUnknown, logging.ECNTriggerNoTrigger)
+		}
+	}
+}
+
+// LostPacket is called for every packet in the application data packet number space that is declared lost.
+func (e *ecnTracker) LostPacket(pn protocol.PacketNumber) {
+	if e.state != ecnStateTesting && e.state != ecnStateUnknown {
+		return
+	}
+	if e.firstTestingPacket == protocol.InvalidPacketNumber || pn < e.firstTestingPacket || pn > e.lastTestingPacket {
+		return
+	}
+	e.numLostTesting++
+	// Only fail ECN validation once all testing packets were sent.
+	if e.state == ecnStateUnknown && e.numLostTesting >= e.numSentTesting {
+		e.logger.Debugf("Disabling ECN. All testing packets were lost.")
+		e.setState(ecnStateFailed, logging.ECNFailedLostAllTestingPackets)
+	}
+}
+
+// HandleNewlyAcked processes the ECN counts of an ACK frame.
+// It must only be called for ACK frames that increase the largest acknowledged packet number.
+// It returns true if the ECN-CE count increased, i.e. if the network experienced congestion.
+func (e *ecnTracker) HandleNewlyAcked(packets []*Packet, ect0, ect1, ecnce int64) (congested bool) {
+	if e.state == ecnStateFailed {
+		return false
+	}
+
+	var numNewlyAckedECT0 int64
+	var ackedTestingPacket bool
+	for _, p := range packets {
+		if p.ECN != protocol.ECT0 {
+			continue
+		}
+		numNewlyAckedECT0++
+		if p.PacketNumber >= e.firstTestingPacket && p.PacketNumber <= e.lastTestingPacket {
+			ackedTestingPacket = true
+		}
+	}
+
+	// ECN counts are cumulative, they must never decrease.
+	if ect0 < e.numAckedECT0 || ect1 < e.numAckedECT1 || ecnce < e.numAckedECNCE {
+		e.logger.Debugf("Disabling ECN. ECN counts decreased unexpectedly.")
+		e.setState(ecnStateFailed, logging.ECNFailedDecreasedECNCounts)
+		return false
+	}
+	// An ACK frame that acknowledges ECN-marked packets without any ECN counts
+	// indicates that the peer or the network doesn't support ECN.
+	if ect0 == 0 && ect1 == 0 && ecnce == 0 {
+		if numNewlyAckedECT0 > 0 {
+			e.logger.Debugf("Disabling ECN. ECN-marked packet acknowledged, but no ECN counts on ACK frame.")
+			e.setState(ecnStateFailed, logging.ECNFailedNoECNCounts)
+		}
+		return false
+	}
+	// We never send packets marked ECT(1).
+	if ect1 > 0 || ect0+ecnce > e.numSentECT0 {
+		e.logger.Debugf("Disabling ECN. Received more ECN counts than packets sent with ECN marks.")
+		e.setState(ecnStateFailed, logging.ECNFailedMoreECNCountsThanSent)
+		return false
+	}
+	newECT0 := ect0 - e.numAckedECT0
+	newECNCE := ecnce - e.numAckedECNCE
+	e.numAckedECT0 = ect0
+	e.numAckedECT1 = ect1
+	e.numAckedECNCE = ecnce
+	// The increase of the ECT(0) and ECN-CE counts must account for all newly acknowledged ECT(0) packets.
+	if newECT0+newECNCE < numNewlyAckedECT0 {
+		e.logger.Debugf("Disabling ECN. ECN counts increased by less than the number of newly acknowledged ECN-marked packets.")
+		e.setState(ecnStateFailed, logging.ECNFailedTooFewECNCounts)
+		return false
+	}
+
+	if e.state == ecnStateTesting || e.state == ecnStateUnknown {
+		// If all testing packets were marked CE, the network is likely re-marking ECT(0) as CE.
+		if e.state == ecnStateUnknown && ackedTestingPacket && e.numAckedECT0 == e.validationStartECT0 && e.numAckedECNCE-e.validationStartECNCE >= int64(e.numSentTesting) {
+			e.logger.Debugf("Disabling ECN. All testing packets were marked CE.")
+			e.setState(ecnStateFailed, logging.ECNFailedManglingDetected)
+			return false
+		}
+		if ackedTestingPacket && e.numAckedECT0 > e.validationStartECT0 {
+			e.logger.Debugf("ECN validation succeeded.")
+			e.setState(ecnStateCapable, logging.ECNTriggerNoTrigger)
+		}
+	}
+	// CE marks are only treated as a congestion signal once the path was validated to support ECN.
+	return e.state == ecnStateCapable && newECNCE > 0
+}
+
+func (e *ecnTracker) setState(state ecnState, trigger logging.ECNStateTrigger) {
+	e.state = state
+	if e.tracer == nil {
+		return
+	}
+	switch state {
+	case ecnStateTesting:
+		e.tracer.ECNStateUpdated(logging.ECNStateTesting, trigger)
+	case ecnStateUnknown:
+		e.tracer.ECNStateUpdated(logging.ECNStateUnknown, trigger)
+	case ecnStateCapable:
+		e.tracer.ECNStateUpdated(logging.ECNStateCapable, trigger)
+	case ecnStateFailed:
+		e.tracer.ECNStateUpdated(logging.ECNStateFailed, trigger)
+	}
+}
@@ -0,0 +1,145 @@
+package ackhandler
+
+import (
+	mocklogging "github.com/For-ACGN/quic-go/internal/mocks/logging"
+	"github.com/For-ACGN/quic-go/internal/protocol"
+	"github.com/For-ACGN/quic-go/internal/utils"
+	"github.com/For-ACGN/quic-go/logging"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ECN tracker", func() {
+	var (
+		ecn    *ecnTracker
+		tracer *mocklogging.MockConnectionTracer
+	)
+
+	getAckedPackets := func(pns ...protocol.PacketNumber) []*Packet {
+		var packets []*Packet
+		for _, p := range pns {
+			packets = append(packets, &Packet{PacketNumber: p, ECN: protocol.ECT0})
+		}
+		return packets
+	}
+
+	// sendTestingPackets sends all ECN testing packets
+	sendTestingPackets := func() {
+		tracer.EXPECT().ECNStateUpdated(logging.ECNStateTesting, logging.ECNTriggerNoTrigger)
+		tracer.EXPECT().ECNStateUpdated(logging.ECNStateUnknown, logging.ECNTriggerNoTrigger)
+		for i := 0; i < numECNTestingPackets; i++ {
+			Expect(ecn.Mode()).To(Equal(protocol.ECT0))
+			ecn.SentPacket(protocol.PacketNumber(i), protocol.ECT0)
+		}
+		Expect(ecn.Mode()).To(Equal(protocol.ECNNon))
+	}
+
+	BeforeEach(func() {
+		tracer = mocklogging.NewMockConnectionTracer(mockCtrl)
+		ecn = newECNTracker(utils.DefaultLogger, tracer)
+	})
+
+	It("sends exactly 10 testing packets", func() {
+		sendTestingPackets()
+		// packets sent after the testing phase are not marked
+		ecn.SentPacket(20, protocol.ECNNon)
+		Expect(ecn.numSentECT0).To(BeEquivalentTo(numECNTestingPackets))
+	})
+
+	It("fails ECN validation if all testing packets are lost", func() {
+		sendTestingPackets()
+		for i := 0; i < numECNTestingPackets-1; i++ {
+			ecn.LostPacket(protocol.PacketNumber(i))
+		}
+		// packets sent after the testing phase are ignored
+		ecn.LostPacket(20)
+		tracer.EXPECT().ECNStateUpdated(logging.ECNStateFailed, logging.ECNFailedLostAllTestingPackets)
+		ecn.LostPacket(numECNTestingPackets - 1)
+		Expect(ecn.Mode()).To(Equal(protocol.ECNNon))
+	})
+
+	It("passes ECN validation when a testing packet is acknowledged", func() {
+		sendTestingPackets()
+		tracer.EXPECT().ECNStateUpdated(logging.ECNStateCapable, logging.ECNTriggerNoTrigger)
+		Expect(ecn.HandleNewlyAcked(getAckedPackets(1, 2, 3), 3, 0, 0)).To(BeFalse())
+		Expect(ecn.Mode()).To(Equal(protocol.ECT0))
+	})
+
+	It("reports congestion once the path is ECN capable", func() {
+		sendTestingPackets()
+		tracer.EXPECT().ECNStateUpdated(logging.ECNStateCapable, logging.ECNTriggerNoTrigger)
+		Expect(ecn.HandleNewlyAcked(getAckedPackets(1, 2, 3), 3, 0, 0)).To(BeFalse())
+		Expect(ecn.HandleNewlyAcked(getAckedPackets(4, 5), 4, 0, 1)).To(BeTrue())
+		// the ECN-CE count didn't increase
+		Expect(ecn.HandleNewlyAcked(getAckedPackets(6), 5, 0, 1)).To(BeFalse())
+	})
+
+	It("fails ECN validation if the ACK doesn't contain ECN counts", func() {
+		sendTestingPackets()
+		tracer.EXPECT().ECNStateUpdated(logging.ECNStateFailed, logging.ECNFailedNoECNCounts)
+		Expect(ecn.HandleNewlyAcked(getAckedPackets(1, 2), 0, 0, 0)).To(BeFalse())
+		Expect(ecn.Mode()).To(Equal(protocol.ECNNon))
+	})
+
+	It("fails ECN validation if the ECN counts decrease", func() {
+		sendTestingPackets()
+		tracer.EXPECT().ECNStateUpdated(logging.ECNStateCapable, logging.ECNTriggerNoTrigger)
+		Expect(ecn.HandleNewlyAcked(getAckedPackets(1, 2), 2, 0, 0)).To(BeFalse())
+		tracer.EXPECT().ECNStateUpdated(logging.ECNStateFailed, logging.ECNFailedDecreasedECNCounts)
+		Expect(ecn.HandleNewlyAcked(getAckedPackets(3), 1, 0, 0)).To(BeFalse())
+		Expect(ecn.Mode()).To(Equal(protocol.ECNNon))
+	})
+
+	It("fails ECN validation if the peer reports ECT(1) counts", func() {
+		sendTestingPackets()
+		tracer.EXPECT().ECNStateUpdated(logging.ECNStateFailed, logging.ECNFailedMoreECNCountsThanSent)
+		Expect(ecn.HandleNewlyAcked(getAckedPackets(1, 2), 1, 1, 0)).To(BeFalse())
+	})
+
+	It("fails ECN validation if the peer reports more ECN counts than packets were sent", func() {
+		sendTestingPackets()
+		tracer.EXPECT().ECNStateUpdated(logging.ECNStateFailed, logging.ECNFailedMoreECNCountsThanSent)
+		Expect(ecn.HandleNewlyAcked(getAckedPackets(1, 2), numECNTestingPackets+1, 0, 0)).To(BeFalse())
+	})
+
+	It("fails ECN validation if the ECN counts increase by less than the number of newly acknowledged packets", func() {
+		sendTestingPackets()
+		tracer.EXPECT().ECNStateUpdated(logging.ECNStateFailed, logging.ECNFailedTooFewECNCounts)
+		Expect(ecn.HandleNewlyAcked(getAckedPackets(1, 2, 3), 2, 0, 0)).To(BeFalse())
+	})
+
+	It("fails ECN validation if all testing packets are marked CE", func() {
+		sendTestingPackets()
+		var pns []protocol.PacketNumber
+		for i := 0; i < numECNTestingPackets; i++ {
+			pns = append(pns, protocol.PacketNumber(i))
+		}
+		tracer.EXPECT().ECNStateUpdated(logging.ECNStateFailed, logging.ECNFailedManglingDetected)
+		Expect(ecn.HandleNewlyAcked(getAckedPackets(pns...), 0, 0, numECNTestingPackets)).To(BeFalse())
+	})
+
+	It("doesn't treat CE marks as congestion before validation succeeded", func() {
+		tracer.EXPECT().ECNStateUpdated(logging.ECNStateTesting, logging.ECNTriggerNoTrigger)
+		ecn.SentPacket(0, protocol.ECT0)
+		ecn.SentPacket(1, protocol.ECT0)
+		Expect(ecn.HandleNewlyAcked(getAckedPackets(0, 1), 0, 0, 2)).To(BeFalse())
+	})
+
+	It("restarts ECN validation when migrating to a new path, keeping the ECN counts", func() {
+		sendTestingPackets()
+		tracer.EXPECT().ECNStateUpdated(logging.ECNStateCapable, logging.ECNTriggerNoTrigger)
+		Expect(ecn.HandleNewlyAcked(getAckedPackets(1, 2, 3), 3, 0, 0)).To(BeFalse())
+		ecn.MigratedPath()
+		Expect(ecn.Mode()).To(Equal(protocol.ECT0))
+		tracer.EXPECT().ECNStateUpdated(logging.ECNStateTesting, logging.ECNTriggerNoTrigger)
+		ecn.SentPacket(20, protocol.ECT0)
+		ecn.SentPacket(21, protocol.ECT0)
+		// CE marks are not a congestion signal until the new path was validated
+		Expect(ecn.HandleNewlyAcked(getAckedPackets(20), 3, 0, 1)).To(BeFalse())
+		// the ECN counts include the packets sent on the old path
+		tracer.EXPECT().ECNStateUpdated(logging.ECNStateCapable, logging.ECNTriggerNoTrigger)
+		Expect(ecn.HandleNewlyAcked(getAckedPackets(21), 4, 0, 1)).To(BeFalse())
+		Expect(ecn.numSentECT0).To(BeEquivalentTo(numECNTestingPackets + 2))
+	})
+})
@@ -3,6 +3,7 @@ package ackhandler
 import (
 	"time"
 
+	"github.com/For-ACGN/quic-go/internal/congestion"
 	"github.com/For-ACGN/quic-go/internal/protocol"
 	"github.com/For-ACGN/quic-go/internal/wire"
 )
@@ -15,12 +16,28 @@ type Packet struct {
 	Length          protocol.ByteCount
 	EncryptionLevel protocol.EncryptionLevel
 	SendTime        time.Time
+	// Path probe packets are sent on a path that is being validated.
+	// They are not counted towards bytes in flight, and they are not used for RTT measurements.
+	IsPathProbePacket bool
+	// Path MTU probe packets are used to discover the maximum packet size.
+	// When they are lost, this is not reported to the congestion controller.
+	IsPathMTUProbePacket bool
+	// ECN is the ECN codepoint the packet was sent with.
+	ECN protocol.ECN
 
 	includedInBytesInFlight bool
 	declaredLost            bool
 	skippedPacket           bool
 }
 
+func (p *Packet) toCongestionPacket() protocol.Packet {
+	return protocol.Packet{
+		PacketNumber: p.PacketNumber,
+		Length:       p.Length,
+		SendTime:     p.SendTime,
+	}
+}
+
 // SentPacketHandler handles ACKs received for outgoing packets
 type SentPacketHandler interface {
 	// SentPacket may modify the packet
@@ -30,6 +47,14 @@ type SentPacketHandler interface {
 	DropPackets(protocol.EncryptionLevel)
 	ResetForRetry() error
 	SetHandshakeConfirmed()
+	// MigratedPath is called when the connection migrated to a new path.
+	// It resets the RTT estimate and the congestion controller.
+	MigratedPath()
+	// SetMaxDatagramSize is called when path MTU discovery changes the maximum packet size.
+	SetMaxDatagramSize(protocol.ByteCount)
+	// DetectedPathMTUBlackHole says if repeated PTOs indicate that packets of the maximum packet size are dropped.
+	// It returns true only once after the black hole was detected.
+	DetectedPathMTUBlackHole() bool
 
 	// The SendMode determines if and what kind of packets can be sent.
 	SendMode() SendMode
@@ -38,6 +63,14 @@ type SentPacketHandler interface {
 	TimeUntilSend() time.Time
 	// HasPacingBudget says if the pacer allows sending of a (full size) packet at this moment.
 	HasPacingBudget() bool
+	// ECNMode returns the ECN codepoint that should be used for the next 1-RTT packet.
+	ECNMode() protocol.ECN
+	// EnableAckFrequency is called when the peer supports the ACK frequency extension.
+	EnableAckFrequency(peerMinAckDelay time.Duration)
+	// GetAckFrequencyFrame returns an ACK_FREQUENCY frame, if the ACK frequency requested from the peer should be updated.
+	GetAckFrequencyFrame(now time.Time) *wire.AckFrequencyFrame
+	// GetStats returns statistics about the packets sent and received.
+	GetStats() Stats
 
 	// only to be called once the handshake is complete
 	QueueProbePacket(protocol.EncryptionLevel) bool /* was a packet queued */
@@ -49,6 +82,25 @@ type SentPacketHandler interface {
 	OnLossDetectionTimeout() error
 }
 
+// Stats are statistics about the packets sent and received on a connection.
+type Stats struct {
+	CongestionWindow protocol.ByteCount
+	BytesInFlight    protocol.ByteCount
+	// The maximum packet size set by path MTU discovery. 0 if it wasn't set.
+	MaxDatagramSize protocol.ByteCount
+
+	BytesSent, BytesReceived     protocol.ByteCount
+	PacketsSent, PacketsReceived uint64
+	PacketsLost                  uint64
+	// The number of packets whose frames were queued for retransmission,
+	// either because the packet was declared lost, or to send a PTO probe packet.
+	PacketsRetransmitted uint64
+	// The number of consecutive PTOs.
+	PTOCount uint32
+	// Statistics about slow start. Only set if the congestion controller collects them.
+	SlowStart congestion.SlowStartStats
+}
+
 type sentPacketTracker interface {
 	GetLowestPacketNotConfirmedAcked() protocol.PacketNumber
 	ReceivedPacket(protocol.EncryptionLevel)
@@ -58,6 +110,9 @@ type sentPacketTracker interface {
 type ReceivedPacketHandler interface {
 	IsPotentiallyDuplicate(protocol.PacketNumber, protocol.EncryptionLevel) bool
 	ReceivedPacket(pn protocol.PacketNumber, ecn protocol.ECN, encLevel protocol.EncryptionLevel, rcvTime time.Time, shouldInstigateAck bool) error
+	// ReceivedAckFrequencyFrame and ReceivedImmediateAck apply to the application data packet number space.
+	ReceivedAckFrequencyFrame(*wire.AckFrequencyFrame)
+	ReceivedImmediateAck()
 	DropPackets(protocol.EncryptionLevel)
 
 	GetAlarmTimeout() time.Time
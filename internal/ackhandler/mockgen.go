@@ -1,3 +1,4 @@
 package ackhandler
 
 //go:generate sh -c "../../mockgen_private.sh ackhandler mock_sent_packet_tracker_test.go github.com/lucas-clemente/quic-go/internal/ackhandler sentPacketTracker"
+//go:generate sh -c "../../mockgen_private.sh ackhandler mock_ecn_handler_test.go github.com/lucas-clemente/quic-go/internal/ackhandler ecnHandler"
@@ -8,8 +8,13 @@ import (
 	"github.com/For-ACGN/quic-go/internal/wire"
 )
 
-// number of ack-eliciting packets received before sending an ack.
-const packetsBeforeAck = 2
+// The default number of ack-eliciting packets that can be received without sending an ACK.
+// This value can be changed by the peer using an ACK_FREQUENCY frame.
+const defaultAckElicitingThreshold = 1
+
+// The default reordering threshold.
+// With a threshold of 1, an ACK is sent as soon as a packet is received out of order.
+const defaultReorderingThreshold = 1
 
 type receivedPacketTracker struct {
 	largestObserved             protocol.PacketNumber
@@ -22,8 +27,13 @@ type receivedPacketTracker struct {
 	maxAckDelay time.Duration
 	rttStats    *utils.RTTStats
 
+	// parameters set by the peer using ACK_FREQUENCY frames
+	ackElicitingThreshold  uint64
+	reorderingThreshold    uint64
+	nextAckFrequencySeqNum uint64
+
 	hasNewAck bool // true as soon as we received an ack-eliciting new packet
-	ackQueued bool // true once we received more than 2 (or later in the connection 10) ack-eliciting packets
+	ackQueued bool // true once we received more than ackElicitingThreshold ack-eliciting packets
 
 	ackElicitingPacketsReceivedSinceLastAck int
 	ackAlarm                                time.Time
@@ -40,11 +50,13 @@ func newReceivedPacketTracker(
 	version protocol.VersionNumber,
 ) *receivedPacketTracker {
 	return &receivedPacketTracker{
-		packetHistory: newReceivedPacketHistory(),
-		maxAckDelay:   protocol.MaxAckDelay,
-		rttStats:      rttStats,
-		logger:        logger,
-		version:       version,
+		packetHistory:         newReceivedPacketHistory(),
+		maxAckDelay:           protocol.MaxAckDelay,
+		ackElicitingThreshold: defaultAckElicitingThreshold,
+		reorderingThreshold:   defaultReorderingThreshold,
+		rttStats:              rttStats,
+		logger:                logger,
+		version:               version,
 	}
 }
 
@@ -76,6 +88,30 @@ func (h *receivedPacketTracker) ReceivedPacket(packetNumber protocol.PacketNumbe
 	}
 }
 
+// ReceivedAckFrequencyFrame applies the parameters of an ACK_FREQUENCY frame.
+// Frames that arrive out of order (i.e. with a smaller sequence number) are ignored.
+func (h *receivedPacketTracker) ReceivedAckFrequencyFrame(f *wire.AckFrequencyFrame) {
+	if f.SequenceNumber < h.nextAckFrequencySeqNum {
+		return
+	}
+	h.nextAckFrequencySeqNum = f.SequenceNumber + 1
+	h.ackElicitingThreshold = f.AckElicitingThreshold
+	h.maxAckDelay = f.RequestMaxAckDelay
+	h.reorderingThreshold = f.ReorderingThreshold
+	if h.logger.Debug() {
+		h.logger.Debugf("\tUpdating ACK frequency: ack-eliciting threshold: %d, max ack delay: %s, reordering threshold: %d", h.ackElicitingThreshold, h.maxAckDelay, h.reorderingThreshold)
+	}
+}
+
+// ReceivedImmediateAck queues an ACK, as requested by an IMMEDIATE_ACK frame.
+func (h *receivedPacketTracker) ReceivedImmediateAck() {
+	if !h.ackQueued {
+		h.logger.Debugf("\tQueueing ACK because an IMMEDIATE_ACK frame was received.")
+	}
+	h.ackQueued = true
+	h.ackAlarm = time.Time{}
+}
+
 // IgnoreBelow sets a lower limit for acknowledging packets.
 // Packets with packet numbers smaller than p will not be acked.
 func (h *receivedPacketTracker) IgnoreBelow(p protocol.PacketNumber) {
@@ -97,12 +133,14 @@ func (h *receivedPacketTracker) isMissing(p protocol.PacketNumber) bool {
 	return p < h.lastAck.LargestAcked() && !h.lastAck.AcksPacket(p)
 }
 
+// hasNewMissingPackets says if a gap in the received packet numbers that wasn't reported in the last ACK
+// just reached the reordering threshold.
 func (h *receivedPacketTracker) hasNewMissingPackets() bool {
-	if h.lastAck == nil {
+	if h.lastAck == nil || h.reorderingThreshold == 0 {
 		return false
 	}
 	highestRange := h.packetHistory.GetHighestAckRange()
-	return highestRange.Smallest > h.lastAck.LargestAcked()+1 && highestRange.Len() == 1
+	return highestRange.Smallest > h.lastAck.LargestAcked()+1 && uint64(highestRange.Len()) == h.reorderingThreshold
 }
 
 // maybeQueueAck queues an ACK, if necessary.
@@ -125,17 +163,17 @@ func (h *receivedPacketTracker) maybeQueueAck(pn protocol.PacketNumber, rcvTime
 	// Send an ACK if this packet was reported missing in an ACK sent before.
 	// Ack decimation with reordering relies on the timer to send an ACK, but if
 	// missing packets we reported in the previous ack, send an ACK immediately.
-	if wasMissing {
+	if wasMissing && h.reorderingThreshold > 0 {
 		if h.logger.Debug() {
 			h.logger.Debugf("\tQueueing ACK because packet %d was missing before.", pn)
 		}
 		h.ackQueued = true
 	}
 
-	// send an ACK every 2 ack-eliciting packets
-	if h.ackElicitingPacketsReceivedSinceLastAck >= packetsBeforeAck {
+	// send an ACK once more than ackElicitingThreshold ack-eliciting packets were received
+	if uint64(h.ackElicitingPacketsReceivedSinceLastAck) > h.ackElicitingThreshold {
 		if h.logger.Debug() {
-			h.logger.Debugf("\tQueueing ACK because packet %d packets were received after the last ACK (using initial threshold: %d).", h.ackElicitingPacketsReceivedSinceLastAck, packetsBeforeAck)
+			h.logger.Debugf("\tQueueing ACK because %d packets were received after the last ACK (using threshold: %d).", h.ackElicitingPacketsReceivedSinceLastAck, h.ackElicitingThreshold)
 		}
 		h.ackQueued = true
 	} else if h.ackAlarm.IsZero() {
@@ -189,6 +189,79 @@ var _ = Describe("Received Packet Tracker", func() {
 				tracker.ReceivedPacket(11, protocol.ECNNon, time.Now(), true)
 				Expect(tracker.GetAckFrame(true)).To(BeNil())
 			})
+
+			Context("ACK frequency", func() {
+				It("uses the ack-eliciting threshold from the ACK_FREQUENCY frame", func() {
+					receiveAndAck10Packets()
+					tracker.ReceivedAckFrequencyFrame(&wire.AckFrequencyFrame{
+						AckElicitingThreshold: 4,
+						RequestMaxAckDelay:    50 * time.Millisecond,
+						ReorderingThreshold:   1,
+					})
+					rcvTime := time.Now()
+					for i := 11; i < 15; i++ {
+						tracker.ReceivedPacket(protocol.PacketNumber(i), protocol.ECNNon, rcvTime, true)
+						Expect(tracker.ackQueued).To(BeFalse())
+					}
+					Expect(tracker.GetAlarmTimeout()).To(Equal(rcvTime.Add(50 * time.Millisecond)))
+					tracker.ReceivedPacket(15, protocol.ECNNon, rcvTime, true)
+					Expect(tracker.ackQueued).To(BeTrue())
+				})
+
+				It("ignores reordered ACK_FREQUENCY frames", func() {
+					tracker.ReceivedAckFrequencyFrame(&wire.AckFrequencyFrame{
+						SequenceNumber:        5,
+						AckElicitingThreshold: 4,
+						RequestMaxAckDelay:    50 * time.Millisecond,
+					})
+					tracker.ReceivedAckFrequencyFrame(&wire.AckFrequencyFrame{
+						SequenceNumber:        4,
+						AckElicitingThreshold: 8,
+						RequestMaxAckDelay:    10 * time.Millisecond,
+					})
+					Expect(tracker.ackElicitingThreshold).To(BeEquivalentTo(4))
+					Expect(tracker.maxAckDelay).To(Equal(50 * time.Millisecond))
+				})
+
+				It("doesn't queue an ACK for reordered packets if the reordering threshold is 0", func() {
+					receiveAndAck10Packets()
+					tracker.ReceivedAckFrequencyFrame(&wire.AckFrequencyFrame{
+						AckElicitingThreshold: 10,
+						RequestMaxAckDelay:    protocol.MaxAckDelay,
+					})
+					tracker.ReceivedPacket(12, protocol.ECNNon, time.Now(), true)
+					Expect(tracker.ackQueued).To(BeFalse())
+					Expect(tracker.GetAckFrame(false)).ToNot(BeNil()) // ACK: 1-10, 12, missing: 11
+					tracker.ReceivedPacket(11, protocol.ECNNon, time.Now(), true)
+					Expect(tracker.ackQueued).To(BeFalse())
+				})
+
+				It("queues an ACK once a gap reaches the reordering threshold", func() {
+					receiveAndAck10Packets()
+					tracker.ReceivedAckFrequencyFrame(&wire.AckFrequencyFrame{
+						AckElicitingThreshold: 10,
+						RequestMaxAckDelay:    protocol.MaxAckDelay,
+						ReorderingThreshold:   3,
+					})
+					// 11 is missing
+					tracker.ReceivedPacket(12, protocol.ECNNon, time.Now(), true)
+					Expect(tracker.ackQueued).To(BeFalse())
+					tracker.ReceivedPacket(13, protocol.ECNNon, time.Now(), true)
+					Expect(tracker.ackQueued).To(BeFalse())
+					tracker.ReceivedPacket(14, protocol.ECNNon, time.Now(), true)
+					Expect(tracker.ackQueued).To(BeTrue())
+				})
+
+				It("queues an ACK when receiving an IMMEDIATE_ACK frame", func() {
+					receiveAndAck10Packets()
+					tracker.ReceivedPacket(11, protocol.ECNNon, time.Now(), true)
+					Expect(tracker.ackQueued).To(BeFalse())
+					Expect(tracker.GetAlarmTimeout()).ToNot(BeZero())
+					tracker.ReceivedImmediateAck()
+					Expect(tracker.GetAlarmTimeout()).To(BeZero())
+					Expect(tracker.GetAckFrame(true)).ToNot(BeNil())
+				})
+			})
 		})
 
 		Context("ACK generation", func() {
@@ -21,6 +21,8 @@ const (
 	packetThreshold = 3
 	// Before validating the client's address, the server won't send more than 3x bytes than it received.
 	amplificationFactor = 3
+	// After the path MTU was increased, this many consecutive PTOs are interpreted as a path MTU black hole.
+	mtuBlackHolePTOCount = 3
 )
 
 type packetNumberSpace struct {
@@ -63,6 +65,10 @@ type sentPacketHandler struct {
 	// Always true for the client.
 	peerAddressValidated bool
 
+	// statistics, see Stats
+	packetsSent, packetsReceived      uint64
+	packetsLost, packetsRetransmitted uint64
+
 	handshakeConfirmed bool
 
 	// lowestNotConfirmedAcked is the lowest packet number that we sent an ACK for, but haven't received confirmation, that this ACK actually arrived
@@ -75,8 +81,22 @@ type sentPacketHandler struct {
 
 	bytesInFlight protocol.ByteCount
 
-	congestion congestion.SendAlgorithmWithDebugInfos
-	rttStats   *utils.RTTStats
+	congestion           congestion.SendAlgorithm
+	congestionControl    protocol.CongestionControlAlgorithm
+	newCongestionControl func(*utils.RTTStats) congestion.SendAlgorithm
+	// Only set if the congestion controller processes acknowledged and lost packets in a single event.
+	congestionEvent congestion.CongestionEvent
+	// nil if the congestion controller doesn't react to ECN-CE marks
+	ecnCongestion congestion.ECNCongestionHandler
+	// Packets acknowledged and declared lost since the last congestion event.
+	ackedForCongestion []protocol.Packet
+	lostForCongestion  []protocol.Packet
+	rttStats           *utils.RTTStats
+
+	// ECN validation is only performed for the application data packet number space.
+	ecnTracker ecnHandler
+	// Decides when to send ACK_FREQUENCY frames. Only used if the peer supports the ACK frequency extension.
+	ackFrequency *ackFrequencyController
 
 	// The number of times a PTO has been sent without receiving an ack.
 	ptoCount uint32
@@ -85,6 +105,10 @@ type sentPacketHandler struct {
 	// Only applies to the application-data packet number space.
 	numProbesToSend int
 
+	// The maximum packet size set by path MTU discovery. 0 if it wasn't set.
+	maxDatagramSize          protocol.ByteCount
+	pathMTUBlackHoleDetected bool
+
 	// The alarm timeout
 	alarm time.Time
 
@@ -103,28 +127,71 @@ func newSentPacketHandler(
 	initialPN protocol.PacketNumber,
 	rttStats *utils.RTTStats,
 	pers protocol.Perspective,
+	congestionControl protocol.CongestionControlAlgorithm,
+	newCongestionControl func(*utils.RTTStats) congestion.SendAlgorithm,
 	tracer logging.ConnectionTracer,
 	logger utils.Logger,
 ) *sentPacketHandler {
-	congestion := congestion.NewCubicSender(
-		congestion.DefaultClock{},
-		rttStats,
-		true, // use Reno
-		tracer,
-	)
-
-	return &sentPacketHandler{
+	h := &sentPacketHandler{
 		peerCompletedAddressValidation: pers == protocol.PerspectiveServer,
 		peerAddressValidated:           pers == protocol.PerspectiveClient,
 		initialPackets:                 newPacketNumberSpace(initialPN, false, rttStats),
 		handshakePackets:               newPacketNumberSpace(0, false, rttStats),
 		appDataPackets:                 newPacketNumberSpace(0, true, rttStats),
 		rttStats:                       rttStats,
-		congestion:                     congestion,
+		congestionControl:              congestionControl,
+		newCongestionControl:           newCongestionControl,
+		ecnTracker:                     newECNTracker(logger, tracer),
+		ackFrequency:                   newAckFrequencyController(rttStats),
 		perspective:                    pers,
 		tracer:                         tracer,
 		logger:                         logger,
 	}
+	h.resetCongestionController()
+	return h
+}
+
+func newCongestionController(
+	algorithm protocol.CongestionControlAlgorithm,
+	rttStats *utils.RTTStats,
+	getBytesInFlight func() protocol.ByteCount,
+	tracer logging.ConnectionTracer,
+) congestion.SendAlgorithmWithDebugInfos {
+	switch algorithm {
+	case protocol.CongestionControlCubic:
+		return congestion.NewCubicSender(congestion.DefaultClock{}, rttStats, false, tracer)
+	case protocol.CongestionControlBBR:
+		return congestion.NewBBRSender(congestion.DefaultClock{}, rttStats, getBytesInFlight, tracer)
+	default:
+		return congestion.NewCubicSender(congestion.DefaultClock{}, rttStats, true, tracer)
+	}
+}
+
+func (h *sentPacketHandler) resetCongestionController() {
+	if h.newCongestionControl != nil {
+		h.setCongestionController(h.newCongestionControl(h.rttStats))
+	} else {
+		h.setCongestionController(newCongestionController(h.congestionControl, h.rttStats, h.getBytesInFlight, h.tracer))
+	}
+}
+
+func (h *sentPacketHandler) setCongestionController(c congestion.SendAlgorithm) {
+	h.congestion = c
+	h.congestionEvent, _ = c.(congestion.CongestionEvent)
+	h.ecnCongestion, _ = c.(congestion.ECNCongestionHandler)
+	if h.maxDatagramSize != 0 {
+		h.setCongestionMaxDatagramSize()
+	}
+}
+
+func (h *sentPacketHandler) setCongestionMaxDatagramSize() {
+	if c, ok := h.congestion.(congestion.MaxDatagramSizeSetter); ok {
+		c.SetMaxDatagramSize(h.maxDatagramSize)
+	}
+}
+
+func (h *sentPacketHandler) getBytesInFlight() protocol.ByteCount {
+	return h.bytesInFlight
 }
 
 func (h *sentPacketHandler) DropPackets(encLevel protocol.EncryptionLevel) {
@@ -199,6 +266,7 @@ func (h *sentPacketHandler) ReceivedBytes(n protocol.ByteCount) {
 }
 
 func (h *sentPacketHandler) ReceivedPacket(encLevel protocol.EncryptionLevel) {
+	h.packetsReceived++
 	if h.perspective == protocol.PerspectiveServer && encLevel == protocol.EncryptionHandshake {
 		h.peerAddressValidated = true
 	}
@@ -217,11 +285,15 @@ func (h *sentPacketHandler) packetsInFlight() int {
 
 func (h *sentPacketHandler) SentPacket(packet *Packet) {
 	h.bytesSent += packet.Length
+	h.packetsSent++
 	// For the client, drop the Initial packet number space when the first Handshake packet is sent.
 	if h.perspective == protocol.PerspectiveClient && packet.EncryptionLevel == protocol.EncryptionHandshake && h.initialPackets != nil {
 		h.dropPackets(protocol.EncryptionInitial)
 	}
 	isAckEliciting := h.sentPacketImpl(packet)
+	if (packet.EncryptionLevel == protocol.Encryption0RTT || packet.EncryptionLevel == protocol.Encryption1RTT) && !packet.IsPathProbePacket {
+		h.ecnTracker.SentPacket(packet.PacketNumber, packet.ECN)
+	}
 	h.getPacketNumberSpace(packet.EncryptionLevel).history.SentPacket(packet, isAckEliciting)
 	if h.tracer != nil && isAckEliciting {
 		h.tracer.UpdatedMetrics(h.rttStats, h.congestion.GetCongestionWindow(), h.bytesInFlight, h.packetsInFlight())
@@ -256,6 +328,9 @@ func (h *sentPacketHandler) sentPacketImpl(packet *Packet) bool /* is ack-elicit
 	pnSpace.largestSent = packet.PacketNumber
 	isAckEliciting := len(packet.Frames) > 0
 
+	if packet.IsPathProbePacket {
+		return isAckEliciting
+	}
 	if isAckEliciting {
 		pnSpace.lastAckElicitingPacketTime = packet.SendTime
 		packet.includedInBytesInFlight = true
@@ -277,6 +352,9 @@ func (h *sentPacketHandler) ReceivedAck(ack *wire.AckFrame, encLevel protocol.En
 		return qerr.NewError(qerr.ProtocolViolation, "Received ACK for an unsent packet")
 	}
 
+	// ECN counts are only processed if the ACK frame increases the largest acknowledged packet number,
+	// since ACK frames might be reordered. See section 13.4.2.1 of RFC 9000.
+	largestAckedIncreased := pnSpace.largestAcked == protocol.InvalidPacketNumber || largestAcked > pnSpace.largestAcked
 	pnSpace.largestAcked = utils.MaxPacketNumber(pnSpace.largestAcked, largestAcked)
 
 	// Servers complete address validation when a protected packet is received.
@@ -295,7 +373,7 @@ func (h *sentPacketHandler) ReceivedAck(ack *wire.AckFrame, encLevel protocol.En
 	}
 	// update the RTT, if the largest acked is newly acknowledged
 	if len(ackedPackets) > 0 {
-		if p := ackedPackets[len(ackedPackets)-1]; p.PacketNumber == ack.LargestAcked() {
+		if p := ackedPackets[len(ackedPackets)-1]; p.PacketNumber == ack.LargestAcked() && !p.IsPathProbePacket {
 			// don't use the ack delay for Initial and Handshake packets
 			var ackDelay time.Duration
 			if encLevel == protocol.Encryption1RTT {
@@ -316,10 +394,20 @@ func (h *sentPacketHandler) ReceivedAck(ack *wire.AckFrame, encLevel protocol.En
 			return fmt.Errorf("received an ACK for skipped packet number: %d (%s)", p.PacketNumber, encLevel)
 		}
 		if p.includedInBytesInFlight && !p.declaredLost {
-			h.congestion.OnPacketAcked(p.PacketNumber, p.Length, priorInFlight, rcvTime)
+			if h.congestionEvent != nil {
+				h.ackedForCongestion = append(h.ackedForCongestion, p.toCongestionPacket())
+			} else {
+				h.congestion.OnPacketAcked(p.PacketNumber, p.Length, priorInFlight, rcvTime)
+			}
 		}
 		h.removeFromBytesInFlight(p)
 	}
+	if encLevel == protocol.Encryption1RTT && largestAckedIncreased {
+		if congested := h.ecnTracker.HandleNewlyAcked(ackedPackets, int64(ack.ECT0), int64(ack.ECT1), int64(ack.ECNCE)); congested {
+			h.onECNCongestion(ackedPackets[len(ackedPackets)-1].PacketNumber, priorInFlight)
+		}
+	}
+	h.onCongestionEvent(priorInFlight, rcvTime)
 
 	// Reset the pto_count unless the client is unsure if the server has validated the client's address.
 	if h.peerCompletedAddressValidation {
@@ -557,8 +645,20 @@ func (h *sentPacketHandler) detectLostPackets(now time.Time, encLevel protocol.E
 			pnSpace.lossTime = lossTime
 		}
 		if packetLost {
-			h.congestion.OnPacketLost(p.PacketNumber, p.Length, priorInFlight)
+			// Packets sent on a previous path are not reported to the congestion controller.
+			// Neither are path MTU probe packets, since they are expected to be lost when they exceed the path MTU.
+			if p.includedInBytesInFlight && !p.IsPathMTUProbePacket {
+				if h.congestionEvent != nil {
+					h.lostForCongestion = append(h.lostForCongestion, p.toCongestionPacket())
+				} else {
+					h.congestion.OnPacketLost(p.PacketNumber, p.Length, priorInFlight)
+				}
+			}
+			if p.EncryptionLevel == protocol.Encryption0RTT || p.EncryptionLevel == protocol.Encryption1RTT {
+				h.ecnTracker.LostPacket(p.PacketNumber)
+			}
 			p.declaredLost = true
+			h.packetsLost++
 			h.queueFramesForRetransmission(p)
 			// the bytes in flight need to be reduced no matter if this packet will be retransmitted
 			h.removeFromBytesInFlight(p)
@@ -567,6 +667,29 @@ func (h *sentPacketHandler) detectLostPackets(now time.Time, encLevel protocol.E
 	})
 }
 
+// onCongestionEvent passes the packets acknowledged and lost since the last call to the congestion controller,
+// if it processes them in a single event.
+func (h *sentPacketHandler) onCongestionEvent(priorInFlight protocol.ByteCount, eventTime time.Time) {
+	if h.congestionEvent == nil || (len(h.ackedForCongestion) == 0 && len(h.lostForCongestion) == 0) {
+		return
+	}
+	h.congestionEvent.OnCongestionEvent(priorInFlight, eventTime, h.ackedForCongestion, h.lostForCongestion)
+	h.ackedForCongestion = h.ackedForCongestion[:0]
+	h.lostForCongestion = h.lostForCongestion[:0]
+}
+
+// onECNCongestion is called when the peer reports an increase of the ECN-CE count.
+// Congestion controllers react to this the same way as to a packet loss, see section 7 of RFC 9002.
+func (h *sentPacketHandler) onECNCongestion(largestAcked protocol.PacketNumber, priorInFlight protocol.ByteCount) {
+	if h.logger.Debug() {
+		h.logger.Debugf("\tECN-CE count increased, largest acked: %d", largestAcked)
+	}
+	if h.ecnCongestion == nil {
+		return
+	}
+	h.ecnCongestion.OnECNCongestion(largestAcked, priorInFlight)
+}
+
 func (h *sentPacketHandler) OnLossDetectionTimeout() error {
 	// When all outstanding are acknowledged, the alarm is canceled in
 	// setLossDetectionTimer. This doesn't reset the timer in the session though.
@@ -591,11 +714,20 @@ func (h *sentPacketHandler) onVerifiedLossDetectionTimeout() error {
 			h.tracer.LossTimerExpired(logging.TimerTypeACK, encLevel)
 		}
 		// Early retransmit or time loss detection
-		return h.detectLostPackets(time.Now(), encLevel)
+		now := time.Now()
+		priorInFlight := h.bytesInFlight
+		if err := h.detectLostPackets(now, encLevel); err != nil {
+			return err
+		}
+		h.onCongestionEvent(priorInFlight, now)
+		return nil
 	}
 
 	// PTO
 	h.ptoCount++
+	if h.ptoCount == mtuBlackHolePTOCount && h.maxDatagramSize != 0 {
+		h.pathMTUBlackHoleDetected = true
+	}
 	if h.bytesInFlight > 0 {
 		_, encLevel = h.getPTOTimeAndSpace()
 		if h.logger.Debug() {
@@ -707,6 +839,46 @@ func (h *sentPacketHandler) HasPacingBudget() bool {
 	return h.congestion.HasPacingBudget()
 }
 
+func (h *sentPacketHandler) ECNMode() protocol.ECN {
+	return h.ecnTracker.Mode()
+}
+
+func (h *sentPacketHandler) EnableAckFrequency(peerMinAckDelay time.Duration) {
+	h.ackFrequency.Enable(peerMinAckDelay)
+}
+
+func (h *sentPacketHandler) GetAckFrequencyFrame(now time.Time) *wire.AckFrequencyFrame {
+	// If no data is in flight, the ACK frequency doesn't matter.
+	// Sending an ACK_FREQUENCY frame would only keep an idle connection alive.
+	if !h.handshakeConfirmed || h.bytesInFlight == 0 {
+		return nil
+	}
+	maxDatagramSize := h.maxDatagramSize
+	if maxDatagramSize == 0 {
+		maxDatagramSize = protocol.MinInitialPacketSize
+	}
+	return h.ackFrequency.GetFrame(now, h.congestion.GetCongestionWindow(), maxDatagramSize)
+}
+
+func (h *sentPacketHandler) GetStats() Stats {
+	stats := Stats{
+		CongestionWindow:     h.congestion.GetCongestionWindow(),
+		BytesInFlight:        h.bytesInFlight,
+		MaxDatagramSize:      h.maxDatagramSize,
+		BytesSent:            h.bytesSent,
+		BytesReceived:        h.bytesReceived,
+		PacketsSent:          h.packetsSent,
+		PacketsReceived:      h.packetsReceived,
+		PacketsLost:          h.packetsLost,
+		PacketsRetransmitted: h.packetsRetransmitted,
+		PTOCount:             h.ptoCount,
+	}
+	if s, ok := h.congestion.(congestion.SlowStartStatsProvider); ok {
+		stats.SlowStart = s.SlowStartStats()
+	}
+	return stats
+}
+
 func (h *sentPacketHandler) isAmplificationLimited() bool {
 	if h.peerAddressValidated {
 		return false
@@ -732,6 +904,7 @@ func (h *sentPacketHandler) queueFramesForRetransmission(p *Packet) {
 	if len(p.Frames) == 0 {
 		panic("no frames")
 	}
+	h.packetsRetransmitted++
 	for _, f := range p.Frames {
 		f.OnLost(f.Frame)
 	}
@@ -786,6 +959,40 @@ func (h *sentPacketHandler) ResetForRetry() error {
 	return nil
 }
 
+func (h *sentPacketHandler) MigratedPath() {
+	// The RTT and the bandwidth of the new path are unknown.
+	h.rttStats.OnConnectionMigration()
+	// Packets sent on the old path don't count towards the congestion window of the new path.
+	h.appDataPackets.history.Iterate(func(p *Packet) (bool, error) {
+		h.removeFromBytesInFlight(p)
+		return true, nil
+	})
+	h.resetCongestionController()
+	h.ecnTracker.MigratedPath()
+	h.ackFrequency.Reset()
+	if h.tracer != nil {
+		if h.ptoCount != 0 {
+			h.tracer.UpdatedPTOCount(0)
+		}
+		h.tracer.UpdatedMetrics(h.rttStats, h.congestion.GetCongestionWindow(), h.bytesInFlight, h.packetsInFlight())
+	}
+	h.ptoCount = 0
+	h.numProbesToSend = 0
+	h.setLossDetectionTimer()
+}
+
+func (h *sentPacketHandler) SetMaxDatagramSize(s protocol.ByteCount) {
+	h.maxDatagramSize = s
+	h.pathMTUBlackHoleDetected = false
+	h.setCongestionMaxDatagramSize()
+}
+
+func (h *sentPacketHandler) DetectedPathMTUBlackHole() bool {
+	detected := h.pathMTUBlackHoleDetected
+	h.pathMTUBlackHoleDetected = false
+	return detected
+}
+
 func (h *sentPacketHandler) SetHandshakeConfirmed() {
 	h.handshakeConfirmed = true
 	// We don't send PTOs for application data packets before the handshake completes.
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/For-ACGN/quic-go/internal/congestion"
 	"github.com/For-ACGN/quic-go/internal/mocks"
 	"github.com/For-ACGN/quic-go/internal/protocol"
 	"github.com/For-ACGN/quic-go/internal/utils"
@@ -27,7 +28,7 @@ var _ = Describe("SentPacketHandler", func() {
 	JustBeforeEach(func() {
 		lostPackets = nil
 		rttStats := utils.NewRTTStats()
-		handler = newSentPacketHandler(42, rttStats, perspective, nil, utils.DefaultLogger)
+		handler = newSentPacketHandler(42, rttStats, perspective, protocol.CongestionControlReno, nil, nil, utils.DefaultLogger)
 		streamFrame = wire.StreamFrame{
 			StreamID: 5,
 			Data:     []byte{0x13, 0x37},
@@ -450,6 +451,60 @@ var _ = Describe("SentPacketHandler", func() {
 			})
 		})
 
+		It("doesn't pass path probe packets to the congestion controller", func() {
+			handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 1, IsPathProbePacket: true}))
+			Expect(handler.bytesInFlight).To(BeZero())
+			ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 1, Largest: 1}}}
+			Expect(handler.ReceivedAck(ack, protocol.Encryption1RTT, time.Now())).To(Succeed())
+			Expect(handler.rttStats.SmoothedRTT()).To(BeZero())
+		})
+
+		It("doesn't report lost path MTU probe packets to the congestion controller", func() {
+			cong.EXPECT().OnPacketSent(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(2)
+			handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 1, SendTime: time.Now().Add(-time.Hour), IsPathMTUProbePacket: true}))
+			handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 2}))
+			// lose packet 1, but don't EXPECT a call to OnPacketLost
+			cong.EXPECT().MaybeExitSlowStart()
+			cong.EXPECT().OnPacketAcked(protocol.PacketNumber(2), gomock.Any(), gomock.Any(), gomock.Any())
+			ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 2, Largest: 2}}}
+			Expect(handler.ReceivedAck(ack, protocol.Encryption1RTT, time.Now())).To(Succeed())
+			Expect(lostPackets).To(Equal([]protocol.PacketNumber{1}))
+			Expect(handler.bytesInFlight).To(BeZero())
+		})
+
+		It("passes the max datagram size to the congestion controller", func() {
+			sender := &maxDatagramSizeSender{MockSendAlgorithmWithDebugInfos: cong}
+			handler.setCongestionController(sender)
+			handler.SetMaxDatagramSize(1400)
+			Expect(sender.maxDatagramSize).To(Equal(protocol.ByteCount(1400)))
+			// the congestion controller for a new path also uses this max datagram size
+			newSender := &maxDatagramSizeSender{MockSendAlgorithmWithDebugInfos: cong}
+			handler.newCongestionControl = func(*utils.RTTStats) congestion.SendAlgorithm { return newSender }
+			handler.MigratedPath()
+			Expect(handler.congestion).To(Equal(newSender))
+			Expect(newSender.maxDatagramSize).To(Equal(protocol.ByteCount(1400)))
+		})
+
+		It("resets the congestion controller and the RTT estimate when migrating to a new path", func() {
+			cong.EXPECT().OnPacketSent(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(2)
+			handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 1, SendTime: time.Now().Add(-time.Hour)}))
+			cong.EXPECT().MaybeExitSlowStart()
+			cong.EXPECT().OnPacketAcked(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
+			ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 1, Largest: 1}}}
+			Expect(handler.ReceivedAck(ack, protocol.Encryption1RTT, time.Now())).To(Succeed())
+			Expect(handler.rttStats.SmoothedRTT()).To(BeNumerically("~", time.Hour, time.Second))
+			handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 2}))
+			Expect(handler.bytesInFlight).To(Equal(protocol.ByteCount(1)))
+			handler.MigratedPath()
+			Expect(handler.congestion).ToNot(Equal(cong))
+			Expect(handler.bytesInFlight).To(BeZero())
+			Expect(handler.rttStats.SmoothedRTT()).To(BeZero())
+			Expect(handler.ptoCount).To(BeZero())
+			// the packet sent on the old path is not declared lost to the new congestion controller
+			ack = &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 2, Largest: 2}}}
+			Expect(handler.ReceivedAck(ack, protocol.Encryption1RTT, time.Now())).To(Succeed())
+		})
+
 		It("should call MaybeExitSlowStart and OnPacketAcked", func() {
 			rcvTime := time.Now().Add(-5 * time.Second)
 			cong.EXPECT().OnPacketSent(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(3)
@@ -582,6 +637,264 @@ var _ = Describe("SentPacketHandler", func() {
 			cong.EXPECT().TimeUntilSend(gomock.Any()).Return(t)
 			Expect(handler.TimeUntilSend()).To(Equal(t))
 		})
+
+		Context("congestion events", func() {
+			type congestionEvent struct {
+				priorInFlight protocol.ByteCount
+				eventTime     time.Time
+				acked, lost   []protocol.PacketNumber
+			}
+
+			var events []congestionEvent
+
+			JustBeforeEach(func() {
+				events = nil
+				handler.setCongestionController(&congestionEventSender{
+					MockSendAlgorithmWithDebugInfos: cong,
+					onCongestionEvent: func(priorInFlight protocol.ByteCount, eventTime time.Time, acked, lost []protocol.Packet) {
+						e := congestionEvent{priorInFlight: priorInFlight, eventTime: eventTime}
+						for _, p := range acked {
+							e.acked = append(e.acked, p.PacketNumber)
+						}
+						for _, p := range lost {
+							e.lost = append(e.lost, p.PacketNumber)
+						}
+						events = append(events, e)
+					},
+				})
+			})
+
+			It("passes acknowledged and lost packets in a single event", func() {
+				rcvTime := time.Now()
+				cong.EXPECT().OnPacketSent(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(4)
+				handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 1, SendTime: time.Now().Add(-time.Hour)}))
+				handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 2}))
+				handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 3}))
+				handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 4}))
+				cong.EXPECT().MaybeExitSlowStart()
+				ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 2, Largest: 3}}}
+				Expect(handler.ReceivedAck(ack, protocol.Encryption1RTT, rcvTime)).To(Succeed())
+				Expect(events).To(Equal([]congestionEvent{{
+					priorInFlight: 4,
+					eventTime:     rcvTime,
+					acked:         []protocol.PacketNumber{2, 3},
+					lost:          []protocol.PacketNumber{1},
+				}}))
+			})
+
+			It("doesn't pass retransmitted packets when they are acknowledged", func() {
+				cong.EXPECT().OnPacketSent(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(2)
+				handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 1, SendTime: time.Now().Add(-time.Hour)}))
+				handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 2}))
+				cong.EXPECT().MaybeExitSlowStart()
+				ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 2, Largest: 2}}}
+				Expect(handler.ReceivedAck(ack, protocol.Encryption1RTT, time.Now())).To(Succeed())
+				Expect(events).To(HaveLen(1))
+				ack = &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 1, Largest: 2}}}
+				Expect(handler.ReceivedAck(ack, protocol.Encryption1RTT, time.Now())).To(Succeed())
+				Expect(events).To(HaveLen(1))
+			})
+
+			It("passes packets declared lost by the loss detection timer", func() {
+				handler.ReceivedPacket(protocol.EncryptionHandshake)
+				handler.SetHandshakeConfirmed()
+				cong.EXPECT().OnPacketSent(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(3)
+				handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 1, SendTime: time.Now().Add(-time.Second)}))
+				handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 2, SendTime: time.Now().Add(-time.Second)}))
+				handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 3, SendTime: time.Now().Add(-time.Second)}))
+				cong.EXPECT().MaybeExitSlowStart()
+				ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 3, Largest: 3}}}
+				Expect(handler.ReceivedAck(ack, protocol.Encryption1RTT, time.Now().Add(-time.Second))).To(Succeed())
+				Expect(events).To(HaveLen(1))
+				Expect(events[0].acked).To(Equal([]protocol.PacketNumber{3}))
+				Expect(events[0].lost).To(BeEmpty())
+				Expect(handler.GetLossDetectionTimeout()).ToNot(BeZero())
+				Expect(handler.OnLossDetectionTimeout()).To(Succeed())
+				Expect(events).To(HaveLen(2))
+				Expect(events[1].priorInFlight).To(Equal(protocol.ByteCount(2)))
+				Expect(events[1].acked).To(BeEmpty())
+				Expect(events[1].lost).To(Equal([]protocol.PacketNumber{1, 2}))
+			})
+		})
+	})
+
+	Context("ECN", func() {
+		var ecnHandler *MockECNHandler
+
+		JustBeforeEach(func() {
+			ecnHandler = NewMockECNHandler(mockCtrl)
+			handler.ecnTracker = ecnHandler
+		})
+
+		It("returns the ECN mode", func() {
+			ecnHandler.EXPECT().Mode().Return(protocol.ECT0)
+			Expect(handler.ECNMode()).To(Equal(protocol.ECT0))
+		})
+
+		It("only passes 0-RTT and 1-RTT packets to the ECN tracker", func() {
+			ecnHandler.EXPECT().SentPacket(protocol.PacketNumber(2), protocol.ECT0)
+			ecnHandler.EXPECT().SentPacket(protocol.PacketNumber(4), protocol.ECT0)
+			handler.SentPacket(initialPacket(&Packet{PacketNumber: 1}))
+			handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 2, EncryptionLevel: protocol.Encryption0RTT, ECN: protocol.ECT0}))
+			handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 3, IsPathProbePacket: true, ECN: protocol.ECT0}))
+			handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 4, ECN: protocol.ECT0}))
+		})
+
+		It("passes the ECN counts to the ECN tracker, if the ACK increases the largest acked", func() {
+			ecnHandler.EXPECT().SentPacket(gomock.Any(), gomock.Any()).Times(3)
+			for i := protocol.PacketNumber(1); i <= 3; i++ {
+				handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: i, ECN: protocol.ECT0}))
+			}
+			ecnHandler.EXPECT().HandleNewlyAcked(gomock.Any(), int64(1), int64(0), int64(1)).Do(func(packets []*Packet, _, _, _ int64) {
+				Expect(packets).To(HaveLen(2))
+				Expect(packets[0].PacketNumber).To(Equal(protocol.PacketNumber(2)))
+				Expect(packets[1].PacketNumber).To(Equal(protocol.PacketNumber(3)))
+			})
+			ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 2, Largest: 3}}, ECT0: 1, ECNCE: 1}
+			Expect(handler.ReceivedAck(ack, protocol.Encryption1RTT, time.Now())).To(Succeed())
+			// this ACK doesn't increase the largest acked, so the ECN counts are ignored
+			ack = &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 1, Largest: 3}}, ECT0: 2, ECNCE: 1}
+			Expect(handler.ReceivedAck(ack, protocol.Encryption1RTT, time.Now())).To(Succeed())
+		})
+
+		It("passes lost packets to the ECN tracker", func() {
+			ecnHandler.EXPECT().SentPacket(gomock.Any(), gomock.Any()).Times(2)
+			handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 1, ECN: protocol.ECT0, SendTime: time.Now().Add(-time.Hour)}))
+			handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 2, ECN: protocol.ECT0}))
+			gomock.InOrder(
+				ecnHandler.EXPECT().LostPacket(protocol.PacketNumber(1)),
+				ecnHandler.EXPECT().HandleNewlyAcked(gomock.Any(), int64(1), int64(0), int64(0)),
+			)
+			ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 2, Largest: 2}}, ECT0: 1}
+			Expect(handler.ReceivedAck(ack, protocol.Encryption1RTT, time.Now())).To(Succeed())
+			Expect(lostPackets).To(Equal([]protocol.PacketNumber{1}))
+		})
+
+		It("reports an increase of the ECN-CE count to the congestion controller", func() {
+			cong := mocks.NewMockSendAlgorithmWithDebugInfos(mockCtrl)
+			var ecnCongestion []protocol.PacketNumber
+			handler.setCongestionController(&ecnCongestionSender{
+				MockSendAlgorithmWithDebugInfos: cong,
+				onECNCongestion: func(largestAcked protocol.PacketNumber, priorInFlight protocol.ByteCount) {
+					Expect(priorInFlight).To(Equal(protocol.ByteCount(3)))
+					ecnCongestion = append(ecnCongestion, largestAcked)
+				},
+			})
+			cong.EXPECT().OnPacketSent(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(3)
+			ecnHandler.EXPECT().SentPacket(gomock.Any(), gomock.Any()).Times(3)
+			for i := protocol.PacketNumber(1); i <= 3; i++ {
+				handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: i, ECN: protocol.ECT0}))
+			}
+			cong.EXPECT().MaybeExitSlowStart()
+			cong.EXPECT().OnPacketAcked(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(2)
+			ecnHandler.EXPECT().HandleNewlyAcked(gomock.Any(), int64(1), int64(0), int64(1)).Return(true)
+			ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 1, Largest: 2}}, ECT0: 1, ECNCE: 1}
+			Expect(handler.ReceivedAck(ack, protocol.Encryption1RTT, time.Now())).To(Succeed())
+			Expect(ecnCongestion).To(Equal([]protocol.PacketNumber{2}))
+		})
+
+		It("ignores an increase of the ECN-CE count if the congestion controller doesn't react to ECN-CE marks", func() {
+			cong := mocks.NewMockSendAlgorithmWithDebugInfos(mockCtrl)
+			handler.congestion = cong
+			cong.EXPECT().OnPacketSent(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
+			ecnHandler.EXPECT().SentPacket(gomock.Any(), gomock.Any())
+			handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 1, ECN: protocol.ECT0}))
+			cong.EXPECT().MaybeExitSlowStart()
+			cong.EXPECT().OnPacketAcked(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
+			ecnHandler.EXPECT().HandleNewlyAcked(gomock.Any(), int64(0), int64(0), int64(1)).Return(true)
+			ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 1, Largest: 1}}, ECNCE: 1}
+			Expect(handler.ReceivedAck(ack, protocol.Encryption1RTT, time.Now())).To(Succeed())
+		})
+
+		It("restarts ECN validation when migrating to a new path", func() {
+			ecnHandler.EXPECT().MigratedPath()
+			handler.MigratedPath()
+		})
+	})
+
+	Context("statistics", func() {
+		It("counts sent, received and lost packets", func() {
+			for i := protocol.PacketNumber(1); i <= 5; i++ {
+				handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: i, Length: 1000}))
+			}
+			handler.ReceivedBytes(1234)
+			handler.ReceivedPacket(protocol.Encryption1RTT)
+			ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 4, Largest: 5}}}
+			Expect(handler.ReceivedAck(ack, protocol.Encryption1RTT, time.Now())).To(Succeed())
+			Expect(lostPackets).To(Equal([]protocol.PacketNumber{1, 2}))
+			stats := handler.GetStats()
+			Expect(stats.BytesSent).To(Equal(protocol.ByteCount(5000)))
+			Expect(stats.BytesReceived).To(Equal(protocol.ByteCount(1234)))
+			Expect(stats.PacketsSent).To(BeEquivalentTo(5))
+			Expect(stats.PacketsReceived).To(BeEquivalentTo(1))
+			Expect(stats.PacketsLost).To(BeEquivalentTo(2))
+			Expect(stats.PacketsRetransmitted).To(BeEquivalentTo(2))
+			Expect(stats.BytesInFlight).To(Equal(protocol.ByteCount(1000)))
+			Expect(stats.CongestionWindow).To(Equal(handler.congestion.GetCongestionWindow()))
+			Expect(stats.PTOCount).To(BeZero())
+			Expect(stats.SlowStart).To(BeZero())
+		})
+
+		It("reports the slow start statistics of BBR", func() {
+			handler = newSentPacketHandler(0, utils.NewRTTStats(), perspective, protocol.CongestionControlBBR, nil, nil, utils.DefaultLogger)
+			Expect(handler.GetStats().SlowStart.Count).To(BeEquivalentTo(1))
+		})
+	})
+
+	Context("ACK frequency", func() {
+		JustBeforeEach(func() {
+			handler.rttStats.SetMaxAckDelay(25 * time.Millisecond)
+			handler.rttStats.UpdateRTT(40*time.Millisecond, 0, time.Now())
+		})
+
+		It("doesn't send ACK_FREQUENCY frames if the peer doesn't support the extension", func() {
+			handler.SetHandshakeConfirmed()
+			Expect(handler.GetAckFrequencyFrame(time.Now())).To(BeNil())
+		})
+
+		It("only sends ACK_FREQUENCY frames after the handshake was confirmed", func() {
+			handler.EnableAckFrequency(time.Millisecond)
+			handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 1}))
+			Expect(handler.GetAckFrequencyFrame(time.Now())).To(BeNil())
+			handler.SetHandshakeConfirmed()
+			f := handler.GetAckFrequencyFrame(time.Now())
+			Expect(f).ToNot(BeNil())
+			Expect(f.RequestMaxAckDelay).To(Equal(10 * time.Millisecond))
+		})
+
+		It("doesn't send ACK_FREQUENCY frames if no data is in flight", func() {
+			handler.EnableAckFrequency(time.Millisecond)
+			handler.SetHandshakeConfirmed()
+			Expect(handler.GetAckFrequencyFrame(time.Now())).To(BeNil())
+		})
+	})
+
+	Context("selecting the congestion controller", func() {
+		It("uses Reno by default", func() {
+			Expect(handler.congestionEvent).To(BeNil())
+			Expect(handler.congestion.GetCongestionWindow()).ToNot(BeZero())
+		})
+
+		It("uses BBR", func() {
+			handler = newSentPacketHandler(0, utils.NewRTTStats(), perspective, protocol.CongestionControlBBR, nil, nil, utils.DefaultLogger)
+			Expect(handler.congestionEvent).ToNot(BeNil())
+			Expect(handler.congestion.(congestion.SendAlgorithmWithDebugInfos).InSlowStart()).To(BeTrue())
+		})
+
+		It("uses a custom congestion controller", func() {
+			rttStats := utils.NewRTTStats()
+			cong := mocks.NewMockSendAlgorithmWithDebugInfos(mockCtrl)
+			var calledWith *utils.RTTStats
+			handler = newSentPacketHandler(0, rttStats, perspective, protocol.CongestionControlBBR, func(r *utils.RTTStats) congestion.SendAlgorithm {
+				calledWith = r
+				return cong
+			}, nil, utils.DefaultLogger)
+			Expect(calledWith).To(Equal(rttStats))
+			Expect(handler.congestion).To(Equal(cong))
+			Expect(handler.congestionEvent).To(BeNil())
+			cong.EXPECT().OnPacketSent(gomock.Any(), protocol.ByteCount(1), protocol.PacketNumber(1), protocol.ByteCount(1), true)
+			handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 1}))
+		})
 	})
 
 	It("doesn't set an alarm if there are no outstanding packets", func() {
@@ -807,6 +1120,33 @@ var _ = Describe("SentPacketHandler", func() {
 		})
 	})
 
+	Context("path MTU black hole detection", func() {
+		JustBeforeEach(func() {
+			handler.ReceivedPacket(protocol.EncryptionHandshake)
+			handler.SetHandshakeConfirmed()
+		})
+
+		It("detects a black hole after repeated PTOs", func() {
+			handler.SetMaxDatagramSize(1400)
+			handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 1, SendTime: time.Now().Add(-time.Hour)}))
+			for i := 0; i < mtuBlackHolePTOCount; i++ {
+				Expect(handler.DetectedPathMTUBlackHole()).To(BeFalse())
+				Expect(handler.OnLossDetectionTimeout()).To(Succeed())
+			}
+			Expect(handler.DetectedPathMTUBlackHole()).To(BeTrue())
+			// only reported once
+			Expect(handler.DetectedPathMTUBlackHole()).To(BeFalse())
+		})
+
+		It("doesn't detect a black hole if path MTU discovery didn't change the max datagram size", func() {
+			handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 1, SendTime: time.Now().Add(-time.Hour)}))
+			for i := 0; i < mtuBlackHolePTOCount; i++ {
+				Expect(handler.OnLossDetectionTimeout()).To(Succeed())
+			}
+			Expect(handler.DetectedPathMTUBlackHole()).To(BeFalse())
+		})
+	})
+
 	Context("amplification limit", func() {
 		BeforeEach(func() {
 			perspective = protocol.PerspectiveClient
@@ -1199,3 +1539,30 @@ var _ = Describe("SentPacketHandler", func() {
 		})
 	})
 })
+
+type congestionEventSender struct {
+	*mocks.MockSendAlgorithmWithDebugInfos
+	onCongestionEvent func(priorInFlight protocol.ByteCount, eventTime time.Time, acked, lost []protocol.Packet)
+}
+
+func (s *congestionEventSender) OnCongestionEvent(priorInFlight protocol.ByteCount, eventTime time.Time, acked, lost []protocol.Packet) {
+	s.onCongestionEvent(priorInFlight, eventTime, acked, lost)
+}
+
+type ecnCongestionSender struct {
+	*mocks.MockSendAlgorithmWithDebugInfos
+	onECNCongestion func(largestAcked protocol.PacketNumber, priorInFlight protocol.ByteCount)
+}
+
+func (s *ecnCongestionSender) OnECNCongestion(largestAcked protocol.PacketNumber, priorInFlight protocol.ByteCount) {
+	s.onECNCongestion(largestAcked, priorInFlight)
+}
+
+type maxDatagramSizeSender struct {
+	*mocks.MockSendAlgorithmWithDebugInfos
+	maxDatagramSize protocol.ByteCount
+}
+
+func (s *maxDatagramSizeSender) SetMaxDatagramSize(size protocol.ByteCount) {
+	s.maxDatagramSize = size
+}
@@ -8,6 +8,8 @@ import (
 	"time"
 
 	"github.com/For-ACGN/quic-go/internal/protocol"
+	"github.com/For-ACGN/quic-go/internal/utils"
+	"github.com/For-ACGN/quic-go/logging"
 )
 
 var (
@@ -99,10 +101,26 @@ const (
 	GROWTH
 )
 
+// slowStartStats are the statistics collected about the STARTUP mode.
+type slowStartStats struct {
+	// The number of times STARTUP was entered.
+	count int
+	// The number of round trips spent in STARTUP.
+	numRtts int64
+	// The number of packets and bytes lost while in STARTUP.
+	packetsLost int
+	bytesLost   protocol.ByteCount
+	// The total time spent in STARTUP, not including the current period.
+	duration time.Duration
+	// The time the current STARTUP period began. Zero if not in STARTUP.
+	startTime time.Time
+}
+
 type bbrSender struct {
 	mode     bbrMode
 	clock    Clock
-	rttStats *RTTStats
+	rttStats *utils.RTTStats
+	pacer    *pacer
 	// return total bytes of unacked packets.
 	GetBytesInFlight func() protocol.ByteCount
 	// Bandwidth sampler provides BBR with the bandwidth measurements at
@@ -193,6 +211,9 @@ type bbrSender struct {
 	recoveryWindow protocol.ByteCount
 	// If true, consider all samples in recovery app-limited.
 	isAppLimitedRecovery bool
+	// Set when the peer reported ECN-CE marks since the last congestion event.
+	// ECN-CE marks are treated like losses when processing the next congestion event.
+	ecnCongestion bool
 	// When true, pace at 1.5x and disable packet conservation in STARTUP.
 	slowerStartup bool
 	// When true, disables packet conservation in STARTUP.
@@ -224,15 +245,34 @@ type bbrSender struct {
 	minRttSinceLastProbeRtt      time.Duration
 	// Latched value of --quic_always_get_bw_sample_when_acked.
 	alwaysGetBwSampleWhenAcked bool
+
+	stats slowStartStats
+
+	lastState logging.CongestionState
+	tracer    logging.ConnectionTracer
+}
+
+var (
+	_ SendAlgorithm               = &bbrSender{}
+	_ SendAlgorithmWithDebugInfos = &bbrSender{}
+	_ CongestionEvent             = &bbrSender{}
+	_ MaxDatagramSizeSetter       = &bbrSender{}
+	_ ECNCongestionHandler        = &bbrSender{}
+)
+
+// NewBBRSender makes a new BBR sender
+func NewBBRSender(clock Clock, rttStats *utils.RTTStats, getBytesInFlight func() protocol.ByteCount, tracer logging.ConnectionTracer) *bbrSender {
+	return newBBRSender(clock, rttStats, initialCongestionWindow, maxCongestionWindow, getBytesInFlight, tracer)
 }
 
-func NewBBRSender(clock Clock, rttStats *RTTStats, initialCongestionWindow, maxCongestionWindow protocol.ByteCount, getBytesInFlight func() protocol.ByteCount) *bbrSender {
-	return &bbrSender{
+func newBBRSender(clock Clock, rttStats *utils.RTTStats, initialCongestionWindow, maxCongestionWindow protocol.ByteCount, getBytesInFlight func() protocol.ByteCount, tracer logging.ConnectionTracer) *bbrSender {
+	b := &bbrSender{
 		rttStats:                  rttStats,
 		GetBytesInFlight:          getBytesInFlight,
-		mode:                      STARTUP,
 		clock:                     clock,
 		sampler:                   NewBandwidthSampler(),
+		lastSendPacket:            protocol.InvalidPacketNumber,
+		currentRoundTripEnd:       protocol.InvalidPacketNumber,
 		maxBandwidth:              NewWindowedFilter(int64(BandwidthWindowSize), MaxFilter),
 		maxAckHeight:              NewWindowedFilter(int64(BandwidthWindowSize), MaxFilter),
 		congestionWindow:          initialCongestionWindow,
@@ -247,19 +287,55 @@ func NewBBRSender(clock Clock, rttStats *RTTStats, initialCongestionWindow, maxC
 		congestionWindowGainConst: DefaultCongestionWindowGainConst,
 		numStartupRtts:            RoundTripsWithoutGrowthBeforeExitingStartup,
 		recoveryState:             NOT_IN_RECOVERY,
+		endRecoveryAt:             protocol.InvalidPacketNumber,
 		recoveryWindow:            maxCongestionWindow,
 		minRttSinceLastProbeRtt:   InfiniteRTT,
+		tracer:                    tracer,
+	}
+	// The pacer paces at 5/4 of the bandwidth it is given.
+	// BBR already applies its own pacing gain, so undo this adjustment.
+	b.pacer = newPacer(func() Bandwidth { return b.PacingRate() * 4 / 5 })
+	b.EnterStartupMode(clock.Now())
+	if b.tracer != nil {
+		b.lastState = logging.CongestionStateSlowStart
+		b.tracer.UpdatedCongestionState(logging.CongestionStateSlowStart)
 	}
+	return b
 }
 
-func (b *bbrSender) TimeUntilSend(bytesInFlight protocol.ByteCount) time.Duration {
-	return time.Microsecond
+// TimeUntilSend returns when the next packet should be sent.
+func (b *bbrSender) TimeUntilSend(_ protocol.ByteCount) time.Time {
+	return b.pacer.TimeUntilSend()
+}
+
+func (b *bbrSender) HasPacingBudget() bool {
+	return b.pacer.Budget(b.clock.Now()) >= b.pacer.maxDatagramSize
+}
+
+// SetMaxDatagramSize sets the maximum size of the packets sent.
+// The congestion window is tracked in units of MaxSegmentSize, so only the pacer needs to be updated.
+func (b *bbrSender) SetMaxDatagramSize(s protocol.ByteCount) {
+	b.pacer.SetMaxDatagramSize(s)
+}
+
+// PacingRate returns the rate at which packets are sent.
+func (b *bbrSender) PacingRate() Bandwidth {
+	if b.pacingRate == 0 {
+		return Bandwidth(b.highGain * float64(BandwidthFromDelta(b.initialCongestionWindow, b.GetMinRtt())))
+	}
+	return b.pacingRate
 }
 
 func (b *bbrSender) OnPacketSent(sentTime time.Time, bytesInFlight protocol.ByteCount, packetNumber protocol.PacketNumber, bytes protocol.ByteCount, isRetransmittable bool) {
+	b.pacer.SentPacket(sentTime, bytes)
 	b.lastSendPacket = packetNumber
 
-	if bytesInFlight == 0 && b.sampler.isAppLimited {
+	// bytesInFlight already includes this packet, if it is retransmittable.
+	priorInFlight := bytesInFlight
+	if isRetransmittable {
+		priorInFlight -= bytes
+	}
+	if priorInFlight == 0 && b.sampler.isAppLimited {
 		b.exitingQuiescence = true
 	}
 
@@ -267,7 +343,7 @@ func (b *bbrSender) OnPacketSent(sentTime time.Time, bytesInFlight protocol.Byte
 		b.aggregationEpochStartTime = sentTime
 	}
 
-	b.sampler.OnPacketSent(sentTime, packetNumber, bytes, bytesInFlight, isRetransmittable)
+	b.sampler.OnPacketSent(sentTime, packetNumber, bytes, priorInFlight, isRetransmittable)
 }
 
 func (b *bbrSender) CanSend(bytesInFlight protocol.ByteCount) bool {
@@ -298,9 +374,13 @@ func (b *bbrSender) OnPacketLost(number protocol.PacketNumber, lostBytes protoco
 	panic("should call OnCongestionEvent()")
 }
 
-func (b *bbrSender) OnCongestionEvent(priorInFlight protocol.ByteCount, eventTime time.Time, ackedPackets, lostPackets []*protocol.Packet) {
+// OnCongestionEvent processes all packets acknowledged and declared lost by a single ACK frame,
+// or by a single firing of the loss detection timer.
+func (b *bbrSender) OnCongestionEvent(priorInFlight protocol.ByteCount, eventTime time.Time, ackedPackets, lostPackets []protocol.Packet) {
 	totalBytesAckedBefore := b.sampler.totalBytesAcked
 	isRoundStart, minRttExpired := false, false
+	hasLosses := len(lostPackets) > 0 || b.ecnCongestion
+	b.ecnCongestion = false
 
 	if lostPackets != nil {
 		b.DiscardLostPackets(lostPackets)
@@ -312,14 +392,14 @@ func (b *bbrSender) OnCongestionEvent(priorInFlight protocol.ByteCount, eventTim
 		lastAckedPacket := ackedPackets[len(ackedPackets)-1].PacketNumber
 		isRoundStart = b.UpdateRoundTripCounter(lastAckedPacket)
 		minRttExpired = b.UpdateBandwidthAndMinRtt(eventTime, ackedPackets)
-		b.UpdateRecoveryState(lastAckedPacket, len(lostPackets) > 0, isRoundStart)
+		b.UpdateRecoveryState(lastAckedPacket, hasLosses, isRoundStart)
 		bytesAcked := b.sampler.totalBytesAcked - totalBytesAckedBefore
 		excessAcked = b.UpdateAckAggregationBytes(eventTime, bytesAcked)
 	}
 
 	// Handle logic specific to PROBE_BW mode.
 	if b.mode == PROBE_BW {
-		b.UpdateGainCyclePhase(eventTime, priorInFlight, len(lostPackets) > 0)
+		b.UpdateGainCyclePhase(eventTime, priorInFlight, hasLosses)
 	}
 
 	// Handle logic specific to STARTUP and DRAIN modes.
@@ -343,16 +423,25 @@ func (b *bbrSender) OnCongestionEvent(priorInFlight protocol.ByteCount, eventTim
 	b.CalculatePacingRate()
 	b.CalculateCongestionWindow(bytesAcked, excessAcked)
 	b.CalculateRecoveryWindow(bytesAcked, bytesLost)
-}
 
-func (b *bbrSender) SetNumEmulatedConnections(n int) {
+	b.maybeTraceStateChange()
+}
 
+// OnECNCongestion is called when the peer reports ECN-CE marks.
+// It is called before the packets acknowledged by the same ACK frame are processed in OnCongestionEvent.
+// Like a loss, this starts the recovery, and prevents the gain cycle from probing for more bandwidth.
+func (b *bbrSender) OnECNCongestion(protocol.PacketNumber, protocol.ByteCount) {
+	b.ecnCongestion = true
 }
 
-func (b *bbrSender) OnRetransmissionTimeout(packetsRetransmitted bool) {
+func (b *bbrSender) SetNumEmulatedConnections(n int) {
 
 }
 
+// OnRetransmissionTimeout is called on an retransmission timeout.
+// BBR doesn't react to retransmission timeouts, losses are handled in OnCongestionEvent.
+func (b *bbrSender) OnRetransmissionTimeout(packetsRetransmitted bool) {}
+
 func (b *bbrSender) OnConnectionMigration() {
 
 }
@@ -386,6 +475,21 @@ func (b *bbrSender) InSlowStart() bool {
 	return b.mode == STARTUP
 }
 
+// SlowStartStats returns the statistics collected about the STARTUP mode.
+func (b *bbrSender) SlowStartStats() SlowStartStats {
+	duration := b.stats.duration
+	if !b.stats.startTime.IsZero() {
+		duration += b.clock.Now().Sub(b.stats.startTime)
+	}
+	return SlowStartStats{
+		Count:       uint64(b.stats.count),
+		RTTs:        uint64(b.stats.numRtts),
+		PacketsLost: uint64(b.stats.packetsLost),
+		BytesLost:   b.stats.bytesLost,
+		Duration:    duration,
+	}
+}
+
 func (b *bbrSender) ShouldSendProbingPacket() bool {
 	if b.pacingGain <= 1 {
 		return false
@@ -421,18 +525,18 @@ func (b *bbrSender) SetFromConfig() {
 }
 
 func (b *bbrSender) UpdateRoundTripCounter(lastAckedPacket protocol.PacketNumber) bool {
-	if b.currentRoundTripEnd == 0 || lastAckedPacket > b.currentRoundTripEnd {
-		b.currentRoundTripEnd = lastAckedPacket
+	if lastAckedPacket > b.currentRoundTripEnd {
+		b.currentRoundTripEnd = b.lastSendPacket
 		b.roundTripCount++
-		// if b.rttStats != nil && b.InSlowStart() {
-		// TODO: ++stats_->slowstart_num_rtts;
-		// }
+		if b.InSlowStart() {
+			b.stats.numRtts++
+		}
 		return true
 	}
 	return false
 }
 
-func (b *bbrSender) UpdateBandwidthAndMinRtt(now time.Time, ackedPackets []*protocol.Packet) bool {
+func (b *bbrSender) UpdateBandwidthAndMinRtt(now time.Time, ackedPackets []protocol.Packet) bool {
 	sampleMinRtt := InfiniteRTT
 
 	for _, packet := range ackedPackets {
@@ -500,13 +604,12 @@ func (b *bbrSender) ShouldExtendMinRttExpiry() bool {
 	return false
 }
 
-func (b *bbrSender) DiscardLostPackets(lostPackets []*protocol.Packet) {
+func (b *bbrSender) DiscardLostPackets(lostPackets []protocol.Packet) {
 	for _, packet := range lostPackets {
 		b.sampler.OnPacketLost(packet.PacketNumber)
 		if b.mode == STARTUP {
-			// if b.rttStats != nil {
-			// TODO: slow start.
-			// }
+			b.stats.packetsLost++
+			b.stats.bytesLost += packet.Length
 			if b.startupRateReductionMultiplier != 0 {
 				b.startupBytesLost += packet.Length
 			}
@@ -516,7 +619,7 @@ func (b *bbrSender) DiscardLostPackets(lostPackets []*protocol.Packet) {
 
 func (b *bbrSender) UpdateRecoveryState(lastAckedPacket protocol.PacketNumber, hasLosses, isRoundStart bool) {
 	// Exit recovery when there are no losses for a round.
-	if !hasLosses {
+	if hasLosses {
 		b.endRecoveryAt = b.lastSendPacket
 	}
 	switch b.recoveryState {
@@ -541,7 +644,7 @@ func (b *bbrSender) UpdateRecoveryState(lastAckedPacket protocol.PacketNumber, h
 		fallthrough
 	case GROWTH:
 		// Exit recovery if appropriate.
-		if !hasLosses && b.lastSendPacket > b.endRecoveryAt {
+		if !hasLosses && lastAckedPacket > b.endRecoveryAt {
 			b.recoveryState = NOT_IN_RECOVERY
 			b.isAppLimitedRecovery = false
 		}
@@ -555,8 +658,7 @@ func (b *bbrSender) UpdateRecoveryState(lastAckedPacket protocol.PacketNumber, h
 func (b *bbrSender) UpdateAckAggregationBytes(ackTime time.Time, ackedBytes protocol.ByteCount) protocol.ByteCount {
 	// Compute how many bytes are expected to be delivered, assuming max bandwidth
 	// is correct.
-	expectedAckedBytes := protocol.ByteCount(b.maxBandwidth.GetBest()) *
-		protocol.ByteCount((ackTime.Sub(b.aggregationEpochStartTime)))
+	expectedAckedBytes := bytesPerPeriod(Bandwidth(b.maxBandwidth.GetBest()), ackTime.Sub(b.aggregationEpochStartTime))
 	// Reset the current aggregation epoch as soon as the ack arrival rate is less
 	// than or equal to the max bandwidth.
 	if b.aggregationEpochBytes <= expectedAckedBytes {
@@ -607,7 +709,7 @@ func (b *bbrSender) UpdateGainCyclePhase(now time.Time, priorInFlight protocol.B
 }
 
 func (b *bbrSender) GetTargetCongestionWindow(gain float64) protocol.ByteCount {
-	bdp := protocol.ByteCount(b.GetMinRtt()) * protocol.ByteCount(b.BandwidthEstimate())
+	bdp := bytesPerPeriod(b.BandwidthEstimate(), b.GetMinRtt())
 	congestionWindow := protocol.ByteCount(gain * float64(bdp))
 
 	// BDP estimate will be zero if no bandwidth samples are available yet.
@@ -716,19 +818,19 @@ func (b *bbrSender) ProbeRttCongestionWindow() protocol.ByteCount {
 }
 
 func (b *bbrSender) EnterStartupMode(now time.Time) {
-	// if b.rttStats != nil {
-	// TODO: slow start.
-	// }
+	b.stats.count++
+	b.stats.startTime = now
 	b.mode = STARTUP
 	b.pacingGain = b.highGain
 	b.congestionWindowGain = b.highCwndGain
 }
 
 func (b *bbrSender) OnExitStartup(now time.Time) {
-	if b.rttStats == nil {
+	if b.stats.startTime.IsZero() {
 		return
 	}
-	// TODO: slow start.
+	b.stats.duration += now.Sub(b.stats.startTime)
+	b.stats.startTime = time.Time{}
 }
 
 func (b *bbrSender) CalculatePacingRate() {
@@ -745,11 +847,11 @@ func (b *bbrSender) CalculatePacingRate() {
 	// Pace at the rate of initial_window / RTT as soon as RTT measurements are
 	// available.
 	if b.pacingRate == 0 && b.rttStats.MinRTT() > 0 {
-		b.pacingRate = BandwidthFromDelta(b.initialCongestionWindow, b.rttStats.MinRTT())
+		b.pacingRate = Bandwidth(b.highGain * float64(BandwidthFromDelta(b.initialCongestionWindow, b.rttStats.MinRTT())))
 		return
 	}
 	// Slow the pacing rate in STARTUP once loss has ever been detected.
-	hasEverDetectedLoss := b.endRecoveryAt > 0
+	hasEverDetectedLoss := b.endRecoveryAt != protocol.InvalidPacketNumber
 	if b.slowerStartup && hasEverDetectedLoss && b.hasNoAppLimitedSample {
 		b.pacingRate = Bandwidth(StartupAfterLossGain * float64(b.BandwidthEstimate()))
 		return
@@ -841,6 +943,31 @@ func (b *bbrSender) GetMinRtt() time.Duration {
 	}
 }
 
+func (b *bbrSender) maybeTraceStateChange() {
+	if b.tracer == nil {
+		return
+	}
+	var state logging.CongestionState
+	switch {
+	case b.InRecovery():
+		state = logging.CongestionStateRecovery
+	case b.InSlowStart():
+		state = logging.CongestionStateSlowStart
+	default:
+		state = logging.CongestionStateCongestionAvoidance
+	}
+	if state == b.lastState {
+		return
+	}
+	b.tracer.UpdatedCongestionState(state)
+	b.lastState = state
+}
+
+// bytesPerPeriod returns the number of bytes that can be sent in the given period at the given bandwidth.
+func bytesPerPeriod(bandwidth Bandwidth, period time.Duration) protocol.ByteCount {
+	return protocol.ByteCount(float64(bandwidth/BytesPerSecond) * period.Seconds())
+}
+
 func minRtt(a, b time.Duration) time.Duration {
 	if a < b {
 		return a
@@ -0,0 +1,267 @@
+package congestion
+
+import (
+	"time"
+
+	"github.com/For-ACGN/quic-go/internal/mocks/logging"
+	"github.com/For-ACGN/quic-go/internal/protocol"
+	"github.com/For-ACGN/quic-go/internal/utils"
+	"github.com/For-ACGN/quic-go/logging"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("BBR Sender", func() {
+	const rtt = 50 * time.Millisecond
+
+	var (
+		sender        *bbrSender
+		clock         mockClock
+		bytesInFlight protocol.ByteCount
+		packetNumber  protocol.PacketNumber
+		sentPackets   []protocol.Packet
+		rttStats      *utils.RTTStats
+	)
+
+	BeforeEach(func() {
+		bytesInFlight = 0
+		packetNumber = 1
+		sentPackets = nil
+		clock = mockClock{}
+		clock.Advance(time.Hour)
+		rttStats = utils.NewRTTStats()
+		sender = newBBRSender(
+			&clock,
+			rttStats,
+			initialCongestionWindowPackets*initialMaxDatagramSize,
+			MaxCongestionWindow,
+			func() protocol.ByteCount { return bytesInFlight },
+			nil,
+		)
+	})
+
+	sendPacket := func() {
+		bytesInFlight += initialMaxDatagramSize
+		sender.OnPacketSent(clock.Now(), bytesInFlight, packetNumber, initialMaxDatagramSize, true)
+		sentPackets = append(sentPackets, protocol.Packet{
+			PacketNumber: packetNumber,
+			Length:       initialMaxDatagramSize,
+			SendTime:     clock.Now(),
+		})
+		packetNumber++
+	}
+
+	sendNPackets := func(n int) {
+		for i := 0; i < n; i++ {
+			sendPacket()
+		}
+	}
+
+	sendAvailableSendWindow := func() int {
+		var packetsSent int
+		for sender.CanSend(bytesInFlight) {
+			sendPacket()
+			packetsSent++
+		}
+		return packetsSent
+	}
+
+	// ackAndLose acknowledges the first numAcked outstanding packets,
+	// and declares the numLost packets following them lost.
+	ackAndLose := func(numAcked, numLost int) {
+		rttStats.UpdateRTT(clock.Now().Sub(sentPackets[0].SendTime), 0, clock.Now())
+		acked := sentPackets[:numAcked]
+		lost := sentPackets[numAcked : numAcked+numLost]
+		priorInFlight := bytesInFlight
+		bytesInFlight -= protocol.ByteCount(numAcked+numLost) * initialMaxDatagramSize
+		sender.OnCongestionEvent(priorInFlight, clock.Now(), acked, lost)
+		sentPackets = sentPackets[numAcked+numLost:]
+	}
+
+	ackNPackets := func(n int) { ackAndLose(n, 0) }
+
+	// sendAndAckRound sends n packets, and acknowledges all of them one RTT later.
+	sendAndAckRound := func(n int) {
+		sendNPackets(n)
+		clock.Advance(rtt)
+		ackNPackets(len(sentPackets))
+	}
+
+	It("has the right values at startup", func() {
+		Expect(sender.GetCongestionWindow()).To(Equal(defaultWindowTCP))
+		Expect(sender.InSlowStart()).To(BeTrue())
+		Expect(sender.InRecovery()).To(BeFalse())
+		Expect(sender.TimeUntilSend(0)).To(BeZero())
+		Expect(sender.CanSend(bytesInFlight)).To(BeTrue())
+		Expect(sender.BandwidthEstimate()).To(BeZero())
+		Expect(sender.stats.count).To(Equal(1))
+		clock.Advance(rtt)
+		Expect(sender.SlowStartStats()).To(Equal(SlowStartStats{Count: 1, Duration: rtt}))
+
+		// Fill the send window with data, then verify that we can't send.
+		Expect(sendAvailableSendWindow()).To(Equal(initialCongestionWindowPackets))
+		Expect(sender.CanSend(bytesInFlight)).To(BeFalse())
+	})
+
+	It("paces", func() {
+		sendAndAckRound(2)
+		sendAvailableSendWindow()
+		Expect(sender.HasPacingBudget()).To(BeFalse())
+		delay := sender.TimeUntilSend(bytesInFlight)
+		Expect(delay).ToNot(BeZero())
+		Expect(delay).To(BeTemporally(">", clock.Now()))
+		Expect(delay).To(BeTemporally("<", clock.Now().Add(rtt)))
+	})
+
+	It("paces at the high gain before a bandwidth estimate is available", func() {
+		Expect(sender.PacingRate()).To(Equal(Bandwidth(DefaultHighGain * float64(BandwidthFromDelta(defaultWindowTCP, InitialRtt)))))
+	})
+
+	It("grows the congestion window by the acknowledged bytes in STARTUP", func() {
+		sendAvailableSendWindow()
+		clock.Advance(rtt)
+		ackNPackets(initialCongestionWindowPackets)
+		Expect(sender.GetCongestionWindow()).To(Equal(2 * defaultWindowTCP))
+		Expect(sendAvailableSendWindow()).To(Equal(2 * initialCongestionWindowPackets))
+		clock.Advance(rtt)
+		ackNPackets(2 * initialCongestionWindowPackets)
+		Expect(sender.GetCongestionWindow()).To(Equal(4 * defaultWindowTCP))
+		Expect(sender.InSlowStart()).To(BeTrue())
+		Expect(sender.BandwidthEstimate()).ToNot(BeZero())
+	})
+
+	It("never grows the congestion window beyond the maximum", func() {
+		for i := 0; i < 10; i++ {
+			sendAvailableSendWindow()
+			clock.Advance(rtt)
+			ackNPackets(len(sentPackets))
+		}
+		Expect(sender.GetCongestionWindow()).To(Equal(MaxCongestionWindow))
+	})
+
+	It("exits STARTUP once the bandwidth stops growing", func() {
+		// Sending the same amount of data every round trip results in a constant bandwidth estimate.
+		for i := 0; i < int(RoundTripsWithoutGrowthBeforeExitingStartup); i++ {
+			Expect(sender.InSlowStart()).To(BeTrue())
+			sendAndAckRound(5)
+		}
+		Expect(sender.isAtFullBandwidth).To(BeFalse())
+		sendAndAckRound(5)
+		Expect(sender.isAtFullBandwidth).To(BeTrue())
+		Expect(sender.InSlowStart()).To(BeFalse())
+		// All data was acknowledged, so there's no queue to drain.
+		Expect(sender.mode).To(BeEquivalentTo(PROBE_BW))
+		Expect(sender.BandwidthEstimate()).To(Equal(BandwidthFromDelta(5*initialMaxDatagramSize, rtt)))
+		Expect(sender.stats.numRtts).To(BeEquivalentTo(RoundTripsWithoutGrowthBeforeExitingStartup + 1))
+		Expect(sender.stats.duration).To(Equal(time.Duration(RoundTripsWithoutGrowthBeforeExitingStartup+1) * rtt))
+		Expect(sender.stats.startTime).To(BeZero())
+		Expect(sender.SlowStartStats()).To(Equal(SlowStartStats{
+			Count:    1,
+			RTTs:     uint64(RoundTripsWithoutGrowthBeforeExitingStartup + 1),
+			Duration: time.Duration(RoundTripsWithoutGrowthBeforeExitingStartup+1) * rtt,
+		}))
+	})
+
+	It("sets the congestion window based on the bandwidth-delay product in PROBE_BW", func() {
+		for i := 0; i < 5; i++ {
+			sendAndAckRound(20)
+		}
+		Expect(sender.mode).To(BeEquivalentTo(PROBE_BW))
+		// The window converges towards the target window, starting from the window reached in STARTUP.
+		bdp := 20 * initialMaxDatagramSize
+		target := protocol.ByteCount(DefaultCongestionWindowGainConst * float64(bdp))
+		Expect(sender.GetCongestionWindow()).To(BeNumerically(">=", target))
+		for i := 0; i < 10; i++ {
+			sendAndAckRound(20)
+		}
+		Expect(sender.GetTargetCongestionWindow(1)).To(Equal(bdp))
+		Expect(sender.GetCongestionWindow()).To(BeNumerically("~", target, initialMaxDatagramSize+protocol.ByteCount(sender.maxAckHeight.GetBest())))
+	})
+
+	It("enters recovery on packet loss, and exits after a round without losses", func() {
+		sendAndAckRound(10)
+		sendNPackets(10)
+		clock.Advance(rtt)
+		ackAndLose(5, 1)
+		Expect(sender.InRecovery()).To(BeTrue())
+		Expect(sender.recoveryState).To(BeEquivalentTo(CONSERVATION))
+		Expect(sender.stats.packetsLost).To(Equal(1))
+		Expect(sender.stats.bytesLost).To(Equal(initialMaxDatagramSize))
+		// In recovery, the window is limited to the bytes in flight plus the newly acknowledged bytes.
+		Expect(sender.GetCongestionWindow()).To(Equal(bytesInFlight + 5*initialMaxDatagramSize))
+		// acknowledge the rest of the packets sent before the loss
+		ackNPackets(len(sentPackets))
+		Expect(sender.InRecovery()).To(BeTrue())
+		// Another round trip without losses ends recovery.
+		sendAndAckRound(5)
+		Expect(sender.InRecovery()).To(BeFalse())
+	})
+
+	It("enters recovery on ECN-CE marks", func() {
+		sendAndAckRound(10)
+		sendNPackets(10)
+		clock.Advance(rtt)
+		sender.OnECNCongestion(sentPackets[4].PacketNumber, bytesInFlight)
+		Expect(sender.InRecovery()).To(BeFalse())
+		ackNPackets(5)
+		Expect(sender.InRecovery()).To(BeTrue())
+		Expect(sender.recoveryState).To(BeEquivalentTo(CONSERVATION))
+		Expect(sender.GetCongestionWindow()).To(Equal(bytesInFlight + 5*initialMaxDatagramSize))
+		// ECN-CE marks are only taken into account for a single congestion event
+		ackNPackets(len(sentPackets))
+		sendAndAckRound(5)
+		Expect(sender.InRecovery()).To(BeFalse())
+	})
+
+	It("doesn't change the congestion window on a retransmission timeout", func() {
+		sendAvailableSendWindow()
+		sender.OnRetransmissionTimeout(true)
+		Expect(sender.GetCongestionWindow()).To(Equal(defaultWindowTCP))
+	})
+
+	It("enters PROBE_RTT when the min RTT expires", func() {
+		for i := 0; i < 5; i++ {
+			sendAndAckRound(10)
+		}
+		Expect(sender.mode).To(BeEquivalentTo(PROBE_BW))
+		// Let the min RTT expire. The next RTT sample is larger than the min RTT.
+		sendNPackets(10)
+		clock.Advance(MinRttExpiry)
+		ackNPackets(len(sentPackets))
+		Expect(sender.mode).To(BeEquivalentTo(PROBE_RTT))
+		Expect(sender.GetCongestionWindow()).To(Equal(sender.minCongestionWindow))
+		// PROBE_RTT lasts for at least ProbeRttTime and one round trip.
+		sendAndAckRound(1)
+		Expect(sender.mode).To(BeEquivalentTo(PROBE_RTT))
+		clock.Advance(ProbeRttTime)
+		sendAndAckRound(1)
+		Expect(sender.mode).To(BeEquivalentTo(PROBE_BW))
+	})
+
+	It("traces state changes", func() {
+		tracer := mocklogging.NewMockConnectionTracer(mockCtrl)
+		tracer.EXPECT().UpdatedCongestionState(logging.CongestionStateSlowStart)
+		sender = newBBRSender(
+			&clock,
+			rttStats,
+			initialCongestionWindowPackets*initialMaxDatagramSize,
+			MaxCongestionWindow,
+			func() protocol.ByteCount { return bytesInFlight },
+			tracer,
+		)
+		sendAndAckRound(10)
+		sendNPackets(10)
+		clock.Advance(rtt)
+		tracer.EXPECT().UpdatedCongestionState(logging.CongestionStateRecovery)
+		ackAndLose(5, 1)
+		ackNPackets(len(sentPackets))
+		tracer.EXPECT().UpdatedCongestionState(logging.CongestionStateSlowStart)
+		sendAndAckRound(5)
+		tracer.EXPECT().UpdatedCongestionState(logging.CongestionStateCongestionAvoidance)
+		for i := 0; i < 5; i++ {
+			sendAndAckRound(5)
+		}
+		Expect(sender.InSlowStart()).To(BeFalse())
+	})
+})
@@ -3,6 +3,7 @@ package congestion
 import (
 	"testing"
 
+	"github.com/golang/mock/gomock"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
@@ -11,3 +12,13 @@ func TestCongestion(t *testing.T) {
 	RegisterFailHandler(Fail)
 	RunSpecs(t, "Congestion Suite")
 }
+
+var mockCtrl *gomock.Controller
+
+var _ = BeforeEach(func() {
+	mockCtrl = gomock.NewController(GinkgoT())
+})
+
+var _ = AfterEach(func() {
+	mockCtrl.Finish()
+})
@@ -9,14 +9,14 @@ import (
 )
 
 const (
-	// maxDatagramSize is the default maximum packet size used in the Linux TCP implementation.
+	// initialMaxDatagramSize is the default maximum packet size used in the Linux TCP implementation.
 	// Used in QUIC for congestion window computations in bytes.
-	maxDatagramSize         = protocol.ByteCount(protocol.MaxPacketSizeIPv4)
-	maxBurstBytes           = 3 * maxDatagramSize
-	renoBeta                = 0.7 // Reno backoff factor.
-	maxCongestionWindow     = protocol.MaxCongestionWindowPackets * maxDatagramSize
-	minCongestionWindow     = 2 * maxDatagramSize
-	initialCongestionWindow = 32 * maxDatagramSize
+	initialMaxDatagramSize     = protocol.ByteCount(protocol.MaxPacketSizeIPv4)
+	maxBurstPackets            = 3
+	renoBeta                   = 0.7 // Reno backoff factor.
+	maxCongestionWindow        = protocol.MaxCongestionWindowPackets * initialMaxDatagramSize
+	minCongestionWindowPackets = 2
+	initialCongestionWindow    = 32 * initialMaxDatagramSize
 )
 
 type cubicSender struct {
@@ -59,6 +59,8 @@ type cubicSender struct {
 	initialCongestionWindow    protocol.ByteCount
 	initialMaxCongestionWindow protocol.ByteCount
 
+	maxDatagramSize protocol.ByteCount
+
 	lastState logging.CongestionState
 	tracer    logging.ConnectionTracer
 }
@@ -66,6 +68,8 @@ type cubicSender struct {
 var (
 	_ SendAlgorithm               = &cubicSender{}
 	_ SendAlgorithmWithDebugInfos = &cubicSender{}
+	_ MaxDatagramSizeSetter       = &cubicSender{}
+	_ ECNCongestionHandler        = &cubicSender{}
 )
 
 // NewCubicSender makes a new cubic sender
@@ -82,12 +86,13 @@ func newCubicSender(clock Clock, rttStats *utils.RTTStats, reno bool, initialCon
 		initialCongestionWindow:    initialCongestionWindow,
 		initialMaxCongestionWindow: initialMaxCongestionWindow,
 		congestionWindow:           initialCongestionWindow,
-		minCongestionWindow:        minCongestionWindow,
+		minCongestionWindow:        minCongestionWindowPackets * initialMaxDatagramSize,
 		slowStartThreshold:         initialMaxCongestionWindow,
 		maxCongestionWindow:        initialMaxCongestionWindow,
 		cubic:                      NewCubic(clock),
 		clock:                      clock,
 		reno:                       reno,
+		maxDatagramSize:            initialMaxDatagramSize,
 		tracer:                     tracer,
 	}
 	c.pacer = newPacer(c.BandwidthEstimate)
@@ -104,7 +109,7 @@ func (c *cubicSender) TimeUntilSend(_ protocol.ByteCount) time.Time {
 }
 
 func (c *cubicSender) HasPacingBudget() bool {
-	return c.pacer.Budget(c.clock.Now()) >= maxDatagramSize
+	return c.pacer.Budget(c.clock.Now()) >= c.maxDatagramSize
 }
 
 func (c *cubicSender) OnPacketSent(
@@ -139,7 +144,7 @@ func (c *cubicSender) GetCongestionWindow() protocol.ByteCount {
 }
 
 func (c *cubicSender) MaybeExitSlowStart() {
-	if c.InSlowStart() && c.hybridSlowStart.ShouldExitSlowStart(c.rttStats.LatestRTT(), c.rttStats.MinRTT(), c.GetCongestionWindow()/maxDatagramSize) {
+	if c.InSlowStart() && c.hybridSlowStart.ShouldExitSlowStart(c.rttStats.LatestRTT(), c.rttStats.MinRTT(), c.GetCongestionWindow()/c.maxDatagramSize) {
 		// exit slow start
 		c.slowStartThreshold = c.congestionWindow
 		c.maybeTraceStateChange(logging.CongestionStateCongestionAvoidance)
@@ -167,6 +172,16 @@ func (c *cubicSender) OnPacketLost(
 	lostBytes protocol.ByteCount,
 	priorInFlight protocol.ByteCount,
 ) {
+	c.reduceCongestionWindow(packetNumber)
+}
+
+// OnECNCongestion is called when the peer reports ECN-CE marks.
+// The congestion window is reduced the same way as for a packet loss.
+func (c *cubicSender) OnECNCongestion(largestAcked protocol.PacketNumber, _ protocol.ByteCount) {
+	c.reduceCongestionWindow(largestAcked)
+}
+
+func (c *cubicSender) reduceCongestionWindow(packetNumber protocol.PacketNumber) {
 	// TCP NewReno (RFC6582) says that once a loss occurs, any losses in packets
 	// already sent should be treated as a single loss event, since it's expected.
 	if packetNumber <= c.largestSentAtLastCutback {
@@ -210,7 +225,7 @@ func (c *cubicSender) maybeIncreaseCwnd(
 	}
 	if c.InSlowStart() {
 		// TCP slow start, exponential growth, increase by one for each ACK.
-		c.congestionWindow += maxDatagramSize
+		c.congestionWindow += c.maxDatagramSize
 		c.maybeTraceStateChange(logging.CongestionStateSlowStart)
 		return
 	}
@@ -219,8 +234,8 @@ func (c *cubicSender) maybeIncreaseCwnd(
 	if c.reno {
 		// Classic Reno congestion avoidance.
 		c.numAckedPackets++
-		if c.numAckedPackets >= uint64(c.congestionWindow/maxDatagramSize) {
-			c.congestionWindow += maxDatagramSize
+		if c.numAckedPackets >= uint64(c.congestionWindow/c.maxDatagramSize) {
+			c.congestionWindow += c.maxDatagramSize
 			c.numAckedPackets = 0
 		}
 	} else {
@@ -235,7 +250,7 @@ func (c *cubicSender) isCwndLimited(bytesInFlight protocol.ByteCount) bool {
 	}
 	availableBytes := congestionWindow - bytesInFlight
 	slowStartLimited := c.InSlowStart() && bytesInFlight > congestionWindow/2
-	return slowStartLimited || availableBytes <= maxBurstBytes
+	return slowStartLimited || availableBytes <= maxBurstPackets*c.maxDatagramSize
 }
 
 // BandwidthEstimate returns the current bandwidth estimate
@@ -274,6 +289,18 @@ func (c *cubicSender) OnConnectionMigration() {
 	c.maxCongestionWindow = c.initialMaxCongestionWindow
 }
 
+// SetMaxDatagramSize sets the maximum size of the packets sent.
+// It is called when path MTU discovery changes the packet size.
+func (c *cubicSender) SetMaxDatagramSize(s protocol.ByteCount) {
+	cwndIsMinCwnd := c.congestionWindow == c.minCongestionWindow
+	c.maxDatagramSize = s
+	c.minCongestionWindow = minCongestionWindowPackets * s
+	if cwndIsMinCwnd || c.congestionWindow < c.minCongestionWindow {
+		c.congestionWindow = c.minCongestionWindow
+	}
+	c.pacer.SetMaxDatagramSize(s)
+}
+
 func (c *cubicSender) maybeTraceStateChange(new logging.CongestionState) {
 	if c.tracer == nil || new == c.lastState {
 		return
@@ -11,7 +11,7 @@ import (
 
 const (
 	initialCongestionWindowPackets = 10
-	defaultWindowTCP               = protocol.ByteCount(initialCongestionWindowPackets) * maxDatagramSize
+	defaultWindowTCP               = protocol.ByteCount(initialCongestionWindowPackets) * initialMaxDatagramSize
 )
 
 type mockClock time.Time
@@ -24,7 +24,7 @@ func (c *mockClock) Advance(d time.Duration) {
 	*c = mockClock(time.Time(*c).Add(d))
 }
 
-const MaxCongestionWindow protocol.ByteCount = 200 * maxDatagramSize
+const MaxCongestionWindow protocol.ByteCount = 200 * initialMaxDatagramSize
 
 var _ = Describe("Cubic Sender", func() {
 	var (
@@ -42,7 +42,7 @@ var _ = Describe("Cubic Sender", func() {
 		ackedPacketNumber = 0
 		clock = mockClock{}
 		rttStats = utils.NewRTTStats()
-		sender = newCubicSender(&clock, rttStats, true /*reno*/, initialCongestionWindowPackets*maxDatagramSize, MaxCongestionWindow, nil)
+		sender = newCubicSender(&clock, rttStats, true /*reno*/, initialCongestionWindowPackets*initialMaxDatagramSize, MaxCongestionWindow, nil)
 	})
 
 	SendAvailableSendWindowLen := func(packetLength protocol.ByteCount) int {
@@ -62,9 +62,9 @@ var _ = Describe("Cubic Sender", func() {
 		sender.MaybeExitSlowStart()
 		for i := 0; i < n; i++ {
 			ackedPacketNumber++
-			sender.OnPacketAcked(ackedPacketNumber, maxDatagramSize, bytesInFlight, clock.Now())
+			sender.OnPacketAcked(ackedPacketNumber, initialMaxDatagramSize, bytesInFlight, clock.Now())
 		}
-		bytesInFlight -= protocol.ByteCount(n) * maxDatagramSize
+		bytesInFlight -= protocol.ByteCount(n) * initialMaxDatagramSize
 		clock.Advance(time.Millisecond)
 	}
 
@@ -78,12 +78,12 @@ var _ = Describe("Cubic Sender", func() {
 
 	// Does not increment acked_packet_number_.
 	LosePacket := func(number protocol.PacketNumber) {
-		sender.OnPacketLost(number, maxDatagramSize, bytesInFlight)
-		bytesInFlight -= maxDatagramSize
+		sender.OnPacketLost(number, initialMaxDatagramSize, bytesInFlight)
+		bytesInFlight -= initialMaxDatagramSize
 	}
 
-	SendAvailableSendWindow := func() int { return SendAvailableSendWindowLen(maxDatagramSize) }
-	LoseNPackets := func(n int) { LoseNPacketsLen(n, maxDatagramSize) }
+	SendAvailableSendWindow := func() int { return SendAvailableSendWindowLen(initialMaxDatagramSize) }
+	LoseNPackets := func(n int) { LoseNPacketsLen(n, initialMaxDatagramSize) }
 
 	It("has the right values at startup", func() {
 		// At startup make sure we are at the default.
@@ -124,7 +124,7 @@ var _ = Describe("Cubic Sender", func() {
 		bytesToSend := sender.GetCongestionWindow()
 		// It's expected 2 acks will arrive when the bytes_in_flight are greater than
 		// half the CWND.
-		Expect(bytesToSend).To(Equal(defaultWindowTCP + maxDatagramSize*2*2))
+		Expect(bytesToSend).To(Equal(defaultWindowTCP + initialMaxDatagramSize*2*2))
 	})
 
 	It("exponential slow start", func() {
@@ -142,7 +142,7 @@ var _ = Describe("Cubic Sender", func() {
 			AckNPackets(2)
 		}
 		cwnd := sender.GetCongestionWindow()
-		Expect(cwnd).To(Equal(defaultWindowTCP + maxDatagramSize*2*numberOfAcks))
+		Expect(cwnd).To(Equal(defaultWindowTCP + initialMaxDatagramSize*2*numberOfAcks))
 		Expect(sender.BandwidthEstimate()).To(Equal(BandwidthFromDelta(cwnd, rttStats.SmoothedRTT())))
 	})
 
@@ -154,12 +154,12 @@ var _ = Describe("Cubic Sender", func() {
 			AckNPackets(2)
 		}
 		SendAvailableSendWindow()
-		expectedSendWindow := defaultWindowTCP + (maxDatagramSize * 2 * numberOfAcks)
+		expectedSendWindow := defaultWindowTCP + (initialMaxDatagramSize * 2 * numberOfAcks)
 		Expect(sender.GetCongestionWindow()).To(Equal(expectedSendWindow))
 
 		// Lose a packet to exit slow start.
 		LoseNPackets(1)
-		packetsInRecoveryWindow := expectedSendWindow / maxDatagramSize
+		packetsInRecoveryWindow := expectedSendWindow / initialMaxDatagramSize
 
 		// We should now have fallen out of slow start with a reduced window.
 		expectedSendWindow = protocol.ByteCount(float32(expectedSendWindow) * renoBeta)
@@ -167,7 +167,7 @@ var _ = Describe("Cubic Sender", func() {
 
 		// Recovery phase. We need to ack every packet in the recovery window before
 		// we exit recovery.
-		numberOfPacketsInWindow := expectedSendWindow / maxDatagramSize
+		numberOfPacketsInWindow := expectedSendWindow / initialMaxDatagramSize
 		AckNPackets(int(packetsInRecoveryWindow))
 		SendAvailableSendWindow()
 		Expect(sender.GetCongestionWindow()).To(Equal(expectedSendWindow))
@@ -179,7 +179,7 @@ var _ = Describe("Cubic Sender", func() {
 
 		// Next ack should increase cwnd by 1.
 		AckNPackets(1)
-		expectedSendWindow += maxDatagramSize
+		expectedSendWindow += initialMaxDatagramSize
 		Expect(sender.GetCongestionWindow()).To(Equal(expectedSendWindow))
 
 		// Now RTO and ensure slow start gets reset.
@@ -198,7 +198,7 @@ var _ = Describe("Cubic Sender", func() {
 			AckNPackets(2)
 		}
 		SendAvailableSendWindow()
-		expectedSendWindow := defaultWindowTCP + (maxDatagramSize * 2 * numberOfAcks)
+		expectedSendWindow := defaultWindowTCP + (initialMaxDatagramSize * 2 * numberOfAcks)
 		Expect(sender.GetCongestionWindow()).To(Equal(expectedSendWindow))
 
 		LoseNPackets(1)
@@ -213,7 +213,7 @@ var _ = Describe("Cubic Sender", func() {
 		// outstanding packets. The number of packets before we exit recovery is the
 		// original CWND minus the packet that has been lost and the one which
 		// triggered the loss.
-		remainingPacketsInRecovery := sendWindowBeforeLoss/maxDatagramSize - 2
+		remainingPacketsInRecovery := sendWindowBeforeLoss/initialMaxDatagramSize - 2
 
 		for i := protocol.ByteCount(0); i < remainingPacketsInRecovery; i++ {
 			AckNPackets(1)
@@ -222,7 +222,7 @@ var _ = Describe("Cubic Sender", func() {
 		}
 
 		// We need to ack another window before we increase CWND by 1.
-		numberOfPacketsInWindow := expectedSendWindow / maxDatagramSize
+		numberOfPacketsInWindow := expectedSendWindow / initialMaxDatagramSize
 		for i := protocol.ByteCount(0); i < numberOfPacketsInWindow; i++ {
 			AckNPackets(1)
 			Expect(SendAvailableSendWindow()).To(Equal(1))
@@ -230,7 +230,7 @@ var _ = Describe("Cubic Sender", func() {
 		}
 
 		AckNPackets(1)
-		expectedSendWindow += maxDatagramSize
+		expectedSendWindow += initialMaxDatagramSize
 		Expect(sender.GetCongestionWindow()).To(Equal(expectedSendWindow))
 	})
 
@@ -246,13 +246,13 @@ var _ = Describe("Cubic Sender", func() {
 			AckNPackets(2)
 		}
 		SendAvailableSendWindow()
-		expectedSendWindow := defaultWindowTCP + (maxDatagramSize * 2 * numberOfAcks)
+		expectedSendWindow := defaultWindowTCP + (initialMaxDatagramSize * 2 * numberOfAcks)
 		Expect(sender.GetCongestionWindow()).To(Equal(expectedSendWindow))
 
 		// Lose one more than the congestion window reduction, so that after loss,
 		// bytes_in_flight is lesser than the congestion window.
 		sendWindowAfterLoss := protocol.ByteCount(renoBeta * float32(expectedSendWindow))
-		numPacketsToLose := (expectedSendWindow-sendWindowAfterLoss)/maxDatagramSize + 1
+		numPacketsToLose := (expectedSendWindow-sendWindowAfterLoss)/initialMaxDatagramSize + 1
 		LoseNPackets(int(numPacketsToLose))
 		// Immediately after the loss, ensure at least one packet can be sent.
 		// Losses without subsequent acks can occur with timer based loss detection.
@@ -294,8 +294,8 @@ var _ = Describe("Cubic Sender", func() {
 		// Expect the window to decrease to the minimum once the RTO fires
 		// and slow start threshold to be set to 1/2 of the CWND.
 		sender.OnRetransmissionTimeout(true)
-		Expect(sender.GetCongestionWindow()).To(Equal(2 * maxDatagramSize))
-		Expect(sender.slowStartThreshold).To(Equal(5 * maxDatagramSize))
+		Expect(sender.GetCongestionWindow()).To(Equal(2 * initialMaxDatagramSize))
+		Expect(sender.slowStartThreshold).To(Equal(5 * initialMaxDatagramSize))
 	})
 
 	It("RTO congestion window no retransmission", func() {
@@ -309,8 +309,8 @@ var _ = Describe("Cubic Sender", func() {
 
 	It("tcp cubic reset epoch on quiescence", func() {
 		const maxCongestionWindow = 50
-		const maxCongestionWindowBytes = maxCongestionWindow * maxDatagramSize
-		sender = newCubicSender(&clock, rttStats, false, initialCongestionWindowPackets*maxDatagramSize, maxCongestionWindowBytes, nil)
+		const maxCongestionWindowBytes = maxCongestionWindow * initialMaxDatagramSize
+		sender = newCubicSender(&clock, rttStats, false, initialCongestionWindowPackets*initialMaxDatagramSize, maxCongestionWindowBytes, nil)
 
 		numSent := SendAvailableSendWindow()
 
@@ -343,7 +343,7 @@ var _ = Describe("Cubic Sender", func() {
 		savedCwnd = sender.GetCongestionWindow()
 		SendAvailableSendWindow()
 		AckNPackets(1)
-		Expect(savedCwnd).To(BeNumerically("~", sender.GetCongestionWindow(), maxDatagramSize))
+		Expect(savedCwnd).To(BeNumerically("~", sender.GetCongestionWindow(), initialMaxDatagramSize))
 		Expect(maxCongestionWindowBytes).To(BeNumerically(">", sender.GetCongestionWindow()))
 	})
 
@@ -363,6 +363,20 @@ var _ = Describe("Cubic Sender", func() {
 		Expect(postLossWindow).To(BeNumerically(">", sender.GetCongestionWindow()))
 	})
 
+	It("reduces the congestion window once per window on ECN-CE marks", func() {
+		SendAvailableSendWindow()
+		initialWindow := sender.GetCongestionWindow()
+		AckNPackets(2)
+		sender.OnECNCongestion(ackedPacketNumber, bytesInFlight)
+		postCongestionWindow := sender.GetCongestionWindow()
+		Expect(initialWindow).To(BeNumerically(">", postCongestionWindow))
+		Expect(sender.InRecovery()).To(BeTrue())
+		// ECN-CE marks for packets sent before the reduction don't reduce the window again
+		AckNPackets(2)
+		sender.OnECNCongestion(ackedPacketNumber, bytesInFlight)
+		Expect(sender.GetCongestionWindow()).To(Equal(postCongestionWindow))
+	})
+
 	It("1 connection congestion avoidance at end of recovery", func() {
 		// Ack 10 packets in 5 acks to raise the CWND to 20.
 		const numberOfAcks = 5
@@ -372,7 +386,7 @@ var _ = Describe("Cubic Sender", func() {
 			AckNPackets(2)
 		}
 		SendAvailableSendWindow()
-		expectedSendWindow := defaultWindowTCP + (maxDatagramSize * 2 * numberOfAcks)
+		expectedSendWindow := defaultWindowTCP + (initialMaxDatagramSize * 2 * numberOfAcks)
 		Expect(sender.GetCongestionWindow()).To(Equal(expectedSendWindow))
 
 		LoseNPackets(1)
@@ -393,7 +407,7 @@ var _ = Describe("Cubic Sender", func() {
 		Expect(sender.InRecovery()).To(BeFalse())
 
 		// Out of recovery now. Congestion window should not grow during RTT.
-		for i := protocol.ByteCount(0); i < expectedSendWindow/maxDatagramSize-2; i += 2 {
+		for i := protocol.ByteCount(0); i < expectedSendWindow/initialMaxDatagramSize-2; i += 2 {
 			// Send our full send window.
 			SendAvailableSendWindow()
 			AckNPackets(2)
@@ -403,7 +417,7 @@ var _ = Describe("Cubic Sender", func() {
 		// Next ack should cause congestion window to grow by 1MSS.
 		SendAvailableSendWindow()
 		AckNPackets(2)
-		expectedSendWindow += maxDatagramSize
+		expectedSendWindow += initialMaxDatagramSize
 		Expect(sender.GetCongestionWindow()).To(Equal(expectedSendWindow))
 	})
 
@@ -413,7 +427,7 @@ var _ = Describe("Cubic Sender", func() {
 		AckNPackets(1)
 
 		Expect(sender.GetCongestionWindow()).To(Equal(protocol.ByteCount(renoBeta * float32(defaultWindowTCP))))
-		windowInPackets := renoBeta * float32(defaultWindowTCP) / float32(maxDatagramSize)
+		windowInPackets := renoBeta * float32(defaultWindowTCP) / float32(initialMaxDatagramSize)
 		numSent := SendAvailableSendWindow()
 		Expect(numSent).To(BeEquivalentTo(windowInPackets))
 	})
@@ -430,7 +444,7 @@ var _ = Describe("Cubic Sender", func() {
 			AckNPackets(2)
 		}
 		SendAvailableSendWindow()
-		expectedSendWindow := defaultWindowTCP + (maxDatagramSize * 2 * numberOfAcks)
+		expectedSendWindow := defaultWindowTCP + (initialMaxDatagramSize * 2 * numberOfAcks)
 		Expect(sender.GetCongestionWindow()).To(Equal(expectedSendWindow))
 
 		// Loses a packet to exit slow start.
@@ -450,9 +464,9 @@ var _ = Describe("Cubic Sender", func() {
 	})
 
 	It("default max cwnd", func() {
-		sender = newCubicSender(&clock, rttStats, true /*reno*/, initialCongestionWindowPackets*maxDatagramSize, maxCongestionWindow, nil)
+		sender = newCubicSender(&clock, rttStats, true /*reno*/, initialCongestionWindowPackets*initialMaxDatagramSize, maxCongestionWindow, nil)
 
-		defaultMaxCongestionWindowPackets := maxCongestionWindow / maxDatagramSize
+		defaultMaxCongestionWindowPackets := maxCongestionWindow / initialMaxDatagramSize
 		for i := 1; i < int(defaultMaxCongestionWindowPackets); i++ {
 			sender.MaybeExitSlowStart()
 			sender.OnPacketAcked(protocol.PacketNumber(i), 1350, sender.GetCongestionWindow(), clock.Now())
@@ -462,7 +476,7 @@ var _ = Describe("Cubic Sender", func() {
 
 	It("limit cwnd increase in congestion avoidance", func() {
 		// Enable Cubic.
-		sender = newCubicSender(&clock, rttStats, false, initialCongestionWindowPackets*maxDatagramSize, MaxCongestionWindow, nil)
+		sender = newCubicSender(&clock, rttStats, false, initialCongestionWindowPackets*initialMaxDatagramSize, MaxCongestionWindow, nil)
 		numSent := SendAvailableSendWindow()
 
 		// Make sure we fall out of slow start.
@@ -494,6 +508,23 @@ var _ = Describe("Cubic Sender", func() {
 
 		// Ack two packets.  The CWND should increase by only one packet.
 		AckNPackets(2)
-		Expect(sender.GetCongestionWindow()).To(Equal(savedCwnd + maxDatagramSize))
+		Expect(sender.GetCongestionWindow()).To(Equal(savedCwnd + initialMaxDatagramSize))
+	})
+
+	It("increases the congestion window in units of the max datagram size", func() {
+		sender.SetMaxDatagramSize(1400)
+		cwnd := sender.GetCongestionWindow()
+		SendAvailableSendWindowLen(1400)
+		AckNPackets(1)
+		Expect(sender.GetCongestionWindow()).To(Equal(cwnd + 1400))
+	})
+
+	It("adjusts the minimum congestion window when the max datagram size changes", func() {
+		sender.OnRetransmissionTimeout(true)
+		Expect(sender.GetCongestionWindow()).To(Equal(2 * initialMaxDatagramSize))
+		sender.SetMaxDatagramSize(1400)
+		Expect(sender.GetCongestionWindow()).To(Equal(protocol.ByteCount(2 * 1400)))
+		sender.SetMaxDatagramSize(1200)
+		Expect(sender.GetCongestionWindow()).To(Equal(protocol.ByteCount(2 * 1200)))
 	})
 })
@@ -3,29 +3,49 @@ package congestion
 import (
 	"time"
 
+	"github.com/For-ACGN/quic-go/congestion"
 	"github.com/For-ACGN/quic-go/internal/protocol"
 )
 
+// A CongestionEvent is implemented by congestion controllers that process all packets
+// acknowledged and lost by a single ACK frame at once, instead of one packet at a time.
 type CongestionEvent interface {
-	OnCongestionEvent(priorInFlight protocol.ByteCount, eventTime time.Time, ackedPackets, lostPackets []*protocol.Packet)
+	OnCongestionEvent(priorInFlight protocol.ByteCount, eventTime time.Time, ackedPackets, lostPackets []protocol.Packet)
 }
 
-// A SendAlgorithm performs congestion control
-type SendAlgorithm interface {
-	TimeUntilSend(bytesInFlight protocol.ByteCount) time.Time
-	HasPacingBudget() bool
-	OnPacketSent(sentTime time.Time, bytesInFlight protocol.ByteCount, packetNumber protocol.PacketNumber, bytes protocol.ByteCount, isRetransmittable bool)
-	CanSend(bytesInFlight protocol.ByteCount) bool
-	MaybeExitSlowStart()
-	OnPacketAcked(number protocol.PacketNumber, ackedBytes protocol.ByteCount, priorInFlight protocol.ByteCount, eventTime time.Time)
-	OnPacketLost(number protocol.PacketNumber, lostBytes protocol.ByteCount, priorInFlight protocol.ByteCount)
-	OnRetransmissionTimeout(packetsRetransmitted bool)
+// A MaxDatagramSizeSetter is implemented by congestion controllers that take the size of the packets into account.
+// SetMaxDatagramSize is called when path MTU discovery changes the maximum packet size.
+type MaxDatagramSizeSetter interface {
+	SetMaxDatagramSize(protocol.ByteCount)
+}
+
+// SlowStartStats are the statistics collected about the slow start phase (STARTUP in BBR).
+type SlowStartStats struct {
+	// Count is the number of times slow start was entered.
+	Count uint64
+	// RTTs is the number of round trips spent in slow start.
+	RTTs uint64
+	// PacketsLost and BytesLost count the packets lost while in slow start.
+	PacketsLost uint64
+	BytesLost   protocol.ByteCount
+	// Duration is the total time spent in slow start, including the current period.
+	Duration time.Duration
+}
+
+// A SlowStartStatsProvider is implemented by congestion controllers that collect statistics about slow start.
+type SlowStartStatsProvider interface {
+	SlowStartStats() SlowStartStats
 }
 
+// A SendAlgorithm performs congestion control
+type SendAlgorithm = congestion.SendAlgorithm
+
+// An ECNCongestionHandler is a SendAlgorithm that reacts to ECN-CE marks.
+type ECNCongestionHandler = congestion.ECNCongestionHandler
+
 // A SendAlgorithmWithDebugInfos is a SendAlgorithm that exposes some debug infos
 type SendAlgorithmWithDebugInfos interface {
 	SendAlgorithm
 	InSlowStart() bool
 	InRecovery() bool
-	GetCongestionWindow() protocol.ByteCount
 }
@@ -8,25 +8,34 @@ import (
 	"github.com/For-ACGN/quic-go/internal/utils"
 )
 
-const maxBurstSize = 10 * maxDatagramSize
+const maxBurstSizePackets = 10
 
 // The pacer implements a token bucket pacing algorithm.
 type pacer struct {
 	budgetAtLastSent     protocol.ByteCount
+	maxDatagramSize      protocol.ByteCount
 	lastSentTime         time.Time
 	getAdjustedBandwidth func() uint64 // in bytes/s
 }
 
 func newPacer(getBandwidth func() Bandwidth) *pacer {
-	p := &pacer{getAdjustedBandwidth: func() uint64 {
-		// Bandwidth is in bits/s. We need the value in bytes/s.
-		bw := uint64(getBandwidth() / BytesPerSecond)
-		// Use a slightly higher value than the actual measured bandwidth.
-		// RTT variations then won't result in under-utilization of the congestion window.
-		// Ultimately, this will  result in sending packets as acknowledgments are received rather than when timers fire,
-		// provided the congestion window is fully utilized and acknowledgments arrive at regular intervals.
-		return bw * 5 / 4
-	}}
+	p := &pacer{
+		maxDatagramSize: initialMaxDatagramSize,
+		getAdjustedBandwidth: func() uint64 {
+			bandwidth := getBandwidth()
+			// If no RTT has been measured yet (e.g. after a connection migration), the bandwidth is unknown.
+			if bandwidth == infBandwidth {
+				return math.MaxUint64
+			}
+			// Bandwidth is in bits/s. We need the value in bytes/s.
+			bw := uint64(bandwidth / BytesPerSecond)
+			// Use a slightly higher value than the actual measured bandwidth.
+			// RTT variations then won't result in under-utilization of the congestion window.
+			// Ultimately, this will  result in sending packets as acknowledgments are received rather than when timers fire,
+			// provided the congestion window is fully utilized and acknowledgments arrive at regular intervals.
+			return bw * 5 / 4
+		},
+	}
 	p.budgetAtLastSent = p.maxBurstSize()
 	return p
 }
@@ -45,24 +54,40 @@ func (p *pacer) Budget(now time.Time) protocol.ByteCount {
 	if p.lastSentTime.IsZero() {
 		return p.maxBurstSize()
 	}
-	budget := p.budgetAtLastSent + (protocol.ByteCount(p.getAdjustedBandwidth())*protocol.ByteCount(now.Sub(p.lastSentTime).Nanoseconds()))/1e9
+	bw := p.getAdjustedBandwidth()
+	if bw == math.MaxUint64 {
+		if now.After(p.lastSentTime) {
+			return p.maxBurstSize()
+		}
+		return p.budgetAtLastSent
+	}
+	budget := p.budgetAtLastSent + (protocol.ByteCount(bw)*protocol.ByteCount(now.Sub(p.lastSentTime).Nanoseconds()))/1e9
 	return utils.MinByteCount(p.maxBurstSize(), budget)
 }
 
 func (p *pacer) maxBurstSize() protocol.ByteCount {
+	bw := p.getAdjustedBandwidth()
+	if bw == math.MaxUint64 {
+		return maxBurstSizePackets * p.maxDatagramSize
+	}
 	return utils.MaxByteCount(
-		protocol.ByteCount(uint64((protocol.MinPacingDelay+protocol.TimerGranularity).Nanoseconds())*p.getAdjustedBandwidth())/1e9,
-		maxBurstSize,
+		protocol.ByteCount(uint64((protocol.MinPacingDelay+protocol.TimerGranularity).Nanoseconds())*bw)/1e9,
+		maxBurstSizePackets*p.maxDatagramSize,
 	)
 }
 
 // TimeUntilSend returns when the next packet should be sent.
 func (p *pacer) TimeUntilSend() time.Time {
-	if p.budgetAtLastSent >= maxDatagramSize {
+	if p.budgetAtLastSent >= p.maxDatagramSize {
 		return time.Time{}
 	}
 	return p.lastSentTime.Add(utils.MaxDuration(
 		protocol.MinPacingDelay,
-		time.Duration(math.Ceil(float64(maxDatagramSize-p.budgetAtLastSent)*1e9/float64(p.getAdjustedBandwidth())))*time.Nanosecond,
+		time.Duration(math.Ceil(float64(p.maxDatagramSize-p.budgetAtLastSent)*1e9/float64(p.getAdjustedBandwidth())))*time.Nanosecond,
 	))
 }
+
+// SetMaxDatagramSize sets the maximum size of the packets sent.
+func (p *pacer) SetMaxDatagramSize(s protocol.ByteCount) {
+	p.maxDatagramSize = s
+}
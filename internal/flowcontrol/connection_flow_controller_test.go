@@ -90,6 +90,7 @@ var _ = Describe("Connection Flow controller", func() {
 				newWindowSize := controller.receiveWindowSize
 				Expect(newWindowSize).To(Equal(2 * oldWindowSize))
 				Expect(offset).To(Equal(oldOffset + dataRead + newWindowSize))
+				Expect(controller.ReceiveWindowSize()).To(Equal(newWindowSize))
 			})
 		})
 	})
@@ -29,6 +29,9 @@ type StreamFlowController interface {
 // The ConnectionFlowController is the flow controller for the connection.
 type ConnectionFlowController interface {
 	flowController
+	// ReceiveWindowSize returns the current size of the receive window.
+	// It grows over the lifetime of the connection due to auto-tuning.
+	ReceiveWindowSize() protocol.ByteCount
 }
 
 type connectionFlowControllerI interface {
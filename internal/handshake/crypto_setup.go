@@ -99,7 +99,11 @@ type cryptoSetup struct {
 
 	ourParams  *wire.TransportParameters
 	peerParams *wire.TransportParameters
+	extHandler tlsExtensionHandler
 	paramsChan <-chan []byte
+	// only used by the server
+	// Signals that the client's transport parameters were processed.
+	paramsProcessed chan struct{}
 
 	runner handshakeRunner
 
@@ -119,9 +123,14 @@ type cryptoSetup struct {
 	logger utils.Logger
 
 	perspective protocol.Perspective
+	// The connection ID used to derive the Initial keys.
+	connID protocol.ConnectionID
 
 	mutex sync.Mutex // protects all members below
 
+	// The version is changed when compatible version negotiation is performed.
+	version protocol.VersionNumber
+
 	handshakeCompleteTime time.Time
 
 	readEncLevel  protocol.EncryptionLevel
@@ -133,6 +142,8 @@ type cryptoSetup struct {
 	initialStream io.Writer
 	initialOpener LongHeaderOpener
 	initialSealer LongHeaderSealer
+	// only set for the server, after switching to a compatible version
+	origInitialOpener LongHeaderOpener
 
 	handshakeStream io.Writer
 	handshakeOpener LongHeaderOpener
@@ -176,6 +187,7 @@ func NewCryptoSetupClient(
 		tracer,
 		logger,
 		protocol.PerspectiveClient,
+		version,
 	)
 	cs.conn = qtls.Client(newConn(localAddr, remoteAddr, version), cs.tlsConf, cs.extraConf)
 	return cs, clientHelloWritten
@@ -209,6 +221,7 @@ func NewCryptoSetupServer(
 		tracer,
 		logger,
 		protocol.PerspectiveServer,
+		version,
 	)
 	cs.conn = qtls.Server(newConn(localAddr, remoteAddr, version), cs.tlsConf, cs.extraConf)
 	return cs
@@ -226,29 +239,34 @@ func newCryptoSetup(
 	tracer logging.ConnectionTracer,
 	logger utils.Logger,
 	perspective protocol.Perspective,
+	version protocol.VersionNumber,
 ) (*cryptoSetup, <-chan *wire.TransportParameters /* ClientHello written. Receive nil for non-0-RTT */) {
-	initialSealer, initialOpener := NewInitialAEAD(connID, perspective)
+	initialSealer, initialOpener := NewInitialAEAD(connID, perspective, version)
 	if tracer != nil {
 		tracer.UpdatedKeyFromTLS(protocol.EncryptionInitial, protocol.PerspectiveClient)
 		tracer.UpdatedKeyFromTLS(protocol.EncryptionInitial, protocol.PerspectiveServer)
 	}
-	extHandler := newExtensionHandler(tp.Marshal(perspective), perspective)
+	extHandler := newExtensionHandler(tp.Marshal(perspective), perspective, version)
 	cs := &cryptoSetup{
 		tlsConf:                   tlsConf,
 		initialStream:             initialStream,
 		initialSealer:             initialSealer,
 		initialOpener:             initialOpener,
 		handshakeStream:           handshakeStream,
-		aead:                      newUpdatableAEAD(rttStats, tracer, logger),
+		aead:                      newUpdatableAEAD(rttStats, tracer, logger, version),
 		readEncLevel:              protocol.EncryptionInitial,
 		writeEncLevel:             protocol.EncryptionInitial,
 		runner:                    runner,
 		ourParams:                 tp,
+		extHandler:                extHandler,
 		paramsChan:                extHandler.TransportParameters(),
+		paramsProcessed:           make(chan struct{}, 1),
 		rttStats:                  rttStats,
 		tracer:                    tracer,
 		logger:                    logger,
 		perspective:               perspective,
+		connID:                    connID,
+		version:                   version,
 		handshakeDone:             make(chan struct{}),
 		alertChan:                 make(chan uint8),
 		clientHelloWrittenChan:    make(chan *wire.TransportParameters, 1),
@@ -262,7 +280,7 @@ func newCryptoSetup(
 	}
 	cs.extraConf = &qtls.ExtraConfig{
 		GetExtensions:              extHandler.GetExtensions,
-		ReceivedExtensions:         extHandler.ReceivedExtensions,
+		ReceivedExtensions:         cs.receivedExtensions,
 		AlternativeRecordLayer:     cs,
 		EnforceNextProtoSelection:  true,
 		MaxEarlyData:               maxEarlyData,
@@ -276,15 +294,55 @@ func newCryptoSetup(
 }
 
 func (h *cryptoSetup) ChangeConnectionID(id protocol.ConnectionID) {
-	initialSealer, initialOpener := NewInitialAEAD(id, h.perspective)
+	h.mutex.Lock()
+	h.connID = id
+	initialSealer, initialOpener := NewInitialAEAD(id, h.perspective, h.version)
 	h.initialSealer = initialSealer
 	h.initialOpener = initialOpener
+	h.mutex.Unlock()
 	if h.tracer != nil {
 		h.tracer.UpdatedKeyFromTLS(protocol.EncryptionInitial, protocol.PerspectiveClient)
 		h.tracer.UpdatedKeyFromTLS(protocol.EncryptionInitial, protocol.PerspectiveServer)
 	}
 }
 
+// ChangeVersion is called when compatible version negotiation (RFC 9368) switches the QUIC version.
+// It must be called before the Handshake keys are installed.
+// The client calls it when receiving the first Initial packet from the server,
+// the server calls it when processing the client's transport parameters.
+func (h *cryptoSetup) ChangeVersion(v protocol.VersionNumber) {
+	h.mutex.Lock()
+	// The client might still send Initial packets using the original version, e.g. retransmissions.
+	if h.perspective == protocol.PerspectiveServer && h.origInitialOpener == nil {
+		h.origInitialOpener = h.initialOpener
+	}
+	h.version = v
+	initialSealer, initialOpener := NewInitialAEAD(h.connID, h.perspective, v)
+	h.initialSealer = initialSealer
+	h.initialOpener = initialOpener
+	h.aead = newUpdatableAEAD(h.rttStats, h.tracer, h.logger, v)
+	// 0-RTT packets were sent using the original version, so they can't be accepted by the server.
+	had0RTTKeys := h.zeroRTTSealer != nil
+	h.zeroRTTSealer = nil
+	h.mutex.Unlock()
+
+	h.logger.Debugf("Changed QUIC version to %s. Derived new Initial keys.", v)
+	if h.tracer != nil {
+		h.tracer.UpdatedKeyFromTLS(protocol.EncryptionInitial, protocol.PerspectiveClient)
+		h.tracer.UpdatedKeyFromTLS(protocol.EncryptionInitial, protocol.PerspectiveServer)
+	}
+	// The server sends its transport parameters in the EncryptedExtensions,
+	// so it's not too late to update the chosen version.
+	if h.perspective == protocol.PerspectiveServer && h.ourParams.VersionInformation != nil {
+		h.ourParams.VersionInformation.ChosenVersion = v
+		h.extHandler.SetTransportParameters(h.ourParams.Marshal(h.perspective))
+	}
+	if had0RTTKeys {
+		h.logger.Debugf("Dropping 0-RTT keys.")
+		h.runner.DropKeys(protocol.Encryption0RTT)
+	}
+}
+
 func (h *cryptoSetup) SetLargest1RTTAcked(pn protocol.PacketNumber) error {
 	return h.aead.SetLargestAcked(pn)
 }
@@ -355,6 +413,9 @@ readLoop:
 			} else {
 				h.handleTransportParameters(data)
 			}
+			if h.perspective == protocol.PerspectiveServer {
+				h.paramsProcessed <- struct{}{}
+			}
 		case <-h.isReadingHandshakeMessage:
 			break readLoop
 		case <-h.handshakeDone:
@@ -394,6 +455,20 @@ func (h *cryptoSetup) checkEncryptionLevel(msgType messageType, encLevel protoco
 	return nil
 }
 
+// receivedExtensions is called by qtls when it receives the ClientHello (server) or the EncryptedExtensions (client).
+func (h *cryptoSetup) receivedExtensions(msgType uint8, exts []qtls.Extension) {
+	h.extHandler.ReceivedExtensions(msgType, exts)
+	if h.perspective == protocol.PerspectiveClient || messageType(msgType) != typeClientHello {
+		return
+	}
+	// Processing the client's transport parameters might change the QUIC version (compatible version negotiation).
+	// Wait until this is done, since the version determines how the Handshake keys are derived.
+	select {
+	case <-h.paramsProcessed:
+	case <-h.closeChan:
+	}
+}
+
 func (h *cryptoSetup) handleTransportParameters(data []byte) {
 	var tp wire.TransportParameters
 	if err := tp.Unmarshal(data, h.perspective.Opposite()); err != nil {
@@ -545,8 +620,8 @@ func (h *cryptoSetup) SetReadKey(encLevel qtls.EncryptionLevel, suite *qtls.Ciph
 			panic("Received 0-RTT read key for the client")
 		}
 		h.zeroRTTOpener = newLongHeaderOpener(
-			createAEAD(suite, trafficSecret),
-			newHeaderProtector(suite, trafficSecret, true),
+			createAEAD(suite, trafficSecret, h.version),
+			newHeaderProtector(suite, trafficSecret, true, h.version),
 		)
 		h.mutex.Unlock()
 		h.logger.Debugf("Installed 0-RTT Read keys (using %s)", qtls.CipherSuiteName(suite.ID))
@@ -557,8 +632,8 @@ func (h *cryptoSetup) SetReadKey(encLevel qtls.EncryptionLevel, suite *qtls.Ciph
 	case qtls.EncryptionHandshake:
 		h.readEncLevel = protocol.EncryptionHandshake
 		h.handshakeOpener = newHandshakeOpener(
-			createAEAD(suite, trafficSecret),
-			newHeaderProtector(suite, trafficSecret, true),
+			createAEAD(suite, trafficSecret, h.version),
+			newHeaderProtector(suite, trafficSecret, true, h.version),
 			h.dropInitialKeys,
 			h.perspective,
 		)
@@ -585,8 +660,8 @@ func (h *cryptoSetup) SetWriteKey(encLevel qtls.EncryptionLevel, suite *qtls.Cip
 			panic("Received 0-RTT write key for the server")
 		}
 		h.zeroRTTSealer = newLongHeaderSealer(
-			createAEAD(suite, trafficSecret),
-			newHeaderProtector(suite, trafficSecret, true),
+			createAEAD(suite, trafficSecret, h.version),
+			newHeaderProtector(suite, trafficSecret, true, h.version),
 		)
 		h.mutex.Unlock()
 		h.logger.Debugf("Installed 0-RTT Write keys (using %s)", qtls.CipherSuiteName(suite.ID))
@@ -597,8 +672,8 @@ func (h *cryptoSetup) SetWriteKey(encLevel qtls.EncryptionLevel, suite *qtls.Cip
 	case qtls.EncryptionHandshake:
 		h.writeEncLevel = protocol.EncryptionHandshake
 		h.handshakeSealer = newHandshakeSealer(
-			createAEAD(suite, trafficSecret),
-			newHeaderProtector(suite, trafficSecret, true),
+			createAEAD(suite, trafficSecret, h.version),
+			newHeaderProtector(suite, trafficSecret, true, h.version),
 			h.dropInitialKeys,
 			h.perspective,
 		)
@@ -662,6 +737,7 @@ func (h *cryptoSetup) dropInitialKeys() {
 	h.mutex.Lock()
 	h.initialOpener = nil
 	h.initialSealer = nil
+	h.origInitialOpener = nil
 	h.mutex.Unlock()
 	h.runner.DropKeys(protocol.EncryptionInitial)
 	h.logger.Debugf("Dropping Initial keys.")
@@ -737,6 +813,21 @@ func (h *cryptoSetup) GetInitialOpener() (LongHeaderOpener, error) {
 	return h.initialOpener, nil
 }
 
+// GetOriginalInitialOpener returns the opener for Initial packets sent using the version
+// the connection was started with, before the server switched to a compatible version.
+func (h *cryptoSetup) GetOriginalInitialOpener() (LongHeaderOpener, error) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if h.origInitialOpener == nil {
+		if h.initialOpener != nil {
+			return nil, ErrKeysNotYetAvailable
+		}
+		return nil, ErrKeysDropped
+	}
+	return h.origInitialOpener, nil
+}
+
 func (h *cryptoSetup) Get0RTTOpener() (LongHeaderOpener, error) {
 	h.mutex.Lock()
 	defer h.mutex.Unlock()
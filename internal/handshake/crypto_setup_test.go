@@ -518,6 +518,100 @@ var _ = Describe("Crypto Setup TLS", func() {
 			Expect(sTransportParametersRcvd.MaxIdleTimeout).To(Equal(sTransportParameters.MaxIdleTimeout))
 		})
 
+		It("updates the chosen version when the version is changed", func() {
+			var sTransportParametersRcvd *wire.TransportParameters
+			cChunkChan, cInitialStream, cHandshakeStream := initStreams()
+			cRunner := NewMockHandshakeRunner(mockCtrl)
+			cRunner.EXPECT().OnReceivedParams(gomock.Any()).Do(func(tp *wire.TransportParameters) { sTransportParametersRcvd = tp })
+			cRunner.EXPECT().OnHandshakeComplete()
+			client, _ := NewCryptoSetupClient(
+				cInitialStream,
+				cHandshakeStream,
+				protocol.ConnectionID{},
+				nil,
+				nil,
+				&wire.TransportParameters{},
+				cRunner,
+				clientConf,
+				false,
+				&utils.RTTStats{},
+				nil,
+				utils.DefaultLogger.WithPrefix("client"),
+				protocol.Version1,
+			)
+
+			sChunkChan, sInitialStream, sHandshakeStream := initStreams()
+			var token protocol.StatelessResetToken
+			sRunner := NewMockHandshakeRunner(mockCtrl)
+			var server CryptoSetup
+			sRunner.EXPECT().OnReceivedParams(gomock.Any()).Do(func(*wire.TransportParameters) {
+				server.ChangeVersion(protocol.Version2)
+			})
+			sRunner.EXPECT().OnHandshakeComplete()
+			server = NewCryptoSetupServer(
+				sInitialStream,
+				sHandshakeStream,
+				protocol.ConnectionID{},
+				nil,
+				nil,
+				&wire.TransportParameters{
+					StatelessResetToken: &token,
+					VersionInformation: &wire.VersionInformation{
+						ChosenVersion:     protocol.Version1,
+						AvailableVersions: []protocol.VersionNumber{protocol.Version2, protocol.Version1},
+					},
+				},
+				sRunner,
+				serverConf,
+				false,
+				&utils.RTTStats{},
+				nil,
+				utils.DefaultLogger.WithPrefix("server"),
+				protocol.Version1,
+			)
+
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				handshake(client, cChunkChan, server, sChunkChan)
+				close(done)
+			}()
+			Eventually(done).Should(BeClosed())
+			Expect(sTransportParametersRcvd.VersionInformation).ToNot(BeNil())
+			Expect(sTransportParametersRcvd.VersionInformation.ChosenVersion).To(Equal(protocol.Version2))
+		})
+
+		It("keeps the Initial opener of the original version when the server changes the version", func() {
+			_, sInitialStream, sHandshakeStream := initStreams()
+			var token protocol.StatelessResetToken
+			server := NewCryptoSetupServer(
+				sInitialStream,
+				sHandshakeStream,
+				protocol.ConnectionID{1, 2, 3, 4, 5, 6, 7, 8},
+				nil,
+				nil,
+				&wire.TransportParameters{StatelessResetToken: &token},
+				NewMockHandshakeRunner(mockCtrl),
+				serverConf,
+				false,
+				&utils.RTTStats{},
+				nil,
+				utils.DefaultLogger.WithPrefix("server"),
+				protocol.Version1,
+			)
+			_, err := server.GetOriginalInitialOpener()
+			Expect(err).To(MatchError(ErrKeysNotYetAvailable))
+			origOpener, err := server.GetInitialOpener()
+			Expect(err).ToNot(HaveOccurred())
+			server.ChangeVersion(protocol.Version2)
+			opener, err := server.GetOriginalInitialOpener()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(opener).To(Equal(origOpener))
+			newOpener, err := server.GetInitialOpener()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(newOpener).ToNot(Equal(origOpener))
+		})
+
 		Context("with session tickets", func() {
 			It("errors when the NewSessionTicket is sent at the wrong encryption level", func() {
 				cChunkChan, cInitialStream, cHandshakeStream := initStreams()
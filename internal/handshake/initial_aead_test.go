@@ -1,6 +1,7 @@
 package handshake
 
 import (
+	"fmt"
 	"math/rand"
 
 	"github.com/For-ACGN/quic-go/internal/protocol"
@@ -17,7 +18,7 @@ var _ = Describe("Initial AEAD using AES-GCM", func() {
 	})
 
 	// values taken from the Appendix of the draft
-	Context("using the test vector from the QUIC draft", func() {
+	Context("using the test vector from the QUIC draft-29", func() {
 		var connID protocol.ConnectionID
 
 		BeforeEach(func() {
@@ -25,23 +26,23 @@ var _ = Describe("Initial AEAD using AES-GCM", func() {
 		})
 
 		It("computes the client key and IV", func() {
-			clientSecret, _ := computeSecrets(connID)
+			clientSecret, _ := computeSecrets(connID, protocol.VersionDraft29)
 			Expect(clientSecret).To(Equal(splitHexString("0088119288f1d866733ceeed15ff9d50 902cf82952eee27e9d4d4918ea371d87")))
-			key, iv := computeInitialKeyAndIV(clientSecret)
+			key, iv := computeInitialKeyAndIV(clientSecret, protocol.VersionDraft29)
 			Expect(key).To(Equal(splitHexString("175257a31eb09dea9366d8bb79ad80ba")))
 			Expect(iv).To(Equal(splitHexString("6b26114b9cba2b63a9e8dd4f")))
 		})
 
 		It("computes the server key and IV", func() {
-			_, serverSecret := computeSecrets(connID)
+			_, serverSecret := computeSecrets(connID, protocol.VersionDraft29)
 			Expect(serverSecret).To(Equal(splitHexString("006f881359244dd9ad1acf85f595bad6 7c13f9f5586f5e64e1acae1d9ea8f616")))
-			key, iv := computeInitialKeyAndIV(serverSecret)
+			key, iv := computeInitialKeyAndIV(serverSecret, protocol.VersionDraft29)
 			Expect(key).To(Equal(splitHexString("149d0b1662ab871fbe63c49b5e655a5d")))
 			Expect(iv).To(Equal(splitHexString("bab2b12a4c76016ace47856d")))
 		})
 
 		It("encrypts the client's Initial", func() {
-			sealer, _ := NewInitialAEAD(connID, protocol.PerspectiveClient)
+			sealer, _ := NewInitialAEAD(connID, protocol.PerspectiveClient, protocol.VersionDraft29)
 			header := splitHexString("c3ff00001d088394c8f03e5157080000449e00000002")
 			data := splitHexString("060040c4010000c003036660261ff947 cea49cce6cfad687f457cf1b14531ba1 4131a0e8f309a1d0b9c4000006130113 031302010000910000000b0009000006 736572766572ff01000100000a001400 12001d00170018001901000101010201 03010400230000003300260024001d00 204cfdfcd178b784bf328cae793b136f 2aedce005ff183d7bb14952072366470 37002b0003020304000d0020001e0403 05030603020308040805080604010501 060102010402050206020202002d0002 0101001c00024001")
 			data = append(data, make([]byte, 1162-len(data))...) // add PADDING
@@ -56,7 +57,7 @@ var _ = Describe("Initial AEAD using AES-GCM", func() {
 		})
 
 		It("encrypt the server's Initial", func() {
-			sealer, _ := NewInitialAEAD(connID, protocol.PerspectiveServer)
+			sealer, _ := NewInitialAEAD(connID, protocol.PerspectiveServer, protocol.VersionDraft29)
 			header := splitHexString("c1ff00001d0008f067a5502a4262b50040740001")
 			data := splitHexString("0d0000000018410a020000560303eefc e7f7b37ba1d1632e96677825ddf73988 cfc79825df566dc5430b9a045a120013 0100002e00330024001d00209d3c940d 89690b84d08a60993c144eca684d1081 287c834d5311bcf32bb9da1a002b0002 0304")
 			sealed := sealer.Seal(nil, data, 1, header)
@@ -69,10 +70,67 @@ var _ = Describe("Initial AEAD using AES-GCM", func() {
 		})
 	})
 
+	// values taken from Appendix A of RFC 9001 and RFC 9369
+	for _, tc := range []struct {
+		version                           protocol.VersionNumber
+		clientSecret, clientKey, clientIV string
+		serverSecret, serverKey, serverIV string
+	}{
+		{
+			version:      protocol.Version1,
+			clientSecret: "c00cf151ca5be075ed0ebfb5c80323c4 2d6b7db67881289af4008f1f6c357aea",
+			clientKey:    "1f369613dd76d5467730efcbe3b1a22d",
+			clientIV:     "fa044b2f42a3fd3b46fb255c",
+			serverSecret: "3c199828fd139efd216c155ad844cc81 fb82fa8d7446fa7d78be803acdda951b",
+			serverKey:    "cf3a5331653c364c88f0f379b6067e37",
+			serverIV:     "0ac1493ca1905853b0bba03e",
+		},
+		{
+			version:      protocol.Version2,
+			clientSecret: "14ec9d6eb9fd7af83bf5a668bc17a7e2 83766aade7ecd0891f70f9ff7f4bf47b",
+			clientKey:    "8b1a0bc121284290a29e0971b5cd045d",
+			clientIV:     "91f73e2351d8fa91660e909f",
+			serverSecret: "0263db1782731bf4588e7e4d93b74639 07cb8cd8200b5da55a8bd488eafc37c1",
+			serverKey:    "82db637861d55e1d011f19ea71d5d2a7",
+			serverIV:     "dd13c276499c0249d3310652",
+		},
+	} {
+		tc := tc
+
+		Context(fmt.Sprintf("using the test vector for QUIC %s", tc.version), func() {
+			connID := protocol.ConnectionID(splitHexString("0x8394c8f03e515708"))
+
+			It("computes the client key and IV", func() {
+				clientSecret, _ := computeSecrets(connID, tc.version)
+				Expect(clientSecret).To(Equal(splitHexString(tc.clientSecret)))
+				key, iv := computeInitialKeyAndIV(clientSecret, tc.version)
+				Expect(key).To(Equal(splitHexString(tc.clientKey)))
+				Expect(iv).To(Equal(splitHexString(tc.clientIV)))
+			})
+
+			It("computes the server key and IV", func() {
+				_, serverSecret := computeSecrets(connID, tc.version)
+				Expect(serverSecret).To(Equal(splitHexString(tc.serverSecret)))
+				key, iv := computeInitialKeyAndIV(serverSecret, tc.version)
+				Expect(key).To(Equal(splitHexString(tc.serverKey)))
+				Expect(iv).To(Equal(splitHexString(tc.serverIV)))
+			})
+		})
+	}
+
+	It("uses different keys for different versions", func() {
+		connID := protocol.ConnectionID{0xde, 0xca, 0xfb, 0xad}
+		sealer, _ := NewInitialAEAD(connID, protocol.PerspectiveClient, protocol.Version1)
+		_, opener := NewInitialAEAD(connID, protocol.PerspectiveServer, protocol.Version2)
+		msg := sealer.Seal(nil, []byte("foobar"), 42, []byte("aad"))
+		_, err := opener.Open(nil, msg, 42, []byte("aad"))
+		Expect(err).To(MatchError(ErrDecryptionFailed))
+	})
+
 	It("seals and opens", func() {
 		connectionID := protocol.ConnectionID{0x12, 0x34, 0x56, 0x78, 0x90, 0xab, 0xcd, 0xef}
-		clientSealer, clientOpener := NewInitialAEAD(connectionID, protocol.PerspectiveClient)
-		serverSealer, serverOpener := NewInitialAEAD(connectionID, protocol.PerspectiveServer)
+		clientSealer, clientOpener := NewInitialAEAD(connectionID, protocol.PerspectiveClient, protocol.Version1)
+		serverSealer, serverOpener := NewInitialAEAD(connectionID, protocol.PerspectiveServer, protocol.Version1)
 
 		clientMessage := clientSealer.Seal(nil, []byte("foobar"), 42, []byte("aad"))
 		m, err := serverOpener.Open(nil, clientMessage, 42, []byte("aad"))
@@ -87,8 +145,8 @@ var _ = Describe("Initial AEAD using AES-GCM", func() {
 	It("doesn't work if initialized with different connection IDs", func() {
 		c1 := protocol.ConnectionID{0, 0, 0, 0, 0, 0, 0, 1}
 		c2 := protocol.ConnectionID{0, 0, 0, 0, 0, 0, 0, 2}
-		clientSealer, _ := NewInitialAEAD(c1, protocol.PerspectiveClient)
-		_, serverOpener := NewInitialAEAD(c2, protocol.PerspectiveServer)
+		clientSealer, _ := NewInitialAEAD(c1, protocol.PerspectiveClient, protocol.Version1)
+		_, serverOpener := NewInitialAEAD(c2, protocol.PerspectiveServer, protocol.Version1)
 
 		clientMessage := clientSealer.Seal(nil, []byte("foobar"), 42, []byte("aad"))
 		_, err := serverOpener.Open(nil, clientMessage, 42, []byte("aad"))
@@ -97,8 +155,8 @@ var _ = Describe("Initial AEAD using AES-GCM", func() {
 
 	It("encrypts und decrypts the header", func() {
 		connID := protocol.ConnectionID{0xde, 0xca, 0xfb, 0xad}
-		clientSealer, clientOpener := NewInitialAEAD(connID, protocol.PerspectiveClient)
-		serverSealer, serverOpener := NewInitialAEAD(connID, protocol.PerspectiveServer)
+		clientSealer, clientOpener := NewInitialAEAD(connID, protocol.PerspectiveClient, protocol.Version1)
+		serverSealer, serverOpener := NewInitialAEAD(connID, protocol.PerspectiveServer, protocol.Version1)
 
 		// the first byte and the last 4 bytes should be encrypted
 		header := []byte{0x5e, 0, 1, 2, 3, 4, 0xde, 0xad, 0xbe, 0xef}
@@ -61,6 +61,7 @@ type ShortHeaderSealer interface {
 type tlsExtensionHandler interface {
 	GetExtensions(msgType uint8) []qtls.Extension
 	ReceivedExtensions(msgType uint8, exts []qtls.Extension)
+	SetTransportParameters([]byte)
 	TransportParameters() <-chan []byte
 }
 
@@ -76,6 +77,7 @@ type CryptoSetup interface {
 	RunHandshake()
 	io.Closer
 	ChangeConnectionID(protocol.ConnectionID)
+	ChangeVersion(protocol.VersionNumber)
 	GetSessionTicket() ([]byte, error)
 
 	HandleMessage([]byte, protocol.EncryptionLevel) bool
@@ -84,6 +86,7 @@ type CryptoSetup interface {
 	ConnectionState() ConnectionState
 
 	GetInitialOpener() (LongHeaderOpener, error)
+	GetOriginalInitialOpener() (LongHeaderOpener, error)
 	GetHandshakeOpener() (LongHeaderOpener, error)
 	Get0RTTOpener() (LongHeaderOpener, error)
 	Get1RTTOpener() (ShortHeaderOpener, error)
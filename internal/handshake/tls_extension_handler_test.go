@@ -18,13 +18,22 @@ var _ = Describe("TLS Extension Handler, for the server", func() {
 		handlerServer = newExtensionHandler(
 			[]byte("foobar"),
 			protocol.PerspectiveServer,
+			protocol.Version1,
 		)
 		handlerClient = newExtensionHandler(
 			[]byte("raboof"),
 			protocol.PerspectiveClient,
+			protocol.Version1,
 		)
 	})
 
+	It("uses the code point for old drafts", func() {
+		h := newExtensionHandler([]byte("foobar"), protocol.PerspectiveServer, protocol.VersionDraft29)
+		exts := h.GetExtensions(uint8(typeEncryptedExtensions))
+		Expect(exts).To(HaveLen(1))
+		Expect(exts[0].Type).To(BeEquivalentTo(quicTLSExtensionTypeOldDrafts))
+	})
+
 	Context("for the server", func() {
 		Context("sending", func() {
 			It("only adds TransportParameters for the Encrypted Extensions", func() {
@@ -39,6 +48,13 @@ var _ = Describe("TLS Extension Handler, for the server", func() {
 				Expect(exts[0].Type).To(BeEquivalentTo(quicTLSExtensionType))
 				Expect(exts[0].Data).To(Equal([]byte("foobar")))
 			})
+
+			It("sends updated TransportParameters", func() {
+				handlerServer.SetTransportParameters([]byte("raboof"))
+				exts := handlerServer.GetExtensions(uint8(typeEncryptedExtensions))
+				Expect(exts).To(HaveLen(1))
+				Expect(exts[0].Data).To(Equal([]byte("raboof")))
+			})
 		})
 
 		Context("receiving", func() {
@@ -48,6 +48,18 @@ func (mr *MockCryptoSetupMockRecorder) ChangeConnectionID(arg0 interface{}) *gom
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChangeConnectionID", reflect.TypeOf((*MockCryptoSetup)(nil).ChangeConnectionID), arg0)
 }
 
+// ChangeVersion mocks base method
+func (m *MockCryptoSetup) ChangeVersion(arg0 protocol.VersionNumber) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "ChangeVersion", arg0)
+}
+
+// ChangeVersion indicates an expected call of ChangeVersion
+func (mr *MockCryptoSetupMockRecorder) ChangeVersion(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChangeVersion", reflect.TypeOf((*MockCryptoSetup)(nil).ChangeVersion), arg0)
+}
+
 // Close mocks base method
 func (m *MockCryptoSetup) Close() error {
 	m.ctrl.T.Helper()
@@ -196,6 +208,21 @@ func (mr *MockCryptoSetupMockRecorder) GetInitialSealer() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetInitialSealer", reflect.TypeOf((*MockCryptoSetup)(nil).GetInitialSealer))
 }
 
+// GetOriginalInitialOpener mocks base method
+func (m *MockCryptoSetup) GetOriginalInitialOpener() (handshake.LongHeaderOpener, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOriginalInitialOpener")
+	ret0, _ := ret[0].(handshake.LongHeaderOpener)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOriginalInitialOpener indicates an expected call of GetOriginalInitialOpener
+func (mr *MockCryptoSetupMockRecorder) GetOriginalInitialOpener() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOriginalInitialOpener", reflect.TypeOf((*MockCryptoSetup)(nil).GetOriginalInitialOpener))
+}
+
 // GetSessionTicket mocks base method
 func (m *MockCryptoSetup) GetSessionTicket() ([]byte, error) {
 	m.ctrl.T.Helper()
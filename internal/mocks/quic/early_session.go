@@ -95,6 +95,20 @@ func (mr *MockEarlySessionMockRecorder) ConnectionState() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConnectionState", reflect.TypeOf((*MockEarlySession)(nil).ConnectionState))
 }
 
+// ConnectionStats mocks base method
+func (m *MockEarlySession) ConnectionStats() quic.ConnectionStats {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConnectionStats")
+	ret0, _ := ret[0].(quic.ConnectionStats)
+	return ret0
+}
+
+// ConnectionStats indicates an expected call of ConnectionStats
+func (mr *MockEarlySessionMockRecorder) ConnectionStats() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConnectionStats", reflect.TypeOf((*MockEarlySession)(nil).ConnectionStats))
+}
+
 // Context mocks base method
 func (m *MockEarlySession) Context() context.Context {
 	m.ctrl.T.Helper()
@@ -137,6 +151,20 @@ func (mr *MockEarlySessionMockRecorder) LocalAddr() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LocalAddr", reflect.TypeOf((*MockEarlySession)(nil).LocalAddr))
 }
 
+// MigrateConnection mocks base method
+func (m *MockEarlySession) MigrateConnection(arg0 net.PacketConn) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MigrateConnection", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MigrateConnection indicates an expected call of MigrateConnection
+func (mr *MockEarlySessionMockRecorder) MigrateConnection(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MigrateConnection", reflect.TypeOf((*MockEarlySession)(nil).MigrateConnection), arg0)
+}
+
 // OpenStream mocks base method
 func (m *MockEarlySession) OpenStream() (quic.Stream, error) {
 	m.ctrl.T.Helper()
@@ -11,6 +11,10 @@ const MaxPacketSizeIPv4 = 1252
 // MaxPacketSizeIPv6 is the maximum packet size that we use for sending IPv6 packets.
 const MaxPacketSizeIPv6 = 1232
 
+// MaxBatchedPackets is the maximum number of packets that are sent (using GSO or sendmmsg)
+// or received (using recvmmsg) using a single syscall, when the kernel supports it.
+const MaxBatchedPackets = 10
+
 // MaxCongestionWindowPackets is the maximum congestion window in packet.
 const MaxCongestionWindowPackets = 10000
 
@@ -140,8 +144,9 @@ const MaxAckFrameSize ByteCount = 1000
 
 // MaxDatagramFrameSize is the maximum size of a DATAGRAM frame as defined in
 // https://datatracker.ietf.org/doc/draft-pauly-quic-datagram/.
-// The size is chosen such that a DATAGRAM frame fits into a QUIC packet.
-const MaxDatagramFrameSize ByteCount = 1200
+// It is larger than the initial packet size, so that DATAGRAM frames can make use of
+// a larger path MTU (e.g. to tunnel QUIC packets, which are at least 1200 bytes large).
+const MaxDatagramFrameSize ByteCount = MaxReceivePacketSize
 
 // DatagramRcvQueueLen is the length of the receive queue for DATAGRAM frames.
 // See https://datatracker.ietf.org/doc/draft-pauly-quic-datagram/.
@@ -185,6 +190,13 @@ const MaxAckDelay = 25 * time.Millisecond
 // This is the value that should be advertised to the peer.
 const MaxAckDelayInclGranularity = MaxAckDelay + TimerGranularity
 
+// MinAckDelay is the min_ack_delay we advertise to the peer.
+// It is the smallest ACK delay the peer can request using an ACK_FREQUENCY frame.
+const MinAckDelay = time.Millisecond
+
+// MaxAckElicitingThreshold is the maximum ack-eliciting threshold we request from the peer using an ACK_FREQUENCY frame.
+const MaxAckElicitingThreshold = 10
+
 // KeyUpdateInterval is the maximum number of packets we send or receive before initiating a key update.
 const KeyUpdateInterval = 100 * 1000
 
@@ -199,3 +211,10 @@ const Max0RTTQueues = 32
 // To avoid blocking, this value has to be smaller than MaxSessionUnprocessedPackets.
 // To avoid packets being dropped as undecryptable by the session, this value has to be smaller than MaxUndecryptablePackets.
 const Max0RTTQueueLen = 32
+
+// MinPathValidationTimeout is the minimum time that we wait for a PATH_RESPONSE before abandoning a path.
+// This is three times the PTO of a path for which we don't have an RTT estimate yet.
+const MinPathValidationTimeout = 3 * time.Second
+
+// MaxPathChallenges is the maximum number of PATH_CHALLENGE frames we send when validating a path.
+const MaxPathChallenges = 3
@@ -43,6 +43,31 @@ const (
 	ECNCE             // 11
 )
 
+// A CongestionControlAlgorithm is a congestion control algorithm
+type CongestionControlAlgorithm uint8
+
+const (
+	// CongestionControlReno is TCP NewReno
+	CongestionControlReno CongestionControlAlgorithm = iota
+	// CongestionControlCubic is TCP Cubic
+	CongestionControlCubic
+	// CongestionControlBBR is BBR
+	CongestionControlBBR
+)
+
+func (a CongestionControlAlgorithm) String() string {
+	switch a {
+	case CongestionControlReno:
+		return "Reno"
+	case CongestionControlCubic:
+		return "Cubic"
+	case CongestionControlBBR:
+		return "BBR"
+	default:
+		return fmt.Sprintf("unknown congestion control algorithm: %d", a)
+	}
+}
+
 // A ByteCount in QUIC
 type ByteCount int64
 
@@ -64,6 +89,10 @@ type StatelessResetToken [16]byte
 // Ethernet's max packet size is 1500 bytes,  1500 - 48 = 1452.
 const MaxReceivePacketSize ByteCount = 1452
 
+// MaxJumboPacketSize is the largest maximum packet size that can be configured.
+// It allows using jumbo frames with an MTU of 9000 bytes, minus the IPv4 and UDP headers.
+const MaxJumboPacketSize ByteCount = 9000 - 20 - 8
+
 // MinInitialPacketSize is the minimum size an Initial packet is required to have.
 const MinInitialPacketSize = 1200
 
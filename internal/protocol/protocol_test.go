@@ -16,6 +16,13 @@ var _ = Describe("Protocol", func() {
 		})
 	})
 
+	It("has a string representation for the congestion control algorithms", func() {
+		Expect(CongestionControlReno.String()).To(Equal("Reno"))
+		Expect(CongestionControlCubic.String()).To(Equal("Cubic"))
+		Expect(CongestionControlBBR.String()).To(Equal("BBR"))
+		Expect(CongestionControlAlgorithm(10).String()).To(Equal("unknown congestion control algorithm: 10"))
+	})
+
 	It("converts ECN bits from the IP header wire to the correct types", func() {
 		Expect(ECN(0)).To(Equal(ECNNon))
 		Expect(ECN(0b00000010)).To(Equal(ECT0))
@@ -1,5 +1,7 @@
 package protocol
 
+import "fmt"
+
 // StreamType encodes if this is a unidirectional or bidirectional stream
 type StreamType uint8
 
@@ -74,3 +76,39 @@ func (s StreamID) Type() StreamType {
 func (s StreamID) StreamNum() StreamNum {
 	return StreamNum(s/4) + 1
 }
+
+// MaxStreamUrgency is the largest (i.e. least urgent) urgency value of a stream.
+const MaxStreamUrgency = 7
+
+// DefaultStreamUrgency is the urgency of a stream if no priority was set.
+const DefaultStreamUrgency = 3
+
+// A StreamPriority is the priority of a stream.
+type StreamPriority struct {
+	// Urgency ranges from 0 (most urgent) to MaxStreamUrgency (least urgent).
+	Urgency uint8
+	// Incremental streams of the same urgency share the available bandwidth.
+	// Non-incremental streams are sent one after the other.
+	Incremental bool
+}
+
+// A StreamSchedulingMode decides how data of multiple streams is scheduled for sending
+type StreamSchedulingMode uint8
+
+const (
+	// StreamSchedulingStrictPriority always sends data of the most urgent streams first.
+	StreamSchedulingStrictPriority StreamSchedulingMode = iota
+	// StreamSchedulingWeightedFair shares the bandwidth between all streams, weighted by their urgency.
+	StreamSchedulingWeightedFair
+)
+
+func (m StreamSchedulingMode) String() string {
+	switch m {
+	case StreamSchedulingStrictPriority:
+		return "strict priority"
+	case StreamSchedulingWeightedFair:
+		return "weighted fair"
+	default:
+		return fmt.Sprintf("unknown stream scheduling mode: %d", m)
+	}
+}
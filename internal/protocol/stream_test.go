@@ -67,4 +67,10 @@ var _ = Describe("Stream ID", func() {
 			}
 		})
 	})
+
+	It("has a string representation for the stream scheduling mode", func() {
+		Expect(StreamSchedulingStrictPriority.String()).To(Equal("strict priority"))
+		Expect(StreamSchedulingWeightedFair.String()).To(Equal("weighted fair"))
+		Expect(StreamSchedulingMode(10).String()).To(Equal("unknown stream scheduling mode: 10"))
+	})
 })
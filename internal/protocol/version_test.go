@@ -12,19 +12,24 @@ var _ = Describe("Version", func() {
 
 	It("says if a version is valid", func() {
 		Expect(IsValidVersion(VersionTLS)).To(BeTrue())
+		Expect(IsValidVersion(Version1)).To(BeTrue())
+		Expect(IsValidVersion(Version2)).To(BeTrue())
+		Expect(IsValidVersion(VersionDraft29)).To(BeTrue())
 		Expect(IsValidVersion(VersionWhatever)).To(BeFalse())
 		Expect(IsValidVersion(VersionUnknown)).To(BeFalse())
-		Expect(IsValidVersion(VersionDraft29)).To(BeFalse())
 		Expect(IsValidVersion(VersionDraft32)).To(BeFalse())
 		Expect(IsValidVersion(1234)).To(BeFalse())
 	})
 
 	It("versions don't have reserved version numbers", func() {
-		Expect(isReservedVersion(VersionTLS)).To(BeFalse())
+		for _, v := range SupportedVersions {
+			Expect(isReservedVersion(v)).To(BeFalse())
+		}
 	})
 
 	It("has the right string representation", func() {
-		Expect(VersionTLS.String()).To(ContainSubstring("TLS"))
+		Expect(Version1.String()).To(Equal("v1"))
+		Expect(Version2.String()).To(Equal("v2"))
 		Expect(VersionWhatever.String()).To(Equal("whatever"))
 		Expect(VersionUnknown.String()).To(Equal("unknown"))
 		Expect(VersionDraft29.String()).To(Equal("draft-29"))
@@ -43,10 +48,8 @@ var _ = Describe("Version", func() {
 		Expect(IsSupportedVersion(SupportedVersions, SupportedVersions[len(SupportedVersions)-1])).To(BeTrue())
 	})
 
-	It("has supported versions in sorted order", func() {
-		for i := 0; i < len(SupportedVersions)-1; i++ {
-			Expect(SupportedVersions[i]).To(BeNumerically(">", SupportedVersions[i+1]))
-		}
+	It("prefers QUIC version 1", func() {
+		Expect(SupportedVersions[0]).To(Equal(Version1))
 	})
 
 	It("says if backwards compatibility mode should be used", func() {
@@ -56,6 +59,14 @@ var _ = Describe("Version", func() {
 		Expect(UseRetireBugBackwardsCompatibilityMode(false, VersionDraft32)).To(BeFalse())
 	})
 
+	It("says if versions are compatible", func() {
+		Expect(AreCompatibleVersions(Version1, Version1)).To(BeTrue())
+		Expect(AreCompatibleVersions(Version1, Version2)).To(BeTrue())
+		Expect(AreCompatibleVersions(Version2, Version1)).To(BeTrue())
+		Expect(AreCompatibleVersions(Version1, VersionDraft29)).To(BeFalse())
+		Expect(AreCompatibleVersions(VersionDraft29, Version2)).To(BeFalse())
+	})
+
 	Context("highest supported version", func() {
 		It("finds the supported version", func() {
 			supportedVersions := []VersionNumber{1, 2, 3}
@@ -0,0 +1,71 @@
+package wire
+
+import (
+	"bytes"
+	"time"
+
+	"github.com/For-ACGN/quic-go/internal/protocol"
+	"github.com/For-ACGN/quic-go/quicvarint"
+)
+
+// frame type of the ACK_FREQUENCY frame (draft-ietf-quic-ack-frequency)
+const ackFrequencyFrameType = 0xaf
+
+// An AckFrequencyFrame is an ACK_FREQUENCY frame
+type AckFrequencyFrame struct {
+	SequenceNumber        uint64
+	AckElicitingThreshold uint64
+	RequestMaxAckDelay    time.Duration
+	ReorderingThreshold   uint64
+}
+
+func parseAckFrequencyFrame(r *bytes.Reader, _ protocol.VersionNumber) (*AckFrequencyFrame, error) {
+	if _, err := quicvarint.Read(r); err != nil {
+		return nil, err
+	}
+	seq, err := quicvarint.Read(r)
+	if err != nil {
+		return nil, err
+	}
+	threshold, err := quicvarint.Read(r)
+	if err != nil {
+		return nil, err
+	}
+	mad, err := quicvarint.Read(r)
+	if err != nil {
+		return nil, err
+	}
+	reorderingThreshold, err := quicvarint.Read(r)
+	if err != nil {
+		return nil, err
+	}
+	// the Request Max Ack Delay is encoded in microseconds
+	maxAckDelay := protocol.MaxMaxAckDelay
+	if mad < uint64(protocol.MaxMaxAckDelay/time.Microsecond) {
+		maxAckDelay = time.Duration(mad) * time.Microsecond
+	}
+	return &AckFrequencyFrame{
+		SequenceNumber:        seq,
+		AckElicitingThreshold: threshold,
+		RequestMaxAckDelay:    maxAckDelay,
+		ReorderingThreshold:   reorderingThreshold,
+	}, nil
+}
+
+func (f *AckFrequencyFrame) Write(b *bytes.Buffer, _ protocol.VersionNumber) error {
+	quicvarint.Write(b, ackFrequencyFrameType)
+	quicvarint.Write(b, f.SequenceNumber)
+	quicvarint.Write(b, f.AckElicitingThreshold)
+	quicvarint.Write(b, uint64(f.RequestMaxAckDelay/time.Microsecond))
+	quicvarint.Write(b, f.ReorderingThreshold)
+	return nil
+}
+
+// Length of a written frame
+func (f *AckFrequencyFrame) Length(_ protocol.VersionNumber) protocol.ByteCount {
+	return quicvarint.Len(ackFrequencyFrameType) +
+		quicvarint.Len(f.SequenceNumber) +
+		quicvarint.Len(f.AckElicitingThreshold) +
+		quicvarint.Len(uint64(f.RequestMaxAckDelay/time.Microsecond)) +
+		quicvarint.Len(f.ReorderingThreshold)
+}
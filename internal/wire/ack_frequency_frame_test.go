@@ -0,0 +1,88 @@
+package wire
+
+import (
+	"bytes"
+	"io"
+	"time"
+
+	"github.com/For-ACGN/quic-go/internal/protocol"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ACK_FREQUENCY frame", func() {
+	Context("when parsing", func() {
+		It("accepts a sample frame", func() {
+			data := encodeVarInt(0xaf)
+			data = append(data, encodeVarInt(0xdeadbeef)...) // sequence number
+			data = append(data, encodeVarInt(9)...)          // ack-eliciting threshold
+			data = append(data, encodeVarInt(1337)...)       // request max ack delay, in microseconds
+			data = append(data, encodeVarInt(3)...)          // reordering threshold
+			b := bytes.NewReader(data)
+			frame, err := parseAckFrequencyFrame(b, versionIETFFrames)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame.SequenceNumber).To(Equal(uint64(0xdeadbeef)))
+			Expect(frame.AckElicitingThreshold).To(Equal(uint64(9)))
+			Expect(frame.RequestMaxAckDelay).To(Equal(1337 * time.Microsecond))
+			Expect(frame.ReorderingThreshold).To(Equal(uint64(3)))
+			Expect(b.Len()).To(BeZero())
+		})
+
+		It("limits the request max ack delay", func() {
+			data := encodeVarInt(0xaf)
+			data = append(data, encodeVarInt(1)...)
+			data = append(data, encodeVarInt(2)...)
+			data = append(data, encodeVarInt(1<<40)...)
+			data = append(data, encodeVarInt(1)...)
+			frame, err := parseAckFrequencyFrame(bytes.NewReader(data), versionIETFFrames)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame.RequestMaxAckDelay).To(Equal(protocol.MaxMaxAckDelay))
+		})
+
+		It("errors on EOFs", func() {
+			data := encodeVarInt(0xaf)
+			data = append(data, encodeVarInt(0xdeadbeef)...)
+			data = append(data, encodeVarInt(9)...)
+			data = append(data, encodeVarInt(1337)...)
+			data = append(data, encodeVarInt(3)...)
+			_, err := parseAckFrequencyFrame(bytes.NewReader(data), versionIETFFrames)
+			Expect(err).NotTo(HaveOccurred())
+			for i := range data {
+				_, err := parseAckFrequencyFrame(bytes.NewReader(data[0:i]), versionIETFFrames)
+				Expect(err).To(MatchError(io.EOF))
+			}
+		})
+	})
+
+	Context("when writing", func() {
+		It("writes a sample frame", func() {
+			frame := &AckFrequencyFrame{
+				SequenceNumber:        0x1337,
+				AckElicitingThreshold: 10,
+				RequestMaxAckDelay:    25 * time.Millisecond,
+				ReorderingThreshold:   2,
+			}
+			b := &bytes.Buffer{}
+			Expect(frame.Write(b, versionIETFFrames)).To(Succeed())
+			expected := encodeVarInt(0xaf)
+			expected = append(expected, encodeVarInt(0x1337)...)
+			expected = append(expected, encodeVarInt(10)...)
+			expected = append(expected, encodeVarInt(25000)...)
+			expected = append(expected, encodeVarInt(2)...)
+			Expect(b.Bytes()).To(Equal(expected))
+		})
+
+		It("has the correct length", func() {
+			frame := &AckFrequencyFrame{
+				SequenceNumber:        0xdecafbad,
+				AckElicitingThreshold: 0x1234,
+				RequestMaxAckDelay:    time.Second,
+				ReorderingThreshold:   1,
+			}
+			b := &bytes.Buffer{}
+			Expect(frame.Write(b, versionIETFFrames)).To(Succeed())
+			Expect(frame.Length(versionIETFFrames)).To(BeEquivalentTo(b.Len()))
+		})
+	})
+})
@@ -129,16 +129,30 @@ func (h *ExtendedHeader) Write(b *bytes.Buffer, ver protocol.VersionNumber) erro
 
 func (h *ExtendedHeader) writeLongHeader(b *bytes.Buffer, _ protocol.VersionNumber) error {
 	var packetType uint8
-	//nolint:exhaustive
-	switch h.Type {
-	case protocol.PacketTypeInitial:
-		packetType = 0x0
-	case protocol.PacketType0RTT:
-		packetType = 0x1
-	case protocol.PacketTypeHandshake:
-		packetType = 0x2
-	case protocol.PacketTypeRetry:
-		packetType = 0x3
+	if h.Version == protocol.Version2 {
+		//nolint:exhaustive
+		switch h.Type {
+		case protocol.PacketTypeInitial:
+			packetType = 0x1
+		case protocol.PacketType0RTT:
+			packetType = 0x2
+		case protocol.PacketTypeHandshake:
+			packetType = 0x3
+		case protocol.PacketTypeRetry:
+			packetType = 0x0
+		}
+	} else {
+		//nolint:exhaustive
+		switch h.Type {
+		case protocol.PacketTypeInitial:
+			packetType = 0x0
+		case protocol.PacketType0RTT:
+			packetType = 0x1
+		case protocol.PacketTypeHandshake:
+			packetType = 0x2
+		case protocol.PacketTypeRetry:
+			packetType = 0x3
+		}
 	}
 	firstByte := 0xc0 | packetType<<4
 	if h.Type != protocol.PacketTypeRetry {
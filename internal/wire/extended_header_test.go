@@ -115,6 +115,25 @@ var _ = Describe("Header", func() {
 				Expect(buf.Bytes()[buf.Len()-6 : buf.Len()-4]).To(Equal(b.Bytes()))
 			})
 
+			It("uses the QUIC v2 packet type encoding", func() {
+				for t, typeBits := range map[protocol.PacketType]byte{
+					protocol.PacketTypeInitial:   0x1,
+					protocol.PacketType0RTT:      0x2,
+					protocol.PacketTypeHandshake: 0x3,
+				} {
+					buf.Reset()
+					Expect((&ExtendedHeader{
+						Header: Header{
+							IsLongHeader: true,
+							Type:         t,
+							Version:      protocol.Version2,
+						},
+						PacketNumberLen: protocol.PacketNumberLen1,
+					}).Write(buf, protocol.Version2)).To(Succeed())
+					Expect(buf.Bytes()[0] & 0x30 >> 4).To(Equal(typeBits))
+				}
+			})
+
 			It("writes a Retry packet", func() {
 				token := []byte("Ut enim ad minim veniam, quis nostrud exercitation ullamco laboris nisi ut aliquip ex ea commodo consequat.")
 				Expect((&ExtendedHeader{Header: Header{
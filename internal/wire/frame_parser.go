@@ -4,10 +4,12 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"io"
 	"reflect"
 
 	"github.com/For-ACGN/quic-go/internal/protocol"
 	"github.com/For-ACGN/quic-go/internal/qerr"
+	"github.com/For-ACGN/quic-go/quicvarint"
 )
 
 type frameParser struct {
@@ -36,22 +38,34 @@ func (p *frameParser) ParseNext(r *bytes.Reader, encLevel protocol.EncryptionLev
 		}
 		r.UnreadByte()
 
-		f, err := p.parseFrame(r, typeByte, encLevel)
+		frameType := uint64(typeByte)
+		// Frame types defined by extensions might be encoded using multiple bytes.
+		if typeByte&0xc0 != 0 {
+			startLen := r.Len()
+			t, err := quicvarint.Read(r)
+			if err != nil {
+				return nil, qerr.NewErrorWithFrameType(qerr.FrameEncodingError, uint64(typeByte), err.Error())
+			}
+			frameType = t
+			r.Seek(int64(r.Len()-startLen), io.SeekCurrent)
+		}
+
+		f, err := p.parseFrame(r, frameType, encLevel)
 		if err != nil {
-			return nil, qerr.NewErrorWithFrameType(qerr.FrameEncodingError, uint64(typeByte), err.Error())
+			return nil, qerr.NewErrorWithFrameType(qerr.FrameEncodingError, frameType, err.Error())
 		}
 		return f, nil
 	}
 	return nil, nil
 }
 
-func (p *frameParser) parseFrame(r *bytes.Reader, typeByte byte, encLevel protocol.EncryptionLevel) (Frame, error) {
+func (p *frameParser) parseFrame(r *bytes.Reader, frameType uint64, encLevel protocol.EncryptionLevel) (Frame, error) {
 	var frame Frame
 	var err error
-	if typeByte&0xf8 == 0x8 {
+	if frameType&0xf8 == 0x8 {
 		frame, err = parseStreamFrame(r, p.version)
 	} else {
-		switch typeByte {
+		switch frameType {
 		case 0x1:
 			frame, err = parsePingFrame(r, p.version)
 		case 0x2, 0x3:
@@ -92,6 +106,10 @@ func (p *frameParser) parseFrame(r *bytes.Reader, typeByte byte, encLevel protoc
 			frame, err = parseConnectionCloseFrame(r, p.version)
 		case 0x1e:
 			frame, err = parseHandshakeDoneFrame(r, p.version)
+		case 0x1f:
+			frame, err = parseImmediateAckFrame(r, p.version)
+		case ackFrequencyFrameType:
+			frame, err = parseAckFrequencyFrame(r, p.version)
 		case 0x30, 0x31:
 			if p.supportsDatagrams {
 				frame, err = parseDatagramFrame(r, p.version)
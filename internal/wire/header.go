@@ -163,15 +163,28 @@ func (h *Header) parseLongHeader(b *bytes.Reader) error {
 		return ErrUnsupportedVersion
 	}
 
-	switch (h.typeByte & 0x30) >> 4 {
-	case 0x0:
-		h.Type = protocol.PacketTypeInitial
-	case 0x1:
-		h.Type = protocol.PacketType0RTT
-	case 0x2:
-		h.Type = protocol.PacketTypeHandshake
-	case 0x3:
-		h.Type = protocol.PacketTypeRetry
+	if h.Version == protocol.Version2 {
+		switch (h.typeByte & 0x30) >> 4 {
+		case 0x0:
+			h.Type = protocol.PacketTypeRetry
+		case 0x1:
+			h.Type = protocol.PacketTypeInitial
+		case 0x2:
+			h.Type = protocol.PacketType0RTT
+		case 0x3:
+			h.Type = protocol.PacketTypeHandshake
+		}
+	} else {
+		switch (h.typeByte & 0x30) >> 4 {
+		case 0x0:
+			h.Type = protocol.PacketTypeInitial
+		case 0x1:
+			h.Type = protocol.PacketType0RTT
+		case 0x2:
+			h.Type = protocol.PacketTypeHandshake
+		case 0x3:
+			h.Type = protocol.PacketTypeRetry
+		}
 	}
 
 	if h.Type == protocol.PacketTypeRetry {
@@ -246,6 +246,37 @@ var _ = Describe("Header Parsing", func() {
 			Expect(rest).To(BeEmpty())
 		})
 
+		It("parses QUIC v2 packet types", func() {
+			for _, t := range []protocol.PacketType{protocol.PacketTypeInitial, protocol.PacketType0RTT, protocol.PacketTypeHandshake} {
+				buf := &bytes.Buffer{}
+				Expect((&ExtendedHeader{
+					Header: Header{
+						IsLongHeader:     true,
+						Type:             t,
+						Version:          protocol.Version2,
+						DestConnectionID: protocol.ConnectionID{1, 2, 3, 4},
+						Length:           1,
+					},
+					PacketNumberLen: protocol.PacketNumberLen1,
+				}).Write(buf, protocol.Version2)).To(Succeed())
+				hdr, _, _, err := ParsePacket(buf.Bytes(), 0)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(hdr.Type).To(Equal(t))
+			}
+		})
+
+		It("parses a QUIC v2 Retry packet", func() {
+			data := []byte{0xc0 | 0x0<<4}
+			data = appendVersion(data, protocol.Version2)
+			data = append(data, []byte{0, 0}...)                         // conn ID lens
+			data = append(data, []byte{'f', 'o', 'o', 'b', 'a', 'r'}...) // token
+			data = append(data, []byte{16, 15, 14, 13, 12, 11, 10, 9, 8, 7, 6, 5, 4, 3, 2, 1}...)
+			hdr, _, _, err := ParsePacket(data, 0)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(hdr.Type).To(Equal(protocol.PacketTypeRetry))
+			Expect(hdr.Token).To(Equal([]byte("foobar")))
+		})
+
 		It("errors if the Retry packet is too short for the integrity tag", func() {
 			data := []byte{0xc0 | 0x3<<4 | (10 - 3) /* connection ID length */}
 			data = appendVersion(data, versionIETFFrames)
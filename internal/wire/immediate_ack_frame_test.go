@@ -0,0 +1,31 @@
+package wire
+
+import (
+	"bytes"
+	"io"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("IMMEDIATE_ACK frame", func() {
+	It("parses a frame", func() {
+		b := bytes.NewReader([]byte{0x1f})
+		_, err := parseImmediateAckFrame(b, versionIETFFrames)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(b.Len()).To(BeZero())
+	})
+
+	It("errors on EOF", func() {
+		_, err := parseImmediateAckFrame(bytes.NewReader(nil), versionIETFFrames)
+		Expect(err).To(MatchError(io.EOF))
+	})
+
+	It("writes a frame", func() {
+		b := &bytes.Buffer{}
+		frame := &ImmediateAckFrame{}
+		Expect(frame.Write(b, versionIETFFrames)).To(Succeed())
+		Expect(b.Bytes()).To(Equal([]byte{0x1f}))
+		Expect(frame.Length(versionIETFFrames)).To(BeEquivalentTo(1))
+	})
+})
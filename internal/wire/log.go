@@ -66,6 +66,8 @@ func LogFrame(logger utils.Logger, frame Frame, sent bool) {
 		logger.Debugf("\t%s &wire.NewConnectionIDFrame{SequenceNumber: %d, ConnectionID: %s, StatelessResetToken: %#x}", dir, f.SequenceNumber, f.ConnectionID, f.StatelessResetToken)
 	case *NewTokenFrame:
 		logger.Debugf("\t%s &wire.NewTokenFrame{Token: %#x}", dir, f.Token)
+	case *AckFrequencyFrame:
+		logger.Debugf("\t%s &wire.AckFrequencyFrame{SequenceNumber: %d, AckElicitingThreshold: %d, RequestMaxAckDelay: %s, ReorderingThreshold: %d}", dir, f.SequenceNumber, f.AckElicitingThreshold, f.RequestMaxAckDelay, f.ReorderingThreshold)
 	default:
 		logger.Debugf("\t%s %#v", dir, frame)
 	}
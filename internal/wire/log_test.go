@@ -165,4 +165,14 @@ var _ = Describe("Frame logging", func() {
 		}, true)
 		Expect(buf.String()).To(ContainSubstring("\t-> &wire.NewTokenFrame{Token: 0xdeadbeef"))
 	})
+
+	It("logs ACK_FREQUENCY frames", func() {
+		LogFrame(logger, &AckFrequencyFrame{
+			SequenceNumber:        3,
+			AckElicitingThreshold: 9,
+			RequestMaxAckDelay:    12 * time.Millisecond,
+			ReorderingThreshold:   1,
+		}, false)
+		Expect(buf.String()).To(ContainSubstring("\t<- &wire.AckFrequencyFrame{SequenceNumber: 3, AckElicitingThreshold: 9, RequestMaxAckDelay: 12ms, ReorderingThreshold: 1}"))
+	})
 })
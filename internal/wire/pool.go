@@ -22,6 +22,16 @@ func GetStreamFrame() *StreamFrame {
 	return f
 }
 
+// GetStreamFrameForSize returns a StreamFrame that can hold dataLen bytes of data.
+// StreamFrames that are larger than the StreamFrames in the pool (e.g. when using jumbo packets) are allocated,
+// and not put back into the pool.
+func GetStreamFrameForSize(dataLen protocol.ByteCount) *StreamFrame {
+	if dataLen > protocol.MaxReceivePacketSize {
+		return &StreamFrame{Data: make([]byte, 0, dataLen)}
+	}
+	return GetStreamFrame()
+}
+
 func putStreamFrame(f *StreamFrame) {
 	if !f.fromPool {
 		return
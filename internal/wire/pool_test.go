@@ -1,6 +1,8 @@
 package wire
 
 import (
+	"github.com/For-ACGN/quic-go/internal/protocol"
+
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
@@ -11,6 +13,15 @@ var _ = Describe("Pool", func() {
 		putStreamFrame(f)
 	})
 
+	It("gets STREAM frames for large data lengths", func() {
+		f := GetStreamFrameForSize(protocol.MaxReceivePacketSize)
+		Expect(f.Data).To(HaveCap(int(protocol.MaxReceivePacketSize)))
+		putStreamFrame(f)
+		f = GetStreamFrameForSize(protocol.MaxReceivePacketSize + 1)
+		Expect(cap(f.Data)).To(BeNumerically(">", protocol.MaxReceivePacketSize))
+		putStreamFrame(f)
+	})
+
 	It("panics when putting a STREAM frame with a wrong capacity", func() {
 		f := GetStreamFrame()
 		f.Data = []byte("foobar")
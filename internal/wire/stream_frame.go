@@ -59,12 +59,11 @@ func parseStreamFrame(r *bytes.Reader, _ protocol.VersionNumber) (*StreamFrame,
 	if dataLen < protocol.MinStreamFrameBufferSize {
 		frame = &StreamFrame{Data: make([]byte, dataLen)}
 	} else {
-		frame = GetStreamFrame()
-		// The STREAM frame can't be larger than the StreamFrame we obtained from the buffer,
-		// since those StreamFrames have a buffer length of the maximum packet size.
-		if dataLen > uint64(cap(frame.Data)) {
+		// The STREAM frame can't be larger than the rest of the packet.
+		if dataLen > uint64(r.Len()) {
 			return nil, io.EOF
 		}
+		frame = GetStreamFrameForSize(protocol.ByteCount(dataLen))
 		frame.Data = frame.Data[:dataLen]
 	}
 
@@ -167,7 +166,7 @@ func (f *StreamFrame) MaybeSplitOffFrame(maxSize protocol.ByteCount, version pro
 		return nil, true
 	}
 
-	new := GetStreamFrame()
+	new := GetStreamFrameForSize(protocol.ByteCount(len(f.Data)) - n)
 	new.StreamID = f.StreamID
 	new.Offset = f.Offset
 	new.Fin = false
@@ -84,12 +84,24 @@ var _ = Describe("STREAM frame", func() {
 			data := []byte{0x8 ^ 0x2}
 			data = append(data, encodeVarInt(0x12345)...)                                 // stream ID
 			data = append(data, encodeVarInt(uint64(protocol.MaxReceivePacketSize)+1)...) // data length
-			data = append(data, make([]byte, protocol.MaxReceivePacketSize+1)...)
+			data = append(data, make([]byte, protocol.MaxReceivePacketSize)...)
 			r := bytes.NewReader(data)
 			_, err := parseStreamFrame(r, versionIETFFrames)
 			Expect(err).To(Equal(io.EOF))
 		})
 
+		It("parses frames that are larger than the STREAM frames in the pool", func() {
+			data := []byte{0x8 ^ 0x2}
+			data = append(data, encodeVarInt(0x12345)...)                                // stream ID
+			data = append(data, encodeVarInt(uint64(protocol.MaxJumboPacketSize)-10)...) // data length
+			data = append(data, make([]byte, protocol.MaxJumboPacketSize-10)...)
+			r := bytes.NewReader(data)
+			f, err := parseStreamFrame(r, versionIETFFrames)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(f.Data).To(HaveLen(int(protocol.MaxJumboPacketSize) - 10))
+			Expect(r.Len()).To(BeZero())
+		})
+
 		It("errors on EOFs", func() {
 			data := []byte{0x8 ^ 0x4 ^ 0x2}
 			data = append(data, encodeVarInt(0x12345)...)    // stream ID
@@ -343,6 +355,19 @@ var _ = Describe("STREAM frame", func() {
 			f.PutBack()
 		})
 
+		It("splits frames that are larger than the STREAM frames in the pool", func() {
+			f := &StreamFrame{
+				StreamID: 0x1337,
+				Offset:   0x100,
+				Data:     make([]byte, 3*protocol.MaxReceivePacketSize),
+			}
+			frame, needsSplit := f.MaybeSplitOffFrame(100, versionIETFFrames)
+			Expect(needsSplit).To(BeTrue())
+			Expect(frame).ToNot(BeNil())
+			Expect(f.Offset).To(Equal(0x100 + frame.DataLen()))
+			Expect(frame.DataLen() + f.DataLen()).To(Equal(3 * protocol.MaxReceivePacketSize))
+		})
+
 		It("keeps the data len", func() {
 			f := &StreamFrame{
 				StreamID:       0x1337,
@@ -56,8 +56,12 @@ var _ = Describe("Transport Parameters", func() {
 			StatelessResetToken:             &protocol.StatelessResetToken{0x11, 0x22, 0x33, 0x44, 0x55, 0x66, 0x77, 0x88, 0x99, 0xaa, 0xbb, 0xcc, 0xdd, 0xee, 0xff, 0x00},
 			ActiveConnectionIDLimit:         123,
 			MaxDatagramFrameSize:            876,
+			VersionInformation: &VersionInformation{
+				ChosenVersion:     protocol.Version1,
+				AvailableVersions: []protocol.VersionNumber{protocol.Version2, protocol.Version1},
+			},
 		}
-		Expect(p.String()).To(Equal("&wire.TransportParameters{OriginalDestinationConnectionID: 0xdeadbeef, InitialSourceConnectionID: 0xdecafbad, RetrySourceConnectionID: 0xdeadc0de, InitialMaxStreamDataBidiLocal: 1234, InitialMaxStreamDataBidiRemote: 2345, InitialMaxStreamDataUni: 3456, InitialMaxData: 4567, MaxBidiStreamNum: 1337, MaxUniStreamNum: 7331, MaxIdleTimeout: 42s, AckDelayExponent: 14, MaxAckDelay: 37ms, ActiveConnectionIDLimit: 123, StatelessResetToken: 0x112233445566778899aabbccddeeff00, MaxDatagramFrameSize: 876}"))
+		Expect(p.String()).To(Equal("&wire.TransportParameters{OriginalDestinationConnectionID: 0xdeadbeef, InitialSourceConnectionID: 0xdecafbad, RetrySourceConnectionID: 0xdeadc0de, InitialMaxStreamDataBidiLocal: 1234, InitialMaxStreamDataBidiRemote: 2345, InitialMaxStreamDataUni: 3456, InitialMaxData: 4567, MaxBidiStreamNum: 1337, MaxUniStreamNum: 7331, MaxIdleTimeout: 42s, AckDelayExponent: 14, MaxAckDelay: 37ms, ActiveConnectionIDLimit: 123, StatelessResetToken: 0x112233445566778899aabbccddeeff00, MaxDatagramFrameSize: 876, ChosenVersion: v1, AvailableVersions: [v2 v1]}"))
 	})
 
 	It("has a string representation, if there's no stateless reset token, no Retry source connection id and no datagram support", func() {
@@ -99,6 +103,7 @@ var _ = Describe("Transport Parameters", func() {
 			MaxAckDelay:                     42 * time.Millisecond,
 			ActiveConnectionIDLimit:         getRandomValue(),
 			MaxDatagramFrameSize:            protocol.ByteCount(getRandomValue()),
+			MaxUDPPayloadSize:               1337 + protocol.ByteCount(getRandomValueUpTo(10000)),
 		}
 		data := params.Marshal(protocol.PerspectiveServer)
 
@@ -120,6 +125,14 @@ var _ = Describe("Transport Parameters", func() {
 		Expect(p.MaxAckDelay).To(Equal(42 * time.Millisecond))
 		Expect(p.ActiveConnectionIDLimit).To(Equal(params.ActiveConnectionIDLimit))
 		Expect(p.MaxDatagramFrameSize).To(Equal(params.MaxDatagramFrameSize))
+		Expect(p.MaxUDPPayloadSize).To(Equal(params.MaxUDPPayloadSize))
+	})
+
+	It("uses the default max_packet_size, if not set", func() {
+		data := (&TransportParameters{StatelessResetToken: &protocol.StatelessResetToken{}}).Marshal(protocol.PerspectiveServer)
+		p := &TransportParameters{}
+		Expect(p.Unmarshal(data, protocol.PerspectiveServer)).To(Succeed())
+		Expect(p.MaxUDPPayloadSize).To(Equal(protocol.MaxReceivePacketSize))
 	})
 
 	It("doesn't marshal a retry_source_connection_id, if no Retry was performed", func() {
@@ -390,6 +403,35 @@ var _ = Describe("Transport Parameters", func() {
 			Expect(p.PreferredAddress.StatelessResetToken).To(Equal(pa.StatelessResetToken))
 		})
 
+		It("marshals a preferred_address without an IPv4 address", func() {
+			pa.IPv4 = nil
+			pa.IPv4Port = 0
+			data := (&TransportParameters{
+				PreferredAddress:    pa,
+				StatelessResetToken: &protocol.StatelessResetToken{},
+			}).Marshal(protocol.PerspectiveServer)
+			p := &TransportParameters{}
+			Expect(p.Unmarshal(data, protocol.PerspectiveServer)).To(Succeed())
+			Expect(p.PreferredAddress.IPv4.IsUnspecified()).To(BeTrue())
+			Expect(p.PreferredAddress.IPv4Port).To(BeZero())
+			Expect(p.PreferredAddress.IPv6.String()).To(Equal(pa.IPv6.String()))
+			Expect(p.PreferredAddress.IPv6Port).To(Equal(pa.IPv6Port))
+		})
+
+		It("marshals a preferred_address without an IPv6 address", func() {
+			pa.IPv6 = nil
+			pa.IPv6Port = 0
+			data := (&TransportParameters{
+				PreferredAddress:    pa,
+				StatelessResetToken: &protocol.StatelessResetToken{},
+			}).Marshal(protocol.PerspectiveServer)
+			p := &TransportParameters{}
+			Expect(p.Unmarshal(data, protocol.PerspectiveServer)).To(Succeed())
+			Expect(p.PreferredAddress.IPv4.String()).To(Equal(pa.IPv4.String()))
+			Expect(p.PreferredAddress.IPv6.IsUnspecified()).To(BeTrue())
+			Expect(p.PreferredAddress.IPv6Port).To(BeZero())
+		})
+
 		It("errors if the client sent a preferred_address", func() {
 			b := &bytes.Buffer{}
 			quicvarint.Write(b, uint64(preferredAddressParameterID))
@@ -440,6 +482,107 @@ var _ = Describe("Transport Parameters", func() {
 		})
 	})
 
+	Context("version information", func() {
+		It("marshals and unmarshals", func() {
+			vi := &VersionInformation{
+				ChosenVersion:     protocol.Version1,
+				AvailableVersions: []protocol.VersionNumber{protocol.Version2, protocol.Version1},
+			}
+			data := (&TransportParameters{VersionInformation: vi}).Marshal(protocol.PerspectiveClient)
+			p := &TransportParameters{}
+			Expect(p.Unmarshal(data, protocol.PerspectiveClient)).To(Succeed())
+			Expect(p.VersionInformation).To(Equal(vi))
+		})
+
+		It("marshals and unmarshals without available versions", func() {
+			data := (&TransportParameters{
+				VersionInformation: &VersionInformation{ChosenVersion: protocol.Version2},
+			}).Marshal(protocol.PerspectiveClient)
+			p := &TransportParameters{}
+			Expect(p.Unmarshal(data, protocol.PerspectiveClient)).To(Succeed())
+			Expect(p.VersionInformation.ChosenVersion).To(Equal(protocol.Version2))
+			Expect(p.VersionInformation.AvailableVersions).To(BeEmpty())
+		})
+
+		It("doesn't marshal the version_information, if not set", func() {
+			data := (&TransportParameters{}).Marshal(protocol.PerspectiveClient)
+			p := &TransportParameters{}
+			Expect(p.Unmarshal(data, protocol.PerspectiveClient)).To(Succeed())
+			Expect(p.VersionInformation).To(BeNil())
+		})
+
+		It("errors if the length is not a multiple of 4", func() {
+			b := &bytes.Buffer{}
+			addInitialSourceConnectionID(b)
+			quicvarint.Write(b, uint64(versionInformationParameterID))
+			quicvarint.Write(b, 6)
+			b.Write([]byte{0, 0, 0, 1, 0, 0})
+			p := &TransportParameters{}
+			Expect(p.Unmarshal(b.Bytes(), protocol.PerspectiveClient)).To(MatchError("TRANSPORT_PARAMETER_ERROR: invalid length for version_information: 6"))
+		})
+
+		It("errors if the chosen version is missing", func() {
+			b := &bytes.Buffer{}
+			addInitialSourceConnectionID(b)
+			quicvarint.Write(b, uint64(versionInformationParameterID))
+			quicvarint.Write(b, 0)
+			p := &TransportParameters{}
+			Expect(p.Unmarshal(b.Bytes(), protocol.PerspectiveClient)).To(MatchError("TRANSPORT_PARAMETER_ERROR: invalid length for version_information: 0"))
+		})
+
+		It("errors if it contains version 0", func() {
+			b := &bytes.Buffer{}
+			addInitialSourceConnectionID(b)
+			quicvarint.Write(b, uint64(versionInformationParameterID))
+			quicvarint.Write(b, 8)
+			b.Write([]byte{0, 0, 0, 1, 0, 0, 0, 0})
+			p := &TransportParameters{}
+			Expect(p.Unmarshal(b.Bytes(), protocol.PerspectiveClient)).To(MatchError("TRANSPORT_PARAMETER_ERROR: version_information contains version 0"))
+		})
+	})
+
+	Context("min_ack_delay", func() {
+		It("marshals and unmarshals", func() {
+			minAckDelay := 1337 * time.Microsecond
+			data := (&TransportParameters{
+				MaxAckDelay: protocol.DefaultMaxAckDelay,
+				MinAckDelay: &minAckDelay,
+			}).Marshal(protocol.PerspectiveClient)
+			p := &TransportParameters{}
+			Expect(p.Unmarshal(data, protocol.PerspectiveClient)).To(Succeed())
+			Expect(p.MinAckDelay).ToNot(BeNil())
+			Expect(*p.MinAckDelay).To(Equal(minAckDelay))
+		})
+
+		It("doesn't marshal the min_ack_delay, if not set", func() {
+			data := (&TransportParameters{MaxAckDelay: protocol.DefaultMaxAckDelay}).Marshal(protocol.PerspectiveClient)
+			p := &TransportParameters{}
+			Expect(p.Unmarshal(data, protocol.PerspectiveClient)).To(Succeed())
+			Expect(p.MinAckDelay).To(BeNil())
+		})
+
+		It("errors if the min_ack_delay is larger than the max_ack_delay", func() {
+			minAckDelay := 30 * time.Millisecond
+			data := (&TransportParameters{
+				MaxAckDelay: 20 * time.Millisecond,
+				MinAckDelay: &minAckDelay,
+			}).Marshal(protocol.PerspectiveClient)
+			p := &TransportParameters{}
+			Expect(p.Unmarshal(data, protocol.PerspectiveClient)).To(MatchError("TRANSPORT_PARAMETER_ERROR: min_ack_delay (30ms) larger than max_ack_delay (20ms)"))
+		})
+
+		It("errors if the min_ack_delay is too large", func() {
+			b := &bytes.Buffer{}
+			addInitialSourceConnectionID(b)
+			val := uint64(1<<14) * 1000
+			quicvarint.Write(b, uint64(minAckDelayParameterID))
+			quicvarint.Write(b, uint64(quicvarint.Len(val)))
+			quicvarint.Write(b, val)
+			p := &TransportParameters{}
+			Expect(p.Unmarshal(b.Bytes(), protocol.PerspectiveClient)).To(MatchError("TRANSPORT_PARAMETER_ERROR: invalid value for min_ack_delay: 16384000us"))
+		})
+	})
+
 	Context("saving and retrieving from a session ticket", func() {
 		It("saves and retrieves the parameters", func() {
 			params := &TransportParameters{
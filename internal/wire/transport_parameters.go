@@ -42,8 +42,12 @@ const (
 	activeConnectionIDLimitParameterID         transportParameterID = 0xe
 	initialSourceConnectionIDParameterID       transportParameterID = 0xf
 	retrySourceConnectionIDParameterID         transportParameterID = 0x10
+	// https://datatracker.ietf.org/doc/rfc9368/
+	versionInformationParameterID transportParameterID = 0x11
 	// https://datatracker.ietf.org/doc/draft-ietf-quic-datagram/
 	maxDatagramFrameSizeParameterID transportParameterID = 0x20
+	// https://datatracker.ietf.org/doc/draft-ietf-quic-ack-frequency/
+	minAckDelayParameterID transportParameterID = 0xff04de1b
 )
 
 // PreferredAddress is the value encoding in the preferred_address transport parameter
@@ -56,6 +60,12 @@ type PreferredAddress struct {
 	StatelessResetToken protocol.StatelessResetToken
 }
 
+// VersionInformation is the value encoded in the version_information transport parameter
+type VersionInformation struct {
+	ChosenVersion     protocol.VersionNumber
+	AvailableVersions []protocol.VersionNumber
+}
+
 // TransportParameters are parameters sent to the peer during the handshake
 type TransportParameters struct {
 	InitialMaxStreamDataBidiLocal  protocol.ByteCount
@@ -65,6 +75,8 @@ type TransportParameters struct {
 
 	MaxAckDelay      time.Duration
 	AckDelayExponent uint8
+	// MinAckDelay is set if the endpoint supports the ACK frequency extension.
+	MinAckDelay *time.Duration
 
 	DisableActiveMigration bool
 
@@ -85,6 +97,8 @@ type TransportParameters struct {
 	ActiveConnectionIDLimit uint64
 
 	MaxDatagramFrameSize protocol.ByteCount
+
+	VersionInformation *VersionInformation
 }
 
 // Unmarshal the transport parameters
@@ -181,6 +195,14 @@ func (p *TransportParameters) unmarshal(r *bytes.Reader, sentBy protocol.Perspec
 			}
 			connID, _ := protocol.ReadConnectionID(r, int(paramLen))
 			p.RetrySourceConnectionID = &connID
+		case versionInformationParameterID:
+			if err := p.readVersionInformation(r, int(paramLen)); err != nil {
+				return err
+			}
+		case minAckDelayParameterID:
+			if err := p.readNumericTransportParameter(r, paramID, int(paramLen)); err != nil {
+				return err
+			}
 		default:
 			r.Seek(int64(paramLen), io.SeekCurrent)
 		}
@@ -198,6 +220,10 @@ func (p *TransportParameters) unmarshal(r *bytes.Reader, sentBy protocol.Perspec
 		}
 	}
 
+	if p.MinAckDelay != nil && *p.MinAckDelay > p.MaxAckDelay {
+		return fmt.Errorf("min_ack_delay (%s) larger than max_ack_delay (%s)", *p.MinAckDelay, p.MaxAckDelay)
+	}
+
 	// check that every transport parameter was sent at most once
 	sort.Slice(parameterIDs, func(i, j int) bool { return parameterIDs[i] < parameterIDs[j] })
 	for i := 0; i < len(parameterIDs)-1; i++ {
@@ -254,6 +280,36 @@ func (p *TransportParameters) readPreferredAddress(r *bytes.Reader, expectedLen
 	return nil
 }
 
+func (p *TransportParameters) readVersionInformation(r *bytes.Reader, l int) error {
+	if l < 4 || l%4 != 0 {
+		return fmt.Errorf("invalid length for version_information: %d", l)
+	}
+	readVersion := func() (protocol.VersionNumber, error) {
+		v, err := utils.BigEndian.ReadUint32(r)
+		if err != nil {
+			return 0, err
+		}
+		if v == 0 {
+			return 0, errors.New("version_information contains version 0")
+		}
+		return protocol.VersionNumber(v), nil
+	}
+	chosen, err := readVersion()
+	if err != nil {
+		return err
+	}
+	vi := &VersionInformation{ChosenVersion: chosen}
+	for i := 4; i < l; i += 4 {
+		v, err := readVersion()
+		if err != nil {
+			return err
+		}
+		vi.AvailableVersions = append(vi.AvailableVersions, v)
+	}
+	p.VersionInformation = vi
+	return nil
+}
+
 func (p *TransportParameters) readNumericTransportParameter(
 	r *bytes.Reader,
 	paramID transportParameterID,
@@ -312,6 +368,13 @@ func (p *TransportParameters) readNumericTransportParameter(
 		p.ActiveConnectionIDLimit = val
 	case maxDatagramFrameSizeParameterID:
 		p.MaxDatagramFrameSize = protocol.ByteCount(val)
+	case minAckDelayParameterID:
+		// min_ack_delay is encoded in microseconds
+		if val > uint64(protocol.MaxMaxAckDelay/time.Microsecond) {
+			return fmt.Errorf("invalid value for min_ack_delay: %dus", val)
+		}
+		minAckDelay := time.Duration(val) * time.Microsecond
+		p.MinAckDelay = &minAckDelay
 	default:
 		return fmt.Errorf("TransportParameter BUG: transport parameter %d not found", paramID)
 	}
@@ -345,12 +408,20 @@ func (p *TransportParameters) Marshal(pers protocol.Perspective) []byte {
 	// idle_timeout
 	p.marshalVarintParam(b, maxIdleTimeoutParameterID, uint64(p.MaxIdleTimeout/time.Millisecond))
 	// max_packet_size
-	p.marshalVarintParam(b, maxUDPPayloadSizeParameterID, uint64(protocol.MaxReceivePacketSize))
+	maxUDPPayloadSize := p.MaxUDPPayloadSize
+	if maxUDPPayloadSize == 0 {
+		maxUDPPayloadSize = protocol.MaxReceivePacketSize
+	}
+	p.marshalVarintParam(b, maxUDPPayloadSizeParameterID, uint64(maxUDPPayloadSize))
 	// max_ack_delay
 	// Only send it if is different from the default value.
 	if p.MaxAckDelay != protocol.DefaultMaxAckDelay {
 		p.marshalVarintParam(b, maxAckDelayParameterID, uint64(p.MaxAckDelay/time.Millisecond))
 	}
+	// min_ack_delay
+	if p.MinAckDelay != nil {
+		p.marshalVarintParam(b, minAckDelayParameterID, uint64(*p.MinAckDelay/time.Microsecond))
+	}
 	// ack_delay_exponent
 	// Only send it if is different from the default value.
 	if p.AckDelayExponent != protocol.DefaultAckDelayExponent {
@@ -376,10 +447,18 @@ func (p *TransportParameters) Marshal(pers protocol.Perspective) []byte {
 		if p.PreferredAddress != nil {
 			quicvarint.Write(b, uint64(preferredAddressParameterID))
 			quicvarint.Write(b, 4+2+16+2+1+uint64(p.PreferredAddress.ConnectionID.Len())+16)
-			ipv4 := p.PreferredAddress.IPv4
-			b.Write(ipv4[len(ipv4)-4:])
+			// An address that is not set is encoded as the unspecified address.
+			ipv4 := make([]byte, 4)
+			if p.PreferredAddress.IPv4 != nil {
+				copy(ipv4, p.PreferredAddress.IPv4.To4())
+			}
+			b.Write(ipv4)
 			utils.BigEndian.WriteUint16(b, p.PreferredAddress.IPv4Port)
-			b.Write(p.PreferredAddress.IPv6)
+			ipv6 := make([]byte, 16)
+			if p.PreferredAddress.IPv6 != nil {
+				copy(ipv6, p.PreferredAddress.IPv6.To16())
+			}
+			b.Write(ipv6)
 			utils.BigEndian.WriteUint16(b, p.PreferredAddress.IPv6Port)
 			b.WriteByte(uint8(p.PreferredAddress.ConnectionID.Len()))
 			b.Write(p.PreferredAddress.ConnectionID.Bytes())
@@ -401,6 +480,15 @@ func (p *TransportParameters) Marshal(pers protocol.Perspective) []byte {
 	if p.MaxDatagramFrameSize != protocol.InvalidByteCount {
 		p.marshalVarintParam(b, maxDatagramFrameSizeParameterID, uint64(p.MaxDatagramFrameSize))
 	}
+	// version_information
+	if p.VersionInformation != nil {
+		quicvarint.Write(b, uint64(versionInformationParameterID))
+		quicvarint.Write(b, 4*uint64(1+len(p.VersionInformation.AvailableVersions)))
+		utils.BigEndian.WriteUint32(b, uint32(p.VersionInformation.ChosenVersion))
+		for _, v := range p.VersionInformation.AvailableVersions {
+			utils.BigEndian.WriteUint32(b, uint32(v))
+		}
+	}
 	return b.Bytes()
 }
 
@@ -477,6 +565,14 @@ func (p *TransportParameters) String() string {
 		logString += ", MaxDatagramFrameSize: %d"
 		logParams = append(logParams, p.MaxDatagramFrameSize)
 	}
+	if p.MinAckDelay != nil {
+		logString += ", MinAckDelay: %s"
+		logParams = append(logParams, *p.MinAckDelay)
+	}
+	if p.VersionInformation != nil {
+		logString += ", ChosenVersion: %s, AvailableVersions: %s"
+		logParams = append(logParams, p.VersionInformation.ChosenVersion, p.VersionInformation.AvailableVersions)
+	}
 	logString += "}"
 	return fmt.Sprintf(logString, logParams...)
 }
@@ -135,6 +135,12 @@ var _ = Describe("Tracing", func() {
 			tracer.SentPacket(hdr, 1337, ack, []Frame{ping})
 		})
 
+		It("traces the NegotiatedVersion event", func() {
+			tr1.EXPECT().NegotiatedVersion(VersionNumber(2), []VersionNumber{1, 2}, []VersionNumber{2})
+			tr2.EXPECT().NegotiatedVersion(VersionNumber(2), []VersionNumber{1, 2}, []VersionNumber{2})
+			tracer.NegotiatedVersion(2, []VersionNumber{1, 2}, []VersionNumber{2})
+		})
+
 		It("traces the ReceivedVersionNegotiationPacket event", func() {
 			hdr := &Header{DestConnectionID: ConnectionID{1, 2, 3}}
 			tr1.EXPECT().ReceivedVersionNegotiationPacket(hdr, []VersionNumber{1337})
@@ -195,6 +201,12 @@ var _ = Describe("Tracing", func() {
 			tracer.UpdatedPTOCount(88)
 		})
 
+		It("traces the ECNStateUpdated event", func() {
+			tr1.EXPECT().ECNStateUpdated(ECNStateFailed, ECNFailedTooFewECNCounts)
+			tr2.EXPECT().ECNStateUpdated(ECNStateFailed, ECNFailedTooFewECNCounts)
+			tracer.ECNStateUpdated(ECNStateFailed, ECNFailedTooFewECNCounts)
+		})
+
 		It("traces the UpdatedKeyFromTLS event", func() {
 			tr1.EXPECT().UpdatedKeyFromTLS(EncryptionHandshake, PerspectiveClient)
 			tr2.EXPECT().UpdatedKeyFromTLS(EncryptionHandshake, PerspectiveClient)
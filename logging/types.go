@@ -94,3 +94,38 @@ const (
 	// CongestionStateApplicationLimited means that the congestion controller is application limited
 	CongestionStateApplicationLimited
 )
+
+// ECNState is the state of the ECN validation.
+type ECNState uint8
+
+const (
+	// ECNStateTesting is the testing state: The first packets are sent with ECT(0).
+	ECNStateTesting ECNState = iota + 1
+	// ECNStateUnknown is the state after the testing packets were sent, before any of them was acknowledged.
+	ECNStateUnknown
+	// ECNStateFailed is the state when ECN validation failed. No more packets are sent with ECN marks.
+	ECNStateFailed
+	// ECNStateCapable is the state when the path was validated to support ECN.
+	ECNStateCapable
+)
+
+// ECNStateTrigger is the reason for a change of the ECN state.
+type ECNStateTrigger uint8
+
+const (
+	// ECNTriggerNoTrigger is used if there's no trigger for the ECN state change
+	ECNTriggerNoTrigger ECNStateTrigger = iota
+	// ECNFailedNoECNCounts is used if an ACK acknowledges packets sent with ECN marks,
+	// but doesn't contain any ECN counts
+	ECNFailedNoECNCounts
+	// ECNFailedDecreasedECNCounts is used if an ACK frame decreases ECN counts
+	ECNFailedDecreasedECNCounts
+	// ECNFailedLostAllTestingPackets is used if all packets sent with ECN marks during the testing period were declared lost
+	ECNFailedLostAllTestingPackets
+	// ECNFailedMoreECNCountsThanSent is used if the ECN counts exceed the number of packets sent with the respective ECN marks
+	ECNFailedMoreECNCountsThanSent
+	// ECNFailedTooFewECNCounts is used if an ACK acknowledges more packets sent with ECN marks than the increase of the ECN counts
+	ECNFailedTooFewECNCounts
+	// ECNFailedManglingDetected is used if all packets sent with ECN marks during the testing period were reported as CE
+	ECNFailedManglingDetected
+)
@@ -0,0 +1,250 @@
+package masque
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/For-ACGN/quic-go/http3"
+)
+
+var errTunnelClosed = errors.New("masque: tunnel closed")
+
+// A Client establishes UDP tunnels through a CONNECT-UDP proxy.
+type Client struct {
+	// Template is the URI template of the proxy.
+	Template *Template
+	// RoundTripper is used to send the CONNECT-UDP requests.
+	// It must have EnableDatagrams set.
+	RoundTripper *http3.RoundTripper
+}
+
+// Dial establishes a UDP tunnel to the target (host:port) through the proxy.
+// The host is resolved by the proxy.
+// The context only applies to establishing the tunnel.
+//
+// The returned net.PacketConn sends all packets to the target, regardless of the address passed to WriteTo.
+// Every tunnel has its own local address, such that QUIC connections can be dialed on multiple tunnels.
+// The tunnel is closed when the net.PacketConn is closed.
+func (c *Client) Dial(ctx context.Context, target string) (net.PacketConn, error) {
+	if !c.RoundTripper.EnableDatagrams {
+		return nil, errors.New("masque: HTTP datagrams not enabled")
+	}
+	host, portStr, err := net.SplitHostPort(target)
+	if err != nil {
+		return nil, err
+	}
+	port, err := strconv.ParseUint(portStr, 10, 16)
+	if err != nil || port == 0 {
+		return nil, fmt.Errorf("masque: invalid port: %s", portStr)
+	}
+
+	// The request body is used to close the request stream when the tunnel is closed.
+	pr, pw := io.Pipe()
+	reqCtx, cancel := context.WithCancel(context.Background())
+	req, err := http.NewRequestWithContext(reqCtx, http.MethodConnect, c.Template.expand(host, int(port)), pr)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	req.Proto = ConnectUDPProtocol
+	req.Header.Set(http3.CapsuleProtocolHeader, "?1")
+
+	// cancel the request if the context is canceled before the tunnel is established
+	dialDone := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			cancel()
+		case <-dialDone:
+		}
+	}()
+	rsp, err := c.RoundTripper.RoundTrip(req)
+	close(dialDone)
+	if err != nil {
+		pw.Close()
+		cancel()
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, err
+	}
+	if ctx.Err() != nil {
+		pw.Close()
+		rsp.Body.Close()
+		cancel()
+		return nil, ctx.Err()
+	}
+	if rsp.StatusCode < 200 || rsp.StatusCode > 299 {
+		pw.Close()
+		rsp.Body.Close()
+		cancel()
+		return nil, fmt.Errorf("masque: CONNECT-UDP request failed: %s", rsp.Status)
+	}
+	d, ok := rsp.Body.(http3.Datagrammer)
+	if !ok {
+		pw.Close()
+		rsp.Body.Close()
+		cancel()
+		return nil, errors.New("masque: response body doesn't support HTTP datagrams")
+	}
+	return newProxiedConn(pw, rsp.Body, d, cancel, req.URL.Host, target), nil
+}
+
+// tunnelID is used to assign a unique local address to every tunnel
+var tunnelID uint64
+
+// tunnelAddr is the address of an endpoint of a tunnel.
+type tunnelAddr struct {
+	addr string
+}
+
+var _ net.Addr = &tunnelAddr{}
+
+func (a *tunnelAddr) Network() string { return ConnectUDPProtocol }
+func (a *tunnelAddr) String() string  { return a.addr }
+
+// A proxiedConn is a net.PacketConn that sends and receives UDP payloads through a CONNECT-UDP tunnel.
+type proxiedConn struct {
+	reqBody   io.Closer // closing the request body closes the request stream
+	rspBody   io.ReadCloser
+	datagrams http3.Datagrammer
+	// cancelRequest cancels the context of the CONNECT-UDP request
+	cancelRequest context.CancelFunc
+
+	localAddr, remoteAddr net.Addr
+
+	ctx       context.Context // canceled when the tunnel is closed
+	cancel    context.CancelFunc
+	closeOnce sync.Once
+
+	mutex        sync.Mutex
+	readDeadline time.Time
+	// deadlineGen is incremented every time the read deadline is changed.
+	// Changing the deadline cancels a running read (using cancelRead), which is then restarted.
+	deadlineGen uint64
+	cancelRead  context.CancelFunc
+}
+
+var _ net.PacketConn = &proxiedConn{}
+
+func newProxiedConn(reqBody io.Closer, rspBody io.ReadCloser, d http3.Datagrammer, cancelRequest context.CancelFunc, proxy, target string) *proxiedConn {
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &proxiedConn{
+		reqBody:       reqBody,
+		rspBody:       rspBody,
+		datagrams:     d,
+		cancelRequest: cancelRequest,
+		localAddr:     &tunnelAddr{addr: fmt.Sprintf("%s#%d", proxy, atomic.AddUint64(&tunnelID, 1))},
+		remoteAddr:    &tunnelAddr{addr: target},
+		ctx:           ctx,
+		cancel:        cancel,
+	}
+	go c.run()
+	return c
+}
+
+// run reads the response body until the proxy closes the tunnel.
+// Capsules sent by the proxy are ignored.
+func (c *proxiedConn) run() {
+	io.Copy(ioutil.Discard, c.rspBody)
+	c.Close()
+}
+
+// ReadFrom reads the next UDP payload received from the target.
+func (c *proxiedConn) ReadFrom(b []byte) (int, net.Addr, error) {
+	for {
+		ctx, cancel, gen := c.readContext()
+		data, err := c.datagrams.ReceiveDatagram(ctx)
+		cancel()
+		if err != nil {
+			if c.ctx.Err() != nil {
+				return 0, nil, errTunnelClosed
+			}
+			c.mutex.Lock()
+			deadlineChanged := c.deadlineGen != gen
+			c.mutex.Unlock()
+			if deadlineChanged {
+				continue
+			}
+			if ctx.Err() == context.DeadlineExceeded {
+				return 0, nil, errDeadline
+			}
+			return 0, nil, err
+		}
+		if payload, ok := parseUDPPayload(data); ok {
+			return copy(b, payload), c.remoteAddr, nil
+		}
+	}
+}
+
+func (c *proxiedConn) readContext() (context.Context, context.CancelFunc, uint64) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	var ctx context.Context
+	var cancel context.CancelFunc
+	if c.readDeadline.IsZero() {
+		ctx, cancel = context.WithCancel(c.ctx)
+	} else {
+		ctx, cancel = context.WithDeadline(c.ctx, c.readDeadline)
+	}
+	c.cancelRead = cancel
+	return ctx, cancel, c.deadlineGen
+}
+
+// WriteTo sends a UDP payload to the target. The address is ignored.
+// Like on any other UDP path, payloads that can't be sent (e.g. because they're too large) are dropped.
+func (c *proxiedConn) WriteTo(b []byte, _ net.Addr) (int, error) {
+	if c.ctx.Err() != nil {
+		return 0, errTunnelClosed
+	}
+	if err := c.datagrams.SendDatagram(composeUDPPayload(b)); err != nil {
+		logger.Debugf("Sending HTTP datagram failed: %s", err)
+	}
+	return len(b), nil
+}
+
+// Close closes the tunnel.
+func (c *proxiedConn) Close() error {
+	c.closeOnce.Do(func() {
+		c.cancel()
+		c.reqBody.Close()
+		c.rspBody.Close()
+		// Closing the response body completes the request,
+		// so canceling its context doesn't reset the request stream any more.
+		c.cancelRequest()
+	})
+	return nil
+}
+
+func (c *proxiedConn) LocalAddr() net.Addr {
+	return c.localAddr
+}
+
+func (c *proxiedConn) SetDeadline(t time.Time) error {
+	return c.SetReadDeadline(t)
+}
+
+func (c *proxiedConn) SetReadDeadline(t time.Time) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.readDeadline = t
+	c.deadlineGen++
+	if c.cancelRead != nil {
+		c.cancelRead()
+	}
+	return nil
+}
+
+// SetWriteDeadline is a no-op, since WriteTo doesn't block.
+func (c *proxiedConn) SetWriteDeadline(time.Time) error {
+	return nil
+}
@@ -0,0 +1,131 @@
+package masque
+
+import (
+	"context"
+	"io"
+	"net"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type mockDatagrammer struct {
+	sent     chan []byte
+	received chan []byte
+}
+
+func (d *mockDatagrammer) SendDatagram(b []byte) error {
+	d.sent <- b
+	return nil
+}
+
+func (d *mockDatagrammer) ReceiveDatagram(ctx context.Context) ([]byte, error) {
+	select {
+	case b := <-d.received:
+		return b, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+type mockCloser struct {
+	closed chan struct{}
+}
+
+func (c *mockCloser) Close() error {
+	close(c.closed)
+	return nil
+}
+
+var _ = Describe("Proxied Conn", func() {
+	var (
+		conn      *proxiedConn
+		datagrams *mockDatagrammer
+		reqBody   *mockCloser
+		rspBody   *io.PipeWriter
+		reqCtx    context.Context
+	)
+
+	BeforeEach(func() {
+		datagrams = &mockDatagrammer{
+			sent:     make(chan []byte, 10),
+			received: make(chan []byte, 10),
+		}
+		reqBody = &mockCloser{closed: make(chan struct{})}
+		var pr *io.PipeReader
+		pr, rspBody = io.Pipe()
+		var cancel context.CancelFunc
+		reqCtx, cancel = context.WithCancel(context.Background())
+		conn = newProxiedConn(reqBody, pr, datagrams, cancel, "proxy.example.org", "example.com:443")
+	})
+
+	AfterEach(func() {
+		Expect(conn.Close()).To(Succeed())
+	})
+
+	It("sends and receives UDP payloads", func() {
+		Expect(conn.remoteAddr.String()).To(Equal("example.com:443"))
+		n, err := conn.WriteTo([]byte("foobar"), &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 443})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(n).To(Equal(6))
+		Expect(datagrams.sent).To(Receive(Equal(append([]byte{0}, []byte("foobar")...))))
+
+		datagrams.received <- append([]byte{1}, []byte("unknown context ID")...)
+		datagrams.received <- append([]byte{0}, []byte("lorem ipsum")...)
+		b := make([]byte, 100)
+		n, addr, err := conn.ReadFrom(b)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(b[:n]).To(Equal([]byte("lorem ipsum")))
+		Expect(addr).To(Equal(conn.remoteAddr))
+	})
+
+	It("uses a unique local address for every tunnel", func() {
+		pr, _ := io.Pipe()
+		conn2 := newProxiedConn(&mockCloser{closed: make(chan struct{})}, pr, datagrams, func() {}, "proxy.example.org", "example.com:443")
+		defer conn2.Close()
+		Expect(conn.LocalAddr().Network()).To(Equal("connect-udp"))
+		Expect(conn.LocalAddr().String()).ToNot(Equal(conn2.LocalAddr().String()))
+	})
+
+	It("respects the read deadline", func() {
+		Expect(conn.SetReadDeadline(time.Now().Add(-time.Second))).To(Succeed())
+		_, _, err := conn.ReadFrom(make([]byte, 100))
+		Expect(err).To(MatchError(errDeadline))
+		Expect(err.(net.Error).Timeout()).To(BeTrue())
+
+		Expect(conn.SetReadDeadline(time.Time{})).To(Succeed())
+		errChan := make(chan error, 1)
+		go func() {
+			_, _, err := conn.ReadFrom(make([]byte, 100))
+			errChan <- err
+		}()
+		Consistently(errChan, scaleDuration(50*time.Millisecond)).ShouldNot(Receive())
+		// setting a deadline unblocks the running read
+		deadline := time.Now().Add(scaleDuration(50 * time.Millisecond))
+		Expect(conn.SetDeadline(deadline)).To(Succeed())
+		Eventually(errChan).Should(Receive(MatchError(errDeadline)))
+		Expect(time.Now()).To(BeTemporally(">=", deadline))
+	})
+
+	It("closes the tunnel", func() {
+		errChan := make(chan error, 1)
+		go func() {
+			_, _, err := conn.ReadFrom(make([]byte, 100))
+			errChan <- err
+		}()
+		Expect(conn.Close()).To(Succeed())
+		Eventually(errChan).Should(Receive(MatchError(errTunnelClosed)))
+		Expect(reqBody.closed).To(BeClosed())
+		Expect(reqCtx.Done()).To(BeClosed())
+		_, err := conn.WriteTo([]byte("foobar"), nil)
+		Expect(err).To(MatchError(errTunnelClosed))
+	})
+
+	It("closes the tunnel when the proxy closes the request stream", func() {
+		rspBody.Close()
+		Eventually(reqBody.closed).Should(BeClosed())
+		_, _, err := conn.ReadFrom(make([]byte, 100))
+		Expect(err).To(MatchError(errTunnelClosed))
+	})
+})
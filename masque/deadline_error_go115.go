@@ -0,0 +1,9 @@
+// +build go1.15
+
+package masque
+
+import (
+	"os"
+)
+
+func (deadlineError) Unwrap() error { return os.ErrDeadlineExceeded }
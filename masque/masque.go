@@ -0,0 +1,46 @@
+// Package masque implements proxying UDP in HTTP (CONNECT-UDP, RFC 9298) on top of the http3 package.
+//
+// The Proxy is an http.Handler for the http3.Server, which relays UDP payloads between HTTP datagrams and
+// the target. The Client establishes UDP tunnels through the proxy. Every tunnel is exposed as a
+// net.PacketConn, which can be used to run QUIC connections through the proxy.
+//
+// QUIC packets are at least 1200 bytes large. To tunnel QUIC connections, the DATAGRAM frames
+// on the connection to the proxy need to be larger than that, so path MTU discovery
+// (quic.Config.EnablePathMTUDiscovery) should be enabled on the client and on the proxy.
+package masque
+
+import (
+	"bytes"
+
+	"github.com/For-ACGN/quic-go/internal/utils"
+	"github.com/For-ACGN/quic-go/quicvarint"
+)
+
+var logger = utils.DefaultLogger.WithPrefix("masque")
+
+// ConnectUDPProtocol is the value of the :protocol pseudo header of CONNECT-UDP requests.
+// The http3 package carries it in http.Request.Proto.
+const ConnectUDPProtocol = "connect-udp"
+
+// contextIDUDPPayload is the context ID of HTTP datagrams that carry a UDP payload, see RFC 9298, section 5.
+const contextIDUDPPayload = 0
+
+// composeUDPPayload composes the HTTP datagram payload carrying a UDP payload.
+func composeUDPPayload(b []byte) []byte {
+	buf := &bytes.Buffer{}
+	buf.Grow(1 + len(b))
+	quicvarint.Write(buf, contextIDUDPPayload)
+	buf.Write(b)
+	return buf.Bytes()
+}
+
+// parseUDPPayload parses the payload of an HTTP datagram.
+// HTTP datagrams with an unknown context ID are ignored, and ok is false.
+func parseUDPPayload(data []byte) (payload []byte, ok bool) {
+	r := bytes.NewReader(data)
+	contextID, err := quicvarint.Read(r)
+	if err != nil || contextID != contextIDUDPPayload {
+		return nil, false
+	}
+	return data[len(data)-r.Len():], true
+}
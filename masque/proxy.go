@@ -0,0 +1,126 @@
+package masque
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strconv"
+
+	"github.com/For-ACGN/quic-go/http3"
+	"github.com/For-ACGN/quic-go/internal/protocol"
+)
+
+// A Proxy is an http.Handler that proxies UDP in HTTP (CONNECT-UDP, RFC 9298).
+// For every CONNECT-UDP request, it opens a UDP socket to the target,
+// and relays UDP payloads between the target and the HTTP datagrams of the request.
+// The tunnel is closed when the client closes the request stream.
+//
+// The Proxy must be served by an http3.Server that has EnableExtendedConnect and EnableDatagrams set.
+// It doesn't restrict the targets that can be reached, applications that expose it
+// should authenticate requests before calling ServeHTTP.
+type Proxy struct {
+	// Template is the URI template of the proxy.
+	// Requests that don't match the template are rejected.
+	Template *Template
+}
+
+var _ http.Handler = &Proxy{}
+
+// ServeHTTP handles a CONNECT-UDP request.
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodConnect || r.Proto != ConnectUDPProtocol {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	host, port, ok := p.Template.match(r.URL)
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	d, ok := w.(http3.Datagrammer)
+	if !ok {
+		w.WriteHeader(http.StatusNotImplemented)
+		return
+	}
+	addr, err := net.ResolveUDPAddr("udp", net.JoinHostPort(host, strconv.Itoa(port)))
+	if err != nil {
+		logger.Debugf("Resolving CONNECT-UDP target %s failed: %s", host, err)
+		w.WriteHeader(http.StatusBadGateway)
+		return
+	}
+	conn, err := net.DialUDP("udp", nil, addr)
+	if err != nil {
+		logger.Debugf("Dialing CONNECT-UDP target %s failed: %s", addr, err)
+		w.WriteHeader(http.StatusBadGateway)
+		return
+	}
+	defer conn.Close()
+
+	w.Header().Set(http3.CapsuleProtocolHeader, "?1")
+	w.WriteHeader(http.StatusOK)
+	w.(http.Flusher).Flush()
+
+	// Both go routines return after this handler returned:
+	// Receiving datagrams fails once the request is done, and reading from the UDP socket fails once it is closed.
+	go p.relayToTarget(d, conn)
+	go p.relayToClient(conn, d)
+	p.handleCapsules(r.Body, conn)
+}
+
+// relayToTarget sends the UDP payloads received in HTTP datagrams to the target.
+func (p *Proxy) relayToTarget(d http3.Datagrammer, conn *net.UDPConn) {
+	for {
+		data, err := d.ReceiveDatagram(context.Background())
+		if err != nil {
+			return
+		}
+		if payload, ok := parseUDPPayload(data); ok {
+			if _, err := conn.Write(payload); err != nil {
+				logger.Debugf("Sending UDP payload to %s failed: %s", conn.RemoteAddr(), err)
+			}
+		}
+	}
+}
+
+// relayToClient sends the UDP payloads received from the target in HTTP datagrams.
+func (p *Proxy) relayToClient(conn *net.UDPConn, d http3.Datagrammer) {
+	b := make([]byte, protocol.MaxReceivePacketSize)
+	for {
+		n, err := conn.Read(b)
+		if err != nil {
+			return
+		}
+		// Like on any other UDP path, payloads that can't be sent are dropped.
+		if err := d.SendDatagram(composeUDPPayload(b[:n])); err != nil {
+			logger.Debugf("Sending HTTP datagram failed: %s", err)
+		}
+	}
+}
+
+// handleCapsules reads the capsules sent on the request stream, until the client closes the stream.
+// UDP payloads can also be sent in DATAGRAM capsules, all other capsules are ignored.
+func (p *Proxy) handleCapsules(r io.Reader, conn *net.UDPConn) {
+	for {
+		ct, cr, err := http3.ParseCapsule(r)
+		if err != nil {
+			return
+		}
+		if ct != http3.CapsuleTypeDatagram {
+			if _, err := io.Copy(ioutil.Discard, cr); err != nil {
+				return
+			}
+			continue
+		}
+		data, err := ioutil.ReadAll(cr)
+		if err != nil {
+			return
+		}
+		if payload, ok := parseUDPPayload(data); ok {
+			if _, err := conn.Write(payload); err != nil {
+				logger.Debugf("Sending UDP payload to %s failed: %s", conn.RemoteAddr(), err)
+			}
+		}
+	}
+}
@@ -0,0 +1,248 @@
+package masque
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	templateVarTargetHost = "target_host"
+	templateVarTargetPort = "target_port"
+)
+
+// A Template is the URI template of a CONNECT-UDP proxy, see RFC 9298, section 2. For example:
+//
+//	https://proxy.example.org/.well-known/masque/udp/{target_host}/{target_port}/
+//	https://proxy.example.org/masque{?target_host,target_port}
+//
+// Only the subset of URI templates (RFC 6570) used by CONNECT-UDP is supported:
+// simple string expansion ({var}) in the path, and form-style query expansion ({?var1,var2} and {&var1,var2}).
+// The scheme and the authority must not contain any expressions.
+type Template struct {
+	raw   string
+	parts []templatePart
+
+	// path matches the (escaped) path of a request, with one submatch for every variable in pathVars
+	path      *regexp.Regexp
+	pathVars  []string
+	queryVars []string
+}
+
+// A templatePart is either a literal or an expression.
+type templatePart struct {
+	literal string
+	op      byte     // 0 for simple string expansion, '?' or '&' for form-style query expansion
+	vars    []string // nil for literals
+}
+
+// ParseTemplate parses a URI template.
+// The template must be an https URI, and use the variables target_host and target_port.
+func ParseTemplate(s string) (*Template, error) {
+	t := &Template{raw: s}
+	for len(s) > 0 {
+		if s[0] != '{' {
+			end := strings.IndexByte(s, '{')
+			if end == -1 {
+				end = len(s)
+			}
+			if strings.IndexByte(s[:end], '}') != -1 {
+				return nil, errors.New("masque: unbalanced braces in URI template")
+			}
+			t.parts = append(t.parts, templatePart{literal: s[:end]})
+			s = s[end:]
+			continue
+		}
+		end := strings.IndexByte(s, '}')
+		if end == -1 {
+			return nil, errors.New("masque: unbalanced braces in URI template")
+		}
+		raw, expr := s[:end+1], s[1:end]
+		s = s[end+1:]
+		var part templatePart
+		if len(expr) > 0 && (expr[0] == '?' || expr[0] == '&') {
+			part.op = expr[0]
+			expr = expr[1:]
+		}
+		for _, name := range strings.Split(expr, ",") {
+			if !isValidTemplateVarName(name) {
+				return nil, fmt.Errorf("masque: unsupported expression in URI template: %s", raw)
+			}
+			part.vars = append(part.vars, name)
+		}
+		if part.op == 0 && len(part.vars) > 1 {
+			return nil, fmt.Errorf("masque: unsupported expression in URI template: %s", raw)
+		}
+		t.parts = append(t.parts, part)
+	}
+	if err := t.compile(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// compile builds the regular expression used to match the path of requests.
+func (t *Template) compile() error {
+	if len(t.parts) == 0 || t.parts[0].vars != nil {
+		return errors.New("masque: URI template must start with the scheme and the authority")
+	}
+	prefix := t.parts[0].literal
+	u, err := url.Parse(prefix)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return errors.New("masque: URI template must be an https URI")
+	}
+	authority := prefix[strings.Index(prefix, "://")+3:]
+	var path string
+	if i := strings.IndexAny(authority, "/?"); i != -1 {
+		path = authority[i:]
+	} else if len(t.parts) > 1 && t.parts[1].op == 0 {
+		return errors.New("masque: URI template must not contain expressions in the authority")
+	}
+
+	parts := append([]templatePart{{literal: path}}, t.parts[1:]...)
+	var pattern strings.Builder
+	var inQuery bool
+	for _, p := range parts {
+		switch {
+		case p.vars == nil:
+			if inQuery {
+				continue
+			}
+			lit := p.literal
+			if i := strings.IndexByte(lit, '?'); i != -1 {
+				lit = lit[:i]
+				inQuery = true
+			}
+			pattern.WriteString(regexp.QuoteMeta(lit))
+		case p.op != 0:
+			inQuery = true
+			t.queryVars = append(t.queryVars, p.vars...)
+		case inQuery:
+			return errors.New("masque: URI template must not contain simple string expansions in the query")
+		default:
+			pattern.WriteString("([^/]*)")
+			t.pathVars = append(t.pathVars, p.vars[0])
+		}
+	}
+	if !t.hasVar(templateVarTargetHost) || !t.hasVar(templateVarTargetPort) {
+		return errors.New("masque: URI template must contain the variables target_host and target_port")
+	}
+	// an empty path is sent as "/"
+	if pattern.Len() == 0 {
+		pattern.WriteString("/")
+	}
+	t.path, err = regexp.Compile("^" + pattern.String() + "$")
+	return err
+}
+
+func (t *Template) hasVar(name string) bool {
+	for _, v := range t.pathVars {
+		if v == name {
+			return true
+		}
+	}
+	for _, v := range t.queryVars {
+		if v == name {
+			return true
+		}
+	}
+	return false
+}
+
+// String returns the URI template.
+func (t *Template) String() string {
+	return t.raw
+}
+
+// expand expands the template for the target.
+// An IPv6 host must not be enclosed in square brackets.
+func (t *Template) expand(host string, port int) string {
+	values := map[string]string{
+		templateVarTargetHost: host,
+		templateVarTargetPort: strconv.Itoa(port),
+	}
+	var b strings.Builder
+	for _, p := range t.parts {
+		if p.vars == nil {
+			b.WriteString(p.literal)
+			continue
+		}
+		if p.op == 0 {
+			b.WriteString(escapeTemplateValue(values[p.vars[0]]))
+			continue
+		}
+		sep := p.op
+		for _, name := range p.vars {
+			v, ok := values[name]
+			if !ok { // undefined variables are omitted
+				continue
+			}
+			b.WriteByte(sep)
+			b.WriteString(name)
+			b.WriteByte('=')
+			b.WriteString(escapeTemplateValue(v))
+			sep = '&'
+		}
+	}
+	return b.String()
+}
+
+// match matches the URL of a request against the template, and returns the target.
+func (t *Template) match(u *url.URL) (host string, port int, ok bool) {
+	m := t.path.FindStringSubmatch(u.EscapedPath())
+	if m == nil {
+		return "", 0, false
+	}
+	values := make(map[string]string, len(t.pathVars)+len(t.queryVars))
+	for i, name := range t.pathVars {
+		v, err := url.PathUnescape(m[i+1])
+		if err != nil {
+			return "", 0, false
+		}
+		values[name] = v
+	}
+	if len(t.queryVars) > 0 {
+		query := u.Query()
+		for _, name := range t.queryVars {
+			values[name] = query.Get(name)
+		}
+	}
+	host = values[templateVarTargetHost]
+	p, err := strconv.ParseUint(values[templateVarTargetPort], 10, 16)
+	if host == "" || err != nil || p == 0 {
+		return "", 0, false
+	}
+	return host, int(p), true
+}
+
+func isValidTemplateVarName(name string) bool {
+	if len(name) == 0 {
+		return false
+	}
+	for _, c := range name {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '.') {
+			return false
+		}
+	}
+	return true
+}
+
+// escapeTemplateValue percent-encodes all characters, except for the unreserved characters (RFC 3986, section 2.3).
+func escapeTemplateValue(s string) string {
+	const hex = "0123456789ABCDEF"
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '.' || c == '_' || c == '~' {
+			b.WriteByte(c)
+			continue
+		}
+		b.WriteByte('%')
+		b.WriteByte(hex[c>>4])
+		b.WriteByte(hex[c&0xf])
+	}
+	return b.String()
+}
@@ -0,0 +1,86 @@
+package masque
+
+import (
+	"net/url"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("URI Template", func() {
+	match := func(t *Template, s string) (string, int, bool) {
+		u, err := url.ParseRequestURI(s)
+		Expect(err).ToNot(HaveOccurred())
+		return t.match(u)
+	}
+
+	It("expands and matches templates with variables in the path", func() {
+		t, err := ParseTemplate("https://proxy.example.org:4443/.well-known/masque/udp/{target_host}/{target_port}/")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(t.String()).To(Equal("https://proxy.example.org:4443/.well-known/masque/udp/{target_host}/{target_port}/"))
+		Expect(t.expand("192.0.2.6", 443)).To(Equal("https://proxy.example.org:4443/.well-known/masque/udp/192.0.2.6/443/"))
+		host, port, ok := match(t, "/.well-known/masque/udp/192.0.2.6/443/")
+		Expect(ok).To(BeTrue())
+		Expect(host).To(Equal("192.0.2.6"))
+		Expect(port).To(Equal(443))
+	})
+
+	It("expands and matches templates with variables in the query", func() {
+		t, err := ParseTemplate("https://proxy.example.org/masque?version=1{&target_host,target_port}")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(t.expand("example.com", 1234)).To(Equal("https://proxy.example.org/masque?version=1&target_host=example.com&target_port=1234"))
+		host, port, ok := match(t, "/masque?target_port=1234&target_host=example.com&version=1")
+		Expect(ok).To(BeTrue())
+		Expect(host).To(Equal("example.com"))
+		Expect(port).To(Equal(1234))
+
+		t, err = ParseTemplate("https://proxy.example.org{?target_host,target_port}")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(t.expand("example.com", 1234)).To(Equal("https://proxy.example.org?target_host=example.com&target_port=1234"))
+		_, _, ok = match(t, "/?target_host=example.com&target_port=1234")
+		Expect(ok).To(BeTrue())
+	})
+
+	It("escapes IPv6 addresses", func() {
+		t, err := ParseTemplate("https://proxy.example.org/masque/{target_host}/{target_port}")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(t.expand("2001:db8::42", 443)).To(Equal("https://proxy.example.org/masque/2001%3Adb8%3A%3A42/443"))
+		host, port, ok := match(t, "/masque/2001%3Adb8%3A%3A42/443")
+		Expect(ok).To(BeTrue())
+		Expect(host).To(Equal("2001:db8::42"))
+		Expect(port).To(Equal(443))
+	})
+
+	It("doesn't match invalid requests", func() {
+		t, err := ParseTemplate("https://proxy.example.org/masque/{target_host}/{target_port}")
+		Expect(err).ToNot(HaveOccurred())
+		_, _, ok := match(t, "/foo/example.com/443")
+		Expect(ok).To(BeFalse())
+		_, _, ok = match(t, "/masque/example.com/443/foo")
+		Expect(ok).To(BeFalse())
+		_, _, ok = match(t, "/masque//443")
+		Expect(ok).To(BeFalse())
+		_, _, ok = match(t, "/masque/example.com/0")
+		Expect(ok).To(BeFalse())
+		_, _, ok = match(t, "/masque/example.com/65536")
+		Expect(ok).To(BeFalse())
+	})
+
+	It("rejects invalid templates", func() {
+		for tmpl, msg := range map[string]string{
+			"https://proxy.example.org/masque/{target_host/{target_port}":   "masque: unsupported expression in URI template: {target_host/{target_port}",
+			"https://proxy.example.org/masque/}{target_host}/{target_port}": "masque: unbalanced braces in URI template",
+			"https://proxy.example.org/masque/{target_host}/{target_port":   "masque: unbalanced braces in URI template",
+			"https://proxy.example.org/masque/{+target_host}/{target_port}": "masque: unsupported expression in URI template: {+target_host}",
+			"https://proxy.example.org/masque/{target_host,target_port}":    "masque: unsupported expression in URI template: {target_host,target_port}",
+			"https://proxy.example.org/masque/{target_host}":                "masque: URI template must contain the variables target_host and target_port",
+			"http://proxy.example.org/masque/{target_host}/{target_port}":   "masque: URI template must be an https URI",
+			"{target_host}/{target_port}":                                   "masque: URI template must start with the scheme and the authority",
+			"https://proxy{target_host}/{target_port}":                      "masque: URI template must not contain expressions in the authority",
+			"https://proxy.example.org/masque?{target_host}/{target_port}":  "masque: URI template must not contain simple string expansions in the query",
+		} {
+			_, err := ParseTemplate(tmpl)
+			Expect(err).To(MatchError(msg))
+		}
+	})
+})
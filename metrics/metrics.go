@@ -163,6 +163,8 @@ func (t *connTracer) ClosedConnection(r logging.CloseReason) {
 	}
 	stats.RecordWithTags(context.Background(), tags, closes.M(1))
 }
+func (t *connTracer) NegotiatedVersion(logging.VersionNumber, []logging.VersionNumber, []logging.VersionNumber) {
+}
 func (t *connTracer) SentTransportParameters(*logging.TransportParameters)     {}
 func (t *connTracer) ReceivedTransportParameters(*logging.TransportParameters) {}
 func (t *connTracer) SentPacket(hdr *logging.ExtendedHeader, _ logging.ByteCount, _ *logging.AckFrame, _ []logging.Frame) {
@@ -211,6 +213,7 @@ func (t *connTracer) UpdatedPTOCount(value uint32) {
 		ptos.M(1),
 	)
 }
+func (t *connTracer) ECNStateUpdated(logging.ECNState, logging.ECNStateTrigger)          {}
 func (t *connTracer) UpdatedKeyFromTLS(logging.EncryptionLevel, logging.Perspective)     {}
 func (t *connTracer) UpdatedKey(logging.KeyPhase, bool)                                  {}
 func (t *connTracer) DroppedEncryptionLevel(logging.EncryptionLevel)                     {}
@@ -8,6 +8,7 @@ import (
 	reflect "reflect"
 	time "time"
 
+	protocol "github.com/For-ACGN/quic-go/internal/protocol"
 	wire "github.com/For-ACGN/quic-go/internal/wire"
 	gomock "github.com/golang/mock/gomock"
 )
@@ -35,6 +36,18 @@ func (m *MockUnpacker) EXPECT() *MockUnpackerMockRecorder {
 	return m.recorder
 }
 
+// SetVersion mocks base method
+func (m *MockUnpacker) SetVersion(arg0 protocol.VersionNumber) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetVersion", arg0)
+}
+
+// SetVersion indicates an expected call of SetVersion
+func (mr *MockUnpackerMockRecorder) SetVersion(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetVersion", reflect.TypeOf((*MockUnpacker)(nil).SetVersion), arg0)
+}
+
 // Unpack mocks base method
 func (m *MockUnpacker) Unpack(arg0 *wire.Header, arg1 time.Time, arg2 []byte) (*unpackedPacket, error) {
 	m.ctrl.T.Helper()
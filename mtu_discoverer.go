@@ -0,0 +1,130 @@
+package quic
+
+import (
+	"time"
+
+	"github.com/For-ACGN/quic-go/internal/ackhandler"
+	"github.com/For-ACGN/quic-go/internal/protocol"
+	"github.com/For-ACGN/quic-go/internal/utils"
+	"github.com/For-ACGN/quic-go/internal/wire"
+)
+
+type mtuDiscoverer interface {
+	ShouldSendProbe(now time.Time) bool
+	// NextProbeTime returns the time when the next probe packet should be sent.
+	// It returns the zero value if no probe packet needs to be sent.
+	NextProbeTime() time.Time
+	GetPing() (ping ackhandler.Frame, datagramSize protocol.ByteCount)
+	// DetectedBlackHole is called when packets of the current size are dropped.
+	// It falls back to the initial packet size.
+	DetectedBlackHole()
+	// Reset restarts path MTU discovery, e.g. after migrating to a new path.
+	Reset(start protocol.ByteCount)
+}
+
+const (
+	// At some point, we have to stop searching for a higher MTU.
+	// We're happy to send a packet that's 10 bytes smaller than the actual MTU.
+	maxMTUDiff = 20
+	// send a probe packet every mtuProbeDelay RTTs
+	mtuProbeDelay = 5
+)
+
+// The mtuFinder implements DPLPMTUD (RFC 8899).
+// It performs a binary search between the packet size that is known to work and the maximum packet size.
+type mtuFinder struct {
+	lastProbeTime time.Time
+	probeInFlight bool
+	mtuChanged    func(protocol.ByteCount)
+	// The generation is incremented every time the search is restarted.
+	// Acknowledgements and losses of probe packets sent in a previous generation are ignored.
+	generation uint64
+
+	rttStats *utils.RTTStats
+	start    protocol.ByteCount // the packet size used before path MTU discovery
+	current  protocol.ByteCount // the largest packet size that is known to work
+	max      protocol.ByteCount // the maximum value, as advertised by the peer (or our maximum size buffer)
+	// The maximum value, before any probe packets were lost.
+	initialMax protocol.ByteCount
+}
+
+var _ mtuDiscoverer = &mtuFinder{}
+
+// newMTUDiscoverer creates a new mtuFinder.
+// If start is larger than max, max is used as the start value.
+func newMTUDiscoverer(rttStats *utils.RTTStats, start, max protocol.ByteCount, mtuChanged func(protocol.ByteCount)) *mtuFinder {
+	start = utils.MinByteCount(start, max)
+	return &mtuFinder{
+		start:         start,
+		current:       start,
+		max:           max,
+		initialMax:    max,
+		rttStats:      rttStats,
+		lastProbeTime: time.Now(), // to make sure the first probe packet is not sent immediately
+		mtuChanged:    mtuChanged,
+	}
+}
+
+func (f *mtuFinder) done() bool {
+	return f.max-f.current <= maxMTUDiff+1
+}
+
+func (f *mtuFinder) ShouldSendProbe(now time.Time) bool {
+	next := f.NextProbeTime()
+	return !next.IsZero() && !now.Before(next)
+}
+
+func (f *mtuFinder) NextProbeTime() time.Time {
+	if f.probeInFlight || f.done() {
+		return time.Time{}
+	}
+	return f.lastProbeTime.Add(mtuProbeDelay * f.rttStats.SmoothedRTT())
+}
+
+func (f *mtuFinder) GetPing() (ackhandler.Frame, protocol.ByteCount) {
+	size := (f.max + f.current) / 2
+	generation := f.generation
+	f.lastProbeTime = time.Now()
+	f.probeInFlight = true
+	return ackhandler.Frame{
+		Frame: &wire.PingFrame{},
+		OnLost: func(wire.Frame) {
+			if generation != f.generation {
+				return
+			}
+			f.probeInFlight = false
+			f.max = size
+		},
+		OnAcked: func(wire.Frame) {
+			if generation != f.generation {
+				return
+			}
+			f.probeInFlight = false
+			f.current = size
+			f.mtuChanged(size)
+		},
+	}, size
+}
+
+func (f *mtuFinder) DetectedBlackHole() {
+	if f.current == f.start {
+		return
+	}
+	// Packets of the current size don't make it through, so there's no point in probing for this size again.
+	f.max = f.current
+	f.restart(f.start)
+}
+
+func (f *mtuFinder) Reset(start protocol.ByteCount) {
+	f.start = utils.MinByteCount(start, f.initialMax)
+	f.max = f.initialMax
+	f.restart(f.start)
+}
+
+func (f *mtuFinder) restart(start protocol.ByteCount) {
+	f.generation++
+	f.probeInFlight = false
+	f.lastProbeTime = time.Now()
+	f.current = start
+	f.mtuChanged(start)
+}
@@ -0,0 +1,158 @@
+package quic
+
+import (
+	"time"
+
+	"github.com/For-ACGN/quic-go/internal/protocol"
+	"github.com/For-ACGN/quic-go/internal/utils"
+	"github.com/For-ACGN/quic-go/internal/wire"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("MTU Discoverer", func() {
+	const (
+		rtt                         = 100 * time.Millisecond
+		startMTU protocol.ByteCount = 1000
+		maxMTU   protocol.ByteCount = 2000
+	)
+
+	var (
+		d             *mtuFinder
+		rttStats      *utils.RTTStats
+		discoveredMTU protocol.ByteCount
+	)
+
+	BeforeEach(func() {
+		rttStats = &utils.RTTStats{}
+		rttStats.SetInitialRTT(rtt)
+		Expect(rttStats.SmoothedRTT()).To(Equal(rtt))
+		discoveredMTU = 0
+		d = newMTUDiscoverer(rttStats, startMTU, maxMTU, func(s protocol.ByteCount) { discoveredMTU = s })
+	})
+
+	It("only allows a probe 5 RTTs after the handshake completes", func() {
+		now := time.Now()
+		Expect(d.ShouldSendProbe(now)).To(BeFalse())
+		Expect(d.ShouldSendProbe(now.Add(rtt * 9 / 2))).To(BeFalse())
+		Expect(d.ShouldSendProbe(now.Add(rtt * 5))).To(BeTrue())
+	})
+
+	It("returns the time when the next probe should be sent", func() {
+		now := time.Now()
+		Expect(d.NextProbeTime()).To(BeTemporally("~", now.Add(rtt*5), rtt/2))
+		ping, _ := d.GetPing()
+		Expect(d.NextProbeTime()).To(BeZero())
+		ping.OnAcked(ping.Frame)
+		Expect(d.NextProbeTime()).To(BeTemporally("~", time.Now().Add(rtt*5), rtt/2))
+	})
+
+	It("doesn't allow a probe if another probe is still in flight", func() {
+		ping, _ := d.GetPing()
+		Expect(d.ShouldSendProbe(time.Now().Add(10 * rtt))).To(BeFalse())
+		ping.OnLost(ping.Frame)
+		Expect(d.ShouldSendProbe(time.Now().Add(10 * rtt))).To(BeTrue())
+	})
+
+	It("tries a lower size when a probe is lost", func() {
+		ping, size := d.GetPing()
+		Expect(size).To(Equal(protocol.ByteCount(1500)))
+		ping.OnLost(ping.Frame)
+		_, size = d.GetPing()
+		Expect(size).To(Equal(protocol.ByteCount(1250)))
+		Expect(discoveredMTU).To(BeZero())
+	})
+
+	It("tries a higher size and calls the callback when a probe is acknowledged", func() {
+		ping, size := d.GetPing()
+		Expect(size).To(Equal(protocol.ByteCount(1500)))
+		ping.OnAcked(ping.Frame)
+		Expect(discoveredMTU).To(Equal(protocol.ByteCount(1500)))
+		_, size = d.GetPing()
+		Expect(size).To(Equal(protocol.ByteCount(1750)))
+	})
+
+	It("stops discovery after getting close enough to the MTU", func() {
+		var sizes []protocol.ByteCount
+		t := time.Now().Add(5 * rtt)
+		for d.ShouldSendProbe(t) {
+			ping, size := d.GetPing()
+			sizes = append(sizes, size)
+			ping.OnAcked(ping.Frame)
+			t = t.Add(5 * rtt)
+		}
+		Expect(sizes).To(Equal([]protocol.ByteCount{1500, 1750, 1875, 1937, 1968, 1984}))
+		Expect(d.ShouldSendProbe(t.Add(10 * rtt))).To(BeFalse())
+		Expect(discoveredMTU).To(Equal(protocol.ByteCount(1984)))
+	})
+
+	It("doesn't exceed the maximum size", func() {
+		d = newMTUDiscoverer(rttStats, 3000, maxMTU, func(s protocol.ByteCount) { discoveredMTU = s })
+		Expect(d.ShouldSendProbe(time.Now().Add(10 * rtt))).To(BeFalse())
+	})
+
+	Context("black hole detection", func() {
+		It("ignores black holes if the MTU wasn't raised", func() {
+			d.DetectedBlackHole()
+			Expect(discoveredMTU).To(BeZero())
+			_, size := d.GetPing()
+			Expect(size).To(Equal(protocol.ByteCount(1500)))
+		})
+
+		It("falls back to the start size", func() {
+			ping, _ := d.GetPing()
+			ping.OnAcked(ping.Frame)
+			Expect(discoveredMTU).To(Equal(protocol.ByteCount(1500)))
+			d.DetectedBlackHole()
+			Expect(discoveredMTU).To(Equal(startMTU))
+			// don't probe for the size that caused the black hole again
+			_, size := d.GetPing()
+			Expect(size).To(Equal(protocol.ByteCount(1250)))
+		})
+
+		It("ignores probes sent before the black hole was detected", func() {
+			ping, _ := d.GetPing()
+			ping.OnAcked(ping.Frame)
+			ping, _ = d.GetPing()
+			d.DetectedBlackHole()
+			Expect(discoveredMTU).To(Equal(startMTU))
+			ping.OnAcked(ping.Frame)
+			Expect(discoveredMTU).To(Equal(startMTU))
+			Expect(d.ShouldSendProbe(time.Now().Add(10 * rtt))).To(BeTrue())
+		})
+	})
+
+	Context("resetting", func() {
+		It("restarts discovery", func() {
+			ping, _ := d.GetPing()
+			ping.OnLost(ping.Frame)
+			ping, _ = d.GetPing()
+			ping.OnAcked(ping.Frame)
+			Expect(discoveredMTU).To(Equal(protocol.ByteCount(1250)))
+			d.Reset(1200)
+			Expect(discoveredMTU).To(Equal(protocol.ByteCount(1200)))
+			_, size := d.GetPing()
+			Expect(size).To(Equal(protocol.ByteCount(1600)))
+		})
+
+		It("ignores probes sent before the reset", func() {
+			ping, _ := d.GetPing()
+			d.Reset(startMTU)
+			ping.OnLost(ping.Frame)
+			_, size := d.GetPing()
+			Expect(size).To(Equal(protocol.ByteCount(1500)))
+		})
+
+		It("doesn't start at a size larger than the maximum", func() {
+			d.Reset(3000)
+			Expect(discoveredMTU).To(Equal(maxMTU))
+			Expect(d.ShouldSendProbe(time.Now().Add(10 * rtt))).To(BeFalse())
+		})
+	})
+
+	It("uses PING frames as probes", func() {
+		ping, _ := d.GetPing()
+		Expect(ping.Frame).To(Equal(&wire.PingFrame{}))
+	})
+})
@@ -6,6 +6,7 @@ import (
 	"net"
 	"sync"
 
+	"github.com/For-ACGN/quic-go/internal/protocol"
 	"github.com/For-ACGN/quic-go/internal/utils"
 	"github.com/For-ACGN/quic-go/logging"
 )
@@ -20,12 +21,13 @@ type indexableConn interface {
 }
 
 type multiplexer interface {
-	AddConn(c net.PacketConn, connIDLen int, statelessResetKey []byte, tracer logging.Tracer) (packetHandlerManager, error)
+	AddConn(c net.PacketConn, connIDLen int, maxPacketSize protocol.ByteCount, statelessResetKey []byte, tracer logging.Tracer) (packetHandlerManager, error)
 	RemoveConn(indexableConn) error
 }
 
 type connManager struct {
 	connIDLen         int
+	maxPacketSize     protocol.ByteCount
 	statelessResetKey []byte
 	tracer            logging.Tracer
 	manager           packetHandlerManager
@@ -37,7 +39,7 @@ type connMultiplexer struct {
 	mutex sync.Mutex
 
 	conns                   map[string] /* LocalAddr().String() */ connManager
-	newPacketHandlerManager func(net.PacketConn, int, []byte, logging.Tracer, utils.Logger) (packetHandlerManager, error) // so it can be replaced in the tests
+	newPacketHandlerManager func(net.PacketConn, int, protocol.ByteCount, []byte, logging.Tracer, utils.Logger) (packetHandlerManager, error) // so it can be replaced in the tests
 
 	logger utils.Logger
 }
@@ -58,6 +60,7 @@ func getMultiplexer() multiplexer {
 func (m *connMultiplexer) AddConn(
 	c net.PacketConn,
 	connIDLen int,
+	maxPacketSize protocol.ByteCount,
 	statelessResetKey []byte,
 	tracer logging.Tracer,
 ) (packetHandlerManager, error) {
@@ -68,12 +71,13 @@ func (m *connMultiplexer) AddConn(
 	connIndex := addr.Network() + " " + addr.String()
 	p, ok := m.conns[connIndex]
 	if !ok {
-		manager, err := m.newPacketHandlerManager(c, connIDLen, statelessResetKey, tracer, m.logger)
+		manager, err := m.newPacketHandlerManager(c, connIDLen, maxPacketSize, statelessResetKey, tracer, m.logger)
 		if err != nil {
 			return nil, err
 		}
 		p = connManager{
 			connIDLen:         connIDLen,
+			maxPacketSize:     maxPacketSize,
 			statelessResetKey: statelessResetKey,
 			manager:           manager,
 			tracer:            tracer,
@@ -83,6 +87,9 @@ func (m *connMultiplexer) AddConn(
 		if p.connIDLen != connIDLen {
 			return nil, fmt.Errorf("cannot use %d byte connection IDs on a connection that is already using %d byte connction IDs", connIDLen, p.connIDLen)
 		}
+		if p.maxPacketSize != maxPacketSize {
+			return nil, fmt.Errorf("cannot use different maximum packet sizes on the same packet conn")
+		}
 		if statelessResetKey != nil && !bytes.Equal(p.statelessResetKey, statelessResetKey) {
 			return nil, fmt.Errorf("cannot use different stateless reset keys on the same packet conn")
 		}
@@ -4,6 +4,7 @@ import (
 	"net"
 
 	mocklogging "github.com/For-ACGN/quic-go/internal/mocks/logging"
+	"github.com/For-ACGN/quic-go/internal/protocol"
 	"github.com/golang/mock/gomock"
 
 	. "github.com/onsi/ginkgo"
@@ -20,7 +21,7 @@ var _ = Describe("Multiplexer", func() {
 		conn := NewMockPacketConn(mockCtrl)
 		conn.EXPECT().ReadFrom(gomock.Any()).Do(func([]byte) { <-(make(chan struct{})) }).MaxTimes(1)
 		conn.EXPECT().LocalAddr().Return(&net.UDPAddr{IP: net.IPv4(1, 2, 3, 4), Port: 1234})
-		_, err := getMultiplexer().AddConn(conn, 8, nil, nil)
+		_, err := getMultiplexer().AddConn(conn, 8, protocol.MaxReceivePacketSize, nil, nil)
 		Expect(err).ToNot(HaveOccurred())
 	})
 
@@ -30,10 +31,10 @@ var _ = Describe("Multiplexer", func() {
 		pconn.EXPECT().ReadFrom(gomock.Any()).Do(func([]byte) { <-(make(chan struct{})) }).MaxTimes(1)
 		conn := testConn{PacketConn: pconn}
 		tracer := mocklogging.NewMockTracer(mockCtrl)
-		_, err := getMultiplexer().AddConn(conn, 8, []byte("foobar"), tracer)
+		_, err := getMultiplexer().AddConn(conn, 8, protocol.MaxReceivePacketSize, []byte("foobar"), tracer)
 		Expect(err).ToNot(HaveOccurred())
 		conn.counter++
-		_, err = getMultiplexer().AddConn(conn, 8, []byte("foobar"), tracer)
+		_, err = getMultiplexer().AddConn(conn, 8, protocol.MaxReceivePacketSize, []byte("foobar"), tracer)
 		Expect(err).ToNot(HaveOccurred())
 		Expect(getMultiplexer().(*connMultiplexer).conns).To(HaveLen(1))
 	})
@@ -42,19 +43,29 @@ var _ = Describe("Multiplexer", func() {
 		conn := NewMockPacketConn(mockCtrl)
 		conn.EXPECT().ReadFrom(gomock.Any()).Do(func([]byte) { <-(make(chan struct{})) }).MaxTimes(1)
 		conn.EXPECT().LocalAddr().Return(&net.UDPAddr{IP: net.IPv4(1, 2, 3, 4), Port: 1234}).Times(2)
-		_, err := getMultiplexer().AddConn(conn, 5, nil, nil)
+		_, err := getMultiplexer().AddConn(conn, 5, protocol.MaxReceivePacketSize, nil, nil)
 		Expect(err).ToNot(HaveOccurred())
-		_, err = getMultiplexer().AddConn(conn, 6, nil, nil)
+		_, err = getMultiplexer().AddConn(conn, 6, protocol.MaxReceivePacketSize, nil, nil)
 		Expect(err).To(MatchError("cannot use 6 byte connection IDs on a connection that is already using 5 byte connction IDs"))
 	})
 
+	It("errors when adding an existing conn with a different maximum packet size", func() {
+		conn := NewMockPacketConn(mockCtrl)
+		conn.EXPECT().ReadFrom(gomock.Any()).Do(func([]byte) { <-(make(chan struct{})) }).MaxTimes(1)
+		conn.EXPECT().LocalAddr().Return(&net.UDPAddr{IP: net.IPv4(1, 2, 3, 4), Port: 1234}).Times(2)
+		_, err := getMultiplexer().AddConn(conn, 7, protocol.MaxReceivePacketSize, nil, nil)
+		Expect(err).ToNot(HaveOccurred())
+		_, err = getMultiplexer().AddConn(conn, 7, protocol.MaxJumboPacketSize, nil, nil)
+		Expect(err).To(MatchError("cannot use different maximum packet sizes on the same packet conn"))
+	})
+
 	It("errors when adding an existing conn with a different stateless rest key", func() {
 		conn := NewMockPacketConn(mockCtrl)
 		conn.EXPECT().ReadFrom(gomock.Any()).Do(func([]byte) { <-(make(chan struct{})) }).MaxTimes(1)
 		conn.EXPECT().LocalAddr().Return(&net.UDPAddr{IP: net.IPv4(1, 2, 3, 4), Port: 1234}).Times(2)
-		_, err := getMultiplexer().AddConn(conn, 7, []byte("foobar"), nil)
+		_, err := getMultiplexer().AddConn(conn, 7, protocol.MaxReceivePacketSize, []byte("foobar"), nil)
 		Expect(err).ToNot(HaveOccurred())
-		_, err = getMultiplexer().AddConn(conn, 7, []byte("raboof"), nil)
+		_, err = getMultiplexer().AddConn(conn, 7, protocol.MaxReceivePacketSize, []byte("raboof"), nil)
 		Expect(err).To(MatchError("cannot use different stateless reset keys on the same packet conn"))
 	})
 
@@ -62,9 +73,9 @@ var _ = Describe("Multiplexer", func() {
 		conn := NewMockPacketConn(mockCtrl)
 		conn.EXPECT().ReadFrom(gomock.Any()).Do(func([]byte) { <-(make(chan struct{})) }).MaxTimes(1)
 		conn.EXPECT().LocalAddr().Return(&net.UDPAddr{IP: net.IPv4(1, 2, 3, 4), Port: 1234}).Times(2)
-		_, err := getMultiplexer().AddConn(conn, 7, nil, mocklogging.NewMockTracer(mockCtrl))
+		_, err := getMultiplexer().AddConn(conn, 7, protocol.MaxReceivePacketSize, nil, mocklogging.NewMockTracer(mockCtrl))
 		Expect(err).ToNot(HaveOccurred())
-		_, err = getMultiplexer().AddConn(conn, 7, nil, mocklogging.NewMockTracer(mockCtrl))
+		_, err = getMultiplexer().AddConn(conn, 7, protocol.MaxReceivePacketSize, nil, mocklogging.NewMockTracer(mockCtrl))
 		Expect(err).To(MatchError("cannot use different tracers on the same packet conn"))
 	})
 })
@@ -90,6 +90,7 @@ var receiveBufferWarningOnce sync.Once
 func newPacketHandlerMap(
 	c net.PacketConn,
 	connIDLen int,
+	maxPacketSize protocol.ByteCount,
 	statelessResetKey []byte,
 	tracer logging.Tracer,
 	logger utils.Logger,
@@ -99,7 +100,7 @@ func newPacketHandlerMap(
 			log.Printf("%s. See https://github.com/lucas-clemente/quic-go/wiki/UDP-Receive-Buffer-Size for details.", err)
 		})
 	}
-	conn, err := wrapConn(c)
+	conn, err := wrapConn(c, maxPacketSize)
 	if err != nil {
 		return nil, err
 	}
@@ -72,7 +72,7 @@ var _ = Describe("Packet Handler Map", func() {
 			}
 			return copy(b, p.data), p.addr, p.err
 		}).AnyTimes()
-		phm, err := newPacketHandlerMap(conn, connIDLen, statelessResetKey, tracer, utils.DefaultLogger)
+		phm, err := newPacketHandlerMap(conn, connIDLen, protocol.MaxReceivePacketSize, statelessResetKey, tracer, utils.DefaultLogger)
 		Expect(err).ToNot(HaveOccurred())
 		handler = phm.(*packetHandlerMap)
 	})
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"sync/atomic"
 	"time"
 
 	"github.com/For-ACGN/quic-go/internal/ackhandler"
@@ -18,12 +19,19 @@ import (
 type packer interface {
 	PackCoalescedPacket() (*coalescedPacket, error)
 	PackPacket() (*packedPacket, error)
+	AppendPacket(*packetBuffer) (*packetContents, error)
 	MaybePackProbePacket(protocol.EncryptionLevel) (*packedPacket, error)
 	MaybePackAckPacket(handshakeConfirmed bool) (*packedPacket, error)
+	PackPathProbePacket(connID protocol.ConnectionID, frames []ackhandler.Frame, maxSize protocol.ByteCount) (*packedPacket, error)
+	PackMTUProbePacket(ping ackhandler.Frame, size protocol.ByteCount, immediateAck bool) (*packedPacket, error)
 	PackConnectionClose(*qerr.QuicError) (*coalescedPacket, error)
 
 	HandleTransportParameters(*wire.TransportParameters)
 	SetToken([]byte)
+	SetMaxPacketSize(protocol.ByteCount)
+	MaxPacketSize() protocol.ByteCount
+	MaxDatagramFrameSize() protocol.ByteCount
+	SetVersion(protocol.VersionNumber)
 }
 
 type sealer interface {
@@ -47,6 +55,8 @@ type packetContents struct {
 	frames []ackhandler.Frame
 
 	length protocol.ByteCount
+
+	isMTUProbePacket bool
 }
 
 type coalescedPacket struct {
@@ -95,12 +105,13 @@ func (p *packetContents) ToAckHandlerPacket(now time.Time, q *retransmissionQueu
 		}
 	}
 	return &ackhandler.Packet{
-		PacketNumber:    p.header.PacketNumber,
-		LargestAcked:    largestAcked,
-		Frames:          p.frames,
-		Length:          p.length,
-		EncryptionLevel: encLevel,
-		SendTime:        now,
+		PacketNumber:         p.header.PacketNumber,
+		LargestAcked:         largestAcked,
+		Frames:               p.frames,
+		Length:               p.length,
+		EncryptionLevel:      encLevel,
+		SendTime:             now,
+		IsPathMTUProbePacket: p.isMTUProbePacket,
 	}
 }
 
@@ -118,6 +129,10 @@ func getMaxPacketSize(addr net.Addr) protocol.ByteCount {
 	return maxSize
 }
 
+// maxShortHeaderPacketOverhead is the maximum overhead of a 1-RTT packet:
+// the first byte, the longest possible connection ID, a 4 byte packet number, and the AEAD tag.
+const maxShortHeaderPacketOverhead = 1 + protocol.MaxConnIDLen + protocol.ByteCount(protocol.PacketNumberLen4) + 16
+
 type packetNumberManager interface {
 	PeekPacketNumber(protocol.EncryptionLevel) (protocol.PacketNumber, protocol.PacketNumberLen)
 	PopPacketNumber(protocol.EncryptionLevel) protocol.PacketNumber
@@ -159,7 +174,10 @@ type packetPacker struct {
 	datagramQueue       *datagramQueue
 	retransmissionQueue *retransmissionQueue
 
-	maxPacketSize          protocol.ByteCount
+	maxPacketSize protocol.ByteCount
+	// maxPacketSizeAtomic holds the same value as maxPacketSize.
+	// It is used by MaxDatagramFrameSize, which is called concurrently with packing packets.
+	maxPacketSizeAtomic    uint64
 	numNonAckElicitingAcks int
 }
 
@@ -180,6 +198,7 @@ func newPacketPacker(
 	perspective protocol.Perspective,
 	version protocol.VersionNumber,
 ) *packetPacker {
+	maxPacketSize := getMaxPacketSize(remoteAddr)
 	return &packetPacker{
 		cryptoSetup:         cryptoSetup,
 		getDestConnID:       getDestConnID,
@@ -193,7 +212,8 @@ func newPacketPacker(
 		framer:              framer,
 		acks:                acks,
 		pnManager:           packetNumberManager,
-		maxPacketSize:       getMaxPacketSize(remoteAddr),
+		maxPacketSize:       maxPacketSize,
+		maxPacketSizeAtomic: uint64(maxPacketSize),
 	}
 }
 
@@ -272,7 +292,7 @@ func (p *packetPacker) PackConnectionClose(quicErr *qerr.QuicError) (*coalescedP
 		numPackets++
 	}
 	contents := make([]*packetContents, 0, numPackets)
-	buffer := getPacketBuffer()
+	buffer := getPacketBufferForSize(p.maxPacketSize)
 	for i, encLevel := range encLevels {
 		if sealers[i] == nil {
 			continue
@@ -281,7 +301,7 @@ func (p *packetPacker) PackConnectionClose(quicErr *qerr.QuicError) (*coalescedP
 		if encLevel == protocol.EncryptionInitial {
 			paddingLen = p.paddingLen(payloads[i].frames, size)
 		}
-		c, err := p.appendPacket(buffer, hdrs[i], payloads[i], paddingLen, encLevel, sealers[i])
+		c, err := p.appendPacket(buffer, hdrs[i], payloads[i], paddingLen, encLevel, sealers[i], false)
 		if err != nil {
 			return nil, err
 		}
@@ -418,28 +438,28 @@ func (p *packetPacker) PackCoalescedPacket() (*coalescedPacket, error) {
 		return nil, nil
 	}
 
-	buffer := getPacketBuffer()
+	buffer := getPacketBufferForSize(p.maxPacketSize)
 	packet := &coalescedPacket{
 		buffer:  buffer,
 		packets: make([]*packetContents, 0, numPackets),
 	}
 	if initialPayload != nil {
 		padding := p.paddingLen(initialPayload.frames, size)
-		cont, err := p.appendPacket(buffer, initialHdr, initialPayload, padding, protocol.EncryptionInitial, initialSealer)
+		cont, err := p.appendPacket(buffer, initialHdr, initialPayload, padding, protocol.EncryptionInitial, initialSealer, false)
 		if err != nil {
 			return nil, err
 		}
 		packet.packets = append(packet.packets, cont)
 	}
 	if handshakePayload != nil {
-		cont, err := p.appendPacket(buffer, handshakeHdr, handshakePayload, 0, protocol.EncryptionHandshake, handshakeSealer)
+		cont, err := p.appendPacket(buffer, handshakeHdr, handshakePayload, 0, protocol.EncryptionHandshake, handshakeSealer, false)
 		if err != nil {
 			return nil, err
 		}
 		packet.packets = append(packet.packets, cont)
 	}
 	if appDataPayload != nil {
-		cont, err := p.appendPacket(buffer, appDataHdr, appDataPayload, 0, appDataEncLevel, appDataSealer)
+		cont, err := p.appendPacket(buffer, appDataHdr, appDataPayload, 0, appDataEncLevel, appDataSealer, false)
 		if err != nil {
 			return nil, err
 		}
@@ -455,12 +475,70 @@ func (p *packetPacker) PackPacket() (*packedPacket, error) {
 	if payload == nil {
 		return nil, nil
 	}
-	buffer := getPacketBuffer()
+	buffer := getPacketBufferForSize(p.maxPacketSize)
+	cont, err := p.appendAppDataPacket(buffer, sealer, hdr, payload)
+	if err != nil {
+		return nil, err
+	}
+	return &packedPacket{
+		buffer:         buffer,
+		packetContents: cont,
+	}, nil
+}
+
+// AppendPacket packs a packet in the application data packet number space,
+// and appends it to the buffer.
+// It is used to pack multiple packets into one buffer, such that they can be sent out using a single syscall.
+// The buffer needs to have enough capacity left for a packet of the maximum packet size.
+// It returns nil if there's nothing to send.
+func (p *packetPacker) AppendPacket(buffer *packetBuffer) (*packetContents, error) {
+	if buffer.Cap()-buffer.Len() < p.maxPacketSize {
+		return nil, errors.New("packetPacker BUG: buffer too small")
+	}
+	sealer, hdr, payload := p.maybeGetAppDataPacket(p.maxPacketSize, 0)
+	if payload == nil {
+		return nil, nil
+	}
+	return p.appendAppDataPacket(buffer, sealer, hdr, payload)
+}
+
+func (p *packetPacker) appendAppDataPacket(buffer *packetBuffer, sealer sealer, hdr *wire.ExtendedHeader, payload *payload) (*packetContents, error) {
 	encLevel := protocol.Encryption1RTT
 	if hdr.IsLongHeader {
 		encLevel = protocol.Encryption0RTT
 	}
-	cont, err := p.appendPacket(buffer, hdr, payload, 0, encLevel, sealer)
+	return p.appendPacket(buffer, hdr, payload, 0, encLevel, sealer, false)
+}
+
+// PackPathProbePacket packs a packet that is sent on a path that is being validated.
+// The packet is padded to the minimum size of an Initial packet, unless this exceeds maxSize.
+// It returns nil if the frames don't fit into maxSize.
+func (p *packetPacker) PackPathProbePacket(connID protocol.ConnectionID, frames []ackhandler.Frame, maxSize protocol.ByteCount) (*packedPacket, error) {
+	sealer, err := p.cryptoSetup.Get1RTTSealer()
+	if err != nil {
+		return nil, err
+	}
+	pn, pnLen := p.pnManager.PeekPacketNumber(protocol.Encryption1RTT)
+	hdr := &wire.ExtendedHeader{}
+	hdr.PacketNumber = pn
+	hdr.PacketNumberLen = pnLen
+	hdr.DestConnectionID = connID
+	hdr.KeyPhase = sealer.KeyPhase()
+
+	payload := &payload{frames: frames}
+	for _, f := range frames {
+		payload.length += f.Length(p.version)
+	}
+	size := p.packetLength(hdr, payload) + protocol.ByteCount(sealer.Overhead())
+	if size > maxSize || size > p.maxPacketSize {
+		return nil, nil
+	}
+	var padding protocol.ByteCount
+	if targetSize := utils.MinByteCount(utils.MinByteCount(protocol.MinInitialPacketSize, maxSize), p.maxPacketSize); size < targetSize {
+		padding = targetSize - size
+	}
+	buffer := getPacketBuffer()
+	cont, err := p.appendPacket(buffer, hdr, payload, padding, protocol.Encryption1RTT, sealer, false)
 	if err != nil {
 		return nil, err
 	}
@@ -470,6 +548,38 @@ func (p *packetPacker) PackPacket() (*packedPacket, error) {
 	}, nil
 }
 
+// PackMTUProbePacket packs a packet that is used to probe the path MTU.
+// It contains a single PING frame, and is padded to the requested size.
+// If immediateAck is set, it also contains an IMMEDIATE_ACK frame, such that the peer acknowledges the probe right away.
+func (p *packetPacker) PackMTUProbePacket(ping ackhandler.Frame, size protocol.ByteCount, immediateAck bool) (*packedPacket, error) {
+	payload := &payload{
+		frames: []ackhandler.Frame{ping},
+		length: ping.Length(p.version),
+	}
+	if immediateAck {
+		f := &wire.ImmediateAckFrame{}
+		// don't retransmit the IMMEDIATE_ACK frame when the probe is lost
+		payload.frames = append(payload.frames, ackhandler.Frame{Frame: f, OnLost: func(wire.Frame) {}})
+		payload.length += f.Length(p.version)
+	}
+	sealer, err := p.cryptoSetup.Get1RTTSealer()
+	if err != nil {
+		return nil, err
+	}
+	hdr := p.getShortHeader(sealer.KeyPhase())
+	padding := size - p.packetLength(hdr, payload) - protocol.ByteCount(sealer.Overhead())
+	buffer := getPacketBufferForSize(size)
+	contents, err := p.appendPacket(buffer, hdr, payload, padding, protocol.Encryption1RTT, sealer, true)
+	if err != nil {
+		return nil, err
+	}
+	contents.isMTUProbePacket = true
+	return &packedPacket{
+		buffer:         buffer,
+		packetContents: contents,
+	}, nil
+}
+
 func (p *packetPacker) maybeGetCryptoPacket(maxPacketSize, currentSize protocol.ByteCount, encLevel protocol.EncryptionLevel) (*wire.ExtendedHeader, *payload) {
 	var s cryptoStream
 	var hasRetransmission bool
@@ -583,14 +693,23 @@ func (p *packetPacker) composeNextPacket(maxFrameSize protocol.ByteCount, ackAll
 
 	var hasDatagram bool
 	if p.datagramQueue != nil {
-		if datagram := p.datagramQueue.Get(); datagram != nil {
-			payload.frames = append(payload.frames, ackhandler.Frame{
-				Frame: datagram,
-				// set it to a no-op. Then we won't set the default callback, which would retransmit the frame.
-				OnLost: func(wire.Frame) {},
-			})
-			payload.length += datagram.Length(p.version)
-			hasDatagram = true
+		if datagram := p.datagramQueue.Peek(); datagram != nil {
+			if size := datagram.Length(p.version); size <= maxFrameSize {
+				payload.frames = append(payload.frames, ackhandler.Frame{
+					Frame: datagram,
+					// set it to a no-op. Then we won't set the default callback, which would retransmit the frame.
+					OnLost: func(wire.Frame) {},
+				})
+				payload.length += size
+				hasDatagram = true
+				p.datagramQueue.Pop()
+			} else if ackAllowed {
+				// This is a full-size 1-RTT packet, and the DATAGRAM frame still doesn't fit.
+				// This only happens if the maximum packet size was reduced after the frame was queued,
+				// e.g. when a path MTU black hole was detected. It will never be sent, so drop it.
+				p.datagramQueue.Pop()
+			}
+			// Otherwise the frame is sent in one of the next packets.
 		}
 	}
 
@@ -675,8 +794,8 @@ func (p *packetPacker) MaybePackProbePacket(encLevel protocol.EncryptionLevel) (
 	if encLevel == protocol.EncryptionInitial {
 		padding = p.paddingLen(payload.frames, size)
 	}
-	buffer := getPacketBuffer()
-	cont, err := p.appendPacket(buffer, hdr, payload, padding, encLevel, sealer)
+	buffer := getPacketBufferForSize(p.maxPacketSize)
+	cont, err := p.appendPacket(buffer, hdr, payload, padding, encLevel, sealer, false)
 	if err != nil {
 		return nil, err
 	}
@@ -762,12 +881,12 @@ func (p *packetPacker) writeSinglePacket(
 	encLevel protocol.EncryptionLevel,
 	sealer sealer,
 ) (*packedPacket, error) {
-	buffer := getPacketBuffer()
+	buffer := getPacketBufferForSize(p.maxPacketSize)
 	var paddingLen protocol.ByteCount
 	if encLevel == protocol.EncryptionInitial {
 		paddingLen = p.paddingLen(payload.frames, hdr.GetLength(p.version)+payload.length+protocol.ByteCount(sealer.Overhead()))
 	}
-	contents, err := p.appendPacket(buffer, hdr, payload, paddingLen, encLevel, sealer)
+	contents, err := p.appendPacket(buffer, hdr, payload, paddingLen, encLevel, sealer, false)
 	if err != nil {
 		return nil, err
 	}
@@ -784,6 +903,7 @@ func (p *packetPacker) appendPacket(
 	padding protocol.ByteCount, // add padding such that the packet has this length. 0 for no padding.
 	encLevel protocol.EncryptionLevel,
 	sealer sealer,
+	isMTUProbePacket bool,
 ) (*packetContents, error) {
 	var paddingLen protocol.ByteCount
 	pnLen := protocol.ByteCount(header.PacketNumberLen)
@@ -819,7 +939,7 @@ func (p *packetPacker) appendPacket(
 	if payloadSize := protocol.ByteCount(buf.Len()-payloadOffset) - paddingLen; payloadSize != payload.length {
 		return nil, fmt.Errorf("PacketPacker BUG: payload size inconsistent (expected %d, got %d bytes)", payload.length, payloadSize)
 	}
-	if size := protocol.ByteCount(buf.Len() + sealer.Overhead()); size > p.maxPacketSize {
+	if size := protocol.ByteCount(buf.Len()+sealer.Overhead()) - hdrOffset; !isMTUProbePacket && size > p.maxPacketSize {
 		return nil, fmt.Errorf("PacketPacker BUG: packet too large (%d bytes, allowed %d bytes)", size, p.maxPacketSize)
 	}
 
@@ -849,8 +969,38 @@ func (p *packetPacker) SetToken(token []byte) {
 	p.token = token
 }
 
+// SetMaxPacketSize sets the maximum size of the packets sent.
+// It is called when path MTU discovery changes the packet size.
+func (p *packetPacker) SetMaxPacketSize(s protocol.ByteCount) {
+	p.setMaxPacketSize(s)
+}
+
+func (p *packetPacker) setMaxPacketSize(s protocol.ByteCount) {
+	p.maxPacketSize = s
+	atomic.StoreUint64(&p.maxPacketSizeAtomic, uint64(s))
+}
+
+// MaxPacketSize returns the maximum size of the packets sent.
+func (p *packetPacker) MaxPacketSize() protocol.ByteCount {
+	return p.maxPacketSize
+}
+
+// MaxDatagramFrameSize returns the size of the largest DATAGRAM frame that fits into a 1-RTT packet.
+// The estimate is conservative: it assumes the longest possible connection ID and packet number.
+// It is safe to call MaxDatagramFrameSize from any goroutine.
+func (p *packetPacker) MaxDatagramFrameSize() protocol.ByteCount {
+	maxPacketSize := protocol.ByteCount(atomic.LoadUint64(&p.maxPacketSizeAtomic))
+	return maxPacketSize - maxShortHeaderPacketOverhead
+}
+
+// SetVersion sets the QUIC version used for packets sent from now on.
+// It is used when performing compatible version negotiation.
+func (p *packetPacker) SetVersion(v protocol.VersionNumber) {
+	p.version = v
+}
+
 func (p *packetPacker) HandleTransportParameters(params *wire.TransportParameters) {
 	if params.MaxUDPPayloadSize != 0 {
-		p.maxPacketSize = utils.MinByteCount(p.maxPacketSize, params.MaxUDPPayloadSize)
+		p.setMaxPacketSize(utils.MinByteCount(p.maxPacketSize, params.MaxUDPPayloadSize))
 	}
 }
@@ -273,6 +273,80 @@ var _ = Describe("Packet packer", func() {
 			})
 		})
 
+		Context("packing path probe packets", func() {
+			probeConnID := protocol.ConnectionID{8, 7, 6, 5, 4, 3, 2, 1}
+
+			It("packs a path probe packet and pads it", func() {
+				pnManager.EXPECT().PeekPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42), protocol.PacketNumberLen2)
+				pnManager.EXPECT().PopPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42))
+				sealingManager.EXPECT().Get1RTTSealer().Return(getSealer(), nil)
+				frames := []ackhandler.Frame{{Frame: &wire.PathChallengeFrame{Data: [8]byte{1, 2, 3, 4, 5, 6, 7, 8}}}}
+				p, err := packer.PackPathProbePacket(probeConnID, frames, protocol.MaxByteCount)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(p).ToNot(BeNil())
+				Expect(p.EncryptionLevel()).To(Equal(protocol.Encryption1RTT))
+				Expect(p.frames).To(Equal(frames))
+				Expect(p.buffer.Len()).To(BeEquivalentTo(protocol.MinInitialPacketSize))
+				hdrs := parsePacket(p.buffer.Data)
+				Expect(hdrs).To(HaveLen(1))
+				Expect(hdrs[0].DestConnectionID).To(Equal(probeConnID))
+			})
+
+			It("only pads up to the maximum size", func() {
+				pnManager.EXPECT().PeekPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42), protocol.PacketNumberLen2)
+				pnManager.EXPECT().PopPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42))
+				sealingManager.EXPECT().Get1RTTSealer().Return(getSealer(), nil)
+				frames := []ackhandler.Frame{{Frame: &wire.PathResponseFrame{Data: [8]byte{1, 2, 3, 4, 5, 6, 7, 8}}}}
+				p, err := packer.PackPathProbePacket(probeConnID, frames, 500)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(p).ToNot(BeNil())
+				Expect(p.buffer.Len()).To(BeEquivalentTo(500))
+			})
+
+			It("doesn't pack a packet if the frames don't fit into the maximum size", func() {
+				pnManager.EXPECT().PeekPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42), protocol.PacketNumberLen2)
+				sealingManager.EXPECT().Get1RTTSealer().Return(getSealer(), nil)
+				frames := []ackhandler.Frame{{Frame: &wire.PathChallengeFrame{Data: [8]byte{1, 2, 3, 4, 5, 6, 7, 8}}}}
+				p, err := packer.PackPathProbePacket(probeConnID, frames, 20)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(p).To(BeNil())
+			})
+		})
+
+		Context("packing MTU probe packets", func() {
+			It("packs a PING frame and pads the packet to the probe size", func() {
+				pnManager.EXPECT().PeekPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x43), protocol.PacketNumberLen2)
+				pnManager.EXPECT().PopPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x43))
+				sealingManager.EXPECT().Get1RTTSealer().Return(getSealer(), nil)
+				ping := ackhandler.Frame{Frame: &wire.PingFrame{}}
+				const probeSize = maxPacketSize + 42
+				p, err := packer.PackMTUProbePacket(ping, probeSize, false)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(p).ToNot(BeNil())
+				Expect(p.EncryptionLevel()).To(Equal(protocol.Encryption1RTT))
+				Expect(p.frames).To(Equal([]ackhandler.Frame{ping}))
+				Expect(p.length).To(BeEquivalentTo(probeSize))
+				Expect(p.buffer.Len()).To(BeEquivalentTo(probeSize))
+				Expect(p.ToAckHandlerPacket(time.Now(), nil).IsPathMTUProbePacket).To(BeTrue())
+				parsePacket(p.buffer.Data)
+			})
+
+			It("adds an IMMEDIATE_ACK frame, if requested", func() {
+				pnManager.EXPECT().PeekPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x43), protocol.PacketNumberLen2)
+				pnManager.EXPECT().PopPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x43))
+				sealingManager.EXPECT().Get1RTTSealer().Return(getSealer(), nil)
+				ping := ackhandler.Frame{Frame: &wire.PingFrame{}}
+				const probeSize = maxPacketSize + 42
+				p, err := packer.PackMTUProbePacket(ping, probeSize, true)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(p).ToNot(BeNil())
+				Expect(p.frames).To(HaveLen(2))
+				Expect(p.frames[0]).To(Equal(ping))
+				Expect(p.frames[1].Frame).To(Equal(&wire.ImmediateAckFrame{}))
+				Expect(p.buffer.Len()).To(BeEquivalentTo(probeSize))
+			})
+		})
+
 		Context("packing 0-RTT packets", func() {
 			BeforeEach(func() {
 				packer.perspective = protocol.PerspectiveClient
@@ -567,6 +641,38 @@ var _ = Describe("Packet packer", func() {
 				Eventually(done).Should(BeClosed())
 			})
 
+			It("drops DATAGRAM frames that are larger than the maximum packet size", func() {
+				pnManager.EXPECT().PeekPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42), protocol.PacketNumberLen2)
+				sealingManager.EXPECT().Get1RTTSealer().Return(getSealer(), nil)
+				f := &wire.DatagramFrame{
+					DataLenPresent: true,
+					Data:           make([]byte, packer.maxPacketSize),
+				}
+				done := make(chan struct{})
+				go func() {
+					defer GinkgoRecover()
+					defer close(done)
+					datagramQueue.AddAndWait(f)
+				}()
+				// make sure the DATAGRAM has actually been queued
+				time.Sleep(scaleDuration(20 * time.Millisecond))
+
+				framer.EXPECT().HasData()
+				ackFramer.EXPECT().GetAckFrame(protocol.Encryption1RTT, true)
+				p, err := packer.PackPacket()
+				Expect(p).To(BeNil())
+				Expect(err).ToNot(HaveOccurred())
+				Eventually(done).Should(BeClosed())
+				Expect(datagramQueue.Peek()).To(BeNil())
+			})
+
+			It("calculates the maximum size of a DATAGRAM frame", func() {
+				packer.SetMaxPacketSize(1400)
+				maxSize := packer.MaxDatagramFrameSize()
+				Expect(maxSize).To(BeNumerically("<", 1400))
+				Expect(maxSize).To(BeNumerically(">", 1400-50))
+			})
+
 			It("accounts for the space consumed by control frames", func() {
 				pnManager.EXPECT().PeekPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42), protocol.PacketNumberLen2)
 				sealingManager.EXPECT().Get1RTTSealer().Return(getSealer(), nil)
@@ -853,6 +959,79 @@ var _ = Describe("Packet packer", func() {
 					_, err = packer.PackPacket()
 					Expect(err).ToNot(HaveOccurred())
 				})
+
+				It("sets the max packet size discovered by path MTU discovery", func() {
+					pnManager.EXPECT().PeekPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42), protocol.PacketNumberLen2).Times(2)
+					sealingManager.EXPECT().Get1RTTSealer().Return(getSealer(), nil).Times(2)
+					framer.EXPECT().HasData().Return(true).Times(2)
+					ackFramer.EXPECT().GetAckFrame(protocol.Encryption1RTT, false).Times(2)
+					var initialMaxPacketSize protocol.ByteCount
+					framer.EXPECT().AppendControlFrames(gomock.Any(), gomock.Any()).Do(func(_ []ackhandler.Frame, maxLen protocol.ByteCount) ([]ackhandler.Frame, protocol.ByteCount) {
+						initialMaxPacketSize = maxLen
+						return nil, 0
+					})
+					expectAppendStreamFrames()
+					_, err := packer.PackPacket()
+					Expect(err).ToNot(HaveOccurred())
+					// now increase the maxPacketSize
+					packer.SetMaxPacketSize(maxPacketSize + 10)
+					framer.EXPECT().AppendControlFrames(gomock.Any(), gomock.Any()).Do(func(_ []ackhandler.Frame, maxLen protocol.ByteCount) ([]ackhandler.Frame, protocol.ByteCount) {
+						Expect(maxLen).To(Equal(initialMaxPacketSize + 10))
+						return nil, 0
+					})
+					expectAppendStreamFrames()
+					_, err = packer.PackPacket()
+					Expect(err).ToNot(HaveOccurred())
+				})
+			})
+		})
+
+		Context("appending packets", func() {
+			It("appends a packet to the buffer", func() {
+				pnManager.EXPECT().PeekPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42), protocol.PacketNumberLen2)
+				pnManager.EXPECT().PopPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42))
+				sealingManager.EXPECT().Get1RTTSealer().Return(getSealer(), nil)
+				framer.EXPECT().HasData().Return(true)
+				ackFramer.EXPECT().GetAckFrame(protocol.Encryption1RTT, false)
+				expectAppendControlFrames()
+				f := &wire.StreamFrame{
+					StreamID: 5,
+					Data:     []byte{0xde, 0xca, 0xfb, 0xad},
+				}
+				expectAppendStreamFrames(ackhandler.Frame{Frame: f})
+				buffer := getLargePacketBuffer()
+				buffer.Data = append(buffer.Data, []byte("foobar")...)
+				p, err := packer.AppendPacket(buffer)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(p).ToNot(BeNil())
+				Expect(p.frames).To(Equal([]ackhandler.Frame{{Frame: f}}))
+				Expect(buffer.Data[:6]).To(Equal([]byte("foobar")))
+				Expect(buffer.Len()).To(Equal(6 + p.length))
+				b := &bytes.Buffer{}
+				f.Write(b, packer.version)
+				Expect(buffer.Data[6:]).To(ContainSubstring(b.String()))
+				hdr, _, _, err := wire.ParsePacket(buffer.Data[6:], len(packer.getDestConnID()))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(hdr.IsLongHeader).To(BeFalse())
+			})
+
+			It("returns nil when no packet is queued", func() {
+				pnManager.EXPECT().PeekPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42), protocol.PacketNumberLen2)
+				sealingManager.EXPECT().Get1RTTSealer().Return(getSealer(), nil)
+				ackFramer.EXPECT().GetAckFrame(protocol.Encryption1RTT, true)
+				framer.EXPECT().HasData()
+				buffer := getLargePacketBuffer()
+				p, err := packer.AppendPacket(buffer)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(p).To(BeNil())
+				Expect(buffer.Len()).To(BeZero())
+			})
+
+			It("refuses to append a packet if the buffer doesn't have enough space left", func() {
+				buffer := getPacketBuffer()
+				buffer.Data = buffer.Data[:buffer.Cap()-packer.MaxPacketSize()+1]
+				_, err := packer.AppendPacket(buffer)
+				Expect(err).To(MatchError("packetPacker BUG: buffer too small"))
 			})
 		})
 
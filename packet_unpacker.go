@@ -54,6 +54,12 @@ func newPacketUnpacker(cs handshake.CryptoSetup, version protocol.VersionNumber)
 	}
 }
 
+// SetVersion sets the QUIC version used to parse packets received from now on.
+// It is used when performing compatible version negotiation.
+func (u *packetUnpacker) SetVersion(v protocol.VersionNumber) {
+	u.version = v
+}
+
 // If the reserved bits are invalid, the error is wire.ErrInvalidReservedBits.
 // If any other error occurred when parsing the header, the error is of type headerParseError.
 // If decrypting the payload fails for any reason, the error is the error returned by the AEAD.
@@ -65,7 +71,14 @@ func (u *packetUnpacker) Unpack(hdr *wire.Header, rcvTime time.Time, data []byte
 	switch hdr.Type {
 	case protocol.PacketTypeInitial:
 		encLevel = protocol.EncryptionInitial
-		opener, err := u.cs.GetInitialOpener()
+		var opener handshake.LongHeaderOpener
+		var err error
+		if hdr.Version != u.version {
+			// After compatible version negotiation, the server still accepts Initial packets of the original version.
+			opener, err = u.cs.GetOriginalInitialOpener()
+		} else {
+			opener, err = u.cs.GetInitialOpener()
+		}
 		if err != nil {
 			return nil, err
 		}
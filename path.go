@@ -0,0 +1,158 @@
+package quic
+
+import (
+	"crypto/rand"
+	"net"
+	"time"
+
+	"github.com/For-ACGN/quic-go/internal/ackhandler"
+	"github.com/For-ACGN/quic-go/internal/protocol"
+	"github.com/For-ACGN/quic-go/internal/wire"
+)
+
+// Before validating a path, the server won't send more than 3x bytes than it received on this path.
+const pathAmplificationFactor = 3
+
+// A pathMigration is a request to migrate the connection to a new packet conn.
+type pathMigration struct {
+	conn           sendConn
+	packetHandlers sessionRunner
+	result         chan error
+
+	// The connection IDs that were registered with the packet handlers of the new packet conn during path validation.
+	// They are removed if path validation fails.
+	connIDs []protocol.ConnectionID
+}
+
+// A path is a network path that is not (yet) used for sending application data.
+// Before switching to a new path, it is validated using PATH_CHALLENGE frames.
+type path struct {
+	conn sendConn
+	// The destination connection ID used for packets sent on this path.
+	connID protocol.ConnectionID
+
+	// The server is limited by the anti-amplification limit when sending on an unvalidated path.
+	amplificationLimited bool
+	bytesReceived        protocol.ByteCount
+	bytesSent            protocol.ByteCount
+
+	// PATH_CHALLENGE frames received on this path that we haven't responded to yet.
+	pathResponses []*wire.PathResponseFrame
+
+	isValidating  bool
+	challenges    [][8]byte
+	nextChallenge time.Time
+	probeInterval time.Duration
+	deadline      time.Time
+
+	// only set for the client, when migrating to a new packet conn
+	migration *pathMigration
+}
+
+func newPath(conn sendConn, connID protocol.ConnectionID, amplificationLimited bool) *path {
+	return &path{
+		conn:                 conn,
+		connID:               connID,
+		amplificationLimited: amplificationLimited,
+	}
+}
+
+// StartValidation starts sending PATH_CHALLENGE frames.
+// Path validation fails if no PATH_RESPONSE is received within the timeout.
+func (p *path) StartValidation(timeout time.Duration, now time.Time) {
+	if p.isValidating {
+		return
+	}
+	p.isValidating = true
+	p.nextChallenge = now
+	p.probeInterval = timeout / protocol.MaxPathChallenges
+	p.deadline = now.Add(timeout)
+}
+
+// RemoteAddr returns the remote address of the path.
+func (p *path) RemoteAddr() net.Addr {
+	return p.conn.RemoteAddr()
+}
+
+// QueuePathResponse queues a PATH_RESPONSE frame in response to a PATH_CHALLENGE frame received on this path.
+func (p *path) QueuePathResponse(f *wire.PathChallengeFrame) {
+	p.pathResponses = append(p.pathResponses, &wire.PathResponseFrame{Data: f.Data})
+}
+
+// AppendFrames appends the PATH_RESPONSE frames that are queued,
+// and a new PATH_CHALLENGE frame if it's time to send one.
+func (p *path) AppendFrames(frames []ackhandler.Frame, now time.Time) []ackhandler.Frame {
+	// PATH_CHALLENGE and PATH_RESPONSE frames are never retransmitted.
+	// We send a new PATH_CHALLENGE after the probe interval instead.
+	for _, f := range p.pathResponses {
+		frames = append(frames, ackhandler.Frame{Frame: f, OnLost: func(wire.Frame) {}})
+	}
+	p.pathResponses = p.pathResponses[:0]
+	if !p.isValidating || len(p.challenges) >= protocol.MaxPathChallenges || now.Before(p.nextChallenge) {
+		return frames
+	}
+	var data [8]byte
+	rand.Read(data[:])
+	p.challenges = append(p.challenges, data)
+	p.nextChallenge = now.Add(p.probeInterval)
+	return append(frames, ackhandler.Frame{Frame: &wire.PathChallengeFrame{Data: data}, OnLost: func(wire.Frame) {}})
+}
+
+// HandlePathResponse says if the PATH_RESPONSE frame validates this path.
+func (p *path) HandlePathResponse(f *wire.PathResponseFrame) bool {
+	for _, c := range p.challenges {
+		if c == f.Data {
+			return true
+		}
+	}
+	return false
+}
+
+// ReceivedBytes is called for every packet received on this path.
+func (p *path) ReceivedBytes(n protocol.ByteCount) {
+	p.bytesReceived += n
+}
+
+// SentBytes is called for every packet sent on this path.
+func (p *path) SentBytes(n protocol.ByteCount) {
+	p.bytesSent += n
+}
+
+// SendBudget is the maximum number of bytes that can be sent on this path at this moment.
+func (p *path) SendBudget() protocol.ByteCount {
+	if !p.amplificationLimited {
+		return protocol.MaxByteCount
+	}
+	if p.bytesSent >= pathAmplificationFactor*p.bytesReceived {
+		return 0
+	}
+	return pathAmplificationFactor*p.bytesReceived - p.bytesSent
+}
+
+// Timeout returns the time when the next PATH_CHALLENGE should be sent, or when path validation fails.
+// It returns a zero time if path validation wasn't started.
+func (p *path) Timeout() time.Time {
+	if !p.isValidating {
+		return time.Time{}
+	}
+	if len(p.challenges) < protocol.MaxPathChallenges {
+		return p.nextChallenge
+	}
+	return p.deadline
+}
+
+// HasTimedOut says if path validation failed.
+func (p *path) HasTimedOut(now time.Time) bool {
+	return p.isValidating && !now.Before(p.deadline)
+}
+
+// isProbingFrame says if a frame is a probing frame.
+// A packet that only contains probing frames doesn't cause the server to migrate to a new path.
+func isProbingFrame(f wire.Frame) bool {
+	switch f.(type) {
+	case *wire.PathChallengeFrame, *wire.PathResponseFrame, *wire.NewConnectionIDFrame:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,106 @@
+package quic
+
+import (
+	"net"
+	"time"
+
+	"github.com/For-ACGN/quic-go/internal/protocol"
+	"github.com/For-ACGN/quic-go/internal/wire"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Path", func() {
+	var (
+		p     *path
+		mconn *MockSendConn
+	)
+	connID := protocol.ConnectionID{1, 2, 3, 4}
+
+	BeforeEach(func() {
+		mconn = NewMockSendConn(mockCtrl)
+		p = newPath(mconn, connID, true)
+	})
+
+	It("returns the remote address", func() {
+		addr := &net.UDPAddr{IP: net.IPv4(192, 168, 0, 1), Port: 1337}
+		mconn.EXPECT().RemoteAddr().Return(addr)
+		Expect(p.RemoteAddr()).To(Equal(addr))
+	})
+
+	It("doesn't send PATH_CHALLENGE frames before path validation is started", func() {
+		Expect(p.AppendFrames(nil, time.Now())).To(BeEmpty())
+		Expect(p.Timeout()).To(BeZero())
+		Expect(p.HasTimedOut(time.Now().Add(time.Hour))).To(BeFalse())
+	})
+
+	It("sends PATH_CHALLENGE frames", func() {
+		now := time.Now()
+		p.StartValidation(3*time.Second, now)
+		Expect(p.Timeout()).To(Equal(now))
+		frames := p.AppendFrames(nil, now)
+		Expect(frames).To(HaveLen(1))
+		Expect(frames[0].Frame).To(BeAssignableToTypeOf(&wire.PathChallengeFrame{}))
+		Expect(p.AppendFrames(nil, now.Add(time.Second-time.Nanosecond))).To(BeEmpty())
+		Expect(p.Timeout()).To(Equal(now.Add(time.Second)))
+		frames2 := p.AppendFrames(nil, now.Add(time.Second))
+		Expect(frames2).To(HaveLen(1))
+		Expect(frames2[0].Frame).ToNot(Equal(frames[0].Frame))
+	})
+
+	It("stops sending PATH_CHALLENGE frames after the maximum number was sent", func() {
+		now := time.Now()
+		p.StartValidation(3*time.Second, now)
+		for i := 0; i < protocol.MaxPathChallenges; i++ {
+			Expect(p.AppendFrames(nil, now.Add(time.Duration(i)*time.Second))).To(HaveLen(1))
+		}
+		Expect(p.Timeout()).To(Equal(now.Add(3 * time.Second)))
+		Expect(p.AppendFrames(nil, now.Add(3*time.Second))).To(BeEmpty())
+		Expect(p.HasTimedOut(now.Add(3*time.Second - time.Nanosecond))).To(BeFalse())
+		Expect(p.HasTimedOut(now.Add(3 * time.Second))).To(BeTrue())
+	})
+
+	It("validates the path when receiving a matching PATH_RESPONSE", func() {
+		now := time.Now()
+		p.StartValidation(3*time.Second, now)
+		frames := p.AppendFrames(nil, now)
+		Expect(frames).To(HaveLen(1))
+		frames = p.AppendFrames(frames, now.Add(time.Second))
+		Expect(frames).To(HaveLen(2))
+		Expect(p.HandlePathResponse(&wire.PathResponseFrame{Data: [8]byte{1, 2, 3, 4, 5, 6, 7, 8}})).To(BeFalse())
+		Expect(p.HandlePathResponse(&wire.PathResponseFrame{Data: frames[0].Frame.(*wire.PathChallengeFrame).Data})).To(BeTrue())
+		Expect(p.HandlePathResponse(&wire.PathResponseFrame{Data: frames[1].Frame.(*wire.PathChallengeFrame).Data})).To(BeTrue())
+	})
+
+	It("sends PATH_RESPONSE frames", func() {
+		p.QueuePathResponse(&wire.PathChallengeFrame{Data: [8]byte{1, 2, 3, 4, 5, 6, 7, 8}})
+		frames := p.AppendFrames(nil, time.Now())
+		Expect(frames).To(HaveLen(1))
+		Expect(frames[0].Frame).To(Equal(&wire.PathResponseFrame{Data: [8]byte{1, 2, 3, 4, 5, 6, 7, 8}}))
+		Expect(p.AppendFrames(nil, time.Now())).To(BeEmpty())
+	})
+
+	It("enforces the anti-amplification limit", func() {
+		Expect(p.SendBudget()).To(BeZero())
+		p.ReceivedBytes(100)
+		Expect(p.SendBudget()).To(Equal(protocol.ByteCount(300)))
+		p.SentBytes(250)
+		Expect(p.SendBudget()).To(Equal(protocol.ByteCount(50)))
+		p.SentBytes(100)
+		Expect(p.SendBudget()).To(BeZero())
+	})
+
+	It("doesn't limit sending on paths that are not amplification limited", func() {
+		p = newPath(mconn, connID, false)
+		Expect(p.SendBudget()).To(Equal(protocol.MaxByteCount))
+	})
+
+	It("identifies probing frames", func() {
+		Expect(isProbingFrame(&wire.PathChallengeFrame{})).To(BeTrue())
+		Expect(isProbingFrame(&wire.PathResponseFrame{})).To(BeTrue())
+		Expect(isProbingFrame(&wire.NewConnectionIDFrame{})).To(BeTrue())
+		Expect(isProbingFrame(&wire.PingFrame{})).To(BeFalse())
+		Expect(isProbingFrame(&wire.StreamFrame{})).To(BeFalse())
+	})
+})
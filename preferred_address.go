@@ -0,0 +1,177 @@
+package quic
+
+import (
+	"net"
+
+	"github.com/For-ACGN/quic-go/internal/protocol"
+	"github.com/For-ACGN/quic-go/internal/wire"
+	"github.com/For-ACGN/quic-go/logging"
+)
+
+// listenPreferredAddresses opens the packet conns for the server's preferred addresses.
+// If the port of a preferred address is 0, the address in the config is updated to the port that was chosen.
+func listenPreferredAddresses(config *Config) ([]net.PacketConn, error) {
+	var conns []net.PacketConn
+	for _, addr := range []**net.UDPAddr{&config.PreferredAddressIPv4, &config.PreferredAddressIPv6} {
+		if *addr == nil {
+			continue
+		}
+		conn, err := net.ListenUDP("udp", *addr)
+		if err != nil {
+			for _, c := range conns {
+				c.Close()
+			}
+			return nil, err
+		}
+		*addr = conn.LocalAddr().(*net.UDPAddr)
+		conns = append(conns, conn)
+	}
+	return conns, nil
+}
+
+// newPreferredAddress creates the value of the preferred_address transport parameter.
+func newPreferredAddress(config *Config, connID protocol.ConnectionID, token protocol.StatelessResetToken) *wire.PreferredAddress {
+	pa := &wire.PreferredAddress{
+		ConnectionID:        connID,
+		StatelessResetToken: token,
+	}
+	if addr := config.PreferredAddressIPv4; addr != nil {
+		pa.IPv4 = addr.IP
+		pa.IPv4Port = uint16(addr.Port)
+	}
+	if addr := config.PreferredAddressIPv6; addr != nil {
+		pa.IPv6 = addr.IP
+		pa.IPv6Port = uint16(addr.Port)
+	}
+	return pa
+}
+
+// parsePreferredAddress returns the preferred address that the client should migrate to.
+// It only returns an address of the same address family as the current path.
+func parsePreferredAddress(pa *wire.PreferredAddress, remoteAddr net.Addr) *net.UDPAddr {
+	addr, ok := remoteAddr.(*net.UDPAddr)
+	if !ok {
+		return nil
+	}
+	if addr.IP.To4() != nil {
+		if pa.IPv4Port == 0 || pa.IPv4.IsUnspecified() {
+			return nil
+		}
+		return &net.UDPAddr{IP: pa.IPv4, Port: int(pa.IPv4Port)}
+	}
+	if pa.IPv6Port == 0 || pa.IPv6.IsUnspecified() {
+		return nil
+	}
+	return &net.UDPAddr{IP: pa.IPv6, Port: int(pa.IPv6Port)}
+}
+
+// A preferredAddressHandler passes packets received on a preferred address to the session.
+type preferredAddressHandler struct {
+	packetHandler
+
+	conn net.PacketConn
+}
+
+func (h *preferredAddressHandler) handlePacket(p *receivedPacket) {
+	p.conn = h.conn
+	h.packetHandler.handlePacket(p)
+}
+
+type preferredAddressConn struct {
+	conn           net.PacketConn
+	packetHandlers packetHandlerManager
+}
+
+// The preferredAddressSessionHandler is used by a server that advertises preferred addresses.
+// It registers all connection IDs with the packet handler managers of the preferred addresses,
+// such that sessions also receive packets sent to the preferred addresses.
+type preferredAddressSessionHandler struct {
+	packetHandlerManager
+
+	conns []preferredAddressConn
+}
+
+var _ packetHandlerManager = &preferredAddressSessionHandler{}
+
+func newPreferredAddressSessionHandler(
+	sessionHandler packetHandlerManager,
+	conns []net.PacketConn,
+	connIDLen int,
+	maxPacketSize protocol.ByteCount,
+	statelessResetKey []byte,
+	tracer logging.Tracer,
+) (*preferredAddressSessionHandler, error) {
+	h := &preferredAddressSessionHandler{packetHandlerManager: sessionHandler}
+	for i, conn := range conns {
+		packetHandlers, err := getMultiplexer().AddConn(conn, connIDLen, maxPacketSize, statelessResetKey, tracer)
+		if err != nil {
+			h.destroyPreferredAddresses()
+			for _, c := range conns[i:] {
+				c.Close()
+			}
+			return nil, err
+		}
+		h.conns = append(h.conns, preferredAddressConn{conn: conn, packetHandlers: packetHandlers})
+	}
+	return h, nil
+}
+
+func (h *preferredAddressSessionHandler) AddWithConnID(clientDestConnID, newConnID protocol.ConnectionID, fn func() packetHandler) bool {
+	var handler packetHandler
+	if !h.packetHandlerManager.AddWithConnID(clientDestConnID, newConnID, func() packetHandler {
+		handler = fn()
+		return handler
+	}) {
+		return false
+	}
+	h.addToPreferredAddresses(newConnID, handler)
+	return true
+}
+
+func (h *preferredAddressSessionHandler) Add(connID protocol.ConnectionID, handler packetHandler) bool {
+	if !h.packetHandlerManager.Add(connID, handler) {
+		return false
+	}
+	h.addToPreferredAddresses(connID, handler)
+	return true
+}
+
+func (h *preferredAddressSessionHandler) addToPreferredAddresses(connID protocol.ConnectionID, handler packetHandler) {
+	for _, c := range h.conns {
+		c.packetHandlers.Add(connID, &preferredAddressHandler{packetHandler: handler, conn: c.conn})
+	}
+}
+
+func (h *preferredAddressSessionHandler) Retire(connID protocol.ConnectionID) {
+	h.packetHandlerManager.Retire(connID)
+	for _, c := range h.conns {
+		c.packetHandlers.Retire(connID)
+	}
+}
+
+func (h *preferredAddressSessionHandler) Remove(connID protocol.ConnectionID) {
+	h.packetHandlerManager.Remove(connID)
+	for _, c := range h.conns {
+		c.packetHandlers.Remove(connID)
+	}
+}
+
+func (h *preferredAddressSessionHandler) ReplaceWithClosed(connID protocol.ConnectionID, handler packetHandler) {
+	h.packetHandlerManager.ReplaceWithClosed(connID, handler)
+	for _, c := range h.conns {
+		c.packetHandlers.ReplaceWithClosed(connID, handler)
+	}
+}
+
+// CloseServer closes all sessions, and the packet conns of the preferred addresses.
+func (h *preferredAddressSessionHandler) CloseServer() {
+	h.packetHandlerManager.CloseServer()
+	h.destroyPreferredAddresses()
+}
+
+// destroyPreferredAddresses closes the packet conns of the preferred addresses.
+func (h *preferredAddressSessionHandler) destroyPreferredAddresses() {
+	for _, c := range h.conns {
+		c.packetHandlers.Destroy()
+	}
+}
@@ -0,0 +1,182 @@
+package quic
+
+import (
+	"errors"
+	"net"
+
+	"github.com/For-ACGN/quic-go/internal/protocol"
+	"github.com/For-ACGN/quic-go/internal/wire"
+
+	"github.com/golang/mock/gomock"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Preferred Address", func() {
+	Context("listening", func() {
+		It("listens on the preferred addresses", func() {
+			config := &Config{PreferredAddressIPv4: &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)}}
+			conns, err := listenPreferredAddresses(config)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(conns).To(HaveLen(1))
+			defer conns[0].Close()
+			Expect(config.PreferredAddressIPv4.Port).ToNot(BeZero())
+			Expect(conns[0].LocalAddr()).To(Equal(config.PreferredAddressIPv4))
+		})
+
+		It("errors when it can't listen on a preferred address", func() {
+			conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+			Expect(err).ToNot(HaveOccurred())
+			defer conn.Close()
+			_, err = listenPreferredAddresses(&Config{PreferredAddressIPv4: conn.LocalAddr().(*net.UDPAddr)})
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("creating the session handler", func() {
+		var (
+			mockMultiplexer *MockMultiplexer
+			origMultiplexer multiplexer
+		)
+
+		BeforeEach(func() {
+			getMultiplexer() // make the sync.Once execute
+			mockMultiplexer = NewMockMultiplexer(mockCtrl)
+			origMultiplexer = connMuxer
+			connMuxer = mockMultiplexer
+		})
+
+		AfterEach(func() {
+			connMuxer = origMultiplexer
+		})
+
+		It("closes all packet conns when adding a packet conn fails", func() {
+			conn1 := NewMockPacketConn(mockCtrl)
+			conn2 := NewMockPacketConn(mockCtrl)
+			conn3 := NewMockPacketConn(mockCtrl)
+			packetHandlers := NewMockPacketHandlerManager(mockCtrl)
+			gomock.InOrder(
+				mockMultiplexer.EXPECT().AddConn(conn1, 4, protocol.ByteCount(1337), nil, nil).Return(packetHandlers, nil),
+				mockMultiplexer.EXPECT().AddConn(conn2, 4, protocol.ByteCount(1337), nil, nil).Return(nil, errors.New("add failed")),
+			)
+			packetHandlers.EXPECT().Destroy()
+			conn2.EXPECT().Close()
+			conn3.EXPECT().Close()
+			_, err := newPreferredAddressSessionHandler(NewMockPacketHandlerManager(mockCtrl), []net.PacketConn{conn1, conn2, conn3}, 4, 1337, nil, nil)
+			Expect(err).To(MatchError("add failed"))
+		})
+	})
+
+	It("creates the preferred_address transport parameter", func() {
+		config := &Config{
+			PreferredAddressIPv4: &net.UDPAddr{IP: net.IPv4(192, 0, 2, 1), Port: 443},
+			PreferredAddressIPv6: &net.UDPAddr{IP: net.ParseIP("2001:db8::1"), Port: 8443},
+		}
+		connID := protocol.ConnectionID{1, 2, 3, 4}
+		token := protocol.StatelessResetToken{16, 15, 14, 13, 12, 11, 10, 9, 8, 7, 6, 5, 4, 3, 2, 1}
+		pa := newPreferredAddress(config, connID, token)
+		Expect(pa.IPv4).To(Equal(config.PreferredAddressIPv4.IP))
+		Expect(pa.IPv4Port).To(BeEquivalentTo(443))
+		Expect(pa.IPv6).To(Equal(config.PreferredAddressIPv6.IP))
+		Expect(pa.IPv6Port).To(BeEquivalentTo(8443))
+		Expect(pa.ConnectionID).To(Equal(connID))
+		Expect(pa.StatelessResetToken).To(Equal(token))
+	})
+
+	Context("choosing the preferred address", func() {
+		pa := &wire.PreferredAddress{
+			IPv4:     net.IPv4(192, 0, 2, 1),
+			IPv4Port: 443,
+			IPv6:     net.ParseIP("2001:db8::1"),
+			IPv6Port: 8443,
+		}
+
+		It("uses the IPv4 address on an IPv4 path", func() {
+			addr := parsePreferredAddress(pa, &net.UDPAddr{IP: net.IPv4(198, 51, 100, 1), Port: 1234})
+			Expect(addr).To(Equal(&net.UDPAddr{IP: pa.IPv4, Port: 443}))
+		})
+
+		It("uses the IPv6 address on an IPv6 path", func() {
+			addr := parsePreferredAddress(pa, &net.UDPAddr{IP: net.ParseIP("2001:db8::2"), Port: 1234})
+			Expect(addr).To(Equal(&net.UDPAddr{IP: pa.IPv6, Port: 8443}))
+		})
+
+		It("doesn't use an address that wasn't set", func() {
+			pa := &wire.PreferredAddress{IPv4: net.IPv4zero, IPv6: net.IPv6unspecified}
+			Expect(parsePreferredAddress(pa, &net.UDPAddr{IP: net.IPv4(198, 51, 100, 1), Port: 1234})).To(BeNil())
+			Expect(parsePreferredAddress(pa, &net.UDPAddr{IP: net.ParseIP("2001:db8::2"), Port: 1234})).To(BeNil())
+		})
+	})
+
+	It("sets the packet conn on received packets", func() {
+		handler := NewMockPacketHandler(mockCtrl)
+		conn := NewMockPacketConn(mockCtrl)
+		h := &preferredAddressHandler{packetHandler: handler, conn: conn}
+		handler.EXPECT().handlePacket(gomock.Any()).Do(func(p *receivedPacket) {
+			Expect(p.conn).To(Equal(conn))
+		})
+		h.handlePacket(&receivedPacket{})
+	})
+
+	Context("session handler", func() {
+		var (
+			sessionHandler, preferredHandler *MockPacketHandlerManager
+			preferredConn                    *MockPacketConn
+			h                                *preferredAddressSessionHandler
+		)
+		connID := protocol.ConnectionID{1, 2, 3, 4}
+
+		BeforeEach(func() {
+			sessionHandler = NewMockPacketHandlerManager(mockCtrl)
+			preferredHandler = NewMockPacketHandlerManager(mockCtrl)
+			preferredConn = NewMockPacketConn(mockCtrl)
+			h = &preferredAddressSessionHandler{
+				packetHandlerManager: sessionHandler,
+				conns:                []preferredAddressConn{{conn: preferredConn, packetHandlers: preferredHandler}},
+			}
+		})
+
+		It("adds connection IDs", func() {
+			handler := NewMockPacketHandler(mockCtrl)
+			sessionHandler.EXPECT().Add(connID, handler).Return(true)
+			preferredHandler.EXPECT().Add(connID, &preferredAddressHandler{packetHandler: handler, conn: preferredConn})
+			Expect(h.Add(connID, handler)).To(BeTrue())
+		})
+
+		It("doesn't add connection IDs that are already in use", func() {
+			sessionHandler.EXPECT().Add(connID, gomock.Any()).Return(false)
+			Expect(h.Add(connID, NewMockPacketHandler(mockCtrl))).To(BeFalse())
+		})
+
+		It("adds the connection ID of new sessions", func() {
+			handler := NewMockPacketHandler(mockCtrl)
+			clientDestConnID := protocol.ConnectionID{4, 3, 2, 1}
+			sessionHandler.EXPECT().AddWithConnID(clientDestConnID, connID, gomock.Any()).DoAndReturn(func(_, _ protocol.ConnectionID, fn func() packetHandler) bool {
+				Expect(fn()).To(Equal(handler))
+				return true
+			})
+			preferredHandler.EXPECT().Add(connID, &preferredAddressHandler{packetHandler: handler, conn: preferredConn})
+			Expect(h.AddWithConnID(clientDestConnID, connID, func() packetHandler { return handler })).To(BeTrue())
+		})
+
+		It("retires, removes and replaces connection IDs", func() {
+			sessionHandler.EXPECT().Retire(connID)
+			preferredHandler.EXPECT().Retire(connID)
+			h.Retire(connID)
+			sessionHandler.EXPECT().Remove(connID)
+			preferredHandler.EXPECT().Remove(connID)
+			h.Remove(connID)
+			closed := NewMockPacketHandler(mockCtrl)
+			sessionHandler.EXPECT().ReplaceWithClosed(connID, closed)
+			preferredHandler.EXPECT().ReplaceWithClosed(connID, closed)
+			h.ReplaceWithClosed(connID, closed)
+		})
+
+		It("closes the packet conns of the preferred addresses when the server is closed", func() {
+			sessionHandler.EXPECT().CloseServer()
+			preferredHandler.EXPECT().Destroy()
+			h.CloseServer()
+		})
+	})
+})
@@ -76,6 +76,25 @@ func (e eventConnectionStarted) MarshalJSONObject(enc *gojay.Encoder) {
 	enc.StringKey("dst_cid", connectionID(e.DestConnectionID).String())
 }
 
+type eventVersionNegotiated struct {
+	ClientVersions, ServerVersions []versionNumber
+	ChosenVersion                  versionNumber
+}
+
+func (e eventVersionNegotiated) Category() category { return categoryTransport }
+func (e eventVersionNegotiated) Name() string       { return "version_information" }
+func (e eventVersionNegotiated) IsNil() bool        { return false }
+
+func (e eventVersionNegotiated) MarshalJSONObject(enc *gojay.Encoder) {
+	if len(e.ClientVersions) > 0 {
+		enc.ArrayKey("client_versions", versions(e.ClientVersions))
+	}
+	if len(e.ServerVersions) > 0 {
+		enc.ArrayKey("server_versions", versions(e.ServerVersions))
+	}
+	enc.StringKey("chosen_version", e.ChosenVersion.String())
+}
+
 type eventConnectionClosed struct {
 	Reason logging.CloseReason
 }
@@ -335,6 +354,7 @@ type eventTransportParameters struct {
 	MaxUDPPayloadSize       protocol.ByteCount
 	AckDelayExponent        uint8
 	MaxAckDelay             time.Duration
+	MinAckDelay             *time.Duration
 	ActiveConnectionIDLimit uint64
 
 	InitialMaxData                 protocol.ByteCount
@@ -368,6 +388,9 @@ func (e eventTransportParameters) MarshalJSONObject(enc *gojay.Encoder) {
 	enc.Uint64KeyNullEmpty("max_udp_payload_size", uint64(e.MaxUDPPayloadSize))
 	enc.Uint8KeyOmitEmpty("ack_delay_exponent", e.AckDelayExponent)
 	enc.FloatKeyOmitEmpty("max_ack_delay", milliseconds(e.MaxAckDelay))
+	if e.MinAckDelay != nil {
+		enc.FloatKey("min_ack_delay", milliseconds(*e.MinAckDelay))
+	}
 	enc.Uint64KeyOmitEmpty("active_connection_id_limit", e.ActiveConnectionIDLimit)
 
 	enc.Int64KeyOmitEmpty("initial_max_data", int64(e.InitialMaxData))
@@ -455,6 +478,20 @@ func (e eventCongestionStateUpdated) MarshalJSONObject(enc *gojay.Encoder) {
 	enc.StringKey("new", e.state.String())
 }
 
+type eventECNStateUpdated struct {
+	state   ecnState
+	trigger ecnStateTrigger
+}
+
+func (e eventECNStateUpdated) Category() category { return categoryRecovery }
+func (e eventECNStateUpdated) Name() string       { return "ecn_state_updated" }
+func (e eventECNStateUpdated) IsNil() bool        { return false }
+
+func (e eventECNStateUpdated) MarshalJSONObject(enc *gojay.Encoder) {
+	enc.StringKey("new", e.state.String())
+	enc.StringKeyOmitEmpty("trigger", e.trigger.String())
+}
+
 type eventGeneric struct {
 	name string
 	msg  string
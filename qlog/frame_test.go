@@ -374,4 +374,31 @@ var _ = Describe("Frames", func() {
 			},
 		)
 	})
+
+	It("marshals ACK_FREQUENCY frames", func() {
+		check(
+			&logging.AckFrequencyFrame{
+				SequenceNumber:        42,
+				AckElicitingThreshold: 9,
+				RequestMaxAckDelay:    12 * time.Millisecond,
+				ReorderingThreshold:   1,
+			},
+			map[string]interface{}{
+				"frame_type":              "ack_frequency",
+				"sequence_number":         42,
+				"ack_eliciting_threshold": 9,
+				"request_max_ack_delay":   12,
+				"reordering_threshold":    1,
+			},
+		)
+	})
+
+	It("marshals IMMEDIATE_ACK frames", func() {
+		check(
+			&logging.ImmediateAckFrame{},
+			map[string]interface{}{
+				"frame_type": "immediate_ack",
+			},
+		)
+	})
 })
@@ -154,6 +154,29 @@ func (t *connectionTracer) StartedConnection(local, remote net.Addr, version pro
 	t.mutex.Unlock()
 }
 
+func (t *connectionTracer) NegotiatedVersion(chosen logging.VersionNumber, client, server []logging.VersionNumber) {
+	var clientVersions, serverVersions []versionNumber
+	if len(client) > 0 {
+		clientVersions = make([]versionNumber, len(client))
+		for i, v := range client {
+			clientVersions[i] = versionNumber(v)
+		}
+	}
+	if len(server) > 0 {
+		serverVersions = make([]versionNumber, len(server))
+		for i, v := range server {
+			serverVersions[i] = versionNumber(v)
+		}
+	}
+	t.mutex.Lock()
+	t.recordEvent(time.Now(), &eventVersionNegotiated{
+		ClientVersions: clientVersions,
+		ServerVersions: serverVersions,
+		ChosenVersion:  versionNumber(chosen),
+	})
+	t.mutex.Unlock()
+}
+
 func (t *connectionTracer) ClosedConnection(r logging.CloseReason) {
 	t.mutex.Lock()
 	t.recordEvent(time.Now(), &eventConnectionClosed{Reason: r})
@@ -197,6 +220,7 @@ func (t *connectionTracer) recordTransportParameters(sentBy protocol.Perspective
 		MaxUDPPayloadSize:               tp.MaxUDPPayloadSize,
 		AckDelayExponent:                tp.AckDelayExponent,
 		MaxAckDelay:                     tp.MaxAckDelay,
+		MinAckDelay:                     tp.MinAckDelay,
 		ActiveConnectionIDLimit:         tp.ActiveConnectionIDLimit,
 		InitialMaxData:                  tp.InitialMaxData,
 		InitialMaxStreamDataBidiLocal:   tp.InitialMaxStreamDataBidiLocal,
@@ -324,6 +348,12 @@ func (t *connectionTracer) UpdatedPTOCount(value uint32) {
 	t.mutex.Unlock()
 }
 
+func (t *connectionTracer) ECNStateUpdated(state logging.ECNState, trigger logging.ECNStateTrigger) {
+	t.mutex.Lock()
+	t.recordEvent(time.Now(), &eventECNStateUpdated{state: ecnState(state), trigger: ecnStateTrigger(trigger)})
+	t.mutex.Unlock()
+}
+
 func (t *connectionTracer) UpdatedKeyFromTLS(encLevel protocol.EncryptionLevel, pers protocol.Perspective) {
 	t.mutex.Lock()
 	t.recordEvent(time.Now(), &eventKeyUpdated{
@@ -215,6 +215,8 @@ func (e transportError) String() string {
 		return "aead_limit_reached"
 	case qerr.NoViablePathError:
 		return "no_viable_path"
+	case qerr.VersionNegotiationError:
+		return "version_negotiation_error"
 	default:
 		return ""
 	}
@@ -331,3 +333,43 @@ func (s congestionState) String() string {
 		return "unknown congestion state"
 	}
 }
+
+type ecnState logging.ECNState
+
+func (s ecnState) String() string {
+	switch logging.ECNState(s) {
+	case logging.ECNStateTesting:
+		return "testing"
+	case logging.ECNStateUnknown:
+		return "unknown"
+	case logging.ECNStateFailed:
+		return "failed"
+	case logging.ECNStateCapable:
+		return "capable"
+	default:
+		return "unknown ECN state"
+	}
+}
+
+type ecnStateTrigger logging.ECNStateTrigger
+
+func (t ecnStateTrigger) String() string {
+	switch logging.ECNStateTrigger(t) {
+	case logging.ECNTriggerNoTrigger:
+		return ""
+	case logging.ECNFailedNoECNCounts:
+		return "ACK doesn't contain ECN marks"
+	case logging.ECNFailedDecreasedECNCounts:
+		return "ACK decreases ECN counts"
+	case logging.ECNFailedLostAllTestingPackets:
+		return "all ECN testing packets declared lost"
+	case logging.ECNFailedMoreECNCountsThanSent:
+		return "ACK contains more ECN counts than ECN-marked packets sent"
+	case logging.ECNFailedTooFewECNCounts:
+		return "ACK contains fewer new ECN counts than acknowledged ECN-marked packets"
+	case logging.ECNFailedManglingDetected:
+		return "ECN mangling detected"
+	default:
+		return "unknown ECN state trigger"
+	}
+}
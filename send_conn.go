@@ -2,6 +2,8 @@ package quic
 
 import (
 	"net"
+
+	"github.com/For-ACGN/quic-go/internal/protocol"
 )
 
 // A sendConn allows sending using a simple Write() on a non-connected packet conn.
@@ -10,18 +12,42 @@ type sendConn interface {
 	Close() error
 	LocalAddr() net.Addr
 	RemoteAddr() net.Addr
+	// WithRemoteAddr returns a sendConn that uses the same packet conn to send to a different remote address.
+	WithRemoteAddr(net.Addr) sendConn
+}
+
+// A batchSendConn is a sendConn that can send multiple packets at once.
+type batchSendConn interface {
+	sendConn
+	// WriteBatch sends multiple packets, marked with the ECN codepoint ecn.
+	// All packets are segmentSize bytes large, except for the last one, which may be smaller.
+	WriteBatch(b []byte, segmentSize protocol.ByteCount, ecn protocol.ECN) error
+	// SupportsECN says if WriteBatch can set the ECN codepoint on outgoing packets.
+	SupportsECN() bool
+}
+
+// A batchWriter sends multiple packets to the same remote address, ideally using a single syscall.
+type batchWriter interface {
+	// WriteBatch sends the packets contained in b to addr, marked with the ECN codepoint ecn.
+	// All packets are segmentSize bytes large, except for the last one, which may be smaller.
+	WriteBatch(b []byte, segmentSize int, addr net.Addr, ecn protocol.ECN) error
 }
 
 type sconn struct {
 	net.PacketConn
 
-	remoteAddr net.Addr
+	remoteAddr  net.Addr
+	batchWriter batchWriter // nil if the platform doesn't support sending batches of packets
 }
 
-var _ sendConn = &sconn{}
+var _ batchSendConn = &sconn{}
 
 func newSendConn(c net.PacketConn, remote net.Addr) sendConn {
-	return &sconn{PacketConn: c, remoteAddr: remote}
+	return &sconn{
+		PacketConn:  c,
+		remoteAddr:  remote,
+		batchWriter: newBatchWriter(c),
+	}
 }
 
 func (c *sconn) Write(p []byte) error {
@@ -29,6 +55,37 @@ func (c *sconn) Write(p []byte) error {
 	return err
 }
 
+func (c *sconn) WriteBatch(b []byte, segmentSize protocol.ByteCount, ecn protocol.ECN) error {
+	if c.batchWriter != nil {
+		return c.batchWriter.WriteBatch(b, int(segmentSize), c.remoteAddr, ecn)
+	}
+	for len(b) > 0 {
+		n := int(segmentSize)
+		if n > len(b) {
+			n = len(b)
+		}
+		if err := c.Write(b[:n]); err != nil {
+			return err
+		}
+		b = b[n:]
+	}
+	return nil
+}
+
+// SupportsECN says if packets can be sent with ECN marks.
+// This is only possible if the platform supports sending batches of packets.
+func (c *sconn) SupportsECN() bool {
+	return c.batchWriter != nil
+}
+
 func (c *sconn) RemoteAddr() net.Addr {
 	return c.remoteAddr
 }
+
+func (c *sconn) WithRemoteAddr(remote net.Addr) sendConn {
+	return &sconn{
+		PacketConn:  c.PacketConn,
+		remoteAddr:  remote,
+		batchWriter: c.batchWriter,
+	}
+}
@@ -3,6 +3,10 @@ package quic
 import (
 	"net"
 
+	"github.com/For-ACGN/quic-go/internal/protocol"
+
+	"github.com/golang/mock/gomock"
+
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
@@ -25,6 +29,27 @@ var _ = Describe("Connection (for sending packets)", func() {
 		Expect(c.Write([]byte("foobar"))).To(Succeed())
 	})
 
+	It("writes batches of packets, if the packet conn can't send batches", func() {
+		gomock.InOrder(
+			packetConn.EXPECT().WriteTo([]byte("foo"), addr),
+			packetConn.EXPECT().WriteTo([]byte("bar"), addr),
+			packetConn.EXPECT().WriteTo([]byte("b"), addr),
+		)
+		Expect(c.(batchSendConn).WriteBatch([]byte("foobarb"), 3, protocol.ECNNon)).To(Succeed())
+	})
+
+	It("doesn't support ECN if the packet conn can't send batches", func() {
+		Expect(c.(batchSendConn).SupportsECN()).To(BeFalse())
+	})
+
+	It("uses the same packet conn when changing the remote address", func() {
+		remote := &net.UDPAddr{IP: net.IPv4(192, 168, 100, 201), Port: 1234}
+		c2 := c.WithRemoteAddr(remote)
+		Expect(c2.RemoteAddr()).To(Equal(remote))
+		packetConn.EXPECT().WriteTo([]byte("foobar"), remote)
+		Expect(c2.Write([]byte("foobar"))).To(Succeed())
+	})
+
 	It("gets the remote address", func() {
 		Expect(c.RemoteAddr().String()).To(Equal("192.168.100.200:1337"))
 	})
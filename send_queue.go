@@ -1,10 +1,22 @@
 package quic
 
+import "github.com/For-ACGN/quic-go/internal/protocol"
+
+type queueEntry struct {
+	buf *packetBuffer
+	// segmentSize is the size of the packets, if buf contains a batch of packets.
+	// It is 0 if buf contains a single (potentially coalesced) packet.
+	segmentSize protocol.ByteCount
+	ecn         protocol.ECN
+	isMTUProbe  bool
+}
+
 type sendQueue struct {
-	queue       chan *packetBuffer
+	queue       chan queueEntry
 	closeCalled chan struct{} // runStopped when Close() is called
 	runStopped  chan struct{} // runStopped when the run loop returns
 	conn        sendConn
+	batchConn   batchSendConn // nil if the conn can't send batches of packets
 }
 
 func newSendQueue(conn sendConn) *sendQueue {
@@ -12,14 +24,46 @@ func newSendQueue(conn sendConn) *sendQueue {
 		conn:        conn,
 		runStopped:  make(chan struct{}),
 		closeCalled: make(chan struct{}),
-		queue:       make(chan *packetBuffer, 1),
+		queue:       make(chan queueEntry, 1),
+	}
+	if c, ok := conn.(batchSendConn); ok {
+		s.batchConn = c
 	}
 	return s
 }
 
 func (h *sendQueue) Send(p *packetBuffer) {
+	h.send(queueEntry{buf: p})
+}
+
+// SendMTUProbe sends a path MTU probe packet.
+// Probe packets might be larger than the MTU of the local interface.
+// The kernel then refuses to send them, and the probe will be declared lost.
+func (h *sendQueue) SendMTUProbe(p *packetBuffer) {
+	h.send(queueEntry{buf: p, isMTUProbe: true})
+}
+
+// SendBatch sends multiple packets contained in a single buffer, marked with the ECN codepoint ecn.
+// All packets are segmentSize bytes large, except for the last one, which may be smaller.
+// It must only be called if SupportsBatching returns true.
+func (h *sendQueue) SendBatch(p *packetBuffer, segmentSize protocol.ByteCount, ecn protocol.ECN) {
+	h.send(queueEntry{buf: p, segmentSize: segmentSize, ecn: ecn})
+}
+
+// SupportsBatching says if the underlying conn can send batches of packets.
+func (h *sendQueue) SupportsBatching() bool {
+	return h.batchConn != nil
+}
+
+// SupportsECN says if the underlying conn can send packets with ECN marks.
+// ECN marks can only be set on packets sent using SendBatch.
+func (h *sendQueue) SupportsECN() bool {
+	return h.batchConn != nil && h.batchConn.SupportsECN()
+}
+
+func (h *sendQueue) send(e queueEntry) {
 	select {
-	case h.queue <- p:
+	case h.queue <- e:
 	case <-h.runStopped:
 	}
 }
@@ -36,11 +80,17 @@ func (h *sendQueue) Run() error {
 			h.closeCalled = nil // prevent this case from being selected again
 			// make sure that all queued packets are actually sent out
 			shouldClose = true
-		case p := <-h.queue:
-			if err := h.conn.Write(p.Data); err != nil {
+		case e := <-h.queue:
+			var err error
+			if e.segmentSize > 0 {
+				err = h.batchConn.WriteBatch(e.buf.Data, e.segmentSize, e.ecn)
+			} else {
+				err = h.conn.Write(e.buf.Data)
+			}
+			if err != nil && !(e.isMTUProbe && isMsgSizeErr(err)) {
 				return err
 			}
-			p.Release()
+			e.buf.Release()
 		}
 	}
 }
@@ -2,6 +2,11 @@ package quic
 
 import (
 	"errors"
+	"net"
+	"os"
+	"syscall"
+
+	"github.com/For-ACGN/quic-go/internal/protocol"
 
 	"github.com/golang/mock/gomock"
 	. "github.com/onsi/ginkgo"
@@ -120,4 +125,78 @@ var _ = Describe("Send Queue", func() {
 		Eventually(done).Should(BeClosed())
 		Eventually(closed).Should(BeClosed())
 	})
+
+	Context("message size errors", func() {
+		msgSizeErr := &net.OpError{Op: "write", Err: os.NewSyscallError("sendto", syscall.EMSGSIZE)}
+
+		BeforeEach(func() {
+			if !isMsgSizeErr(msgSizeErr) {
+				Skip("message size errors are not detected on this platform")
+			}
+		})
+
+		It("ignores message size errors for path MTU probe packets", func() {
+			q.SendMTUProbe(getPacket([]byte("foobar")))
+			written := make(chan struct{})
+			c.EXPECT().Write([]byte("foobar")).Do(func([]byte) { close(written) }).Return(msgSizeErr)
+			errChan := make(chan error, 1)
+			go func() {
+				defer GinkgoRecover()
+				errChan <- q.Run()
+			}()
+
+			Eventually(written).Should(BeClosed())
+			Consistently(errChan).ShouldNot(Receive())
+			q.Close()
+			Eventually(errChan).Should(Receive(BeNil()))
+		})
+
+		It("returns message size errors for other packets", func() {
+			q.Send(getPacket([]byte("foobar")))
+			c.EXPECT().Write([]byte("foobar")).Return(msgSizeErr)
+			errChan := make(chan error, 1)
+			go func() {
+				defer GinkgoRecover()
+				errChan <- q.Run()
+			}()
+
+			Eventually(errChan).Should(Receive(MatchError(msgSizeErr)))
+		})
+	})
+
+	It("doesn't support batching if the conn can't send batches", func() {
+		Expect(q.SupportsBatching()).To(BeFalse())
+		Expect(q.SupportsECN()).To(BeFalse())
+	})
+
+	It("says if the conn supports ECN", func() {
+		bc := NewMockBatchSendConn(mockCtrl)
+		q = newSendQueue(bc)
+		bc.EXPECT().SupportsECN().Return(true)
+		Expect(q.SupportsECN()).To(BeTrue())
+		bc.EXPECT().SupportsECN().Return(false)
+		Expect(q.SupportsECN()).To(BeFalse())
+	})
+
+	It("sends batches of packets", func() {
+		bc := NewMockBatchSendConn(mockCtrl)
+		q = newSendQueue(bc)
+		Expect(q.SupportsBatching()).To(BeTrue())
+
+		buf := getLargePacketBuffer()
+		buf.Data = append(buf.Data, []byte("foobarfoo")...)
+		q.SendBatch(buf, 6, protocol.ECT0)
+		written := make(chan struct{})
+		bc.EXPECT().WriteBatch([]byte("foobarfoo"), protocol.ByteCount(6), protocol.ECT0).Do(func([]byte, protocol.ByteCount, protocol.ECN) { close(written) })
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			q.Run()
+			close(done)
+		}()
+
+		Eventually(written).Should(BeClosed())
+		q.Close()
+		Eventually(done).Should(BeClosed())
+	})
 })
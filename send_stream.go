@@ -284,7 +284,7 @@ func (s *sendStream) popNewStreamFrame(maxBytes, sendWindow protocol.ByteCount)
 		return nextFrame, s.nextFrame != nil || s.dataForWriting != nil
 	}
 
-	f := wire.GetStreamFrame()
+	f := wire.GetStreamFrameForSize(utils.MinByteCount(maxBytes, protocol.ByteCount(len(s.dataForWriting))))
 	f.Fin = false
 	f.StreamID = s.streamID
 	f.Offset = s.writeOffset
@@ -458,6 +458,19 @@ func (s *sendStream) SetWriteDeadline(t time.Time) error {
 	return nil
 }
 
+func (s *sendStream) SetPriority(prio StreamPriority) {
+	if prio.Urgency > protocol.MaxStreamUrgency {
+		prio.Urgency = protocol.MaxStreamUrgency
+	}
+	s.mutex.Lock()
+	completed := s.completed
+	s.mutex.Unlock()
+	if completed {
+		return
+	}
+	s.sender.onStreamPriorityChanged(s.streamID, prio) // must be called without holding the mutex
+}
+
 // CloseForShutdown closes a stream abruptly.
 // It makes Write unblock (and return the error) immediately.
 // The peer will NOT be informed about this: the stream is closed without sending a FIN or RST.
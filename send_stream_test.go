@@ -71,6 +71,26 @@ var _ = Describe("Send Stream", func() {
 		Expect(str.StreamID()).To(Equal(protocol.StreamID(1337)))
 	})
 
+	Context("priorities", func() {
+		It("reports the priority", func() {
+			prio := protocol.StreamPriority{Urgency: 1, Incremental: true}
+			mockSender.EXPECT().onStreamPriorityChanged(streamID, prio)
+			str.SetPriority(prio)
+		})
+
+		It("caps the urgency", func() {
+			mockSender.EXPECT().onStreamPriorityChanged(streamID, protocol.StreamPriority{Urgency: protocol.MaxStreamUrgency})
+			str.SetPriority(protocol.StreamPriority{Urgency: 42})
+		})
+
+		It("doesn't report the priority after the stream was completed", func() {
+			str.mutex.Lock()
+			str.completed = true
+			str.mutex.Unlock()
+			str.SetPriority(protocol.StreamPriority{Urgency: 1})
+		})
+	})
+
 	Context("writing", func() {
 		It("writes and gets all data at once", func() {
 			done := make(chan struct{})
@@ -152,6 +152,7 @@ func listenAddr(addr string, tlsConf *tls.Config, config *Config, acceptEarly bo
 // Listen listens for QUIC connections on a given net.PacketConn.
 // If the PacketConn satisfies the ECNCapablePacketConn interface (as a net.UDPConn does), ECN support will be enabled.
 // In this case, ReadMsgUDP will be used instead of ReadFrom to read packets.
+// Outgoing packets are only marked with ECT(0) on Linux, when using a net.UDPConn.
 // A single net.PacketConn only be used for a single call to Listen.
 // The PacketConn can be used for simultaneous calls to Dial.
 // QUIC connection IDs are used for demultiplexing the different connections.
@@ -186,12 +187,27 @@ func listen(conn net.PacketConn, tlsConf *tls.Config, config *Config, acceptEarl
 		}
 	}
 
-	sessionHandler, err := getMultiplexer().AddConn(conn, config.ConnectionIDLength, config.StatelessResetKey, config.Tracer)
+	sessionHandler, err := getMultiplexer().AddConn(conn, config.ConnectionIDLength, protocol.ByteCount(config.MaxPacketSize), config.StatelessResetKey, config.Tracer)
 	if err != nil {
 		return nil, err
 	}
+	var preferredAddrHandler *preferredAddressSessionHandler
+	if config.PreferredAddressIPv4 != nil || config.PreferredAddressIPv6 != nil {
+		conns, err := listenPreferredAddresses(config)
+		if err != nil {
+			return nil, err
+		}
+		preferredAddrHandler, err = newPreferredAddressSessionHandler(sessionHandler, conns, config.ConnectionIDLength, protocol.ByteCount(config.MaxPacketSize), config.StatelessResetKey, config.Tracer)
+		if err != nil {
+			return nil, err
+		}
+		sessionHandler = preferredAddrHandler
+	}
 	tokenGenerator, err := handshake.NewTokenGenerator(rand.Reader)
 	if err != nil {
+		if preferredAddrHandler != nil {
+			preferredAddrHandler.destroyPreferredAddresses()
+		}
 		return nil, err
 	}
 	s := &baseServer{
@@ -579,7 +595,7 @@ func (s *baseServer) sendRetry(remoteAddr net.Addr, hdr *wire.Header) error {
 		return err
 	}
 	// append the Retry integrity tag
-	tag := handshake.GetRetryIntegrityTag(buf.Bytes(), hdr.DestConnectionID)
+	tag := handshake.GetRetryIntegrityTag(buf.Bytes(), hdr.DestConnectionID, hdr.Version)
 	buf.Write(tag[:])
 	if s.config.Tracer != nil {
 		s.config.Tracer.SentPacket(remoteAddr, &replyHdr.Header, protocol.ByteCount(buf.Len()), nil)
@@ -591,7 +607,7 @@ func (s *baseServer) sendRetry(remoteAddr net.Addr, hdr *wire.Header) error {
 func (s *baseServer) maybeSendInvalidToken(p *receivedPacket, hdr *wire.Header) error {
 	// Only send INVALID_TOKEN if we can unprotect the packet.
 	// This makes sure that we won't send it for packets that were corrupted.
-	sealer, opener := handshake.NewInitialAEAD(hdr.DestConnectionID, protocol.PerspectiveServer)
+	sealer, opener := handshake.NewInitialAEAD(hdr.DestConnectionID, protocol.PerspectiveServer, hdr.Version)
 	data := p.data[:hdr.ParsedLen()+hdr.Length]
 	extHdr, err := unpackHeader(opener, hdr, data, hdr.Version)
 	if err != nil {
@@ -616,7 +632,7 @@ func (s *baseServer) maybeSendInvalidToken(p *receivedPacket, hdr *wire.Header)
 }
 
 func (s *baseServer) sendConnectionRefused(remoteAddr net.Addr, hdr *wire.Header) error {
-	sealer, _ := handshake.NewInitialAEAD(hdr.DestConnectionID, protocol.PerspectiveServer)
+	sealer, _ := handshake.NewInitialAEAD(hdr.DestConnectionID, protocol.PerspectiveServer, hdr.Version)
 	return s.sendError(remoteAddr, hdr, sealer, qerr.ConnectionRefused)
 }
 
@@ -25,6 +25,7 @@ import (
 
 type unpacker interface {
 	Unpack(hdr *wire.Header, rcvTime time.Time, data []byte) (*unpackedPacket, error)
+	SetVersion(protocol.VersionNumber)
 }
 
 type streamGetter interface {
@@ -50,6 +51,7 @@ type streamManager interface {
 type cryptoStreamHandler interface {
 	RunHandshake()
 	ChangeConnectionID(protocol.ConnectionID)
+	ChangeVersion(protocol.VersionNumber)
 	SetLargest1RTTAcked(protocol.PacketNumber) error
 	SetHandshakeConfirmed()
 	GetSessionTicket() ([]byte, error)
@@ -65,6 +67,10 @@ type receivedPacket struct {
 	data       []byte
 
 	ecn protocol.ECN
+
+	// conn is the packet conn that the packet was received on.
+	// It is only set if the packet was received on one of the server's preferred addresses.
+	conn net.PacketConn
 }
 
 func (p *receivedPacket) Size() protocol.ByteCount { return protocol.ByteCount(len(p.data)) }
@@ -76,6 +82,7 @@ func (p *receivedPacket) Clone() *receivedPacket {
 		data:       p.data,
 		buffer:     p.buffer,
 		ecn:        p.ecn,
+		conn:       p.conn,
 	}
 }
 
@@ -107,6 +114,8 @@ type closeError struct {
 	immediate bool
 }
 
+var errSessionClosed = errors.New("session closed")
+
 type errCloseForRecreating struct {
 	nextPacketNumber protocol.PacketNumber
 	nextVersion      protocol.VersionNumber
@@ -136,14 +145,30 @@ type session struct {
 	version     protocol.VersionNumber
 	config      *Config
 
-	conn      sendConn
+	conn sendConn
+	// connMutex protects conn, which is replaced by the run loop when the connection is migrated.
+	// The run loop itself doesn't need to acquire the mutex for reading conn.
+	connMutex sync.Mutex
 	sendQueue *sendQueue
+	// The conn that the server session was created with.
+	// Packets received on one of the server's preferred addresses are sent via a different packet conn.
+	initialConn sendConn
+
+	// The path that is currently being validated.
+	// For the client, this is the path that we're migrating to.
+	// For the server, this is the path that the client is (potentially) migrating to.
+	probingPath   *path
+	migrationChan chan *pathMigration // only used by the client
+	// Set when the handshake is confirmed, if the server sent a preferred_address.
+	migrateToPreferredAddress bool
 
 	streamsMap      streamManager
 	connIDManager   *connIDManager
 	connIDGenerator *connIDGenerator
 
 	rttStats *utils.RTTStats
+	// statsRequests is used to request a snapshot of the ConnectionStats from the run loop.
+	statsRequests chan chan ConnectionStats
 
 	cryptoStreamManager   *cryptoStreamManager
 	sentPacketHandler     ackhandler.SentPacketHandler
@@ -158,6 +183,8 @@ type session struct {
 	unpacker    unpacker
 	frameParser wire.FrameParser
 	packer      packer
+	// Only set once the handshake completes, unless path MTU discovery is disabled.
+	mtuDiscoverer mtuDiscoverer
 
 	oneRTTStream        cryptoStream // only set for the server
 	cryptoStreamHandler cryptoStreamHandler
@@ -184,6 +211,7 @@ type session struct {
 
 	receivedRetry       bool
 	versionNegotiated   bool
+	originalVersion     protocol.VersionNumber // set when switching to a compatible version
 	receivedFirstPacket bool
 
 	idleTimeout         time.Duration
@@ -235,6 +263,7 @@ var newSession = func(
 ) quicSession {
 	s := &session{
 		conn:                  conn,
+		initialConn:           conn,
 		config:                conf,
 		handshakeDestConnID:   destConnID,
 		srcConnIDLen:          srcConnID.Len(),
@@ -273,12 +302,15 @@ var newSession = func(
 		0,
 		s.rttStats,
 		s.perspective,
+		s.config.CongestionControl,
+		s.config.NewCongestionControl,
 		s.tracer,
 		s.logger,
 		s.version,
 	)
 	initialStream := newCryptoStream()
 	handshakeStream := newCryptoStream()
+	minAckDelay := protocol.MinAckDelay
 	params := &wire.TransportParameters{
 		InitialMaxStreamDataBidiLocal:   protocol.InitialMaxStreamData,
 		InitialMaxStreamDataBidiRemote:  protocol.InitialMaxStreamData,
@@ -288,17 +320,30 @@ var newSession = func(
 		MaxBidiStreamNum:                protocol.StreamNum(s.config.MaxIncomingStreams),
 		MaxUniStreamNum:                 protocol.StreamNum(s.config.MaxIncomingUniStreams),
 		MaxAckDelay:                     protocol.MaxAckDelayInclGranularity,
+		MinAckDelay:                     &minAckDelay,
 		AckDelayExponent:                protocol.AckDelayExponent,
-		DisableActiveMigration:          true,
 		StatelessResetToken:             &statelessResetToken,
 		OriginalDestinationConnectionID: origDestConnID,
 		ActiveConnectionIDLimit:         protocol.MaxActiveConnectionIDs,
+		MaxUDPPayloadSize:               protocol.ByteCount(s.config.MaxPacketSize),
 		InitialSourceConnectionID:       srcConnID,
 		RetrySourceConnectionID:         retrySrcConnID,
+		VersionInformation: &wire.VersionInformation{
+			ChosenVersion:     s.version,
+			AvailableVersions: s.config.Versions,
+		},
 	}
 	if s.config.EnableDatagrams {
 		params.MaxDatagramFrameSize = protocol.MaxDatagramFrameSize
 	}
+	if s.config.PreferredAddressIPv4 != nil || s.config.PreferredAddressIPv6 != nil {
+		connID, token, err := s.connIDGenerator.IssuePreferredAddressConnID()
+		if err != nil {
+			s.closeLocal(err)
+		} else {
+			params.PreferredAddress = newPreferredAddress(s.config, connID, token)
+		}
+	}
 	if s.tracer != nil {
 		s.tracer.SentTransportParameters(params)
 	}
@@ -397,12 +442,15 @@ var newClientSession = func(
 		initialPacketNumber,
 		s.rttStats,
 		s.perspective,
+		s.config.CongestionControl,
+		s.config.NewCongestionControl,
 		s.tracer,
 		s.logger,
 		s.version,
 	)
 	initialStream := newCryptoStream()
 	handshakeStream := newCryptoStream()
+	minAckDelay := protocol.MinAckDelay
 	params := &wire.TransportParameters{
 		InitialMaxStreamDataBidiRemote: protocol.InitialMaxStreamData,
 		InitialMaxStreamDataBidiLocal:  protocol.InitialMaxStreamData,
@@ -412,10 +460,16 @@ var newClientSession = func(
 		MaxBidiStreamNum:               protocol.StreamNum(s.config.MaxIncomingStreams),
 		MaxUniStreamNum:                protocol.StreamNum(s.config.MaxIncomingUniStreams),
 		MaxAckDelay:                    protocol.MaxAckDelayInclGranularity,
+		MinAckDelay:                    &minAckDelay,
 		AckDelayExponent:               protocol.AckDelayExponent,
 		DisableActiveMigration:         true,
 		ActiveConnectionIDLimit:        protocol.MaxActiveConnectionIDs,
+		MaxUDPPayloadSize:              protocol.ByteCount(s.config.MaxPacketSize),
 		InitialSourceConnectionID:      srcConnID,
+		VersionInformation: &wire.VersionInformation{
+			ChosenVersion:     s.version,
+			AvailableVersions: compatibleVersions(s.config.Versions, s.version),
+		},
 	}
 	if s.config.EnableDatagrams {
 		params.MaxDatagramFrameSize = protocol.MaxDatagramFrameSize
@@ -496,10 +550,12 @@ func (s *session) preSetup() {
 		s.perspective,
 		s.version,
 	)
-	s.framer = newFramer(s.streamsMap, s.version)
+	s.framer = newFramer(s.streamsMap, s.config.StreamScheduling, s.version)
 	s.receivedPackets = make(chan *receivedPacket, protocol.MaxSessionUnprocessedPackets)
 	s.closeChan = make(chan closeError, 1)
 	s.sendingScheduled = make(chan struct{}, 1)
+	s.migrationChan = make(chan *pathMigration)
+	s.statsRequests = make(chan chan ConnectionStats)
 	s.ctx, s.ctxCancel = context.WithCancel(context.Background())
 	s.handshakeCtx, s.handshakeCtxCancel = context.WithCancel(context.Background())
 
@@ -520,11 +576,7 @@ func (s *session) run() error {
 	s.timer = utils.NewTimer()
 
 	go s.cryptoStreamHandler.RunHandshake()
-	go func() {
-		if err := s.sendQueue.Run(); err != nil {
-			s.destroyImpl(err)
-		}
-	}()
+	s.runSendQueue(s.sendQueue)
 
 	if s.perspective == protocol.PerspectiveClient {
 		select {
@@ -580,6 +632,11 @@ runLoop:
 			}
 		case <-s.handshakeCompleteChan:
 			s.handleHandshakeComplete()
+		case m := <-s.migrationChan:
+			s.startMigration(m)
+		case c := <-s.statsRequests:
+			c <- s.getConnectionStats()
+			continue
 		}
 
 		now := time.Now()
@@ -589,6 +646,10 @@ runLoop:
 			if err := s.sentPacketHandler.OnLossDetectionTimeout(); err != nil {
 				s.closeLocal(err)
 			}
+			if s.mtuDiscoverer != nil && s.sentPacketHandler.DetectedPathMTUBlackHole() {
+				s.logger.Debugf("Detected a path MTU black hole. Falling back to the initial packet size.")
+				s.mtuDiscoverer.DetectedBlackHole()
+			}
 		}
 
 		if keepAliveTime := s.nextKeepAliveTime(); !keepAliveTime.IsZero() && !now.Before(keepAliveTime) {
@@ -614,11 +675,17 @@ runLoop:
 			}
 		}
 
+		s.maybeMigrateToPreferredAddress()
+		s.maybeProbePath(now)
 		if err := s.sendPackets(); err != nil {
 			s.closeLocal(err)
 		}
 	}
 
+	// Remove the connection IDs registered with the packet conn of an ongoing migration.
+	if s.probingPath != nil && s.probingPath.migration != nil {
+		s.abandonPath(closeErr.err)
+	}
 	s.handleCloseError(closeErr)
 	if !errors.Is(closeErr.err, errCloseForRecreating{}) && s.tracer != nil {
 		s.tracer.Close()
@@ -654,6 +721,49 @@ func (s *session) ConnectionState() ConnectionState {
 	}
 }
 
+func (s *session) ConnectionStats() ConnectionStats {
+	c := make(chan ConnectionStats, 1)
+	select {
+	case s.statsRequests <- c:
+		return <-c
+	case <-s.ctx.Done():
+		// The run loop has returned, so the state can't change any more.
+		return s.getConnectionStats()
+	}
+}
+
+// getConnectionStats must only be called from the run loop, or after the run loop returned.
+func (s *session) getConnectionStats() ConnectionStats {
+	stats := s.sentPacketHandler.GetStats()
+	mtu := stats.MaxDatagramSize
+	if mtu == 0 {
+		mtu = getMaxPacketSize(s.conn.RemoteAddr())
+	}
+	return ConnectionStats{
+		MinRTT:               s.rttStats.MinRTT(),
+		LatestRTT:            s.rttStats.LatestRTT(),
+		SmoothedRTT:          s.rttStats.SmoothedRTT(),
+		MeanDeviation:        s.rttStats.MeanDeviation(),
+		CongestionWindow:     uint64(stats.CongestionWindow),
+		BytesInFlight:        uint64(stats.BytesInFlight),
+		MTU:                  uint64(mtu),
+		SlowStartCount:       stats.SlowStart.Count,
+		SlowStartRTTs:        stats.SlowStart.RTTs,
+		SlowStartPacketsLost: stats.SlowStart.PacketsLost,
+		SlowStartBytesLost:   uint64(stats.SlowStart.BytesLost),
+		SlowStartDuration:    stats.SlowStart.Duration,
+		BytesSent:            uint64(stats.BytesSent),
+		BytesReceived:        uint64(stats.BytesReceived),
+		PacketsSent:          stats.PacketsSent,
+		PacketsReceived:      stats.PacketsReceived,
+		PacketsLost:          stats.PacketsLost,
+		PacketsRetransmitted: stats.PacketsRetransmitted,
+		PTOCount:             stats.PTOCount,
+		SendWindow:           uint64(s.connFlowController.SendWindowSize()),
+		ReceiveWindow:        uint64(s.connFlowController.ReceiveWindowSize()),
+	}
+}
+
 // Time when the next keep-alive packet should be sent.
 // It returns a zero time if no keep-alive should be sent.
 func (s *session) nextKeepAliveTime() time.Time {
@@ -684,6 +794,16 @@ func (s *session) maybeResetTimer() {
 	if !s.pacingDeadline.IsZero() {
 		deadline = utils.MinTime(deadline, s.pacingDeadline)
 	}
+	if s.probingPath != nil {
+		if pathTimeout := s.probingPath.Timeout(); !pathTimeout.IsZero() {
+			deadline = utils.MinTime(deadline, pathTimeout)
+		}
+	}
+	if s.mtuDiscoverer != nil {
+		if probeTime := s.mtuDiscoverer.NextProbeTime(); !probeTime.IsZero() {
+			deadline = utils.MinTime(deadline, probeTime)
+		}
+	}
 
 	s.timer.Reset(deadline)
 }
@@ -703,6 +823,10 @@ func (s *session) handleHandshakeComplete() {
 	s.connIDManager.SetHandshakeComplete()
 	s.connIDGenerator.SetHandshakeComplete()
 
+	if s.config.EnablePathMTUDiscovery && s.peerParams != nil {
+		s.startPathMTUDiscovery()
+	}
+
 	if s.perspective == protocol.PerspectiveServer {
 		s.handshakeConfirmed = true
 		s.sentPacketHandler.SetHandshakeConfirmed()
@@ -726,6 +850,21 @@ func (s *session) handleHandshakeComplete() {
 	}
 }
 
+// startPathMTUDiscovery searches for the largest packet size that can be sent on the current path.
+// Packets are never larger than the peer's max_udp_payload_size, or than the configured maximum packet size.
+func (s *session) startPathMTUDiscovery() {
+	s.mtuDiscoverer = newMTUDiscoverer(
+		s.rttStats,
+		getMaxPacketSize(s.conn.RemoteAddr()),
+		utils.MinByteCount(s.peerParams.MaxUDPPayloadSize, protocol.ByteCount(s.config.MaxPacketSize)),
+		func(size protocol.ByteCount) {
+			s.logger.Debugf("Setting the maximum packet size to %d bytes.", size)
+			s.sentPacketHandler.SetMaxDatagramSize(size)
+			s.packer.SetMaxPacketSize(size)
+		},
+	)
+}
+
 func (s *session) handlePacketImpl(rp *receivedPacket) bool {
 	if wire.IsVersionNegotiationPacket(rp.data) {
 		s.handleVersionNegotiationPacket(rp)
@@ -757,7 +896,7 @@ func (s *session) handlePacketImpl(rp *receivedPacket) bool {
 			break
 		}
 
-		if hdr.IsLongHeader && hdr.Version != s.version {
+		if hdr.IsLongHeader && hdr.Version != s.version && !s.isOriginalVersionInitial(hdr) && !s.maybeSwitchVersion(hdr) {
 			if s.tracer != nil {
 				s.tracer.DroppedPacket(logging.PacketTypeFromHeader(hdr), protocol.ByteCount(len(data)), logging.PacketDropUnexpectedVersion)
 			}
@@ -875,7 +1014,11 @@ func (s *session) handleSinglePacket(p *receivedPacket, hdr *wire.Header) bool /
 		return false
 	}
 
-	if err := s.handleUnpackedPacket(packet, p.ecn, p.rcvTime, p.Size()); err != nil {
+	pth := s.getPath(p, packet)
+	if pth != nil {
+		pth.ReceivedBytes(p.Size())
+	}
+	if err := s.handleUnpackedPacket(packet, pth, p.ecn, p.rcvTime, p.Size()); err != nil {
 		s.closeLocal(err)
 		return false
 	}
@@ -913,7 +1056,7 @@ func (s *session) handleRetryPacket(hdr *wire.Header, data []byte) bool /* was t
 		return false
 	}
 
-	tag := handshake.GetRetryIntegrityTag(data[:len(data)-16], destConnID)
+	tag := handshake.GetRetryIntegrityTag(data[:len(data)-16], destConnID, hdr.Version)
 	if !bytes.Equal(data[len(data)-16:], tag[:]) {
 		if s.tracer != nil {
 			s.tracer.DroppedPacket(logging.PacketTypeRetry, protocol.ByteCount(len(data)), logging.PacketDropPayloadDecryptError)
@@ -994,8 +1137,42 @@ func (s *session) handleVersionNegotiationPacket(p *receivedPacket) {
 	})
 }
 
+// maybeSwitchVersion is called by the client when it receives a long header packet with an unexpected version.
+// The server performs compatible version negotiation (RFC 9368) by responding with an Initial packet
+// using a version that's compatible with the version the client used.
+func (s *session) maybeSwitchVersion(hdr *wire.Header) bool /* switched */ {
+	if s.perspective == protocol.PerspectiveServer || s.receivedFirstPacket || hdr.Type != protocol.PacketTypeInitial {
+		return false
+	}
+	if !protocol.AreCompatibleVersions(s.version, hdr.Version) || !protocol.IsSupportedVersion(s.config.Versions, hdr.Version) {
+		return false
+	}
+	s.logger.Infof("Server performed compatible version negotiation. Switching to QUIC version %s.", hdr.Version)
+	s.changeVersion(hdr.Version)
+	return true
+}
+
+// isOriginalVersionInitial is called by the server when it receives a long header packet with an unexpected version.
+// After switching to a compatible version, the client might still send Initial packets using the original version,
+// e.g. when retransmitting the ClientHello. The server must accept these packets (RFC 9368).
+func (s *session) isOriginalVersionInitial(hdr *wire.Header) bool {
+	return s.perspective == protocol.PerspectiveServer && s.originalVersion != 0 &&
+		hdr.Version == s.originalVersion && hdr.Type == protocol.PacketTypeInitial
+}
+
+func (s *session) changeVersion(v protocol.VersionNumber) {
+	if s.originalVersion == 0 {
+		s.originalVersion = s.version
+	}
+	s.version = v
+	s.cryptoStreamHandler.ChangeVersion(v)
+	s.packer.SetVersion(v)
+	s.unpacker.SetVersion(v)
+}
+
 func (s *session) handleUnpackedPacket(
 	packet *unpackedPacket,
+	pth *path, // nil if the packet was received on the current path
 	ecn protocol.ECN,
 	rcvTime time.Time,
 	packetSize protocol.ByteCount, // only for logging
@@ -1043,7 +1220,7 @@ func (s *session) handleUnpackedPacket(
 	// If we're not tracing, this slice will always remain empty.
 	var frames []wire.Frame
 	r := bytes.NewReader(packet.data)
-	var isAckEliciting bool
+	var isAckEliciting, isNonProbing bool
 	for {
 		frame, err := s.frameParser.ParseNext(r, packet.encryptionLevel)
 		if err != nil {
@@ -1055,10 +1232,13 @@ func (s *session) handleUnpackedPacket(
 		if ackhandler.IsFrameAckEliciting(frame) {
 			isAckEliciting = true
 		}
+		if !isProbingFrame(frame) {
+			isNonProbing = true
+		}
 		// Only process frames now if we're not logging.
 		// If we're logging, we need to make sure that the packet_received event is logged first.
 		if s.tracer == nil {
-			if err := s.handleFrame(frame, packet.encryptionLevel, packet.hdr.DestConnectionID); err != nil {
+			if err := s.handleFrameOnPath(frame, pth, packet.encryptionLevel, packet.hdr.DestConnectionID); err != nil {
 				return err
 			}
 		} else {
@@ -1073,12 +1253,18 @@ func (s *session) handleUnpackedPacket(
 		}
 		s.tracer.ReceivedPacket(packet.hdr, packetSize, fs)
 		for _, frame := range frames {
-			if err := s.handleFrame(frame, packet.encryptionLevel, packet.hdr.DestConnectionID); err != nil {
+			if err := s.handleFrameOnPath(frame, pth, packet.encryptionLevel, packet.hdr.DestConnectionID); err != nil {
 				return err
 			}
 		}
 	}
 
+	// The client sent a non-probing packet on a new path.
+	// We only switch to the new path once it is validated.
+	if pth != nil && isNonProbing {
+		pth.StartValidation(s.pathValidationTimeout(), rcvTime)
+	}
+
 	return s.receivedPacketHandler.ReceivedPacket(packet.packetNumber, ecn, packet.encryptionLevel, rcvTime, isAckEliciting)
 }
 
@@ -1111,8 +1297,7 @@ func (s *session) handleFrame(f wire.Frame, encLevel protocol.EncryptionLevel, d
 	case *wire.PathChallengeFrame:
 		s.handlePathChallengeFrame(frame)
 	case *wire.PathResponseFrame:
-		// since we don't send PATH_CHALLENGEs, we don't expect PATH_RESPONSEs
-		err = errors.New("unexpected PATH_RESPONSE frame")
+		s.handlePathResponseFrame(frame)
 	case *wire.NewTokenFrame:
 		err = s.handleNewTokenFrame(frame)
 	case *wire.NewConnectionIDFrame:
@@ -1123,6 +1308,10 @@ func (s *session) handleFrame(f wire.Frame, encLevel protocol.EncryptionLevel, d
 		err = s.handleHandshakeDoneFrame()
 	case *wire.DatagramFrame:
 		err = s.handleDatagramFrame(frame)
+	case *wire.AckFrequencyFrame:
+		err = s.handleAckFrequencyFrame(frame)
+	case *wire.ImmediateAckFrame:
+		s.receivedPacketHandler.ReceivedImmediateAck()
 	default:
 		err = fmt.Errorf("unexpected frame type: %s", reflect.ValueOf(&frame).Elem().Type().Name())
 	}
@@ -1226,6 +1415,185 @@ func (s *session) handlePathChallengeFrame(frame *wire.PathChallengeFrame) {
 	s.queueControlFrame(&wire.PathResponseFrame{Data: frame.Data})
 }
 
+// handleFrameOnPath handles a frame that was received on pth.
+// pth is nil if the frame was received on the current path.
+func (s *session) handleFrameOnPath(f wire.Frame, pth *path, encLevel protocol.EncryptionLevel, destConnID protocol.ConnectionID) error {
+	// PATH_CHALLENGE frames are answered on the path they were received on.
+	if frame, ok := f.(*wire.PathChallengeFrame); ok && pth != nil {
+		wire.LogFrame(s.logger, frame, false)
+		pth.QueuePathResponse(frame)
+		return nil
+	}
+	return s.handleFrame(f, encLevel, destConnID)
+}
+
+func (s *session) handlePathResponseFrame(frame *wire.PathResponseFrame) {
+	// PATH_RESPONSE frames that don't belong to the path that we're currently validating are ignored.
+	// They might have been sent in response to a PATH_CHALLENGE sent on an abandoned path.
+	if s.probingPath == nil || !s.probingPath.HandlePathResponse(frame) {
+		return
+	}
+	s.migrateToPath(s.probingPath)
+}
+
+// getPath returns the path that a packet was received on.
+// It returns nil if the packet was received on the current path.
+// Only the server follows the client to a new path.
+// A path is identified by the client's address and the local packet conn,
+// since the client might use the same address when migrating to the server's preferred address.
+// The client can't observe the server's address change, since the server doesn't migrate.
+func (s *session) getPath(p *receivedPacket, packet *unpackedPacket) *path {
+	if s.perspective == protocol.PerspectiveClient || !s.handshakeConfirmed || packet.encryptionLevel != protocol.Encryption1RTT ||
+		p.remoteAddr == nil {
+		return nil
+	}
+	localAddr := s.initialConn.LocalAddr()
+	if p.conn != nil {
+		localAddr = p.conn.LocalAddr()
+	}
+	if isSamePath(s.conn, localAddr, p.remoteAddr) {
+		return nil
+	}
+	if s.probingPath != nil {
+		if isSamePath(s.probingPath.conn, localAddr, p.remoteAddr) {
+			return s.probingPath
+		}
+		// Don't let packets from a third address interrupt the validation of the probing path.
+		// Otherwise a single packet with a spoofed address would be enough to prevent the migration.
+		s.logger.Debugf("Ignoring address change to %s -> %s while validating a path.", p.remoteAddr, localAddr)
+		return nil
+	}
+	// Use a new connection ID for the new path, if the client provided one.
+	// Otherwise, we have to continue using the current connection ID.
+	connID, ok := s.connIDManager.GetForPathProbe()
+	if !ok {
+		connID = s.connIDManager.Get()
+	}
+	s.logger.Debugf("Received a packet from a new address: %s -> %s", p.remoteAddr, localAddr)
+	var conn sendConn
+	if p.conn != nil {
+		conn = newSendConn(p.conn, p.remoteAddr)
+	} else {
+		conn = s.initialConn.WithRemoteAddr(p.remoteAddr)
+	}
+	s.probingPath = newPath(conn, connID, true)
+	return s.probingPath
+}
+
+func isSamePath(conn sendConn, localAddr, remoteAddr net.Addr) bool {
+	return conn.RemoteAddr().String() == remoteAddr.String() && conn.LocalAddr().String() == localAddr.String()
+}
+
+// pathValidationTimeout is the time that we wait for a PATH_RESPONSE before abandoning a path.
+func (s *session) pathValidationTimeout() time.Duration {
+	return utils.MaxDuration(3*s.rttStats.PTO(true), protocol.MinPathValidationTimeout)
+}
+
+// startMigration starts validating the path that the client wants to migrate to.
+func (s *session) startMigration(m *pathMigration) {
+	if !s.handshakeConfirmed {
+		m.result <- errors.New("cannot migrate before the handshake is confirmed")
+		return
+	}
+	if s.peerParams.DisableActiveMigration {
+		m.result <- errors.New("the server disabled active migration")
+		return
+	}
+	if s.probingPath != nil {
+		m.result <- errors.New("connection migration already in progress")
+		return
+	}
+	connID, ok := s.connIDManager.GetForPathProbe()
+	if !ok {
+		m.result <- errors.New("no unused connection ID available")
+		return
+	}
+	// Register the connection IDs with the new packet conn, so we can receive the PATH_RESPONSE.
+	// All other state is only moved to the new packet conn once the path has been validated.
+	for _, c := range s.connIDGenerator.ActiveConnIDs() {
+		if m.packetHandlers.Add(c, s) {
+			m.connIDs = append(m.connIDs, c)
+		}
+	}
+	s.logger.Debugf("Migrating connection to %s. Validating path.", m.conn.LocalAddr())
+	s.probingPath = newPath(m.conn, connID, false)
+	s.probingPath.migration = m
+	s.probingPath.StartValidation(s.pathValidationTimeout(), time.Now())
+}
+
+// migrateToPath switches to a path that was successfully validated.
+func (s *session) migrateToPath(pth *path) {
+	s.probingPath = nil
+	s.logger.Infof("Migrating connection to path %s -> %s.", pth.conn.LocalAddr(), pth.RemoteAddr())
+	s.connMutex.Lock()
+	s.conn = pth.conn
+	s.connMutex.Unlock()
+	s.sendQueue.Close()
+	s.sendQueue = newSendQueue(s.conn)
+	s.runSendQueue(s.sendQueue)
+	if m := pth.migration; m != nil {
+		// Stop receiving packets on the old packet conn.
+		s.connIDGenerator.ReplaceRunner(m.packetHandlers, s)
+		s.connIDManager.ReplaceRunner(m.packetHandlers, s)
+	}
+	s.connIDManager.SwitchToPathProbeConnID()
+	s.sentPacketHandler.MigratedPath()
+	// The MTU of the new path is unknown.
+	if s.mtuDiscoverer != nil {
+		s.mtuDiscoverer.Reset(getMaxPacketSize(s.conn.RemoteAddr()))
+	}
+	if pth.migration != nil {
+		pth.migration.result <- nil
+	}
+}
+
+// maybeMigrateToPreferredAddress starts validating the path to the server's preferred address.
+// If path validation fails, the client continues using the current path.
+func (s *session) maybeMigrateToPreferredAddress() {
+	if !s.migrateToPreferredAddress {
+		return
+	}
+	s.migrateToPreferredAddress = false
+	addr := parsePreferredAddress(s.peerParams.PreferredAddress, s.conn.RemoteAddr())
+	if addr == nil {
+		return
+	}
+	if s.probingPath != nil {
+		s.logger.Debugf("Not migrating to the server's preferred address. Connection migration already in progress.")
+		return
+	}
+	connID, ok := s.connIDManager.GetForPathProbe()
+	if !ok {
+		s.logger.Debugf("Not migrating to the server's preferred address. No unused connection ID available.")
+		return
+	}
+	s.logger.Debugf("Migrating connection to the server's preferred address %s. Validating path.", addr)
+	s.probingPath = newPath(s.conn.WithRemoteAddr(addr), connID, false)
+	s.probingPath.StartValidation(s.pathValidationTimeout(), time.Now())
+}
+
+// abandonPath stops validating the probing path.
+func (s *session) abandonPath(err error) {
+	pth := s.probingPath
+	s.probingPath = nil
+	s.logger.Debugf("Abandoning path to %s.", pth.RemoteAddr())
+	s.connIDManager.RetirePathProbeConnID()
+	if m := pth.migration; m != nil {
+		for _, connID := range m.connIDs {
+			m.packetHandlers.Remove(connID)
+		}
+		m.result <- err
+	}
+}
+
+func (s *session) runSendQueue(q *sendQueue) {
+	go func() {
+		if err := q.Run(); err != nil {
+			s.destroyImpl(err)
+		}
+	}()
+}
+
 func (s *session) handleNewTokenFrame(frame *wire.NewTokenFrame) error {
 	if s.perspective == protocol.PerspectiveServer {
 		return qerr.NewError(qerr.ProtocolViolation, "Received NEW_TOKEN frame from the client.")
@@ -1251,6 +1619,9 @@ func (s *session) handleHandshakeDoneFrame() error {
 	s.handshakeConfirmed = true
 	s.sentPacketHandler.SetHandshakeConfirmed()
 	s.cryptoStreamHandler.SetHandshakeConfirmed()
+	// The preferred address is probed from the run loop,
+	// so that NEW_CONNECTION_ID frames in the same packet are processed first.
+	s.migrateToPreferredAddress = s.peerParams != nil && s.peerParams.PreferredAddress != nil
 	return nil
 }
 
@@ -1261,9 +1632,22 @@ func (s *session) handleAckFrame(frame *wire.AckFrame, encLevel protocol.Encrypt
 	if encLevel != protocol.Encryption1RTT {
 		return nil
 	}
+	if s.peerParams != nil && s.peerParams.MinAckDelay != nil {
+		if f := s.sentPacketHandler.GetAckFrequencyFrame(s.lastPacketReceivedTime); f != nil {
+			s.framer.QueueControlFrame(f)
+		}
+	}
 	return s.cryptoStreamHandler.SetLargest1RTTAcked(frame.LargestAcked())
 }
 
+func (s *session) handleAckFrequencyFrame(f *wire.AckFrequencyFrame) error {
+	if f.RequestMaxAckDelay < protocol.MinAckDelay {
+		return qerr.NewError(qerr.ProtocolViolation, fmt.Sprintf("requested max ack delay (%s) smaller than min_ack_delay (%s)", f.RequestMaxAckDelay, protocol.MinAckDelay))
+	}
+	s.receivedPacketHandler.ReceivedAckFrequencyFrame(f)
+	return nil
+}
+
 func (s *session) handleDatagramFrame(f *wire.DatagramFrame) error {
 	if f.Length(s.version) > protocol.MaxDatagramFrameSize {
 		return qerr.NewError(qerr.ProtocolViolation, "DATAGRAM frame too large")
@@ -1425,6 +1809,10 @@ func (s *session) processTransportParametersImpl(params *wire.TransportParameter
 		}
 	}
 
+	if err := s.handleVersionInformation(params.VersionInformation); err != nil {
+		return err
+	}
+
 	s.peerParams = params
 	// Our local idle timeout will always be > 0.
 	s.idleTimeout = utils.MinNonZeroDuration(s.config.MaxIdleTimeout, params.MaxIdleTimeout)
@@ -1434,13 +1822,15 @@ func (s *session) processTransportParametersImpl(params *wire.TransportParameter
 	s.frameParser.SetAckDelayExponent(params.AckDelayExponent)
 	s.connFlowController.UpdateSendWindow(params.InitialMaxData)
 	s.rttStats.SetMaxAckDelay(params.MaxAckDelay)
+	if params.MinAckDelay != nil {
+		s.sentPacketHandler.EnableAckFrequency(*params.MinAckDelay)
+	}
 	s.connIDGenerator.SetMaxActiveConnIDs(params.ActiveConnectionIDLimit)
 	if params.StatelessResetToken != nil {
 		s.connIDManager.SetStatelessResetToken(*params.StatelessResetToken)
 	}
-	// We don't support connection migration yet, so we don't have any use for the preferred_address.
+	// The client migrates to the preferred address once the handshake is confirmed.
 	if params.PreferredAddress != nil {
-		// Retire the connection ID.
 		s.connIDManager.AddFromPreferredAddress(params.PreferredAddress.ConnectionID, params.PreferredAddress.StatelessResetToken)
 	}
 	// On the server side, the early session is ready as soon as we processed
@@ -1451,6 +1841,74 @@ func (s *session) processTransportParametersImpl(params *wire.TransportParameter
 	return nil
 }
 
+// handleVersionInformation validates the peer's version_information transport parameter (RFC 9368).
+// The server uses it to perform compatible version negotiation.
+// The client uses it to detect downgrade attacks on the version negotiation.
+func (s *session) handleVersionInformation(vi *wire.VersionInformation) error {
+	if s.perspective == protocol.PerspectiveServer {
+		var clientVersions []protocol.VersionNumber
+		if vi != nil {
+			if vi.ChosenVersion != s.version {
+				return qerr.NewError(qerr.VersionNegotiationError, fmt.Sprintf("expected chosen version to equal %s, is %s", s.version, vi.ChosenVersion))
+			}
+			clientVersions = vi.AvailableVersions
+			// Switch to a compatible version, if we prefer it over the version the client used.
+			for _, v := range s.config.Versions {
+				if v == s.version {
+					break
+				}
+				if protocol.AreCompatibleVersions(s.version, v) && protocol.IsSupportedVersion(vi.AvailableVersions, v) {
+					s.logger.Infof("Performing compatible version negotiation. Switching from QUIC version %s to %s.", s.version, v)
+					s.changeVersion(v)
+					break
+				}
+			}
+		}
+		if s.tracer != nil {
+			s.tracer.NegotiatedVersion(s.version, clientVersions, s.config.Versions)
+		}
+		return nil
+	}
+
+	if vi == nil {
+		// If we reacted to a Version Negotiation packet, we need to be able to check that the server didn't downgrade us.
+		if s.versionNegotiated {
+			return qerr.NewError(qerr.VersionNegotiationError, "missing version_information after version negotiation")
+		}
+		if s.tracer != nil {
+			s.tracer.NegotiatedVersion(s.version, s.config.Versions, nil)
+		}
+		return nil
+	}
+	if vi.ChosenVersion != s.version {
+		return qerr.NewError(qerr.VersionNegotiationError, fmt.Sprintf("expected chosen version to equal %s, is %s", s.version, vi.ChosenVersion))
+	}
+	if s.versionNegotiated {
+		// Check that we would have picked the same version if the Version Negotiation packet
+		// had listed the versions the server actually supports.
+		// The server might have switched to a compatible version after that.
+		if v, ok := protocol.ChooseSupportedVersion(s.config.Versions, vi.AvailableVersions); !ok || !protocol.AreCompatibleVersions(v, s.version) {
+			return qerr.NewError(qerr.VersionNegotiationError, fmt.Sprintf("version downgrade detected: using %s, server supports %s", s.version, vi.AvailableVersions))
+		}
+	}
+	if s.tracer != nil {
+		s.tracer.NegotiatedVersion(s.version, s.config.Versions, vi.AvailableVersions)
+	}
+	return nil
+}
+
+// compatibleVersions returns the versions that a connection using version v can be switched to
+// using compatible version negotiation.
+func compatibleVersions(supported []protocol.VersionNumber, v protocol.VersionNumber) []protocol.VersionNumber {
+	versions := make([]protocol.VersionNumber, 0, len(supported))
+	for _, ver := range supported {
+		if protocol.AreCompatibleVersions(v, ver) {
+			versions = append(versions, ver)
+		}
+	}
+	return versions
+}
+
 func (s *session) sendPackets() error {
 	s.pacingDeadline = time.Time{}
 
@@ -1575,6 +2033,21 @@ func (s *session) sendPacket() (bool, error) {
 		s.sendQueue.Send(packet.buffer)
 		return true, nil
 	}
+	if s.mtuDiscoverer != nil && s.mtuDiscoverer.ShouldSendProbe(time.Now()) {
+		ping, size := s.mtuDiscoverer.GetPing()
+		// If the peer supports the ACK frequency extension, ask it to acknowledge the probe immediately.
+		// Otherwise every step of the search would be delayed by the peer's max_ack_delay.
+		packet, err := s.packer.PackMTUProbePacket(ping, size, s.peerParams.MinAckDelay != nil)
+		if err != nil {
+			return false, err
+		}
+		s.registerSentPacket(packet.packetContents, time.Now(), protocol.ECNNon)
+		s.sendQueue.SendMTUProbe(packet.buffer)
+		return true, nil
+	}
+	if s.sendQueue.SupportsBatching() {
+		return s.sendPacketBatch()
+	}
 	packet, err := s.packer.PackPacket()
 	if err != nil || packet == nil {
 		return false, err
@@ -1583,15 +2056,105 @@ func (s *session) sendPacket() (bool, error) {
 	return true, nil
 }
 
+// sendPacketBatch packs multiple packets into a single buffer, such that they can be sent using a single syscall.
+// All packets except for the last one have the maximum packet size.
+// If supported by the conn, the packets are marked with the ECN codepoint requested by the sent packet handler.
+func (s *session) sendPacketBatch() (bool, error) {
+	maxSize := s.packer.MaxPacketSize()
+	// All packets in a batch use the same ECN codepoint.
+	ecn := protocol.ECNNon
+	if s.sendQueue.SupportsECN() {
+		ecn = s.sentPacketHandler.ECNMode()
+	}
+	buffer := getLargePacketBuffer()
+	var numPackets int
+	for {
+		packet, err := s.packer.AppendPacket(buffer)
+		if err != nil {
+			buffer.Release()
+			return false, err
+		}
+		if packet == nil {
+			break
+		}
+		numPackets++
+		s.registerSentPacket(packet, time.Now(), ecn)
+		// Only a packet of the maximum size can be followed by another packet.
+		if packet.length != maxSize || buffer.Cap()-buffer.Len() < maxSize {
+			break
+		}
+		// Check that congestion control and pacing allow us to send another packet.
+		if s.sentPacketHandler.SendMode() != ackhandler.SendAny || !s.sentPacketHandler.HasPacingBudget() {
+			break
+		}
+	}
+	switch numPackets {
+	case 0:
+		buffer.Release()
+		return false, nil
+	case 1:
+		if ecn == protocol.ECNNon {
+			s.sendQueue.Send(buffer)
+		} else {
+			s.sendQueue.SendBatch(buffer, maxSize, ecn)
+		}
+	default:
+		s.sendQueue.SendBatch(buffer, maxSize, ecn)
+	}
+	return true, nil
+}
+
 func (s *session) sendPackedPacket(packet *packedPacket) {
-	now := time.Now()
+	s.registerSentPacket(packet.packetContents, time.Now(), protocol.ECNNon)
+	s.sendQueue.Send(packet.buffer)
+}
+
+// registerSentPacket logs a packet and passes it to the sent packet handler.
+// It must be called before the packet is sent.
+func (s *session) registerSentPacket(packet *packetContents, now time.Time, ecn protocol.ECN) {
 	if s.firstAckElicitingPacketAfterIdleSentTime.IsZero() && packet.IsAckEliciting() {
 		s.firstAckElicitingPacketAfterIdleSentTime = now
 	}
 	s.logPacket(packet)
-	s.sentPacketHandler.SentPacket(packet.ToAckHandlerPacket(now, s.retransmissionQueue))
+	p := packet.ToAckHandlerPacket(now, s.retransmissionQueue)
+	p.ECN = ecn
+	s.sentPacketHandler.SentPacket(p)
 	s.connIDManager.SentPacket()
-	s.sendQueue.Send(packet.buffer)
+}
+
+// maybeProbePath sends PATH_CHALLENGE and PATH_RESPONSE frames on the path that is being validated.
+func (s *session) maybeProbePath(now time.Time) {
+	pth := s.probingPath
+	if pth == nil {
+		return
+	}
+	if pth.HasTimedOut(now) {
+		s.abandonPath(errors.New("path validation timed out"))
+		return
+	}
+	frames := pth.AppendFrames(nil, now)
+	if len(frames) == 0 {
+		return
+	}
+	packet, err := s.packer.PackPathProbePacket(pth.connID, frames, pth.SendBudget())
+	if err != nil {
+		s.closeLocal(err)
+		return
+	}
+	if packet == nil {
+		return
+	}
+	s.logPacket(packet.packetContents)
+	p := packet.ToAckHandlerPacket(now, s.retransmissionQueue)
+	p.IsPathProbePacket = true
+	s.sentPacketHandler.SentPacket(p)
+	pth.SentBytes(packet.buffer.Len())
+	err = pth.conn.Write(packet.buffer.Data)
+	packet.buffer.Release()
+	if err != nil {
+		s.logger.Debugf("Error sending path probe packet: %s", err)
+		s.abandonPath(err)
+	}
 }
 
 func (s *session) sendConnectionClose(quicErr *qerr.QuicError) ([]byte, error) {
@@ -1639,11 +2202,11 @@ func (s *session) logCoalescedPacket(packet *coalescedPacket) {
 	}
 }
 
-func (s *session) logPacket(packet *packedPacket) {
+func (s *session) logPacket(packet *packetContents) {
 	if s.logger.Debug() {
-		s.logger.Debugf("-> Sending packet %d (%d bytes) for connection %s, %s", packet.header.PacketNumber, packet.buffer.Len(), s.logID, packet.EncryptionLevel())
+		s.logger.Debugf("-> Sending packet %d (%d bytes) for connection %s, %s", packet.header.PacketNumber, packet.length, s.logID, packet.EncryptionLevel())
 	}
-	s.logPacketContents(packet.packetContents)
+	s.logPacketContents(packet)
 }
 
 // AcceptStream returns the next stream openend by the peer
@@ -1746,15 +2309,23 @@ func (s *session) onHasStreamData(id protocol.StreamID) {
 	s.scheduleSending()
 }
 
+func (s *session) onStreamPriorityChanged(id protocol.StreamID, prio protocol.StreamPriority) {
+	s.framer.SetStreamPriority(id, prio)
+}
+
 func (s *session) onStreamCompleted(id protocol.StreamID) {
+	// The stream needs to be deleted first, see framerI.SetStreamPriority.
 	if err := s.streamsMap.DeleteStream(id); err != nil {
 		s.closeLocal(err)
 	}
+	s.framer.RemoveStream(id)
 }
 
 func (s *session) SendMessage(p []byte) error {
 	f := &wire.DatagramFrame{DataLenPresent: true}
-	if protocol.ByteCount(len(p)) > f.MaxDataLen(s.peerParams.MaxDatagramFrameSize, s.version) {
+	// The DATAGRAM frame needs to fit into a single packet of the current maximum packet size.
+	maxFrameSize := utils.MinByteCount(s.peerParams.MaxDatagramFrameSize, s.packer.MaxDatagramFrameSize())
+	if protocol.ByteCount(len(p)) > f.MaxDataLen(maxFrameSize, s.version) {
 		return errors.New("message too large")
 	}
 	f.Data = make([]byte, len(p))
@@ -1768,13 +2339,43 @@ func (s *session) ReceiveMessage() ([]byte, error) {
 }
 
 func (s *session) LocalAddr() net.Addr {
+	s.connMutex.Lock()
+	defer s.connMutex.Unlock()
 	return s.conn.LocalAddr()
 }
 
 func (s *session) RemoteAddr() net.Addr {
+	s.connMutex.Lock()
+	defer s.connMutex.Unlock()
 	return s.conn.RemoteAddr()
 }
 
+func (s *session) MigrateConnection(conn net.PacketConn) error {
+	if s.perspective == protocol.PerspectiveServer {
+		return errors.New("only the client can migrate a connection")
+	}
+	packetHandlers, err := getMultiplexer().AddConn(conn, s.srcConnIDLen, protocol.ByteCount(s.config.MaxPacketSize), s.config.StatelessResetKey, s.config.Tracer)
+	if err != nil {
+		return err
+	}
+	m := &pathMigration{
+		conn:           newSendConn(conn, s.RemoteAddr()),
+		packetHandlers: packetHandlers,
+		result:         make(chan error, 1),
+	}
+	select {
+	case s.migrationChan <- m:
+	case <-s.ctx.Done():
+		return errSessionClosed
+	}
+	select {
+	case err := <-m.result:
+		return err
+	case <-s.ctx.Done():
+		return errSessionClosed
+	}
+}
+
 func (s *session) getPerspective() protocol.Perspective {
 	return s.perspective
 }
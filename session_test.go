@@ -167,6 +167,43 @@ var _ = Describe("Session", func() {
 				err := sess.handleAckFrame(f, protocol.EncryptionHandshake)
 				Expect(err).ToNot(HaveOccurred())
 			})
+
+			It("queues ACK_FREQUENCY frames, if the peer supports the ACK frequency extension", func() {
+				minAckDelay := time.Millisecond
+				sess.peerParams = &wire.TransportParameters{MinAckDelay: &minAckDelay}
+				f := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 2, Largest: 3}}}
+				afFrame := &wire.AckFrequencyFrame{AckElicitingThreshold: 3, RequestMaxAckDelay: 10 * time.Millisecond}
+				sph := mockackhandler.NewMockSentPacketHandler(mockCtrl)
+				sph.EXPECT().ReceivedAck(f, protocol.Encryption1RTT, gomock.Any())
+				sph.EXPECT().GetAckFrequencyFrame(gomock.Any()).Return(afFrame)
+				sess.sentPacketHandler = sph
+				cryptoSetup.EXPECT().SetLargest1RTTAcked(protocol.PacketNumber(3))
+				Expect(sess.handleAckFrame(f, protocol.Encryption1RTT)).To(Succeed())
+				frames, _ := sess.framer.AppendControlFrames(nil, 1000)
+				Expect(frames).To(Equal([]ackhandler.Frame{{Frame: afFrame}}))
+			})
+		})
+
+		Context("handling ACK_FREQUENCY frames", func() {
+			It("passes ACK_FREQUENCY frames to the ReceivedPacketHandler", func() {
+				f := &wire.AckFrequencyFrame{AckElicitingThreshold: 5, RequestMaxAckDelay: 20 * time.Millisecond}
+				rph := mockackhandler.NewMockReceivedPacketHandler(mockCtrl)
+				rph.EXPECT().ReceivedAckFrequencyFrame(f)
+				sess.receivedPacketHandler = rph
+				Expect(sess.handleFrame(f, protocol.Encryption1RTT, protocol.ConnectionID{})).To(Succeed())
+			})
+
+			It("rejects ACK_FREQUENCY frames requesting a delay smaller than the min_ack_delay", func() {
+				f := &wire.AckFrequencyFrame{AckElicitingThreshold: 5, RequestMaxAckDelay: protocol.MinAckDelay - 1}
+				Expect(sess.handleFrame(f, protocol.Encryption1RTT, protocol.ConnectionID{})).To(MatchError(ContainSubstring("PROTOCOL_VIOLATION: requested max ack delay")))
+			})
+
+			It("passes IMMEDIATE_ACK frames to the ReceivedPacketHandler", func() {
+				rph := mockackhandler.NewMockReceivedPacketHandler(mockCtrl)
+				rph.EXPECT().ReceivedImmediateAck()
+				sess.receivedPacketHandler = rph
+				Expect(sess.handleFrame(&wire.ImmediateAckFrame{}, protocol.Encryption1RTT, protocol.ConnectionID{})).To(Succeed())
+			})
 		})
 
 		Context("handling RESET_STREAM frames", func() {
@@ -295,9 +332,9 @@ var _ = Describe("Session", func() {
 			Expect(err).NotTo(HaveOccurred())
 		})
 
-		It("rejects PATH_RESPONSE frames", func() {
+		It("ignores PATH_RESPONSE frames that don't belong to a path that is being validated", func() {
 			err := sess.handleFrame(&wire.PathResponseFrame{Data: [8]byte{1, 2, 3, 4, 5, 6, 7, 8}}, protocol.Encryption1RTT, protocol.ConnectionID{})
-			Expect(err).To(MatchError("unexpected PATH_RESPONSE frame"))
+			Expect(err).ToNot(HaveOccurred())
 		})
 
 		It("handles PATH_CHALLENGE frames", func() {
@@ -462,6 +499,30 @@ var _ = Describe("Session", func() {
 			Expect(sess.Context().Done()).To(BeClosed())
 		})
 
+		It("returns the connection stats, while running and after closing", func() {
+			sess.rttStats.UpdateRTT(20*time.Millisecond, 0, time.Now())
+			sess.connFlowController.UpdateSendWindow(1000)
+			runSession()
+			stats := sess.ConnectionStats()
+			Expect(stats.SmoothedRTT).To(Equal(20 * time.Millisecond))
+			Expect(stats.MinRTT).To(Equal(20 * time.Millisecond))
+			Expect(stats.CongestionWindow).ToNot(BeZero())
+			Expect(stats.MTU).To(BeEquivalentTo(getMaxPacketSize(remoteAddr)))
+			Expect(stats.SendWindow).To(BeEquivalentTo(1000))
+			Expect(stats.ReceiveWindow).To(BeEquivalentTo(protocol.InitialMaxData))
+			Expect(stats.SlowStartCount).To(BeZero()) // only collected by BBR
+			streamManager.EXPECT().CloseWithError(gomock.Any())
+			expectReplaceWithClosed()
+			cryptoSetup.EXPECT().Close()
+			packer.EXPECT().PackConnectionClose(gomock.Any()).Return(&coalescedPacket{buffer: getPacketBuffer()}, nil)
+			mconn.EXPECT().Write(gomock.Any())
+			tracer.EXPECT().ClosedConnection(gomock.Any())
+			tracer.EXPECT().Close()
+			sess.shutdown()
+			Eventually(areSessionsRunning).Should(BeFalse())
+			Expect(sess.ConnectionStats()).To(Equal(stats))
+		})
+
 		It("only closes once", func() {
 			runSession()
 			streamManager.EXPECT().CloseWithError(gomock.Any())
@@ -606,7 +667,7 @@ var _ = Describe("Session", func() {
 			// don't EXPECT any calls to packer.PackPacket()
 			sess.handlePacket(&receivedPacket{
 				rcvTime:    time.Now(),
-				remoteAddr: &net.UDPAddr{},
+				remoteAddr: remoteAddr,
 				buffer:     getPacketBuffer(),
 				data:       buf.Bytes(),
 			})
@@ -748,6 +809,50 @@ var _ = Describe("Session", func() {
 			Expect(sess.handlePacketImpl(p)).To(BeFalse())
 		})
 
+		It("accepts Initial packets of the original version after switching to a compatible version", func() {
+			sess.version = protocol.Version2
+			sess.originalVersion = protocol.Version1
+			hdr := &wire.ExtendedHeader{
+				Header: wire.Header{
+					IsLongHeader:     true,
+					Type:             protocol.PacketTypeInitial,
+					DestConnectionID: srcConnID,
+					SrcConnectionID:  destConnID,
+					Version:          protocol.Version1,
+					Length:           1,
+				},
+				PacketNumber:    0x37,
+				PacketNumberLen: protocol.PacketNumberLen1,
+			}
+			packet := getPacket(hdr, nil)
+			unpacker.EXPECT().Unpack(gomock.Any(), gomock.Any(), gomock.Any()).Return(&unpackedPacket{
+				packetNumber:    0x37,
+				encryptionLevel: protocol.EncryptionInitial,
+				hdr:             hdr,
+				data:            []byte{0}, // one PADDING frame
+			}, nil)
+			tracer.EXPECT().StartedConnection(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
+			tracer.EXPECT().ReceivedPacket(hdr, gomock.Any(), []logging.Frame{})
+			Expect(sess.handlePacketImpl(packet)).To(BeTrue())
+		})
+
+		It("drops Handshake packets of the original version after switching to a compatible version", func() {
+			sess.version = protocol.Version2
+			sess.originalVersion = protocol.Version1
+			p := getPacket(&wire.ExtendedHeader{
+				Header: wire.Header{
+					IsLongHeader:     true,
+					Type:             protocol.PacketTypeHandshake,
+					DestConnectionID: srcConnID,
+					SrcConnectionID:  destConnID,
+					Version:          protocol.Version1,
+				},
+				PacketNumberLen: protocol.PacketNumberLen2,
+			}, nil)
+			tracer.EXPECT().DroppedPacket(logging.PacketTypeHandshake, p.Size(), logging.PacketDropUnexpectedVersion)
+			Expect(sess.handlePacketImpl(p)).To(BeFalse())
+		})
+
 		It("informs the ReceivedPacketHandler about non-ack-eliciting packets", func() {
 			hdr := &wire.ExtendedHeader{
 				Header:          wire.Header{DestConnectionID: srcConnID},
@@ -1210,6 +1315,115 @@ var _ = Describe("Session", func() {
 			Eventually(sent).Should(BeClosed())
 		})
 
+		Context("sending batches of packets", func() {
+			var bconn *MockBatchSendConn
+
+			BeforeEach(func() {
+				bconn = NewMockBatchSendConn(mockCtrl)
+				sess.sendQueue = newSendQueue(bconn)
+				sess.handshakeConfirmed = true
+				packer.EXPECT().MaxPacketSize().Return(protocol.ByteCount(1000)).AnyTimes()
+			})
+
+			expectSupportsECN := func(supported bool) {
+				bconn.EXPECT().SupportsECN().Return(supported).AnyTimes()
+			}
+
+			// appendPacket expects a call to AppendPacket, which appends a packet of the given length
+			appendPacket := func(pn protocol.PacketNumber, length protocol.ByteCount) *gomock.Call {
+				return packer.EXPECT().AppendPacket(gomock.Any()).DoAndReturn(func(buffer *packetBuffer) (*packetContents, error) {
+					buffer.Data = append(buffer.Data, make([]byte, length)...)
+					return &packetContents{
+						header: &wire.ExtendedHeader{PacketNumber: pn},
+						length: length,
+					}, nil
+				})
+			}
+
+			It("sends multiple packets in a single batch", func() {
+				sph := mockackhandler.NewMockSentPacketHandler(mockCtrl)
+				sph.EXPECT().TimeUntilSend().AnyTimes()
+				sph.EXPECT().GetLossDetectionTimeout().AnyTimes()
+				sph.EXPECT().SendMode().Return(ackhandler.SendAny).AnyTimes()
+				sph.EXPECT().HasPacingBudget().Return(true).AnyTimes()
+				sph.EXPECT().SentPacket(gomock.Any()).Times(4)
+				sess.sentPacketHandler = sph
+				expectSupportsECN(false)
+				gomock.InOrder(
+					appendPacket(1, 1000),
+					appendPacket(2, 1000),
+					appendPacket(3, 1000),
+					appendPacket(4, 500),
+				)
+				packer.EXPECT().AppendPacket(gomock.Any()).AnyTimes()
+				tracer.EXPECT().SentPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(4)
+				sent := make(chan struct{})
+				bconn.EXPECT().WriteBatch(gomock.Any(), protocol.ByteCount(1000), protocol.ECNNon).Do(func(b []byte, _ protocol.ByteCount, _ protocol.ECN) {
+					defer GinkgoRecover()
+					Expect(b).To(HaveLen(3500))
+					close(sent)
+				})
+				runSession()
+				sess.scheduleSending()
+				Eventually(sent).Should(BeClosed())
+			})
+
+			It("stops batching when the pacer doesn't allow sending another packet", func() {
+				sph := mockackhandler.NewMockSentPacketHandler(mockCtrl)
+				sph.EXPECT().TimeUntilSend().AnyTimes()
+				sph.EXPECT().GetLossDetectionTimeout().AnyTimes()
+				sph.EXPECT().SendMode().Return(ackhandler.SendAny).AnyTimes()
+				gomock.InOrder(
+					sph.EXPECT().HasPacingBudget().Return(true).MaxTimes(1),
+					sph.EXPECT().HasPacingBudget().Return(false).AnyTimes(),
+				)
+				sph.EXPECT().SentPacket(gomock.Any())
+				sess.sentPacketHandler = sph
+				expectSupportsECN(false)
+				appendPacket(1, 1000)
+				packer.EXPECT().AppendPacket(gomock.Any()).AnyTimes()
+				tracer.EXPECT().SentPacket(gomock.Any(), protocol.ByteCount(1000), gomock.Any(), gomock.Any())
+				sent := make(chan struct{})
+				bconn.EXPECT().Write(gomock.Any()).Do(func(b []byte) {
+					defer GinkgoRecover()
+					Expect(b).To(HaveLen(1000))
+					close(sent)
+				})
+				runSession()
+				sess.scheduleSending()
+				Eventually(sent).Should(BeClosed())
+			})
+
+			It("marks packets with the ECN codepoint requested by the sent packet handler", func() {
+				sph := mockackhandler.NewMockSentPacketHandler(mockCtrl)
+				sph.EXPECT().TimeUntilSend().AnyTimes()
+				sph.EXPECT().GetLossDetectionTimeout().AnyTimes()
+				sph.EXPECT().SendMode().Return(ackhandler.SendAny).AnyTimes()
+				sph.EXPECT().HasPacingBudget().Return(true).AnyTimes()
+				sph.EXPECT().ECNMode().Return(protocol.ECT0).AnyTimes()
+				sph.EXPECT().SentPacket(gomock.Any()).Do(func(p *ackhandler.Packet) {
+					Expect(p.ECN).To(Equal(protocol.ECT0))
+				}).Times(2)
+				sess.sentPacketHandler = sph
+				expectSupportsECN(true)
+				gomock.InOrder(
+					appendPacket(1, 1000),
+					appendPacket(2, 500),
+				)
+				packer.EXPECT().AppendPacket(gomock.Any()).AnyTimes()
+				tracer.EXPECT().SentPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(2)
+				sent := make(chan struct{})
+				bconn.EXPECT().WriteBatch(gomock.Any(), protocol.ByteCount(1000), protocol.ECT0).Do(func(b []byte, _ protocol.ByteCount, _ protocol.ECN) {
+					defer GinkgoRecover()
+					Expect(b).To(HaveLen(1500))
+					close(sent)
+				})
+				runSession()
+				sess.scheduleSending()
+				Eventually(sent).Should(BeClosed())
+			})
+		})
+
 		It("doesn't send packets if there's nothing to send", func() {
 			sess.handshakeConfirmed = true
 			runSession()
@@ -1258,6 +1472,56 @@ var _ = Describe("Session", func() {
 			Expect(frames).To(Equal([]ackhandler.Frame{{Frame: &logging.DataBlockedFrame{MaximumData: 1337}}}))
 		})
 
+		It("sends a path MTU probe packet", func() {
+			mtuDiscoverer := NewMockMtuDiscoverer(mockCtrl)
+			sess.mtuDiscoverer = mtuDiscoverer
+			mtuDiscoverer.EXPECT().NextProbeTime().AnyTimes()
+			sess.handshakeConfirmed = true
+			minAckDelay := time.Millisecond
+			sess.peerParams = &wire.TransportParameters{MinAckDelay: &minAckDelay}
+			sph := mockackhandler.NewMockSentPacketHandler(mockCtrl)
+			sph.EXPECT().TimeUntilSend().AnyTimes()
+			sph.EXPECT().GetLossDetectionTimeout().AnyTimes()
+			sph.EXPECT().SendMode().Return(ackhandler.SendAny)
+			sph.EXPECT().SendMode().Return(ackhandler.SendNone)
+			sph.EXPECT().HasPacingBudget().Return(true).AnyTimes()
+			sph.EXPECT().SentPacket(gomock.Any()).Do(func(packet *ackhandler.Packet) {
+				Expect(packet.IsPathMTUProbePacket).To(BeTrue())
+			})
+			sess.sentPacketHandler = sph
+			ping := ackhandler.Frame{Frame: &wire.PingFrame{}}
+			mtuDiscoverer.EXPECT().ShouldSendProbe(gomock.Any()).Return(true)
+			mtuDiscoverer.EXPECT().GetPing().Return(ping, protocol.ByteCount(1234))
+			p := getPacket(1)
+			p.isMTUProbePacket = true
+			packer.EXPECT().PackMTUProbePacket(ping, protocol.ByteCount(1234), true).Return(p, nil)
+			runSession()
+			sent := make(chan struct{})
+			mconn.EXPECT().Write(gomock.Any()).Do(func([]byte) { close(sent) })
+			tracer.EXPECT().SentPacket(p.header, p.buffer.Len(), nil, []logging.Frame{})
+			sess.scheduleSending()
+			Eventually(sent).Should(BeClosed())
+		})
+
+		It("falls back to a smaller packet size when a path MTU black hole is detected", func() {
+			mtuDiscoverer := NewMockMtuDiscoverer(mockCtrl)
+			sess.mtuDiscoverer = mtuDiscoverer
+			mtuDiscoverer.EXPECT().NextProbeTime().AnyTimes()
+			sph := mockackhandler.NewMockSentPacketHandler(mockCtrl)
+			sph.EXPECT().TimeUntilSend().AnyTimes()
+			sph.EXPECT().SendMode().Return(ackhandler.SendNone).AnyTimes()
+			// the loss detection timeout is queried when setting the timer, and after the timer fired
+			sph.EXPECT().GetLossDetectionTimeout().Return(time.Now().Add(-time.Second)).Times(2)
+			sph.EXPECT().GetLossDetectionTimeout().Return(time.Now().Add(time.Hour)).AnyTimes()
+			sph.EXPECT().OnLossDetectionTimeout()
+			sph.EXPECT().DetectedPathMTUBlackHole().Return(true)
+			done := make(chan struct{})
+			mtuDiscoverer.EXPECT().DetectedBlackHole().Do(func() { close(done) })
+			sess.sentPacketHandler = sph
+			runSession()
+			Eventually(done).Should(BeClosed())
+		})
+
 		It("doesn't send when the SentPacketHandler doesn't allow it", func() {
 			sph := mockackhandler.NewMockSentPacketHandler(mockCtrl)
 			sph.EXPECT().GetLossDetectionTimeout().AnyTimes()
@@ -1830,9 +2094,94 @@ var _ = Describe("Session", func() {
 			sessionRunner.EXPECT().GetStatelessResetToken(gomock.Any()).Times(2)
 			sessionRunner.EXPECT().Add(gomock.Any(), sess).Times(2)
 			tracer.EXPECT().ReceivedTransportParameters(params)
+			tracer.EXPECT().NegotiatedVersion(sess.version, nil, sess.config.Versions)
 			sess.processTransportParameters(params)
 			Expect(sess.earlySessionReady()).To(BeClosed())
 		})
+
+		It("switches to a compatible version that it prefers", func() {
+			sess.version = protocol.Version1
+			sess.config.Versions = []protocol.VersionNumber{protocol.Version2, protocol.Version1}
+			unpacker := NewMockUnpacker(mockCtrl)
+			sess.unpacker = unpacker
+			params := &wire.TransportParameters{
+				InitialSourceConnectionID: destConnID,
+				VersionInformation: &wire.VersionInformation{
+					ChosenVersion:     protocol.Version1,
+					AvailableVersions: []protocol.VersionNumber{protocol.Version1, protocol.Version2},
+				},
+			}
+			streamManager.EXPECT().UpdateLimits(params)
+			packer.EXPECT().HandleTransportParameters(params)
+			packer.EXPECT().PackCoalescedPacket().MaxTimes(3)
+			sessionRunner.EXPECT().GetStatelessResetToken(gomock.Any()).AnyTimes()
+			sessionRunner.EXPECT().Add(gomock.Any(), sess).AnyTimes()
+			tracer.EXPECT().ReceivedTransportParameters(params)
+			cryptoSetup.EXPECT().ChangeVersion(protocol.Version2)
+			packer.EXPECT().SetVersion(protocol.Version2)
+			unpacker.EXPECT().SetVersion(protocol.Version2)
+			tracer.EXPECT().NegotiatedVersion(
+				protocol.Version2,
+				[]protocol.VersionNumber{protocol.Version1, protocol.Version2},
+				[]protocol.VersionNumber{protocol.Version2, protocol.Version1},
+			)
+			sess.processTransportParameters(params)
+			Expect(sess.version).To(Equal(protocol.Version2))
+			Expect(sess.originalVersion).To(Equal(protocol.Version1))
+		})
+
+		It("doesn't switch versions if the client doesn't support the version", func() {
+			sess.version = protocol.Version1
+			sess.config.Versions = []protocol.VersionNumber{protocol.Version2, protocol.Version1}
+			params := &wire.TransportParameters{
+				InitialSourceConnectionID: destConnID,
+				VersionInformation: &wire.VersionInformation{
+					ChosenVersion:     protocol.Version1,
+					AvailableVersions: []protocol.VersionNumber{protocol.Version1},
+				},
+			}
+			streamManager.EXPECT().UpdateLimits(params)
+			packer.EXPECT().HandleTransportParameters(params)
+			packer.EXPECT().PackCoalescedPacket().MaxTimes(3)
+			sessionRunner.EXPECT().GetStatelessResetToken(gomock.Any()).AnyTimes()
+			sessionRunner.EXPECT().Add(gomock.Any(), sess).AnyTimes()
+			tracer.EXPECT().ReceivedTransportParameters(params)
+			tracer.EXPECT().NegotiatedVersion(protocol.Version1, []protocol.VersionNumber{protocol.Version1}, gomock.Any())
+			sess.processTransportParameters(params)
+			Expect(sess.version).To(Equal(protocol.Version1))
+		})
+
+		It("errors if the chosen version doesn't match the version in use", func() {
+			sess.version = protocol.Version1
+			params := &wire.TransportParameters{
+				InitialSourceConnectionID: destConnID,
+				VersionInformation: &wire.VersionInformation{
+					ChosenVersion:     protocol.Version2,
+					AvailableVersions: []protocol.VersionNumber{protocol.Version2},
+				},
+			}
+			tracer.EXPECT().ReceivedTransportParameters(params)
+			go func() {
+				defer GinkgoRecover()
+				cryptoSetup.EXPECT().RunHandshake().MaxTimes(1)
+				sess.run()
+			}()
+			expectReplaceWithClosed()
+			streamManager.EXPECT().CloseWithError(gomock.Any())
+			cryptoSetup.EXPECT().Close()
+			packer.EXPECT().PackConnectionClose(gomock.Any()).DoAndReturn(func(e *qerr.QuicError) (*coalescedPacket, error) {
+				Expect(e.ErrorCode).To(Equal(qerr.VersionNegotiationError))
+				Expect(e.ErrorMessage).To(Equal("expected chosen version to equal v1, is v2"))
+				return &coalescedPacket{buffer: getPacketBuffer()}, nil
+			})
+			mconn.EXPECT().Write(gomock.Any())
+			gomock.InOrder(
+				tracer.EXPECT().ClosedConnection(gomock.Any()),
+				tracer.EXPECT().Close(),
+			)
+			sess.processTransportParameters(params)
+			Eventually(sess.Context().Done()).Should(BeClosed())
+		})
 	})
 
 	Context("keep-alives", func() {
@@ -1840,6 +2189,7 @@ var _ = Describe("Session", func() {
 			streamManager.EXPECT().UpdateLimits(gomock.Any())
 			packer.EXPECT().HandleTransportParameters(gomock.Any())
 			tracer.EXPECT().ReceivedTransportParameters(gomock.Any())
+			tracer.EXPECT().NegotiatedVersion(gomock.Any(), gomock.Any(), gomock.Any())
 			sess.processTransportParameters(&wire.TransportParameters{
 				MaxIdleTimeout:            t,
 				InitialSourceConnectionID: destConnID,
@@ -2165,6 +2515,191 @@ var _ = Describe("Session", func() {
 	It("returns the remote address", func() {
 		Expect(sess.RemoteAddr()).To(Equal(remoteAddr))
 	})
+
+	Context("connection migration", func() {
+		newAddr := &net.UDPAddr{IP: net.IPv4(192, 168, 0, 1), Port: 4242}
+		newConnID := protocol.ConnectionID{0xde, 0xca, 0xfb, 0xad}
+		var newConn *MockSendConn
+
+		BeforeEach(func() {
+			sess.handshakeConfirmed = true
+			newConn = NewMockSendConn(mockCtrl)
+			newConn.EXPECT().RemoteAddr().Return(newAddr).AnyTimes()
+			newConn.EXPECT().LocalAddr().Return(localAddr).AnyTimes()
+		})
+
+		receivePacket := func(f wire.Frame) bool {
+			sess.receivedFirstPacket = true
+			unpacker := NewMockUnpacker(mockCtrl)
+			sess.unpacker = unpacker
+			hdr := &wire.ExtendedHeader{
+				Header:          wire.Header{DestConnectionID: srcConnID},
+				PacketNumber:    0x37,
+				PacketNumberLen: protocol.PacketNumberLen1,
+			}
+			buf := &bytes.Buffer{}
+			Expect(hdr.Write(buf, sess.version)).To(Succeed())
+			p := &receivedPacket{
+				remoteAddr: newAddr,
+				data:       buf.Bytes(),
+				buffer:     getPacketBuffer(),
+				rcvTime:    time.Now(),
+			}
+			data := &bytes.Buffer{}
+			Expect(f.Write(data, sess.version)).To(Succeed())
+			unpacker.EXPECT().Unpack(gomock.Any(), gomock.Any(), gomock.Any()).Return(&unpackedPacket{
+				packetNumber:    0x1337,
+				encryptionLevel: protocol.Encryption1RTT,
+				hdr:             hdr,
+				data:            data.Bytes(),
+			}, nil)
+			tracer.EXPECT().ReceivedPacket(hdr, gomock.Any(), gomock.Any())
+			return sess.handlePacketImpl(p)
+		}
+
+		It("doesn't create a path for packets received on the current path", func() {
+			Expect(sess.getPath(&receivedPacket{remoteAddr: remoteAddr}, &unpackedPacket{encryptionLevel: protocol.Encryption1RTT})).To(BeNil())
+		})
+
+		It("doesn't create a path before the handshake is confirmed", func() {
+			sess.handshakeConfirmed = false
+			Expect(sess.getPath(&receivedPacket{remoteAddr: newAddr}, &unpackedPacket{encryptionLevel: protocol.Encryption1RTT})).To(BeNil())
+		})
+
+		It("creates a path for packets received from a new address", func() {
+			mconn.EXPECT().WithRemoteAddr(newAddr).Return(newConn)
+			pth := sess.getPath(&receivedPacket{remoteAddr: newAddr}, &unpackedPacket{encryptionLevel: protocol.Encryption1RTT})
+			Expect(pth).ToNot(BeNil())
+			Expect(pth.RemoteAddr()).To(Equal(newAddr))
+			Expect(pth.amplificationLimited).To(BeTrue())
+			Expect(sess.probingPath).To(Equal(pth))
+			Expect(sess.getPath(&receivedPacket{remoteAddr: newAddr}, &unpackedPacket{encryptionLevel: protocol.Encryption1RTT})).To(Equal(pth))
+		})
+
+		It("creates a path for packets received on a preferred address", func() {
+			preferredAddr := &net.UDPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 443}
+			pconn := NewMockPacketConn(mockCtrl)
+			pconn.EXPECT().LocalAddr().Return(preferredAddr).AnyTimes()
+			// The client uses the same address to send to the preferred address.
+			pth := sess.getPath(&receivedPacket{remoteAddr: remoteAddr, conn: pconn}, &unpackedPacket{encryptionLevel: protocol.Encryption1RTT})
+			Expect(pth).ToNot(BeNil())
+			Expect(pth.RemoteAddr()).To(Equal(remoteAddr))
+			Expect(pth.conn.LocalAddr()).To(Equal(preferredAddr))
+			Expect(sess.getPath(&receivedPacket{remoteAddr: remoteAddr, conn: pconn}, &unpackedPacket{encryptionLevel: protocol.Encryption1RTT})).To(Equal(pth))
+			// packets received on the original address still belong to the current path
+			Expect(sess.getPath(&receivedPacket{remoteAddr: remoteAddr}, &unpackedPacket{encryptionLevel: protocol.Encryption1RTT})).To(BeNil())
+		})
+
+		It("ignores packets from a different address while validating a path", func() {
+			mconn.EXPECT().WithRemoteAddr(newAddr).Return(newConn)
+			pth := sess.getPath(&receivedPacket{remoteAddr: newAddr}, &unpackedPacket{encryptionLevel: protocol.Encryption1RTT})
+			Expect(pth).ToNot(BeNil())
+			otherAddr := &net.UDPAddr{IP: net.IPv4(192, 168, 0, 2), Port: 4242}
+			Expect(sess.getPath(&receivedPacket{remoteAddr: otherAddr}, &unpackedPacket{encryptionLevel: protocol.Encryption1RTT})).To(BeNil())
+			Expect(sess.probingPath).To(Equal(pth))
+		})
+
+		It("uses an unused connection ID on the new path", func() {
+			Expect(sess.connIDManager.Add(&wire.NewConnectionIDFrame{
+				SequenceNumber: 1,
+				ConnectionID:   newConnID,
+			})).To(Succeed())
+			mconn.EXPECT().WithRemoteAddr(newAddr).Return(newConn)
+			pth := sess.getPath(&receivedPacket{remoteAddr: newAddr}, &unpackedPacket{encryptionLevel: protocol.Encryption1RTT})
+			Expect(pth.connID).To(Equal(newConnID))
+		})
+
+		It("starts validating the new path when receiving a non-probing packet", func() {
+			mconn.EXPECT().WithRemoteAddr(newAddr).Return(newConn)
+			Expect(receivePacket(&wire.PingFrame{})).To(BeTrue())
+			Expect(sess.probingPath).ToNot(BeNil())
+			Expect(sess.probingPath.isValidating).To(BeTrue())
+			Expect(sess.probingPath.bytesReceived).ToNot(BeZero())
+			Expect(sess.RemoteAddr()).To(Equal(remoteAddr))
+		})
+
+		It("doesn't start validating the new path when receiving a probing packet", func() {
+			mconn.EXPECT().WithRemoteAddr(newAddr).Return(newConn)
+			Expect(receivePacket(&wire.PathChallengeFrame{Data: [8]byte{1, 2, 3, 4, 5, 6, 7, 8}})).To(BeTrue())
+			Expect(sess.probingPath).ToNot(BeNil())
+			Expect(sess.probingPath.isValidating).To(BeFalse())
+			// the PATH_RESPONSE is sent on the new path
+			frames, _ := sess.framer.AppendControlFrames(nil, 1000)
+			Expect(frames).To(BeEmpty())
+			pathFrames := sess.probingPath.AppendFrames(nil, time.Now())
+			Expect(pathFrames).To(HaveLen(1))
+			Expect(pathFrames[0].Frame).To(Equal(&wire.PathResponseFrame{Data: [8]byte{1, 2, 3, 4, 5, 6, 7, 8}}))
+		})
+
+		It("sends path probe packets", func() {
+			sph := mockackhandler.NewMockSentPacketHandler(mockCtrl)
+			sess.sentPacketHandler = sph
+			pth := newPath(newConn, newConnID, true)
+			pth.ReceivedBytes(500)
+			pth.StartValidation(time.Second, time.Now())
+			sess.probingPath = pth
+			p := getPacket(10)
+			packer.EXPECT().PackPathProbePacket(newConnID, gomock.Any(), protocol.ByteCount(1500)).DoAndReturn(func(_ protocol.ConnectionID, frames []ackhandler.Frame, _ protocol.ByteCount) (*packedPacket, error) {
+				Expect(frames).To(HaveLen(1))
+				Expect(frames[0].Frame).To(BeAssignableToTypeOf(&wire.PathChallengeFrame{}))
+				return p, nil
+			})
+			sph.EXPECT().SentPacket(gomock.Any()).Do(func(p *ackhandler.Packet) {
+				Expect(p.IsPathProbePacket).To(BeTrue())
+			})
+			tracer.EXPECT().SentPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
+			newConn.EXPECT().Write([]byte("foobar"))
+			sess.maybeProbePath(time.Now())
+			Expect(pth.bytesSent).To(Equal(protocol.ByteCount(6)))
+		})
+
+		It("migrates to the new path when it is validated", func() {
+			go sess.sendQueue.Run()
+			sph := mockackhandler.NewMockSentPacketHandler(mockCtrl)
+			sess.sentPacketHandler = sph
+			pth := newPath(newConn, newConnID, true)
+			pth.StartValidation(time.Second, time.Now())
+			frames := pth.AppendFrames(nil, time.Now())
+			Expect(frames).To(HaveLen(1))
+			sess.probingPath = pth
+			sph.EXPECT().MigratedPath()
+			Expect(sess.handleFrame(&wire.PathResponseFrame{Data: frames[0].Frame.(*wire.PathChallengeFrame).Data}, protocol.Encryption1RTT, protocol.ConnectionID{})).To(Succeed())
+			Expect(sess.probingPath).To(BeNil())
+			Expect(sess.RemoteAddr()).To(Equal(newAddr))
+			sess.sendQueue.Close()
+		})
+
+		It("restarts path MTU discovery after migrating", func() {
+			go sess.sendQueue.Run()
+			sph := mockackhandler.NewMockSentPacketHandler(mockCtrl)
+			sess.sentPacketHandler = sph
+			mtuDiscoverer := NewMockMtuDiscoverer(mockCtrl)
+			sess.mtuDiscoverer = mtuDiscoverer
+			mtuDiscoverer.EXPECT().NextProbeTime().AnyTimes()
+			pth := newPath(newConn, newConnID, true)
+			pth.StartValidation(time.Second, time.Now())
+			frames := pth.AppendFrames(nil, time.Now())
+			sess.probingPath = pth
+			sph.EXPECT().MigratedPath()
+			mtuDiscoverer.EXPECT().Reset(protocol.ByteCount(protocol.MaxPacketSizeIPv4))
+			Expect(sess.handleFrame(&wire.PathResponseFrame{Data: frames[0].Frame.(*wire.PathChallengeFrame).Data}, protocol.Encryption1RTT, protocol.ConnectionID{})).To(Succeed())
+			sess.sendQueue.Close()
+		})
+
+		It("abandons the path when path validation times out", func() {
+			now := time.Now()
+			pth := newPath(newConn, newConnID, true)
+			pth.StartValidation(time.Second, now)
+			sess.probingPath = pth
+			sess.maybeProbePath(now.Add(time.Second))
+			Expect(sess.probingPath).To(BeNil())
+			Expect(sess.RemoteAddr()).To(Equal(remoteAddr))
+		})
+
+		It("doesn't allow the server to migrate", func() {
+			Expect(sess.MigrateConnection(nil)).To(MatchError("only the client can migrate a connection"))
+		})
+	})
 })
 
 var _ = Describe("Client Session", func() {
@@ -2313,6 +2848,124 @@ var _ = Describe("Client Session", func() {
 		Expect(sess.handleHandshakeDoneFrame()).To(Succeed())
 	})
 
+	Context("migrating to the preferred address", func() {
+		preferredConnID := protocol.ConnectionID{0xde, 0xca, 0xfb, 0xad}
+		preferredAddr := &net.UDPAddr{IP: net.IPv4(192, 0, 2, 2), Port: 443}
+
+		JustBeforeEach(func() {
+			sess.peerParams = &wire.TransportParameters{
+				PreferredAddress: &wire.PreferredAddress{
+					IPv4:         preferredAddr.IP,
+					IPv4Port:     uint16(preferredAddr.Port),
+					ConnectionID: preferredConnID,
+				},
+			}
+			Expect(sess.connIDManager.AddFromPreferredAddress(preferredConnID, protocol.StatelessResetToken{})).To(Succeed())
+			sph := mockackhandler.NewMockSentPacketHandler(mockCtrl)
+			sess.sentPacketHandler = sph
+			sph.EXPECT().SetHandshakeConfirmed()
+			cryptoSetup.EXPECT().SetHandshakeConfirmed()
+		})
+
+		It("starts validating the path to the preferred address when the handshake is confirmed", func() {
+			Expect(sess.handleHandshakeDoneFrame()).To(Succeed())
+			newConn := NewMockSendConn(mockCtrl)
+			newConn.EXPECT().RemoteAddr().Return(preferredAddr).AnyTimes()
+			mconn.EXPECT().RemoteAddr().Return(&net.UDPAddr{IP: net.IPv4(192, 0, 2, 1), Port: 443})
+			mconn.EXPECT().WithRemoteAddr(preferredAddr).Return(newConn)
+			sess.maybeMigrateToPreferredAddress()
+			Expect(sess.probingPath).ToNot(BeNil())
+			Expect(sess.probingPath.RemoteAddr()).To(Equal(preferredAddr))
+			Expect(sess.probingPath.connID).To(Equal(preferredConnID))
+			Expect(sess.probingPath.isValidating).To(BeTrue())
+			// only try once
+			sess.probingPath = nil
+			sess.maybeMigrateToPreferredAddress()
+			Expect(sess.probingPath).To(BeNil())
+		})
+
+		It("doesn't migrate to a preferred address of a different address family", func() {
+			Expect(sess.handleHandshakeDoneFrame()).To(Succeed())
+			mconn.EXPECT().RemoteAddr().Return(&net.UDPAddr{IP: net.ParseIP("2001:db8::1"), Port: 443})
+			sess.maybeMigrateToPreferredAddress()
+			Expect(sess.probingPath).To(BeNil())
+		})
+
+		It("doesn't migrate to the preferred address if a migration is already in progress", func() {
+			Expect(sess.handleHandshakeDoneFrame()).To(Succeed())
+			pth := newPath(NewMockSendConn(mockCtrl), protocol.ConnectionID{1, 2, 3, 4}, false)
+			sess.probingPath = pth
+			mconn.EXPECT().RemoteAddr().Return(&net.UDPAddr{IP: net.IPv4(192, 0, 2, 1), Port: 443})
+			sess.maybeMigrateToPreferredAddress()
+			Expect(sess.probingPath).To(Equal(pth))
+		})
+	})
+
+	Context("migrating to a new packet conn", func() {
+		var (
+			newConn        *MockSendConn
+			packetHandlers *MockSessionRunner
+			migration      *pathMigration
+		)
+
+		JustBeforeEach(func() {
+			sess.handshakeConfirmed = true
+			sess.peerParams = &wire.TransportParameters{}
+			Expect(sess.connIDManager.Add(&wire.NewConnectionIDFrame{
+				SequenceNumber: 1,
+				ConnectionID:   protocol.ConnectionID{0xde, 0xca, 0xfb, 0xad},
+			})).To(Succeed())
+			newConn = NewMockSendConn(mockCtrl)
+			newConn.EXPECT().LocalAddr().Return(&net.UDPAddr{IP: net.IPv4(192, 168, 0, 1), Port: 1234}).AnyTimes()
+			newConn.EXPECT().RemoteAddr().Return(&net.UDPAddr{IP: net.IPv4(192, 0, 2, 1), Port: 443}).AnyTimes()
+			packetHandlers = NewMockSessionRunner(mockCtrl)
+			migration = &pathMigration{
+				conn:           newConn,
+				packetHandlers: packetHandlers,
+				result:         make(chan error, 1),
+			}
+		})
+
+		It("moves the connection IDs to the new packet conn when the path is validated", func() {
+			packetHandlers.EXPECT().Add(srcConnID, sess).Return(true)
+			sess.startMigration(migration)
+			Expect(sess.probingPath).ToNot(BeNil())
+			frames := sess.probingPath.AppendFrames(nil, time.Now())
+			Expect(frames).To(HaveLen(1))
+			go sess.sendQueue.Run()
+			sph := mockackhandler.NewMockSentPacketHandler(mockCtrl)
+			sess.sentPacketHandler = sph
+			sph.EXPECT().MigratedPath()
+			sessionRunner.EXPECT().Remove(srcConnID)
+			packetHandlers.EXPECT().Add(srcConnID, sess)
+			packetHandlers.EXPECT().AddResetToken(gomock.Any(), sess)
+			Expect(sess.handleFrame(&wire.PathResponseFrame{Data: frames[0].Frame.(*wire.PathChallengeFrame).Data}, protocol.Encryption1RTT, protocol.ConnectionID{})).To(Succeed())
+			Expect(migration.result).To(Receive(BeNil()))
+			Expect(sess.LocalAddr()).To(Equal(newConn.LocalAddr()))
+			sess.sendQueue.Close()
+		})
+
+		It("removes the connection IDs from the new packet conn when path validation fails", func() {
+			packetHandlers.EXPECT().Add(srcConnID, sess).Return(true)
+			sess.startMigration(migration)
+			Expect(sess.probingPath).ToNot(BeNil())
+			packetHandlers.EXPECT().Remove(srcConnID)
+			sess.maybeProbePath(time.Now().Add(time.Hour))
+			Expect(sess.probingPath).To(BeNil())
+			var err error
+			Expect(migration.result).To(Receive(&err))
+			Expect(err).To(MatchError("path validation timed out"))
+		})
+
+		It("doesn't remove connection IDs that were already registered with the packet conn", func() {
+			packetHandlers.EXPECT().Add(srcConnID, sess).Return(false)
+			sess.startMigration(migration)
+			Expect(sess.probingPath).ToNot(BeNil())
+			sess.maybeProbePath(time.Now().Add(time.Hour))
+			Expect(migration.result).To(Receive(HaveOccurred()))
+		})
+	})
+
 	Context("handling tokens", func() {
 		var mockTokenStore *MockTokenStore
 
@@ -2428,7 +3081,7 @@ var _ = Describe("Client Session", func() {
 		getRetryTag := func(hdr *wire.ExtendedHeader) []byte {
 			buf := &bytes.Buffer{}
 			hdr.Write(buf, sess.version)
-			return handshake.GetRetryIntegrityTag(buf.Bytes(), origDestConnID)[:]
+			return handshake.GetRetryIntegrityTag(buf.Bytes(), origDestConnID, hdr.Version)[:]
 		}
 
 		It("handles Retry packets", func() {
@@ -2524,6 +3177,7 @@ var _ = Describe("Client Session", func() {
 			packer.EXPECT().HandleTransportParameters(gomock.Any())
 			packer.EXPECT().PackCoalescedPacket().MaxTimes(1)
 			tracer.EXPECT().ReceivedTransportParameters(params)
+			tracer.EXPECT().NegotiatedVersion(sess.version, sess.config.Versions, nil)
 			sess.processTransportParameters(params)
 			sess.connIDManager.SetHandshakeComplete()
 			// make sure the connection ID is not retired
@@ -2545,10 +3199,80 @@ var _ = Describe("Client Session", func() {
 			}
 			packer.EXPECT().HandleTransportParameters(gomock.Any())
 			tracer.EXPECT().ReceivedTransportParameters(params)
+			tracer.EXPECT().NegotiatedVersion(sess.version, sess.config.Versions, nil)
 			sess.processTransportParameters(params)
 			Expect(sess.idleTimeout).To(Equal(18 * time.Second))
 		})
 
+		It("accepts the version_information if the server supports the version in use", func() {
+			sess.version = protocol.Version2
+			sess.versionNegotiated = true
+			sess.config.Versions = []protocol.VersionNumber{protocol.Version2, protocol.Version1}
+			params := &wire.TransportParameters{
+				OriginalDestinationConnectionID: destConnID,
+				InitialSourceConnectionID:       destConnID,
+				VersionInformation: &wire.VersionInformation{
+					ChosenVersion:     protocol.Version2,
+					AvailableVersions: []protocol.VersionNumber{protocol.Version1, protocol.Version2},
+				},
+			}
+			packer.EXPECT().HandleTransportParameters(gomock.Any())
+			tracer.EXPECT().ReceivedTransportParameters(params)
+			tracer.EXPECT().NegotiatedVersion(
+				protocol.Version2,
+				[]protocol.VersionNumber{protocol.Version2, protocol.Version1},
+				[]protocol.VersionNumber{protocol.Version1, protocol.Version2},
+			)
+			sess.processTransportParameters(params)
+			Consistently(errChan).ShouldNot(Receive())
+		})
+
+		It("errors if the version_information is missing after version negotiation", func() {
+			sess.versionNegotiated = true
+			params := &wire.TransportParameters{
+				OriginalDestinationConnectionID: destConnID,
+				InitialSourceConnectionID:       destConnID,
+			}
+			expectClose()
+			tracer.EXPECT().ReceivedTransportParameters(params)
+			sess.processTransportParameters(params)
+			Eventually(errChan).Should(Receive(MatchError("VERSION_NEGOTIATION_ERROR: missing version_information after version negotiation")))
+		})
+
+		It("errors if the chosen version doesn't match the version in use", func() {
+			sess.version = protocol.Version1
+			params := &wire.TransportParameters{
+				OriginalDestinationConnectionID: destConnID,
+				InitialSourceConnectionID:       destConnID,
+				VersionInformation: &wire.VersionInformation{
+					ChosenVersion:     protocol.Version2,
+					AvailableVersions: []protocol.VersionNumber{protocol.Version2},
+				},
+			}
+			expectClose()
+			tracer.EXPECT().ReceivedTransportParameters(params)
+			sess.processTransportParameters(params)
+			Eventually(errChan).Should(Receive(MatchError("VERSION_NEGOTIATION_ERROR: expected chosen version to equal v1, is v2")))
+		})
+
+		It("detects a version downgrade", func() {
+			sess.version = protocol.VersionDraft29
+			sess.versionNegotiated = true
+			sess.config.Versions = []protocol.VersionNumber{protocol.Version1, protocol.VersionDraft29}
+			params := &wire.TransportParameters{
+				OriginalDestinationConnectionID: destConnID,
+				InitialSourceConnectionID:       destConnID,
+				VersionInformation: &wire.VersionInformation{
+					ChosenVersion:     protocol.VersionDraft29,
+					AvailableVersions: []protocol.VersionNumber{protocol.VersionDraft29, protocol.Version1},
+				},
+			}
+			expectClose()
+			tracer.EXPECT().ReceivedTransportParameters(params)
+			sess.processTransportParameters(params)
+			Eventually(errChan).Should(Receive(MatchError("VERSION_NEGOTIATION_ERROR: version downgrade detected: using draft-29, server supports [draft-29 v1]")))
+		})
+
 		It("errors if the TransportParameters contain a wrong initial_source_connection_id", func() {
 			sess.handshakeDestConnID = protocol.ConnectionID{0xde, 0xad, 0xbe, 0xef}
 			params := &wire.TransportParameters{
@@ -0,0 +1,124 @@
+package quic
+
+import (
+	"github.com/For-ACGN/quic-go/internal/protocol"
+)
+
+// A streamScheduler decides in which order streams get to send their data.
+// It is not safe for concurrent use.
+type streamScheduler interface {
+	Len() int
+	// Add adds a stream that has data to send.
+	Add(protocol.StreamID, protocol.StreamPriority)
+	// Pop removes the stream that is allowed to send next.
+	// It must only be called if Len() > 0.
+	Pop() protocol.StreamID
+	// Sent is called after a popped stream sent (at most) one STREAM frame.
+	// If the stream has more data to send, it is scheduled again.
+	Sent(id protocol.StreamID, prio protocol.StreamPriority, n protocol.ByteCount, hasMoreData bool)
+}
+
+func newStreamScheduler(mode protocol.StreamSchedulingMode) streamScheduler {
+	if mode == protocol.StreamSchedulingWeightedFair {
+		return newWeightedFairScheduler()
+	}
+	return &strictPriorityScheduler{}
+}
+
+// The strictPriorityScheduler always schedules the most urgent stream.
+// Incremental streams of the same urgency are scheduled round-robin,
+// non-incremental streams of the same urgency one after the other.
+type strictPriorityScheduler struct {
+	queues [protocol.MaxStreamUrgency + 1][]protocol.StreamID
+	len    int
+}
+
+var _ streamScheduler = &strictPriorityScheduler{}
+
+func (s *strictPriorityScheduler) Len() int { return s.len }
+
+func (s *strictPriorityScheduler) Add(id protocol.StreamID, prio protocol.StreamPriority) {
+	s.queues[prio.Urgency] = append(s.queues[prio.Urgency], id)
+	s.len++
+}
+
+func (s *strictPriorityScheduler) Pop() protocol.StreamID {
+	for i, q := range s.queues {
+		if len(q) == 0 {
+			continue
+		}
+		id := q[0]
+		s.queues[i] = q[1:]
+		s.len--
+		return id
+	}
+	panic("strictPriorityScheduler BUG: Pop called on empty scheduler")
+}
+
+func (s *strictPriorityScheduler) Sent(id protocol.StreamID, prio protocol.StreamPriority, _ protocol.ByteCount, hasMoreData bool) {
+	if !hasMoreData {
+		return
+	}
+	if prio.Incremental { // put the stream back in the queue (at the end)
+		s.Add(id, prio)
+		return
+	}
+	// A non-incremental stream keeps sending until it doesn't have any more data.
+	q := s.queues[prio.Urgency]
+	s.queues[prio.Urgency] = append(append(make([]protocol.StreamID, 0, len(q)+1), id), q...)
+	s.len++
+}
+
+// weightedFairQuantum is the number of bytes a stream with the lowest weight
+// is allowed to send per round.
+const weightedFairQuantum protocol.ByteCount = protocol.MaxPacketSizeIPv4
+
+// The weightedFairScheduler implements deficit round-robin scheduling.
+// In every round, each stream is allowed to send a number of bytes proportional to its weight.
+// The weight is derived from the urgency: the most urgent streams get a weight of 8,
+// the least urgent streams a weight of 1.
+// The incremental flag is ignored.
+type weightedFairScheduler struct {
+	queue   []protocol.StreamID
+	deficit map[protocol.StreamID]protocol.ByteCount
+}
+
+var _ streamScheduler = &weightedFairScheduler{}
+
+func newWeightedFairScheduler() *weightedFairScheduler {
+	return &weightedFairScheduler{deficit: make(map[protocol.StreamID]protocol.ByteCount)}
+}
+
+func (s *weightedFairScheduler) quantum(prio protocol.StreamPriority) protocol.ByteCount {
+	return protocol.ByteCount(protocol.MaxStreamUrgency+1-prio.Urgency) * weightedFairQuantum
+}
+
+func (s *weightedFairScheduler) Len() int { return len(s.queue) }
+
+func (s *weightedFairScheduler) Add(id protocol.StreamID, prio protocol.StreamPriority) {
+	s.queue = append(s.queue, id)
+	s.deficit[id] = s.quantum(prio)
+}
+
+func (s *weightedFairScheduler) Pop() protocol.StreamID {
+	id := s.queue[0]
+	s.queue = s.queue[1:]
+	return id
+}
+
+func (s *weightedFairScheduler) Sent(id protocol.StreamID, prio protocol.StreamPriority, n protocol.ByteCount, hasMoreData bool) {
+	if !hasMoreData {
+		delete(s.deficit, id)
+		return
+	}
+	deficit := s.deficit[id] - n
+	if deficit > 0 { // the stream is allowed to send more data in this round
+		s.deficit[id] = deficit
+		s.queue = append(append(make([]protocol.StreamID, 0, len(s.queue)+1), id), s.queue...)
+		return
+	}
+	// The stream used up its share, and might even have sent a bit more than that.
+	// Move it to the end of the queue, and account for the excess in the next round.
+	s.deficit[id] = deficit + s.quantum(prio)
+	s.queue = append(s.queue, id)
+}
@@ -0,0 +1,135 @@
+package quic
+
+import (
+	"github.com/For-ACGN/quic-go/internal/protocol"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Stream Scheduler", func() {
+	// send simulates sending n packets of the given size.
+	// All streams always have more data to send.
+	send := func(s streamScheduler, priorities map[protocol.StreamID]protocol.StreamPriority, n int, size protocol.ByteCount) map[protocol.StreamID]protocol.ByteCount {
+		sent := make(map[protocol.StreamID]protocol.ByteCount)
+		for i := 0; i < n; i++ {
+			id := s.Pop()
+			sent[id] += size
+			s.Sent(id, priorities[id], size, true)
+		}
+		return sent
+	}
+
+	Context("strict priority", func() {
+		var s streamScheduler
+
+		BeforeEach(func() {
+			s = newStreamScheduler(protocol.StreamSchedulingStrictPriority)
+		})
+
+		It("schedules the most urgent stream first", func() {
+			s.Add(4, protocol.StreamPriority{Urgency: 5, Incremental: true})
+			s.Add(8, protocol.StreamPriority{Urgency: 1, Incremental: true})
+			s.Add(12, protocol.StreamPriority{Urgency: 3, Incremental: true})
+			Expect(s.Len()).To(Equal(3))
+			Expect(s.Pop()).To(Equal(protocol.StreamID(8)))
+			Expect(s.Pop()).To(Equal(protocol.StreamID(12)))
+			Expect(s.Pop()).To(Equal(protocol.StreamID(4)))
+			Expect(s.Len()).To(BeZero())
+		})
+
+		It("schedules incremental streams of the same urgency round-robin", func() {
+			prio := protocol.StreamPriority{Urgency: 3, Incremental: true}
+			priorities := map[protocol.StreamID]protocol.StreamPriority{4: prio, 8: prio, 12: prio}
+			s.Add(4, prio)
+			s.Add(8, prio)
+			s.Add(12, prio)
+			sent := send(s, priorities, 300, 1000)
+			Expect(sent).To(HaveLen(3))
+			for _, n := range sent {
+				Expect(n).To(Equal(protocol.ByteCount(100 * 1000)))
+			}
+		})
+
+		It("schedules non-incremental streams of the same urgency one after the other", func() {
+			prio := protocol.StreamPriority{Urgency: 3}
+			s.Add(4, prio)
+			s.Add(8, prio)
+			for i := 0; i < 10; i++ {
+				id := s.Pop()
+				Expect(id).To(Equal(protocol.StreamID(4)))
+				s.Sent(id, prio, 1000, true)
+			}
+			Expect(s.Pop()).To(Equal(protocol.StreamID(4)))
+			s.Sent(4, prio, 1000, false)
+			Expect(s.Pop()).To(Equal(protocol.StreamID(8)))
+			s.Sent(8, prio, 1000, false)
+			Expect(s.Len()).To(BeZero())
+		})
+
+		It("doesn't reschedule streams that don't have any more data", func() {
+			prio := protocol.StreamPriority{Urgency: 3, Incremental: true}
+			s.Add(4, prio)
+			s.Sent(s.Pop(), prio, 1000, false)
+			Expect(s.Len()).To(BeZero())
+		})
+	})
+
+	Context("weighted fair", func() {
+		var s streamScheduler
+
+		BeforeEach(func() {
+			s = newStreamScheduler(protocol.StreamSchedulingWeightedFair)
+		})
+
+		It("shares the bandwidth equally between streams of the same urgency", func() {
+			prio := protocol.StreamPriority{Urgency: 3}
+			priorities := map[protocol.StreamID]protocol.StreamPriority{4: prio, 8: prio, 12: prio}
+			s.Add(4, prio)
+			s.Add(8, prio)
+			s.Add(12, prio)
+			sent := send(s, priorities, 3000, 1000)
+			Expect(sent).To(HaveLen(3))
+			for _, n := range sent {
+				Expect(n).To(BeNumerically("~", 1000*1000, 10*1000))
+			}
+		})
+
+		It("weights the bandwidth by urgency", func() {
+			priorities := map[protocol.StreamID]protocol.StreamPriority{
+				4: {Urgency: 0},
+				8: {Urgency: 7},
+			}
+			s.Add(4, priorities[4])
+			s.Add(8, priorities[8])
+			sent := send(s, priorities, 9000, 1000)
+			Expect(sent[4] + sent[8]).To(Equal(protocol.ByteCount(9000 * 1000)))
+			// the most urgent stream gets a weight of 8, the least urgent stream a weight of 1
+			Expect(sent[4]).To(BeNumerically("~", 8*sent[8], 8*weightedFairQuantum))
+		})
+
+		It("doesn't starve the least urgent stream", func() {
+			priorities := map[protocol.StreamID]protocol.StreamPriority{8: {Urgency: protocol.MaxStreamUrgency}}
+			s.Add(8, priorities[8])
+			for id := protocol.StreamID(12); id < 12+4*10; id += 4 {
+				priorities[id] = protocol.StreamPriority{Urgency: 0}
+				s.Add(id, priorities[id])
+			}
+			// The least urgent stream is scheduled at least once per round.
+			// In every round, each of the 10 most urgent streams sends at most 8 quanta.
+			maxPacketsPerRound := 10*8*int(weightedFairQuantum/1000+1) + 1
+			for round := 0; round < 5; round++ {
+				sent := send(s, priorities, maxPacketsPerRound, 1000)
+				Expect(sent).To(HaveKey(protocol.StreamID(8)))
+			}
+		})
+
+		It("forgets about streams that don't have any more data", func() {
+			prio := protocol.StreamPriority{Urgency: 3}
+			s.Add(4, prio)
+			s.Sent(s.Pop(), prio, 1000, false)
+			Expect(s.Len()).To(BeZero())
+			Expect(s.(*weightedFairScheduler).deficit).To(BeEmpty())
+		})
+	})
+})